@@ -48,7 +48,7 @@ MICROSERVICES ARCHITECTURE:
         - document-processor (port 8082) Document chunking
         - embedding (port 8083)        Azure OpenAI embeddings
         - vector-storage (port 8084)   Pinecone integration
-        - notification (port 8085)     Slack notifications
+        - notification (port 8085)     Slack and Teams notifications
 
 GETTING STARTED:
     1. Copy .env.example to .env and configure credentials
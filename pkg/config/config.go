@@ -15,12 +15,18 @@ type Config struct {
 	// Azure OpenAI
 	AzureOpenAI AzureOpenAIConfig
 
+	// Embedding provider
+	Embedding EmbeddingConfig
+
 	// GitHub
 	GitHub GitHubConfig
 
 	// Pinecone
 	Pinecone PineconeConfig
 
+	// Vector store backend
+	VectorStore VectorStoreConfig
+
 	// Processing
 	Processing ProcessingConfig
 
@@ -47,21 +53,143 @@ type AzureOpenAIConfig struct {
 	EmbeddingsDeployment string
 	APIVersion           string
 	ChatDeployment       string
+	Failover             []AzureDeployment // further embeddings deployments, tried after the primary
+	Routing              string            // failover (primary first) or round-robin
+}
+
+// AzureDeployment is an embeddings deployment in some Azure OpenAI resource
+type AzureDeployment struct {
+	Endpoint   string
+	Deployment string
+	APIKey     string
+}
+
+type EmbeddingConfig struct {
+	Provider        string   // azure, openai, ollama, cohere, vertex, bedrock, or tei
+	Concurrency     int      // sub-batches embedded in parallel per request
+	MaxAttempts     int      // attempts per API call when rate limited, including the first
+	Overflow        string   // reject, truncate, or split texts over the model's token limit
+	Dimensions      int      // output dimensions for models that can shorten embeddings, 0 for the model's own
+	CostPer1KTokens float64  // USD per 1,000 input tokens for cost reports, 0 uses the built-in price table
+	Normalize       bool     // L2-normalize embeddings unless a request says otherwise
+	DocumentPrefix  string   // put before texts embedded as documents, overriding the model's known prefix
+	QueryPrefix     string   // put before texts embedded as queries, overriding the model's known prefix
+	ModelVersion    string   // stamped on embeddings with the model name, e.g. an Azure deployment's model version
+	MaxPendingJobs  int      // /embed/jobs waiting for a slot before submissions get 429
+	CallbackHosts   []string // hosts, optionally host:port, a job's callback_url may name; empty refuses callbacks
+	OpenAI          OpenAIConfig
+	Ollama          OllamaConfig
+	Cohere          CohereConfig
+	Vertex          VertexConfig
+	Bedrock         BedrockConfig
+	TEI             TEIConfig
+}
+
+type OpenAIConfig struct {
+	APIKey       string
+	BaseURL      string // OpenAI-compatible API root, e.g. a proxy
+	Model        string
+	Organization string
+}
+
+type OllamaConfig struct {
+	URL   string
+	Model string
+}
+
+type CohereConfig struct {
+	APIKey    string
+	BaseURL   string
+	Model     string
+	InputType string // document or query
+}
+
+type VertexConfig struct {
+	CredentialsFile string // service account JSON key
+	ProjectID       string
+	Location        string
+	Model           string
+	BaseURL         string // overrides the regional endpoint
+}
+
+// BedrockConfig selects the model; credentials come from the default AWS
+// chain (AWS_ACCESS_KEY_ID, AWS_PROFILE, or an instance/task role)
+type BedrockConfig struct {
+	Region      string
+	Model       string
+	EndpointURL string // overrides the regional endpoint
+}
+
+// TEIConfig points at a self-hosted text-embeddings-inference server
+type TEIConfig struct {
+	URL          string
+	Model        string // reported on /health; the server decides the model
+	APIKey       string
+	MaxBatchSize int
 }
 
 type GitHubConfig struct {
-	Token         string
-	Organization  string
-	FilterKeyword string
+	Token              string
+	Organization       string
+	FilterKeyword      string
+	Provider           string   // github, local, or ssh
+	LocalPaths         []string // directories served by the local provider
+	LocalStateDir      string   // file lists for non-git local directories, empty disables deletion tracking
+	ContentCacheDir    string   // blob-SHA keyed content cache, empty disables
+	IncludeWikis       bool
+	IncludeIssues      bool
+	IncludeDiscussions bool
+	IncludeReleases    bool
+	RepoCallBudget     int // API calls per repository per sync before warning, 0 disables
+
+	// SSH provider: clones repositories with deploy keys instead of using a PAT
+	SSHRepositories   []string          // repository names within Organization
+	SSHKeyPath        string            // default private key
+	SSHDeployKeys     map[string]string // repository name -> private key path
+	SSHHost           string
+	SSHKnownHostsPath string // required: host keys are never trusted on first use
+	SSHCloneDir       string
+}
+
+type VectorStoreConfig struct {
+	Backend       string // pinecone, qdrant, weaviate, or memory
+	Qdrant        QdrantConfig
+	Weaviate      WeaviateConfig
+	Memory        MemoryConfig
+	SparseVectors bool    // store BM25-style sparse vectors for hybrid search
+	HybridAlpha   float64 // default weight of the dense vector in hybrid queries, 0 to 1
+	StoreText     bool    // keep chunk text in vector metadata, for re-embedding
+	DedupUpserts  bool    // skip upserting vectors whose stored checksum matches
+	RefreshDays   int     // upsert unchanged vectors anyway once indexed this long ago
+}
+
+type QdrantConfig struct {
+	URL        string
+	APIKey     string
+	Collection string // for vectors without a namespace; namespaces are collections of their own
+	Dimension  int    // vector size collections are created with
+}
+
+type WeaviateConfig struct {
+	URL       string
+	APIKey    string
+	Class     string // for vectors without a namespace; each project namespace is a class of its own
+	Dimension int    // vector size upserts and queries are checked against
+}
+
+type MemoryConfig struct {
+	Dimension int // vector size upserts and queries are checked against
 }
 
 type PineconeConfig struct {
 	APIKey        string
 	IndexName     string
 	Dimension     int
+	Metric        string // cosine, dotproduct, or euclidean
 	Cloud         string
 	Region        string
 	UseNamespaces bool
+	CreateIndex   bool // create a missing serverless index on startup
 }
 
 type ProcessingConfig struct {
@@ -70,12 +198,46 @@ type ProcessingConfig struct {
 	MaxWorkers              int
 	RateLimitRequestsPerMin int
 	EmbeddingBatchSize      int
+	EmbeddingAsyncThreshold int    // texts from which the orchestrator uses background embedding jobs, 0 never
+	EmbeddingModel          string // chunks are split to fit this model's token limit
+	EmbeddingMaxTokens      int    // overrides the model's token limit, 0 uses the built-in table
 	MaxChunkSize            int
-	ChunkOverlap            int
+	ChunkOverlap            int               // characters, converted to whole tokens
+	ChunkOverlapTokens      int               // overrides ChunkOverlap when set
+	ChunkOverlapSentences   int               // overlap in whole sentences, wins over tokens
+	CSVRowsPerChunk         int               // data rows per CSV/TSV chunk, header repeated in each
+	ChunkStrategies         map[string]string // file extension -> chunking strategy
+	ChunkCleaning           map[string]string // strategy.step -> bool, e.g. plain.preserve_indentation=true
+	PIIRedaction            []string          // email, phone, ip; empty disables
+	PIIMaskMode             string            // label, hash, or partial
+	DedupChunks             bool              // skip chunks whose content hash was already seen in a run
+	EmbeddingCacheSize      int               // vectors reused across runs by content hash, 0 disables
+	SummarizeChunks         bool              // add an LLM summary and keywords to chunk metadata
+	SkipUnchangedChunks     bool              // don't re-embed chunks whose content-derived ID is already stored
+	SkipGeneratedFiles      bool              // don't index lockfiles and generated, minified, or vendored files
+	StripLicenseHeaders     bool              // remove license/copyright comment headers from source files
+	ChangeDetection         string            // commit or blob
+	LazyContentFetch        bool
 }
 
 type DatabaseConfig struct {
-	MetadataDBPath string
+	Driver         string // sqlite, mysql, or redis
+	MetadataDBPath string // SQLite database file
+	DSN            string // MySQL/MariaDB data source name, e.g. user:pass@tcp(host:3306)/reposync
+	RedisURL       string // Redis server, e.g. redis://:password@localhost:6379/0
+
+	// Callers of the metadata service: when APIKeys is set, every request but
+	// /health must send one of its keys and is audited under that key's ID
+	APIKeys map[string]string // caller ID to API key
+	APIKey  string            // key the orchestrator and document processor send
+
+	// Retention of purged data, 0 to keep it forever
+	JobRetentionDays       int           // finished sync jobs
+	TombstoneRetentionDays int           // records of deleted files
+	AuditRetentionDays     int           // audit log entries
+	PurgeInterval          time.Duration // between scheduled purges, 0 for manual purges only
+
+	AuditRequired bool // refuse to start on a driver that keeps no audit log (redis)
 }
 
 type LoggingConfig struct {
@@ -84,7 +246,11 @@ type LoggingConfig struct {
 }
 
 type NotificationsConfig struct {
+	Channels        []string // slack, teams, webhook
 	SlackWebhookURL string
+	TeamsWebhookURL string   // Microsoft Teams incoming webhook
+	WebhookURLs     []string // outbound webhooks posted the raw payload
+	WebhookSecret   string   // HMAC key signing outbound webhook payloads
 }
 
 type SchedulerConfig struct {
@@ -97,7 +263,9 @@ type ServicesConfig struct {
 	GitHubServicePort       int
 	DocumentProcessorPort   int
 	EmbeddingServicePort    int
+	EmbeddingGRPCPort       int // 0 serves no gRPC API
 	VectorStoragePort       int
+	VectorStorageGRPCPort   int // 0 serves no gRPC API
 	NotificationServicePort int
 	MetadataServicePort     int
 }
@@ -115,19 +283,109 @@ func Load() (*Config, error) {
 			EmbeddingsDeployment: getEnv("AZURE_OPENAI_EMBEDDINGS_DEPLOYMENT", "text-embedding-ada-002"),
 			APIVersion:           getEnv("AZURE_OPENAI_API_VERSION", "2023-05-15"),
 			ChatDeployment:       getEnv("AZURE_OPENAI_CHAT_DEPLOYMENT", "gpt-35-turbo"),
+			Failover:             parseAzureDeployments(getEnv("AZURE_OPENAI_FAILOVER_DEPLOYMENTS", ""), getEnv("AZURE_OPENAI_API_KEY", "")),
+			Routing:              strings.ToLower(getEnv("AZURE_OPENAI_ROUTING", "failover")),
+		},
+		Embedding: EmbeddingConfig{
+			Provider:        strings.ToLower(getEnv("EMBEDDING_PROVIDER", "azure")),
+			Concurrency:     getEnvInt("EMBEDDING_CONCURRENCY", 4),
+			MaxAttempts:     getEnvInt("EMBEDDING_MAX_ATTEMPTS", 5),
+			Overflow:        strings.ToLower(getEnv("EMBEDDING_OVERFLOW", "truncate")),
+			Dimensions:      getEnvInt("EMBEDDING_DIMENSIONS", 0),
+			CostPer1KTokens: getEnvFloat("EMBEDDING_COST_PER_1K_TOKENS", 0),
+			Normalize:       getEnvBool("EMBEDDING_NORMALIZE", false),
+			DocumentPrefix:  getEnv("EMBEDDING_DOCUMENT_PREFIX", ""),
+			QueryPrefix:     getEnv("EMBEDDING_QUERY_PREFIX", ""),
+			ModelVersion:    getEnv("EMBEDDING_MODEL_VERSION", ""),
+			MaxPendingJobs:  getEnvInt("EMBEDDING_MAX_PENDING_JOBS", 100),
+			CallbackHosts:   parseCSV(getEnv("EMBEDDING_CALLBACK_HOSTS", "")),
+			OpenAI: OpenAIConfig{
+				APIKey:       getEnv("OPENAI_API_KEY", ""),
+				BaseURL:      getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+				Model:        getEnv("OPENAI_EMBEDDING_MODEL", "text-embedding-3-small"),
+				Organization: getEnv("OPENAI_ORGANIZATION", ""),
+			},
+			Ollama: OllamaConfig{
+				URL:   getEnv("OLLAMA_URL", "http://localhost:11434"),
+				Model: getEnv("OLLAMA_EMBEDDING_MODEL", "nomic-embed-text"),
+			},
+			Cohere: CohereConfig{
+				APIKey:    getEnv("COHERE_API_KEY", ""),
+				BaseURL:   getEnv("COHERE_BASE_URL", "https://api.cohere.com"),
+				Model:     getEnv("COHERE_EMBEDDING_MODEL", "embed-english-v3.0"),
+				InputType: getEnv("COHERE_INPUT_TYPE", "document"),
+			},
+			Vertex: VertexConfig{
+				CredentialsFile: getEnv("VERTEX_CREDENTIALS_FILE", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")),
+				ProjectID:       getEnv("VERTEX_PROJECT_ID", ""),
+				Location:        getEnv("VERTEX_LOCATION", "us-central1"),
+				Model:           getEnv("VERTEX_EMBEDDING_MODEL", "text-embedding-004"),
+				BaseURL:         getEnv("VERTEX_BASE_URL", ""),
+			},
+			Bedrock: BedrockConfig{
+				Region:      getEnv("BEDROCK_REGION", getEnv("AWS_REGION", "us-east-1")),
+				Model:       getEnv("BEDROCK_EMBEDDING_MODEL", "amazon.titan-embed-text-v2:0"),
+				EndpointURL: getEnv("BEDROCK_ENDPOINT_URL", ""),
+			},
+			TEI: TEIConfig{
+				URL:          getEnv("TEI_URL", ""),
+				Model:        getEnv("TEI_MODEL", ""),
+				APIKey:       getEnv("TEI_API_KEY", ""),
+				MaxBatchSize: getEnvInt("TEI_MAX_BATCH_SIZE", 32),
+			},
 		},
 		GitHub: GitHubConfig{
-			Token:         getEnv("GH_TOKEN", ""),
-			Organization:  getEnv("GH_ORGANIZATION", ""),
-			FilterKeyword: getEnv("GH_FILTER_KEYWORD", ""),
+			Token:              getEnv("GH_TOKEN", ""),
+			Organization:       getEnv("GH_ORGANIZATION", ""),
+			FilterKeyword:      getEnv("GH_FILTER_KEYWORD", ""),
+			Provider:           getEnv("REPOSITORY_PROVIDER", "github"),
+			LocalPaths:         parseCSV(getEnv("LOCAL_REPOSITORY_PATHS", "")),
+			LocalStateDir:      getEnv("LOCAL_STATE_DIR", "./data/local-state"),
+			ContentCacheDir:    getEnv("CONTENT_CACHE_DIR", "./data/blob-cache"),
+			IncludeWikis:       getEnvBool("GH_INCLUDE_WIKIS", false),
+			IncludeIssues:      getEnvBool("GH_INCLUDE_ISSUES", false),
+			IncludeDiscussions: getEnvBool("GH_INCLUDE_DISCUSSIONS", false),
+			IncludeReleases:    getEnvBool("GH_INCLUDE_RELEASES", false),
+			RepoCallBudget:     getEnvInt("GH_REPO_CALL_BUDGET", 0),
+			SSHRepositories:    parseCSV(getEnv("SSH_REPOSITORIES", "")),
+			SSHKeyPath:         getEnv("SSH_KEY_PATH", ""),
+			SSHDeployKeys:      parseKeyValueCSV(getEnv("SSH_DEPLOY_KEYS", "")),
+			SSHHost:            getEnv("SSH_HOST", "github.com"),
+			SSHKnownHostsPath:  getEnv("SSH_KNOWN_HOSTS_PATH", ""),
+			SSHCloneDir:        getEnv("SSH_CLONE_DIR", "./data/clones"),
 		},
 		Pinecone: PineconeConfig{
 			APIKey:        getEnv("PINECONE_API_KEY", ""),
 			IndexName:     getEnv("PINECONE_INDEX_NAME", "reposync-index"),
 			Dimension:     getEnvInt("PINECONE_DIMENSION", 1536),
-			Cloud:         getEnv("PINECONE_CLOUD", "aws"),
+			Metric:        strings.ToLower(getEnv("PINECONE_METRIC", "cosine")),
+			Cloud:         strings.ToLower(getEnv("PINECONE_CLOUD", "aws")),
 			Region:        getEnv("PINECONE_REGION", "us-east-1"),
 			UseNamespaces: getEnvBool("PINECONE_USE_NAMESPACES", true),
+			CreateIndex:   getEnvBool("PINECONE_CREATE_INDEX", true),
+		},
+		VectorStore: VectorStoreConfig{
+			Backend: strings.ToLower(getEnv("VECTOR_BACKEND", "pinecone")),
+			Qdrant: QdrantConfig{
+				URL:        getEnv("QDRANT_URL", "http://localhost:6333"),
+				APIKey:     getEnv("QDRANT_API_KEY", ""),
+				Collection: getEnv("QDRANT_COLLECTION", "reposync"),
+				Dimension:  getEnvInt("QDRANT_DIMENSION", 1536),
+			},
+			Weaviate: WeaviateConfig{
+				URL:       getEnv("WEAVIATE_URL", "http://localhost:8080"),
+				APIKey:    getEnv("WEAVIATE_API_KEY", ""),
+				Class:     getEnv("WEAVIATE_CLASS", "RepoSync"),
+				Dimension: getEnvInt("WEAVIATE_DIMENSION", 1536),
+			},
+			Memory: MemoryConfig{
+				Dimension: getEnvInt("MEMORY_DIMENSION", 1536),
+			},
+			SparseVectors: getEnvBool("SPARSE_VECTORS", false),
+			HybridAlpha:   getEnvFloat("HYBRID_ALPHA", 0.5),
+			StoreText:     getEnvBool("STORE_CHUNK_TEXT", false),
+			DedupUpserts:  getEnvBool("DEDUP_UPSERTS", true),
+			RefreshDays:   getEnvInt("VECTOR_REFRESH_DAYS", 7),
 		},
 		Processing: ProcessingConfig{
 			AllowedExtensions:       parseCSV(getEnv("ALLOWED_FILE_EXTENSIONS", ".md,.rst,.txt,.yaml,.yml,.json")),
@@ -135,18 +393,52 @@ func Load() (*Config, error) {
 			MaxWorkers:              getEnvInt("MAX_WORKERS", 5),
 			RateLimitRequestsPerMin: getEnvInt("RATE_LIMIT_REQUESTS_PER_MINUTE", 60),
 			EmbeddingBatchSize:      getEnvInt("EMBEDDING_BATCH_SIZE", 100),
+			EmbeddingAsyncThreshold: getEnvInt("EMBEDDING_ASYNC_THRESHOLD", 1000),
+			EmbeddingModel:          getEnv("EMBEDDING_MODEL", "text-embedding-ada-002"),
+			EmbeddingMaxTokens:      getEnvInt("EMBEDDING_MODEL_MAX_TOKENS", 0),
 			MaxChunkSize:            getEnvInt("MAX_CHUNK_SIZE", 1000),
 			ChunkOverlap:            getEnvInt("CHUNK_OVERLAP", 200),
+			ChunkOverlapTokens:      getEnvInt("CHUNK_OVERLAP_TOKENS", 0),
+			ChunkOverlapSentences:   getEnvInt("CHUNK_OVERLAP_SENTENCES", 0),
+			CSVRowsPerChunk:         getEnvInt("CSV_ROWS_PER_CHUNK", 20),
+			ChunkStrategies:         parseKeyValueCSV(getEnv("CHUNK_STRATEGIES", "")),
+			ChunkCleaning:           parseKeyValueCSV(getEnv("CHUNK_CLEANING", "")),
+			PIIRedaction:            parseCSV(getEnv("PII_REDACTION", "")),
+			PIIMaskMode:             getEnv("PII_MASK_MODE", "label"),
+			DedupChunks:             getEnvBool("DEDUP_CHUNKS", true),
+			EmbeddingCacheSize:      getEnvInt("EMBEDDING_CACHE_SIZE", 0),
+			SummarizeChunks:         getEnvBool("SUMMARIZE_CHUNKS", false),
+			SkipUnchangedChunks:     getEnvBool("SKIP_UNCHANGED_CHUNKS", true),
+			SkipGeneratedFiles:      getEnvBool("SKIP_GENERATED_FILES", true),
+			StripLicenseHeaders:     getEnvBool("STRIP_LICENSE_HEADERS", true),
+			ChangeDetection:         getEnv("CHANGE_DETECTION", "commit"),
+			LazyContentFetch:        getEnvBool("LAZY_CONTENT_FETCH", false),
 		},
 		Database: DatabaseConfig{
+			Driver:         strings.ToLower(getEnv("METADATA_DB_DRIVER", "sqlite")),
 			MetadataDBPath: getEnv("METADATA_DB_PATH", "./data/metadata.db"),
+			DSN:            getEnv("METADATA_DB_DSN", ""),
+			RedisURL:       getEnv("METADATA_REDIS_URL", "redis://localhost:6379/0"),
+			APIKeys:        parseKeyValueCSV(getEnv("METADATA_API_KEYS", "")),
+			APIKey:         getEnv("METADATA_API_KEY", ""),
+
+			JobRetentionDays:       getEnvInt("METADATA_JOB_RETENTION_DAYS", 90),
+			TombstoneRetentionDays: getEnvInt("METADATA_TOMBSTONE_RETENTION_DAYS", 30),
+			AuditRetentionDays:     getEnvInt("METADATA_AUDIT_RETENTION_DAYS", 365),
+			PurgeInterval:          getEnvDuration("METADATA_PURGE_INTERVAL", 24*time.Hour),
+
+			AuditRequired: getEnvBool("METADATA_AUDIT_REQUIRED", false),
 		},
 		Logging: LoggingConfig{
 			Level:    getEnv("LOG_LEVEL", "INFO"),
 			FilePath: getEnv("LOG_FILE_PATH", "./logs/reposync.log"),
 		},
 		Notifications: NotificationsConfig{
+			Channels:        parseCSV(strings.ToLower(getEnv("NOTIFICATION_CHANNELS", "slack"))),
 			SlackWebhookURL: getEnv("SLACK_WEBHOOK_URL", ""),
+			TeamsWebhookURL: getEnv("TEAMS_WEBHOOK_URL", ""),
+			WebhookURLs:     parseCSV(getEnv("WEBHOOK_URLS", "")),
+			WebhookSecret:   getEnv("WEBHOOK_SECRET", ""),
 		},
 		Scheduler: SchedulerConfig{
 			Time:     getEnv("SCHEDULE_TIME", "08:00"),
@@ -157,7 +449,9 @@ func Load() (*Config, error) {
 			GitHubServicePort:       getEnvInt("GITHUB_SERVICE_PORT", 9081),
 			DocumentProcessorPort:   getEnvInt("DOCUMENT_PROCESSOR_PORT", 9082),
 			EmbeddingServicePort:    getEnvInt("EMBEDDING_SERVICE_PORT", 9083),
+			EmbeddingGRPCPort:       getEnvInt("EMBEDDING_GRPC_PORT", 0),
 			VectorStoragePort:       getEnvInt("VECTOR_STORAGE_PORT", 9084),
+			VectorStorageGRPCPort:   getEnvInt("VECTOR_STORAGE_GRPC_PORT", 0),
 			NotificationServicePort: getEnvInt("NOTIFICATION_SERVICE_PORT", 9085),
 			MetadataServicePort:     getEnvInt("METADATA_SERVICE_PORT", 9086),
 		},
@@ -181,6 +475,32 @@ func (c *Config) Validate() error {
 
 // ValidateForGitHub validates GitHub-specific requirements
 func (c *Config) ValidateForGitHub() error {
+	if c.GitHub.Provider == "local" {
+		if len(c.GitHub.LocalPaths) == 0 {
+			return fmt.Errorf("LOCAL_REPOSITORY_PATHS is required when REPOSITORY_PROVIDER=local")
+		}
+		if c.GitHub.Organization == "" {
+			return fmt.Errorf("GH_ORGANIZATION is required")
+		}
+		return nil
+	}
+	if c.GitHub.Provider == "ssh" {
+		if len(c.GitHub.SSHRepositories) == 0 {
+			return fmt.Errorf("SSH_REPOSITORIES is required when REPOSITORY_PROVIDER=ssh")
+		}
+		if c.GitHub.Organization == "" {
+			return fmt.Errorf("GH_ORGANIZATION is required")
+		}
+		if c.GitHub.SSHKnownHostsPath == "" {
+			return fmt.Errorf("SSH_KNOWN_HOSTS_PATH is required when REPOSITORY_PROVIDER=ssh")
+		}
+		for _, repo := range c.GitHub.SSHRepositories {
+			if c.GitHub.SSHDeployKeys[repo] == "" && c.GitHub.SSHKeyPath == "" {
+				return fmt.Errorf("no SSH key for repository %s: set SSH_KEY_PATH or SSH_DEPLOY_KEYS", repo)
+			}
+		}
+		return nil
+	}
 	if c.GitHub.Token == "" {
 		return fmt.Errorf("GH_TOKEN is required")
 	}
@@ -190,24 +510,208 @@ func (c *Config) ValidateForGitHub() error {
 	return nil
 }
 
-// ValidateForEmbedding validates embedding service requirements
+// ValidateForEmbedding validates embedding service requirements for the
+// selected provider
 func (c *Config) ValidateForEmbedding() error {
+	if c.Embedding.MaxPendingJobs <= 0 {
+		return fmt.Errorf("EMBEDDING_MAX_PENDING_JOBS must be positive")
+	}
+	if d := c.Embedding.Dimensions; d != 0 {
+		if d < 0 {
+			return fmt.Errorf("EMBEDDING_DIMENSIONS must be positive")
+		}
+		if c.Embedding.Provider != "azure" && c.Embedding.Provider != "openai" {
+			return fmt.Errorf("EMBEDDING_DIMENSIONS is only supported with EMBEDDING_PROVIDER=azure or openai")
+		}
+		// Vectors of another size would be rejected by the index
+		if dim, name := c.VectorDimension(); dim > 0 && d != dim {
+			return fmt.Errorf("EMBEDDING_DIMENSIONS (%d) does not match the vector index dimension %s (%d)", d, name, dim)
+		}
+	}
+
+	switch c.Embedding.Provider {
+	case "azure":
+	case "openai":
+		if c.Embedding.OpenAI.APIKey == "" {
+			return fmt.Errorf("OPENAI_API_KEY is required when EMBEDDING_PROVIDER=openai")
+		}
+		return nil
+	case "ollama":
+		// Local server, no credentials
+		return nil
+	case "cohere":
+		if c.Embedding.Cohere.APIKey == "" {
+			return fmt.Errorf("COHERE_API_KEY is required when EMBEDDING_PROVIDER=cohere")
+		}
+		return nil
+	case "vertex":
+		if c.Embedding.Vertex.CredentialsFile == "" {
+			return fmt.Errorf("VERTEX_CREDENTIALS_FILE or GOOGLE_APPLICATION_CREDENTIALS is required when EMBEDDING_PROVIDER=vertex")
+		}
+		if c.Embedding.Vertex.ProjectID == "" {
+			return fmt.Errorf("VERTEX_PROJECT_ID is required when EMBEDDING_PROVIDER=vertex")
+		}
+		return nil
+	case "bedrock":
+		// Credentials are resolved by the AWS SDK
+		return nil
+	case "tei":
+		if c.Embedding.TEI.URL == "" {
+			return fmt.Errorf("TEI_URL is required when EMBEDDING_PROVIDER=tei")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown EMBEDDING_PROVIDER %q (available: azure, openai, ollama, cohere, vertex, bedrock, tei)", c.Embedding.Provider)
+	}
 	if c.AzureOpenAI.APIKey == "" {
 		return fmt.Errorf("AZURE_OPENAI_API_KEY is required")
 	}
 	if c.AzureOpenAI.Endpoint == "" {
 		return fmt.Errorf("AZURE_OPENAI_ENDPOINT is required")
 	}
+	for i, d := range c.AzureOpenAI.Failover {
+		if d.Endpoint == "" || d.Deployment == "" {
+			return fmt.Errorf("AZURE_OPENAI_FAILOVER_DEPLOYMENTS entry %d must be endpoint|deployment[|api-key]", i+1)
+		}
+	}
+	if r := c.AzureOpenAI.Routing; r != "failover" && r != "round-robin" {
+		return fmt.Errorf("unknown AZURE_OPENAI_ROUTING %q (available: failover, round-robin)", r)
+	}
 	return nil
 }
 
 // ValidateForVectorStorage validates vector storage requirements
 func (c *Config) ValidateForVectorStorage() error {
-	if c.Pinecone.APIKey == "" {
-		return fmt.Errorf("PINECONE_API_KEY is required")
+	switch c.VectorStore.Backend {
+	case "pinecone":
+		if c.Pinecone.APIKey == "" {
+			return fmt.Errorf("PINECONE_API_KEY is required")
+		}
+		if c.Pinecone.IndexName == "" {
+			return fmt.Errorf("PINECONE_INDEX_NAME is required")
+		}
+		switch c.Pinecone.Metric {
+		case "cosine", "dotproduct", "euclidean":
+		default:
+			return fmt.Errorf("unknown PINECONE_METRIC %q (available: cosine, dotproduct, euclidean)", c.Pinecone.Metric)
+		}
+		switch c.Pinecone.Cloud {
+		case "aws", "gcp", "azure":
+		default:
+			return fmt.Errorf("unknown PINECONE_CLOUD %q (available: aws, gcp, azure)", c.Pinecone.Cloud)
+		}
+		if c.Pinecone.Region == "" {
+			return fmt.Errorf("PINECONE_REGION is required")
+		}
+	case "qdrant":
+		if c.VectorStore.Qdrant.URL == "" {
+			return fmt.Errorf("QDRANT_URL is required when VECTOR_BACKEND=qdrant")
+		}
+		if c.VectorStore.Qdrant.Collection == "" {
+			return fmt.Errorf("QDRANT_COLLECTION is required when VECTOR_BACKEND=qdrant")
+		}
+	case "weaviate":
+		if c.VectorStore.Weaviate.URL == "" {
+			return fmt.Errorf("WEAVIATE_URL is required when VECTOR_BACKEND=weaviate")
+		}
+		if c.VectorStore.Weaviate.Class == "" {
+			return fmt.Errorf("WEAVIATE_CLASS is required when VECTOR_BACKEND=weaviate")
+		}
+	case "memory":
+	default:
+		return fmt.Errorf("unknown VECTOR_BACKEND %q (available: pinecone, qdrant, weaviate, memory)", c.VectorStore.Backend)
+	}
+	if dim, name := c.VectorDimension(); dim <= 0 {
+		return fmt.Errorf("%s must be positive", name)
+	}
+	if c.VectorStore.RefreshDays < 0 {
+		return fmt.Errorf("VECTOR_REFRESH_DAYS must not be negative")
+	}
+	if c.VectorStore.HybridAlpha < 0 || c.VectorStore.HybridAlpha > 1 {
+		return fmt.Errorf("HYBRID_ALPHA must be between 0 and 1")
+	}
+	if c.VectorStore.SparseVectors {
+		if c.VectorStore.Backend != "pinecone" {
+			return fmt.Errorf("SPARSE_VECTORS is only supported with VECTOR_BACKEND=pinecone")
+		}
+		if c.Pinecone.Metric != "dotproduct" {
+			return fmt.Errorf("SPARSE_VECTORS requires PINECONE_METRIC=dotproduct")
+		}
+	}
+	return nil
+}
+
+// VectorDimension returns the vector size of the selected backend and the
+// variable that sets it, or 0 for an unknown backend
+func (c *Config) VectorDimension() (int, string) {
+	switch c.VectorStore.Backend {
+	case "pinecone":
+		return c.Pinecone.Dimension, "PINECONE_DIMENSION"
+	case "qdrant":
+		return c.VectorStore.Qdrant.Dimension, "QDRANT_DIMENSION"
+	case "weaviate":
+		return c.VectorStore.Weaviate.Dimension, "WEAVIATE_DIMENSION"
+	case "memory":
+		return c.VectorStore.Memory.Dimension, "MEMORY_DIMENSION"
+	}
+	return 0, ""
+}
+
+// ValidateForMetadata validates the metadata database selection
+func (c *Config) ValidateForMetadata() error {
+	switch c.Database.Driver {
+	case "sqlite":
+		if c.Database.MetadataDBPath == "" {
+			return fmt.Errorf("METADATA_DB_PATH is required when METADATA_DB_DRIVER=sqlite")
+		}
+	case "mysql":
+		if c.Database.DSN == "" {
+			return fmt.Errorf("METADATA_DB_DSN is required when METADATA_DB_DRIVER=mysql")
+		}
+	case "redis":
+		if c.Database.RedisURL == "" {
+			return fmt.Errorf("METADATA_REDIS_URL is required when METADATA_DB_DRIVER=redis")
+		}
+		if c.Database.AuditRequired {
+			return fmt.Errorf("METADATA_DB_DRIVER=redis keeps no audit log, which METADATA_AUDIT_REQUIRED needs")
+		}
+	default:
+		return fmt.Errorf("unknown METADATA_DB_DRIVER %q (available: sqlite, mysql, redis)", c.Database.Driver)
+	}
+	callers := make(map[string]string, len(c.Database.APIKeys))
+	for id, key := range c.Database.APIKeys {
+		if id == "" || key == "" {
+			return fmt.Errorf("METADATA_API_KEYS entries must be id=key pairs")
+		}
+		if other, ok := callers[key]; ok {
+			return fmt.Errorf("METADATA_API_KEYS gives %s and %s the same key", other, id)
+		}
+		callers[key] = id
 	}
-	if c.Pinecone.IndexName == "" {
-		return fmt.Errorf("PINECONE_INDEX_NAME is required")
+	if c.Database.JobRetentionDays < 0 || c.Database.TombstoneRetentionDays < 0 || c.Database.AuditRetentionDays < 0 {
+		return fmt.Errorf("METADATA_JOB_RETENTION_DAYS, METADATA_TOMBSTONE_RETENTION_DAYS, and METADATA_AUDIT_RETENTION_DAYS must not be negative")
+	}
+	if c.Database.PurgeInterval < 0 {
+		return fmt.Errorf("METADATA_PURGE_INTERVAL must not be negative")
+	}
+	return nil
+}
+
+// ValidateForNotification validates the notification channel selection. A
+// channel without a webhook URL is skipped rather than rejected, so Slack
+// can stay selected by default without being set up.
+func (c *Config) ValidateForNotification() error {
+	for _, channel := range c.Notifications.Channels {
+		switch channel {
+		case "slack", "teams":
+		case "webhook":
+			// Receivers can't tell an unsigned payload from a forged one
+			if len(c.Notifications.WebhookURLs) > 0 && c.Notifications.WebhookSecret == "" {
+				return fmt.Errorf("WEBHOOK_SECRET is required when NOTIFICATION_CHANNELS includes webhook")
+			}
+		default:
+			return fmt.Errorf("unknown notification channel %q in NOTIFICATION_CHANNELS (available: slack, teams, webhook)", channel)
+		}
 	}
 	return nil
 }
@@ -243,6 +747,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolVal, err := strconv.ParseBool(value); err == nil {
@@ -253,7 +766,6 @@ func getEnvBool(key string, defaultValue bool) bool {
 }
 
 // getEnvDuration retrieves a duration from environment variable.
-// nolint:unused // Reserved for future use
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -263,6 +775,37 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+// parseKeyValueCSV parses "key=value,key2=value2" into a map
+func parseKeyValueCSV(value string) map[string]string {
+	result := make(map[string]string)
+	for _, part := range parseCSV(value) {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		result[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return result
+}
+
+// parseAzureDeployments parses comma-separated endpoint|deployment|api-key
+// entries; entries without a key use defaultKey
+func parseAzureDeployments(value, defaultKey string) []AzureDeployment {
+	var deployments []AzureDeployment
+	for _, entry := range parseCSV(value) {
+		fields := strings.Split(entry, "|")
+		d := AzureDeployment{Endpoint: strings.TrimSpace(fields[0]), APIKey: defaultKey}
+		if len(fields) > 1 {
+			d.Deployment = strings.TrimSpace(fields[1])
+		}
+		if len(fields) > 2 && strings.TrimSpace(fields[2]) != "" {
+			d.APIKey = strings.TrimSpace(fields[2])
+		}
+		deployments = append(deployments, d)
+	}
+	return deployments
+}
+
 func parseCSV(value string) []string {
 	if value == "" {
 		return []string{}
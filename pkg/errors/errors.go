@@ -10,6 +10,7 @@ type ErrorType string
 const (
 	ErrTypeValidation   ErrorType = "VALIDATION_ERROR"
 	ErrTypeNotFound     ErrorType = "NOT_FOUND"
+	ErrTypeConflict     ErrorType = "CONFLICT"
 	ErrTypeUnauthorized ErrorType = "UNAUTHORIZED"
 	ErrTypeRateLimit    ErrorType = "RATE_LIMIT"
 	ErrTypeNetwork      ErrorType = "NETWORK_ERROR"
@@ -63,6 +64,14 @@ func NotFound(resource string) *AppError {
 	}
 }
 
+// Conflict creates an error for a write based on outdated data
+func Conflict(message string) *AppError {
+	return &AppError{
+		Type:    ErrTypeConflict,
+		Message: message,
+	}
+}
+
 // Unauthorized creates an unauthorized error
 func Unauthorized(message string) *AppError {
 	return &AppError{
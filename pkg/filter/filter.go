@@ -0,0 +1,110 @@
+package filter
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// Reasons a file is rejected by the filter rules
+const (
+	RejectExtension      = "extension"       // extension not in the allowed list
+	RejectExcludePattern = "exclude_pattern" // a path segment matches an exclude pattern
+	RejectDeleted        = "deleted"         // the change removes the file
+)
+
+// Rules decide which repository files are indexed
+type Rules struct {
+	AllowedExtensions []string `json:"allowed_extensions"` // path suffixes such as ".md" or ".d.ts"; empty allows all
+	ExcludePatterns   []string `json:"exclude_patterns"`   // path segments such as "node_modules" or "docs/drafts", globs allowed
+}
+
+// ForProject returns a project's rules, using defaults for each list the
+// project leaves empty; a nil project gets the defaults
+func ForProject(project *models.Project, defaults Rules) Rules {
+	rules := defaults
+	if project == nil {
+		return rules
+	}
+	if len(project.AllowedExtensions) > 0 {
+		rules.AllowedExtensions = project.AllowedExtensions
+	}
+	if len(project.ExcludePatterns) > 0 {
+		rules.ExcludePatterns = project.ExcludePatterns
+	}
+	return rules
+}
+
+// Decision explains whether a file passes the rules
+type Decision struct {
+	Reason string // empty when the file is accepted
+	Detail string // the extension, pattern, or change type that rejected it
+}
+
+// Accepted reports whether no rule rejected the file
+func (d Decision) Accepted() bool {
+	return d.Reason == ""
+}
+
+// Check applies the allowed extensions, exclude patterns, and change type to
+// a file, in that order, reporting the first rule that rejects it. A removed
+// file that passes the path rules is rejected as deleted, so callers that
+// clean up after removals can still tell it belonged to the index.
+func Check(file *models.FileChange, rules Rules) Decision {
+	if len(rules.AllowedExtensions) > 0 {
+		found := false
+		for _, ext := range rules.AllowedExtensions {
+			if MatchesExtension(file.FilePath, ext) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return Decision{Reason: RejectExtension, Detail: filepath.Ext(file.FilePath)}
+		}
+	}
+
+	for _, pattern := range rules.ExcludePatterns {
+		if MatchesPattern(file.FilePath, pattern) {
+			return Decision{Reason: RejectExcludePattern, Detail: pattern}
+		}
+	}
+
+	if file.ChangeType == "deleted" || file.ChangeType == "removed" {
+		return Decision{Reason: RejectDeleted, Detail: file.ChangeType}
+	}
+
+	return Decision{}
+}
+
+// MatchesExtension reports whether a path ends with ext, ignoring case
+func MatchesExtension(filePath, ext string) bool {
+	return ext != "" && strings.HasSuffix(strings.ToLower(filePath), strings.ToLower(ext))
+}
+
+// MatchesPattern reports whether consecutive segments of a slash-separated
+// path match the pattern's segments, each compared with path.Match. "dist"
+// excludes "dist/app.md" and "web/dist/app.md" but not "distribution.md".
+func MatchesPattern(filePath, pattern string) bool {
+	want := strings.Split(strings.Trim(pattern, "/"), "/")
+	if len(want) == 0 || want[0] == "" {
+		return false
+	}
+
+	segments := strings.Split(strings.Trim(filePath, "/"), "/")
+	for start := 0; start+len(want) <= len(segments); start++ {
+		matched := true
+		for i, w := range want {
+			if ok, err := path.Match(w, segments[start+i]); err != nil || !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
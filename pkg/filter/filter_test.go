@@ -0,0 +1,99 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestCheck(t *testing.T) {
+	rules := Rules{
+		AllowedExtensions: []string{".md", ".d.ts"},
+		ExcludePatterns:   []string{"node_modules", ".git", "dist", "docs/drafts", "*.min.md"},
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		changeType string
+		rules      Rules
+		wantReason string
+		wantDetail string
+	}{
+		{name: "allowed extension", path: "docs/guide.md", rules: rules},
+		{name: "extension ignores case", path: "README.MD", rules: rules},
+		{name: "multi-part extension", path: "types/index.d.ts", rules: rules},
+		{name: "other extension", path: "main.go", rules: rules, wantReason: RejectExtension, wantDetail: ".go"},
+		{name: "no allowed extensions allows all", path: "main.go", rules: Rules{}},
+		{name: "excluded segment at the root", path: "dist/guide.md", rules: rules, wantReason: RejectExcludePattern, wantDetail: "dist"},
+		{name: "excluded segment nested", path: "web/node_modules/pkg/README.md", rules: rules, wantReason: RejectExcludePattern, wantDetail: "node_modules"},
+		{name: "pattern inside a segment is not excluded", path: "docs/distribution.md", rules: rules},
+		{name: "dot pattern does not match a longer name", path: ".github/CONTRIBUTING.md", rules: rules},
+		{name: "multi-segment pattern", path: "site/docs/drafts/next.md", rules: rules, wantReason: RejectExcludePattern, wantDetail: "docs/drafts"},
+		{name: "multi-segment pattern needs both segments", path: "drafts/docs/next.md", rules: rules},
+		{name: "glob pattern", path: "assets/app.min.md", rules: rules, wantReason: RejectExcludePattern, wantDetail: "*.min.md"},
+		{name: "removed file", path: "docs/old.md", changeType: "removed", rules: rules, wantReason: RejectDeleted, wantDetail: "removed"},
+		{name: "path rules come before deletion", path: "dist/old.md", changeType: "deleted", rules: rules, wantReason: RejectExcludePattern, wantDetail: "dist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changeType := tt.changeType
+			if changeType == "" {
+				changeType = "added"
+			}
+
+			got := Check(&models.FileChange{FilePath: tt.path, ChangeType: changeType}, tt.rules)
+			if got.Reason != tt.wantReason || got.Detail != tt.wantDetail {
+				t.Errorf("Check(%q) = %+v, want reason %q detail %q", tt.path, got, tt.wantReason, tt.wantDetail)
+			}
+			if got.Accepted() != (tt.wantReason == "") {
+				t.Errorf("Accepted() = %v with reason %q", got.Accepted(), got.Reason)
+			}
+		})
+	}
+}
+
+func TestForProject(t *testing.T) {
+	defaults := Rules{AllowedExtensions: []string{".md"}, ExcludePatterns: []string{"dist"}}
+
+	tests := []struct {
+		name    string
+		project *models.Project
+		want    Rules
+	}{
+		{name: "no project", want: defaults},
+		{name: "project without rules", project: &models.Project{ID: "p"}, want: defaults},
+		{
+			name:    "project extensions",
+			project: &models.Project{AllowedExtensions: []string{".rst"}},
+			want:    Rules{AllowedExtensions: []string{".rst"}, ExcludePatterns: []string{"dist"}},
+		},
+		{
+			name:    "project patterns",
+			project: &models.Project{ExcludePatterns: []string{"vendor"}},
+			want:    Rules{AllowedExtensions: []string{".md"}, ExcludePatterns: []string{"vendor"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ForProject(tt.project, defaults)
+			if !equal(got.AllowedExtensions, tt.want.AllowedExtensions) || !equal(got.ExcludePatterns, tt.want.ExcludePatterns) {
+				t.Errorf("ForProject() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -19,6 +19,12 @@ type RepositoryClient interface {
 
 	// GetLatestCommitSHA gets the latest commit SHA for a repository
 	GetLatestCommitSHA(ctx context.Context, owner, repo, branch string) (string, error)
+
+	// GetRepository retrieves a single repository by owner and name
+	GetRepository(ctx context.Context, owner, name string) (*models.Repository, error)
+
+	// Health checks the connection health
+	Health(ctx context.Context) error
 }
 
 // DocumentProcessor defines the interface for document processing (SOLID: Single Responsibility)
@@ -53,8 +59,9 @@ type VectorStore interface {
 	// DeleteVectors removes vectors by IDs
 	DeleteVectors(ctx context.Context, ids []string, namespace string) error
 
-	// QueryVectors searches for similar vectors
-	QueryVectors(ctx context.Context, vector []float32, topK int, namespace string) ([]*models.Embedding, error)
+	// QueryVectors searches for similar vectors, restricted to those matching
+	// filter if it isn't empty
+	QueryVectors(ctx context.Context, vector []float32, topK int, namespace string, filter models.MetadataFilter) ([]*models.Embedding, error)
 
 	// DescribeIndex gets index statistics
 	DescribeIndex(ctx context.Context) (map[string]interface{}, error)
@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
 
 // Repository represents a GitHub repository
 type Repository struct {
@@ -23,6 +29,30 @@ type FileChange struct {
 	LastModified time.Time `json:"last_modified"`
 	ChangeType   string    `json:"change_type"` // added, modified, deleted
 	Size         int64     `json:"size"`
+	BlobSHA      string    `json:"blob_sha,omitempty"`
+	Branch       string    `json:"branch,omitempty"`   // branch the content was read from, if known
+	Source       string    `json:"source,omitempty"`   // empty for repository files, e.g. wiki
+	Encoding     string    `json:"encoding,omitempty"` // "base64" when Content holds binary data
+}
+
+// SetContent stores file content, base64-encoding it when it is not valid
+// UTF-8 so binary files (PDFs, ...) survive JSON transport intact
+func (f *FileChange) SetContent(content []byte) {
+	if utf8.Valid(content) {
+		f.Content = string(content)
+		f.Encoding = ""
+		return
+	}
+	f.Content = base64.StdEncoding.EncodeToString(content)
+	f.Encoding = "base64"
+}
+
+// RawContent returns the file content as bytes, decoding it if necessary
+func (f *FileChange) RawContent() ([]byte, error) {
+	if f.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(f.Content)
+	}
+	return []byte(f.Content), nil
 }
 
 // Document represents a processed document chunk
@@ -46,6 +76,50 @@ type Embedding struct {
 	Repository string            `json:"repository"`
 	FilePath   string            `json:"file_path"`
 	Namespace  string            `json:"namespace"`
+	Sparse     *SparseVector     `json:"sparse,omitempty"` // term weights for hybrid search
+}
+
+// MetadataText is the vector metadata key holding the chunk text, stored
+// with STORE_CHUNK_TEXT so vectors can be embedded again without a re-sync
+const MetadataText = "text"
+
+// MetadataIndexedAt is the vector metadata key holding when the vector was
+// last upserted, in RFC 3339 UTC
+const MetadataIndexedAt = "indexed_at"
+
+// SparseVector holds the weights of the terms in a text, each term hashed to
+// an index, for keyword matching alongside the dense vector
+type SparseVector struct {
+	Indices []uint32  `json:"indices"`
+	Values  []float32 `json:"values"`
+}
+
+// MetadataFilter restricts vectors to those whose metadata have, for every
+// key, one of the listed values, e.g. repository, file_ext, language, or
+// branch. In JSON a single value may be given as a plain string.
+type MetadataFilter map[string][]string
+
+// UnmarshalJSON accepts a string or a list of strings for each key
+func (f *MetadataFilter) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	filter := make(MetadataFilter, len(raw))
+	for k, v := range raw {
+		var one string
+		if err := json.Unmarshal(v, &one); err == nil {
+			filter[k] = []string{one}
+			continue
+		}
+		var many []string
+		if err := json.Unmarshal(v, &many); err != nil || len(many) == 0 {
+			return fmt.Errorf("filter %q must be a string or a non-empty list of strings", k)
+		}
+		filter[k] = many
+	}
+	*f = filter
+	return nil
 }
 
 // SyncMetadata tracks synchronization state
@@ -58,20 +132,70 @@ type SyncMetadata struct {
 	LastSyncedAt   time.Time `json:"last_synced_at"`
 	EmbeddingCount int       `json:"embedding_count"`
 	Status         string    `json:"status"`
+	BlobSHA        string    `json:"blob_sha"`
+	EmbeddingModel string    `json:"embedding_model,omitempty"` // provider/model[@version] of the file's vectors
+	ChunkIDs       []string  `json:"chunk_ids,omitempty"`       // vector IDs of the file's chunks, to delete them once stale
+	Version        int64     `json:"version,omitempty"`         // bumped on every save; a save giving it fails if the record changed since
+	Create         bool      `json:"create,omitempty"`          // a save giving it fails if the record exists
+}
+
+// RepoSyncState is the commit a repository was last synced at, which the
+// next incremental sync diffs against
+type RepoSyncState struct {
+	ProjectID     string    `json:"project_id"`
+	Repository    string    `json:"repository"`
+	LastCommitSHA string    `json:"last_commit_sha"`
+	LastSyncedAt  time.Time `json:"last_synced_at"`
+	Version       int64     `json:"version,omitempty"` // as on SyncMetadata
+	Create        bool      `json:"create,omitempty"`  // as on SyncMetadata
+}
+
+// Sync job states
+const (
+	SyncJobPending   = "pending"
+	SyncJobRunning   = "running"
+	SyncJobCompleted = "completed"
+	SyncJobFailed    = "failed"
+	SyncJobCancelled = "cancelled"
+)
+
+// SyncJob is a sync of a project queued, running, or run by the
+// orchestrator, as kept by the metadata service. Checkpoint is whatever the
+// orchestrator needs to resume an interrupted run; the metadata service
+// stores it as is.
+type SyncJob struct {
+	ID         string          `json:"id"`
+	ProjectID  string          `json:"project_id"`
+	Status     string          `json:"status"`
+	Priority   int             `json:"priority"` // higher runs first
+	Checkpoint json.RawMessage `json:"checkpoint,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// SyncBatch is file records and the repository sync states they bring up to
+// date, which the metadata service saves together or not at all
+type SyncBatch struct {
+	Records    []*SyncMetadata  `json:"records"`
+	RepoStates []*RepoSyncState `json:"repo_states,omitempty"`
 }
 
 // Project represents a multi-project configuration
 type Project struct {
-	ID                string    `json:"id"`
-	Name              string    `json:"name"`
-	Organization      string    `json:"organization"`
-	FilterKeyword     string    `json:"filter_keyword"`
-	Namespace         string    `json:"namespace"`
-	Enabled           bool      `json:"enabled"`
-	AllowedExtensions []string  `json:"allowed_extensions"`
-	ExcludePatterns   []string  `json:"exclude_patterns"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                string            `json:"id"`
+	Name              string            `json:"name"`
+	Organization      string            `json:"organization"`
+	FilterKeyword     string            `json:"filter_keyword"`
+	Namespace         string            `json:"namespace"`
+	Enabled           bool              `json:"enabled"`
+	AllowedExtensions []string          `json:"allowed_extensions"`
+	ExcludePatterns   []string          `json:"exclude_patterns"`
+	ChunkStrategies   map[string]string `json:"chunk_strategies,omitempty"` // file extension -> chunking strategy
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
 }
 
 // SyncResult represents the outcome of a sync operation
@@ -85,6 +209,7 @@ type SyncResult struct {
 	FilesChanged        int           `json:"files_changed"`
 	FilesProcessed      int           `json:"files_processed"`
 	ChunksCreated       int           `json:"chunks_created"`
+	ChunksUnchanged     int           `json:"chunks_unchanged"` // already stored, not re-embedded
 	EmbeddingsGenerated int           `json:"embeddings_generated"`
 	VectorsUpserted     int           `json:"vectors_upserted"`
 	VectorsDeleted      int           `json:"vectors_deleted"`
@@ -93,6 +218,60 @@ type SyncResult struct {
 	Success             bool          `json:"success"`
 }
 
+// IndexStats counts the vectors in the vector store, per namespace. The
+// default namespace is "".
+type IndexStats struct {
+	Backend      string           `json:"backend"`
+	Dimension    int              `json:"dimension"`
+	TotalVectors int64            `json:"total_vectors"`
+	Namespaces   map[string]int64 `json:"namespaces"`
+	Fullness     *float32         `json:"fullness,omitempty"` // share of capacity used, Pinecone only
+}
+
+// SyncStats summarizes a project's sync records, in total and per repository
+type SyncStats struct {
+	ProjectID    string             `json:"project_id"`
+	Files        int64              `json:"files"`
+	Embeddings   int64              `json:"embeddings"`
+	LastSyncedAt *time.Time         `json:"last_synced_at,omitempty"`
+	Statuses     map[string]int64   `json:"statuses"` // files per status
+	Repositories []*RepositoryStats `json:"repositories"`
+}
+
+// RepositoryStats summarizes the sync records of one repository
+type RepositoryStats struct {
+	Repository   string           `json:"repository"`
+	Files        int64            `json:"files"`
+	Embeddings   int64            `json:"embeddings"`
+	LastSyncedAt time.Time        `json:"last_synced_at"`
+	Statuses     map[string]int64 `json:"statuses"` // files per status
+}
+
+// RateUsage reports API calls consumed per repository since Since
+type RateUsage struct {
+	Repositories map[string]int `json:"repositories"`
+	Total        int            `json:"total"`
+	Since        time.Time      `json:"since"`
+	Remaining    int            `json:"remaining"` // -1 when unknown
+	Limit        int            `json:"limit,omitempty"`
+	ResetAt      time.Time      `json:"reset_at,omitempty"`
+}
+
+// Delta returns the calls made since an earlier snapshot of the same counters,
+// keeping the quota fields of the newer snapshot
+func (u *RateUsage) Delta(earlier *RateUsage) *RateUsage {
+	delta := *u
+	delta.Repositories = make(map[string]int, len(u.Repositories))
+	delta.Total = 0
+	for repo, n := range u.Repositories {
+		if n -= earlier.Repositories[repo]; n > 0 {
+			delta.Repositories[repo] = n
+			delta.Total += n
+		}
+	}
+	return &delta
+}
+
 // NotificationPayload represents data for notifications
 type NotificationPayload struct {
 	Type      string      `json:"type"` // success, error, warning
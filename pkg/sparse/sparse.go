@@ -0,0 +1,109 @@
+package sparse
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// BM25 parameters: k1 saturates repeated terms, b normalizes for length
+// against a typical chunk
+const (
+	k1            = 1.2
+	b             = 0.75
+	averageTokens = 200
+)
+
+// Encode returns the document-side vector of text: each term weighted by its
+// saturated, length-normalized frequency. Nil if text has no terms.
+func Encode(text string) *models.SparseVector {
+	tokens := Tokenize(text)
+	counts := make(map[string]int)
+	for _, token := range tokens {
+		counts[token]++
+	}
+
+	norm := k1 * (1 - b + b*float64(len(tokens))/averageTokens)
+	weights := make(map[uint32]float32, len(counts))
+	for term, tf := range counts {
+		weights[index(term)] += float32(float64(tf) * (k1 + 1) / (float64(tf) + norm))
+	}
+	return build(weights)
+}
+
+// EncodeQuery returns the query-side vector of text, weighting each distinct
+// term 1 so documents score by their own term weights. Nil if text has no
+// terms.
+func EncodeQuery(text string) *models.SparseVector {
+	weights := make(map[uint32]float32)
+	for _, token := range Tokenize(text) {
+		weights[index(token)] = 1
+	}
+	return build(weights)
+}
+
+// Tokenize lowercases text and splits it into runs of letters, digits, and
+// underscores, so "ERR_CONN_RESET" and "E1001" stay whole. Single characters
+// are dropped.
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	tokens := fields[:0]
+	for _, field := range fields {
+		if len([]rune(field)) > 1 {
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+// Scale weights a dense and a sparse query for hybrid search: alpha 1 is
+// purely dense, 0 purely sparse. Inputs are left unchanged.
+func Scale(dense []float32, sv *models.SparseVector, alpha float64) ([]float32, *models.SparseVector) {
+	scaledDense := make([]float32, len(dense))
+	for i, v := range dense {
+		scaledDense[i] = v * float32(alpha)
+	}
+	if sv == nil {
+		return scaledDense, nil
+	}
+
+	scaledSparse := &models.SparseVector{
+		Indices: append([]uint32(nil), sv.Indices...),
+		Values:  make([]float32, len(sv.Values)),
+	}
+	for i, v := range sv.Values {
+		scaledSparse.Values[i] = v * float32(1-alpha)
+	}
+	return scaledDense, scaledSparse
+}
+
+// index hashes a term to its dimension
+func index(term string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(term))
+	return h.Sum32()
+}
+
+// build turns weights into a vector sorted by index
+func build(weights map[uint32]float32) *models.SparseVector {
+	if len(weights) == 0 {
+		return nil
+	}
+	sv := &models.SparseVector{
+		Indices: make([]uint32, 0, len(weights)),
+		Values:  make([]float32, 0, len(weights)),
+	}
+	for i := range weights {
+		sv.Indices = append(sv.Indices, i)
+	}
+	sort.Slice(sv.Indices, func(a, c int) bool { return sv.Indices[a] < sv.Indices[c] })
+	for _, i := range sv.Indices {
+		sv.Values = append(sv.Values, weights[i])
+	}
+	return sv
+}
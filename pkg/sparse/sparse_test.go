@@ -0,0 +1,99 @@
+package sparse
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "words are lowercased", text: "Sync the Index", want: []string{"sync", "the", "index"}},
+		{name: "identifiers stay whole", text: "got ERR_CONN_RESET (E1001)", want: []string{"got", "err_conn_reset", "e1001"}},
+		{name: "punctuation splits", text: "pkg/config.Load()", want: []string{"pkg", "config", "load"}},
+		{name: "single characters are dropped", text: "a b cd", want: []string{"cd"}},
+		{name: "no terms", text: " -- ", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Tokenize(tt.text)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	weight := func(sv *models.SparseVector, term string) float32 {
+		for i, idx := range sv.Indices {
+			if idx == index(term) {
+				return sv.Values[i]
+			}
+		}
+		return 0
+	}
+
+	if Encode("  ") != nil || EncodeQuery("?") != nil {
+		t.Fatal("text without terms should encode to nil")
+	}
+
+	sv := Encode("retry retry retry timeout")
+	if len(sv.Indices) != 2 || len(sv.Values) != 2 {
+		t.Fatalf("Encode() = %+v, want 2 terms", sv)
+	}
+	if sv.Indices[0] >= sv.Indices[1] {
+		t.Errorf("indices %v are not sorted", sv.Indices)
+	}
+	retry, timeout := weight(sv, "retry"), weight(sv, "timeout")
+	if retry <= timeout {
+		t.Errorf("repeated term weight %v should exceed single term weight %v", retry, timeout)
+	}
+	if retry >= 3*timeout {
+		t.Errorf("repeated term weight %v should saturate below %v", retry, 3*timeout)
+	}
+
+	query := EncodeQuery("Retry retry TIMEOUT")
+	if !reflect.DeepEqual(query.Indices, sv.Indices) || !reflect.DeepEqual(query.Values, []float32{1, 1}) {
+		t.Errorf("EncodeQuery() = %+v, want the same terms weighted 1", query)
+	}
+}
+
+func TestScale(t *testing.T) {
+	sv := &models.SparseVector{Indices: []uint32{3, 7}, Values: []float32{1, 2}}
+
+	tests := []struct {
+		name       string
+		alpha      float64
+		wantDense  []float32
+		wantSparse []float32
+	}{
+		{name: "balanced", alpha: 0.5, wantDense: []float32{0.5, -1}, wantSparse: []float32{0.5, 1}},
+		{name: "dense only", alpha: 1, wantDense: []float32{1, -2}, wantSparse: []float32{0, 0}},
+		{name: "sparse only", alpha: 0, wantDense: []float32{0, 0}, wantSparse: []float32{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dense, scaled := Scale([]float32{1, -2}, sv, tt.alpha)
+			if !reflect.DeepEqual(dense, tt.wantDense) {
+				t.Errorf("dense = %v, want %v", dense, tt.wantDense)
+			}
+			if !reflect.DeepEqual(scaled.Values, tt.wantSparse) || !reflect.DeepEqual(scaled.Indices, sv.Indices) {
+				t.Errorf("sparse = %+v, want values %v", scaled, tt.wantSparse)
+			}
+			if sv.Values[0] != 1 || sv.Values[1] != 2 {
+				t.Errorf("input sparse vector was modified: %+v", sv)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v4.25.3
+// source: vectorpb/vector.proto
+
+package vectorpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	VectorStorageService_Upsert_FullMethodName = "/reposync.vector.v1.VectorStorageService/Upsert"
+	VectorStorageService_Query_FullMethodName  = "/reposync.vector.v1.VectorStorageService/Query"
+	VectorStorageService_Delete_FullMethodName = "/reposync.vector.v1.VectorStorageService/Delete"
+)
+
+// VectorStorageServiceClient is the client API for VectorStorageService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// VectorStorageService upserts, queries, and deletes vectors like the HTTP
+// /upsert, /query, and /delete endpoints, with vectors sent as packed floats
+// instead of JSON numbers
+type VectorStorageServiceClient interface {
+	// Upsert stores the embeddings, skipping unchanged ones like /upsert
+	Upsert(ctx context.Context, in *UpsertRequest, opts ...grpc.CallOption) (*UpsertResponse, error)
+	// Query returns the stored vectors most similar to a vector or text
+	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error)
+	// Delete removes vectors by ID, by metadata filter, or every vector in the
+	// namespace
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+}
+
+type vectorStorageServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVectorStorageServiceClient(cc grpc.ClientConnInterface) VectorStorageServiceClient {
+	return &vectorStorageServiceClient{cc}
+}
+
+func (c *vectorStorageServiceClient) Upsert(ctx context.Context, in *UpsertRequest, opts ...grpc.CallOption) (*UpsertResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpsertResponse)
+	err := c.cc.Invoke(ctx, VectorStorageService_Upsert_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorStorageServiceClient) Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryResponse)
+	err := c.cc.Invoke(ctx, VectorStorageService_Query_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vectorStorageServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, VectorStorageService_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VectorStorageServiceServer is the server API for VectorStorageService service.
+// All implementations must embed UnimplementedVectorStorageServiceServer
+// for forward compatibility.
+//
+// VectorStorageService upserts, queries, and deletes vectors like the HTTP
+// /upsert, /query, and /delete endpoints, with vectors sent as packed floats
+// instead of JSON numbers
+type VectorStorageServiceServer interface {
+	// Upsert stores the embeddings, skipping unchanged ones like /upsert
+	Upsert(context.Context, *UpsertRequest) (*UpsertResponse, error)
+	// Query returns the stored vectors most similar to a vector or text
+	Query(context.Context, *QueryRequest) (*QueryResponse, error)
+	// Delete removes vectors by ID, by metadata filter, or every vector in the
+	// namespace
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	mustEmbedUnimplementedVectorStorageServiceServer()
+}
+
+// UnimplementedVectorStorageServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedVectorStorageServiceServer struct{}
+
+func (UnimplementedVectorStorageServiceServer) Upsert(context.Context, *UpsertRequest) (*UpsertResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Upsert not implemented")
+}
+func (UnimplementedVectorStorageServiceServer) Query(context.Context, *QueryRequest) (*QueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Query not implemented")
+}
+func (UnimplementedVectorStorageServiceServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedVectorStorageServiceServer) mustEmbedUnimplementedVectorStorageServiceServer() {}
+func (UnimplementedVectorStorageServiceServer) testEmbeddedByValue()                              {}
+
+// UnsafeVectorStorageServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VectorStorageServiceServer will
+// result in compilation errors.
+type UnsafeVectorStorageServiceServer interface {
+	mustEmbedUnimplementedVectorStorageServiceServer()
+}
+
+func RegisterVectorStorageServiceServer(s grpc.ServiceRegistrar, srv VectorStorageServiceServer) {
+	// If the following call pancis, it indicates UnimplementedVectorStorageServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&VectorStorageService_ServiceDesc, srv)
+}
+
+func _VectorStorageService_Upsert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpsertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorStorageServiceServer).Upsert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorStorageService_Upsert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorStorageServiceServer).Upsert(ctx, req.(*UpsertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorStorageService_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorStorageServiceServer).Query(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorStorageService_Query_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorStorageServiceServer).Query(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VectorStorageService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VectorStorageServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VectorStorageService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VectorStorageServiceServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// VectorStorageService_ServiceDesc is the grpc.ServiceDesc for VectorStorageService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VectorStorageService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "reposync.vector.v1.VectorStorageService",
+	HandlerType: (*VectorStorageServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Upsert",
+			Handler:    _VectorStorageService_Upsert_Handler,
+		},
+		{
+			MethodName: "Query",
+			Handler:    _VectorStorageService_Query_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _VectorStorageService_Delete_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "vectorpb/vector.proto",
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	adocTitleRe      = regexp.MustCompile(`^(={1,6}|#{1,6})\s+(\S.*)$`)
+	adocAttrEntryRe  = regexp.MustCompile(`^:(!?[A-Za-z0-9_][A-Za-z0-9_-]*!?):\s*(.*)$`)
+	adocAttrRefRe    = regexp.MustCompile(`\{([A-Za-z0-9_][A-Za-z0-9_-]*)\}`)
+	adocBlockAttrRe  = regexp.MustCompile(`^\[([^\]]*)\]\s*$`)
+	adocBlockTitleRe = regexp.MustCompile(`^\.([^.\s].*)$`)
+	adocMacroLineRe  = regexp.MustCompile(`^(include|image|toc|video|audio)::.*\[.*\]\s*$`)
+	adocAdmonitionRe = regexp.MustCompile(`^(NOTE|TIP|IMPORTANT|WARNING|CAUTION):\s+(.*)$`)
+	adocXrefRe       = regexp.MustCompile(`(?:xref|link|mailto):[^\s\[]+\[([^\]]*)\]`)
+	adocURLRe        = regexp.MustCompile(`(https?://[^\s\[]+)\[([^\]]*)\]`)
+	adocCrossRefRe   = regexp.MustCompile(`<<([^,>]+)(?:,\s*([^>]+))?>>`)
+	adocAnchorRe     = regexp.MustCompile(`\[\[[^\]]*\]\]|\[#[^\]]*\]`)
+)
+
+func isAsciiDoc(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".adoc", ".asciidoc", ".asc":
+		return true
+	}
+	return false
+}
+
+// extractAsciiDoc converts AsciiDoc into Markdown so it can be chunked along
+// its sections: titles become headings, listing blocks become fenced code,
+// admonitions and block titles become labelled paragraphs, attribute
+// references are substituted, and comments, includes, and images are dropped.
+func extractAsciiDoc(src string) string {
+	lines := strings.Split(cleanPreformatted(src), "\n")
+	attrs := map[string]string{}
+	var out []string
+
+	var blockAttr string // pending [attributes] line for the next block
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		// Delimited blocks run until the matching delimiter
+		if delim, ok := adocDelimiter(trimmed); ok {
+			body, next := adocBlock(lines, i+1, trimmed)
+			out = append(out, adocRenderBlock(delim, blockAttr, body, attrs)...)
+			blockAttr = ""
+			i = next
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "//"):
+			// Line comment
+		case adocAttrEntryRe.MatchString(line):
+			m := adocAttrEntryRe.FindStringSubmatch(line)
+			attrs[m[1]] = m[2]
+		case adocMacroLineRe.MatchString(trimmed):
+			// include::, image::, toc:: produce no readable text
+		case adocBlockAttrRe.MatchString(trimmed) && !strings.HasPrefix(trimmed, "[["):
+			blockAttr = strings.Trim(trimmed, "[]")
+		case adocTitleRe.MatchString(line):
+			m := adocTitleRe.FindStringSubmatch(line)
+			out = append(out, "", heading(len(m[1]), adocInline(m[2], attrs)), "")
+		case adocBlockTitleRe.MatchString(line):
+			out = append(out, "**"+adocInline(adocBlockTitleRe.FindStringSubmatch(line)[1], attrs)+"**")
+		case adocAdmonitionRe.MatchString(line):
+			m := adocAdmonitionRe.FindStringSubmatch(line)
+			out = append(out, fmt.Sprintf("**%s:** %s", adocLabel(m[1]), adocInline(m[2], attrs)))
+		case trimmed == "+":
+			// List continuation marker
+		default:
+			if trimmed == "" {
+				blockAttr = ""
+			}
+			out = append(out, adocInline(line, attrs))
+		}
+	}
+
+	return strings.TrimSpace(collapseBlankLines(strings.Join(out, "\n")))
+}
+
+// adocDelimiter reports whether a line opens a delimited block, returning its kind
+func adocDelimiter(line string) (string, bool) {
+	if line == "--" || line == "|===" || line == ",===" || line == ":===" {
+		return line, true
+	}
+	if len(line) < 4 || strings.Count(line, line[:1]) != len(line) {
+		return "", false
+	}
+	switch line[0] {
+	case '-', '.', '=', '*', '_', '/', '+':
+		return line[:1], true
+	}
+	return "", false
+}
+
+// adocBlock returns the lines up to the closing delimiter and the index of that delimiter
+func adocBlock(lines []string, start int, delim string) ([]string, int) {
+	for i := start; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == delim {
+			return lines[start:i], i
+		}
+	}
+	return lines[start:], len(lines)
+}
+
+// adocRenderBlock renders a delimited block according to its kind and attributes
+func adocRenderBlock(kind, attr string, body []string, attrs map[string]string) []string {
+	style := strings.SplitN(attr, ",", 2)[0]
+
+	switch kind {
+	case "/", "+":
+		// Comment and passthrough blocks
+		return nil
+	case "-", ".":
+		lang := ""
+		if parts := strings.Split(attr, ","); len(parts) > 1 && (parts[0] == "source" || parts[0] == "") {
+			lang = strings.TrimSpace(parts[1])
+		}
+		out := append([]string{"", "```" + lang}, body...)
+		return append(out, "```", "")
+	case "|===", ",===", ":===":
+		return append(append([]string{""}, adocTable(kind, body, attrs)...), "")
+	}
+
+	content := make([]string, 0, len(body))
+	for _, l := range body {
+		content = append(content, adocInline(l, attrs))
+	}
+
+	switch style {
+	case "NOTE", "TIP", "IMPORTANT", "WARNING", "CAUTION":
+		text := strings.TrimSpace(strings.Join(content, "\n"))
+		return []string{"", fmt.Sprintf("**%s:** %s", adocLabel(style), text), ""}
+	case "quote", "verse":
+		for i, l := range content {
+			content[i] = "> " + l
+		}
+	}
+	return append(append([]string{""}, content...), "")
+}
+
+// adocTable renders table rows as Markdown table lines so they stay together
+func adocTable(kind string, body []string, attrs map[string]string) []string {
+	sep := "|"
+	switch kind {
+	case ",===":
+		sep = ","
+	case ":===":
+		sep = ":"
+	}
+
+	var rows []string
+	for _, l := range body {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		cells := strings.Split(strings.TrimPrefix(l, sep), sep)
+		for i, c := range cells {
+			cells[i] = adocInline(strings.TrimSpace(c), attrs)
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+	}
+	return rows
+}
+
+// adocInline substitutes attributes and reduces inline macros to their text
+func adocInline(line string, attrs map[string]string) string {
+	line = adocAttrRefRe.ReplaceAllStringFunc(line, func(ref string) string {
+		if v, ok := attrs[ref[1:len(ref)-1]]; ok {
+			return v
+		}
+		return ref
+	})
+	line = adocAnchorRe.ReplaceAllString(line, "")
+	line = adocXrefRe.ReplaceAllString(line, "$1")
+	line = adocURLRe.ReplaceAllStringFunc(line, func(link string) string {
+		m := adocURLRe.FindStringSubmatch(link)
+		if m[2] == "" {
+			return m[1]
+		}
+		return m[2]
+	})
+	line = adocCrossRefRe.ReplaceAllStringFunc(line, func(ref string) string {
+		m := adocCrossRefRe.FindStringSubmatch(ref)
+		if m[2] != "" {
+			return m[2]
+		}
+		return m[1]
+	})
+	return line
+}
+
+// adocLabel turns an admonition style like WARNING into "Warning"
+func adocLabel(style string) string {
+	return style[:1] + strings.ToLower(style[1:])
+}
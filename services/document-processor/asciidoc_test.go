@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestExtractAsciiDoc(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "titles and attribute references",
+			src:  ":product: RepoSync\n\n= {product} Guide\n\n== Install\n\nInstall {product} and {unknown}.",
+			want: "# RepoSync Guide\n\n## Install\n\nInstall RepoSync and {unknown}.",
+		},
+		{
+			name: "listing blocks become fenced code",
+			src:  "[source,go]\n----\nfunc main() {}\n----\n\n....\nliteral\n....",
+			want: "```go\nfunc main() {}\n```\n\n```\nliteral\n```",
+		},
+		{
+			name: "admonitions and block titles",
+			src:  "NOTE: Back up first.\n\n.Steps\n[WARNING]\n====\nThis deletes data.\n====",
+			want: "**Note:** Back up first.\n\n**Steps**\n\n**Warning:** This deletes data.",
+		},
+		{
+			name: "comments, includes, and images are dropped",
+			src:  "Intro.\n// a comment\ninclude::chapter.adoc[]\nimage::logo.png[Logo]\n\n////\nhidden\n////\n\nOutro.",
+			want: "Intro.\n\nOutro.",
+		},
+		{
+			name: "tables and quotes",
+			src:  "|===\n|Plan |Quota\n|Free |10\n|===\n\n[quote]\n____\nShip it.\n____",
+			want: "| Plan | Quota |\n| Free | 10 |\n\n> Ship it.",
+		},
+		{
+			name: "links and cross references keep their text",
+			src:  "See https://example.com[the site], xref:install.adoc[Install], <<setup,Setup>>, and <<faq>>.[[anchor]]",
+			want: "See the site, Install, Setup, and faq.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractAsciiDoc(tt.src); got != tt.want {
+				t.Errorf("extractAsciiDoc() = %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+
+	for ext, want := range map[string]bool{".adoc": true, ".ASCIIDOC": true, ".asc": true, ".md": false} {
+		if got := isAsciiDoc(ext); got != want {
+			t.Errorf("isAsciiDoc(%q) = %v, want %v", ext, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Charsets reported in chunk metadata when content had to be converted
+const (
+	charsetUTF8        = "utf-8"
+	charsetUTF16LE     = "utf-16le"
+	charsetUTF16BE     = "utf-16be"
+	charsetWindows1252 = "windows-1252"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Bytes inspected when guessing an encoding without a BOM
+const charsetSniffLen = 4096
+
+// decodeText converts file content to UTF-8 and strips any byte order mark.
+// UTF-16 is recognised by its BOM or by the NUL bytes ASCII text leaves in
+// every other position; bytes that are not valid UTF-8 are read as
+// Windows-1252, a superset of Latin-1. It returns the text and the charset
+// it was decoded from.
+func decodeText(raw []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(raw, utf8BOM):
+		return string(raw[len(utf8BOM):]), charsetUTF8
+	case bytes.HasPrefix(raw, []byte{0xFF, 0xFE}):
+		return decodeWith(unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), raw, charsetUTF16LE)
+	case bytes.HasPrefix(raw, []byte{0xFE, 0xFF}):
+		return decodeWith(unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), raw, charsetUTF16BE)
+	}
+
+	if charset := sniffUTF16(raw); charset == charsetUTF16LE {
+		return decodeWith(unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), raw, charsetUTF16LE)
+	} else if charset == charsetUTF16BE {
+		return decodeWith(unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), raw, charsetUTF16BE)
+	}
+
+	if utf8.Valid(raw) || bytes.IndexByte(raw, 0) >= 0 {
+		// Valid UTF-8, or binary content that no text charset will fix
+		return string(raw), charsetUTF8
+	}
+	return decodeWith(charmap.Windows1252, raw, charsetWindows1252)
+}
+
+func decodeWith(enc encoding.Encoding, raw []byte, charset string) (string, string) {
+	decoded, err := enc.NewDecoder().Bytes(raw)
+	if err != nil {
+		return string(raw), charsetUTF8
+	}
+	return string(bytes.TrimPrefix(decoded, utf8BOM)), charset
+}
+
+// sniffUTF16 guesses BOM-less UTF-16 from where NUL bytes fall: mostly-ASCII
+// text has a NUL in the high byte of nearly every code unit
+func sniffUTF16(raw []byte) string {
+	sample := raw
+	if len(sample) > charsetSniffLen {
+		sample = sample[:charsetSniffLen]
+	}
+	if len(sample) < 4 {
+		return ""
+	}
+
+	var evenNUL, oddNUL int
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenNUL++
+		} else {
+			oddNUL++
+		}
+	}
+
+	units := len(sample) / 2
+	switch {
+	case oddNUL*10 >= units*7 && evenNUL*10 <= units:
+		return charsetUTF16LE
+	case evenNUL*10 >= units*7 && oddNUL*10 <= units:
+		return charsetUTF16BE
+	}
+	return ""
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         []byte
+		want        string
+		wantCharset string
+	}{
+		{name: "utf-8", raw: []byte("naïve café"), want: "naïve café", wantCharset: charsetUTF8},
+		{name: "utf-8 bom", raw: []byte("\xEF\xBB\xBFhello"), want: "hello", wantCharset: charsetUTF8},
+		{name: "utf-16le bom", raw: []byte("\xFF\xFEh\x00i\x00"), want: "hi", wantCharset: charsetUTF16LE},
+		{name: "utf-16be bom", raw: []byte("\xFE\xFF\x00h\x00i"), want: "hi", wantCharset: charsetUTF16BE},
+		{name: "utf-16le without bom", raw: []byte("r\x00e\x00a\x00d\x00m\x00e\x00"), want: "readme", wantCharset: charsetUTF16LE},
+		{name: "utf-16be without bom", raw: []byte("\x00r\x00e\x00a\x00d\x00m\x00e"), want: "readme", wantCharset: charsetUTF16BE},
+		{name: "windows-1252", raw: []byte("caf\xE9 \x93quoted\x94"), want: "café “quoted”", wantCharset: charsetWindows1252},
+		{name: "binary is left alone", raw: []byte("\x00\x00\xFF\xFE\x01\x00\x00\x80"), want: "\x00\x00\xFF\xFE\x01\x00\x00\x80", wantCharset: charsetUTF8},
+		{name: "short input isn't sniffed", raw: []byte("a\x00"), want: "a\x00", wantCharset: charsetUTF8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, charset := decodeText(tt.raw)
+			if got != tt.want || charset != tt.wantCharset {
+				t.Errorf("decodeText() = %q, %s; want %q, %s", got, charset, tt.want, tt.wantCharset)
+			}
+		})
+	}
+}
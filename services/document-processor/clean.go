@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Cleaning steps, as named in requests and CHUNK_CLEANING
+const (
+	CleanTrim                = "trim"                 // drop trailing whitespace
+	CleanRemoveBlankLines    = "remove_blank_lines"   // drop empty lines
+	CleanStripControlChars   = "strip_control_chars"  // drop non-printable characters except tabs
+	CleanPreserveIndentation = "preserve_indentation" // keep leading whitespace
+)
+
+// cleanOptions selects the steps of the cleaning pipeline
+type cleanOptions struct {
+	trim                bool
+	removeBlankLines    bool
+	stripControlChars   bool
+	preserveIndentation bool
+}
+
+var (
+	// Prose is flattened to non-empty, unindented lines
+	proseCleaning = cleanOptions{trim: true, removeBlankLines: true, stripControlChars: true}
+	// Code and markup keep their layout
+	preformattedCleaning = cleanOptions{trim: true, stripControlChars: true, preserveIndentation: true}
+)
+
+// defaultCleaning is the built-in cleaning for a strategy
+func defaultCleaning(strategy string) cleanOptions {
+	switch strategy {
+	case StrategyPlain, StrategySemantic, StrategyPDF:
+		return proseCleaning
+	}
+	return preformattedCleaning
+}
+
+// CleaningOptions overrides individual cleaning steps for a request; unset
+// fields keep the strategy's default
+type CleaningOptions struct {
+	Trim                *bool `json:"trim,omitempty"`
+	RemoveBlankLines    *bool `json:"remove_blank_lines,omitempty"`
+	StripControlChars   *bool `json:"strip_control_chars,omitempty"`
+	PreserveIndentation *bool `json:"preserve_indentation,omitempty"`
+}
+
+// apply returns base with the set fields of o applied
+func (o *CleaningOptions) apply(base cleanOptions) cleanOptions {
+	if o == nil {
+		return base
+	}
+	for _, step := range []struct {
+		value *bool
+		dst   *bool
+	}{
+		{o.Trim, &base.trim},
+		{o.RemoveBlankLines, &base.removeBlankLines},
+		{o.StripControlChars, &base.stripControlChars},
+		{o.PreserveIndentation, &base.preserveIndentation},
+	} {
+		if step.value != nil {
+			*step.dst = *step.value
+		}
+	}
+	return base
+}
+
+// set changes one step by name
+func (o *CleaningOptions) set(step string, value bool) error {
+	switch step {
+	case CleanTrim:
+		o.Trim = &value
+	case CleanRemoveBlankLines:
+		o.RemoveBlankLines = &value
+	case CleanStripControlChars:
+		o.StripControlChars = &value
+	case CleanPreserveIndentation:
+		o.PreserveIndentation = &value
+	default:
+		return fmt.Errorf("unknown cleaning step %q (available: %s, %s, %s, %s)",
+			step, CleanTrim, CleanRemoveBlankLines, CleanStripControlChars, CleanPreserveIndentation)
+	}
+	return nil
+}
+
+// configureCleaning sets per-strategy cleaning overrides from entries of the
+// form "strategy.step=bool", where strategy "*" applies to every strategy
+func (p *DocumentProcessor) configureCleaning(entries map[string]string) error {
+	cleaning := make(map[string]*CleaningOptions)
+	for key, raw := range entries {
+		strategy, step, ok := strings.Cut(strings.ToLower(key), ".")
+		if !ok {
+			return fmt.Errorf("invalid cleaning entry %q, expected strategy.step=bool", key)
+		}
+		if _, known := p.strategies[strategy]; !known && strategy != "*" {
+			return fmt.Errorf("unknown chunking strategy %q in cleaning entry %q", strategy, key)
+		}
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid value %q for cleaning entry %q", raw, key)
+		}
+		if cleaning[strategy] == nil {
+			cleaning[strategy] = &CleaningOptions{}
+		}
+		if err := cleaning[strategy].set(step, value); err != nil {
+			return err
+		}
+	}
+	p.cleaning = cleaning
+	return nil
+}
+
+// resolveCleaning layers the strategy default, the configured overrides for
+// all strategies and for this one, and the request's overrides
+func (p *DocumentProcessor) resolveCleaning(strategy string, request *CleaningOptions) cleanOptions {
+	opts := defaultCleaning(strategy)
+	opts = p.cleaning["*"].apply(opts)
+	opts = p.cleaning[strategy].apply(opts)
+	return request.apply(opts)
+}
+
+// cleanText runs the selected cleaning steps over each line
+func cleanText(content string, opts cleanOptions) string {
+	lines := strings.Split(content, "\n")
+	cleaned := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		if opts.stripControlChars {
+			line = strings.Map(func(r rune) rune {
+				if r == '\t' || unicode.IsPrint(r) {
+					return r
+				}
+				return -1
+			}, line)
+		}
+		if opts.trim {
+			line = strings.TrimRightFunc(line, unicode.IsSpace)
+		}
+		if !opts.preserveIndentation {
+			line = strings.TrimLeftFunc(line, unicode.IsSpace)
+		}
+		if opts.removeBlankLines && strings.TrimSpace(line) == "" {
+			continue
+		}
+		cleaned = append(cleaned, line)
+	}
+
+	return strings.Join(cleaned, "\n")
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		opts    cleanOptions
+		want    string
+	}{
+		{
+			name:    "prose",
+			content: "  Title  \n\n\tindented\x07 text\r\n\n",
+			opts:    proseCleaning,
+			want:    "Title\nindented text",
+		},
+		{
+			name:    "preformatted keeps layout",
+			content: "func f() {\n\treturn 1   \n}\n\n",
+			opts:    preformattedCleaning,
+			want:    "func f() {\n\treturn 1\n}\n\n",
+		},
+		{
+			name:    "control characters kept without the step",
+			content: "a\x1b[0m b",
+			opts:    cleanOptions{preserveIndentation: true},
+			want:    "a\x1b[0m b",
+		},
+		{
+			name:    "control characters stripped, tabs kept",
+			content: "a\x1b[0m\tb\x00",
+			opts:    cleanOptions{stripControlChars: true, preserveIndentation: true},
+			want:    "a[0m\tb",
+		},
+		{
+			name:    "indentation dropped without trimming",
+			content: "  a  \n\tb\t",
+			opts:    cleanOptions{},
+			want:    "a  \nb\t",
+		},
+		{
+			name:    "whitespace-only lines count as blank",
+			content: "a\n  \t\nb",
+			opts:    cleanOptions{removeBlankLines: true, preserveIndentation: true},
+			want:    "a\nb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanText(tt.content, tt.opts); got != tt.want {
+				t.Errorf("cleanText(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveCleaning(t *testing.T) {
+	on, off := true, false
+	p := newTestProcessor()
+	if err := p.configureCleaning(map[string]string{
+		"*.strip_control_chars":       "false",
+		"markdown.remove_blank_lines": "true",
+		"Plain.Trim":                  "false",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		strategy string
+		request  *CleaningOptions
+		want     cleanOptions
+	}{
+		{
+			name:     "all strategies and own override",
+			strategy: StrategyMarkdown,
+			want:     cleanOptions{trim: true, removeBlankLines: true, preserveIndentation: true},
+		},
+		{
+			name:     "configured keys ignore case",
+			strategy: StrategyPlain,
+			want:     cleanOptions{removeBlankLines: true},
+		},
+		{
+			name:     "request overrides configuration",
+			strategy: StrategyMarkdown,
+			request:  &CleaningOptions{RemoveBlankLines: &off, StripControlChars: &on},
+			want:     cleanOptions{trim: true, stripControlChars: true, preserveIndentation: true},
+		},
+		{
+			name:     "prose default for semantic",
+			strategy: StrategySemantic,
+			want:     cleanOptions{trim: true, removeBlankLines: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.resolveCleaning(tt.strategy, tt.request); got != tt.want {
+				t.Errorf("resolveCleaning(%s) = %+v, want %+v", tt.strategy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigureCleaningErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries map[string]string
+		wantErr string
+	}{
+		{name: "missing step", entries: map[string]string{"markdown": "true"}, wantErr: "expected strategy.step=bool"},
+		{name: "unknown strategy", entries: map[string]string{"fancy.trim": "true"}, wantErr: `unknown chunking strategy "fancy"`},
+		{name: "unknown step", entries: map[string]string{"plain.squash": "true"}, wantErr: `unknown cleaning step "squash"`},
+		{name: "invalid value", entries: map[string]string{"plain.trim": "maybe"}, wantErr: `invalid value "maybe"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProcessor()
+			err := p.configureCleaning(tt.entries)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("configureCleaning() = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if p.cleaning != nil {
+				t.Errorf("cleaning = %v after an error, want it unchanged", p.cleaning)
+			}
+		})
+	}
+}
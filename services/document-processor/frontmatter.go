@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// extractFrontmatter splits YAML (---) or TOML (+++) frontmatter from the
+// start of a document and flattens it into metadata: scalars as-is, lists
+// comma-joined, and nested tables as dotted keys. Documents without valid
+// frontmatter are returned unchanged.
+func extractFrontmatter(content string) (map[string]string, string) {
+	trimmed := strings.TrimPrefix(content, "\ufeff")
+
+	var delim string
+	switch {
+	case strings.HasPrefix(trimmed, "---\n") || strings.HasPrefix(trimmed, "---\r\n"):
+		delim = "---"
+	case strings.HasPrefix(trimmed, "+++\n") || strings.HasPrefix(trimmed, "+++\r\n"):
+		delim = "+++"
+	default:
+		return nil, content
+	}
+
+	lines := strings.SplitAfter(trimmed, "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r\n")
+		if line == delim || (delim == "---" && line == "...") {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, content
+	}
+
+	raw := strings.Join(lines[1:end], "")
+	body := strings.Join(lines[end+1:], "")
+
+	data := map[string]interface{}{}
+	var err error
+	if delim == "+++" {
+		_, err = toml.Decode(raw, &data)
+	} else {
+		err = yaml.Unmarshal([]byte(raw), &data)
+	}
+	if err != nil {
+		// Not frontmatter after all (e.g. a document starting with a horizontal rule)
+		return nil, content
+	}
+
+	meta := map[string]string{}
+	flattenFrontmatter("", data, meta)
+	return meta, body
+}
+
+// flattenFrontmatter writes nested frontmatter values into meta under dotted keys
+func flattenFrontmatter(prefix string, value interface{}, meta map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flattenFrontmatter(key, v[k], meta)
+		}
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				continue
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		meta[prefix] = strings.Join(items, ",")
+	case []map[string]interface{}:
+		// TOML arrays of tables carry no useful chunk metadata
+	case nil:
+	default:
+		meta[prefix] = fmt.Sprint(v)
+	}
+}
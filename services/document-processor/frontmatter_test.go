@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractFrontmatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantMeta map[string]string
+		wantBody string
+	}{
+		{
+			name:     "yaml with lists and nested maps",
+			content:  "---\ntitle: Install\ntags: [setup, docker]\nauthor:\n  name: Ada\ndraft: false\nempty:\n---\n# Install\n",
+			wantMeta: map[string]string{"title": "Install", "tags": "setup,docker", "author.name": "Ada", "draft": "false"},
+			wantBody: "# Install\n",
+		},
+		{
+			name:     "yaml closed by dots, with a BOM and CRLF",
+			content:  "\ufeff---\r\ntitle: Notes\r\n...\r\nBody",
+			wantMeta: map[string]string{"title": "Notes"},
+			wantBody: "Body",
+		},
+		{
+			name:     "toml",
+			content:  "+++\ntitle = \"Usage\"\nweight = 3\n[params]\nsection = \"cli\"\n[[menu]]\nname = \"x\"\n+++\nText",
+			wantMeta: map[string]string{"title": "Usage", "weight": "3", "params.section": "cli"},
+			wantBody: "Text",
+		},
+		{
+			name:     "horizontal rule is not frontmatter",
+			content:  "---\nJust a rule: [unclosed\n---\nText",
+			wantBody: "---\nJust a rule: [unclosed\n---\nText",
+		},
+		{
+			name:     "unclosed block",
+			content:  "---\ntitle: x\nText",
+			wantBody: "---\ntitle: x\nText",
+		},
+		{
+			name:     "no frontmatter",
+			content:  "# Title\n",
+			wantBody: "# Title\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, body := extractFrontmatter(tt.content)
+			if !reflect.DeepEqual(meta, tt.wantMeta) || body != tt.wantBody {
+				t.Errorf("extractFrontmatter() = %v, %q; want %v, %q", meta, body, tt.wantMeta, tt.wantBody)
+			}
+		})
+	}
+}
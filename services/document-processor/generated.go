@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Dependency lockfiles: large, machine-written, and useless for retrieval
+var lockfiles = map[string]bool{
+	"package-lock.json": true, "npm-shrinkwrap.json": true, "yarn.lock": true, "pnpm-lock.yaml": true,
+	"bun.lockb": true, "cargo.lock": true, "gemfile.lock": true, "poetry.lock": true, "pipfile.lock": true,
+	"composer.lock": true, "go.sum": true, "mix.lock": true, "podfile.lock": true, "flake.lock": true,
+	"packages.lock.json": true, "gradle.lockfile": true, "pubspec.lock": true,
+}
+
+// Directories holding third-party code copied into the repository
+var vendoredDirs = []string{"vendor/", "third_party/", "third-party/", "node_modules/", "bower_components/", "external/"}
+
+// File name suffixes written by code generators and bundlers
+var (
+	generatedSuffixes = []string{
+		".pb.go", ".pb.gw.go", "_pb2.py", "_pb2_grpc.py", "_pb2.pyi", ".pb.cc", ".pb.h", "_pb.js", "_pb.d.ts",
+		"_grpc_pb.js", ".pb.swift", ".pb.dart", "_generated.go", ".generated.cs", ".designer.cs", ".g.dart",
+	}
+	bundleSuffixes = []string{".min.js", ".min.css", "-min.js", ".min.mjs", ".bundle.js", ".chunk.js"}
+)
+
+// Markers generators write near the top of their output
+var generatedMarkerRe = regexp.MustCompile(`(?i)(code generated .* do not edit|@generated\b|this (file|code) (is|was|has been) (auto-?|automatically )?generated|auto-?generated (file|code)|generated by the protocol buffer compiler|generated by protoc)`)
+
+const (
+	// Only the head of a file is searched for generator markers
+	generatedMarkerLines = 20
+	// Files under this size are never treated as minified
+	minifiedMinSize = 1000
+	// Average line length above which non-prose content counts as minified
+	minifiedAvgLine = 500
+)
+
+// skipReason explains why a file should not be indexed as generated,
+// minified, or vendored content, or returns "" to index it
+func skipReason(filePath, content string) string {
+	lower := strings.ToLower(filePath)
+	base := path.Base(lower)
+
+	if lockfiles[base] {
+		return "lockfile"
+	}
+	for _, dir := range vendoredDirs {
+		if strings.HasPrefix(lower, dir) || strings.Contains(lower, "/"+dir) {
+			return "vendored (" + strings.TrimSuffix(dir, "/") + ")"
+		}
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return "generated (" + suffix + ")"
+		}
+	}
+	for _, suffix := range bundleSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return "minified bundle (" + suffix + ")"
+		}
+	}
+
+	head := content
+	if lines := strings.SplitN(content, "\n", generatedMarkerLines+1); len(lines) > generatedMarkerLines {
+		head = strings.Join(lines[:generatedMarkerLines], "\n")
+	}
+	if m := generatedMarkerRe.FindString(head); m != "" {
+		return "generated (marker \"" + m + "\")"
+	}
+
+	if !proseExts[path.Ext(base)] && isMinified(content) {
+		return "minified"
+	}
+	return ""
+}
+
+// isMinified reports whether content is dominated by very long lines, as
+// left by minifiers and bundlers
+func isMinified(content string) bool {
+	if len(content) < minifiedMinSize {
+		return false
+	}
+	lines := strings.Count(strings.TrimRight(content, "\n"), "\n") + 1
+	return len(content)/lines > minifiedAvgLine
+}
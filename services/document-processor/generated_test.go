@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSkipReason(t *testing.T) {
+	minified := "var a=1;" + strings.Repeat("function f(){return a+1};", 80)
+	lateMarker := strings.Repeat("// line\n", generatedMarkerLines) + "// Code generated by stringer. DO NOT EDIT.\n"
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    string
+	}{
+		{name: "lockfile", path: "package-lock.json", want: "lockfile"},
+		{name: "nested lockfile ignores case", path: "crates/cli/Cargo.lock", want: "lockfile"},
+		{name: "vendored at the root", path: "vendor/github.com/pkg/errors/errors.go", want: "vendored (vendor)"},
+		{name: "vendored nested", path: "web/node_modules/left-pad/index.js", want: "vendored (node_modules)"},
+		{name: "generated suffix", path: "api/v1/service.pb.go", want: "generated (.pb.go)"},
+		{name: "generated python stubs", path: "proto/service_pb2_grpc.py", want: "generated (_pb2_grpc.py)"},
+		{name: "minified bundle", path: "static/app.min.js", want: "minified bundle (.min.js)"},
+		{
+			name:    "go generated marker",
+			path:    "internal/mocks/store.go",
+			content: "// Code generated by MockGen. DO NOT EDIT.\npackage mocks\n",
+			want:    `generated (marker "Code generated by MockGen. DO NOT EDIT")`,
+		},
+		{
+			name:    "at-generated marker",
+			path:    "src/schema.ts",
+			content: "/**\n * @generated\n */\nexport type Query = {};\n",
+			want:    `generated (marker "@generated")`,
+		},
+		{name: "marker past the head", path: "gen/names.go", content: lateMarker, want: ""},
+		{name: "minified by line length", path: "dist/app.js", content: minified, want: "minified"},
+		{name: "long prose lines are not minified", path: "docs/one-line.md", content: strings.Repeat("word ", 400), want: ""},
+		{name: "short long-lined files are not minified", path: "config.js", content: strings.Repeat("x", minifiedMinSize-1), want: ""},
+		{name: "ordinary source", path: "cmd/server/main.go", content: "package main\n\nfunc main() {}\n", want: ""},
+		{name: "lookalike directory", path: "docs/vendors/overview.md", content: "# Vendors\n", want: ""},
+		{name: "lookalike file name", path: "docs/yarn.lock.md", content: "# Lockfiles\n", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skipReason(tt.path, tt.content); got != tt.want {
+				t.Errorf("skipReason(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// chunkGoSource splits Go source at top-level declarations so functions,
+// methods, and types stay whole with their doc comments. Adjacent small
+// declarations are packed together up to maxSize; a declaration larger than
+// maxSize falls back to line-aware splitting.
+func (p *DocumentProcessor) chunkGoSource(filename, src string, maxSize int, overlap chunkOverlap) ([]chunk, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each declaration starts at its doc comment; everything before the first
+	// non-import declaration (package clause, imports) forms the preamble
+	boundaries := []int{0}
+	symbols := [][]string{nil}
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+		start := fset.Position(declStart(decl)).Offset
+		if start > boundaries[len(boundaries)-1] {
+			boundaries = append(boundaries, start)
+			symbols = append(symbols, nil)
+		}
+		symbols[len(symbols)-1] = append(symbols[len(symbols)-1], declNames(decl)...)
+	}
+	boundaries = append(boundaries, len(src))
+
+	segments := make([]codeSegment, 0, len(boundaries)-1)
+	for i := 0; i < len(boundaries)-1; i++ {
+		segments = append(segments, codeSegment{text: src[boundaries[i]:boundaries[i+1]], symbols: symbols[i]})
+	}
+
+	return p.packSegments(segments, maxSize, overlap), nil
+}
+
+// declNames lists the names a declaration defines; methods are qualified
+// with their receiver type (e.g. "Server.Start")
+func declNames(decl ast.Decl) []string {
+	var names []string
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		name := d.Name.Name
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			if recv := receiverType(d.Recv.List[0].Type); recv != "" {
+				name = recv + "." + name
+			}
+		}
+		names = append(names, name)
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, n := range s.Names {
+					if n.Name != "_" {
+						names = append(names, n.Name)
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// receiverType returns the base type name of a method receiver
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// declStart returns the position of a declaration including its doc comment
+func declStart(decl ast.Decl) token.Pos {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			return d.Doc.Pos()
+		}
+	}
+	return decl.Pos()
+}
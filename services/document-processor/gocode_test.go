@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestProcessor() *DocumentProcessor {
+	return NewDocumentProcessor(1000, 0, 0, nil, false, false, nil)
+}
+
+// chunkSymbols lists the "symbols" metadata of each chunk
+func chunkSymbols(chunks []chunk) []string {
+	out := make([]string, len(chunks))
+	for i, c := range chunks {
+		out[i] = c.Metadata["symbols"]
+	}
+	return out
+}
+
+func TestChunkGoSource(t *testing.T) {
+	const src = `package server
+
+import "net/http"
+
+// Server answers requests
+type Server struct{ mux *http.ServeMux }
+
+// Start listens on addr
+func (s *Server) Start(addr string) error {
+	return http.ListenAndServe(addr, s.mux)
+}
+
+const (
+	defaultPort = 8080
+	_           = 0
+)
+
+func list[T any](items []T) []T { return items }
+
+func (c *cache[K, V]) Get(key K) V { var v V; return v }
+`
+
+	tests := []struct {
+		name        string
+		maxSize     int
+		wantSymbols []string
+		wantWhole   string // text some chunk must hold unbroken
+		wantErr     bool
+	}{
+		{
+			name:        "small declarations are packed together",
+			maxSize:     1000,
+			wantSymbols: []string{"Server,Server.Start,defaultPort,list,cache.Get"},
+			wantWhole:   "// Start listens on addr\nfunc (s *Server) Start(addr string) error {\n\treturn http.ListenAndServe(addr, s.mux)\n}",
+		},
+		{
+			name:        "declarations keep their doc comments",
+			maxSize:     120,
+			wantSymbols: []string{"Server", "Server.Start", "defaultPort,list", "cache.Get"},
+			wantWhole:   "// Server answers requests\ntype Server struct{ mux *http.ServeMux }",
+		},
+		{
+			name:    "invalid source",
+			maxSize: 1000,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := src
+			if tt.wantErr {
+				input = "package broken\nfunc {"
+			}
+			chunks, err := newTestProcessor().chunkGoSource("server.go", input, tt.maxSize, chunkOverlap{})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("parsed invalid source")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chunkSymbols(chunks); strings.Join(got, "|") != strings.Join(tt.wantSymbols, "|") {
+				t.Errorf("symbols = %q, want %q", got, tt.wantSymbols)
+			}
+			whole := false
+			for _, c := range chunks {
+				whole = whole || strings.Contains(c.Content, tt.wantWhole)
+			}
+			if !whole {
+				t.Errorf("no chunk holds %q whole", tt.wantWhole)
+			}
+		})
+	}
+}
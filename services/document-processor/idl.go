@@ -0,0 +1,188 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// idlSyntax describes how an interface definition language declares things
+type idlSyntax struct {
+	definition *regexp.Regexp // a top-level definition; group 1 is its name
+	member     *regexp.Regexp // a named member worth indexing, e.g. an RPC
+	pkg        *regexp.Regexp // the package or namespace; the last group is its name
+	hashes     bool           // "#" starts a comment
+}
+
+var idlSyntaxes = map[string]*idlSyntax{
+	".proto": {
+		definition: regexp.MustCompile(`^(?:message|enum|service|extend)\s+([\w.]+)`),
+		member:     regexp.MustCompile(`^\s*rpc\s+(\w+)`),
+		pkg:        regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`),
+	},
+	".thrift": {
+		definition: regexp.MustCompile(`^(?:struct|union|exception|enum|senum|service)\s+(\w+)|^typedef\s+.+?\s+(\w+)\s*[;,]?\s*$|^const\s+\S+\s+(\w+)`),
+		member:     regexp.MustCompile(`^\s*(?:oneway\s+)?[\w.<>, ]+?\s+(\w+)\s*\(`),
+		pkg:        regexp.MustCompile(`(?m)^\s*namespace\s+(\S+)\s+([\w.]+)`),
+		hashes:     true,
+	},
+	".graphql":  graphQLSyntax,
+	".graphqls": graphQLSyntax,
+	".gql":      graphQLSyntax,
+}
+
+var graphQLSyntax = &idlSyntax{
+	definition: regexp.MustCompile(`^(?:extend\s+)?(?:type|interface|enum|input|union|scalar|directive\s+@|query|mutation|subscription|fragment)\s*(\w+)|^(?:extend\s+)?(schema)\b`),
+	hashes:     true,
+}
+
+// isIDL reports whether files with ext are Protobuf, Thrift, or GraphQL schemas
+func isIDL(ext string) bool {
+	_, ok := idlSyntaxes[strings.ToLower(ext)]
+	return ok
+}
+
+// chunkIDL splits an interface definition file at top-level messages,
+// services, and types, keeping leading comments attached. The package or
+// namespace is returned for every chunk's metadata, and chunks list the
+// definitions (and service methods, as "Service.method") they hold.
+func (p *DocumentProcessor) chunkIDL(ext, src string, maxSize int, overlap chunkOverlap) ([]chunk, map[string]string) {
+	syntax := idlSyntaxes[strings.ToLower(ext)]
+	lines := strings.SplitAfter(src, "\n")
+	comment := idlCommentLines(lines, syntax.hashes)
+
+	var segments []codeSegment
+	current := codeSegment{}
+	start, depth := 0, 0
+	owner := ""
+	for i, line := range lines {
+		if depth == 0 && !comment[i] {
+			if name := idlDefinitionName(syntax, strings.TrimSpace(line)); name != "" {
+				// Leading comments belong to the definition
+				cut := i
+				for cut > start && comment[cut-1] && strings.TrimSpace(lines[cut-1]) != "" {
+					cut--
+				}
+				if cut > start {
+					current.text = strings.Join(lines[start:cut], "")
+					segments = append(segments, current)
+					start = cut
+				}
+				current = codeSegment{symbols: []string{name}}
+				owner = name
+			}
+		}
+		if depth > 0 && syntax.member != nil && !comment[i] && owner != "" {
+			if m := syntax.member.FindStringSubmatch(line); m != nil {
+				current.symbols = append(current.symbols, owner+"."+m[1])
+			}
+		}
+		if !comment[i] {
+			depth += idlBraceDelta(line, syntax.hashes)
+			if depth < 0 {
+				depth = 0
+			}
+		}
+	}
+	current.text = strings.Join(lines[start:], "")
+	segments = append(segments, current)
+
+	var docMeta map[string]string
+	if pkg := idlPackage(syntax, src); pkg != "" {
+		docMeta = map[string]string{"package": pkg}
+	}
+	return p.packSegments(segments, maxSize, overlap), docMeta
+}
+
+// idlPackage returns the declared package. Thrift declares a namespace per
+// target language; the "*" namespace wins, else the first one declared.
+func idlPackage(syntax *idlSyntax, src string) string {
+	if syntax.pkg == nil {
+		return ""
+	}
+	matches := syntax.pkg.FindAllStringSubmatch(src, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	for _, m := range matches {
+		if len(m) == 3 && m[1] == "*" {
+			return m[2]
+		}
+	}
+	return matches[0][len(matches[0])-1]
+}
+
+// idlDefinitionName returns the name a top-level definition line declares
+func idlDefinitionName(syntax *idlSyntax, line string) string {
+	m := syntax.definition.FindStringSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	for _, group := range m[1:] {
+		if group != "" {
+			return group
+		}
+	}
+	return ""
+}
+
+// idlCommentLines marks lines that are entirely comments or GraphQL
+// descriptions, including the inside of block comments
+func idlCommentLines(lines []string, hashes bool) []bool {
+	comment := make([]bool, len(lines))
+	var closing string // delimiter that ends the open block, if any
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if closing != "" {
+			comment[i] = true
+			if strings.Contains(trimmed, closing) {
+				closing = ""
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "//"), hashes && strings.HasPrefix(trimmed, "#"):
+			comment[i] = true
+		case strings.HasPrefix(trimmed, "/*"):
+			comment[i] = true
+			if !strings.Contains(trimmed[2:], "*/") {
+				closing = "*/"
+			}
+		case strings.HasPrefix(trimmed, `"""`):
+			comment[i] = true
+			if !strings.Contains(trimmed[3:], `"""`) {
+				closing = `"""`
+			}
+		case strings.HasPrefix(trimmed, `"`) && strings.HasSuffix(trimmed, `"`) && len(trimmed) > 1:
+			// Single-line GraphQL description
+			comment[i] = true
+		}
+	}
+	return comment
+}
+
+// idlBraceDelta counts the braces a line opens minus those it closes,
+// ignoring string literals and trailing comments
+func idlBraceDelta(line string, hashes bool) int {
+	delta := 0
+	inString := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(line) && line[i+1] == '/', hashes && c == '#':
+			return delta
+		case c == '{':
+			delta++
+		case c == '}':
+			delta--
+		}
+	}
+	return delta
+}
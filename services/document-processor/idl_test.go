@@ -0,0 +1,133 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkIDL(t *testing.T) {
+	tests := []struct {
+		name        string
+		ext         string
+		src         string
+		maxSize     int
+		wantSymbols []string
+		wantPackage string
+		wantFirst   string // prefix of the first chunk
+	}{
+		{
+			name: "proto messages and service methods",
+			ext:  ".proto",
+			src: `syntax = "proto3";
+package shop.v1;
+
+// A product in the catalog
+message Product {
+  string id = 1; // "{" in a comment
+}
+
+service Catalog {
+  rpc GetProduct(GetRequest) returns (Product);
+  // rpc Hidden(X) returns (Y);
+  rpc ListProducts(ListRequest) returns (stream Product);
+}
+`,
+			maxSize:     200,
+			wantSymbols: []string{"Product", "Catalog,Catalog.GetProduct,Catalog.ListProducts"},
+			wantPackage: "shop.v1",
+			wantFirst:   `syntax = "proto3";`,
+		},
+		{
+			name: "thrift prefers the wildcard namespace",
+			ext:  ".thrift",
+			src: `namespace java com.example.shop
+namespace * shop
+
+# Raised on bad input
+exception InvalidInput {
+  1: string reason
+}
+
+typedef i64 Timestamp
+
+service Orders {
+  Order place(1: Cart cart) throws (1: InvalidInput err),
+  oneway void ping()
+}
+`,
+			maxSize:     1000,
+			wantSymbols: []string{"InvalidInput,Timestamp,Orders,Orders.place,Orders.ping"},
+			wantPackage: "shop",
+			wantFirst:   "namespace java",
+		},
+		{
+			name: "graphql descriptions stay with their types",
+			ext:  ".GraphQL",
+			src: `"""
+A user account
+"""
+type User {
+  id: ID!
+}
+
+extend type Query {
+  me: User
+}
+
+schema {
+  query: Query
+}
+`,
+			maxSize:     50,
+			wantSymbols: []string{"User", "Query", "schema"},
+			wantFirst:   "\"\"\"\nA user account\n\"\"\"\ntype User {",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !isIDL(tt.ext) {
+				t.Fatalf("isIDL(%q) = false", tt.ext)
+			}
+			chunks, docMeta := newTestProcessor().chunkIDL(tt.ext, tt.src, tt.maxSize, chunkOverlap{})
+			if got := chunkSymbols(chunks); strings.Join(got, "|") != strings.Join(tt.wantSymbols, "|") {
+				t.Errorf("symbols = %q, want %q", got, tt.wantSymbols)
+			}
+			var wantMeta map[string]string
+			if tt.wantPackage != "" {
+				wantMeta = map[string]string{"package": tt.wantPackage}
+			}
+			if !reflect.DeepEqual(docMeta, wantMeta) {
+				t.Errorf("doc metadata = %v, want %v", docMeta, wantMeta)
+			}
+			if len(chunks) == 0 || !strings.HasPrefix(chunks[0].Content, tt.wantFirst) {
+				t.Errorf("chunks = %q, want the first to start with %q", chunks, tt.wantFirst)
+			}
+		})
+	}
+
+	if isIDL(".avsc") {
+		t.Error("isIDL(.avsc) = true")
+	}
+}
+
+func TestIDLBraceDelta(t *testing.T) {
+	tests := []struct {
+		line   string
+		hashes bool
+		want   int
+	}{
+		{line: "message A {", want: 1},
+		{line: "}", want: -1},
+		{line: `option (x) = "{";`, want: 0},
+		{line: `string s = 1 [default = "\"{"];`, want: 0},
+		{line: "enum E { A = 1; } // }", want: 0},
+		{line: "struct S { # {", hashes: true, want: 1},
+		{line: "struct S { # {", want: 2},
+	}
+	for _, tt := range tests {
+		if got := idlBraceDelta(tt.line, tt.hashes); got != tt.want {
+			t.Errorf("idlBraceDelta(%q, %v) = %d, want %d", tt.line, tt.hashes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"path"
+	"strings"
+	"unicode"
+)
+
+// Programming and markup languages by file extension
+var extLanguages = map[string]string{
+	".go": "go", ".py": "python", ".pyi": "python", ".js": "javascript", ".jsx": "javascript",
+	".mjs": "javascript", ".cjs": "javascript", ".ts": "typescript", ".tsx": "typescript", ".mts": "typescript",
+	".java": "java", ".kt": "kotlin", ".kts": "kotlin", ".scala": "scala", ".rs": "rust", ".rb": "ruby",
+	".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".cxx": "cpp", ".hpp": "cpp", ".cs": "csharp",
+	".php": "php", ".swift": "swift", ".m": "objective-c", ".sh": "shell", ".bash": "shell", ".zsh": "shell",
+	".ps1": "powershell", ".sql": "sql", ".r": "r", ".lua": "lua", ".pl": "perl", ".dart": "dart",
+	".ex": "elixir", ".exs": "elixir", ".erl": "erlang", ".hs": "haskell", ".clj": "clojure",
+	".tf": "terraform", ".proto": "protobuf", ".thrift": "thrift", ".graphql": "graphql", ".gql": "graphql", ".graphqls": "graphql",
+	".html": "html", ".htm": "html", ".css": "css", ".scss": "scss", ".vue": "vue", ".svelte": "svelte",
+	".yaml": "yaml", ".yml": "yaml", ".json": "json", ".toml": "toml", ".xml": "xml", ".ini": "ini",
+	".ipynb": "python", ".csv": "csv", ".tsv": "tsv",
+}
+
+// Well-known files without a telling extension
+var fileLanguages = map[string]string{
+	"dockerfile": "dockerfile", "makefile": "make", "gnumakefile": "make", "jenkinsfile": "groovy",
+	"rakefile": "ruby", "gemfile": "ruby", "vagrantfile": "ruby", "cmakelists.txt": "cmake",
+}
+
+// Interpreters named on a shebang line
+var shebangLanguages = map[string]string{
+	"sh": "shell", "bash": "shell", "zsh": "shell", "python": "python", "python3": "python",
+	"node": "javascript", "ruby": "ruby", "perl": "perl", "php": "php", "lua": "lua",
+}
+
+// Prose formats get their natural language detected instead
+var proseExts = map[string]bool{
+	".md": true, ".markdown": true, ".mdx": true, ".rst": true, ".rest": true, ".txt": true,
+	".adoc": true, ".asciidoc": true, ".asc": true, ".pdf": true, "": true,
+}
+
+// Frequent function words per language; a few dozen are enough to tell
+// the languages apart on a paragraph of text
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "as", "this", "are", "be", "on", "you", "not", "or", "can", "will"},
+	"es": {"el", "la", "de", "que", "y", "en", "los", "las", "por", "con", "para", "una", "es", "se", "del", "al", "como", "su", "más", "pero"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "un", "une", "que", "dans", "pour", "pas", "sur", "qui", "au", "avec", "ce", "du", "vous"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "von", "sich", "auf", "für", "im", "dem", "auch", "wird", "sie"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "por", "mais", "se", "ao", "você"},
+	"it": {"il", "la", "di", "che", "e", "è", "un", "una", "per", "non", "con", "del", "della", "sono", "gli", "le", "si", "da", "nel", "anche"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "voor", "met", "zijn", "die", "ook", "als", "aan", "er", "maar", "wordt"},
+}
+
+var stopwordIndex = func() map[string][]string {
+	index := map[string][]string{}
+	for lang, words := range stopwords {
+		for _, w := range words {
+			index[w] = append(index[w], lang)
+		}
+	}
+	return index
+}()
+
+// Fewer words than this are not enough to guess a natural language
+const minLanguageWords = 20
+
+// detectLanguage returns the programming language of source files and the
+// ISO 639-1 natural language of prose, or "" when it cannot tell
+func detectLanguage(filePath, content string) string {
+	base := strings.ToLower(path.Base(filePath))
+	if lang, ok := fileLanguages[base]; ok {
+		return lang
+	}
+	ext := strings.ToLower(path.Ext(base))
+	if lang, ok := extLanguages[ext]; ok {
+		return lang
+	}
+	if strings.HasPrefix(content, "#!") {
+		if lang := shebangLanguage(content); lang != "" {
+			return lang
+		}
+	}
+	if proseExts[ext] {
+		return detectNaturalLanguage(content)
+	}
+	return ""
+}
+
+// shebangLanguage maps "#!/usr/bin/env python3" and the like to a language
+func shebangLanguage(content string) string {
+	line := strings.SplitN(content, "\n", 2)[0]
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = fields[1]
+	}
+	return shebangLanguages[interpreter]
+}
+
+// detectNaturalLanguage identifies non-Latin scripts by their characters
+// and Latin-script languages by stopword frequency
+func detectNaturalLanguage(text string) string {
+	if len(text) > 20000 {
+		text = text[:20000]
+	}
+
+	var letters, han, kana, hangul, cyrillic, arabic, devanagari, hebrew, greek, thai int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Devanagari, r):
+			devanagari++
+		case unicode.Is(unicode.Hebrew, r):
+			hebrew++
+		case unicode.Is(unicode.Greek, r):
+			greek++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// A script counts once it makes up a fifth of the letters; code samples
+	// and identifiers in Latin script are common in non-English docs
+	dominant := func(n int) bool { return n*5 >= letters }
+	switch {
+	case dominant(kana):
+		return "ja"
+	case dominant(hangul):
+		return "ko"
+	case dominant(han):
+		return "zh"
+	case dominant(cyrillic):
+		return "ru"
+	case dominant(arabic):
+		return "ar"
+	case dominant(devanagari):
+		return "hi"
+	case dominant(hebrew):
+		return "he"
+	case dominant(greek):
+		return "el"
+	case dominant(thai):
+		return "th"
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < minLanguageWords {
+		return ""
+	}
+
+	scores := map[string]int{}
+	for _, w := range words {
+		for _, lang := range stopwordIndex[w] {
+			scores[lang]++
+		}
+	}
+
+	best, bestScore := "", 0
+	for lang, score := range scores {
+		if score > bestScore || (score == bestScore && lang < best) {
+			best, bestScore = lang, score
+		}
+	}
+	// Require a meaningful share of stopwords so lists of names or tables
+	// are not labelled
+	if bestScore*20 < len(words) {
+		return ""
+	}
+	return best
+}
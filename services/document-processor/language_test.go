@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	english := "This is the guide to the service. It explains how to install it and how you can run it with the default settings for the first time on your own machine."
+	spanish := "Esta es la guía del servicio. Explica cómo instalar el programa y cómo se puede ejecutar con la configuración por defecto en su máquina para la primera vez que lo usas."
+	german := "Dies ist die Anleitung für den Dienst. Sie erklärt, wie man ihn installiert und wie er mit den Standardeinstellungen auf dem eigenen Rechner zum ersten Mal gestartet wird, auch wenn das nicht immer einfach ist."
+
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    string
+	}{
+		{name: "source extension", path: "cmd/main.go", content: "package main", want: "go"},
+		{name: "extension ignores case", path: "src/App.TSX", want: "typescript"},
+		{name: "well-known file name", path: "build/Dockerfile", want: "dockerfile"},
+		{name: "file name before extension", path: "CMakeLists.txt", content: english, want: "cmake"},
+		{name: "shebang through env", path: "scripts/release", content: "#!/usr/bin/env python3\nprint('hi')\n", want: "python"},
+		{name: "shebang with a path", path: "bin/setup", content: "#!/bin/bash\nset -e\n", want: "shell"},
+		{name: "unknown interpreter", path: "bin/tool", content: "#!/usr/bin/awk -f\n", want: ""},
+		{name: "english prose", path: "README.md", content: english, want: "en"},
+		{name: "spanish prose", path: "docs/LEEME.md", content: spanish, want: "es"},
+		{name: "german prose", path: "docs/anleitung.rst", content: german, want: "de"},
+		{name: "japanese script", path: "docs/ja.md", content: "このサービスはリポジトリのドキュメントを同期します。", want: "ja"},
+		{name: "cyrillic script", path: "docs/ru.md", content: "Это руководство по установке сервиса.", want: "ru"},
+		{name: "korean script", path: "docs/ko.txt", content: "이 서비스는 문서를 동기화합니다.", want: "ko"},
+		{name: "too few words", path: "NOTES.md", content: "Install the service.", want: ""},
+		{name: "names without stopwords", path: "AUTHORS.md", content: "Alice Bob Carol Dave Erin Frank Grace Heidi Ivan Judy Mallory Niaj Olivia Peggy Rupert Sybil Trent Victor Walter Yolanda", want: ""},
+		{name: "unknown extension", path: "assets/logo.bin", content: english, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLanguage(tt.path, tt.content); got != tt.want {
+				t.Errorf("detectLanguage(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Phrases that mark a comment block as license or copyright boilerplate
+var licenseIndicators = []string{
+	"copyright", "spdx-license-identifier", "licensed under", "permission is hereby granted",
+	"all rights reserved", "gnu general public license", "gnu lesser general public license",
+	"apache license", "mit license", "mozilla public license", "redistribution and use in source and binary forms",
+	"this program is free software", "this source code is licensed",
+}
+
+var spdxRe = regexp.MustCompile(`SPDX-License-Identifier:\s*([^\s*]+(?:\s+(?:OR|AND|WITH)\s+[^\s*]+)*)`)
+
+// stripLicenseHeader removes a license or copyright comment block from the
+// top of a source file, keeping a shebang line. It returns the remaining
+// source and the SPDX identifier the header declared, if any.
+func stripLicenseHeader(src string) (string, string) {
+	lines := strings.Split(src, "\n")
+
+	var kept []string
+	i := 0
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "#!") {
+		kept = append(kept, lines[0])
+		i = 1
+	}
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	if i >= len(lines) {
+		return src, ""
+	}
+
+	end := commentBlockEnd(lines, i)
+	if end <= i {
+		return src, ""
+	}
+	header := strings.Join(lines[i:end], "\n")
+	if !isLicenseText(header) {
+		return src, ""
+	}
+
+	license := ""
+	if m := spdxRe.FindStringSubmatch(header); m != nil {
+		license = m[1]
+	}
+
+	// Drop the blank lines that separated the header from the code
+	for end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+		end++
+	}
+	return strings.Join(append(kept, lines[end:]...), "\n"), license
+}
+
+// commentBlockEnd returns the index just past the comment block starting at
+// line start, or start if the line does not open a comment
+func commentBlockEnd(lines []string, start int) int {
+	first := strings.TrimSpace(lines[start])
+
+	for _, delims := range [][2]string{{"/*", "*/"}, {"<!--", "-->"}, {`"""`, `"""`}, {"{-", "-}"}} {
+		if !strings.HasPrefix(first, delims[0]) {
+			continue
+		}
+		// The closing delimiter may sit on the opening line
+		if strings.Contains(first[len(delims[0]):], delims[1]) {
+			return start + 1
+		}
+		for j := start + 1; j < len(lines); j++ {
+			if strings.Contains(lines[j], delims[1]) {
+				return j + 1
+			}
+		}
+		return start
+	}
+
+	for _, prefix := range []string{"//", "#", "--", ";;"} {
+		if !isLineComment(first, prefix) {
+			continue
+		}
+		j := start
+		for j < len(lines) && isLineComment(strings.TrimSpace(lines[j]), prefix) {
+			j++
+		}
+		return j
+	}
+	return start
+}
+
+// isLineComment reports whether a trimmed line is a comment with the given
+// prefix. "#" must be followed by a space or end the line so preprocessor
+// directives like #include are not mistaken for comments.
+func isLineComment(line, prefix string) bool {
+	if !strings.HasPrefix(line, prefix) {
+		return false
+	}
+	if prefix == "//" && (strings.HasPrefix(line, "//go:") || strings.HasPrefix(line, "// +build")) {
+		// Build constraints belong to the code
+		return false
+	}
+	if prefix == "#" {
+		rest := line[1:]
+		return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '#'
+	}
+	return true
+}
+
+func isLicenseText(text string) bool {
+	lower := strings.ToLower(text)
+	for _, indicator := range licenseIndicators {
+		if strings.Contains(lower, indicator) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestStripLicenseHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		want        string
+		wantLicense string
+	}{
+		{
+			name:        "block comment with spdx",
+			src:         "/*\n * Copyright 2024 Acme\n * SPDX-License-Identifier: Apache-2.0 OR MIT\n */\n\npackage main\n",
+			want:        "package main\n",
+			wantLicense: "Apache-2.0 OR MIT",
+		},
+		{
+			name: "line comments",
+			src:  "// Copyright (c) Acme. All rights reserved.\n// Licensed under the MIT License.\n\nconst x = 1",
+			want: "const x = 1",
+		},
+		{
+			name:        "shebang is kept",
+			src:         "#!/usr/bin/env python\n# SPDX-License-Identifier: GPL-2.0-only\n# Copyright Acme\nimport os",
+			want:        "#!/usr/bin/env python\nimport os",
+			wantLicense: "GPL-2.0-only",
+		},
+		{
+			name: "doc comments are kept",
+			src:  "// Package sync copies repositories\npackage sync",
+			want: "// Package sync copies repositories\npackage sync",
+		},
+		{
+			name: "build constraints end the header",
+			src:  "//go:build linux\n\n// Copyright Acme\npackage main",
+			want: "//go:build linux\n\n// Copyright Acme\npackage main",
+		},
+		{
+			name: "preprocessor directives aren't comments",
+			src:  "#include <stdio.h>\n/* Copyright Acme */\n",
+			want: "#include <stdio.h>\n/* Copyright Acme */\n",
+		},
+		{
+			name: "unclosed block comment",
+			src:  "/* Copyright Acme\nint x;",
+			want: "/* Copyright Acme\nint x;",
+		},
+		{
+			name: "html comment",
+			src:  "<!-- Licensed under the Apache License -->\n<p>Hi</p>",
+			want: "<p>Hi</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, license := stripLicenseHeader(tt.src)
+			if got != tt.want || license != tt.wantLicense {
+				t.Errorf("stripLicenseHeader() = %q, %q; want %q, %q", got, license, tt.want, tt.wantLicense)
+			}
+		})
+	}
+}
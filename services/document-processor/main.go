@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -12,180 +14,461 @@ import (
 	"strings"
 	"syscall"
 	"time"
-	"unicode"
 
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
 )
 
 // DocumentProcessor implements interfaces.DocumentProcessor
 type DocumentProcessor struct {
-	maxChunkSize int
-	chunkOverlap int
+	maxChunkSize  int
+	chunkOverlap  int
+	rowsPerChunk  int
+	skipGenerated bool
+	stripLicenses bool
+	redactor      *PIIRedactor
+
+	strategies    map[string]chunkStrategy
+	extStrategies map[string]string           // file extension -> strategy name
+	embedder      *embeddingClient            // sentence embeddings for semantic chunking
+	projects      *projectClient              // project filter rules for /validate
+	defaultRules  filter.Rules                // filter rules without a project
+	cleaning      map[string]*CleaningOptions // strategy ("*" for all) -> configured cleaning
+	overlap       chunkOverlap                // default overlap in tokens or sentences
 }
 
-// NewDocumentProcessor creates a new document processor
-func NewDocumentProcessor(maxChunkSize, chunkOverlap int) *DocumentProcessor {
-	return &DocumentProcessor{
-		maxChunkSize: maxChunkSize,
-		chunkOverlap: chunkOverlap,
+// NewDocumentProcessor creates a new document processor; extStrategies maps
+// file extensions to chunking strategies, skipGenerated drops lockfiles and
+// generated, minified, or vendored files, stripLicenses removes license
+// headers from source files, and redactor may be nil
+func NewDocumentProcessor(maxChunkSize, chunkOverlap, rowsPerChunk int, extStrategies map[string]string, skipGenerated, stripLicenses bool, redactor *PIIRedactor) *DocumentProcessor {
+	if rowsPerChunk <= 0 {
+		rowsPerChunk = 20
 	}
+	p := &DocumentProcessor{
+		maxChunkSize:  maxChunkSize,
+		chunkOverlap:  chunkOverlap,
+		rowsPerChunk:  rowsPerChunk,
+		skipGenerated: skipGenerated,
+		stripLicenses: stripLicenses,
+		redactor:      redactor,
+		extStrategies: normalizeStrategyMap(extStrategies),
+		overlap:       overlapFromChars(chunkOverlap),
+	}
+	p.registerStrategies()
+	return p
+}
+
+// useEmbeddingService enables semantic chunking against the embedding service at url
+func (p *DocumentProcessor) useEmbeddingService(url string) {
+	p.embedder = newEmbeddingClient(url)
+}
+
+// chunk is a piece of a document plus any structure-specific metadata
+type chunk struct {
+	Content  string
+	Metadata map[string]string
 }
 
-// ChunkDocument splits a document into smaller chunks
+// chunkOptions are the per-request chunking parameters
+type chunkOptions struct {
+	maxSize    int
+	overlap    chunkOverlap
+	strategy   string            // overrides the extension mapping when set
+	strategies map[string]string // file extension -> strategy
+	tokenLimit int               // embedding model input limit, 0 for none
+	cleaning   *CleaningOptions  // overrides the strategy's cleaning steps
+}
+
+// ChunkDocument splits a document into smaller chunks using the strategy
+// configured for its extension
 func (p *DocumentProcessor) ChunkDocument(ctx context.Context, fileChange *models.FileChange, maxSize, overlap int) ([]*models.Document, error) {
-	content := p.CleanContent(fileChange.Content)
+	documents, _, err := p.chunkFile(ctx, fileChange, chunkOptions{maxSize: maxSize, overlap: overlapFromChars(overlap)})
+	return documents, err
+}
+
+// chunkFile splits a document according to opts. Files recognised as
+// generated, minified, or vendored produce no documents and a skip reason.
+func (p *DocumentProcessor) chunkFile(ctx context.Context, fileChange *models.FileChange, opts chunkOptions) ([]*models.Document, string, error) {
+	raw, err := fileChange.RawContent()
+	if err != nil {
+		return nil, "", errors.Validation(fmt.Sprintf("invalid %s content for %s", fileChange.Encoding, fileChange.FilePath))
+	}
 
-	if len(content) == 0 {
-		return []*models.Document{}, nil
+	ext := filepath.Ext(fileChange.FilePath)
+	name := p.resolveStrategy(ext, opts.strategy, opts.strategies)
+	chunkFn, ok := p.strategies[name]
+	if !ok {
+		return nil, "", errors.Validation(fmt.Sprintf("unknown chunking strategy %q", name))
 	}
 
-	var chunks []string
+	// Normalize to UTF-8 before any cleaning so non-UTF-8 text is not mangled
+	content, charset := decodeText(raw)
+
+	if p.skipGenerated {
+		if reason := skipReason(fileChange.FilePath, content); reason != "" {
+			logger.Debug("Skipping %s: %s", fileChange.FilePath, reason)
+			return []*models.Document{}, reason, nil
+		}
+	}
+
+	// License boilerplate would otherwise make every file's first chunk alike
+	var license string
+	if p.stripLicenses && !proseExts[strings.ToLower(ext)] {
+		content, license = stripLicenseHeader(content)
+	}
 
-	// Simple sentence-aware chunking
-	if len(content) <= maxSize {
-		chunks = []string{content}
-	} else {
-		chunks = p.splitIntoChunks(content, maxSize, overlap)
+	in := &chunkInput{
+		path:     fileChange.FilePath,
+		ext:      ext,
+		content:  content,
+		raw:      raw,
+		maxSize:  opts.maxSize,
+		overlap:  opts.overlap,
+		cleaning: p.resolveCleaning(name, opts.cleaning),
 	}
+	chunks, docMeta, err := chunkFn(ctx, in) // docMeta applies to every chunk
+	if err != nil || chunks == nil {
+		if err != nil {
+			logger.Debug("Falling back to plain chunking for %s (%s): %v", fileChange.FilePath, name, err)
+		}
+		name = StrategyPlain
+		in.cleaning = p.resolveCleaning(name, opts.cleaning)
+		chunks, docMeta, _ = p.chunkPlainStrategy(ctx, in)
+	}
+	// The embedding service would silently truncate anything longer
+	chunks = p.enforceTokenLimit(chunks, opts.tokenLimit)
+
+	if len(chunks) == 0 || (len(chunks) == 1 && chunks[0].Content == "") {
+		return []*models.Document{}, "", nil
+	}
+	language := detectLanguage(fileChange.FilePath, in.content)
+	seen := make(map[string]int, len(chunks)) // occurrences of each content hash in this file
+
+	// Frontmatter and license headers are stored alongside every chunk, so
+	// they are masked like the text
+	docMeta, docRedacted := p.redactor.RedactValues(docMeta)
+	license, licenseRedacted := p.redactor.Redact(license)
+	docRedacted += licenseRedacted
 
 	// Create documents
 	documents := make([]*models.Document, len(chunks))
-	for i, chunk := range chunks {
-		docID := fmt.Sprintf("%x", md5.Sum([]byte(fmt.Sprintf("%s-%s-%d", fileChange.Repository, fileChange.FilePath, i))))
+	for i, c := range chunks {
+		text, redacted := p.redactor.Redact(c.Content)
+		chunkMeta, metaRedacted := p.redactor.RedactValues(c.Metadata)
+		redacted += metaRedacted + docRedacted
+		hash := contentHash(text)
+
+		docID := chunkID(fileChange, hash, seen[hash])
+		seen[hash]++
 
 		documents[i] = &models.Document{
 			ID:           docID,
 			Repository:   fileChange.Repository,
 			FilePath:     fileChange.FilePath,
-			Content:      chunk,
+			Content:      text,
 			ChunkIndex:   i,
 			TotalChunks:  len(chunks),
 			CommitSHA:    fileChange.CommitSHA,
 			LastModified: fileChange.LastModified,
 			Metadata: map[string]string{
-				"repository":   fileChange.Repository,
-				"file_path":    fileChange.FilePath,
-				"commit_sha":   fileChange.CommitSHA,
-				"chunk_index":  fmt.Sprintf("%d", i),
-				"total_chunks": fmt.Sprintf("%d", len(chunks)),
-				"file_ext":     filepath.Ext(fileChange.FilePath),
+				"repository":     fileChange.Repository,
+				"file_path":      fileChange.FilePath,
+				"commit_sha":     fileChange.CommitSHA,
+				"chunk_index":    fmt.Sprintf("%d", i),
+				"total_chunks":   fmt.Sprintf("%d", len(chunks)),
+				"file_ext":       ext,
+				"content_hash":   hash,
+				"chunk_strategy": name,
 			},
 		}
+		if fileChange.Source != "" {
+			documents[i].Metadata["source"] = fileChange.Source
+		}
+		if fileChange.Branch != "" {
+			documents[i].Metadata["branch"] = fileChange.Branch
+		}
+		if language != "" {
+			documents[i].Metadata["language"] = language
+		}
+		if license != "" {
+			documents[i].Metadata["license"] = license
+		}
+		if charset != charsetUTF8 {
+			documents[i].Metadata["source_charset"] = charset
+		}
+		for k, v := range chunkMeta {
+			documents[i].Metadata[k] = v
+		}
+		if redacted > 0 {
+			documents[i].Metadata["pii_redacted"] = fmt.Sprintf("%d", redacted)
+		}
+		for k, v := range docMeta {
+			// Never let document-supplied keys override the pipeline's own
+			if _, exists := documents[i].Metadata[k]; !exists {
+				documents[i].Metadata[k] = v
+			}
+		}
 	}
 
 	logger.Debug("Split %s into %d chunks", fileChange.FilePath, len(documents))
-	return documents, nil
+	return documents, "", nil
 }
 
-// splitIntoChunks splits text into chunks with overlap
-func (p *DocumentProcessor) splitIntoChunks(text string, maxSize, overlap int) []string {
+// splitIntoChunks splits text into chunks with overlap, breaking at
+// sentence ends or word boundaries and never inside a UTF-8 character
+func (p *DocumentProcessor) splitIntoChunks(text string, maxSize int, overlap chunkOverlap) []string {
 	var chunks []string
 	start := 0
 	textLen := len(text)
 
 	for start < textLen {
-		end := start + maxSize
-		if end > textLen {
-			end = textLen
-		}
-
-		// Try to break at sentence boundary
-		if end < textLen {
-			// Look for sentence endings
-			lastPeriod := strings.LastIndexAny(text[start:end], ".!?\n")
-			if lastPeriod > maxSize/2 { // Only break if we're past halfway
-				end = start + lastPeriod + 1
-			}
-		}
+		end := chunkEnd(text, start, maxSize)
 
 		chunk := strings.TrimSpace(text[start:end])
 		if len(chunk) > 0 {
 			chunks = append(chunks, chunk)
 		}
+		if end >= textLen {
+			break
+		}
 
-		// Move start position with overlap
-		start = end - overlap
-		if start < 0 {
-			start = 0
+		// Move start position with overlap, always making progress
+		next := overlapStart(text, start, end, overlap)
+		if next <= start {
+			next = end
 		}
+		start = next
 	}
 
 	return chunks
 }
 
-// ValidateDocument checks if document should be processed
-func (p *DocumentProcessor) ValidateDocument(fileChange *models.FileChange, allowedExtensions []string, excludePatterns []string) bool {
-	// Check file extension
-	ext := filepath.Ext(fileChange.FilePath)
-	if len(allowedExtensions) > 0 {
-		found := false
-		for _, allowed := range allowedExtensions {
-			if ext == allowed {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
+// codeSegment is a run of source code and the symbols it defines
+type codeSegment struct {
+	text    string
+	symbols []string
+}
+
+// packSegments joins consecutive, already cleaned source segments into chunks of up to
+// maxSize, splitting a segment by lines only when it is too large on its own.
+// Chunks list the symbols defined in them as comma-separated "symbols"
+// metadata.
+func (p *DocumentProcessor) packSegments(segments []codeSegment, maxSize int, overlap chunkOverlap) []chunk {
+	var chunks []chunk
+	var current strings.Builder
+	var symbols []string
+	flush := func() {
+		// Trim blank lines only; leading indentation is part of the code
+		if text := strings.Trim(current.String(), "\n"); strings.TrimSpace(text) != "" {
+			chunks = append(chunks, newCodeChunk(text, symbols))
 		}
+		current.Reset()
+		symbols = nil
 	}
 
-	// Check exclude patterns
-	for _, pattern := range excludePatterns {
-		if strings.Contains(fileChange.FilePath, pattern) {
-			return false
+	for _, segment := range segments {
+		text := segment.text
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if len(text) > maxSize {
+			flush()
+			for _, part := range p.splitIntoChunks(text, maxSize, overlap) {
+				chunks = append(chunks, newCodeChunk(part, segment.symbols))
+			}
+			continue
 		}
+		if current.Len()+len(text) > maxSize {
+			flush()
+		}
+		current.WriteString(text)
+		symbols = append(symbols, segment.symbols...)
 	}
+	flush()
+
+	return chunks
+}
 
-	// Check if file is deleted
-	if fileChange.ChangeType == "deleted" || fileChange.ChangeType == "removed" {
-		return false
+func newCodeChunk(text string, symbols []string) chunk {
+	c := chunk{Content: text}
+	if len(symbols) > 0 {
+		c.Metadata = map[string]string{"symbols": strings.Join(uniqueStrings(symbols), ",")}
 	}
+	return c
+}
 
-	return true
+// uniqueStrings drops repeated values, keeping first occurrences in order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := values[:0:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
 }
 
-// CleanContent cleans and normalizes document content
-func (p *DocumentProcessor) CleanContent(content string) string {
-	// Remove excessive whitespace
-	lines := strings.Split(content, "\n")
-	var cleaned []string
+// packBlocks cleans text blocks and packs them into chunks of up to maxSize.
+// Prose is split at sentence boundaries; a fenced code block either fits in a
+// chunk or becomes a chunk of its own, keeping its indentation, and tables
+// become dedicated chunks of linearized rows.
+func (p *DocumentProcessor) packBlocks(blocks []textBlock, maxSize int, overlap chunkOverlap, clean cleanOptions) []chunk {
+	chunks := []chunk{}
+	var current strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			chunks = append(chunks, chunk{Content: text})
+		}
+		current.Reset()
+	}
+	add := func(text string) {
+		if current.Len() > 0 && current.Len()+len(text)+1 > maxSize {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n")
+		}
+		current.WriteString(text)
+	}
 
-	for _, line := range lines {
-		// Trim whitespace
-		line = strings.TrimSpace(line)
+	for _, block := range blocks {
+		if block.atomic {
+			if tables := parseTables(block.text); tables != nil {
+				flush()
+				chunks = append(chunks, tableChunks(tables, maxSize, nil)...)
+				continue
+			}
+			text := strings.Trim(cleanPreformatted(block.text), "\n")
+			if len(text) > maxSize {
+				flush()
+				chunks = append(chunks, chunk{Content: text})
+				continue
+			}
+			add(text)
+			continue
+		}
 
-		// Skip empty lines
-		if len(line) == 0 {
+		text := strings.Trim(cleanText(block.text, clean), "\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if len(text) <= maxSize {
+			add(text)
 			continue
 		}
+		// Split pieces overlap, so only the last one may share a chunk
+		pieces := p.splitIntoChunks(text, maxSize, overlap)
+		flush()
+		for _, piece := range pieces[:len(pieces)-1] {
+			chunks = append(chunks, chunk{Content: piece})
+		}
+		current.WriteString(pieces[len(pieces)-1])
+	}
+	flush()
 
-		// Remove control characters except newlines and tabs
-		line = strings.Map(func(r rune) rune {
-			if r == '\t' || unicode.IsPrint(r) {
-				return r
-			}
-			return -1
-		}, line)
+	return chunks
+}
+
+// ValidateDocument checks if document should be processed
+func (p *DocumentProcessor) ValidateDocument(fileChange *models.FileChange, allowedExtensions []string, excludePatterns []string) bool {
+	return filter.Check(fileChange, filter.Rules{AllowedExtensions: allowedExtensions, ExcludePatterns: excludePatterns}).Accepted()
+}
+
+// CleanContent cleans and normalizes document content
+func (p *DocumentProcessor) CleanContent(content string) string {
+	return cleanText(content, proseCleaning)
+}
 
-		cleaned = append(cleaned, line)
+// chunkID derives a chunk's vector ID from its file and content hash rather
+// than its position, so a chunk that moves within its file keeps its ID and
+// its stored vector stays valid. occurrence counts earlier chunks of the file
+// with the same hash, so repeated content still gets distinct IDs.
+func chunkID(fileChange *models.FileChange, hash string, occurrence int) string {
+	docKey := fmt.Sprintf("%s-%s-%s", fileChange.Repository, fileChange.FilePath, hash)
+	if occurrence > 0 {
+		docKey = fmt.Sprintf("%s-%d", docKey, occurrence)
 	}
+	if fileChange.Source != "" {
+		// Keep non-file sources (issues, discussions, ...) from colliding with repository paths
+		docKey = fileChange.Source + ":" + docKey
+	}
+	return fmt.Sprintf("%x", md5.Sum([]byte(docKey)))
+}
 
-	return strings.Join(cleaned, "\n")
+// contentHash hashes chunk text with whitespace normalized, so copies that
+// differ only in indentation or line endings are recognised as duplicates
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// cleanPreformatted removes control characters and trailing whitespace while
+// keeping indentation and blank lines, which carry meaning in code and Markdown
+func cleanPreformatted(content string) string {
+	return cleanText(content, preformattedCleaning)
 }
 
 // HTTP Handlers
 type ChunkRequest struct {
-	FileChange   *models.FileChange `json:"file_change"`
-	MaxChunkSize int                `json:"max_chunk_size,omitempty"`
-	ChunkOverlap int                `json:"chunk_overlap,omitempty"`
+	FileChange       *models.FileChange `json:"file_change"`
+	MaxChunkSize     int                `json:"max_chunk_size,omitempty"`
+	ChunkOverlap     int                `json:"chunk_overlap,omitempty"` // characters, converted to whole tokens
+	OverlapTokens    int                `json:"overlap_tokens,omitempty"`
+	OverlapSentences int                `json:"overlap_sentences,omitempty"` // wins over token overlap
+	Strategy         string             `json:"strategy,omitempty"`          // overrides the extension mapping
+	Strategies       map[string]string  `json:"strategies,omitempty"`        // file extension -> strategy
+	Model            string             `json:"model,omitempty"`             // embedding model whose token limit chunks must fit
+	MaxTokens        int                `json:"max_tokens,omitempty"`        // overrides the model's limit
+	Cleaning         *CleaningOptions   `json:"cleaning,omitempty"`          // overrides individual cleaning steps
 }
 
 type ChunkResponse struct {
 	Documents []*models.Document `json:"documents"`
 	Count     int                `json:"count"`
+	Skipped   string             `json:"skipped,omitempty"` // why the file was not chunked
+}
+
+type BatchChunkRequest struct {
+	FileChanges      []*models.FileChange `json:"file_changes"`
+	MaxChunkSize     int                  `json:"max_chunk_size,omitempty"`
+	ChunkOverlap     int                  `json:"chunk_overlap,omitempty"`
+	OverlapTokens    int                  `json:"overlap_tokens,omitempty"`
+	OverlapSentences int                  `json:"overlap_sentences,omitempty"`
+	Strategy         string               `json:"strategy,omitempty"`
+	Strategies       map[string]string    `json:"strategies,omitempty"`
+	Model            string               `json:"model,omitempty"`
+	MaxTokens        int                  `json:"max_tokens,omitempty"`
+	Cleaning         *CleaningOptions     `json:"cleaning,omitempty"`
+}
+
+// BatchChunkResult holds one file's documents, in request order
+type BatchChunkResult struct {
+	Repository string             `json:"repository"`
+	FilePath   string             `json:"file_path"`
+	Documents  []*models.Document `json:"documents"`
+	Count      int                `json:"count"`
+	Skipped    string             `json:"skipped,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+type BatchChunkResponse struct {
+	Results []*BatchChunkResult `json:"results"`
+	Count   int                 `json:"count"` // total documents across files
+}
+
+// chunkOptions resolves per-request chunking parameters against the service defaults
+func (p *DocumentProcessor) chunkOptions(maxSize int, overlap chunkOverlap, strategy string, strategies map[string]string) chunkOptions {
+	if maxSize == 0 {
+		maxSize = p.maxChunkSize
+	}
+	return chunkOptions{maxSize: maxSize, overlap: overlap, strategy: strategy, strategies: strategies}
 }
 
 func (p *DocumentProcessor) handleChunk(w http.ResponseWriter, r *http.Request) {
@@ -200,17 +483,21 @@ func (p *DocumentProcessor) handleChunk(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	maxSize := req.MaxChunkSize
-	if maxSize == 0 {
-		maxSize = p.maxChunkSize
+	if err := p.validateStrategies(req.Strategy, req.Strategies); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-
-	overlap := req.ChunkOverlap
-	if overlap == 0 {
-		overlap = p.chunkOverlap
+	tokenLimit, err := modelTokenLimit(req.Model, req.MaxTokens)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	documents, err := p.ChunkDocument(r.Context(), req.FileChange, maxSize, overlap)
+	opts := p.chunkOptions(req.MaxChunkSize, p.resolveOverlap(req.ChunkOverlap, req.OverlapTokens, req.OverlapSentences), req.Strategy, req.Strategies)
+	opts.tokenLimit = tokenLimit
+	opts.cleaning = req.Cleaning
+
+	documents, skipped, err := p.chunkFile(r.Context(), req.FileChange, opts)
 	if err != nil {
 		logger.Error("Failed to chunk document: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -220,6 +507,61 @@ func (p *DocumentProcessor) handleChunk(w http.ResponseWriter, r *http.Request)
 	resp := ChunkResponse{
 		Documents: documents,
 		Count:     len(documents),
+		Skipped:   skipped,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handleBatchChunk chunks many files in one round-trip. A file that fails
+// is reported in its result rather than failing the whole batch.
+func (p *DocumentProcessor) handleBatchChunk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BatchChunkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := p.validateStrategies(req.Strategy, req.Strategies); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tokenLimit, err := modelTokenLimit(req.Model, req.MaxTokens)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := p.chunkOptions(req.MaxChunkSize, p.resolveOverlap(req.ChunkOverlap, req.OverlapTokens, req.OverlapSentences), req.Strategy, req.Strategies)
+	opts.tokenLimit = tokenLimit
+	opts.cleaning = req.Cleaning
+
+	resp := BatchChunkResponse{Results: make([]*BatchChunkResult, 0, len(req.FileChanges))}
+	for _, fileChange := range req.FileChanges {
+		if fileChange == nil {
+			// Keep results aligned with the request
+			resp.Results = append(resp.Results, &BatchChunkResult{Documents: []*models.Document{}, Error: "missing file change"})
+			continue
+		}
+		result := &BatchChunkResult{Repository: fileChange.Repository, FilePath: fileChange.FilePath}
+
+		documents, skipped, err := p.chunkFile(r.Context(), fileChange, opts)
+		result.Skipped = skipped
+		if err != nil {
+			logger.Error("Failed to chunk document %s: %v", fileChange.FilePath, err)
+			result.Error = err.Error()
+			documents = []*models.Document{}
+		}
+		result.Documents = documents
+		result.Count = len(documents)
+		resp.Count += len(documents)
+		resp.Results = append(resp.Results, result)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -230,7 +572,7 @@ func (p *DocumentProcessor) handleHealth(w http.ResponseWriter, r *http.Request)
 	_ = json.NewEncoder(w).Encode(map[string]string{
 		"status":         "healthy",
 		"max_chunk_size": fmt.Sprintf("%d", p.maxChunkSize),
-		"chunk_overlap":  fmt.Sprintf("%d", p.chunkOverlap),
+		"chunk_overlap":  p.overlap.String(),
 	})
 }
 
@@ -251,12 +593,34 @@ func main() {
 	logger.Info("Starting Document Processor Service on port %d", cfg.Services.DocumentProcessorPort)
 
 	// Create document processor
-	service := NewDocumentProcessor(cfg.Processing.MaxChunkSize, cfg.Processing.ChunkOverlap)
+	redactor, err := NewPIIRedactor(cfg.Processing.PIIRedaction, cfg.Processing.PIIMaskMode)
+	if err != nil {
+		logger.Fatal("Invalid PII redaction configuration: %v", err)
+	}
+	if redactor != nil {
+		logger.Info("PII redaction enabled for %v (%s masking)", cfg.Processing.PIIRedaction, cfg.Processing.PIIMaskMode)
+	}
+
+	service := NewDocumentProcessor(cfg.Processing.MaxChunkSize, cfg.Processing.ChunkOverlap, cfg.Processing.CSVRowsPerChunk, cfg.Processing.ChunkStrategies, cfg.Processing.SkipGeneratedFiles, cfg.Processing.StripLicenseHeaders, redactor)
+	if err := service.validateStrategies("", cfg.Processing.ChunkStrategies); err != nil {
+		logger.Fatal("Invalid CHUNK_STRATEGIES: %v", err)
+	}
+	if err := service.configureCleaning(cfg.Processing.ChunkCleaning); err != nil {
+		logger.Fatal("Invalid CHUNK_CLEANING: %v", err)
+	}
+	service.configureOverlap(cfg.Processing.ChunkOverlapTokens, cfg.Processing.ChunkOverlapSentences)
+	service.useEmbeddingService(getServiceURL("EMBEDDING_SERVICE_URL", "http://localhost:8083"))
+	service.useProjectRules(getServiceURL("METADATA_SERVICE_URL", "http://localhost:8086"), cfg.Database.APIKey, filter.Rules{
+		AllowedExtensions: cfg.Processing.AllowedExtensions,
+		ExcludePatterns:   cfg.Processing.ExcludePatterns,
+	})
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", service.handleHealth)
 	mux.HandleFunc("/chunk", service.handleChunk)
+	mux.HandleFunc("/chunk/batch", service.handleBatchChunk)
+	mux.HandleFunc("/validate", service.handleValidate)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Services.DocumentProcessorPort),
@@ -284,3 +648,10 @@ func main() {
 		logger.Fatal("Failed to start server: %v", err)
 	}
 }
+
+func getServiceURL(envVar, defaultURL string) string {
+	if url := os.Getenv(envVar); url != "" {
+		return url
+	}
+	return defaultURL
+}
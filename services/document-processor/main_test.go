@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestChunkID(t *testing.T) {
+	file := &models.FileChange{Repository: "org/repo", FilePath: "docs/guide.md"}
+	hash := contentHash("Install the CLI, then run sync.")
+
+	tests := []struct {
+		name       string
+		a, b       *models.FileChange
+		hashA      string
+		hashB      string
+		occurrence [2]int
+		wantSame   bool
+	}{
+		{
+			name:     "same file and content is stable",
+			a:        file,
+			b:        &models.FileChange{Repository: "org/repo", FilePath: "docs/guide.md", CommitSHA: "other"},
+			hashA:    hash,
+			hashB:    hash,
+			wantSame: true,
+		},
+		{
+			name:     "whitespace-only edits keep the ID",
+			a:        file,
+			b:        file,
+			hashA:    hash,
+			hashB:    contentHash("Install  the CLI,\nthen run sync.  "),
+			wantSame: true,
+		},
+		{
+			name:  "edited content gets a new ID",
+			a:     file,
+			b:     file,
+			hashA: hash,
+			hashB: contentHash("Install the CLI, then run a full sync."),
+		},
+		{
+			name:       "repeated content in one file is distinct",
+			a:          file,
+			b:          file,
+			hashA:      hash,
+			hashB:      hash,
+			occurrence: [2]int{0, 1},
+		},
+		{
+			name:  "same content in another file is distinct",
+			a:     file,
+			b:     &models.FileChange{Repository: "org/repo", FilePath: "docs/other.md"},
+			hashA: hash,
+			hashB: hash,
+		},
+		{
+			name:  "same path in another repository is distinct",
+			a:     file,
+			b:     &models.FileChange{Repository: "org/fork", FilePath: "docs/guide.md"},
+			hashA: hash,
+			hashB: hash,
+		},
+		{
+			name:  "non-file sources do not collide with paths",
+			a:     file,
+			b:     &models.FileChange{Repository: "org/repo", FilePath: "docs/guide.md", Source: "wiki"},
+			hashA: hash,
+			hashB: hash,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := chunkID(tt.a, tt.hashA, tt.occurrence[0])
+			b := chunkID(tt.b, tt.hashB, tt.occurrence[1])
+
+			if len(a) != 32 {
+				t.Errorf("chunkID = %q, want a 32-character hex MD5", a)
+			}
+			if (a == b) != tt.wantSame {
+				t.Errorf("IDs %s and %s: same = %v, want %v", a, b, a == b, tt.wantSame)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	atxHeadingRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextH1Re   = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+	setextH2Re   = regexp.MustCompile(`^ {0,3}-+[ \t]*$`)
+	fenceOpenRe  = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	markdownExts = map[string]bool{".md": true, ".markdown": true, ".mdx": true}
+)
+
+// isMarkdown reports whether a file extension is chunked as Markdown
+func isMarkdown(ext string) bool {
+	return markdownExts[strings.ToLower(ext)]
+}
+
+// markdownSection is the content under one heading
+type markdownSection struct {
+	path    []string // heading titles from the top level down
+	heading string   // heading line(s) as written
+	blocks  []string
+}
+
+// chunkMarkdown splits cleaned Markdown along its heading hierarchy. Each chunk stays
+// within one section and records the heading path (e.g. "Install > Docker").
+// Fenced code blocks are never split, even when larger than maxSize, and
+// tables become dedicated chunks of linearized rows.
+func (p *DocumentProcessor) chunkMarkdown(src string, maxSize int, overlap chunkOverlap) []chunk {
+	var chunks []chunk
+	for _, section := range parseMarkdownSections(src) {
+		headingPath := strings.Join(section.path, " > ")
+		var tableMeta map[string]string
+		if headingPath != "" {
+			tableMeta = map[string]string{"heading_path": headingPath}
+		}
+
+		var current strings.Builder
+		flush := func() {
+			if text := strings.TrimSpace(current.String()); text != "" {
+				chunks = append(chunks, newMarkdownChunk(text, headingPath))
+			}
+			current.Reset()
+		}
+
+		blocks := section.blocks
+		if section.heading != "" {
+			blocks = append([]string{section.heading}, blocks...)
+		}
+
+		for _, block := range blocks {
+			if tables := parseTables(block); tables != nil {
+				if current.String() == section.heading {
+					// The table chunks carry the heading path already
+					current.Reset()
+				}
+				flush()
+				chunks = append(chunks, tableChunks(tables, maxSize, tableMeta)...)
+				continue
+			}
+			if current.Len() > 0 && current.Len()+len(block)+2 > maxSize {
+				flush()
+			}
+			if len(block) > maxSize && !isAtomicMarkdownBlock(block) {
+				flush()
+				for _, part := range p.splitIntoChunks(block, maxSize, overlap) {
+					chunks = append(chunks, newMarkdownChunk(part, headingPath))
+				}
+				continue
+			}
+			if current.Len() > 0 {
+				current.WriteString("\n\n")
+			}
+			current.WriteString(block)
+		}
+		flush()
+	}
+	return chunks
+}
+
+func newMarkdownChunk(text, headingPath string) chunk {
+	c := chunk{Content: text}
+	if headingPath != "" {
+		c.Metadata = map[string]string{"heading_path": headingPath}
+	}
+	return c
+}
+
+// parseMarkdownSections groups blank-line separated blocks under their
+// headings, treating fenced code blocks and tables as single blocks. Sections
+// without body content are dropped; their titles live on in child heading paths.
+func parseMarkdownSections(src string) []markdownSection {
+	lines := strings.Split(src, "\n")
+
+	var sections []markdownSection
+	var headings []string // indexed by level-1
+	current := markdownSection{}
+	var block []string
+	var fence string
+	inTable := false
+
+	flushBlock := func() {
+		if text := strings.TrimSpace(strings.Join(block, "\n")); text != "" {
+			current.blocks = append(current.blocks, strings.Trim(strings.Join(block, "\n"), "\n"))
+		}
+		block = nil
+		inTable = false
+	}
+	startSection := func(level int, title, heading string) {
+		flushBlock()
+		if len(current.blocks) > 0 {
+			sections = append(sections, current)
+		}
+		if len(headings) >= level {
+			headings = headings[:level-1]
+		}
+		for len(headings) < level-1 {
+			headings = append(headings, "")
+		}
+		headings = append(headings, title)
+
+		path := make([]string, 0, len(headings))
+		for _, h := range headings {
+			if h != "" {
+				path = append(path, h)
+			}
+		}
+		current = markdownSection{path: path, heading: heading}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if fence != "" {
+			block = append(block, line)
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+				flushBlock()
+			}
+			continue
+		}
+
+		if m := fenceOpenRe.FindStringSubmatch(line); m != nil {
+			flushBlock()
+			fence = m[1]
+			block = append(block, line)
+			continue
+		}
+
+		// HTML tables may contain blank lines but are still one block
+		if end := htmlTableEnd(lines, i); end > i {
+			flushBlock()
+			block = lines[i:end]
+			flushBlock()
+			i = end - 1
+			continue
+		}
+
+		if m := atxHeadingRe.FindStringSubmatch(line); m != nil {
+			startSection(len(m[1]), strings.TrimSpace(m[2]), line)
+			continue
+		}
+
+		// Setext headings underline a single paragraph line
+		if i+1 < len(lines) && strings.TrimSpace(line) != "" && len(block) == 0 && !isTableLine(line) {
+			level := 0
+			if setextH1Re.MatchString(lines[i+1]) {
+				level = 1
+			} else if setextH2Re.MatchString(lines[i+1]) {
+				level = 2
+			}
+			if level > 0 {
+				startSection(level, strings.TrimSpace(line), line+"\n"+lines[i+1])
+				i++
+				continue
+			}
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flushBlock()
+			continue
+		}
+
+		// Tables become their own block even without surrounding blank lines
+		if isTableLine(line) != inTable {
+			flushBlock()
+			inTable = isTableLine(line)
+		}
+		block = append(block, line)
+	}
+	flushBlock()
+	if len(current.blocks) > 0 {
+		sections = append(sections, current)
+	}
+
+	return sections
+}
+
+// isTableLine reports whether a line looks like a Markdown table row
+func isTableLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "|") && strings.Count(trimmed, "|") >= 2
+}
+
+// textBlock is a run of prose, or a fenced code block or table that must be
+// kept whole
+type textBlock struct {
+	text   string
+	atomic bool
+}
+
+// splitAtomicBlocks separates fenced code blocks, HTML tables, and pipe
+// tables (two or more rows) from the prose around them. An unterminated fence stays prose
+// so a stray backtick run cannot swallow the rest of the file.
+func splitAtomicBlocks(src string) []textBlock {
+	lines := strings.Split(src, "\n")
+
+	var blocks []textBlock
+	var prose []string
+	flushProse := func() {
+		if text := strings.Join(prose, "\n"); strings.TrimSpace(text) != "" {
+			blocks = append(blocks, textBlock{text: text})
+		}
+		prose = nil
+	}
+	addAtomic := func(from, to int) {
+		flushProse()
+		blocks = append(blocks, textBlock{text: strings.Join(lines[from:to], "\n"), atomic: true})
+	}
+
+	for i := 0; i < len(lines); i++ {
+		if end := htmlTableEnd(lines, i); end > i {
+			addAtomic(i, end)
+			i = end - 1
+			continue
+		}
+		if m := fenceOpenRe.FindStringSubmatch(lines[i]); m != nil {
+			end := -1
+			for j := i + 1; j < len(lines); j++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[j]), m[1]) {
+					end = j
+					break
+				}
+			}
+			if end >= 0 {
+				addAtomic(i, end+1)
+				i = end
+				continue
+			}
+		}
+		if isTableLine(lines[i]) && i+1 < len(lines) && isTableLine(lines[i+1]) {
+			end := i + 1
+			for end < len(lines) && isTableLine(lines[end]) {
+				end++
+			}
+			addAtomic(i, end)
+			i = end - 1
+			continue
+		}
+		prose = append(prose, lines[i])
+	}
+	flushProse()
+	return blocks
+}
+
+// htmlTableEnd returns the index just past the HTML table opening on line
+// start, or start if no table opens there or it is never closed
+func htmlTableEnd(lines []string, start int) int {
+	if !htmlTableOpenRe.MatchString(lines[start]) {
+		return start
+	}
+	for j := start; j < len(lines); j++ {
+		if htmlTableCloseRe.MatchString(lines[j]) {
+			return j + 1
+		}
+	}
+	return start
+}
+
+// isAtomicMarkdownBlock reports whether a block must not be split
+func isAtomicMarkdownBlock(block string) bool {
+	return fenceOpenRe.MatchString(block) || isTableLine(strings.SplitN(block, "\n", 2)[0]) ||
+		htmlTableOpenRe.MatchString(block)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunkMarkdown(t *testing.T) {
+	type want struct {
+		content string
+		meta    map[string]string
+	}
+	path := func(p string) map[string]string { return map[string]string{"heading_path": p} }
+
+	tests := []struct {
+		name    string
+		src     string
+		maxSize int
+		want    []want
+	}{
+		{
+			name:    "heading hierarchy",
+			src:     "# Guide\n\nIntro.\n\n## Install\n\n### Docker\n\nRun it.\n\n## Usage ##\n\nCall it.",
+			maxSize: 1000,
+			want: []want{
+				{"# Guide\n\nIntro.", path("Guide")},
+				{"### Docker\n\nRun it.", path("Guide > Install > Docker")},
+				{"## Usage ##\n\nCall it.", path("Guide > Usage")},
+			},
+		},
+		{
+			name:    "setext headings",
+			src:     "Title\n=====\n\nBody.\n\nPart\n----\n\nMore.",
+			maxSize: 1000,
+			want: []want{
+				{"Title\n=====\n\nBody.", path("Title")},
+				{"Part\n----\n\nMore.", path("Title > Part")},
+			},
+		},
+		{
+			name:    "fenced code is kept whole and hides headings",
+			src:     "## Build\n\n```sh\n# not a heading\nmake all\nmake install\n```",
+			maxSize: 20,
+			want: []want{
+				{"## Build", path("Build")},
+				{"```sh\n# not a heading\nmake all\nmake install\n```", path("Build")},
+			},
+		},
+		{
+			name:    "text before the first heading has no path",
+			src:     "Preface.\n\n# Start\n\nGo.",
+			maxSize: 1000,
+			want: []want{
+				{"Preface.", nil},
+				{"# Start\n\nGo.", path("Start")},
+			},
+		},
+		{
+			name:    "tables become linearized chunks",
+			src:     "# Limits\n\n| Plan | Quota |\n|------|-------|\n| Free | 10 |\n| Pro | 100 |",
+			maxSize: 1000,
+			want: []want{
+				{"Plan: Free; Quota: 10\nPlan: Pro; Quota: 100", map[string]string{
+					"heading_path": "Limits", "chunk_type": "table", "row_start": "1", "row_end": "2", "table_columns": "Plan, Quota",
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := newTestProcessor().chunkMarkdown(tt.src, tt.maxSize, chunkOverlap{})
+			got := make([]want, len(chunks))
+			for i, c := range chunks {
+				got[i] = want{c.Content, c.Metadata}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMarkdown(t *testing.T) {
+	for ext, want := range map[string]bool{".md": true, ".MDX": true, ".markdown": true, ".rst": false, "": false} {
+		if got := isMarkdown(ext); got != want {
+			t.Errorf("isMarkdown(%q) = %v, want %v", ext, got, want)
+		}
+	}
+}
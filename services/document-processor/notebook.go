@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// notebook is the subset of the Jupyter nbformat 4 schema needed for text extraction
+type notebook struct {
+	Cells []struct {
+		CellType string          `json:"cell_type"`
+		Source   json.RawMessage `json:"source"`
+	} `json:"cells"`
+	Metadata struct {
+		KernelSpec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+// extractNotebook converts a Jupyter notebook into Markdown: markdown cells
+// verbatim and code cells as fenced blocks, in order. Outputs (including
+// base64-encoded images) are dropped.
+func extractNotebook(content string) (string, error) {
+	var nb notebook
+	if err := json.Unmarshal([]byte(content), &nb); err != nil {
+		return "", fmt.Errorf("invalid notebook: %w", err)
+	}
+
+	language := nb.Metadata.LanguageInfo.Name
+	if language == "" {
+		language = nb.Metadata.KernelSpec.Language
+	}
+
+	var parts []string
+	for _, cell := range nb.Cells {
+		source, err := cellSource(cell.Source)
+		if err != nil {
+			return "", fmt.Errorf("invalid notebook cell: %w", err)
+		}
+		source = strings.Trim(source, "\n")
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+
+		switch cell.CellType {
+		case "code":
+			parts = append(parts, fmt.Sprintf("```%s\n%s\n```", language, source))
+		case "markdown", "raw":
+			parts = append(parts, source)
+		}
+	}
+
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// cellSource decodes a cell source, which nbformat allows as a string or a list of lines
+func cellSource(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err == nil {
+		return strings.Join(lines, ""), nil
+	}
+
+	var source string
+	if err := json.Unmarshal(raw, &source); err != nil {
+		return "", err
+	}
+	return source, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestExtractNotebook(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "cells in order with the language_info fence",
+			content: `{"cells": [
+				{"cell_type": "markdown", "source": ["# Analysis\n", "Load the data."]},
+				{"cell_type": "code", "source": "import pandas as pd\n", "outputs": [{"data": {"image/png": "iVBORw0KGgo="}}]},
+				{"cell_type": "raw", "source": "raw text"}
+			], "metadata": {"language_info": {"name": "python"}, "kernelspec": {"language": "R"}}}`,
+			want: "# Analysis\nLoad the data.\n\n```python\nimport pandas as pd\n```\n\nraw text",
+		},
+		{
+			name:    "kernelspec language when language_info is missing",
+			content: `{"cells": [{"cell_type": "code", "source": ["x <- 1"]}], "metadata": {"kernelspec": {"language": "R"}}}`,
+			want:    "```R\nx <- 1\n```",
+		},
+		{
+			name:    "empty and unknown cells are dropped",
+			content: `{"cells": [{"cell_type": "markdown", "source": "\n  \n"}, {"cell_type": "widget", "source": "x"}, {"cell_type": "markdown"}]}`,
+			want:    "",
+		},
+		{name: "not json", content: "{", wantErr: true},
+		{name: "invalid cell source", content: `{"cells": [{"cell_type": "code", "source": 42}]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractNotebook(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("extractNotebook() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HTTP methods that may appear in an OpenAPI path item, in display order
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Local $ref resolution stops this deep to survive recursive schemas
+const maxRefDepth = 8
+
+// isOpenAPICandidate reports whether files with ext may hold an OpenAPI spec
+func isOpenAPICandidate(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// chunkOpenAPIStrategy emits one chunk per API operation (method, path,
+// summary, parameters, request body, responses) plus one for the API
+// overview. Files that are not OpenAPI or Swagger specs yield nil so they
+// are chunked as plain text.
+func (p *DocumentProcessor) chunkOpenAPIStrategy(_ context.Context, in *chunkInput) ([]chunk, map[string]string, error) {
+	spec, ok := parseOpenAPISpec(in.ext, in.content)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	version := stringField(spec, "openapi")
+	if version == "" {
+		version = stringField(spec, "swagger")
+	}
+	docMeta := map[string]string{"openapi_version": version}
+
+	var chunks []chunk
+	if overview := openAPIOverview(spec); overview != "" {
+		chunks = append(chunks, chunk{Content: overview, Metadata: map[string]string{"chunk_type": "api_overview"}})
+	}
+
+	paths, _ := spec["paths"].(map[string]interface{})
+	pathNames := make([]string, 0, len(paths))
+	for path := range paths {
+		pathNames = append(pathNames, path)
+	}
+	sort.Strings(pathNames)
+
+	for _, path := range pathNames {
+		item, _ := resolveRef(spec, paths[path], 0).(map[string]interface{})
+		if item == nil {
+			continue
+		}
+		for _, method := range openAPIMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			meta := map[string]string{
+				"chunk_type":  "api_operation",
+				"http_method": strings.ToUpper(method),
+				"api_path":    path,
+			}
+			if id := stringField(op, "operationId"); id != "" {
+				meta["operation_id"] = id
+			}
+			if tags := stringList(op["tags"]); len(tags) > 0 {
+				meta["tags"] = strings.Join(tags, ",")
+			}
+
+			text := describeOperation(spec, path, method, item, op)
+			if len(text) <= in.maxSize {
+				chunks = append(chunks, chunk{Content: text, Metadata: meta})
+				continue
+			}
+			// Continuation parts repeat the operation line for context
+			header := strings.ToUpper(method) + " " + path
+			for i, part := range p.splitIntoChunks(text, max(in.maxSize-len(header)-1, in.maxSize/2), in.overlap) {
+				if i > 0 {
+					part = header + "\n" + part
+				}
+				chunks = append(chunks, chunk{Content: part, Metadata: meta})
+			}
+		}
+	}
+
+	return chunks, docMeta, nil
+}
+
+// parseOpenAPISpec decodes a YAML or JSON document and reports whether it
+// declares an "openapi" or "swagger" version at the top level
+func parseOpenAPISpec(ext, content string) (map[string]interface{}, bool) {
+	var spec map[string]interface{}
+	var err error
+	if strings.EqualFold(ext, ".json") {
+		err = json.Unmarshal([]byte(content), &spec)
+	} else {
+		err = yaml.Unmarshal([]byte(content), &spec)
+	}
+	if err != nil || spec == nil {
+		return nil, false
+	}
+	spec, _ = normalizeYAML(spec).(map[string]interface{})
+	if stringField(spec, "openapi") == "" && stringField(spec, "swagger") == "" {
+		return nil, false
+	}
+	if _, ok := spec["paths"].(map[string]interface{}); !ok {
+		return nil, false
+	}
+	return spec, true
+}
+
+// openAPIOverview describes the API as a whole: title, version,
+// description, and servers
+func openAPIOverview(spec map[string]interface{}) string {
+	info, _ := spec["info"].(map[string]interface{})
+	var b strings.Builder
+	if title := stringField(info, "title"); title != "" {
+		b.WriteString("API: " + title)
+		if version := stringField(info, "version"); version != "" {
+			b.WriteString(" (version " + version + ")")
+		}
+		b.WriteString("\n")
+	}
+	if desc := stringField(info, "description"); desc != "" {
+		b.WriteString(strings.TrimSpace(desc) + "\n")
+	}
+	servers, _ := spec["servers"].([]interface{})
+	for _, s := range servers {
+		if server, ok := s.(map[string]interface{}); ok {
+			b.WriteString("Server: " + stringField(server, "url") + "\n")
+		}
+	}
+	if host := stringField(spec, "host"); host != "" {
+		b.WriteString("Server: " + host + stringField(spec, "basePath") + "\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// describeOperation renders an operation as readable text
+func describeOperation(spec map[string]interface{}, path, method string, item, op map[string]interface{}) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", strings.ToUpper(method), path)
+	if id := stringField(op, "operationId"); id != "" {
+		b.WriteString("Operation: " + id + "\n")
+	}
+	if summary := stringField(op, "summary"); summary != "" {
+		b.WriteString("Summary: " + strings.TrimSpace(summary) + "\n")
+	}
+	if desc := stringField(op, "description"); desc != "" {
+		b.WriteString("Description: " + strings.TrimSpace(desc) + "\n")
+	}
+	if tags := stringList(op["tags"]); len(tags) > 0 {
+		b.WriteString("Tags: " + strings.Join(tags, ", ") + "\n")
+	}
+	if deprecated, _ := op["deprecated"].(bool); deprecated {
+		b.WriteString("Deprecated: yes\n")
+	}
+
+	// Path-level parameters apply unless the operation overrides them
+	params := map[string]map[string]interface{}{}
+	var order []string
+	for _, list := range []interface{}{item["parameters"], op["parameters"]} {
+		entries, _ := list.([]interface{})
+		for _, entry := range entries {
+			param, _ := resolveRef(spec, entry, 0).(map[string]interface{})
+			if param == nil {
+				continue
+			}
+			key := stringField(param, "in") + ":" + stringField(param, "name")
+			if _, seen := params[key]; !seen {
+				order = append(order, key)
+			}
+			params[key] = param
+		}
+	}
+	if len(order) > 0 {
+		b.WriteString("Parameters:\n")
+		for _, key := range order {
+			param := params[key]
+			details := []string{stringField(param, "in")}
+			if required, _ := param["required"].(bool); required {
+				details = append(details, "required")
+			}
+			schemaType := describeSchema(spec, param["schema"], 0)
+			if schemaType == "" {
+				schemaType = stringField(param, "type") // Swagger 2.0
+			}
+			if schemaType != "" {
+				details = append(details, schemaType)
+			}
+			fmt.Fprintf(&b, "- %s (%s)", stringField(param, "name"), strings.Join(details, ", "))
+			if desc := stringField(param, "description"); desc != "" {
+				b.WriteString(": " + strings.TrimSpace(desc))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if body, _ := resolveRef(spec, op["requestBody"], 0).(map[string]interface{}); body != nil {
+		b.WriteString("Request body")
+		if required, _ := body["required"].(bool); required {
+			b.WriteString(" (required)")
+		}
+		b.WriteString(":")
+		if desc := stringField(body, "description"); desc != "" {
+			b.WriteString(" " + strings.TrimSpace(desc))
+		}
+		b.WriteString("\n")
+		writeContent(&b, spec, body["content"])
+	}
+
+	responses, _ := op["responses"].(map[string]interface{})
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	if len(codes) > 0 {
+		b.WriteString("Responses:\n")
+		for _, code := range codes {
+			resp, _ := resolveRef(spec, responses[code], 0).(map[string]interface{})
+			fmt.Fprintf(&b, "- %s", code)
+			if desc := stringField(resp, "description"); desc != "" {
+				b.WriteString(": " + strings.TrimSpace(desc))
+			}
+			if schema := describeSchema(spec, resp["schema"], 0); schema != "" {
+				b.WriteString(" (" + schema + ")") // Swagger 2.0
+			}
+			b.WriteString("\n")
+			writeContent(&b, spec, resp["content"])
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// writeContent lists the media types of a request or response body
+func writeContent(b *strings.Builder, spec map[string]interface{}, content interface{}) {
+	media, _ := content.(map[string]interface{})
+	types := make([]string, 0, len(media))
+	for mediaType := range media {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	for _, mediaType := range types {
+		entry, _ := media[mediaType].(map[string]interface{})
+		fmt.Fprintf(b, "  %s", mediaType)
+		if schema := describeSchema(spec, entry["schema"], 0); schema != "" {
+			b.WriteString(": " + schema)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// describeSchema summarizes a schema: its name when referenced, its type,
+// and the properties of objects one level deep
+func describeSchema(spec map[string]interface{}, schema interface{}, depth int) string {
+	s, _ := schema.(map[string]interface{})
+	if s == nil || depth > 1 {
+		return ""
+	}
+	if ref := stringField(s, "$ref"); ref != "" {
+		name := ref[strings.LastIndex(ref, "/")+1:]
+		if resolved := describeSchema(spec, resolveRef(spec, s, 0), depth+1); resolved != "" {
+			return name + " " + resolved
+		}
+		return name
+	}
+
+	typ := stringField(s, "type")
+	switch typ {
+	case "array":
+		if items := describeSchema(spec, s["items"], depth); items != "" {
+			return "array of " + items
+		}
+		return "array"
+	case "object", "":
+		props, _ := s["properties"].(map[string]interface{})
+		if len(props) == 0 {
+			return typ
+		}
+		required := map[string]bool{}
+		for _, name := range stringList(s["required"]) {
+			required[name] = true
+		}
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields := make([]string, 0, len(names))
+		for _, name := range names {
+			field := name
+			prop, _ := resolveRef(spec, props[name], 0).(map[string]interface{})
+			if t := stringField(prop, "type"); t != "" {
+				field += ": " + t
+			}
+			if required[name] {
+				field += " (required)"
+			}
+			fields = append(fields, field)
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	if format := stringField(s, "format"); format != "" {
+		return typ + " (" + format + ")"
+	}
+	if enum, ok := s["enum"].([]interface{}); ok && len(enum) > 0 {
+		values := make([]string, len(enum))
+		for i, v := range enum {
+			values[i] = fmt.Sprint(v)
+		}
+		return typ + " (one of " + strings.Join(values, ", ") + ")"
+	}
+	return typ
+}
+
+// resolveRef follows local "#/..." references; external references and
+// unresolvable pointers are returned unchanged
+func resolveRef(spec map[string]interface{}, node interface{}, depth int) interface{} {
+	m, ok := node.(map[string]interface{})
+	if !ok || depth >= maxRefDepth {
+		return node
+	}
+	ref := stringField(m, "$ref")
+	if !strings.HasPrefix(ref, "#/") {
+		return node
+	}
+
+	var target interface{} = spec
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		parent, ok := target.(map[string]interface{})
+		if !ok {
+			return node
+		}
+		if target, ok = parent[part]; !ok {
+			return node
+		}
+	}
+	return resolveRef(spec, target, depth+1)
+}
+
+// normalizeYAML converts maps with non-string keys, as YAML produces for
+// unquoted response codes, into string-keyed maps
+func normalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(val)
+		}
+		return m
+	case map[string]interface{}:
+		for k, val := range t {
+			t[k] = normalizeYAML(val)
+		}
+		return t
+	case []interface{}:
+		for i, val := range t {
+			t[i] = normalizeYAML(val)
+		}
+		return t
+	}
+	return v
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	if m == nil {
+		return ""
+	}
+	switch v := m[key].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkOpenAPIStrategy(t *testing.T) {
+	const petstore = `openapi: 3.0.3
+info:
+  title: Petstore
+  version: 1.0.0
+  description: Sells pets.
+servers:
+  - url: https://api.example.com/v1
+paths:
+  /pets/{id}:
+    parameters:
+      - $ref: '#/components/parameters/PetID'
+    get:
+      operationId: getPet
+      summary: Fetch a pet
+      tags: [pets]
+      responses:
+        200:
+          description: The pet
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+        404:
+          description: Not found
+    delete:
+      operationId: deletePet
+      deprecated: true
+      parameters:
+        - name: id
+          in: path
+          required: true
+          description: Overridden
+          schema: {type: string}
+      responses:
+        204:
+          description: Deleted
+components:
+  parameters:
+    PetID:
+      name: id
+      in: path
+      required: true
+      schema: {type: integer, format: int64}
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        tag: {type: string}
+`
+
+	tests := []struct {
+		name     string
+		ext      string
+		content  string
+		maxSize  int
+		wantMeta []map[string]string
+		wantText []string // text each chunk must contain, in order
+		wantDoc  map[string]string
+	}{
+		{
+			name:    "openapi 3 with refs and path parameters",
+			ext:     ".yaml",
+			content: petstore,
+			maxSize: 1000,
+			wantMeta: []map[string]string{
+				{"chunk_type": "api_overview"},
+				{"chunk_type": "api_operation", "http_method": "GET", "api_path": "/pets/{id}", "operation_id": "getPet", "tags": "pets"},
+				{"chunk_type": "api_operation", "http_method": "DELETE", "api_path": "/pets/{id}", "operation_id": "deletePet"},
+			},
+			wantText: []string{
+				"API: Petstore (version 1.0.0)\nSells pets.\nServer: https://api.example.com/v1",
+				"- id (path, required, integer (int64))\n" +
+					"Responses:\n- 200: The pet\n  application/json: Pet {name: string (required), tag: string}\n- 404: Not found",
+				"Deprecated: yes\nParameters:\n- id (path, required, string): Overridden",
+			},
+			wantDoc: map[string]string{"openapi_version": "3.0.3"},
+		},
+		{
+			name:    "swagger 2 json",
+			ext:     ".JSON",
+			content: `{"swagger":"2.0","host":"api.example.com","basePath":"/v2","paths":{"/users":{"get":{"parameters":[{"name":"limit","in":"query","type":"integer"}],"responses":{"200":{"description":"OK","schema":{"type":"array","items":{"type":"string"}}}}}}}}`,
+			maxSize: 1000,
+			wantMeta: []map[string]string{
+				{"chunk_type": "api_overview"},
+				{"chunk_type": "api_operation", "http_method": "GET", "api_path": "/users"},
+			},
+			wantText: []string{
+				"Server: api.example.com/v2",
+				"GET /users\nParameters:\n- limit (query, integer)\nResponses:\n- 200: OK (array of string)",
+			},
+			wantDoc: map[string]string{"openapi_version": "2.0"},
+		},
+		{
+			name:    "long operations repeat the operation line",
+			ext:     ".yml",
+			content: "openapi: 3.1.0\npaths:\n  /search:\n    post:\n      description: " + strings.Repeat("Searches everything. ", 10),
+			maxSize: 80,
+			wantMeta: []map[string]string{
+				{"chunk_type": "api_operation", "http_method": "POST", "api_path": "/search"},
+				{"chunk_type": "api_operation", "http_method": "POST", "api_path": "/search"},
+				{"chunk_type": "api_operation", "http_method": "POST", "api_path": "/search"},
+				{"chunk_type": "api_operation", "http_method": "POST", "api_path": "/search"},
+			},
+			wantText: []string{"POST /search\nDescription:", "POST /search\nSearches", "POST /search\nSearches", "POST /search\nSearches"},
+			wantDoc:  map[string]string{"openapi_version": "3.1.0"},
+		},
+		{name: "plain yaml is left to the text chunker", ext: ".yaml", content: "name: app\nreplicas: 3\n"},
+		{name: "a version without paths is not a spec", ext: ".yaml", content: "openapi: 3.0.0\ninfo: {title: x}\n"},
+		{name: "invalid json", ext: ".json", content: `{"openapi":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &chunkInput{ext: tt.ext, content: tt.content, maxSize: tt.maxSize}
+			chunks, docMeta, err := newTestProcessor().chunkOpenAPIStrategy(context.Background(), in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(docMeta, tt.wantDoc) {
+				t.Errorf("doc metadata = %v, want %v", docMeta, tt.wantDoc)
+			}
+			if len(chunks) != len(tt.wantMeta) {
+				t.Fatalf("got %d chunks %q, want %d", len(chunks), chunks, len(tt.wantMeta))
+			}
+			for i, c := range chunks {
+				if !reflect.DeepEqual(c.Metadata, tt.wantMeta[i]) {
+					t.Errorf("chunk %d metadata = %v, want %v", i, c.Metadata, tt.wantMeta[i])
+				}
+				if !strings.Contains(c.Content, tt.wantText[i]) {
+					t.Errorf("chunk %d = %q, want it to contain %q", i, c.Content, tt.wantText[i])
+				}
+				if len(c.Content) > tt.maxSize {
+					t.Errorf("chunk %d is %d bytes, over %d", i, len(c.Content), tt.maxSize)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	spec := map[string]interface{}{
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"Pet":     map[string]interface{}{"type": "object"},
+				"Alias":   map[string]interface{}{"$ref": "#/components/schemas/Pet"},
+				"Loop":    map[string]interface{}{"$ref": "#/components/schemas/Loop"},
+				"a/b~c":   map[string]interface{}{"type": "string"},
+				"NotAMap": "scalar",
+			},
+		},
+	}
+	ref := func(r string) map[string]interface{} { return map[string]interface{}{"$ref": r} }
+
+	tests := []struct {
+		name string
+		node interface{}
+		want interface{}
+	}{
+		{name: "local ref", node: ref("#/components/schemas/Pet"), want: map[string]interface{}{"type": "object"}},
+		{name: "chained refs", node: ref("#/components/schemas/Alias"), want: map[string]interface{}{"type": "object"}},
+		{name: "escaped pointer", node: ref("#/components/schemas/a~1b~0c"), want: map[string]interface{}{"type": "string"}},
+		{name: "recursive ref stops", node: ref("#/components/schemas/Loop"), want: ref("#/components/schemas/Loop")},
+		{name: "missing target", node: ref("#/components/schemas/Nope"), want: ref("#/components/schemas/Nope")},
+		{name: "through a scalar", node: ref("#/components/schemas/NotAMap/x"), want: ref("#/components/schemas/NotAMap/x")},
+		{name: "external ref", node: ref("other.yaml#/Pet"), want: ref("other.yaml#/Pet")},
+		{name: "not a ref", node: "text", want: "text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveRef(spec, tt.node, 0); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveRef() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// chunkOverlap is how much of a chunk's end is repeated at the start of the
+// next one, in whole sentences or whole tokens so overlap never starts
+// mid-word or mid-character. Sentences take precedence when set.
+type chunkOverlap struct {
+	tokens    int
+	sentences int
+}
+
+func (o chunkOverlap) String() string {
+	if o.sentences > 0 {
+		return fmt.Sprintf("%d sentences", o.sentences)
+	}
+	return fmt.Sprintf("%d tokens", o.tokens)
+}
+
+// overlapFromChars converts a legacy character overlap to whole tokens
+func overlapFromChars(chars int) chunkOverlap {
+	return chunkOverlap{tokens: chars / charsPerToken}
+}
+
+// configureOverlap sets the default overlap: sentences wins over tokens, and
+// with neither set the character overlap is converted to tokens
+func (p *DocumentProcessor) configureOverlap(tokens, sentences int) {
+	p.overlap = p.resolveOverlap(p.chunkOverlap, tokens, sentences)
+}
+
+// resolveOverlap picks the overlap for a request: sentences, then tokens,
+// then characters converted to tokens, then the service default
+func (p *DocumentProcessor) resolveOverlap(chars, tokens, sentences int) chunkOverlap {
+	switch {
+	case sentences > 0:
+		return chunkOverlap{sentences: sentences}
+	case tokens > 0:
+		return chunkOverlap{tokens: tokens}
+	case chars > 0:
+		return overlapFromChars(chars)
+	}
+	return p.overlap
+}
+
+// overlapStart returns where the chunk after text[start:end] begins: at the
+// start of its last overlap.sentences sentences or overlap.tokens tokens,
+// repeating at most half the chunk so splitting keeps moving forward.
+func overlapStart(text string, start, end int, overlap chunkOverlap) int {
+	window := text[start:end]
+
+	if overlap.sentences > 0 {
+		// Sentences start after terminal punctuation and at new lines
+		seen := make(map[int]bool)
+		var starts []int
+		add := func(i int) {
+			if i > 0 && i < len(window) && !seen[i] {
+				seen[i] = true
+				starts = append(starts, i)
+			}
+		}
+		for _, loc := range sentenceEndRe.FindAllStringIndex(window, -1) {
+			add(loc[1])
+		}
+		for i := 0; i < len(window); i++ {
+			if window[i] == '\n' {
+				add(i + 1)
+			}
+		}
+		sort.Ints(starts)
+		n := min(overlap.sentences, (len(starts)+1)/2)
+		if n == 0 {
+			return end
+		}
+		return start + starts[len(starts)-n]
+	}
+
+	if overlap.tokens > 0 {
+		spans := tokenRe.FindAllStringIndex(window, -1)
+		n := min(overlap.tokens, len(spans)/2)
+		if n == 0 {
+			return end
+		}
+		// Start on a word rather than the punctuation that ended a sentence
+		i := len(spans) - n
+		for i < len(spans)-1 && spans[i][1]-spans[i][0] == 1 && !isWordByte(window[spans[i][0]]) {
+			i++
+		}
+		return start + spans[i][0]
+	}
+	return end
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 0x80 || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// chunkEnd picks where a chunk starting at start ends, at most maxSize bytes
+// on: at a sentence end past halfway, else at a word boundary past halfway,
+// else at a character boundary
+func chunkEnd(text string, start, maxSize int) int {
+	end := start + maxSize
+	if end >= len(text) {
+		return len(text)
+	}
+
+	window := text[start:end]
+	if i := strings.LastIndexAny(window, ".!?\n"); i > maxSize/2 {
+		return start + i + 1
+	}
+	if i := strings.LastIndexAny(window, " \t"); i > maxSize/2 {
+		return start + i + 1
+	}
+	for end > start && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	if end == start {
+		// maxSize is smaller than one character; take the whole character
+		_, size := utf8.DecodeRuneInString(text[start:])
+		end = start + size
+	}
+	return end
+}
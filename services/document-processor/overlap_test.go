@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitIntoChunksOverlap(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		maxSize int
+		overlap chunkOverlap
+		want    []string
+	}{
+		{
+			name:    "one sentence",
+			text:    "Alpha one. Beta two. Gamma three. Delta four.",
+			maxSize: 30,
+			overlap: chunkOverlap{sentences: 1},
+			want:    []string{"Alpha one. Beta two.", "Beta two. Gamma three.", "Gamma three. Delta four."},
+		},
+		{
+			name:    "lines start sentences",
+			text:    "# Setup\nRun make.\n# Usage\nCall it.",
+			maxSize: 20,
+			overlap: chunkOverlap{sentences: 1},
+			want:    []string{"# Setup\nRun make.", "Run make.\n# Usage", "# Usage\nCall it."},
+		},
+		{
+			name:    "two tokens",
+			text:    "one two three four five six seven eight",
+			maxSize: 20,
+			overlap: chunkOverlap{tokens: 2},
+			want:    []string{"one two three four", "three four five six", "five six seven eight"},
+		},
+		{
+			name:    "tokens start on a word, not punctuation",
+			text:    "aa bb cc, dd ee ff gg",
+			maxSize: 13,
+			overlap: chunkOverlap{tokens: 2},
+			want:    []string{"aa bb cc, dd", "dd ee ff gg"},
+		},
+		{
+			name:    "at most half the tokens repeat",
+			text:    "aaaa bbbb cccc dddd eeee ffff",
+			maxSize: 15,
+			overlap: chunkOverlap{tokens: 10},
+			want:    []string{"aaaa bbbb cccc", "cccc dddd eeee", "eeee ffff"},
+		},
+		{
+			name:    "no overlap",
+			text:    "Alpha one. Beta two. Gamma three.",
+			maxSize: 22,
+			want:    []string{"Alpha one. Beta two.", "Gamma three."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTestProcessor().splitIntoChunks(tt.text, tt.maxSize, tt.overlap)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %q\nwant %q", got, tt.want)
+			}
+			for _, c := range got {
+				if len(c) > tt.maxSize {
+					t.Errorf("chunk %q is over %d bytes", c, tt.maxSize)
+				}
+			}
+		})
+	}
+}
+
+func TestOverlapNeverSplitsCharacters(t *testing.T) {
+	text := strings.Repeat("日本語のテキスト", 10)
+	chunks := newTestProcessor().splitIntoChunks(text, 20, chunkOverlap{tokens: 1})
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+	for _, c := range chunks {
+		if !utf8.ValidString(c) || !strings.Contains(text, c) {
+			t.Errorf("chunk %q splits a character", c)
+		}
+	}
+}
+
+func TestResolveOverlap(t *testing.T) {
+	p := newTestProcessor()
+	p.overlap = chunkOverlap{tokens: 5}
+
+	tests := []struct {
+		name                    string
+		chars, tokens, sentence int
+		want                    chunkOverlap
+	}{
+		{name: "sentences win", chars: 100, tokens: 10, sentence: 2, want: chunkOverlap{sentences: 2}},
+		{name: "tokens before characters", chars: 100, tokens: 10, want: chunkOverlap{tokens: 10}},
+		{name: "characters converted to tokens", chars: 100, want: chunkOverlap{tokens: 100 / charsPerToken}},
+		{name: "service default", want: chunkOverlap{tokens: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.resolveOverlap(tt.chars, tt.tokens, tt.sentence); got != tt.want {
+				t.Errorf("resolveOverlap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
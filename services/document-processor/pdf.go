@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// chunkPDF extracts text page by page and chunks each page separately so
+// every chunk carries the page number it came from
+func (p *DocumentProcessor) chunkPDF(data []byte, maxSize int, overlap chunkOverlap, clean cleanOptions) (chunks []chunk, err error) {
+	// The PDF reader panics on some malformed documents
+	defer func() {
+		if r := recover(); r != nil {
+			chunks, err = nil, fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PDF: %w", err)
+	}
+
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract page %d: %w", i, err)
+		}
+		text = strings.Trim(cleanText(text, clean), "\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		parts := []string{text}
+		if len(text) > maxSize {
+			parts = p.splitIntoChunks(text, maxSize, overlap)
+		}
+		for _, part := range parts {
+			chunks = append(chunks, chunk{
+				Content:  part,
+				Metadata: map[string]string{"page": strconv.Itoa(i), "page_count": strconv.Itoa(reader.NumPage())},
+			})
+		}
+	}
+
+	return chunks, nil
+}
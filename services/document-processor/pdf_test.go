@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildPDF writes a minimal PDF with one page per entry, each showing its
+// text in Helvetica; an empty entry makes a page without text
+func buildPDF(pages ...string) []byte {
+	var objects []string
+	kids := make([]string, len(pages))
+	for i, text := range pages {
+		page, content := 4+2*i, 5+2*i
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+		stream := ""
+		if text != "" {
+			stream = fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		}
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", content),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+	}
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}, objects...)
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func TestChunkPDF(t *testing.T) {
+	type want struct {
+		content string
+		page    string
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		maxSize int
+		want    []want
+		wantErr bool
+	}{
+		{
+			name:    "one chunk per page",
+			data:    buildPDF("Getting started", "Configuration"),
+			maxSize: 1000,
+			want:    []want{{"Getting started", "1"}, {"Configuration", "2"}},
+		},
+		{
+			name:    "pages without text are skipped",
+			data:    buildPDF("Cover", "", "Index"),
+			maxSize: 1000,
+			want:    []want{{"Cover", "1"}, {"Index", "3"}},
+		},
+		{
+			name:    "long pages are split and keep their page",
+			data:    buildPDF("Alpha beta gamma. Delta epsilon zeta."),
+			maxSize: 20,
+			want:    []want{{"Alpha beta gamma.", "1"}, {"Delta epsilon zeta.", "1"}},
+		},
+		{name: "not a PDF", data: []byte("plain text"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := newTestProcessor().chunkPDF(tt.data, tt.maxSize, chunkOverlap{}, proseCleaning)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []want
+			for _, c := range chunks {
+				got = append(got, want{c.Content, c.Metadata["page"]})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+			for _, c := range chunks {
+				if c.Metadata["page_count"] != fmt.Sprint(strings.Count(string(tt.data), "/Type /Page ")) {
+					t.Errorf("page_count = %q", c.Metadata["page_count"])
+				}
+			}
+		})
+	}
+}
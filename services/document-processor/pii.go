@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// PII kinds that can be redacted
+const (
+	PIIEmail = "email"
+	PIIPhone = "phone"
+	PIIIP    = "ip"
+)
+
+// Mask modes
+const (
+	MaskLabel   = "label"   // [REDACTED_EMAIL]
+	MaskHash    = "hash"    // [EMAIL_1a2b3c4d], stable per value so mentions can still be correlated
+	MaskPartial = "partial" // j***@example.com, ***-1234, 10.*.*.*
+)
+
+var (
+	emailRe = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	ipv4Re  = regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`)
+	ipv6Re  = regexp.MustCompile(`(?i)[0-9a-z]*(?::[0-9a-z]*){2,}`) // whole tokens, validated by net.ParseIP
+	// Digit groups, optionally parenthesized, joined by at most one space,
+	// "-", or "."; a run can hold several numbers, see maskPhones
+	phoneRe = regexp.MustCompile(`\+?(?:\(\d{1,4}\)|\d+)(?:[ .-]?(?:\(\d{1,4}\)|\d+))+`)
+	dateRe  = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+)
+
+// PIIRedactor masks personal data in chunk text before it is embedded
+type PIIRedactor struct {
+	kinds map[string]bool
+	mode  string
+}
+
+// NewPIIRedactor creates a redactor for the given kinds, or returns nil when none are enabled
+func NewPIIRedactor(kinds []string, mode string) (*PIIRedactor, error) {
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+
+	r := &PIIRedactor{kinds: make(map[string]bool, len(kinds)), mode: mode}
+	for _, kind := range kinds {
+		switch kind {
+		case PIIEmail, PIIPhone, PIIIP:
+			r.kinds[kind] = true
+		default:
+			return nil, fmt.Errorf("unknown PII type %q (expected email, phone, or ip)", kind)
+		}
+	}
+
+	switch mode {
+	case "":
+		r.mode = MaskLabel
+	case MaskLabel, MaskHash, MaskPartial:
+	default:
+		return nil, fmt.Errorf("unknown PII mask mode %q (expected label, hash, or partial)", mode)
+	}
+	return r, nil
+}
+
+// RedactValues masks enabled PII kinds in every value of a metadata map,
+// returning a copy and the number of values masked
+func (r *PIIRedactor) RedactValues(values map[string]string) (map[string]string, int) {
+	if r == nil || len(values) == 0 {
+		return values, 0
+	}
+
+	out := make(map[string]string, len(values))
+	total := 0
+	for k, v := range values {
+		masked, count := r.Redact(v)
+		out[k] = masked
+		total += count
+	}
+	return out, total
+}
+
+// Redact masks enabled PII kinds in text and returns the number of values masked.
+// Emails and IPs run before phones so their digits are not mistaken for numbers.
+func (r *PIIRedactor) Redact(text string) (string, int) {
+	if r == nil {
+		return text, 0
+	}
+
+	count := 0
+	replace := func(re *regexp.Regexp, kind string, valid func(string) bool) {
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			if valid != nil && !valid(match) {
+				return match
+			}
+			count++
+			return r.mask(kind, match)
+		})
+	}
+
+	if r.kinds[PIIEmail] {
+		replace(emailRe, PIIEmail, nil)
+	}
+	if r.kinds[PIIIP] {
+		replace(ipv4Re, PIIIP, nil)
+		replace(ipv6Re, PIIIP, func(s string) bool {
+			return strings.Trim(s, ":") != "" && net.ParseIP(s) != nil
+		})
+	}
+	if r.kinds[PIIPhone] {
+		text = phoneRe.ReplaceAllStringFunc(text, func(run string) string {
+			masked, n := r.maskPhones(run)
+			count += n
+			return masked
+		})
+	}
+	return text, count
+}
+
+// maskPhones masks the phone numbers in a run of digit groups. Numbers
+// listed side by side share a run, so each is the shortest span of
+// space-separated groups that passes isPhoneNumber, rather than the run
+// as a whole.
+func (r *PIIRedactor) maskPhones(run string) (string, int) {
+	groups := strings.Split(run, " ")
+	out := make([]string, 0, len(groups))
+	count := 0
+	for i := 0; i < len(groups); {
+		end := 0
+		for j := i + 1; j <= len(groups); j++ {
+			if isPhoneNumber(strings.Join(groups[i:j], " ")) {
+				end = j
+				break
+			}
+		}
+		if end == 0 {
+			out = append(out, groups[i])
+			i++
+			continue
+		}
+		out = append(out, r.mask(PIIPhone, strings.Join(groups[i:end], " ")))
+		count++
+		i = end
+	}
+	return strings.Join(out, " "), count
+}
+
+// mask renders a replacement for a detected value
+func (r *PIIRedactor) mask(kind, value string) string {
+	switch r.mode {
+	case MaskHash:
+		sum := sha256.Sum256([]byte(strings.ToLower(value)))
+		return fmt.Sprintf("[%s_%s]", strings.ToUpper(kind), hex.EncodeToString(sum[:4]))
+	case MaskPartial:
+		return partialMask(kind, value)
+	default:
+		return "[REDACTED_" + strings.ToUpper(kind) + "]"
+	}
+}
+
+// partialMask keeps just enough of a value to recognise it
+func partialMask(kind, value string) string {
+	switch kind {
+	case PIIEmail:
+		at := strings.LastIndex(value, "@")
+		return value[:1] + "***" + value[at:]
+	case PIIPhone:
+		digits := onlyDigits(value)
+		return "***-" + digits[len(digits)-4:]
+	default:
+		if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+			return strings.SplitN(value, ".", 2)[0] + ".*.*.*"
+		}
+		return "****"
+	}
+}
+
+// isPhoneNumber filters phone candidates: 9-15 digits (E.164 allows 15), and
+// either a leading + or phone-style separators, so bare IDs, dates, and
+// dotted version strings are kept
+func isPhoneNumber(s string) bool {
+	digits := onlyDigits(s)
+	if len(digits) < 9 || len(digits) > 15 || dateRe.MatchString(s) {
+		return false
+	}
+	return strings.HasPrefix(s, "+") || strings.ContainsAny(s, " -()")
+}
+
+func onlyDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
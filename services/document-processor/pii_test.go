@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name      string
+		kinds     []string
+		mode      string
+		text      string
+		want      string
+		wantCount int
+	}{
+		{
+			name:      "email",
+			kinds:     []string{PIIEmail},
+			text:      "Mail jane.doe@example.com for access.",
+			want:      "Mail [REDACTED_EMAIL] for access.",
+			wantCount: 1,
+		},
+		{
+			name:      "ipv4 and ipv6",
+			kinds:     []string{PIIIP},
+			text:      "Hosts 10.0.0.12 and 2001:db8::1 are internal.",
+			want:      "Hosts [REDACTED_IP] and [REDACTED_IP] are internal.",
+			wantCount: 2,
+		},
+		{
+			name:      "phone shapes",
+			kinds:     []string{PIIPhone},
+			text:      "Call +1 (555) 123-4567, (555)987-6543, or +44 20 7946 0958.",
+			want:      "Call [REDACTED_PHONE], [REDACTED_PHONE], or [REDACTED_PHONE].",
+			wantCount: 3,
+		},
+		{
+			name:      "adjacent phones are masked one by one",
+			kinds:     []string{PIIPhone},
+			text:      "Numbers: 555-123-4567 555-987-6543 and 555 123 4567 555 987 6543",
+			want:      "Numbers: [REDACTED_PHONE] [REDACTED_PHONE] and [REDACTED_PHONE] [REDACTED_PHONE]",
+			wantCount: 4,
+		},
+		{
+			name:      "phones on consecutive lines stay separate",
+			kinds:     []string{PIIPhone},
+			mode:      MaskPartial,
+			text:      "555-123-4567\n555-987-6543\n",
+			want:      "***-4567\n***-6543\n",
+			wantCount: 2,
+		},
+		{
+			name:      "whitespace runs don't join digits into a number",
+			kinds:     []string{PIIPhone},
+			text:      "| 1234 |  5678 |\n| 12345 |\t67890 |",
+			want:      "| 1234 |  5678 |\n| 12345 |\t67890 |",
+			wantCount: 0,
+		},
+		{
+			name:      "ids, dates, and versions are kept",
+			kinds:     []string{PIIPhone},
+			text:      "Order 123456789012, released 2024-01-15 as 1.22.333.4444",
+			want:      "Order 123456789012, released 2024-01-15 as 1.22.333.4444",
+			wantCount: 0,
+		},
+		{
+			name:      "a date before a phone is kept",
+			kinds:     []string{PIIPhone},
+			text:      "2024-01-15 555 123 4567",
+			want:      "2024-01-15 [REDACTED_PHONE]",
+			wantCount: 1,
+		},
+		{
+			name:      "hash is stable per value",
+			kinds:     []string{PIIEmail},
+			mode:      MaskHash,
+			text:      "a@example.com A@example.com",
+			want:      "[EMAIL_08168cd8] [EMAIL_08168cd8]",
+			wantCount: 2,
+		},
+		{
+			name:      "partial masks",
+			kinds:     []string{PIIEmail, PIIIP},
+			mode:      MaskPartial,
+			text:      "jane@example.com from 192.168.1.20",
+			want:      "j***@example.com from 192.*.*.*",
+			wantCount: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewPIIRedactor(tt.kinds, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, count := r.Redact(tt.text)
+			if got != tt.want || count != tt.wantCount {
+				t.Errorf("Redact(%q) = %q, %d; want %q, %d", tt.text, got, count, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestNewPIIRedactor(t *testing.T) {
+	if r, err := NewPIIRedactor(nil, ""); r != nil || err != nil {
+		t.Errorf("no kinds = %v, %v; want a nil redactor", r, err)
+	}
+	if _, err := NewPIIRedactor([]string{"ssn"}, ""); err == nil {
+		t.Error("unknown kind accepted")
+	}
+	if _, err := NewPIIRedactor([]string{PIIEmail}, "blur"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestChunkFileRedactsMetadata(t *testing.T) {
+	r, err := NewPIIRedactor([]string{PIIEmail}, MaskLabel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewDocumentProcessor(1000, 0, 0, nil, false, false, r)
+
+	// Frontmatter and heading paths are stored in metadata, which is
+	// indexed alongside the text
+	file := &models.FileChange{Repository: "org/docs", FilePath: "docs/contact.md"}
+	file.SetContent([]byte("---\nauthor: jane@example.com\n---\n# Ask ops@example.com\n\nWrite to us.\n"))
+
+	docs, err := p.ChunkDocument(context.Background(), file, 1000, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) == 0 {
+		t.Fatal("no documents")
+	}
+	for _, doc := range docs {
+		for k, v := range doc.Metadata {
+			if strings.Contains(v, "@example.com") {
+				t.Errorf("metadata %s = %q, want the email masked", k, v)
+			}
+		}
+		if doc.Metadata["author"] == "" {
+			t.Error("frontmatter author dropped instead of masked")
+		}
+		// The heading is in the text and the heading path, the author only in metadata
+		if doc.Metadata["pii_redacted"] != "3" {
+			t.Errorf("pii_redacted = %q, want 3", doc.Metadata["pii_redacted"])
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	rstDirectiveRe = regexp.MustCompile(`^\.\.\s+([A-Za-z0-9_:-]+)::\s*(.*)$`)
+	rstRoleRe      = regexp.MustCompile(`:[A-Za-z0-9_:+-]+:` + "`" + `([^` + "`" + `]+)` + "`")
+	rstLinkRe      = regexp.MustCompile("`" + `([^` + "`" + `<]+?)\s*<[^>]+>` + "`" + `__?`)
+	rstRefRe       = regexp.MustCompile("`" + `([^` + "`" + `]+)` + "`" + `__?`)
+	rstLiteralRe   = regexp.MustCompile("``" + `([^` + "`" + `]+)` + "``")
+	rstExplicitRe  = regexp.MustCompile(`^\.\.(\s|$)`)
+)
+
+// Directives whose content is code
+var rstCodeDirectives = map[string]bool{"code": true, "code-block": true, "sourcecode": true}
+
+// Directives that produce no readable text
+var rstDroppedDirectives = map[string]bool{
+	"toctree": true, "image": true, "include": true, "literalinclude": true, "raw": true,
+	"meta": true, "contents": true, "index": true, "highlight": true, "sectnum": true,
+	"automodule": true, "autoclass": true, "autofunction": true,
+}
+
+// Admonitions keep their content behind a label
+var rstAdmonitions = map[string]string{
+	"note": "Note", "warning": "Warning", "tip": "Tip", "important": "Important",
+	"caution": "Caution", "danger": "Danger", "attention": "Attention", "hint": "Hint",
+	"error": "Error", "seealso": "See also", "deprecated": "Deprecated", "versionadded": "New in version",
+	"versionchanged": "Changed in version",
+}
+
+func isRST(ext string) bool {
+	return strings.EqualFold(ext, ".rst") || strings.EqualFold(ext, ".rest")
+}
+
+// isRSTAdornment reports whether a line is a section over/underline: three
+// or more repetitions of a single punctuation character
+func isRSTAdornment(line string) bool {
+	line = strings.TrimRight(line, " \t")
+	if len(line) < 3 || !strings.ContainsRune(`=-~^"'`+"`"+`#*+<>:._`, rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// extractRST converts reStructuredText into Markdown: section titles become
+// headings (levels follow the order adornment styles first appear, as in
+// docutils), directives are resolved to their readable content, and inline
+// roles and links are reduced to their text.
+func extractRST(src string) string {
+	lines := strings.Split(cleanPreformatted(src), "\n")
+	var out []string
+	var styles []string // adornment styles in order of first use
+
+	headingLevel := func(style string) int {
+		for i, s := range styles {
+			if s == style {
+				return i + 1
+			}
+		}
+		styles = append(styles, style)
+		return len(styles)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// Overlined title: adornment, title, adornment
+		if isRSTAdornment(line) && i+2 < len(lines) && strings.TrimSpace(lines[i+1]) != "" &&
+			strings.TrimSpace(lines[i+2]) == strings.TrimSpace(line) {
+			level := headingLevel("over" + line[:1])
+			out = append(out, "", heading(level, lines[i+1]), "")
+			i += 2
+			continue
+		}
+
+		// Underlined title: text, adornment at least as long
+		if i+1 < len(lines) && strings.TrimSpace(line) != "" && !strings.HasPrefix(line, " ") &&
+			isRSTAdornment(lines[i+1]) && len(strings.TrimSpace(lines[i+1])) >= len(strings.TrimSpace(line)) {
+			level := headingLevel(lines[i+1][:1])
+			out = append(out, "", heading(level, line), "")
+			i++
+			continue
+		}
+
+		// Directives and comments own the indented block that follows
+		if rstExplicitRe.MatchString(line) {
+			body, next := rstIndentedBlock(lines, i+1)
+			out = append(out, rstDirective(line, body)...)
+			i = next - 1
+			continue
+		}
+
+		// A paragraph ending in "::" introduces a literal block
+		if trimmed := strings.TrimRight(line, " "); strings.HasSuffix(trimmed, "::") {
+			body, next := rstIndentedBlock(lines, i+1)
+			if len(body) > 0 {
+				// "Example::" reads as "Example:"; a bare "::" disappears
+				if strings.TrimSpace(trimmed) != "::" {
+					out = append(out, rstInline(strings.TrimSuffix(trimmed, ":")))
+				}
+				out = append(out, "", "```")
+				out = append(out, body...)
+				out = append(out, "```", "")
+				i = next - 1
+				continue
+			}
+		}
+
+		out = append(out, rstInline(line))
+	}
+
+	return strings.TrimSpace(collapseBlankLines(strings.Join(out, "\n")))
+}
+
+// rstDirective renders an explicit markup block as Markdown lines
+func rstDirective(line string, body []string) []string {
+	m := rstDirectiveRe.FindStringSubmatch(line)
+	if m == nil {
+		// Comments, hyperlink targets, and footnote definitions
+		return nil
+	}
+
+	name, arg := strings.ToLower(m[1]), strings.TrimSpace(m[2])
+	content := rstStripOptions(body)
+
+	switch {
+	case rstCodeDirectives[name]:
+		out := append([]string{"", "```" + arg}, content...)
+		return append(out, "```", "")
+	case rstDroppedDirectives[name]:
+		return nil
+	case rstAdmonitions[name] != "":
+		text := strings.TrimSpace(strings.Join(append([]string{arg}, content...), "\n"))
+		return []string{"", fmt.Sprintf("**%s:** %s", rstAdmonitions[name], rstInline(text)), ""}
+	case name == "admonition":
+		text := strings.TrimSpace(strings.Join(content, "\n"))
+		return []string{"", fmt.Sprintf("**%s:** %s", arg, rstInline(text)), ""}
+	case name == "figure":
+		// Keep the caption, drop the image reference
+		return append([]string{""}, rstInlineLines(content)...)
+	default:
+		out := []string{""}
+		if arg != "" {
+			out = append(out, rstInline(arg), "")
+		}
+		return append(out, rstInlineLines(content)...)
+	}
+}
+
+// rstIndentedBlock returns the dedented lines indented under line start-1
+// (blank lines included) and the index of the first line after the block
+func rstIndentedBlock(lines []string, start int) ([]string, int) {
+	end := start
+	for end < len(lines) && (strings.TrimSpace(lines[end]) == "" || strings.HasPrefix(lines[end], " ") || strings.HasPrefix(lines[end], "\t")) {
+		end++
+	}
+	// Trailing blank lines belong to the surrounding document
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+
+	block := lines[start:end]
+	indent := -1
+	for _, l := range block {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	dedented := make([]string, 0, len(block))
+	for _, l := range block {
+		if len(l) >= indent && indent > 0 {
+			l = l[indent:]
+		}
+		dedented = append(dedented, l)
+	}
+	for len(dedented) > 0 && strings.TrimSpace(dedented[0]) == "" {
+		dedented = dedented[1:]
+	}
+	return dedented, end
+}
+
+// rstStripOptions drops the ":option: value" field list that opens a directive body
+func rstStripOptions(body []string) []string {
+	i := 0
+	for i < len(body) && strings.HasPrefix(body[i], ":") && strings.Count(body[i], ":") >= 2 {
+		i++
+	}
+	for i < len(body) && strings.TrimSpace(body[i]) == "" {
+		i++
+	}
+	return body[i:]
+}
+
+// rstInline reduces inline markup to plain Markdown
+func rstInline(line string) string {
+	line = rstLiteralRe.ReplaceAllString(line, "`$1`")
+	line = rstRoleRe.ReplaceAllStringFunc(line, func(role string) string {
+		text := rstRoleRe.FindStringSubmatch(role)[1]
+		// :ref:`Title <target>` shows the title
+		if i := strings.Index(text, "<"); i > 0 && strings.HasSuffix(text, ">") {
+			text = strings.TrimSpace(text[:i])
+		}
+		return strings.TrimPrefix(text, "~")
+	})
+	line = rstLinkRe.ReplaceAllString(line, "$1")
+	line = rstRefRe.ReplaceAllStringFunc(line, func(ref string) string {
+		// Leave Markdown-converted inline literals alone
+		if !strings.HasSuffix(ref, "_") {
+			return ref
+		}
+		return rstRefRe.FindStringSubmatch(ref)[1]
+	})
+	return line
+}
+
+func rstInlineLines(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = rstInline(l)
+	}
+	return out
+}
+
+// heading renders a Markdown heading, capping the level at six
+func heading(level int, title string) string {
+	if level > 6 {
+		level = 6
+	}
+	return strings.Repeat("#", level) + " " + strings.TrimSpace(title)
+}
+
+// collapseBlankLines squeezes runs of blank lines down to one
+func collapseBlankLines(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			if !blank {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		out = append(out, l)
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestExtractRST(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "heading levels follow first use",
+			src:  "=====\nGuide\n=====\n\nInstall\n-------\n\nDocker\n~~~~~~\n\nUsage\n-----\n\nRun it.",
+			want: "# Guide\n\n## Install\n\n### Docker\n\n## Usage\n\nRun it.",
+		},
+		{
+			name: "code directives and literal blocks",
+			src:  ".. code-block:: python\n   :linenos:\n\n   print(1)\n\nExample::\n\n    make all\n\n::\n\n    bare",
+			want: "```python\nprint(1)\n```\n\nExample:\n\n```\nmake all\n```\n\n```\nbare\n```",
+		},
+		{
+			name: "admonitions keep their text behind a label",
+			src:  ".. note:: Back up first.\n\n.. admonition:: Heads up\n\n   Read :ref:`the guide <guide>`.",
+			want: "**Note:** Back up first.\n\n**Heads up:** Read the guide.",
+		},
+		{
+			name: "dropped directives and comments",
+			src:  "Intro.\n\n.. toctree::\n   :maxdepth: 2\n\n   install\n\n.. image:: logo.png\n\n.. a comment\n   spanning lines\n\n.. _target:\n\nOutro.",
+			want: "Intro.\n\nOutro.",
+		},
+		{
+			name: "figures keep their caption",
+			src:  ".. figure:: arch.png\n   :width: 400\n\n   The architecture.",
+			want: "The architecture.",
+		},
+		{
+			name: "inline roles, links, and literals",
+			src:  "Call :func:`~pkg.run` with ``--fast``, see `the docs <https://example.com>`_ and `Install`_.",
+			want: "Call pkg.run with `--fast`, see the docs and Install.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractRST(tt.src); got != tt.want {
+				t.Errorf("extractRST() = %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Documents with more sentences than this fall back to plain chunking
+	// rather than embedding every sentence
+	maxSemanticSentences = 1000
+	// Sentences sent to the embedding service per request
+	semanticEmbedBatch = 64
+	// Adjacent windows less similar than this share of all neighbours mark
+	// a topic shift
+	semanticBreakPercentile = 90
+)
+
+// Sentence ends: terminal punctuation followed by whitespace
+var sentenceEndRe = regexp.MustCompile(`[.!?]["')\]]*\s+`)
+
+// embeddingClient requests sentence embeddings from the embedding service
+type embeddingClient struct {
+	url        string
+	httpClient *http.Client
+}
+
+func newEmbeddingClient(url string) *embeddingClient {
+	return &embeddingClient{url: url, httpClient: &http.Client{Timeout: 60 * time.Second}}
+}
+
+func (c *embeddingClient) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += semanticEmbedBatch {
+		end := start + semanticEmbedBatch
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		reqBody, _ := json.Marshal(map[string]interface{}{
+			"texts": texts[start:end],
+		})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/embed", bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Embeddings [][]float32 `json:"embeddings"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("sentence embedding failed: %s", body)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Embeddings) != end-start {
+			return nil, fmt.Errorf("embedding service returned %d embeddings for %d sentences", len(result.Embeddings), end-start)
+		}
+		vectors = append(vectors, result.Embeddings...)
+	}
+	return vectors, nil
+}
+
+// chunkSemanticStrategy places chunk boundaries where the topic shifts:
+// each sentence is embedded together with its neighbours, and a boundary
+// goes where adjacent windows are least similar. Chunks still respect
+// maxSize, and very short runs are merged into the next topic.
+func (p *DocumentProcessor) chunkSemanticStrategy(ctx context.Context, in *chunkInput) ([]chunk, map[string]string, error) {
+	if p.embedder == nil {
+		return nil, nil, fmt.Errorf("no embedding service configured")
+	}
+
+	// Fenced code blocks and tables take part as single, unsplittable units
+	var sentences []string
+	var lineStarts, atomic []bool
+	for _, block := range splitAtomicBlocks(in.content) {
+		if block.atomic {
+			sentences = append(sentences, strings.Trim(cleanPreformatted(block.text), "\n"))
+			lineStarts = append(lineStarts, true)
+			atomic = append(atomic, true)
+			continue
+		}
+		blockSentences, blockStarts := splitSentences(cleanText(block.text, in.cleaning))
+		sentences = append(sentences, blockSentences...)
+		lineStarts = append(lineStarts, blockStarts...)
+		atomic = append(atomic, make([]bool, len(blockSentences))...)
+	}
+	if len(sentences) == 0 {
+		return []chunk{}, nil, nil
+	}
+	if len(strings.Join(sentences, " ")) <= in.maxSize || len(sentences) < 3 {
+		return p.chunkPlainStrategy(ctx, in)
+	}
+	if len(sentences) > maxSemanticSentences {
+		return nil, nil, fmt.Errorf("%d sentences exceed the semantic chunking limit of %d", len(sentences), maxSemanticSentences)
+	}
+
+	// A window of one sentence either side smooths out single-sentence noise
+	windows := make([]string, len(sentences))
+	for i := range sentences {
+		lo, hi := i-1, i+2
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(sentences) {
+			hi = len(sentences)
+		}
+		windows[i] = strings.Join(sentences[lo:hi], " ")
+	}
+	vectors, err := p.embedder.embed(ctx, windows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	distances := make([]float64, len(sentences)-1)
+	for i := range distances {
+		distances[i] = 1 - cosineSimilarity(vectors[i], vectors[i+1])
+	}
+	threshold := percentile(distances, semanticBreakPercentile)
+	// Ties at the threshold only count when it stands out from the typical
+	// distance, so uniform text gets no topic breaks
+	median := percentile(distances, 50)
+	isBreak := func(i int) bool {
+		return i < len(distances) && distances[i] >= threshold && distances[i] > median
+	}
+	minSize := in.maxSize / 4
+
+	var chunks []chunk
+	var current strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			chunks = append(chunks, chunk{Content: text})
+		}
+		current.Reset()
+	}
+
+	for i, sentence := range sentences {
+		if atomic[i] {
+			if tables := parseTables(sentence); tables != nil {
+				flush()
+				chunks = append(chunks, tableChunks(tables, in.maxSize, nil)...)
+				continue
+			}
+		}
+		if len(sentence) > in.maxSize {
+			flush()
+			if atomic[i] {
+				chunks = append(chunks, chunk{Content: sentence})
+				continue
+			}
+			for _, part := range p.splitIntoChunks(sentence, in.maxSize, in.overlap) {
+				chunks = append(chunks, chunk{Content: part})
+			}
+			continue
+		}
+		if current.Len() > 0 && current.Len()+len(sentence)+1 > in.maxSize {
+			flush()
+		}
+		if current.Len() > 0 {
+			if lineStarts[i] {
+				current.WriteString("\n")
+			} else {
+				current.WriteString(" ")
+			}
+		}
+		current.WriteString(sentence)
+
+		if isBreak(i) && current.Len() >= minSize {
+			flush()
+		}
+	}
+	flush()
+
+	return chunks, nil, nil
+}
+
+// splitSentences breaks cleaned text into sentences, reporting which ones
+// start a line; line breaks also end a sentence so headings and list items
+// stand alone
+func splitSentences(text string) ([]string, []bool) {
+	var sentences []string
+	var lineStarts []bool
+	for _, line := range strings.Split(text, "\n") {
+		start := 0
+		first := true
+		add := func(s string) {
+			if s = strings.TrimSpace(s); s != "" {
+				sentences = append(sentences, s)
+				lineStarts = append(lineStarts, first)
+				first = false
+			}
+		}
+		for _, loc := range sentenceEndRe.FindAllStringIndex(line, -1) {
+			add(line[start:loc[1]])
+			start = loc[1]
+		}
+		add(line[start:])
+	}
+	return sentences, lineStarts
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// percentile returns the p-th percentile of values by nearest rank
+func percentile(values []float64, p int) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
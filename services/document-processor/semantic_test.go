@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// topicEmbedder serves /embed with vectors counting the words "cat" and
+// "car", so similarity follows the topic of each sentence window
+func topicEmbedder(t *testing.T) (*embeddingClient, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var req struct {
+			Texts []string `json:"texts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		embeddings := make([][]float32, len(req.Texts))
+		for i, text := range req.Texts {
+			words := strings.Fields(strings.ToLower(text))
+			vector := []float32{0, 0}
+			for _, word := range words {
+				switch strings.Trim(word, ".") {
+				case "cat":
+					vector[0]++
+				case "car":
+					vector[1]++
+				}
+			}
+			embeddings[i] = vector
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings})
+	}))
+	t.Cleanup(server.Close)
+	return newEmbeddingClient(server.URL), &calls
+}
+
+func TestChunkSemantic(t *testing.T) {
+	cats := []string{"The cat sleeps all day.", "A cat hunts at night.", "Every cat likes fish.", "My cat has a bell."}
+	cars := []string{"The car needs new tyres.", "A car burns fuel.", "Every car has a horn.", "My car is parked outside."}
+
+	tests := []struct {
+		name    string
+		content string
+		maxSize int
+		want    []string
+		embeds  bool
+	}{
+		{
+			name:    "breaks where the topic shifts",
+			content: strings.Join(append(append([]string{}, cats...), cars...), " "),
+			maxSize: 120,
+			want:    []string{strings.Join(cats, " "), strings.Join(cars, " ")},
+			embeds:  true,
+		},
+		{
+			name:    "uniform text only breaks at the size limit",
+			content: strings.Join(append(append([]string{}, cats...), cats...), " "),
+			maxSize: 120,
+			want: []string{
+				strings.Join(append(append([]string{}, cats...), cats[:1]...), " "),
+				strings.Join(cats[1:], " "),
+			},
+			embeds: true,
+		},
+		{
+			name:    "line starts are kept",
+			content: strings.Join(cats, "\n") + "\n" + strings.Join(cars, "\n"),
+			maxSize: 120,
+			want:    []string{strings.Join(cats, "\n"), strings.Join(cars, "\n")},
+			embeds:  true,
+		},
+		{
+			name:    "fenced code is one unit",
+			content: strings.Join(cats, " ") + "\n```\nmake cat\nmake car\n```\n" + strings.Join(cars, " "),
+			maxSize: 120,
+			want:    []string{strings.Join(cats, " "), "```\nmake cat\nmake car\n```\n" + strings.Join(cars, " ")},
+			embeds:  true,
+		},
+		{
+			name:    "short documents are chunked plainly",
+			content: strings.Join(cats, " "),
+			maxSize: 1000,
+			want:    []string{strings.Join(cats, " ")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProcessor()
+			var calls *int32
+			p.embedder, calls = topicEmbedder(t)
+
+			chunks, _, err := p.chunkSemanticStrategy(context.Background(), &chunkInput{
+				path: "notes.txt", ext: ".txt", content: tt.content, maxSize: tt.maxSize, cleaning: proseCleaning,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(chunks))
+			for i, c := range chunks {
+				got[i] = c.Content
+				if len(c.Content) > tt.maxSize {
+					t.Errorf("chunk %d is %d bytes, over %d", i, len(c.Content), tt.maxSize)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %q\nwant %q", got, tt.want)
+			}
+			if embedded := atomic.LoadInt32(calls) > 0; embedded != tt.embeds {
+				t.Errorf("embedding service called: %v, want %v", embedded, tt.embeds)
+			}
+		})
+	}
+}
+
+func TestChunkSemanticErrors(t *testing.T) {
+	content := strings.Repeat("The cat sleeps all day. ", 10)
+
+	p := newTestProcessor()
+	if _, _, err := p.chunkSemanticStrategy(context.Background(), &chunkInput{content: content, maxSize: 50}); err == nil {
+		t.Error("chunking without an embedding service succeeded")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	p.embedder = newEmbeddingClient(server.URL)
+	if _, _, err := p.chunkSemanticStrategy(context.Background(), &chunkInput{content: content, maxSize: 50}); err == nil || !strings.Contains(err.Error(), "model overloaded") {
+		t.Errorf("err = %v, want the embedding service's error", err)
+	}
+
+	// Too many sentences to embed one by one
+	many := strings.Repeat("Cats nap. ", maxSemanticSentences+1)
+	if _, _, err := p.chunkSemanticStrategy(context.Background(), &chunkInput{content: many, maxSize: 50}); err == nil || !strings.Contains(err.Error(), "semantic chunking limit") {
+		t.Errorf("err = %v, want the sentence limit", err)
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	sentences, lineStarts := splitSentences("First one. Second one! Third (aside.) Fourth?\n# Heading\nv1.2 is out")
+	wantSentences := []string{"First one.", "Second one!", "Third (aside.)", "Fourth?", "# Heading", "v1.2 is out"}
+	wantStarts := []bool{true, false, false, false, true, true}
+	if !reflect.DeepEqual(sentences, wantSentences) || !reflect.DeepEqual(lineStarts, wantStarts) {
+		t.Errorf("splitSentences() = %q %v\nwant %q %v", sentences, lineStarts, wantSentences, wantStarts)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{0.5, 0.1, 0.9, 0.3, 0.7}
+	for p, want := range map[int]float64{0: 0.1, 50: 0.5, 90: 0.9, 100: 0.9} {
+		if got := percentile(values, p); got != want {
+			t.Errorf("percentile(%d) = %v, want %v", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+// Chunking strategies selectable per request, per project, or per extension
+const (
+	StrategyAuto     = "auto" // pick by file extension
+	StrategyPlain    = "plain"
+	StrategyMarkdown = "markdown"
+	StrategyCode     = "code"
+	StrategyToken    = "token"
+	StrategySemantic = "semantic"
+	StrategyTabular  = "tabular"
+	StrategyPDF      = "pdf"
+	StrategyOpenAPI  = "openapi"
+)
+
+// Roughly four characters per token for English text and code
+const charsPerToken = 4
+
+// Words (in any script) and single punctuation marks
+var tokenRe = regexp.MustCompile(`[\p{L}\p{M}\p{N}_]+|[^\p{L}\p{M}\p{N}_\s]`)
+
+// chunkInput is what a strategy needs to know about one document
+type chunkInput struct {
+	path     string
+	ext      string
+	content  string
+	raw      []byte
+	maxSize  int
+	overlap  chunkOverlap
+	cleaning cleanOptions
+}
+
+// chunkStrategy splits a document into chunks. The returned metadata applies
+// to every chunk (e.g. Markdown frontmatter). A nil chunk slice with a nil
+// error means the strategy has nothing to offer and plain chunking is used.
+type chunkStrategy func(ctx context.Context, in *chunkInput) ([]chunk, map[string]string, error)
+
+// registerStrategies builds the strategy registry
+func (p *DocumentProcessor) registerStrategies() {
+	p.strategies = map[string]chunkStrategy{
+		StrategyPlain:    p.chunkPlainStrategy,
+		StrategyMarkdown: p.chunkMarkdownStrategy,
+		StrategyCode:     p.chunkCodeStrategy,
+		StrategyToken:    p.chunkTokenStrategy,
+		StrategySemantic: p.chunkSemanticStrategy,
+		StrategyTabular:  p.chunkTabularStrategy,
+		StrategyPDF:      p.chunkPDFStrategy,
+		StrategyOpenAPI:  p.chunkOpenAPIStrategy,
+	}
+}
+
+// strategyNames lists the registered strategies for error messages
+func (p *DocumentProcessor) strategyNames() []string {
+	names := make([]string, 0, len(p.strategies)+1)
+	names = append(names, StrategyAuto)
+	for name := range p.strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// validateStrategies rejects unknown strategy names in a request
+func (p *DocumentProcessor) validateStrategies(strategy string, byExt map[string]string) error {
+	for _, name := range append([]string{strategy}, mapValues(byExt)...) {
+		if name == "" || name == StrategyAuto {
+			continue
+		}
+		if _, ok := p.strategies[name]; !ok {
+			return fmt.Errorf("unknown chunking strategy %q (available: %s)", name, strings.Join(p.strategyNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// normalizeStrategyMap lower-cases extension keys and adds the leading dot
+func normalizeStrategyMap(byExt map[string]string) map[string]string {
+	normalized := make(map[string]string, len(byExt))
+	for ext, name := range byExt {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized[ext] = strings.ToLower(strings.TrimSpace(name))
+	}
+	return normalized
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, strings.ToLower(strings.TrimSpace(v)))
+	}
+	return values
+}
+
+// resolveStrategy picks a strategy for an extension: an explicit request
+// strategy wins, then the request's extension mapping, then the configured
+// mapping, then the built-in default for the extension
+func (p *DocumentProcessor) resolveStrategy(ext, strategy string, byExt map[string]string) string {
+	ext = strings.ToLower(ext)
+	if name := strings.ToLower(strings.TrimSpace(strategy)); name != "" && name != StrategyAuto {
+		return name
+	}
+	if name := normalizeStrategyMap(byExt)[ext]; name != "" && name != StrategyAuto {
+		return name
+	}
+	if name := p.extStrategies[ext]; name != "" && name != StrategyAuto {
+		return name
+	}
+	return defaultStrategy(ext)
+}
+
+// defaultStrategy is the built-in strategy for a file extension
+func defaultStrategy(ext string) string {
+	switch {
+	case isMarkdown(ext), isRST(ext), isAsciiDoc(ext), strings.EqualFold(ext, ".ipynb"):
+		return StrategyMarkdown
+	case strings.EqualFold(ext, ".go"), hasTreeSitterGrammar(ext), isIDL(ext):
+		return StrategyCode
+	case tabularDelimiter(ext) != 0:
+		return StrategyTabular
+	case strings.EqualFold(ext, ".pdf"):
+		return StrategyPDF
+	case isOpenAPICandidate(ext):
+		// Specs are recognised by content; other files fall back to plain
+		return StrategyOpenAPI
+	}
+	return StrategyPlain
+}
+
+// chunkPlainStrategy cleans the text and splits it at sentence boundaries,
+// keeping fenced code blocks whole and giving tables their own chunks
+func (p *DocumentProcessor) chunkPlainStrategy(_ context.Context, in *chunkInput) ([]chunk, map[string]string, error) {
+	return p.packBlocks(splitAtomicBlocks(in.content), in.maxSize, in.overlap, in.cleaning), nil, nil
+}
+
+// chunkMarkdownStrategy chunks along the heading hierarchy. RST, AsciiDoc,
+// and notebooks are converted to Markdown first.
+func (p *DocumentProcessor) chunkMarkdownStrategy(_ context.Context, in *chunkInput) ([]chunk, map[string]string, error) {
+	switch {
+	case isRST(in.ext):
+		// Sections become headings so titles end up in the heading path
+		return p.chunkMarkdown(cleanText(extractRST(in.content), in.cleaning), in.maxSize, in.overlap), nil, nil
+	case isAsciiDoc(in.ext):
+		return p.chunkMarkdown(cleanText(extractAsciiDoc(in.content), in.cleaning), in.maxSize, in.overlap), nil, nil
+	case strings.EqualFold(in.ext, ".ipynb"):
+		// Notebooks are extracted to Markdown instead of chunking raw JSON
+		text, err := extractNotebook(in.content)
+		if err != nil {
+			return nil, nil, err
+		}
+		return p.chunkMarkdown(cleanText(text, in.cleaning), in.maxSize, in.overlap), nil, nil
+	}
+
+	// Frontmatter becomes metadata rather than embedded text
+	docMeta, body := extractFrontmatter(in.content)
+	return p.chunkMarkdown(cleanText(body, in.cleaning), in.maxSize, in.overlap), docMeta, nil
+}
+
+// chunkCodeStrategy keeps declarations whole: Go is parsed with go/parser,
+// Protobuf, Thrift, and GraphQL schemas are split at their definitions,
+// languages with a tree-sitter grammar are split along the syntax tree, and
+// anything else is packed from blank-line separated top-level blocks
+func (p *DocumentProcessor) chunkCodeStrategy(ctx context.Context, in *chunkInput) ([]chunk, map[string]string, error) {
+	// Cleaning works line by line, so it can run before parsing
+	src := cleanText(in.content, in.cleaning)
+	if strings.EqualFold(in.ext, ".go") {
+		chunks, err := p.chunkGoSource(in.path, src, in.maxSize, in.overlap)
+		return chunks, nil, err
+	}
+	if isIDL(in.ext) {
+		chunks, docMeta := p.chunkIDL(in.ext, src, in.maxSize, in.overlap)
+		return chunks, docMeta, nil
+	}
+	if hasTreeSitterGrammar(in.ext) {
+		chunks, err := p.chunkTreeSitter(ctx, in.ext, src, in.maxSize, in.overlap)
+		return chunks, nil, err
+	}
+	blocks := topLevelBlocks(src)
+	segments := make([]codeSegment, len(blocks))
+	for i, block := range blocks {
+		segments[i] = codeSegment{text: block}
+	}
+	return p.packSegments(segments, in.maxSize, in.overlap), nil, nil
+}
+
+// topLevelBlocks splits text before each unindented line that follows a
+// blank line, keeping each block's trailing newlines
+func topLevelBlocks(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	var blocks []string
+	var current strings.Builder
+	prevBlank := false
+	for _, line := range lines {
+		blank := strings.TrimSpace(line) == ""
+		if prevBlank && !blank && line[0] != ' ' && line[0] != '\t' && current.Len() > 0 {
+			blocks = append(blocks, current.String())
+			current.Reset()
+		}
+		current.WriteString(line)
+		prevBlank = blank
+	}
+	if current.Len() > 0 {
+		blocks = append(blocks, current.String())
+	}
+	return blocks
+}
+
+// chunkTokenStrategy cuts fixed windows of approximate tokens, ignoring
+// document structure; maxSize is converted from characters and sentence
+// overlap does not apply
+func (p *DocumentProcessor) chunkTokenStrategy(_ context.Context, in *chunkInput) ([]chunk, map[string]string, error) {
+	text := cleanText(in.content, in.cleaning)
+	spans := tokenRe.FindAllStringIndex(text, -1)
+	if len(spans) == 0 {
+		return []chunk{}, nil, nil
+	}
+
+	window := in.maxSize / charsPerToken
+	if window < 1 {
+		window = 1
+	}
+	step := window - in.overlap.tokens
+	if step < 1 {
+		step = window
+	}
+
+	var chunks []chunk
+	for start := 0; start < len(spans); start += step {
+		end := start + window
+		if end > len(spans) {
+			end = len(spans)
+		}
+		chunks = append(chunks, chunk{
+			Content: strings.TrimSpace(text[spans[start][0]:spans[end-1][1]]),
+			Metadata: map[string]string{
+				"token_start": fmt.Sprintf("%d", start),
+				"token_end":   fmt.Sprintf("%d", end),
+			},
+		})
+		if end == len(spans) {
+			break
+		}
+	}
+	return chunks, nil, nil
+}
+
+// chunkTabularStrategy groups CSV/TSV rows, defaulting to commas for
+// extensions without a known delimiter
+func (p *DocumentProcessor) chunkTabularStrategy(_ context.Context, in *chunkInput) ([]chunk, map[string]string, error) {
+	delim := tabularDelimiter(in.ext)
+	if delim == 0 {
+		delim = ','
+	}
+	chunks, err := p.chunkTabular(in.content, delim, in.maxSize)
+	return chunks, nil, err
+}
+
+// chunkPDFStrategy extracts text per page. A PDF that cannot be read yields
+// no chunks: raw PDF bytes are not worth indexing as text.
+func (p *DocumentProcessor) chunkPDFStrategy(_ context.Context, in *chunkInput) ([]chunk, map[string]string, error) {
+	chunks, err := p.chunkPDF(in.raw, in.maxSize, in.overlap, in.cleaning)
+	if err != nil {
+		logger.Warning("Skipping %s: %v", in.path, err)
+		return []chunk{}, nil, nil
+	}
+	return chunks, nil, nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// GFM delimiter row, e.g. "| --- | :---: |"
+	tableDelimiterRe = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	htmlTableOpenRe  = regexp.MustCompile(`(?i)<table[\s>]`)
+	htmlTableCloseRe = regexp.MustCompile(`(?i)</table\s*>`)
+)
+
+// extractedTable is a Markdown or HTML table reduced to its cells
+type extractedTable struct {
+	caption string
+	header  []string
+	rows    [][]string
+}
+
+// parseTables extracts the tables in a block: a GFM pipe table, or any HTML
+// tables it contains. It returns nil when the block holds no table.
+func parseTables(block string) []*extractedTable {
+	if fenceOpenRe.MatchString(block) {
+		// Table syntax inside a code block is code
+		return nil
+	}
+	if htmlTableOpenRe.MatchString(block) {
+		return parseHTMLTables(block)
+	}
+	if t := parseMarkdownTable(block); t != nil {
+		return []*extractedTable{t}
+	}
+	return nil
+}
+
+// parseMarkdownTable parses a pipe table with a header and delimiter row
+func parseMarkdownTable(block string) *extractedTable {
+	lines := strings.Split(strings.TrimSpace(block), "\n")
+	if len(lines) < 2 || !isTableLine(lines[0]) || !tableDelimiterRe.MatchString(lines[1]) {
+		return nil
+	}
+
+	t := &extractedTable{header: splitTableRow(lines[0])}
+	for _, line := range lines[2:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		t.rows = append(t.rows, splitTableRow(line))
+	}
+	return t
+}
+
+// splitTableRow splits a pipe table row into trimmed cells, honouring
+// escaped pipes
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// parseHTMLTables extracts every top-level <table> in src. The header is the
+// <thead> row, or the first row when it consists only of <th> cells.
+func parseHTMLTables(src string) []*extractedTable {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return nil
+	}
+
+	var tables []*extractedTable
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Table {
+			tables = append(tables, parseHTMLTable(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+	return tables
+}
+
+func parseHTMLTable(table *html.Node) *extractedTable {
+	t := &extractedTable{}
+	headerFromHead := false
+
+	var walk func(n *html.Node, inHead bool)
+	walk = func(n *html.Node, inHead bool) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Caption:
+				t.caption = nodeText(c)
+			case atom.Thead:
+				walk(c, true)
+			case atom.Tbody, atom.Tfoot:
+				walk(c, false)
+			case atom.Tr:
+				cells, allHeader := htmlRowCells(c)
+				switch {
+				case inHead && t.header == nil:
+					t.header = cells
+					headerFromHead = true
+				case !headerFromHead && t.header == nil && len(t.rows) == 0 && allHeader:
+					t.header = cells
+				default:
+					t.rows = append(t.rows, cells)
+				}
+			}
+		}
+	}
+	walk(table, false)
+	return t
+}
+
+// htmlRowCells returns a row's cell texts and whether all cells are <th>
+func htmlRowCells(tr *html.Node) ([]string, bool) {
+	var cells []string
+	allHeader := true
+	for c := tr.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || (c.DataAtom != atom.Td && c.DataAtom != atom.Th) {
+			continue
+		}
+		if c.DataAtom == atom.Td {
+			allHeader = false
+		}
+		cells = append(cells, nodeText(c))
+	}
+	return cells, allHeader && len(cells) > 0
+}
+
+// nodeText returns the text inside n with whitespace collapsed, skipping
+// nested tables
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		case n.Type == html.ElementNode && n.DataAtom == atom.Table:
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// linearize renders each row as "column: value" pairs, one row per line,
+// which embeds far better than pipe syntax or markup. Empty cells are left
+// out and columns without a header are numbered.
+func (t *extractedTable) linearize() []string {
+	lines := make([]string, 0, len(t.rows))
+	for _, row := range t.rows {
+		var pairs []string
+		for i, value := range row {
+			if value == "" {
+				continue
+			}
+			column := ""
+			if i < len(t.header) {
+				column = t.header[i]
+			}
+			if column == "" {
+				column = "column " + strconv.Itoa(i+1)
+			}
+			pairs = append(pairs, column+": "+value)
+		}
+		if len(pairs) > 0 {
+			lines = append(lines, strings.Join(pairs, "; "))
+		}
+	}
+	return lines
+}
+
+// tableChunks renders tables as dedicated chunks of linearized rows, up to
+// maxSize each. Chunks record the 1-based row range they cover; base
+// metadata (e.g. the heading path) is copied to each.
+func tableChunks(tables []*extractedTable, maxSize int, base map[string]string) []chunk {
+	var chunks []chunk
+	for _, t := range tables {
+		meta := func(first, last int) map[string]string {
+			m := make(map[string]string, len(base)+5)
+			for k, v := range base {
+				m[k] = v
+			}
+			m["chunk_type"] = "table"
+			m["row_start"] = strconv.Itoa(first + 1)
+			m["row_end"] = strconv.Itoa(last + 1)
+			if len(t.header) > 0 {
+				m["table_columns"] = strings.Join(t.header, ", ")
+			}
+			if t.caption != "" {
+				m["table_caption"] = t.caption
+			}
+			return m
+		}
+
+		prefix := ""
+		if t.caption != "" {
+			prefix = t.caption + "\n"
+		}
+		lines := t.linearize()
+		if len(lines) == 0 {
+			continue
+		}
+
+		var current strings.Builder
+		first := 0
+		for i, line := range lines {
+			if i > first && len(prefix)+current.Len()+len(line)+1 > maxSize {
+				chunks = append(chunks, chunk{Content: prefix + current.String(), Metadata: meta(first, i-1)})
+				current.Reset()
+				first = i
+			}
+			if current.Len() > 0 {
+				current.WriteString("\n")
+			}
+			current.WriteString(line)
+		}
+		chunks = append(chunks, chunk{Content: prefix + current.String(), Metadata: meta(first, len(lines)-1)})
+	}
+	return chunks
+}
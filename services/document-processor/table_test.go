@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTables(t *testing.T) {
+	tests := []struct {
+		name  string
+		block string
+		want  []*extractedTable
+	}{
+		{
+			name:  "pipe table with escaped pipes",
+			block: "| Flag | Meaning |\n| :--- | ---: |\n| `-a` | all \\| any |\n|  | blank |",
+			want:  []*extractedTable{{header: []string{"Flag", "Meaning"}, rows: [][]string{{"`-a`", "all | any"}, {"", "blank"}}}},
+		},
+		{
+			name:  "html table with thead and caption",
+			block: "<table><caption>Plans</caption><thead><tr><th>Plan</th><th>Quota</th></tr></thead>\n\n<tbody><tr><td>Free</td><td> 10\n GB </td></tr></tbody></table>",
+			want:  []*extractedTable{{caption: "Plans", header: []string{"Plan", "Quota"}, rows: [][]string{{"Free", "10 GB"}}}},
+		},
+		{
+			name:  "html header from a first row of th cells",
+			block: "<table><tr><th>A</th></tr><tr><td>1</td></tr></table><table><tr><td>x</td></tr></table>",
+			want: []*extractedTable{
+				{header: []string{"A"}, rows: [][]string{{"1"}}},
+				{rows: [][]string{{"x"}}},
+			},
+		},
+		{name: "no delimiter row", block: "| a | b |\n| c | d |"},
+		{name: "table in a code block", block: "```\n| a |\n|---|\n```"},
+		{name: "prose", block: "Just text."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTables(tt.block); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTables() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableChunks(t *testing.T) {
+	table := &extractedTable{
+		caption: "Plans",
+		header:  []string{"Plan", ""},
+		rows:    [][]string{{"Free", "10"}, {"Pro", ""}, {"", ""}, {"Team", "1000"}},
+	}
+
+	tests := []struct {
+		name     string
+		maxSize  int
+		want     []string
+		wantRows [][2]string
+	}{
+		{
+			name:     "one chunk",
+			maxSize:  1000,
+			want:     []string{"Plans\nPlan: Free; column 2: 10\nPlan: Pro\nPlan: Team; column 2: 1000"},
+			wantRows: [][2]string{{"1", "3"}},
+		},
+		{
+			name:     "split by size, each with the caption",
+			maxSize:  40,
+			want:     []string{"Plans\nPlan: Free; column 2: 10\nPlan: Pro", "Plans\nPlan: Team; column 2: 1000"},
+			wantRows: [][2]string{{"1", "2"}, {"3", "3"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := tableChunks([]*extractedTable{table}, tt.maxSize, map[string]string{"heading_path": "Pricing"})
+			var got []string
+			var rows [][2]string
+			for _, c := range chunks {
+				got = append(got, c.Content)
+				rows = append(rows, [2]string{c.Metadata["row_start"], c.Metadata["row_end"]})
+				if c.Metadata["heading_path"] != "Pricing" || c.Metadata["table_caption"] != "Plans" || c.Metadata["table_columns"] != "Plan, " {
+					t.Errorf("metadata = %v", c.Metadata)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("chunks = %q rows %v, want %q rows %v", got, rows, tt.want, tt.wantRows)
+			}
+		})
+	}
+}
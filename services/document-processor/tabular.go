@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+)
+
+// tabularDelimiter returns the field separator for CSV/TSV files, or 0 otherwise
+func tabularDelimiter(ext string) rune {
+	switch strings.ToLower(ext) {
+	case ".csv":
+		return ','
+	case ".tsv", ".tab":
+		return '\t'
+	}
+	return 0
+}
+
+// chunkTabular groups rows so every chunk repeats the header followed by up
+// to rowsPerChunk data rows, ending a group early if it would exceed maxSize.
+// Chunks record the 1-based data row range they cover.
+func (p *DocumentProcessor) chunkTabular(src string, delimiter rune, maxSize int) ([]chunk, error) {
+	reader := csv.NewReader(strings.NewReader(src))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []chunk{}, nil
+	}
+
+	header := encodeRecord(records[0], delimiter)
+	rows := records[1:]
+	if len(rows) == 0 {
+		return []chunk{{Content: strings.TrimSpace(header)}}, nil
+	}
+
+	var chunks []chunk
+	var current strings.Builder
+	first := 0
+	flush := func(last int) {
+		chunks = append(chunks, chunk{
+			Content: strings.TrimSpace(header + current.String()),
+			Metadata: map[string]string{
+				"row_start": strconv.Itoa(first + 1),
+				"row_end":   strconv.Itoa(last + 1),
+			},
+		})
+		current.Reset()
+	}
+
+	for i, record := range rows {
+		line := encodeRecord(record, delimiter)
+		count := i - first
+		if count > 0 && (count >= p.rowsPerChunk || len(header)+current.Len()+len(line) > maxSize) {
+			flush(i - 1)
+			first = i
+		}
+		current.WriteString(line)
+	}
+	flush(len(rows) - 1)
+
+	return chunks, nil
+}
+
+// encodeRecord renders a record back to delimited text with quoting as needed
+func encodeRecord(record []string, delimiter rune) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = delimiter
+	_ = w.Write(record)
+	w.Flush()
+	return buf.String()
+}
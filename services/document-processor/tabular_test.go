@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunkTabular(t *testing.T) {
+	type want struct {
+		content    string
+		start, end string
+	}
+
+	tests := []struct {
+		name         string
+		src          string
+		delimiter    rune
+		rowsPerChunk int
+		maxSize      int
+		want         []want
+		wantErr      bool
+	}{
+		{
+			name:         "rows per chunk repeat the header",
+			src:          "name,qty\napple,1\npear,2\nplum,3\n",
+			delimiter:    ',',
+			rowsPerChunk: 2,
+			maxSize:      1000,
+			want:         []want{{"name,qty\napple,1\npear,2", "1", "2"}, {"name,qty\nplum,3", "3", "3"}},
+		},
+		{
+			name:         "size ends a group early",
+			src:          "name,qty\napple,1\npear,2\nplum,3\n",
+			delimiter:    ',',
+			rowsPerChunk: 20,
+			maxSize:      20,
+			want:         []want{{"name,qty\napple,1", "1", "1"}, {"name,qty\npear,2", "2", "2"}, {"name,qty\nplum,3", "3", "3"}},
+		},
+		{
+			name:         "tsv with quoted fields",
+			src:          "id\tnote\n1\t\"tab\there\"\n2\tplain",
+			delimiter:    '\t',
+			rowsPerChunk: 20,
+			maxSize:      1000,
+			want:         []want{{"id\tnote\n1\t\"tab\there\"\n2\tplain", "1", "2"}},
+		},
+		{
+			name:         "header only",
+			src:          "name,qty\n",
+			delimiter:    ',',
+			rowsPerChunk: 20,
+			maxSize:      1000,
+			want:         []want{{"name,qty", "", ""}},
+		},
+		{
+			name:         "ragged rows are kept",
+			src:          "a,b\n1\n2,3,4",
+			delimiter:    ',',
+			rowsPerChunk: 20,
+			maxSize:      1000,
+			want:         []want{{"a,b\n1\n2,3,4", "1", "2"}},
+		},
+		{name: "empty", src: "", delimiter: ',', rowsPerChunk: 20, maxSize: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewDocumentProcessor(1000, 0, tt.rowsPerChunk, nil, false, false, nil)
+			chunks, err := p.chunkTabular(tt.src, tt.delimiter, tt.maxSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []want
+			for _, c := range chunks {
+				got = append(got, want{c.Content, c.Metadata["row_start"], c.Metadata["row_end"]})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for ext, want := range map[string]rune{".csv": ',', ".TSV": '\t', ".tab": '\t', ".txt": 0} {
+		if got := tabularDelimiter(ext); got != want {
+			t.Errorf("tabularDelimiter(%q) = %q, want %q", ext, got, want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Input token limits of common embedding models
+var modelTokenLimits = map[string]int{
+	"text-embedding-ada-002":          8191,
+	"text-embedding-3-small":          8191,
+	"text-embedding-3-large":          8191,
+	"embed-english-v3.0":              512,
+	"embed-multilingual-v3.0":         512,
+	"embed-english-light-v3.0":        512,
+	"embed-multilingual-light-v3.0":   512,
+	"nomic-embed-text":                8192,
+	"mxbai-embed-large":               512,
+	"all-minilm":                      256,
+	"bge-m3":                          8192,
+	"bge-large-en-v1.5":               512,
+	"bge-base-en-v1.5":                512,
+	"bge-small-en-v1.5":               512,
+	"text-embedding-004":              2048,
+	"text-multilingual-embedding-002": 2048,
+	"textembedding-gecko":             3072,
+	"amazon.titan-embed-text-v1":      8192,
+	"amazon.titan-embed-text-v2:0":    8192,
+	"cohere.embed-english-v3":         512,
+	"cohere.embed-multilingual-v3":    512,
+}
+
+// Tokenizers average about four bytes per token on English text; assuming
+// three keeps the estimate above the real count for code and non-English text
+const bytesPerTokenEstimate = 3
+
+// modelTokenLimit resolves the token limit for a request: an explicit
+// maxTokens wins, otherwise the model must be known. Zero means no limit.
+func modelTokenLimit(model string, maxTokens int) (int, error) {
+	if maxTokens > 0 {
+		return maxTokens, nil
+	}
+	if model == "" {
+		return 0, nil
+	}
+	if limit, ok := modelTokenLimits[strings.ToLower(model)]; ok {
+		return limit, nil
+	}
+
+	known := make([]string, 0, len(modelTokenLimits))
+	for name := range modelTokenLimits {
+		known = append(known, name)
+	}
+	sort.Strings(known)
+	return 0, fmt.Errorf("unknown embedding model %q; set max_tokens or use one of: %s", model, strings.Join(known, ", "))
+}
+
+// estimateTokens is a conservative token count: the larger of the word and
+// punctuation count and the byte length divided by bytesPerTokenEstimate
+func estimateTokens(text string) int {
+	words := len(tokenRe.FindAllStringIndex(text, -1))
+	bytesEstimate := (len(text) + bytesPerTokenEstimate - 1) / bytesPerTokenEstimate
+	if words > bytesEstimate {
+		return words
+	}
+	return bytesEstimate
+}
+
+// enforceTokenLimit splits any chunk whose estimated token count exceeds
+// limit, copying its metadata to each part
+func (p *DocumentProcessor) enforceTokenLimit(chunks []chunk, limit int) []chunk {
+	if limit <= 0 {
+		return chunks
+	}
+
+	out := make([]chunk, 0, len(chunks))
+	for _, c := range chunks {
+		if estimateTokens(c.Content) <= limit {
+			out = append(out, c)
+			continue
+		}
+		for _, part := range p.splitToTokenLimit(c.Content, limit) {
+			meta := make(map[string]string, len(c.Metadata)+1)
+			for k, v := range c.Metadata {
+				meta[k] = v
+			}
+			meta["token_limit_split"] = "true"
+			out = append(out, chunk{Content: part, Metadata: meta})
+		}
+	}
+	return out
+}
+
+// splitToTokenLimit cuts text into pieces sized in proportion to how far it
+// is over the limit, recursing on any piece that is still too large
+func (p *DocumentProcessor) splitToTokenLimit(text string, limit int) []string {
+	tokens := estimateTokens(text)
+	if tokens <= limit {
+		return []string{text}
+	}
+
+	size := len(text) * limit / tokens
+	if size < 1 {
+		size = 1
+	}
+
+	var parts []string
+	for _, part := range p.splitIntoChunks(text, size, chunkOverlap{}) {
+		if estimateTokens(part) > limit && len(part) < len(text) {
+			parts = append(parts, p.splitToTokenLimit(part, limit)...)
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
@@ -0,0 +1,216 @@
+//go:build cgo
+
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/csharp"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/kotlin"
+	"github.com/smacker/go-tree-sitter/php"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/ruby"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/scala"
+	"github.com/smacker/go-tree-sitter/swift"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// treeSitterGrammars maps file extensions to tree-sitter grammars
+var treeSitterGrammars = map[string]func() *sitter.Language{
+	".py":    python.GetLanguage,
+	".js":    javascript.GetLanguage,
+	".jsx":   javascript.GetLanguage,
+	".mjs":   javascript.GetLanguage,
+	".cjs":   javascript.GetLanguage,
+	".ts":    typescript.GetLanguage,
+	".mts":   typescript.GetLanguage,
+	".tsx":   tsx.GetLanguage,
+	".java":  java.GetLanguage,
+	".kt":    kotlin.GetLanguage,
+	".scala": scala.GetLanguage,
+	".rs":    rust.GetLanguage,
+	".rb":    ruby.GetLanguage,
+	".c":     c.GetLanguage,
+	".h":     c.GetLanguage,
+	".cc":    cpp.GetLanguage,
+	".cpp":   cpp.GetLanguage,
+	".hpp":   cpp.GetLanguage,
+	".cs":    csharp.GetLanguage,
+	".php":   php.GetLanguage,
+	".swift": swift.GetLanguage,
+}
+
+// hasTreeSitterGrammar reports whether files with ext are chunked syntactically
+func hasTreeSitterGrammar(ext string) bool {
+	_, ok := treeSitterGrammars[strings.ToLower(ext)]
+	return ok
+}
+
+// chunkTreeSitter splits source code at syntactic boundaries (classes,
+// functions, methods) using the tree-sitter grammar for its extension.
+// Nodes larger than maxSize are split at their children, so an oversized
+// class is divided between methods with the class header kept on the first.
+func (p *DocumentProcessor) chunkTreeSitter(ctx context.Context, ext, src string, maxSize int, overlap chunkOverlap) ([]chunk, error) {
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(treeSitterGrammars[strings.ToLower(ext)]())
+
+	source := []byte(src)
+	tree, err := parser.ParseCtx(ctx, nil, source)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+
+	root := tree.RootNode()
+	definitions := definitionSymbols(source, root, "")
+	var segments []codeSegment
+	for _, r := range splitNode(source, root, 0, uint32(len(source)), maxSize) {
+		segment := codeSegment{text: src[r[0]:r[1]]}
+		for _, d := range definitions {
+			if d.offset >= r[0] && d.offset < r[1] {
+				segment.symbols = append(segment.symbols, d.name)
+			}
+		}
+		segments = append(segments, segment)
+	}
+
+	return p.packSegments(segments, maxSize, overlap), nil
+}
+
+// definedSymbol is a named definition and the byte offset where it starts
+type definedSymbol struct {
+	offset uint32
+	name   string
+}
+
+var symbolNameRe = regexp.MustCompile(`^[\p{L}_$][\p{L}\p{N}_$]*$`)
+
+// definitionSymbols lists the functions, classes, methods, and other named
+// definitions under n. Grammars name definitions through a "name" field (or,
+// for C and C++ functions, nested declarators); nested definitions are
+// qualified with their parents, e.g. "Class.method".
+func definitionSymbols(source []byte, n *sitter.Node, prefix string) []definedSymbol {
+	var symbols []definedSymbol
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		child := n.NamedChild(i)
+		childPrefix := prefix
+
+		if name := definitionName(source, child); name != "" {
+			qualified := name
+			if prefix != "" {
+				qualified = prefix + "." + name
+			}
+			symbols = append(symbols, definedSymbol{offset: child.StartByte(), name: qualified})
+			childPrefix = qualified
+		} else if child.Type() == "impl_item" {
+			// Rust impl blocks qualify their methods with the implemented type
+			if t := child.ChildByFieldName("type"); t != nil {
+				childPrefix = t.Content(source)
+			}
+		}
+		symbols = append(symbols, definitionSymbols(source, child, childPrefix)...)
+	}
+	return symbols
+}
+
+// definitionName returns the name a definition node declares, or ""
+func definitionName(source []byte, n *sitter.Node) string {
+	typ := n.Type()
+	isDefinition := typ == "class" || typ == "module" || typ == "method" || typ == "singleton_method"
+	for _, suffix := range []string{"_definition", "_declaration", "_item", "_specifier"} {
+		isDefinition = isDefinition || strings.HasSuffix(typ, suffix)
+	}
+	for _, part := range []string{"field", "parameter", "variable", "local", "import", "use_"} {
+		// Members and locals, not definitions worth indexing
+		isDefinition = isDefinition && !strings.Contains(typ, part)
+	}
+	if !isDefinition {
+		return ""
+	}
+
+	name := n.ChildByFieldName("name")
+	if name == nil && strings.Contains(typ, "function") {
+		// C and C++ functions name themselves through nested declarators
+		for d := n.ChildByFieldName("declarator"); d != nil; d = d.ChildByFieldName("declarator") {
+			name = d
+		}
+	}
+	if name == nil {
+		return ""
+	}
+	if text := name.Content(source); symbolNameRe.MatchString(text) {
+		return text
+	}
+	return ""
+}
+
+// splitNode partitions the byte range [lo, hi) of a node into ranges that
+// start at its named children, recursing into children that are too large.
+// Comments stay attached to the declaration that follows them.
+func splitNode(source []byte, n *sitter.Node, lo, hi uint32, maxSize int) [][2]uint32 {
+	count := int(n.NamedChildCount())
+	if int(hi-lo) <= maxSize || count == 0 {
+		return [][2]uint32{{lo, hi}}
+	}
+
+	children := make([]*sitter.Node, 0, count)
+	for i := 0; i < count; i++ {
+		children = append(children, n.NamedChild(i))
+	}
+
+	// Cut before each child unless it follows a comment; the text before the
+	// first child (a class or function header) stays with that child. Each
+	// range remembers its declaration so oversized ranges can be split further.
+	cuts := []uint32{lo}
+	owners := []*sitter.Node{nil}
+	for i, child := range children {
+		start := lineStart(source, child.StartByte())
+		if i > 0 && !isCommentNode(children[i-1]) && start > cuts[len(cuts)-1] && start < hi {
+			cuts = append(cuts, start)
+			owners = append(owners, nil)
+		}
+		if !isCommentNode(child) {
+			owners[len(owners)-1] = child
+		}
+	}
+	cuts = append(cuts, hi)
+
+	var ranges [][2]uint32
+	for i := 0; i < len(cuts)-1; i++ {
+		a, b := cuts[i], cuts[i+1]
+		if int(b-a) > maxSize && owners[i] != nil {
+			ranges = append(ranges, splitNode(source, owners[i], a, b, maxSize)...)
+			continue
+		}
+		ranges = append(ranges, [2]uint32{a, b})
+	}
+	return ranges
+}
+
+// lineStart moves an offset back to the start of its line when only
+// indentation precedes it, so chunks keep their leading whitespace
+func lineStart(source []byte, offset uint32) uint32 {
+	i := offset
+	for i > 0 && (source[i-1] == ' ' || source[i-1] == '\t') {
+		i--
+	}
+	if i == 0 || source[i-1] == '\n' {
+		return i
+	}
+	return offset
+}
+
+// isCommentNode reports whether a node is a comment in any grammar
+func isCommentNode(n *sitter.Node) bool {
+	return strings.Contains(n.Type(), "comment")
+}
@@ -0,0 +1,18 @@
+//go:build !cgo
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// Tree-sitter grammars are C libraries; builds without cgo fall back to text chunking
+
+func hasTreeSitterGrammar(ext string) bool {
+	return false
+}
+
+func (p *DocumentProcessor) chunkTreeSitter(ctx context.Context, ext, src string, maxSize int, overlap chunkOverlap) ([]chunk, error) {
+	return nil, errors.New("tree-sitter chunking requires cgo")
+}
@@ -0,0 +1,96 @@
+//go:build cgo
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestChunkTreeSitter(t *testing.T) {
+	const python = `import os
+
+# Greets people
+class Greeter:
+    def __init__(self, name):
+        self.name = name
+
+    def greet(self):
+        return "hello " + self.name
+
+def main():
+    print(Greeter(os.getenv("USER")).greet())
+`
+
+	tests := []struct {
+		name        string
+		ext         string
+		src         string
+		maxSize     int
+		wantSymbols []string
+		wantFirst   string // prefix of the first chunk
+	}{
+		{
+			name:        "python in one chunk",
+			ext:         ".py",
+			src:         python,
+			maxSize:     1000,
+			wantSymbols: []string{"Greeter,Greeter.__init__,Greeter.greet,main"},
+			wantFirst:   "import os",
+		},
+		{
+			name:        "oversized class is split between methods",
+			ext:         ".py",
+			src:         python,
+			maxSize:     110,
+			wantSymbols: []string{"Greeter,Greeter.__init__", "Greeter.greet", "main"},
+			wantFirst:   "import os\n\n# Greets people\nclass Greeter:",
+		},
+		{
+			name:        "javascript class and function",
+			ext:         ".JS",
+			src:         "class Cart {\n  add(item) { this.items.push(item) }\n}\n\nfunction total(cart) { return cart.items.length }\n",
+			maxSize:     1000,
+			wantSymbols: []string{"Cart,Cart.add,total"},
+			wantFirst:   "class Cart {",
+		},
+		{
+			name:        "c functions are named through declarators",
+			ext:         ".c",
+			src:         "static int add(int a, int b) {\n  return a + b;\n}\n\nint *first(int *xs) {\n  return xs;\n}\n",
+			maxSize:     50,
+			wantSymbols: []string{"add", "first"},
+			wantFirst:   "static int add",
+		},
+		{
+			name:        "rust impl methods are qualified with the type",
+			ext:         ".rs",
+			src:         "struct Point { x: i32 }\n\nimpl Point {\n    fn norm(&self) -> i32 { self.x }\n}\n",
+			maxSize:     1000,
+			wantSymbols: []string{"Point,Point.norm"},
+			wantFirst:   "struct Point",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !hasTreeSitterGrammar(tt.ext) {
+				t.Fatalf("no grammar for %s", tt.ext)
+			}
+			chunks, err := newTestProcessor().chunkTreeSitter(context.Background(), tt.ext, tt.src, tt.maxSize, chunkOverlap{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chunkSymbols(chunks); strings.Join(got, "|") != strings.Join(tt.wantSymbols, "|") {
+				t.Errorf("symbols = %q, want %q", got, tt.wantSymbols)
+			}
+			if len(chunks) == 0 || !strings.HasPrefix(chunks[0].Content, tt.wantFirst) {
+				t.Errorf("chunks = %q, want the first to start with %q", chunks, tt.wantFirst)
+			}
+		})
+	}
+
+	if hasTreeSitterGrammar(".md") {
+		t.Error("markdown has a tree-sitter grammar")
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// projectClient reads project filter rules from the metadata service
+type projectClient struct {
+	url        string
+	apiKey     string // sent as X-API-Key if set
+	httpClient *http.Client
+}
+
+func newProjectClient(url, apiKey string) *projectClient {
+	return &projectClient{url: url, apiKey: apiKey, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// project gets a project's settings, or a NotFound error if it is not registered
+func (c *projectClient) project(ctx context.Context, projectID string) (*models.Project, error) {
+	url := fmt.Sprintf("%s/projects?%s", c.url, neturl.Values{"id": {projectID}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.External("metadata", "failed to get project", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.NotFound(fmt.Sprintf("project %s", projectID))
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, errors.External("metadata", fmt.Sprintf("get project failed: %s", body), nil)
+	}
+
+	var project models.Project
+	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+		return nil, errors.External("metadata", "invalid project response", err)
+	}
+	return &project, nil
+}
+
+// useProjectRules checks /validate requests against the rules of the project
+// they name, read from the metadata service at url with apiKey, with defaults
+// filling in whatever a project leaves unset
+func (p *DocumentProcessor) useProjectRules(url, apiKey string, defaults filter.Rules) {
+	p.projects = newProjectClient(url, apiKey)
+	p.defaultRules = defaults
+}
+
+// rulesFor returns the filter rules of a project, or the defaults without one
+func (p *DocumentProcessor) rulesFor(ctx context.Context, projectID string) (filter.Rules, error) {
+	if projectID == "" || p.projects == nil {
+		return p.defaultRules, nil
+	}
+	project, err := p.projects.project(ctx, projectID)
+	if err != nil {
+		return filter.Rules{}, err
+	}
+	return filter.ForProject(project, p.defaultRules), nil
+}
+
+// ValidateRequest lists files to check against a project's filter rules,
+// which are loaded from the metadata service; without a project the
+// configured defaults apply. Plain paths are checked as added files; file
+// changes also carry their change type.
+type ValidateRequest struct {
+	ProjectID   string               `json:"project_id,omitempty"`
+	FilePaths   []string             `json:"file_paths,omitempty"`
+	FileChanges []*models.FileChange `json:"file_changes,omitempty"`
+}
+
+// ValidateResult is the filter decision for one file
+type ValidateResult struct {
+	FilePath string `json:"file_path"`
+	Accepted bool   `json:"accepted"`
+	Reason   string `json:"reason,omitempty"` // extension, exclude_pattern, or deleted
+	Detail   string `json:"detail,omitempty"` // the offending extension, pattern, or change type
+}
+
+type ValidateResponse struct {
+	Rules    filter.Rules      `json:"rules"` // the rules the files were checked against
+	Results  []*ValidateResult `json:"results"`
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+}
+
+// handleValidate reports, per file, whether ValidateDocument would accept it
+// and which rule rejected it otherwise, without fetching or chunking content
+func (p *DocumentProcessor) handleValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ValidateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.FilePaths) == 0 && len(req.FileChanges) == 0 {
+		http.Error(w, "file_paths or file_changes is required", http.StatusBadRequest)
+		return
+	}
+
+	files := make([]*models.FileChange, 0, len(req.FilePaths)+len(req.FileChanges))
+	for _, path := range req.FilePaths {
+		files = append(files, &models.FileChange{FilePath: path, ChangeType: "added"})
+	}
+	for _, fileChange := range req.FileChanges {
+		if fileChange == nil {
+			http.Error(w, "file_changes must not contain null entries", http.StatusBadRequest)
+			return
+		}
+		files = append(files, fileChange)
+	}
+
+	rules, err := p.rulesFor(r.Context(), req.ProjectID)
+	if err != nil {
+		var appErr *errors.AppError
+		if stderrors.As(err, &appErr) && appErr.Type == errors.ErrTypeNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to load filter rules for project %s: %v", req.ProjectID, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	resp := ValidateResponse{Rules: rules, Results: make([]*ValidateResult, 0, len(files))}
+	for _, fileChange := range files {
+		decision := filter.Check(fileChange, rules)
+		result := &ValidateResult{
+			FilePath: fileChange.FilePath,
+			Accepted: decision.Accepted(),
+			Reason:   decision.Reason,
+			Detail:   decision.Detail,
+		}
+		if result.Accepted {
+			resp.Accepted++
+		} else {
+			resp.Rejected++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestHandleValidate(t *testing.T) {
+	var apiKeys []string
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeys = append(apiKeys, r.Header.Get("X-API-Key"))
+		switch r.URL.Query().Get("id") {
+		case "docs":
+			_ = json.NewEncoder(w).Encode(&models.Project{ID: "docs", AllowedExtensions: []string{".rst"}})
+		case "broken":
+			http.Error(w, "database locked", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer metadata.Close()
+
+	p := newTestProcessor()
+	p.useProjectRules(metadata.URL, "secret", filter.Rules{
+		AllowedExtensions: []string{".md", ".go"},
+		ExcludePatterns:   []string{"vendor"},
+	})
+
+	type result struct {
+		path, reason, detail string
+	}
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantError  string
+		want       []result
+	}{
+		{
+			name:       "default rules",
+			body:       `{"file_paths": ["README.md", "vendor/lib/a.go", "logo.png"]}`,
+			wantStatus: http.StatusOK,
+			want: []result{
+				{path: "README.md"},
+				{path: "vendor/lib/a.go", reason: filter.RejectExcludePattern, detail: "vendor"},
+				{path: "logo.png", reason: filter.RejectExtension, detail: ".png"},
+			},
+		},
+		{
+			name:       "file changes carry their change type",
+			body:       `{"file_changes": [{"file_path": "docs/old.md", "change_type": "removed"}, {"file_path": "docs/new.md", "change_type": "added"}]}`,
+			wantStatus: http.StatusOK,
+			want: []result{
+				{path: "docs/old.md", reason: filter.RejectDeleted, detail: "removed"},
+				{path: "docs/new.md"},
+			},
+		},
+		{
+			// The project's extensions replace the defaults; its unset
+			// exclude patterns keep them
+			name:       "project rules",
+			body:       `{"project_id": "docs", "file_paths": ["guide.rst", "README.md", "vendor/x.rst"]}`,
+			wantStatus: http.StatusOK,
+			want: []result{
+				{path: "guide.rst"},
+				{path: "README.md", reason: filter.RejectExtension, detail: ".md"},
+				{path: "vendor/x.rst", reason: filter.RejectExcludePattern, detail: "vendor"},
+			},
+		},
+		{name: "unknown project", body: `{"project_id": "nope", "file_paths": ["a.md"]}`, wantStatus: http.StatusNotFound},
+		{name: "metadata failure", body: `{"project_id": "broken", "file_paths": ["a.md"]}`, wantStatus: http.StatusBadGateway, wantError: "database locked"},
+		{name: "nothing to check", body: `{"project_id": "docs"}`, wantStatus: http.StatusBadRequest},
+		{name: "null file change", body: `{"file_changes": [null]}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid body", body: `{"file_paths": "a.md"}`, wantStatus: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			rec := httptest.NewRecorder()
+			p.handleValidate(rec, httptest.NewRequest(method, "/validate", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			if tt.wantError != "" && !strings.Contains(rec.Body.String(), tt.wantError) {
+				t.Errorf("body = %q, want it to contain %q", rec.Body.String(), tt.wantError)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var resp ValidateResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			got := make([]result, len(resp.Results))
+			accepted := 0
+			for i, r := range resp.Results {
+				got[i] = result{r.FilePath, r.Reason, r.Detail}
+				if r.Accepted != (r.Reason == "") {
+					t.Errorf("%s: accepted = %v with reason %q", r.FilePath, r.Accepted, r.Reason)
+				}
+				if r.Accepted {
+					accepted++
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results = %+v\nwant %+v", got, tt.want)
+			}
+			if resp.Accepted != accepted || resp.Rejected != len(got)-accepted {
+				t.Errorf("counts = %d accepted, %d rejected", resp.Accepted, resp.Rejected)
+			}
+		})
+	}
+
+	for _, key := range apiKeys {
+		if key != "secret" {
+			t.Errorf("metadata request sent API key %q, want secret", key)
+		}
+	}
+	if len(apiKeys) != 3 {
+		t.Errorf("made %d metadata requests, want one per project lookup", len(apiKeys))
+	}
+}
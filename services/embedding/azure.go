@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+// AzureOpenAIEmbedder implements interfaces.EmbeddingService with an Azure
+// OpenAI embeddings deployment
+type AzureOpenAIEmbedder struct {
+	client     *azopenai.Client
+	deployment string
+	dimensions int // requested output size, 0 for the model's own
+	dimension  learnedDimension
+}
+
+func init() {
+	registerProvider(ProviderAzureOpenAI, func(_ context.Context, cfg *config.Config) (embeddingProvider, error) {
+		if len(cfg.AzureOpenAI.Failover) > 0 {
+			return newAzureDeploymentPool(cfg)
+		}
+		client, err := newAzureOpenAIClient(cfg.AzureOpenAI.Endpoint, cfg.AzureOpenAI.APIKey, cfg.Embedding.MaxAttempts)
+		if err != nil {
+			return nil, err
+		}
+		return NewAzureOpenAIEmbedder(client, cfg.AzureOpenAI.EmbeddingsDeployment, cfg.Embedding.Dimensions), nil
+	})
+}
+
+// newAzureOpenAIClient creates a client for an Azure OpenAI resource. The
+// SDK retries 429 and 5xx responses, honoring Retry-After, up to maxAttempts
+// attempts in total.
+func newAzureOpenAIClient(endpoint, apiKey string, maxAttempts int) (*azopenai.Client, error) {
+	keyCredential := azcore.NewKeyCredential(apiKey)
+	retries := int32(max(maxAttempts, 1) - 1)
+	if retries == 0 {
+		retries = -1 // zero would mean the SDK default
+	}
+	options := &azopenai.ClientOptions{}
+	options.Retry = policy.RetryOptions{
+		MaxRetries:    retries,
+		RetryDelay:    retryBaseDelay,
+		MaxRetryDelay: retryMaxDelay,
+	}
+	client, err := azopenai.NewClientWithKeyCredential(endpoint, keyCredential, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure OpenAI client: %w", err)
+	}
+	return client, nil
+}
+
+// NewAzureOpenAIEmbedder creates an embedder for the given deployment.
+// dimensions shortens embeddings of text-embedding-3 deployments; 0 keeps
+// the model's own size.
+func NewAzureOpenAIEmbedder(client *azopenai.Client, deployment string, dimensions int) *AzureOpenAIEmbedder {
+	dimension := 1536 // text-embedding-ada-002 dimension, corrected by the first response
+	if dimensions > 0 {
+		dimension = dimensions
+	}
+	return &AzureOpenAIEmbedder{
+		client:     client,
+		deployment: deployment,
+		dimensions: dimensions,
+		dimension:  learnedDimension{value: dimension},
+	}
+}
+
+// GenerateEmbedding creates a vector embedding for text
+func (e *AzureOpenAIEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return firstEmbedding(e.GenerateBatchEmbeddings(ctx, []string{text}))
+}
+
+// GenerateBatchEmbeddings creates embeddings for multiple texts
+func (e *AzureOpenAIEmbedder) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	options := azopenai.EmbeddingsOptions{
+		Input:          texts,
+		DeploymentName: &e.deployment,
+	}
+	if e.dimensions > 0 {
+		dimensions := int32(e.dimensions)
+		options.Dimensions = &dimensions
+	}
+	resp, err := e.client.GetEmbeddings(ctx, options, nil)
+
+	if err != nil {
+		return nil, errors.External("Azure OpenAI", "failed to generate embeddings", err)
+	}
+
+	embeddings := make([][]float32, len(resp.Data))
+	for i, item := range resp.Data {
+		embeddings[i] = item.Embedding
+	}
+	if len(embeddings) > 0 {
+		e.dimension.learn(len(embeddings[0]))
+	}
+	if resp.Usage != nil && resp.Usage.PromptTokens != nil {
+		recordUsage(ctx, int(*resp.Usage.PromptTokens))
+	}
+
+	logger.Info("Generated %d embeddings", len(embeddings))
+	return embeddings, nil
+}
+
+// GetDimension returns the dimension of embeddings
+func (e *AzureOpenAIEmbedder) GetDimension() int {
+	return e.dimension.get()
+}
+
+// Azure OpenAI accepts at most 2048 inputs per embeddings request
+const azureMaxBatch = 2048
+
+// MaxBatchSize returns the most texts per request
+func (e *AzureOpenAIEmbedder) MaxBatchSize() int {
+	return azureMaxBatch
+}
+
+// Model returns the embeddings deployment
+func (e *AzureOpenAIEmbedder) Model() string {
+	return e.deployment
+}
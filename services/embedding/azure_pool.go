@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+// A deployment that throttled or failed is passed over for this long
+// unless it asked for a specific wait
+const deploymentCooldown = 10 * time.Second
+
+// azureMember is one deployment of an AzureDeploymentPool
+type azureMember struct {
+	name     string // host/deployment, for logs
+	embedder *AzureOpenAIEmbedder
+
+	mu        sync.Mutex
+	coolUntil time.Time
+}
+
+func (m *azureMember) cooling(now time.Time) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.coolUntil.Sub(now)
+}
+
+func (m *azureMember) coolDown(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.coolUntil = time.Now().Add(d)
+}
+
+// AzureDeploymentPool spreads embeddings over several Azure OpenAI
+// deployments, possibly in different regions. With failover routing the
+// primary takes all traffic while it is healthy; with round-robin each call
+// starts at the next deployment. A deployment that throttles or fails is
+// skipped until its cooldown passes and the call moves on to the next, so a
+// large sync keeps going while one region is saturated.
+type AzureDeploymentPool struct {
+	members    []*azureMember
+	roundRobin bool
+	maxRounds  int // passes over all deployments before giving up
+	next       atomic.Uint32
+}
+
+// newAzureDeploymentPool builds the primary deployment and each failover
+// deployment. Their clients don't retry by themselves; the pool moves to
+// another deployment instead and only waits once all have failed.
+func newAzureDeploymentPool(cfg *config.Config) (*AzureDeploymentPool, error) {
+	deployments := append([]config.AzureDeployment{{
+		Endpoint:   cfg.AzureOpenAI.Endpoint,
+		Deployment: cfg.AzureOpenAI.EmbeddingsDeployment,
+		APIKey:     cfg.AzureOpenAI.APIKey,
+	}}, cfg.AzureOpenAI.Failover...)
+
+	pool := &AzureDeploymentPool{
+		roundRobin: cfg.AzureOpenAI.Routing == "round-robin",
+		maxRounds:  max(cfg.Embedding.MaxAttempts, 1),
+	}
+	for _, d := range deployments {
+		client, err := newAzureOpenAIClient(d.Endpoint, d.APIKey, 1)
+		if err != nil {
+			return nil, err
+		}
+		name := d.Deployment
+		if u, err := url.Parse(d.Endpoint); err == nil && u.Host != "" {
+			name = u.Host + "/" + d.Deployment
+		}
+		pool.members = append(pool.members, &azureMember{
+			name:     name,
+			embedder: NewAzureOpenAIEmbedder(client, d.Deployment, cfg.Embedding.Dimensions),
+		})
+	}
+	return pool, nil
+}
+
+// GenerateEmbedding creates a vector embedding for text
+func (p *AzureDeploymentPool) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return firstEmbedding(p.GenerateBatchEmbeddings(ctx, []string{text}))
+}
+
+// GenerateBatchEmbeddings tries deployments in routing order until one
+// succeeds. Invalid input fails at once, since every deployment would
+// reject it.
+func (p *AzureDeploymentPool) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	for round := 1; ; round++ {
+		var lastErr error
+		for _, m := range p.order() {
+			embeddings, err := m.embedder.GenerateBatchEmbeddings(ctx, texts)
+			if err == nil {
+				return embeddings, nil
+			}
+			if ctx.Err() != nil || !failoverWorthy(err) {
+				return nil, err
+			}
+			m.coolDown(cooldownFor(err))
+			logger.Warning("Azure OpenAI deployment %s failed, trying the next: %v", m.name, err)
+			lastErr = err
+		}
+		if round >= p.maxRounds {
+			return nil, lastErr
+		}
+
+		// Every deployment failed; wait for the first to come back
+		wait := backoff(round)
+		now := time.Now()
+		for _, m := range p.members {
+			wait = min(wait, max(m.cooling(now), 0))
+		}
+		if err := sleepCtx(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// order lists the deployments to try: from the primary, or from the next in
+// turn for round-robin, with those still cooling down moved to the end
+func (p *AzureDeploymentPool) order() []*azureMember {
+	start := 0
+	if p.roundRobin {
+		start = int(p.next.Add(1)-1) % len(p.members)
+	}
+	now := time.Now()
+	ready := make([]*azureMember, 0, len(p.members))
+	var cooling []*azureMember
+	for i := range p.members {
+		m := p.members[(start+i)%len(p.members)]
+		if m.cooling(now) > 0 {
+			cooling = append(cooling, m)
+			continue
+		}
+		ready = append(ready, m)
+	}
+	return append(ready, cooling...)
+}
+
+// failoverWorthy reports whether another deployment might succeed where
+// this one failed: throttling, outages, network errors, and misconfigured
+// deployments, but not a request the service rejected as invalid
+func failoverWorthy(err error) bool {
+	var respErr *azcore.ResponseError
+	if !stderrors.As(err, &respErr) {
+		return true
+	}
+	switch respErr.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return false
+	}
+	return true
+}
+
+// cooldownFor is how long to pass over a deployment after err, using its
+// Retry-After when it sent one
+func cooldownFor(err error) time.Duration {
+	var respErr *azcore.ResponseError
+	if stderrors.As(err, &respErr) && respErr.RawResponse != nil {
+		if wait, ok := headerWait(respErr.RawResponse.Header); ok {
+			return min(wait, retryMaxWait)
+		}
+	}
+	return deploymentCooldown
+}
+
+// GetDimension returns the dimension of embeddings
+func (p *AzureDeploymentPool) GetDimension() int {
+	return p.members[0].embedder.GetDimension()
+}
+
+// MaxBatchSize returns the most texts per request
+func (p *AzureDeploymentPool) MaxBatchSize() int {
+	return azureMaxBatch
+}
+
+// Model returns the primary deployment
+func (p *AzureDeploymentPool) Model() string {
+	return p.members[0].embedder.Model()
+}
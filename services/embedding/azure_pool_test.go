@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// azureDeployment is a fake Azure OpenAI deployment answering with status
+// (and headers) until it is told otherwise
+type azureDeployment struct {
+	status  atomic.Int32
+	headers map[string]string
+	calls   atomic.Int32
+	member  *azureMember
+}
+
+func newAzureDeployment(t *testing.T, name string) *azureDeployment {
+	t.Helper()
+	d := &azureDeployment{}
+	d.status.Store(http.StatusOK)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.calls.Add(1)
+		if r.URL.Path != "/openai/deployments/"+name+"/embeddings" || r.Header.Get("api-key") != "key-"+name {
+			t.Errorf("%s got %s with api-key %q", name, r.URL.Path, r.Header.Get("api-key"))
+		}
+		var body struct {
+			Input      []string `json:"input"`
+			Dimensions int      `json:"dimensions"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if status := int(d.status.Load()); status != http.StatusOK {
+			for k, v := range d.headers {
+				w.Header().Set(k, v)
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"error":{"code":"failed","message":"` + name + ` failed"}}`))
+			return
+		}
+		data := make([]map[string]interface{}, len(body.Input))
+		for i := range body.Input {
+			data[i] = map[string]interface{}{"index": i, "embedding": []float32{float32(i), float32(body.Dimensions)}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data":  data,
+			"usage": map[string]int{"prompt_tokens": 5, "total_tokens": 5},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	options := &azopenai.ClientOptions{}
+	options.Transport = server.Client()
+	options.Retry = policy.RetryOptions{MaxRetries: -1}
+	client, err := azopenai.NewClientWithKeyCredential(server.URL, azcore.NewKeyCredential("key-"+name), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.member = &azureMember{name: name, embedder: NewAzureOpenAIEmbedder(client, name, 64)}
+	return d
+}
+
+func (d *azureDeployment) fail(status int, headers map[string]string) {
+	d.headers = headers
+	d.status.Store(int32(status))
+}
+
+func TestAzureOpenAIEmbedder(t *testing.T) {
+	d := newAzureDeployment(t, "embeddings")
+	e := d.member.embedder
+	if e.GetDimension() != 64 || e.Model() != "embeddings" || e.MaxBatchSize() != azureMaxBatch {
+		t.Errorf("dimension %d, model %q, batch %d", e.GetDimension(), e.Model(), e.MaxBatchSize())
+	}
+
+	got, tokens, err := embedWithUsage(context.Background(), e, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float32{{0, 64}, {1, 64}}; !reflect.DeepEqual(got, want) || tokens != 5 {
+		t.Errorf("got %v with %d tokens, want %v with 5", got, tokens, want)
+	}
+	if e.GetDimension() != 2 {
+		t.Errorf("dimension = %d, want 2 from the response", e.GetDimension())
+	}
+}
+
+func TestAzureDeploymentPool(t *testing.T) {
+	tests := []struct {
+		name       string
+		roundRobin bool
+		maxRounds  int
+		setup      func(primary, secondary *azureDeployment)
+		calls      int
+		wantErr    bool
+		wantCalls  [2]int // API calls each deployment got
+	}{
+		{
+			name:      "primary takes all traffic while healthy",
+			maxRounds: 1,
+			calls:     3,
+			wantCalls: [2]int{3, 0},
+		},
+		{
+			name:       "round-robin alternates",
+			roundRobin: true,
+			maxRounds:  1,
+			calls:      4,
+			wantCalls:  [2]int{2, 2},
+		},
+		{
+			name:      "throttled primary cools down while the secondary serves",
+			maxRounds: 1,
+			setup: func(primary, _ *azureDeployment) {
+				primary.fail(http.StatusTooManyRequests, map[string]string{"retry-after-ms": "60000"})
+			},
+			calls:     3,
+			wantCalls: [2]int{1, 3},
+		},
+		{
+			name:      "invalid input is not failed over",
+			maxRounds: 3,
+			setup: func(primary, _ *azureDeployment) {
+				primary.fail(http.StatusBadRequest, nil)
+			},
+			calls:     1,
+			wantErr:   true,
+			wantCalls: [2]int{1, 0},
+		},
+		{
+			name:      "every deployment failing is retried for the rounds allowed",
+			maxRounds: 2,
+			setup: func(primary, secondary *azureDeployment) {
+				primary.fail(http.StatusServiceUnavailable, map[string]string{"retry-after-ms": "10"})
+				secondary.fail(http.StatusTooManyRequests, map[string]string{"retry-after-ms": "10"})
+			},
+			calls:     1,
+			wantErr:   true,
+			wantCalls: [2]int{2, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, secondary := newAzureDeployment(t, "primary"), newAzureDeployment(t, "secondary")
+			if tt.setup != nil {
+				tt.setup(primary, secondary)
+			}
+			pool := &AzureDeploymentPool{
+				members:    []*azureMember{primary.member, secondary.member},
+				roundRobin: tt.roundRobin,
+				maxRounds:  tt.maxRounds,
+			}
+
+			for i := 0; i < tt.calls; i++ {
+				got, err := pool.GenerateBatchEmbeddings(context.Background(), []string{"a"})
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("call %d: error = %v, wantErr %v", i+1, err, tt.wantErr)
+				}
+				if err == nil && len(got) != 1 {
+					t.Fatalf("call %d: got %d embeddings", i+1, len(got))
+				}
+			}
+			if got := [2]int{int(primary.calls.Load()), int(secondary.calls.Load())}; got != tt.wantCalls {
+				t.Errorf("deployment calls = %v, want %v", got, tt.wantCalls)
+			}
+			if pool.Model() != "primary" {
+				t.Errorf("Model() = %q, want the primary", pool.Model())
+			}
+		})
+	}
+}
+
+func TestCooldownFor(t *testing.T) {
+	respErr := func(status int, headers map[string]string) error {
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+		for k, v := range headers {
+			resp.Header.Set(k, v)
+		}
+		return &azcore.ResponseError{StatusCode: status, RawResponse: resp}
+	}
+
+	tests := []struct {
+		name         string
+		err          error
+		wantFailover bool
+		wantCooldown time.Duration
+	}{
+		{name: "network error", err: errors.New("connection refused"), wantFailover: true, wantCooldown: deploymentCooldown},
+		{name: "throttled with a wait", err: respErr(http.StatusTooManyRequests, map[string]string{"Retry-After": "3"}), wantFailover: true, wantCooldown: 3 * time.Second},
+		{name: "wait is capped", err: respErr(http.StatusTooManyRequests, map[string]string{"Retry-After": "86400"}), wantFailover: true, wantCooldown: retryMaxWait},
+		{name: "missing deployment", err: respErr(http.StatusNotFound, nil), wantFailover: true, wantCooldown: deploymentCooldown},
+		{name: "bad request", err: respErr(http.StatusBadRequest, nil), wantCooldown: deploymentCooldown},
+		{name: "too large", err: respErr(http.StatusRequestEntityTooLarge, nil), wantCooldown: deploymentCooldown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failoverWorthy(tt.err); got != tt.wantFailover {
+				t.Errorf("failoverWorthy() = %v, want %v", got, tt.wantFailover)
+			}
+			if got := cooldownFor(tt.err); got != tt.wantCooldown {
+				t.Errorf("cooldownFor() = %s, want %s", got, tt.wantCooldown)
+			}
+		})
+	}
+}
+
+func TestAzureDeploymentPoolCancel(t *testing.T) {
+	d := newAzureDeployment(t, "only")
+	d.fail(http.StatusTooManyRequests, map[string]string{"Retry-After": "30"})
+	pool := &AzureDeploymentPool{members: []*azureMember{d.member}, maxRounds: 5}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := pool.GenerateBatchEmbeddings(ctx, []string{"a"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the deadline", err)
+	}
+	if d.calls.Load() != 1 {
+		t.Errorf("deployment called %d times while cooling down", d.calls.Load())
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+)
+
+// effectiveBatchSize is the most texts sent to the provider per request:
+// EMBEDDING_BATCH_SIZE capped by the provider's own limit, where 0 means no
+// limit for either
+func (s *EmbeddingService) effectiveBatchSize() int {
+	size := s.batchSize
+	if limit := s.provider.MaxBatchSize(); limit > 0 && (size <= 0 || limit < size) {
+		size = limit
+	}
+	return size
+}
+
+// streamChunkSize is how many texts a streamed request embeds before
+// sending their results: as many as are embedded at once, so streaming costs
+// no throughput. 0 means all of them.
+func (s *EmbeddingService) streamChunkSize() int {
+	size := s.effectiveBatchSize()
+	if size <= 0 {
+		return 0
+	}
+	return size * max(s.concurrency, 1)
+}
+
+// embedBatch makes one provider call once the request rate limit allows it
+func (s *EmbeddingService) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return nil, errors.New(errors.ErrTypeRateLimit, "gave up waiting for the request rate limit", err)
+	}
+	call := &tokenUsage{}
+	embeddings, err := s.provider.GenerateBatchEmbeddings(context.WithValue(ctx, callUsageKey{}, call), texts)
+	if ctx.Err() == nil {
+		s.health.record(err) // a caller giving up says nothing about the provider
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.recordCall(ctx, call, texts)
+	return embeddings, nil
+}
+
+// embedInBatches splits texts into sub-batches, embeds up to s.concurrency
+// of them at a time, and reassembles the results in input order. The first
+// failing sub-batch cancels the rest and fails the whole call. progress, if
+// set, is called with each finished sub-batch's size.
+func (s *EmbeddingService) embedInBatches(ctx context.Context, texts []string, progress func(int)) ([][]float32, error) {
+	if progress == nil {
+		progress = func(int) {}
+	}
+	size := s.effectiveBatchSize()
+	if size <= 0 || len(texts) <= size {
+		embeddings, err := s.embedBatch(ctx, texts)
+		if err == nil {
+			progress(len(texts))
+		}
+		return embeddings, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	embeddings := make([][]float32, len(texts))
+	sem := make(chan struct{}, max(s.concurrency, 1))
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for start := 0; start < len(texts); start += size {
+		end := min(start+size, len(texts))
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+
+			batch, err := s.embedBatch(ctx, texts[start:end])
+			if err == nil && len(batch) != end-start {
+				err = errors.Internal(fmt.Sprintf("provider returned %d embeddings for %d texts", len(batch), end-start), nil)
+			}
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			copy(embeddings[start:end], batch)
+			progress(end - start)
+		}(start, end)
+	}
+
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return embeddings, nil
+}
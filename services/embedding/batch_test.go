@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+)
+
+// lengthProvider embeds each text as a vector of its length, records the
+// batches it is sent, and fails any batch holding failOn
+type lengthProvider struct {
+	maxBatch int
+	failOn   string
+	tokens   int // reported per call, 0 reports none
+
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (p *lengthProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return firstEmbedding(p.GenerateBatchEmbeddings(ctx, []string{text}))
+}
+
+func (p *lengthProvider) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	p.mu.Lock()
+	p.batches = append(p.batches, texts)
+	p.mu.Unlock()
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		if p.failOn != "" && text == p.failOn {
+			return nil, stderrors.New("provider failed on " + text)
+		}
+		out[i] = []float32{float32(len(text)), 0}
+	}
+	if p.tokens > 0 {
+		recordUsage(ctx, p.tokens)
+	}
+	return out, nil
+}
+
+func (p *lengthProvider) GetDimension() int { return 2 }
+func (p *lengthProvider) Model() string     { return "length" }
+func (p *lengthProvider) MaxBatchSize() int { return p.maxBatch }
+
+// batchSizes lists the sizes of the batches p was sent, smallest first
+func (p *lengthProvider) batchSizes() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sizes := make([]int, len(p.batches))
+	for i, batch := range p.batches {
+		sizes[i] = len(batch)
+	}
+	sort.Ints(sizes)
+	return sizes
+}
+
+func TestEffectiveBatchSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		batchSize   int
+		maxBatch    int
+		concurrency int
+		want        int
+		wantStream  int
+	}{
+		{name: "configured size under the provider limit", batchSize: 10, maxBatch: 96, concurrency: 4, want: 10, wantStream: 40},
+		{name: "provider limit caps it", batchSize: 500, maxBatch: 96, concurrency: 2, want: 96, wantStream: 192},
+		{name: "no configured size", maxBatch: 96, want: 96, wantStream: 96},
+		{name: "no limit at all", want: 0, wantStream: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewEmbeddingService("length", &lengthProvider{maxBatch: tt.maxBatch}, tt.batchSize, tt.concurrency)
+			if got := s.effectiveBatchSize(); got != tt.want {
+				t.Errorf("effectiveBatchSize() = %d, want %d", got, tt.want)
+			}
+			if got := s.streamChunkSize(); got != tt.wantStream {
+				t.Errorf("streamChunkSize() = %d, want %d", got, tt.wantStream)
+			}
+		})
+	}
+}
+
+func TestEmbedInBatches(t *testing.T) {
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee", "ffffff", "ggggggg"}
+
+	tests := []struct {
+		name        string
+		batchSize   int
+		concurrency int
+		failOn      string
+		wantSizes   []int
+		wantErr     bool
+	}{
+		{name: "one call when it fits", batchSize: 10, concurrency: 2, wantSizes: []int{7}},
+		{name: "split and reassembled in order", batchSize: 3, concurrency: 2, wantSizes: []int{1, 3, 3}},
+		{name: "sequential", batchSize: 2, concurrency: 1, wantSizes: []int{1, 2, 2, 2}},
+		{name: "a failing sub-batch fails the call", batchSize: 2, concurrency: 1, failOn: "ccc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &lengthProvider{failOn: tt.failOn}
+			s := NewEmbeddingService("length", p, tt.batchSize, tt.concurrency)
+
+			progressed := 0
+			var mu sync.Mutex
+			got, err := s.embedInBatches(context.Background(), texts, func(n int) {
+				mu.Lock()
+				progressed += n
+				mu.Unlock()
+			})
+			if tt.wantErr {
+				if err == nil || got != nil || err.Error() != "provider failed on ccc" {
+					t.Errorf("embedInBatches() = %v, %v; want the provider's error", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, text := range texts {
+				if got[i][0] != float32(len(text)) {
+					t.Fatalf("embedding %d belongs to another text: %v", i, got)
+				}
+			}
+			if sizes := p.batchSizes(); !reflect.DeepEqual(sizes, tt.wantSizes) {
+				t.Errorf("batch sizes = %v, want %v", sizes, tt.wantSizes)
+			}
+			if progressed != len(texts) {
+				t.Errorf("progress reported %d texts, want %d", progressed, len(texts))
+			}
+		})
+	}
+}
+
+func TestEmbedBatchHealth(t *testing.T) {
+	s := NewEmbeddingService("length", &lengthProvider{failOn: "bad"}, 0, 1)
+	if _, err := s.embedBatch(context.Background(), []string{"bad"}); err == nil {
+		t.Fatal("embedBatch() succeeded")
+	}
+	if report := s.health.report(); report["status"] != "degraded" || report["last_error"] != "provider failed on bad" {
+		t.Errorf("health after a failure = %v", report)
+	}
+	if _, err := s.embedBatch(context.Background(), []string{"ok"}); err != nil {
+		t.Fatal(err)
+	}
+	if report := s.health.report(); report["status"] != "healthy" {
+		t.Errorf("health after a success = %v", report)
+	}
+
+	// Giving up on the rate limit never reaches the provider
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.limitRequests(1)
+	s.limiter.reserve()
+	var appErr *errors.AppError
+	if _, err := s.embedBatch(ctx, []string{"ok"}); !stderrors.As(err, &appErr) || appErr.Type != errors.ErrTypeRateLimit {
+		t.Errorf("embedBatch() = %v, want a rate limit error", err)
+	}
+	if report := s.health.report(); report["status"] != "healthy" {
+		t.Errorf("health after giving up = %v", report)
+	}
+}
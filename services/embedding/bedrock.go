@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+// Titan takes one text per call; this many calls run at once
+const bedrockTitanWorkers = 8
+
+// Output dimensions of Bedrock embedding models
+var bedrockModelDimensions = map[string]int{
+	"amazon.titan-embed-text-v2:0": 1024,
+	"amazon.titan-embed-text-v1":   1536,
+	"cohere.embed-english-v3":      1024,
+	"cohere.embed-multilingual-v3": 1024,
+}
+
+// BedrockEmbedder implements interfaces.EmbeddingService with Amazon Titan
+// or Cohere embedding models on AWS Bedrock. Requests are signed with SigV4
+// using the default AWS credential chain (environment, shared profile, or
+// instance/task role).
+type BedrockEmbedder struct {
+	client    *bedrockruntime.Client
+	model     string
+	dimension learnedDimension
+}
+
+func init() {
+	registerProvider(ProviderBedrock, func(ctx context.Context, cfg *config.Config) (embeddingProvider, error) {
+		c := cfg.Embedding.Bedrock
+		return NewBedrockEmbedder(ctx, c.Region, c.Model, c.EndpointURL, cfg.Embedding.MaxAttempts)
+	})
+}
+
+// NewBedrockEmbedder creates an embedder for model in region. endpointURL
+// overrides the regional endpoint, e.g. for a VPC endpoint. Throttled calls
+// are retried by the SDK with jittered backoff, up to maxAttempts in total.
+func NewBedrockEmbedder(ctx context.Context, region, model, endpointURL string, maxAttempts int) (*BedrockEmbedder, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region), awsconfig.WithRetryMaxAttempts(max(maxAttempts, 1)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	client := bedrockruntime.NewFromConfig(cfg, func(o *bedrockruntime.Options) {
+		if endpointURL != "" {
+			o.BaseEndpoint = aws.String(endpointURL)
+		}
+	})
+	return &BedrockEmbedder{
+		client:    client,
+		model:     model,
+		dimension: learnedDimension{value: bedrockModelDimensions[model]},
+	}, nil
+}
+
+// GenerateEmbedding creates a vector embedding for text
+func (e *BedrockEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return firstEmbedding(e.GenerateBatchEmbeddings(ctx, []string{text}))
+}
+
+// GenerateBatchEmbeddings creates embeddings for multiple texts
+func (e *BedrockEmbedder) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	var embeddings [][]float32
+	var err error
+	if strings.HasPrefix(e.model, "cohere.") {
+		embeddings, err = e.embedCohere(ctx, texts)
+	} else {
+		embeddings, err = e.embedTitan(ctx, texts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e.dimension.learn(len(embeddings[0]))
+	logger.Info("Generated %d embeddings", len(embeddings))
+	return embeddings, nil
+}
+
+// embedCohere embeds up to cohereMaxBatch texts with a Cohere model
+func (e *BedrockEmbedder) embedCohere(ctx context.Context, texts []string) ([][]float32, error) {
+	inputType := "search_document" // chunks are indexed as documents
+	if inputTypeOf(ctx) == InputQuery {
+		inputType = "search_query"
+	}
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	err := e.invoke(ctx, map[string]interface{}{
+		"texts":      texts,
+		"input_type": inputType,
+		"truncate":   "END",
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, errors.External("AWS Bedrock", fmt.Sprintf("got %d embeddings for %d texts", len(result.Embeddings), len(texts)), nil)
+	}
+	return result.Embeddings, nil
+}
+
+// embedTitan embeds texts with a Titan model, one call per text
+func (e *BedrockEmbedder) embedTitan(ctx context.Context, texts []string) ([][]float32, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	embeddings := make([][]float32, len(texts))
+	sem := make(chan struct{}, bedrockTitanWorkers)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for i, text := range texts {
+		wg.Add(1)
+		go func(i int, text string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var result struct {
+				Embedding           []float32 `json:"embedding"`
+				InputTextTokenCount int       `json:"inputTextTokenCount"`
+			}
+			if err := e.invoke(ctx, map[string]interface{}{"inputText": text}, &result); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			embeddings[i] = result.Embedding
+			if result.InputTextTokenCount > 0 {
+				recordUsage(ctx, result.InputTextTokenCount)
+			}
+		}(i, text)
+	}
+
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return embeddings, nil
+}
+
+// invoke calls the model with a JSON request body and decodes its response
+func (e *BedrockEmbedder) invoke(ctx context.Context, body interface{}, result interface{}) error {
+	payload, _ := json.Marshal(body)
+	out, err := e.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(e.model),
+		Body:        payload,
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+	})
+	if err != nil {
+		return errors.External("AWS Bedrock", "failed to generate embeddings", err)
+	}
+	if err := json.Unmarshal(out.Body, result); err != nil {
+		return errors.External("AWS Bedrock", "invalid embeddings response", err)
+	}
+	return nil
+}
+
+// GetDimension returns the dimension of embeddings, or 0 for a model not in
+// the table until its first embedding is generated
+func (e *BedrockEmbedder) GetDimension() int {
+	return e.dimension.get()
+}
+
+// Model returns the embedding model
+func (e *BedrockEmbedder) Model() string {
+	return e.model
+}
+
+// MaxBatchSize returns the Cohere limit; Titan takes one text per call and
+// is fanned out internally, so it has none
+func (e *BedrockEmbedder) MaxBatchSize() int {
+	if strings.HasPrefix(e.model, "cohere.") {
+		return cohereMaxBatch
+	}
+	return 0
+}
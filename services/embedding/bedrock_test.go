@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newBedrockServer fakes the Bedrock runtime InvokeModel API, answering each
+// request body with respond
+func newBedrockServer(t *testing.T, respond func(model string, body map[string]interface{}) interface{}) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	// Credentials come from the environment only
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDTEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	var mu sync.Mutex
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDTEST/") {
+			t.Errorf("request not signed: %q", r.Header.Get("Authorization"))
+		}
+		model := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/model/"), "/invoke")
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(respond(model, body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+func TestBedrockEmbedder(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		inputType  string
+		texts      []string
+		wantBodies []map[string]interface{}
+		wantTokens int
+		wantBatch  int
+	}{
+		{
+			name:  "titan embeds one text per call",
+			model: "amazon.titan-embed-text-v2:0",
+			texts: []string{"a", "bb", "ccc"},
+			wantBodies: []map[string]interface{}{
+				{"inputText": "a"}, {"inputText": "bb"}, {"inputText": "ccc"},
+			},
+			wantTokens: 6,
+		},
+		{
+			name:  "cohere embeds the batch at once",
+			model: "cohere.embed-english-v3",
+			texts: []string{"a", "bb"},
+			wantBodies: []map[string]interface{}{
+				{"texts": []interface{}{"a", "bb"}, "input_type": "search_document", "truncate": "END"},
+			},
+			wantBatch: cohereMaxBatch,
+		},
+		{
+			name:      "cohere queries",
+			model:     "cohere.embed-multilingual-v3",
+			inputType: InputQuery,
+			texts:     []string{"q"},
+			wantBodies: []map[string]interface{}{
+				{"texts": []interface{}{"q"}, "input_type": "search_query", "truncate": "END"},
+			},
+			wantBatch: cohereMaxBatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, bodies := newBedrockServer(t, func(model string, body map[string]interface{}) interface{} {
+				if model != tt.model {
+					t.Errorf("invoked %s, want %s", model, tt.model)
+				}
+				if text, ok := body["inputText"].(string); ok {
+					return map[string]interface{}{"embedding": []float32{float32(len(text)), 0}, "inputTextTokenCount": len(text)}
+				}
+				texts, _ := body["texts"].([]interface{})
+				out := make([][]float32, len(texts))
+				for i, text := range texts {
+					out[i] = []float32{float32(len(text.(string))), 0}
+				}
+				return map[string]interface{}{"embeddings": out}
+			})
+
+			e, err := NewBedrockEmbedder(context.Background(), "us-east-1", tt.model, server.URL, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, tokens, err := embedWithUsage(withInputType(context.Background(), tt.inputType), e, tt.texts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, text := range tt.texts {
+				if want := []float32{float32(len(text)), 0}; !reflect.DeepEqual(got[i], want) {
+					t.Errorf("embedding %d = %v, want %v", i, got[i], want)
+				}
+			}
+			if !sameBodies(*bodies, tt.wantBodies) {
+				t.Errorf("request bodies = %v, want %v", *bodies, tt.wantBodies)
+			}
+			if tokens != tt.wantTokens || e.MaxBatchSize() != tt.wantBatch || e.GetDimension() != 2 {
+				t.Errorf("tokens %d, batch %d, dimension %d; want %d, %d, 2", tokens, e.MaxBatchSize(), e.GetDimension(), tt.wantTokens, tt.wantBatch)
+			}
+		})
+	}
+}
+
+func TestBedrockEmbedderErrors(t *testing.T) {
+	server, _ := newBedrockServer(t, func(model string, body map[string]interface{}) interface{} {
+		return map[string]interface{}{"embeddings": [][]float32{{1}}}
+	})
+	e, err := NewBedrockEmbedder(context.Background(), "us-east-1", "cohere.embed-english-v3", server.URL, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.GenerateBatchEmbeddings(context.Background(), []string{"a", "b"}); err == nil || !strings.Contains(err.Error(), "got 1 embeddings for 2 texts") {
+		t.Errorf("error = %v, want a count mismatch", err)
+	}
+}
+
+// sameBodies compares request bodies regardless of the order they arrived in
+func sameBodies(got, want []map[string]interface{}) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	used := make([]bool, len(got))
+	for _, w := range want {
+		found := false
+		for i, g := range got {
+			if !used[i] && reflect.DeepEqual(g, w) {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
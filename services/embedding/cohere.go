@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+// The Cohere Embed API accepts at most this many texts per call
+const cohereMaxBatch = 96
+
+// Output dimensions of Cohere v3 embedding models
+var cohereModelDimensions = map[string]int{
+	"embed-english-v3.0":            1024,
+	"embed-multilingual-v3.0":       1024,
+	"embed-english-light-v3.0":      384,
+	"embed-multilingual-light-v3.0": 384,
+}
+
+// Cohere input types; v3 models embed documents and queries differently
+var cohereInputTypes = map[string]string{
+	"document":        "search_document",
+	"query":           "search_query",
+	"search_document": "search_document",
+	"search_query":    "search_query",
+	"classification":  "classification",
+	"clustering":      "clustering",
+}
+
+// CohereEmbedder implements interfaces.EmbeddingService with the Cohere Embed API
+type CohereEmbedder struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	inputType  string
+	httpClient *http.Client
+	retry      retryPolicy
+	dimension  learnedDimension
+}
+
+func init() {
+	registerProvider(ProviderCohere, func(_ context.Context, cfg *config.Config) (embeddingProvider, error) {
+		c := cfg.Embedding.Cohere
+		return NewCohereEmbedder(c.BaseURL, c.APIKey, c.Model, c.InputType, cfg.Embedding.MaxAttempts)
+	})
+}
+
+// NewCohereEmbedder creates an embedder for model. inputType is document or
+// query (or a Cohere input type name); indexed chunks are documents.
+func NewCohereEmbedder(baseURL, apiKey, model, inputType string, maxAttempts int) (*CohereEmbedder, error) {
+	resolved, ok := cohereInputTypes[strings.ToLower(inputType)]
+	if !ok {
+		return nil, fmt.Errorf("unknown Cohere input type %q (available: document, query, classification, clustering)", inputType)
+	}
+	return &CohereEmbedder{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		inputType:  resolved,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		retry:      newRetryPolicy(maxAttempts),
+		dimension:  learnedDimension{value: cohereModelDimensions[model]},
+	}, nil
+}
+
+// inputTypeFor is the Cohere input type for a call: the request's, or the
+// configured one when the request didn't say
+func (e *CohereEmbedder) inputTypeFor(ctx context.Context) string {
+	if inputType, ok := cohereInputTypes[inputTypeOf(ctx)]; ok {
+		return inputType
+	}
+	return e.inputType
+}
+
+// GenerateEmbedding creates a vector embedding for text
+func (e *CohereEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return firstEmbedding(e.GenerateBatchEmbeddings(ctx, []string{text}))
+}
+
+// GenerateBatchEmbeddings creates embeddings for up to cohereMaxBatch texts
+func (e *CohereEmbedder) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"model":           e.model,
+		"texts":           texts,
+		"input_type":      e.inputTypeFor(ctx),
+		"embedding_types": []string{"float"},
+		"truncate":        "END",
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/v1/embed", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, errors.Internal("failed to build Cohere request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+
+	resp, err := e.retry.do(e.httpClient, req)
+	if err != nil {
+		return nil, errors.Network("Cohere request failed", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, errors.External("Cohere", "failed to generate embeddings", fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(body)))
+	}
+
+	var result struct {
+		Embeddings struct {
+			Float [][]float32 `json:"float"`
+		} `json:"embeddings"`
+		Meta struct {
+			BilledUnits struct {
+				InputTokens int `json:"input_tokens"`
+			} `json:"billed_units"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errors.External("Cohere", "invalid embeddings response", err)
+	}
+	if len(result.Embeddings.Float) != len(texts) {
+		return nil, errors.External("Cohere", fmt.Sprintf("got %d embeddings for %d texts", len(result.Embeddings.Float), len(texts)), nil)
+	}
+	e.dimension.learn(len(result.Embeddings.Float[0]))
+	if result.Meta.BilledUnits.InputTokens > 0 {
+		recordUsage(ctx, result.Meta.BilledUnits.InputTokens)
+	}
+
+	logger.Info("Generated %d embeddings", len(result.Embeddings.Float))
+	return result.Embeddings.Float, nil
+}
+
+// GetDimension returns the dimension of embeddings, or 0 for a model not in
+// the table until its first embedding is generated
+func (e *CohereEmbedder) GetDimension() int {
+	return e.dimension.get()
+}
+
+// Model returns the embedding model
+func (e *CohereEmbedder) Model() string {
+	return e.model
+}
+
+// MaxBatchSize returns the most texts per request
+func (e *CohereEmbedder) MaxBatchSize() int {
+	return cohereMaxBatch
+}
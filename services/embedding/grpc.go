@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/embeddingpb"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Requests of many long texts, and responses of many large vectors, can pass
+// the 4MB gRPC default
+const maxGRPCMessageSize = 64 << 20
+
+// grpcServer serves the embeddingpb API, the same embeddings as /embed
+// without the cost of encoding every float as JSON
+type grpcServer struct {
+	embeddingpb.UnimplementedEmbeddingServiceServer
+	service *EmbeddingService
+}
+
+// serveGRPC listens on port and serves the gRPC API until Stop is called on
+// the returned server
+func serveGRPC(service *EmbeddingService, port int) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, errors.Network(fmt.Sprintf("failed to listen on gRPC port %d", port), err)
+	}
+	server := grpc.NewServer(grpc.MaxRecvMsgSize(maxGRPCMessageSize), grpc.MaxSendMsgSize(maxGRPCMessageSize))
+	embeddingpb.RegisterEmbeddingServiceServer(server, &grpcServer{service: service})
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			logger.Error("gRPC server error: %v", err)
+		}
+	}()
+	return server, nil
+}
+
+// Embed embeds every text and returns them in one response
+func (g *grpcServer) Embed(ctx context.Context, req *embeddingpb.EmbedRequest) (*embeddingpb.EmbedResponse, error) {
+	fitted, err := g.service.fitTokenLimit(req.Texts, req.Overflow, req.InputType)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp, err := g.service.embedFitted(ctx, fitted, len(req.Texts), g.service.normalizeFor(req.Normalize), nil)
+	if err != nil {
+		logger.Error("Failed to generate embeddings: %v", err)
+		return nil, grpcError(err)
+	}
+	return toProto(resp, 0), nil
+}
+
+// EmbedStream embeds the texts a chunk at a time, sending each chunk once
+// it is done
+func (g *grpcServer) EmbedStream(req *embeddingpb.EmbedRequest, stream embeddingpb.EmbeddingService_EmbedStreamServer) error {
+	s := g.service
+	fitted, err := s.fitTokenLimit(req.Texts, req.Overflow, req.InputType)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	normalize := s.normalizeFor(req.Normalize)
+
+	count := len(req.Texts)
+	chunk := s.streamChunkSize()
+	if chunk <= 0 {
+		chunk = max(count, 1)
+	}
+	for start := 0; start < count; start += chunk {
+		end := min(start+chunk, count)
+		resp, err := s.embedChunk(stream.Context(), fitted.slice(start, end), end-start, normalize, nil)
+		if err == nil {
+			err = stream.Send(toProto(resp, start))
+		}
+		if err != nil {
+			s.recordRequest(count, err)
+			logger.Error("Failed to stream embeddings: %v", err)
+			return grpcError(err)
+		}
+	}
+	s.recordRequest(count, nil)
+	s.logAdjustments(len(fitted.adjustments), count)
+	return nil
+}
+
+// grpcError maps an embedding failure to a gRPC status
+func grpcError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		switch appErr.Type {
+		case errors.ErrTypeValidation:
+			return status.Error(codes.InvalidArgument, err.Error())
+		case errors.ErrTypeRateLimit:
+			return status.Error(codes.ResourceExhausted, err.Error())
+		case errors.ErrTypeNetwork, errors.ErrTypeExternal:
+			return status.Error(codes.Unavailable, err.Error())
+		}
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func toProto(resp *EmbeddingResponse, offset int) *embeddingpb.EmbedResponse {
+	out := &embeddingpb.EmbedResponse{
+		Offset:     int32(offset),
+		Embeddings: make([]*embeddingpb.Embedding, len(resp.Embeddings)),
+		Model:      resp.Model,
+		Dimensions: int32(resp.Dimensions),
+	}
+	for i, embedding := range resp.Embeddings {
+		out.Embeddings[i] = &embeddingpb.Embedding{Values: embedding}
+	}
+	for _, adj := range resp.Adjustments {
+		out.Adjustments = append(out.Adjustments, &embeddingpb.TokenAdjustment{
+			Index:  int32(adj.Index),
+			Tokens: int32(adj.Tokens),
+			Limit:  int32(adj.Limit),
+			Action: adj.Action,
+			Parts:  int32(adj.Parts),
+		})
+	}
+	if resp.Usage != nil {
+		out.Usage = &embeddingpb.Usage{
+			PromptTokens: int32(resp.Usage.PromptTokens),
+			Estimated:    resp.Usage.Estimated,
+			CostUsd:      resp.Usage.CostUSD,
+		}
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/embeddingpb"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialGRPC serves s over an in-memory listener and returns a client for it
+func dialGRPC(t *testing.T, s *EmbeddingService) embeddingpb.EmbeddingServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	embeddingpb.RegisterEmbeddingServiceServer(server, &grpcServer{service: s})
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return embeddingpb.NewEmbeddingServiceClient(conn)
+}
+
+func TestGRPCEmbed(t *testing.T) {
+	normalize := true
+	tests := []struct {
+		name     string
+		req      *embeddingpb.EmbedRequest
+		failOn   string
+		wantCode codes.Code
+		want     [][]float32
+	}{
+		{name: "embeds in order", req: &embeddingpb.EmbedRequest{Texts: []string{"a", "bbb"}}, want: [][]float32{{1, 0}, {3, 0}}},
+		{name: "normalized on request", req: &embeddingpb.EmbedRequest{Texts: []string{"bbb"}, Normalize: &normalize}, want: [][]float32{{1, 0}}},
+		{name: "invalid overflow", req: &embeddingpb.EmbedRequest{Texts: []string{"a"}, Overflow: "drop"}, wantCode: codes.InvalidArgument},
+		{name: "provider failure", req: &embeddingpb.EmbedRequest{Texts: []string{"bad"}}, failOn: "bad", wantCode: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewEmbeddingService("length", &lengthProvider{failOn: tt.failOn}, 0, 1)
+			s.limitTokens(0, OverflowReject)
+			resp, err := dialGRPC(t, s).Embed(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %s (%v), want %s", code, err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if resp.Model != "length/length" || resp.Dimensions != 2 || resp.Usage == nil || !resp.Usage.Estimated {
+				t.Errorf("response = %v", resp)
+			}
+			if len(resp.Embeddings) != len(tt.want) {
+				t.Fatalf("got %d embeddings, want %d", len(resp.Embeddings), len(tt.want))
+			}
+			for i, e := range resp.Embeddings {
+				if e.Values[0] != tt.want[i][0] || e.Values[1] != tt.want[i][1] {
+					t.Errorf("embedding %d = %v, want %v", i, e.Values, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCEmbedStream(t *testing.T) {
+	// Two texts per streamed response: batches of one, two in flight
+	s := NewEmbeddingService("length", &lengthProvider{}, 1, 2)
+	s.limitTokens(0, OverflowReject)
+	stream, err := dialGRPC(t, s).EmbedStream(context.Background(), &embeddingpb.EmbedRequest{Texts: []string{"a", "bb", "ccc", "dddd", "eeeee"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var offsets []int32
+	next := 0
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, resp.Offset)
+		for _, e := range resp.Embeddings {
+			next++
+			if e.Values[0] != float32(next) {
+				t.Errorf("embedding %d = %v", next-1, e.Values)
+			}
+		}
+	}
+	if len(offsets) != 3 || offsets[0] != 0 || offsets[1] != 2 || offsets[2] != 4 || next != 5 {
+		t.Errorf("offsets = %v with %d embeddings, want [0 2 4] with 5", offsets, next)
+	}
+	if s.stats.requests != 1 || s.stats.texts != 5 {
+		t.Errorf("stats = %d requests, %d texts; want one request of 5", s.stats.requests, s.stats.texts)
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "status passes through", err: status.Error(codes.NotFound, "gone"), want: codes.NotFound},
+		{name: "cancelled", err: context.Canceled, want: codes.Canceled},
+		{name: "deadline", err: context.DeadlineExceeded, want: codes.DeadlineExceeded},
+		{name: "validation", err: errors.Validation("bad input"), want: codes.InvalidArgument},
+		{name: "rate limit", err: errors.New(errors.ErrTypeRateLimit, "slow down", nil), want: codes.ResourceExhausted},
+		{name: "provider", err: errors.External("OpenAI", "failed", nil), want: codes.Unavailable},
+		{name: "network", err: errors.Network("unreachable", nil), want: codes.Unavailable},
+		{name: "anything else", err: stderrors.New("boom"), want: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(grpcError(tt.err)); got != tt.want {
+				t.Errorf("grpcError() code = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// providerHealth remembers the outcome of the latest provider calls, so
+// /health can answer probes without spending a call of its own
+type providerHealth struct {
+	mu          sync.Mutex
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+}
+
+func (h *providerHealth) record(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		h.lastFailure = time.Now().UTC()
+		h.lastError = err.Error()
+		return
+	}
+	h.lastSuccess = time.Now().UTC()
+}
+
+// report describes the latest calls; the status is degraded when the most
+// recent one failed
+func (h *providerHealth) report() map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	report := map[string]string{"status": "healthy", "check": "cached"}
+	if !h.lastSuccess.IsZero() {
+		report["last_success"] = h.lastSuccess.Format(time.RFC3339)
+	}
+	if h.lastFailure.After(h.lastSuccess) {
+		report["status"] = "degraded"
+		report["last_failure"] = h.lastFailure.Format(time.RFC3339)
+		report["last_error"] = h.lastError
+	}
+	return report
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+)
+
+// What texts are embedded for. Retrieval models embed the two differently,
+// by instruction prefix or by an API parameter.
+const (
+	InputDocument = "document" // stored and searched, the default
+	InputQuery    = "query"    // searched with
+)
+
+// inputPrefixes are the instructions a model expects before each text
+type inputPrefixes struct {
+	document string
+	query    string
+}
+
+func (p inputPrefixes) forType(inputType string) string {
+	if inputType == InputQuery {
+		return p.query
+	}
+	return p.document
+}
+
+const retrievalInstruction = "Represent this sentence for searching relevant passages: "
+
+// Prefixes of models trained with them, by model name prefix
+var modelPrefixes = []struct {
+	model    string
+	prefixes inputPrefixes
+}{
+	{"e5-", inputPrefixes{"passage: ", "query: "}},
+	{"multilingual-e5-", inputPrefixes{"passage: ", "query: "}},
+	{"nomic-embed-text", inputPrefixes{"search_document: ", "search_query: "}},
+	{"bge-small-en", inputPrefixes{"", retrievalInstruction}},
+	{"bge-base-en", inputPrefixes{"", retrievalInstruction}},
+	{"bge-large-en", inputPrefixes{"", retrievalInstruction}},
+	{"mxbai-embed-large", inputPrefixes{"", retrievalInstruction}},
+}
+
+// resolvePrefixes returns the prefixes of the first of the models found in
+// the table, matched on the name after any organization or path, with
+// non-empty overrides taking precedence
+func resolvePrefixes(document, query string, models ...string) inputPrefixes {
+	var resolved inputPrefixes
+	for _, model := range models {
+		name := path.Base(strings.ToLower(model))
+		found := false
+		for _, m := range modelPrefixes {
+			if strings.HasPrefix(name, m.model) {
+				resolved, found = m.prefixes, true
+				break
+			}
+		}
+		if found {
+			break
+		}
+	}
+	if document != "" {
+		resolved.document = document
+	}
+	if query != "" {
+		resolved.query = query
+	}
+	return resolved
+}
+
+// checkInputType validates a requested input type, where empty means document
+func checkInputType(inputType string) error {
+	switch inputType {
+	case "", InputDocument, InputQuery:
+		return nil
+	}
+	return errors.Validation(fmt.Sprintf("unknown input_type %q (available: document, query)", inputType))
+}
+
+type inputTypeKey struct{}
+
+// withInputType tells providers with their own input type parameter what
+// the texts of calls made with ctx are for
+func withInputType(ctx context.Context, inputType string) context.Context {
+	if inputType == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, inputTypeKey{}, inputType)
+}
+
+// inputTypeOf returns the input type set on ctx, or "" if none was
+func inputTypeOf(ctx context.Context) string {
+	inputType, _ := ctx.Value(inputTypeKey{}).(string)
+	return inputType
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+const (
+	// Jobs embedding at once; later submissions wait as pending
+	maxRunningJobs = 2
+	// Finished jobs, results included, are kept this long for polling
+	jobRetention = time.Hour
+	// Jobs waiting for a slot unless EMBEDDING_MAX_PENDING_JOBS says otherwise
+	defaultMaxPendingJobs = 100
+)
+
+// Job states
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// EmbeddingJob is a batch embedded in the background. Processed and Total
+// count texts after any token-limit splitting.
+type EmbeddingJob struct {
+	ID         string             `json:"id"`
+	Status     string             `json:"status"`
+	Total      int                `json:"total"`
+	Processed  int                `json:"processed"`
+	Error      string             `json:"error,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	Result     *EmbeddingResponse `json:"result,omitempty"`
+}
+
+// EmbeddingJobRequest is an EmbeddingRequest with an optional URL the
+// finished job is POSTed to
+type EmbeddingJobRequest struct {
+	EmbeddingRequest
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// jobStore keeps jobs in memory; they don't survive a restart
+type jobStore struct {
+	mu            sync.Mutex
+	jobs          map[string]*EmbeddingJob
+	running       chan struct{}
+	maxPending    int             // pending jobs before add refuses more
+	callbackHosts map[string]bool // lowercase host or host:port callbacks may go to
+	client        *http.Client    // for callbacks
+}
+
+func newJobStore() *jobStore {
+	return &jobStore{
+		jobs:       make(map[string]*EmbeddingJob),
+		running:    make(chan struct{}, maxRunningJobs),
+		maxPending: defaultMaxPendingJobs,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			// A redirect could lead a callback off the allowed hosts
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// limitJobs caps the jobs waiting for a slot at maxPending and lets
+// callbacks go only to hosts, each a host or host:port
+func (s *EmbeddingService) limitJobs(maxPending int, hosts []string) {
+	s.jobs.maxPending = maxPending
+	s.jobs.callbackHosts = make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		s.jobs.callbackHosts[strings.ToLower(host)] = true
+	}
+}
+
+// checkCallback rejects a callback URL that isn't http(s) on an allowed host
+func (js *jobStore) checkCallback(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid callback_url %q", callbackURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	if !js.callbackHosts[host] && !js.callbackHosts[strings.ToLower(u.Host)] {
+		return fmt.Errorf("callback_url host %q is not in EMBEDDING_CALLBACK_HOSTS", u.Host)
+	}
+	return nil
+}
+
+// add registers a new pending job, dropping finished jobs past retention.
+// It returns nil when maxPending jobs are already waiting.
+func (js *jobStore) add(total int) *EmbeddingJob {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	job := &EmbeddingJob{
+		ID:        hex.EncodeToString(id),
+		Status:    JobPending,
+		Total:     total,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	pending := 0
+	for id, j := range js.jobs {
+		if j.FinishedAt != nil && time.Since(*j.FinishedAt) > jobRetention {
+			delete(js.jobs, id)
+		}
+		if j.Status == JobPending {
+			pending++
+		}
+	}
+	if pending >= js.maxPending {
+		return nil
+	}
+	js.jobs[job.ID] = job
+	return job
+}
+
+// get returns a copy of the job safe to encode while it runs
+func (js *jobStore) get(id string) (EmbeddingJob, bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	job, ok := js.jobs[id]
+	if !ok {
+		return EmbeddingJob{}, false
+	}
+	return *job, true
+}
+
+func (js *jobStore) update(job *EmbeddingJob, fn func(*EmbeddingJob)) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	fn(job)
+}
+
+// runJob embeds a job's texts once a slot is free, then notifies the
+// callback URL if one was given
+func (s *EmbeddingService) runJob(job *EmbeddingJob, fitted *fittedTexts, count int, normalize bool, callbackURL string) {
+	s.jobs.running <- struct{}{}
+	defer func() { <-s.jobs.running }()
+
+	s.jobs.update(job, func(j *EmbeddingJob) { j.Status = JobRunning })
+	logger.Info("Embedding job %s started: %d texts", job.ID, job.Total)
+
+	resp, err := s.embedFitted(context.Background(), fitted, count, normalize, func(n int) {
+		s.jobs.update(job, func(j *EmbeddingJob) { j.Processed += n })
+	})
+
+	s.jobs.update(job, func(j *EmbeddingJob) {
+		now := time.Now().UTC()
+		j.FinishedAt = &now
+		if err != nil {
+			j.Status = JobFailed
+			j.Error = err.Error()
+			return
+		}
+		j.Status = JobCompleted
+		j.Processed = j.Total
+		j.Result = resp
+	})
+	if err != nil {
+		logger.Error("Embedding job %s failed: %v", job.ID, err)
+	} else {
+		logger.Info("Embedding job %s completed", job.ID)
+	}
+
+	if callbackURL != "" {
+		s.notifyJob(job.ID, callbackURL)
+	}
+}
+
+// notifyJob POSTs the finished job to its callback URL. Failures are only
+// logged; the job can still be polled.
+func (s *EmbeddingService) notifyJob(id, callbackURL string) {
+	job, _ := s.jobs.get(id)
+	body, _ := json.Marshal(job)
+	resp, err := s.jobs.client.Post(callbackURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.Warning("Embedding job %s callback failed: %v", id, err)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		logger.Warning("Embedding job %s callback returned %d", id, resp.StatusCode)
+	}
+}
+
+// handleJobs submits a job with POST /embed/jobs and reports one with
+// GET /embed/jobs?id=
+func (s *EmbeddingService) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.handleSubmitJob(w, r)
+	case http.MethodGet:
+		s.handleGetJob(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *EmbeddingService) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	var req EmbeddingJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.CallbackURL != "" {
+		if err := s.jobs.checkCallback(req.CallbackURL); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	fitted, err := s.fitTokenLimit(req.Texts, req.Overflow, req.InputType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	job := s.jobs.add(len(fitted.texts))
+	if job == nil {
+		http.Error(w, fmt.Sprintf("%d embedding jobs are already waiting; retry later", s.jobs.maxPending), http.StatusTooManyRequests)
+		return
+	}
+	snapshot, _ := s.jobs.get(job.ID)
+	go s.runJob(job, fitted, len(req.Texts), s.normalizeFor(req.Normalize), req.CallbackURL)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/embed/jobs?id="+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(snapshot)
+}
+
+func (s *EmbeddingService) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id parameter is required", http.StatusBadRequest)
+		return
+	}
+	job, ok := s.jobs.get(id)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider embeds every text as a fixed two-dimensional vector
+type fakeProvider struct{}
+
+func (fakeProvider) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return []float32{1, 0}, nil
+}
+
+func (p fakeProvider) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i := range texts {
+		out[i], _ = p.GenerateEmbedding(ctx, texts[i])
+	}
+	return out, nil
+}
+
+func (fakeProvider) GetDimension() int { return 2 }
+func (fakeProvider) Model() string     { return "fake" }
+func (fakeProvider) MaxBatchSize() int { return 0 }
+
+func newTestService() *EmbeddingService {
+	s := NewEmbeddingService("fake", fakeProvider{}, 10, 1)
+	s.limitTokens(0, OverflowTruncate)
+	return s
+}
+
+func submitJob(s *EmbeddingService, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.handleJobs(rec, httptest.NewRequest(http.MethodPost, "/embed/jobs", strings.NewReader(body)))
+	return rec
+}
+
+func TestJobPendingLimit(t *testing.T) {
+	s := newTestService()
+	s.limitJobs(2, nil)
+
+	first, second := s.jobs.add(1), s.jobs.add(1)
+	if first == nil || second == nil {
+		t.Fatal("add refused a job under the limit")
+	}
+	if s.jobs.add(1) != nil {
+		t.Error("add accepted a job over the limit")
+	}
+	if rec := submitJob(s, `{"texts":["a"]}`); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429 while the queue is full", rec.Code)
+	}
+
+	// A job leaving the queue makes room for another
+	s.jobs.update(first, func(j *EmbeddingJob) { j.Status = JobRunning })
+	if rec := submitJob(s, `{"texts":["a"]}`); rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d (%s), want 202", rec.Code, rec.Body.String())
+	}
+}
+
+func TestJobCallbackHosts(t *testing.T) {
+	done := make(chan EmbeddingJob, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var job EmbeddingJob
+		_ = json.NewDecoder(r.Body).Decode(&job)
+		done <- job
+	}))
+	t.Cleanup(receiver.Close)
+
+	s := newTestService()
+	s.limitJobs(defaultMaxPendingJobs, []string{"127.0.0.1", "hooks.example.com:8443"})
+
+	tests := []struct {
+		name     string
+		callback string
+		wantErr  bool
+	}{
+		{name: "allowed host", callback: receiver.URL},
+		{name: "allowed host and port", callback: "https://HOOKS.example.com:8443/done"},
+		{name: "allowed host on another port", callback: "https://hooks.example.com/done", wantErr: true},
+		{name: "other host", callback: "http://169.254.169.254/latest/meta-data", wantErr: true},
+		{name: "not http", callback: "file:///etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.jobs.checkCallback(tt.callback); (err != nil) != tt.wantErr {
+				t.Errorf("checkCallback(%q) = %v, wantErr %v", tt.callback, err, tt.wantErr)
+			}
+		})
+	}
+
+	if rec := submitJob(s, `{"texts":["a"],"callback_url":"http://169.254.169.254/"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 for a host that isn't allowed", rec.Code)
+	}
+	if rec := submitJob(s, `{"texts":["a"],"callback_url":"`+receiver.URL+`"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d (%s), want 202", rec.Code, rec.Body.String())
+	}
+	select {
+	case job := <-done:
+		if job.Status != JobCompleted || job.Result == nil {
+			t.Errorf("callback got %+v, want the completed job", job)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback never arrived")
+	}
+
+	// With no hosts configured, callbacks are refused
+	s.limitJobs(defaultMaxPendingJobs, nil)
+	if rec := submitJob(s, `{"texts":["a"],"callback_url":"`+receiver.URL+`"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400 without EMBEDDING_CALLBACK_HOSTS", rec.Code)
+	}
+}
@@ -7,87 +7,166 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"google.golang.org/grpc"
 )
 
-// EmbeddingService implements interfaces.EmbeddingService
+// EmbeddingService implements interfaces.EmbeddingService by delegating to
+// the provider chosen at startup
 type EmbeddingService struct {
-	client     *azopenai.Client
-	deployment string
-	dimension  int
+	provider       embeddingProvider
+	providerName   string
+	batchSize      int              // texts per provider request, capped by the provider's limit
+	concurrency    int              // provider requests in flight per batch
+	tokenLimit     int              // estimated input tokens per text, 0 for no limit
+	overflow       string           // default handling of texts over tokenLimit
+	normalize      bool             // L2-normalize embeddings unless a request says otherwise
+	prefixes       inputPrefixes    // instructions the model expects before documents and queries
+	modelVersion   string           // operator-set version of the model, part of its ID
+	limiter        *requestLimiter  // provider calls per minute across all requests, nil for no limit
+	jobs           *jobStore        // background /embed/jobs
+	stats          usageStats       // lifetime counters for /stats
+	health         providerHealth   // latest provider call outcomes for /health
+	pricePer1K     float64          // USD per 1,000 input tokens, 0 when unknown
+	chatClient     *azopenai.Client // Azure OpenAI client for chunk summaries
+	chatDeployment string           // empty disables /summarize
 }
 
 // NewEmbeddingService creates a new embedding service
-func NewEmbeddingService(endpoint, apiKey, deployment string) (*EmbeddingService, error) {
-	keyCredential := azcore.NewKeyCredential(apiKey)
-	client, err := azopenai.NewClientWithKeyCredential(endpoint, keyCredential, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Azure OpenAI client: %w", err)
+func NewEmbeddingService(providerName string, provider embeddingProvider, batchSize, concurrency int) *EmbeddingService {
+	return &EmbeddingService{
+		provider:     provider,
+		providerName: providerName,
+		batchSize:    batchSize,
+		concurrency:  concurrency,
+		jobs:         newJobStore(),
+		stats:        usageStats{since: time.Now().UTC()},
 	}
+}
 
-	return &EmbeddingService{
-		client:     client,
-		deployment: deployment,
-		dimension:  1536, // text-embedding-ada-002 dimension
-	}, nil
+// limitRequests caps provider calls at perMinute across all callers; 0
+// removes the cap
+func (s *EmbeddingService) limitRequests(perMinute int) {
+	s.limiter = newRequestLimiter(perMinute)
+}
+
+// normalizeByDefault makes requests that don't say otherwise return unit
+// length embeddings
+func (s *EmbeddingService) normalizeByDefault(normalize bool) {
+	s.normalize = normalize
+}
+
+// prefixTexts puts the model's instruction prefix before every text
+// embedded as a document or a query
+func (s *EmbeddingService) prefixTexts(prefixes inputPrefixes) {
+	s.prefixes = prefixes
+}
+
+// versionModel sets the version stamped on embeddings with the model name,
+// for providers whose model name doesn't change when the model does
+func (s *EmbeddingService) versionModel(version string) {
+	s.modelVersion = version
+}
+
+// modelID names what embeddings come from as provider/model, plus
+// @version when one is set. Vectors with different IDs are not comparable
+// and don't belong in one index.
+func (s *EmbeddingService) modelID() string {
+	id := s.providerName + "/" + s.provider.Model()
+	if s.modelVersion != "" {
+		id += "@" + s.modelVersion
+	}
+	return id
+}
+
+// priceTokens sets the USD price per 1,000 input tokens used for cost reports
+func (s *EmbeddingService) priceTokens(pricePer1K float64) {
+	s.pricePer1K = pricePer1K
+}
+
+// limitTokens checks texts against the model's input token limit, handling
+// longer ones as overflow says unless a request asks otherwise
+func (s *EmbeddingService) limitTokens(limit int, overflow string) {
+	s.tokenLimit = limit
+	s.overflow = overflow
+}
+
+// useSummaries enables /summarize through an Azure OpenAI chat deployment
+func (s *EmbeddingService) useSummaries(client *azopenai.Client, chatDeployment string) {
+	s.chatClient = client
+	s.chatDeployment = chatDeployment
 }
 
 // GenerateEmbedding creates a vector embedding for text
 func (s *EmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	embeddings, err := s.GenerateBatchEmbeddings(ctx, []string{text})
-	if err != nil {
-		return nil, err
-	}
-	if len(embeddings) == 0 {
-		return nil, errors.Internal("no embeddings generated", nil)
-	}
-	return embeddings[0], nil
+	return firstEmbedding(s.embedBatch(ctx, []string{text}))
 }
 
-// GenerateBatchEmbeddings creates embeddings for multiple texts
+// GenerateBatchEmbeddings creates embeddings for multiple texts, splitting
+// them into sub-batches the provider accepts
 func (s *EmbeddingService) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
-	if len(texts) == 0 {
-		return [][]float32{}, nil
-	}
+	return s.embedInBatches(ctx, texts, nil)
+}
 
-	resp, err := s.client.GetEmbeddings(ctx, azopenai.EmbeddingsOptions{
-		Input:          texts,
-		DeploymentName: &s.deployment,
-	}, nil)
+// GetDimension returns the dimension of embeddings
+func (s *EmbeddingService) GetDimension() int {
+	return s.provider.GetDimension()
+}
 
-	if err != nil {
-		return nil, errors.External("Azure OpenAI", "failed to generate embeddings", err)
-	}
+// learnedDimension is an embedding dimension taken from responses, starting
+// from the expected value when the model's dimension is known up front
+type learnedDimension struct {
+	mu    sync.RWMutex
+	value int
+}
 
-	embeddings := make([][]float32, len(resp.Data))
-	for i, item := range resp.Data {
-		embeddings[i] = item.Embedding
+func (d *learnedDimension) learn(dimension int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if dimension > 0 {
+		d.value = dimension
 	}
+}
 
-	logger.Info("Generated %d embeddings", len(embeddings))
-	return embeddings, nil
+func (d *learnedDimension) get() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.value
 }
 
-// GetDimension returns the dimension of embeddings
-func (s *EmbeddingService) GetDimension() int {
-	return s.dimension
+// firstEmbedding unwraps the result of embedding a single text
+func firstEmbedding(embeddings [][]float32, err error) ([]float32, error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 {
+		return nil, errors.Internal("no embeddings generated", nil)
+	}
+	return embeddings[0], nil
 }
 
 // HTTP Handlers
 type EmbeddingRequest struct {
-	Texts []string `json:"texts"`
+	Texts     []string `json:"texts"`
+	Overflow  string   `json:"overflow,omitempty"`   // reject, truncate, or split; defaults to EMBEDDING_OVERFLOW
+	Normalize *bool    `json:"normalize,omitempty"`  // L2-normalize embeddings; defaults to EMBEDDING_NORMALIZE
+	InputType string   `json:"input_type,omitempty"` // document or query; defaults to document
 }
 
 type EmbeddingResponse struct {
-	Embeddings [][]float32 `json:"embeddings"`
-	Count      int         `json:"count"`
+	Embeddings  [][]float32       `json:"embeddings"`
+	Count       int               `json:"count"`
+	Model       string            `json:"model"` // provider/model[@version] the embeddings come from
+	Dimensions  int               `json:"dimensions"`
+	Adjustments []TokenAdjustment `json:"adjustments,omitempty"` // texts that were over the token limit
+	Usage       *EmbeddingUsage   `json:"usage"`
 }
 
 func (s *EmbeddingService) handleEmbed(w http.ResponseWriter, r *http.Request) {
@@ -102,35 +181,93 @@ func (s *EmbeddingService) handleEmbed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	embeddings, err := s.GenerateBatchEmbeddings(r.Context(), req.Texts)
+	fitted, err := s.fitTokenLimit(req.Texts, req.Overflow, req.InputType)
 	if err != nil {
-		logger.Error("Failed to generate embeddings: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	resp := EmbeddingResponse{
-		Embeddings: embeddings,
-		Count:      len(embeddings),
+	resp, err := s.embedFitted(r.Context(), fitted, len(req.Texts), s.normalizeFor(req.Normalize), nil)
+	if err != nil {
+		logger.Error("Failed to generate embeddings: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
-func (s *EmbeddingService) handleHealth(w http.ResponseWriter, r *http.Request) {
-	// Test with a simple embedding
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
+// embedFitted embeds texts already fitted to the token limit and maps the
+// results back to the count original texts, L2-normalizing them if asked.
+// progress, if set, is called with the number of fitted texts embedded as
+// each sub-batch finishes.
+func (s *EmbeddingService) embedFitted(ctx context.Context, fitted *fittedTexts, count int, normalize bool, progress func(int)) (*EmbeddingResponse, error) {
+	resp, err := s.embedChunk(ctx, fitted, count, normalize, progress)
+	s.recordRequest(count, err)
+	if err != nil {
+		return nil, err
+	}
+	s.logAdjustments(len(fitted.adjustments), count)
+	return resp, nil
+}
 
-	_, err := s.GenerateEmbedding(ctx, "test")
+// embedChunk is embedFitted without accounting the request, for callers
+// embedding one request in several parts
+func (s *EmbeddingService) embedChunk(ctx context.Context, fitted *fittedTexts, count int, normalize bool, progress func(int)) (*EmbeddingResponse, error) {
+	usage := &tokenUsage{}
+	embeddings, err := s.embedInBatches(withInputType(withRequestUsage(ctx, usage), fitted.inputType), fitted.texts, progress)
 	if err != nil {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_ = json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy", "error": err.Error()})
-		return
+		return nil, err
+	}
+	embeddings = fitted.combine(embeddings, count)
+	if normalize {
+		for _, embedding := range embeddings {
+			l2Normalize(embedding)
+		}
 	}
 
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy", "dimension": fmt.Sprintf("%d", s.dimension)})
+	dimensions := s.GetDimension()
+	if len(embeddings) > 0 {
+		dimensions = len(embeddings[0])
+	}
+	return &EmbeddingResponse{
+		Embeddings:  embeddings,
+		Count:       len(embeddings),
+		Model:       s.modelID(),
+		Dimensions:  dimensions,
+		Adjustments: fitted.adjustments,
+		Usage:       s.requestUsage(usage),
+	}, nil
+}
+
+func (s *EmbeddingService) logAdjustments(adjusted, count int) {
+	if adjusted > 0 {
+		logger.Info("Adjusted %d of %d texts over the %d token limit", adjusted, count, s.tokenLimit)
+	}
+}
+
+// handleHealth answers from the latest provider calls, so probes cost
+// nothing; deep=true embeds a test string to check the provider for real
+func (s *EmbeddingService) handleHealth(w http.ResponseWriter, r *http.Request) {
+	report := s.health.report()
+	if r.URL.Query().Get("deep") == "true" {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		if _, err := s.GenerateEmbedding(ctx, "test"); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy", "error": err.Error()})
+			return
+		}
+		report = s.health.report()
+		report["check"] = "deep"
+	}
+
+	report["provider"] = s.providerName
+	report["model"] = s.provider.Model()
+	report["dimension"] = fmt.Sprintf("%d", s.GetDimension())
+	_ = json.NewEncoder(w).Encode(report)
 }
 
 func main() {
@@ -155,26 +292,75 @@ func main() {
 
 	logger.Info("Starting Embedding Service on port %d", cfg.Services.EmbeddingServicePort)
 
+	// Summaries always use Azure OpenAI, whichever provider embeds
+	var azureClient *azopenai.Client
+	if cfg.AzureOpenAI.Endpoint != "" && cfg.AzureOpenAI.APIKey != "" {
+		azureClient, err = newAzureOpenAIClient(cfg.AzureOpenAI.Endpoint, cfg.AzureOpenAI.APIKey, cfg.Embedding.MaxAttempts)
+		if err != nil {
+			logger.Fatal("Failed to create embedding service: %v", err)
+		}
+	}
+
 	// Create embedding service
-	service, err := NewEmbeddingService(
-		cfg.AzureOpenAI.Endpoint,
-		cfg.AzureOpenAI.APIKey,
-		cfg.AzureOpenAI.EmbeddingsDeployment,
-	)
+	provider, err := newProvider(context.Background(), cfg.Embedding.Provider, cfg)
 	if err != nil {
-		logger.Fatal("Failed to create embedding service: %v", err)
+		logger.Fatal("Failed to create embedding provider: %v", err)
+	}
+	logger.Info("Embedding with %s provider, model %s", cfg.Embedding.Provider, provider.Model())
+	service := NewEmbeddingService(cfg.Embedding.Provider, provider, cfg.Processing.EmbeddingBatchSize, cfg.Embedding.Concurrency)
+	if !overflowModes[cfg.Embedding.Overflow] {
+		logger.Fatal("Unknown EMBEDDING_OVERFLOW %q (available: reject, truncate, split)", cfg.Embedding.Overflow)
+	}
+	// Azure deployments are named freely; fall back to EMBEDDING_MODEL for their limit
+	tokenLimit := resolveTokenLimit(cfg.Processing.EmbeddingMaxTokens, provider.Model(), cfg.Processing.EmbeddingModel)
+	if cfg.Embedding.Provider != ProviderAzureOpenAI {
+		tokenLimit = resolveTokenLimit(cfg.Processing.EmbeddingMaxTokens, provider.Model())
+	}
+	service.limitTokens(tokenLimit, cfg.Embedding.Overflow)
+	service.limitRequests(cfg.Processing.RateLimitRequestsPerMin)
+	service.normalizeByDefault(cfg.Embedding.Normalize)
+	prefixModels := []string{provider.Model()}
+	if cfg.Embedding.Provider == ProviderAzureOpenAI {
+		prefixModels = append(prefixModels, cfg.Processing.EmbeddingModel)
+	}
+	prefixes := resolvePrefixes(cfg.Embedding.DocumentPrefix, cfg.Embedding.QueryPrefix, prefixModels...)
+	if prefixes != (inputPrefixes{}) {
+		logger.Info("Prefixing documents with %q and queries with %q", prefixes.document, prefixes.query)
+	}
+	service.prefixTexts(prefixes)
+	service.versionModel(cfg.Embedding.ModelVersion)
+	service.limitJobs(cfg.Embedding.MaxPendingJobs, cfg.Embedding.CallbackHosts)
+	pricingModels := []string{provider.Model()}
+	if cfg.Embedding.Provider == ProviderAzureOpenAI {
+		pricingModels = append(pricingModels, cfg.Processing.EmbeddingModel)
+	}
+	service.priceTokens(resolvePricePer1K(cfg.Embedding.CostPer1KTokens, pricingModels...))
+	if azureClient != nil {
+		service.useSummaries(azureClient, cfg.AzureOpenAI.ChatDeployment)
 	}
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", service.handleHealth)
 	mux.HandleFunc("/embed", service.handleEmbed)
+	mux.HandleFunc("/embed/jobs", service.handleJobs)
+	mux.HandleFunc("/embed/stream", service.handleEmbedStream)
+	mux.HandleFunc("/summarize", service.handleSummarize)
+	mux.HandleFunc("/stats", service.handleStats)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Services.EmbeddingServicePort),
 		Handler: mux,
 	}
 
+	var grpcServer *grpc.Server
+	if port := cfg.Services.EmbeddingGRPCPort; port > 0 {
+		if grpcServer, err = serveGRPC(service, port); err != nil {
+			logger.Fatal("Failed to start gRPC server: %v", err)
+		}
+		logger.Info("Embedding Service gRPC API listening on port %d", port)
+	}
+
 	// Graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
@@ -188,6 +374,9 @@ func main() {
 		if err := server.Shutdown(ctx); err != nil {
 			logger.Error("Server shutdown error: %v", err)
 		}
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
 	}()
 
 	// Start server
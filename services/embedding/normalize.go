@@ -0,0 +1,28 @@
+package main
+
+import "math"
+
+// l2Normalize scales v in place to unit length, so dot product equals cosine
+// similarity. Zero vectors are left as they are.
+func l2Normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for i, x := range v {
+		v[i] = float32(float64(x) / norm)
+	}
+}
+
+// normalizeFor reports whether a request's embeddings are normalized: its
+// own flag when given, otherwise EMBEDDING_NORMALIZE
+func (s *EmbeddingService) normalizeFor(requested *bool) bool {
+	if requested != nil {
+		return *requested
+	}
+	return s.normalize
+}
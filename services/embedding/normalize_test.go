@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestL2Normalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []float32
+		want []float32
+	}{
+		{name: "scaled to unit length", in: []float32{3, 4}, want: []float32{0.6, 0.8}},
+		{name: "already unit", in: []float32{0, 1, 0}, want: []float32{0, 1, 0}},
+		{name: "negative components", in: []float32{-2, 0}, want: []float32{-1, 0}},
+		{name: "zero vector is left alone", in: []float32{0, 0}, want: []float32{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := append([]float32(nil), tt.in...)
+			l2Normalize(v)
+			for i := range v {
+				if math.Abs(float64(v[i]-tt.want[i])) > 1e-6 {
+					t.Fatalf("l2Normalize(%v) = %v, want %v", tt.in, v, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		byDefault bool
+		body      string
+		want      float32 // first component of the embedding of "abc", [3 0] unnormalized
+	}{
+		{name: "off by default", body: `{"texts":["abc"]}`, want: 3},
+		{name: "on by default", byDefault: true, body: `{"texts":["abc"]}`, want: 1},
+		{name: "request turns it on", body: `{"texts":["abc"],"normalize":true}`, want: 1},
+		{name: "request turns it off", byDefault: true, body: `{"texts":["abc"],"normalize":false}`, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewEmbeddingService("length", &lengthProvider{}, 0, 1)
+			s.limitTokens(0, OverflowReject)
+			s.normalizeByDefault(tt.byDefault)
+
+			rec := httptest.NewRecorder()
+			s.handleEmbed(rec, httptest.NewRequest(http.MethodPost, "/embed", strings.NewReader(tt.body)))
+			var resp EmbeddingResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+			}
+			if got := resp.Embeddings[0][0]; got != tt.want {
+				t.Errorf("embedding = %v, want the first component %g", resp.Embeddings[0], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+// OllamaEmbedder implements interfaces.EmbeddingService with a local Ollama
+// server, so embeddings can be generated without leaving the network. The
+// dimension depends on the model and is taken from its first response.
+type OllamaEmbedder struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+	retry      retryPolicy
+	dimension  learnedDimension
+}
+
+func init() {
+	registerProvider(ProviderOllama, func(_ context.Context, cfg *config.Config) (embeddingProvider, error) {
+		return NewOllamaEmbedder(cfg.Embedding.Ollama.URL, cfg.Embedding.Ollama.Model, cfg.Embedding.MaxAttempts), nil
+	})
+}
+
+// NewOllamaEmbedder creates an embedder for model served at baseURL, e.g.
+// http://localhost:11434
+func NewOllamaEmbedder(baseURL, model string, maxAttempts int) *OllamaEmbedder {
+	return &OllamaEmbedder{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		// Local models can be slow, especially while loading
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+		retry:      newRetryPolicy(maxAttempts),
+	}
+}
+
+// GenerateEmbedding creates a vector embedding for text
+func (e *OllamaEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return firstEmbedding(e.GenerateBatchEmbeddings(ctx, []string{text}))
+}
+
+// GenerateBatchEmbeddings creates embeddings for multiple texts
+func (e *OllamaEmbedder) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/api/embed", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, errors.Internal("failed to build Ollama request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.retry.do(e.httpClient, req)
+	if err != nil {
+		return nil, errors.Network("Ollama request failed", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, errors.External("Ollama", "failed to generate embeddings", fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(body)))
+	}
+
+	var result struct {
+		Embeddings      [][]float32 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errors.External("Ollama", "invalid embeddings response", err)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, errors.External("Ollama", fmt.Sprintf("got %d embeddings for %d texts", len(result.Embeddings), len(texts)), nil)
+	}
+	e.dimension.learn(len(result.Embeddings[0]))
+	if result.PromptEvalCount > 0 {
+		recordUsage(ctx, result.PromptEvalCount)
+	}
+
+	logger.Info("Generated %d embeddings", len(result.Embeddings))
+	return result.Embeddings, nil
+}
+
+// GetDimension returns the dimension of embeddings, or 0 until the first
+// embedding is generated
+func (e *OllamaEmbedder) GetDimension() int {
+	return e.dimension.get()
+}
+
+// Model returns the embedding model
+func (e *OllamaEmbedder) Model() string {
+	return e.model
+}
+
+// MaxBatchSize returns 0; Ollama takes any number of inputs
+func (e *OllamaEmbedder) MaxBatchSize() int {
+	return 0
+}
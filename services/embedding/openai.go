@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+// The OpenAI embeddings API accepts at most this many inputs per request
+const openAIMaxBatch = 2048
+
+// Output dimensions of OpenAI embedding models; other models report theirs
+// with the first response
+var openAIModelDimensions = map[string]int{
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+}
+
+// OpenAIEmbedder implements interfaces.EmbeddingService with the OpenAI
+// embeddings API, or any API compatible with it
+type OpenAIEmbedder struct {
+	baseURL      string
+	apiKey       string
+	model        string
+	organization string
+	dimensions   int // requested output size, 0 for the model's own
+	httpClient   *http.Client
+	retry        retryPolicy
+	dimension    learnedDimension
+}
+
+func init() {
+	registerProvider(ProviderOpenAI, func(_ context.Context, cfg *config.Config) (embeddingProvider, error) {
+		c := cfg.Embedding.OpenAI
+		return NewOpenAIEmbedder(c.BaseURL, c.APIKey, c.Model, c.Organization, cfg.Embedding.Dimensions, cfg.Embedding.MaxAttempts)
+	})
+}
+
+// NewOpenAIEmbedder creates an embedder for model at baseURL, e.g.
+// https://api.openai.com/v1. dimensions shortens text-embedding-3 embeddings;
+// 0 keeps the model's own size.
+func NewOpenAIEmbedder(baseURL, apiKey, model, organization string, dimensions, maxAttempts int) (*OpenAIEmbedder, error) {
+	if err := checkDimensions(model, dimensions); err != nil {
+		return nil, err
+	}
+	dimension := openAIModelDimensions[model]
+	if dimensions > 0 {
+		dimension = dimensions
+	}
+	return &OpenAIEmbedder{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		apiKey:       apiKey,
+		model:        model,
+		organization: organization,
+		dimensions:   dimensions,
+		httpClient:   &http.Client{Timeout: 60 * time.Second},
+		retry:        newRetryPolicy(maxAttempts),
+		dimension:    learnedDimension{value: dimension},
+	}, nil
+}
+
+// checkDimensions rejects a requested size the model can't produce. Models
+// not in the table are left for the API to judge.
+func checkDimensions(model string, dimensions int) error {
+	if dimensions <= 0 {
+		return nil
+	}
+	if model == "text-embedding-ada-002" {
+		return fmt.Errorf("%s does not support custom dimensions", model)
+	}
+	if native, ok := openAIModelDimensions[model]; ok && dimensions > native {
+		return fmt.Errorf("%s produces at most %d dimensions, %d requested", model, native, dimensions)
+	}
+	return nil
+}
+
+// GenerateEmbedding creates a vector embedding for text
+func (e *OpenAIEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return firstEmbedding(e.GenerateBatchEmbeddings(ctx, []string{text}))
+}
+
+// GenerateBatchEmbeddings creates embeddings for multiple texts
+func (e *OpenAIEmbedder) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	body := map[string]interface{}{
+		"model":           e.model,
+		"input":           texts,
+		"encoding_format": "float",
+	}
+	if e.dimensions > 0 {
+		body["dimensions"] = e.dimensions
+	}
+	reqBody, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, errors.Internal("failed to build OpenAI request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	if e.organization != "" {
+		req.Header.Set("OpenAI-Organization", e.organization)
+	}
+
+	resp, err := e.retry.do(e.httpClient, req)
+	if err != nil {
+		return nil, errors.Network("OpenAI request failed", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, errors.External("OpenAI", "failed to generate embeddings", fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(body)))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errors.External("OpenAI", "invalid embeddings response", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, errors.External("OpenAI", fmt.Sprintf("got %d embeddings for %d texts", len(result.Data), len(texts)), nil)
+	}
+
+	// Items carry their input index and are not guaranteed to be in order
+	sort.Slice(result.Data, func(i, j int) bool { return result.Data[i].Index < result.Data[j].Index })
+	embeddings := make([][]float32, len(result.Data))
+	for i, item := range result.Data {
+		embeddings[i] = item.Embedding
+	}
+	e.dimension.learn(len(embeddings[0]))
+	if result.Usage.PromptTokens > 0 {
+		recordUsage(ctx, result.Usage.PromptTokens)
+	}
+
+	logger.Info("Generated %d embeddings", len(embeddings))
+	return embeddings, nil
+}
+
+// GetDimension returns the dimension of embeddings, or 0 for a model not in
+// the table until its first embedding is generated
+func (e *OpenAIEmbedder) GetDimension() int {
+	return e.dimension.get()
+}
+
+// Model returns the embedding model
+func (e *OpenAIEmbedder) Model() string {
+	return e.model
+}
+
+// MaxBatchSize returns the most texts per request
+func (e *OpenAIEmbedder) MaxBatchSize() int {
+	return openAIMaxBatch
+}
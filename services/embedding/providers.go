@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/interfaces"
+)
+
+// Embedding providers, selected with EMBEDDING_PROVIDER
+const (
+	ProviderAzureOpenAI = "azure"
+	ProviderOpenAI      = "openai"
+	ProviderOllama      = "ollama"
+	ProviderCohere      = "cohere"
+	ProviderVertex      = "vertex"
+	ProviderBedrock     = "bedrock"
+	ProviderTEI         = "tei"
+)
+
+// embeddingProvider is a backend the embedding service delegates to
+type embeddingProvider interface {
+	interfaces.EmbeddingService
+
+	// Model names the model or deployment embeddings come from
+	Model() string
+
+	// MaxBatchSize is the most texts the backend accepts per request, or 0
+	// for no limit; the service splits larger batches
+	MaxBatchSize() int
+}
+
+// providerFactory builds a provider from configuration
+type providerFactory func(ctx context.Context, cfg *config.Config) (embeddingProvider, error)
+
+var providerFactories = make(map[string]providerFactory)
+
+// registerProvider makes a provider selectable by name. Providers register
+// themselves from init in their own file.
+func registerProvider(name string, factory providerFactory) {
+	if _, exists := providerFactories[name]; exists {
+		panic("embedding provider registered twice: " + name)
+	}
+	providerFactories[name] = factory
+}
+
+// providerNames lists the registered providers in order
+func providerNames() []string {
+	names := make([]string, 0, len(providerFactories))
+	for name := range providerFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newProvider builds the named provider
+func newProvider(ctx context.Context, name string, cfg *config.Config) (embeddingProvider, error) {
+	factory, ok := providerFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown embedding provider %q (available: %s)", name, strings.Join(providerNames(), ", "))
+	}
+	provider, err := factory(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s provider configuration: %w", name, err)
+	}
+	return provider, nil
+}
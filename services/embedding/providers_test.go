@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+)
+
+// providerCall is what a fake provider API saw of one request
+type providerCall struct {
+	path    string
+	headers http.Header
+	body    map[string]interface{}
+}
+
+// newProviderServer answers every request with response and reports the
+// requests it got
+func newProviderServer(t *testing.T, status int, response string) (*httptest.Server, chan providerCall) {
+	calls := make(chan providerCall, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		calls <- providerCall{path: r.URL.Path, headers: r.Header, body: body}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+// embedWithUsage embeds texts and returns the tokens the provider recorded
+func embedWithUsage(ctx context.Context, p embeddingProvider, texts []string) ([][]float32, int, error) {
+	call := &tokenUsage{}
+	embeddings, err := p.GenerateBatchEmbeddings(context.WithValue(ctx, callUsageKey{}, call), texts)
+	tokens, _, _ := call.get()
+	return embeddings, tokens, err
+}
+
+func TestHTTPProviders(t *testing.T) {
+	tests := []struct {
+		name       string
+		newEmbed   func(url string) embeddingProvider
+		inputType  string
+		status     int
+		response   string
+		wantPath   string
+		wantHeader map[string]string
+		wantBody   map[string]interface{}
+		want       [][]float32
+		wantTokens int
+		wantDim    int
+		wantErr    string
+	}{
+		{
+			name: "openai",
+			newEmbed: func(url string) embeddingProvider {
+				e, _ := NewOpenAIEmbedder(url+"/v1/", "sk-test", "text-embedding-3-small", "org-1", 256, 1)
+				return e
+			},
+			status:     http.StatusOK,
+			response:   `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}],"usage":{"prompt_tokens":7}}`,
+			wantPath:   "/v1/embeddings",
+			wantHeader: map[string]string{"Authorization": "Bearer sk-test", "OpenAI-Organization": "org-1"},
+			wantBody:   map[string]interface{}{"model": "text-embedding-3-small", "input": []interface{}{"a", "b"}, "encoding_format": "float", "dimensions": 256.0},
+			want:       [][]float32{{1, 0}, {0, 1}},
+			wantTokens: 7,
+			wantDim:    2,
+		},
+		{
+			name: "openai count mismatch",
+			newEmbed: func(url string) embeddingProvider {
+				e, _ := NewOpenAIEmbedder(url, "sk-test", "custom", "", 0, 1)
+				return e
+			},
+			status:   http.StatusOK,
+			response: `{"data":[{"index":0,"embedding":[1,0]}]}`,
+			wantPath: "/embeddings",
+			wantErr:  "got 1 embeddings for 2 texts",
+		},
+		{
+			name: "openai error status",
+			newEmbed: func(url string) embeddingProvider {
+				e, _ := NewOpenAIEmbedder(url, "sk-test", "custom", "", 0, 1)
+				return e
+			},
+			status:   http.StatusUnauthorized,
+			response: `{"error":"bad key"}`,
+			wantPath: "/embeddings",
+			wantErr:  `status 401: {"error":"bad key"}`,
+		},
+		{
+			name: "cohere documents",
+			newEmbed: func(url string) embeddingProvider {
+				e, _ := NewCohereEmbedder(url, "co-key", "embed-english-v3.0", "document", 1)
+				return e
+			},
+			status:     http.StatusOK,
+			response:   `{"embeddings":{"float":[[1,0,0],[0,1,0]]},"meta":{"billed_units":{"input_tokens":4}}}`,
+			wantPath:   "/v1/embed",
+			wantHeader: map[string]string{"Authorization": "Bearer co-key"},
+			wantBody: map[string]interface{}{
+				"model": "embed-english-v3.0", "texts": []interface{}{"a", "b"}, "input_type": "search_document",
+				"embedding_types": []interface{}{"float"}, "truncate": "END",
+			},
+			want:       [][]float32{{1, 0, 0}, {0, 1, 0}},
+			wantTokens: 4,
+			wantDim:    3,
+		},
+		{
+			name: "cohere queries",
+			newEmbed: func(url string) embeddingProvider {
+				e, _ := NewCohereEmbedder(url, "co-key", "embed-english-v3.0", "document", 1)
+				return e
+			},
+			inputType: InputQuery,
+			status:    http.StatusOK,
+			response:  `{"embeddings":{"float":[[1],[0]]}}`,
+			wantPath:  "/v1/embed",
+			wantBody: map[string]interface{}{
+				"model": "embed-english-v3.0", "texts": []interface{}{"a", "b"}, "input_type": "search_query",
+				"embedding_types": []interface{}{"float"}, "truncate": "END",
+			},
+			want:    [][]float32{{1}, {0}},
+			wantDim: 1,
+		},
+		{
+			name:       "ollama",
+			newEmbed:   func(url string) embeddingProvider { return NewOllamaEmbedder(url+"/", "nomic-embed-text", 1) },
+			status:     http.StatusOK,
+			response:   `{"embeddings":[[0.5,0.5],[1,1]],"prompt_eval_count":9}`,
+			wantPath:   "/api/embed",
+			wantBody:   map[string]interface{}{"model": "nomic-embed-text", "input": []interface{}{"a", "b"}},
+			want:       [][]float32{{0.5, 0.5}, {1, 1}},
+			wantTokens: 9,
+			wantDim:    2,
+		},
+		{
+			name:       "tei",
+			newEmbed:   func(url string) embeddingProvider { return NewTEIEmbedder(url, "bge", "hf-key", 0, 1) },
+			status:     http.StatusOK,
+			response:   `[[1,2,3,4],[4,3,2,1]]`,
+			wantPath:   "/embed",
+			wantHeader: map[string]string{"Authorization": "Bearer hf-key"},
+			wantBody:   map[string]interface{}{"inputs": []interface{}{"a", "b"}, "truncate": true},
+			want:       [][]float32{{1, 2, 3, 4}, {4, 3, 2, 1}},
+			wantDim:    4,
+		},
+		{
+			name:     "tei invalid response",
+			newEmbed: func(url string) embeddingProvider { return NewTEIEmbedder(url, "", "", 0, 1) },
+			status:   http.StatusOK,
+			response: `{"error":"overloaded"}`,
+			wantPath: "/embed",
+			wantErr:  "invalid embeddings response",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := newProviderServer(t, tt.status, tt.response)
+			embedder := tt.newEmbed(server.URL)
+
+			ctx := withInputType(context.Background(), tt.inputType)
+			got, tokens, err := embedWithUsage(ctx, embedder, []string{"a", "b"})
+			call := <-calls
+			if call.path != tt.wantPath {
+				t.Errorf("path = %s, want %s", call.path, tt.wantPath)
+			}
+			for k, v := range tt.wantHeader {
+				if call.headers.Get(k) != v {
+					t.Errorf("header %s = %q, want %q", k, call.headers.Get(k), v)
+				}
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(call.body, tt.wantBody) {
+				t.Errorf("request body = %v, want %v", call.body, tt.wantBody)
+			}
+			if !reflect.DeepEqual(got, tt.want) || tokens != tt.wantTokens {
+				t.Errorf("got %v with %d tokens, want %v with %d", got, tokens, tt.want, tt.wantTokens)
+			}
+			if dim := embedder.GetDimension(); dim != tt.wantDim {
+				t.Errorf("dimension = %d, want %d from the response", dim, tt.wantDim)
+			}
+		})
+	}
+}
+
+func TestProviderConstructors(t *testing.T) {
+	if _, err := NewOpenAIEmbedder("", "", "text-embedding-ada-002", "", 512, 1); err == nil {
+		t.Error("ada-002 accepted custom dimensions")
+	}
+	if _, err := NewOpenAIEmbedder("", "", "text-embedding-3-small", "", 2048, 1); err == nil {
+		t.Error("text-embedding-3-small accepted more dimensions than it has")
+	}
+	if e, err := NewOpenAIEmbedder("", "", "text-embedding-3-large", "", 0, 1); err != nil || e.GetDimension() != 3072 {
+		t.Errorf("text-embedding-3-large = %v, %v; want dimension 3072", e, err)
+	}
+	if _, err := NewCohereEmbedder("", "", "embed-english-v3.0", "reranking", 1); err == nil {
+		t.Error("unknown Cohere input type accepted")
+	}
+	if e := NewTEIEmbedder("http://tei:80", "", "", 0, 1); e.MaxBatchSize() != 32 || e.Model() != "http://tei:80" {
+		t.Errorf("TEI defaults = batch %d, model %q", e.MaxBatchSize(), e.Model())
+	}
+	// Empty batches never reach the API
+	if got, err := NewOllamaEmbedder("http://unreachable.invalid", "m", 1).GenerateBatchEmbeddings(context.Background(), nil); err != nil || len(got) != 0 {
+		t.Errorf("empty batch = %v, %v", got, err)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	want := []string{ProviderAzureOpenAI, ProviderBedrock, ProviderCohere, ProviderOllama, ProviderOpenAI, ProviderTEI, ProviderVertex}
+	if got := providerNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("providerNames() = %v, want %v", got, want)
+	}
+
+	cfg := &config.Config{}
+	cfg.Embedding.Ollama = config.OllamaConfig{URL: "http://localhost:11434", Model: "nomic-embed-text"}
+	cfg.Embedding.Cohere = config.CohereConfig{Model: "embed-english-v3.0", InputType: "sideways"}
+
+	tests := []struct {
+		name      string
+		provider  string
+		wantModel string
+		wantErr   string
+	}{
+		{name: "registered", provider: ProviderOllama, wantModel: "nomic-embed-text"},
+		{name: "unknown", provider: "word2vec", wantErr: `unknown embedding provider "word2vec" (available: azure, bedrock,`},
+		{name: "invalid configuration", provider: ProviderCohere, wantErr: "invalid cohere provider configuration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newProvider(context.Background(), tt.provider, cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("newProvider() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Model() != tt.wantModel {
+				t.Errorf("model = %q, want %q", p.Model(), tt.wantModel)
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a provider twice didn't panic")
+		}
+	}()
+	registerProvider(ProviderOllama, nil)
+}
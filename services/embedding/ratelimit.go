@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// requestLimiter is a token bucket shared by every request to the service,
+// so callers together stay under the provider's requests-per-minute quota
+// however many orchestrators are syncing. The bucket holds ten seconds of
+// quota, since Azure OpenAI enforces its per-minute limit over 10s windows.
+type requestLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // time to earn one request
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+// newRequestLimiter allows perMinute provider calls a minute; 0 or less
+// returns nil, which never waits
+func newRequestLimiter(perMinute int) *requestLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	burst := float64(max(perMinute/6, 1))
+	return &requestLimiter{
+		interval: time.Minute / time.Duration(perMinute),
+		burst:    burst,
+		tokens:   burst,
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a call may be made or ctx is done
+func (l *requestLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	wait := l.reserve()
+	if wait <= 0 {
+		return nil
+	}
+	if err := sleepCtx(ctx, wait); err != nil {
+		l.cancel()
+		return err
+	}
+	return nil
+}
+
+// reserve takes a token, possibly going into debt, and returns how long the
+// caller must wait for it
+func (l *requestLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.tokens+float64(now.Sub(l.last))/float64(l.interval), l.burst)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.interval))
+}
+
+// cancel returns a token reserved by a caller that gave up waiting
+func (l *requestLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(l.tokens+1, l.burst)
+}
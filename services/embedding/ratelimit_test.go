@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRequestLimiter(t *testing.T) {
+	if newRequestLimiter(0) != nil {
+		t.Error("newRequestLimiter(0) != nil")
+	}
+	var unlimited *requestLimiter
+	if err := unlimited.Wait(context.Background()); err != nil {
+		t.Errorf("nil limiter Wait() = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		perMinute int
+		calls     int // reserved back to back
+		wantBurst int // calls that don't wait
+		wantLast  time.Duration
+	}{
+		// 600/min earns a call every 100ms and bursts ten seconds' worth
+		{name: "burst then paced", perMinute: 600, calls: 103, wantBurst: 100, wantLast: 300 * time.Millisecond},
+		{name: "low rates burst one", perMinute: 3, calls: 2, wantBurst: 1, wantLast: 20 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRequestLimiter(tt.perMinute)
+			var last time.Duration
+			for i := 0; i < tt.calls; i++ {
+				last = l.reserve()
+				if i < tt.wantBurst && last != 0 {
+					t.Fatalf("call %d waits %s inside the burst", i+1, last)
+				}
+			}
+			// Allow for the time the loop itself took
+			if last > tt.wantLast || last < tt.wantLast-50*time.Millisecond {
+				t.Errorf("last call waits %s, want about %s", last, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestRequestLimiterWait(t *testing.T) {
+	l := newRequestLimiter(1200) // a call every 50ms, burst of 200
+	for i := 0; i < 200; i++ {
+		l.reserve()
+	}
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Wait() returned after %s, want about 50ms", elapsed)
+	}
+
+	// A caller that gives up hands its token back
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	before := l.reserve()
+	l.cancel()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait() = %v, want context.Canceled", err)
+	}
+	if after := l.reserve(); after > before+10*time.Millisecond {
+		t.Errorf("after a cancelled wait the next call waits %s, want about %s", after, before)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+	// A provider asking for a longer wait than this fails the request
+	// instead of holding it open
+	retryMaxWait = 2 * time.Minute
+)
+
+// retryPolicy retries embedding API calls that are rate limited (429) or
+// overloaded (503). The wait comes from the provider's Retry-After,
+// retry-after-ms, or x-ratelimit-reset-* headers when present, and is
+// otherwise a jittered exponential backoff.
+type retryPolicy struct {
+	maxAttempts int // total attempts including the first
+}
+
+func newRetryPolicy(maxAttempts int) retryPolicy {
+	return retryPolicy{maxAttempts: max(maxAttempts, 1)}
+}
+
+// do sends req with client, retrying while the response is retryable and
+// the attempt budget lasts. The last response is returned as-is when the
+// budget runs out, so callers report the provider's own error.
+func (p retryPolicy) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Do(req)
+		if err != nil || !retryableStatus(resp.StatusCode) || attempt >= p.maxAttempts {
+			return resp, err
+		}
+
+		wait, fromHeader := retryAfter(resp.Header)
+		if !fromHeader {
+			wait = backoff(attempt)
+		} else if wait > retryMaxWait {
+			return resp, nil
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil // body can't be replayed
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+
+		logger.Warning("%s returned %d, retrying in %s (attempt %d of %d)", req.URL.Host, resp.StatusCode, wait.Round(time.Millisecond), attempt+1, p.maxAttempts)
+		if err := sleepCtx(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// retryAfter reads how long the provider asked us to wait. A little jitter
+// is added so parallel sub-batches don't all retry at the same instant.
+func retryAfter(h http.Header) (time.Duration, bool) {
+	wait, ok := headerWait(h)
+	if !ok {
+		return 0, false
+	}
+	return wait + time.Duration(rand.Int63n(int64(wait/10)+1)), true
+}
+
+func headerWait(h http.Header) (time.Duration, bool) {
+	// Azure OpenAI and OpenAI send a millisecond-precision variant
+	if ms, err := strconv.ParseFloat(h.Get("retry-after-ms"), 64); err == nil && ms >= 0 {
+		return time.Duration(ms * float64(time.Millisecond)), true
+	}
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second, true
+		}
+		if at, err := http.ParseTime(v); err == nil {
+			return max(time.Until(at), 0), true
+		}
+	}
+	// OpenAI reports when the exhausted request or token window resets,
+	// e.g. "1s" or "6m0s"
+	for _, kind := range []string{"requests", "tokens"} {
+		if h.Get("x-ratelimit-remaining-"+kind) != "0" {
+			continue
+		}
+		if d, err := time.ParseDuration(h.Get("x-ratelimit-reset-" + kind)); err == nil && d >= 0 {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// backoff returns an exponential delay for attempt with equal jitter:
+// between half and all of retryBaseDelay * 2^(attempt-1), capped
+func backoff(attempt int) time.Duration {
+	delay := retryMaxDelay
+	if attempt < 16 {
+		delay = min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHeaderWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    time.Duration
+		wantOK  bool
+	}{
+		{name: "none"},
+		{name: "retry-after-ms", headers: map[string]string{"retry-after-ms": "1500.5"}, want: 1500*time.Millisecond + 500*time.Microsecond, wantOK: true},
+		{name: "retry-after-ms wins", headers: map[string]string{"retry-after-ms": "20", "Retry-After": "5"}, want: 20 * time.Millisecond, wantOK: true},
+		{name: "retry-after seconds", headers: map[string]string{"Retry-After": "7"}, want: 7 * time.Second, wantOK: true},
+		{name: "retry-after date in the past", headers: map[string]string{"Retry-After": "Wed, 21 Oct 2015 07:28:00 GMT"}, want: 0, wantOK: true},
+		{name: "negative retry-after", headers: map[string]string{"Retry-After": "-3"}},
+		{name: "garbage retry-after", headers: map[string]string{"Retry-After": "soon"}},
+		{
+			name:    "exhausted token window",
+			headers: map[string]string{"x-ratelimit-remaining-requests": "12", "x-ratelimit-remaining-tokens": "0", "x-ratelimit-reset-tokens": "6m0s"},
+			want:    6 * time.Minute,
+			wantOK:  true,
+		},
+		{
+			name:    "window not exhausted",
+			headers: map[string]string{"x-ratelimit-remaining-requests": "3", "x-ratelimit-reset-requests": "1s"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			got, ok := headerWait(h)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("headerWait() = %s, %v; want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRetryAfterJitter(t *testing.T) {
+	h := http.Header{}
+	h.Set("Retry-After", "10")
+	for i := 0; i < 100; i++ {
+		wait, ok := retryAfter(h)
+		if !ok || wait < 10*time.Second || wait > 11*time.Second {
+			t.Fatalf("retryAfter() = %s, %v; want 10s to 11s", wait, ok)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 1, min: retryBaseDelay / 2, max: retryBaseDelay},
+		{attempt: 3, min: 2 * retryBaseDelay, max: 4 * retryBaseDelay},
+		{attempt: 10, min: retryMaxDelay / 2, max: retryMaxDelay},
+		{attempt: 64, min: retryMaxDelay / 2, max: retryMaxDelay},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			if got := backoff(tt.attempt); got < tt.min || got > tt.max {
+				t.Fatalf("backoff(%d) = %s, want %s to %s", tt.attempt, got, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		failures    int               // responses before a 200
+		status      int               // status of the failures
+		headers     map[string]string // headers of the failures
+		wantStatus  int
+		wantCalls   int
+	}{
+		{name: "429 then ok", maxAttempts: 3, failures: 2, status: http.StatusTooManyRequests, headers: map[string]string{"retry-after-ms": "5"}, wantStatus: http.StatusOK, wantCalls: 3},
+		{name: "503 is retried", maxAttempts: 2, failures: 1, status: http.StatusServiceUnavailable, headers: map[string]string{"retry-after-ms": "5"}, wantStatus: http.StatusOK, wantCalls: 2},
+		{name: "budget runs out", maxAttempts: 2, failures: 5, status: http.StatusTooManyRequests, headers: map[string]string{"retry-after-ms": "5"}, wantStatus: http.StatusTooManyRequests, wantCalls: 2},
+		{name: "other errors are not retried", maxAttempts: 3, failures: 1, status: http.StatusBadRequest, wantStatus: http.StatusBadRequest, wantCalls: 1},
+		{name: "too long a wait is not held open", maxAttempts: 3, failures: 1, status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "600"}, wantStatus: http.StatusTooManyRequests, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != "payload" {
+					t.Errorf("attempt %d sent body %q", calls.Load()+1, body)
+				}
+				if int(calls.Add(1)) <= tt.failures {
+					for k, v := range tt.headers {
+						w.Header().Set(k, v)
+					}
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			t.Cleanup(server.Close)
+
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+			resp, err := newRetryPolicy(tt.maxAttempts).do(server.Client(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || int(calls.Load()) != tt.wantCalls {
+				t.Errorf("status %d after %d calls, want %d after %d", resp.StatusCode, calls.Load(), tt.wantStatus, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	start := time.Now()
+	if _, err := newRetryPolicy(3).do(server.Client(), req); err != context.DeadlineExceeded {
+		t.Errorf("do() = %v, want the context's error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("gave up after %s, want as soon as the context ended", elapsed)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+// Longest NDJSON line /embed/stream accepts
+const maxStreamLine = 4 << 20
+
+// StreamedText is one line of a /embed/stream request
+type StreamedText struct {
+	Text string `json:"text"`
+}
+
+// StreamedEmbedding is one line of a /embed/stream response
+type StreamedEmbedding struct {
+	Index      int              `json:"index"`
+	Embedding  []float32        `json:"embedding"`
+	Adjustment *TokenAdjustment `json:"adjustment,omitempty"` // the text was over the token limit
+}
+
+// StreamSummary is the last line of a /embed/stream response. Done is false
+// and Error set if the stream failed after Count embeddings were sent.
+type StreamSummary struct {
+	Done       bool            `json:"done"`
+	Count      int             `json:"count"`
+	Model      string          `json:"model"`
+	Dimensions int             `json:"dimensions,omitempty"`
+	Usage      *EmbeddingUsage `json:"usage"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// handleEmbedStream reads texts as NDJSON lines and writes their embeddings
+// back as NDJSON, a chunk at a time as each is embedded, so a caller can
+// store the first vectors while later texts are still being sent. overflow,
+// normalize, and input_type are query parameters. A failure before any embedding is sent
+// is an ordinary error response; later ones end the stream with an error
+// summary.
+func (s *EmbeddingService) handleEmbedStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	overflow, inputType := query.Get("overflow"), query.Get("input_type")
+	if _, err := s.fitTokenLimit(nil, overflow, inputType); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var normalizeParam *bool
+	if v := query.Get("normalize"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid normalize %q", v), http.StatusBadRequest)
+			return
+		}
+		normalizeParam = &b
+	}
+	normalize := s.normalizeFor(normalizeParam)
+
+	// Embeddings are written while the request body is still being read
+	rc := http.NewResponseController(w)
+	_ = rc.EnableFullDuplex()
+	enc := json.NewEncoder(w)
+
+	chunkSize := s.streamChunkSize()
+	var texts []string
+	adjusted, dimensions := 0, 0
+	usage := &EmbeddingUsage{}
+	sent := 0
+	embed := func() error {
+		fitted, err := s.fitTokenLimitFrom(texts, overflow, inputType, sent)
+		if err != nil {
+			return err
+		}
+		resp, err := s.embedChunk(r.Context(), fitted, len(texts), normalize, nil)
+		if err != nil {
+			return err
+		}
+
+		if sent == 0 {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		adjustments := make(map[int]*TokenAdjustment, len(resp.Adjustments))
+		for i := range resp.Adjustments {
+			adjustments[resp.Adjustments[i].Index] = &resp.Adjustments[i]
+		}
+		for i, embedding := range resp.Embeddings {
+			index := sent + i
+			_ = enc.Encode(StreamedEmbedding{Index: index, Embedding: embedding, Adjustment: adjustments[index]})
+		}
+		_ = rc.Flush()
+
+		adjusted += len(resp.Adjustments)
+		dimensions = resp.Dimensions
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.Estimated = usage.Estimated || resp.Usage.Estimated
+		usage.CostUSD += resp.Usage.CostUSD
+		sent += len(texts)
+		texts = texts[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLine)
+	var err error
+	for line := 1; err == nil && scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var item StreamedText
+		if json.Unmarshal(data, &item) != nil {
+			err = errors.Validation(fmt.Sprintf("line %d is not a JSON object with a text", line))
+			break
+		}
+		texts = append(texts, item.Text)
+		if chunkSize > 0 && len(texts) >= chunkSize {
+			err = embed()
+		}
+	}
+	if err == nil {
+		if err = scanner.Err(); err != nil {
+			err = errors.Validation(fmt.Sprintf("failed to read request body: %v", err))
+		}
+	}
+	if err == nil && len(texts) > 0 {
+		err = embed()
+	}
+	s.recordRequest(sent, err)
+
+	if err != nil {
+		logger.Error("Failed to stream embeddings: %v", err)
+		if sent == 0 {
+			http.Error(w, err.Error(), streamErrorStatus(err))
+			return
+		}
+		_ = enc.Encode(StreamSummary{Count: sent, Model: s.modelID(), Dimensions: dimensions, Usage: usage, Error: err.Error()})
+		return
+	}
+	if sent == 0 {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	s.logAdjustments(adjusted, sent)
+	_ = enc.Encode(StreamSummary{Done: true, Count: sent, Model: s.modelID(), Dimensions: dimensions, Usage: usage})
+}
+
+// streamErrorStatus is 400 for a bad request and 500 for a failed embedding
+func streamErrorStatus(err error) int {
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) && appErr.Type == errors.ErrTypeValidation {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// readStream splits an /embed/stream response into its embeddings and the
+// summary line
+func readStream(t *testing.T, body string) ([]StreamedEmbedding, StreamSummary) {
+	t.Helper()
+	var embeddings []StreamedEmbedding
+	var summary StreamSummary
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if strings.Contains(string(line), `"done"`) {
+			if err := json.Unmarshal(line, &summary); err != nil {
+				t.Fatalf("summary %q: %v", line, err)
+			}
+			continue
+		}
+		var e StreamedEmbedding
+		if err := json.Unmarshal(line, &e); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		embeddings = append(embeddings, e)
+	}
+	return embeddings, summary
+}
+
+func TestHandleEmbedStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		body        string
+		failOn      string
+		tokenLimit  int
+		wantStatus  int
+		wantIndexes []int
+		wantSummary StreamSummary
+		wantAdjust  map[int]string // index to action
+	}{
+		{
+			name:        "embedded a chunk at a time",
+			body:        "{\"text\":\"a\"}\n\n{\"text\":\"bb\"}\n{\"text\":\"ccc\"}\n{\"text\":\"dddd\"}\n{\"text\":\"eeeee\"}\n",
+			wantStatus:  http.StatusOK,
+			wantIndexes: []int{0, 1, 2, 3, 4},
+			wantSummary: StreamSummary{Done: true, Count: 5, Model: "length/length", Dimensions: 2},
+		},
+		{
+			name:        "empty body",
+			wantStatus:  http.StatusOK,
+			wantSummary: StreamSummary{Done: true, Model: "length/length"},
+		},
+		{
+			name:        "adjustments keep their request index",
+			query:       "?overflow=truncate",
+			body:        "{\"text\":\"a\"}\n{\"text\":\"b\"}\n{\"text\":\"one two three four five six\"}\n",
+			tokenLimit:  3,
+			wantStatus:  http.StatusOK,
+			wantIndexes: []int{0, 1, 2},
+			wantSummary: StreamSummary{Done: true, Count: 3, Model: "length/length", Dimensions: 2},
+			wantAdjust:  map[int]string{2: "truncated"},
+		},
+		{
+			name:       "bad line before anything was sent",
+			body:       "{\"text\":\"a\"}\nnot json\n",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid overflow",
+			query:      "?overflow=drop",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid normalize",
+			query:      "?normalize=maybe",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "failure after embeddings were sent ends with an error summary",
+			body:        "{\"text\":\"a\"}\n{\"text\":\"bb\"}\n{\"text\":\"bad\"}\n",
+			failOn:      "bad",
+			wantStatus:  http.StatusOK,
+			wantIndexes: []int{0, 1},
+			wantSummary: StreamSummary{Count: 2, Model: "length/length", Dimensions: 2, Error: "provider failed on bad"},
+		},
+		{
+			name:       "failure before anything was sent",
+			body:       "{\"text\":\"bad\"}\n",
+			failOn:     "bad",
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &lengthProvider{failOn: tt.failOn}
+			// Streamed two texts at a time: batches of one, two in flight
+			s := NewEmbeddingService("length", p, 1, 2)
+			s.limitTokens(tt.tokenLimit, OverflowReject)
+
+			rec := httptest.NewRecorder()
+			s.handleEmbedStream(rec, httptest.NewRequest(http.MethodPost, "/embed/stream"+tt.query, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+
+			embeddings, summary := readStream(t, rec.Body.String())
+			if len(embeddings) != len(tt.wantIndexes) {
+				t.Fatalf("got %d embeddings, want %d", len(embeddings), len(tt.wantIndexes))
+			}
+			for i, e := range embeddings {
+				if e.Index != tt.wantIndexes[i] {
+					t.Errorf("embedding %d has index %d", i, e.Index)
+				}
+				var action string
+				if e.Adjustment != nil {
+					action = e.Adjustment.Action
+				}
+				if action != tt.wantAdjust[e.Index] {
+					t.Errorf("embedding %d adjustment = %q, want %q", e.Index, action, tt.wantAdjust[e.Index])
+				}
+			}
+			summary.Usage = nil
+			if summary != tt.wantSummary {
+				t.Errorf("summary = %+v, want %+v", summary, tt.wantSummary)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+const (
+	// Longer chunks are truncated before summarizing; the start carries most of the meaning
+	maxSummaryInput = 6000
+	// Concurrent chat requests per /summarize call
+	summaryWorkers = 4
+)
+
+const summaryPrompt = `You annotate chunks of source code and documentation for a search index.
+Reply with JSON only: {"summary": "<one sentence describing what the chunk does or explains>", "keywords": ["<up to 8 short keywords>"]}.
+Prefer identifiers, APIs, and concepts a developer would search for.`
+
+// ChunkSummary is the enrichment produced for one text
+type ChunkSummary struct {
+	Summary  string   `json:"summary"`
+	Keywords []string `json:"keywords"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Summarize asks the chat deployment for a one-sentence summary and keywords
+func (s *EmbeddingService) Summarize(ctx context.Context, text string) (*ChunkSummary, error) {
+	if s.chatClient == nil || s.chatDeployment == "" {
+		return nil, errors.Validation("no chat deployment configured")
+	}
+	if len(text) > maxSummaryInput {
+		text = strings.ToValidUTF8(text[:maxSummaryInput], "")
+	}
+
+	prompt := summaryPrompt
+	maxTokens := int32(200)
+	temperature := float32(0)
+	resp, err := s.chatClient.GetChatCompletions(ctx, azopenai.ChatCompletionsOptions{
+		DeploymentName: &s.chatDeployment,
+		Messages: []azopenai.ChatRequestMessageClassification{
+			&azopenai.ChatRequestSystemMessage{Content: &prompt},
+			&azopenai.ChatRequestUserMessage{Content: azopenai.NewChatRequestUserMessageContent(text)},
+		},
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+	}, nil)
+	if err != nil {
+		return nil, errors.External("Azure OpenAI", "failed to summarize chunk", err)
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message == nil || resp.Choices[0].Message.Content == nil {
+		return nil, errors.External("Azure OpenAI", "empty summary response", nil)
+	}
+
+	return parseSummary(*resp.Choices[0].Message.Content)
+}
+
+// parseSummary reads the model's JSON reply, tolerating a surrounding code fence
+func parseSummary(reply string) (*ChunkSummary, error) {
+	reply = strings.TrimSpace(reply)
+	if start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}"); start >= 0 && end > start {
+		reply = reply[start : end+1]
+	}
+
+	var summary ChunkSummary
+	if err := json.Unmarshal([]byte(reply), &summary); err != nil {
+		return nil, fmt.Errorf("unparseable summary reply: %w", err)
+	}
+	summary.Summary = strings.TrimSpace(summary.Summary)
+
+	keywords := summary.Keywords[:0]
+	for _, k := range summary.Keywords {
+		// Keywords are stored comma-joined, so commas inside one would split it
+		if k = strings.TrimSpace(strings.ReplaceAll(k, ",", " ")); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	summary.Keywords = keywords
+	return &summary, nil
+}
+
+// SummarizeBatch summarizes texts concurrently. Results are in input order;
+// a text that fails carries its error instead of failing the batch.
+func (s *EmbeddingService) SummarizeBatch(ctx context.Context, texts []string) []*ChunkSummary {
+	results := make([]*ChunkSummary, len(texts))
+	sem := make(chan struct{}, summaryWorkers)
+	var wg sync.WaitGroup
+
+	for i, text := range texts {
+		wg.Add(1)
+		go func(i int, text string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			summary, err := s.Summarize(ctx, text)
+			if err != nil {
+				results[i] = &ChunkSummary{Keywords: []string{}, Error: err.Error()}
+				return
+			}
+			results[i] = summary
+		}(i, text)
+	}
+
+	wg.Wait()
+	logger.Info("Summarized %d chunks", len(texts))
+	return results
+}
+
+type SummarizeRequest struct {
+	Texts []string `json:"texts"`
+}
+
+type SummarizeResponse struct {
+	Summaries []*ChunkSummary `json:"summaries"`
+	Count     int             `json:"count"`
+}
+
+func (s *EmbeddingService) handleSummarize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SummarizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if s.chatClient == nil || s.chatDeployment == "" {
+		http.Error(w, "Summarization requires AZURE_OPENAI_CHAT_DEPLOYMENT", http.StatusNotImplemented)
+		return
+	}
+
+	summaries := s.SummarizeBatch(r.Context(), req.Texts)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(SummarizeResponse{
+		Summaries: summaries,
+		Count:     len(summaries),
+	})
+}
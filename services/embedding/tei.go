@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+// TEIEmbedder implements interfaces.EmbeddingService with a self-hosted
+// embedding server speaking the HuggingFace text-embeddings-inference API.
+// The server hosts a single model; the configured name is only reported.
+type TEIEmbedder struct {
+	baseURL    string
+	model      string
+	apiKey     string // for HuggingFace Inference Endpoints; empty sends none
+	maxBatch   int    // the server's --max-client-batch-size
+	httpClient *http.Client
+	retry      retryPolicy
+	dimension  learnedDimension
+}
+
+func init() {
+	registerProvider(ProviderTEI, func(_ context.Context, cfg *config.Config) (embeddingProvider, error) {
+		c := cfg.Embedding.TEI
+		return NewTEIEmbedder(c.URL, c.Model, c.APIKey, c.MaxBatchSize, cfg.Embedding.MaxAttempts), nil
+	})
+}
+
+// NewTEIEmbedder creates an embedder for the server at baseURL
+func NewTEIEmbedder(baseURL, model, apiKey string, maxBatch, maxAttempts int) *TEIEmbedder {
+	if maxBatch <= 0 {
+		maxBatch = 32
+	}
+	return &TEIEmbedder{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		model:      model,
+		apiKey:     apiKey,
+		maxBatch:   maxBatch,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+		retry:      newRetryPolicy(maxAttempts),
+	}
+}
+
+// GenerateEmbedding creates a vector embedding for text
+func (e *TEIEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return firstEmbedding(e.GenerateBatchEmbeddings(ctx, []string{text}))
+}
+
+// GenerateBatchEmbeddings creates embeddings for up to maxBatch texts
+func (e *TEIEmbedder) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"inputs":   texts,
+		"truncate": true,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embed", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, errors.Internal("failed to build embedding server request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.retry.do(e.httpClient, req)
+	if err != nil {
+		return nil, errors.Network("embedding server request failed", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, errors.External("embedding server", "failed to generate embeddings", fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(body)))
+	}
+
+	var embeddings [][]float32
+	if err := json.NewDecoder(resp.Body).Decode(&embeddings); err != nil {
+		return nil, errors.External("embedding server", "invalid embeddings response", err)
+	}
+	if len(embeddings) != len(texts) {
+		return nil, errors.External("embedding server", fmt.Sprintf("got %d embeddings for %d texts", len(embeddings), len(texts)), nil)
+	}
+	e.dimension.learn(len(embeddings[0]))
+
+	logger.Info("Generated %d embeddings", len(embeddings))
+	return embeddings, nil
+}
+
+// GetDimension returns the dimension of embeddings, or 0 until the first
+// embedding is generated
+func (e *TEIEmbedder) GetDimension() int {
+	return e.dimension.get()
+}
+
+// MaxBatchSize returns the server's client batch limit
+func (e *TEIEmbedder) MaxBatchSize() int {
+	return e.maxBatch
+}
+
+// Model returns the configured model label, or the server URL without one
+func (e *TEIEmbedder) Model() string {
+	if e.model == "" {
+		return e.baseURL
+	}
+	return e.model
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+)
+
+// Input token limits of embedding models the providers serve
+var modelTokenLimits = map[string]int{
+	"text-embedding-ada-002":          8191,
+	"text-embedding-3-small":          8191,
+	"text-embedding-3-large":          8191,
+	"embed-english-v3.0":              512,
+	"embed-multilingual-v3.0":         512,
+	"embed-english-light-v3.0":        512,
+	"embed-multilingual-light-v3.0":   512,
+	"nomic-embed-text":                8192,
+	"mxbai-embed-large":               512,
+	"all-minilm":                      256,
+	"bge-m3":                          8192,
+	"text-embedding-004":              2048,
+	"text-embedding-005":              2048,
+	"text-multilingual-embedding-002": 2048,
+	"textembedding-gecko@003":         3072,
+	"amazon.titan-embed-text-v1":      8192,
+	"amazon.titan-embed-text-v2:0":    8192,
+	"cohere.embed-english-v3":         512,
+	"cohere.embed-multilingual-v3":    512,
+}
+
+// What to do with a text over the model's token limit
+const (
+	OverflowReject   = "reject"   // fail the request
+	OverflowTruncate = "truncate" // embed the start of the text
+	OverflowSplit    = "split"    // embed parts and average them into one vector
+)
+
+var overflowModes = map[string]bool{OverflowReject: true, OverflowTruncate: true, OverflowSplit: true}
+
+// Same conservative estimate the document processor chunks with: the larger
+// of the word and punctuation count and the byte length divided by three
+var tokenRe = regexp.MustCompile(`[\p{L}\p{M}\p{N}_]+|[^\p{L}\p{M}\p{N}_\s]`)
+
+const bytesPerTokenEstimate = 3
+
+func estimateTokens(text string) int {
+	words := len(tokenRe.FindAllStringIndex(text, -1))
+	return max(words, (len(text)+bytesPerTokenEstimate-1)/bytesPerTokenEstimate)
+}
+
+// resolveTokenLimit returns maxTokens when set, otherwise the first of the
+// models found in the table, or 0 for no limit
+func resolveTokenLimit(maxTokens int, models ...string) int {
+	if maxTokens > 0 {
+		return maxTokens
+	}
+	for _, model := range models {
+		if limit, ok := modelTokenLimits[strings.ToLower(model)]; ok {
+			return limit
+		}
+	}
+	return 0
+}
+
+// TokenAdjustment reports a text that was over the token limit and what was
+// done about it
+type TokenAdjustment struct {
+	Index  int    `json:"index"`
+	Tokens int    `json:"tokens"` // estimated tokens in the original text
+	Limit  int    `json:"limit"`
+	Action string `json:"action"`          // truncated or split
+	Parts  int    `json:"parts,omitempty"` // parts embedded for a split text
+}
+
+// fittedTexts are the texts actually sent to the provider, with the
+// original index each one belongs to
+type fittedTexts struct {
+	texts       []string
+	owners      []int
+	adjustments []TokenAdjustment
+	inputType   string // document or query, "" if the request didn't say
+}
+
+// fitTokenLimit prefixes every text for inputType and applies the overflow
+// mode to those over s.tokenLimit
+func (s *EmbeddingService) fitTokenLimit(texts []string, overflow, inputType string) (*fittedTexts, error) {
+	return s.fitTokenLimitFrom(texts, overflow, inputType, 0)
+}
+
+// fitTokenLimitFrom is fitTokenLimit for texts that start at index first of
+// a larger request; adjustments and errors use indexes in the request
+func (s *EmbeddingService) fitTokenLimitFrom(texts []string, overflow, inputType string, first int) (*fittedTexts, error) {
+	if overflow == "" {
+		overflow = s.overflow
+	}
+	if !overflowModes[overflow] {
+		return nil, errors.Validation(fmt.Sprintf("unknown overflow mode %q (available: reject, truncate, split)", overflow))
+	}
+	if err := checkInputType(inputType); err != nil {
+		return nil, err
+	}
+
+	// The prefix goes before every part, so it comes out of the limit
+	prefix := s.prefixes.forType(inputType)
+	limit := s.tokenLimit
+	if limit > 0 && prefix != "" {
+		limit = max(limit-estimateTokens(prefix), 1)
+	}
+
+	fitted := &fittedTexts{
+		texts:     make([]string, 0, len(texts)),
+		owners:    make([]int, 0, len(texts)),
+		inputType: inputType,
+	}
+	var rejected []string
+	for i, text := range texts {
+		tokens := 0
+		if limit > 0 {
+			tokens = estimateTokens(text)
+		}
+		if tokens <= limit {
+			fitted.texts = append(fitted.texts, prefix+text)
+			fitted.owners = append(fitted.owners, i)
+			continue
+		}
+
+		adj := TokenAdjustment{Index: first + i, Tokens: tokens, Limit: limit}
+		switch overflow {
+		case OverflowReject:
+			rejected = append(rejected, fmt.Sprintf("%d (%d tokens)", first+i, tokens))
+			continue
+		case OverflowTruncate:
+			adj.Action = "truncated"
+			fitted.texts = append(fitted.texts, prefix+truncateToTokens(text, limit))
+			fitted.owners = append(fitted.owners, i)
+		case OverflowSplit:
+			parts := splitToTokens(text, limit)
+			adj.Action = "split"
+			adj.Parts = len(parts)
+			for _, part := range parts {
+				fitted.texts = append(fitted.texts, prefix+part)
+				fitted.owners = append(fitted.owners, i)
+			}
+		}
+		fitted.adjustments = append(fitted.adjustments, adj)
+	}
+
+	if len(rejected) > 0 {
+		return nil, errors.Validation(fmt.Sprintf("texts over the %d token limit: %s", limit, strings.Join(rejected, ", ")))
+	}
+	return fitted, nil
+}
+
+// combine maps embeddings of the fitted texts back to one per original
+// text. Parts of a split text are averaged, weighted by length, and
+// normalized to unit length.
+func (f *fittedTexts) combine(embeddings [][]float32, count int) [][]float32 {
+	if len(f.texts) == count {
+		return embeddings
+	}
+
+	parts := make([]int, count)
+	for _, owner := range f.owners {
+		parts[owner]++
+	}
+
+	out := make([][]float32, count)
+	sums := make([][]float64, count)
+	for j, owner := range f.owners {
+		if parts[owner] == 1 {
+			out[owner] = embeddings[j]
+			continue
+		}
+		if sums[owner] == nil {
+			sums[owner] = make([]float64, len(embeddings[j]))
+		}
+		weight := float64(len(f.texts[j]))
+		for k, v := range embeddings[j] {
+			if k < len(sums[owner]) {
+				sums[owner][k] += float64(v) * weight
+			}
+		}
+	}
+	for i, sum := range sums {
+		if sum == nil {
+			continue
+		}
+		out[i] = make([]float32, len(sum))
+		for k, v := range sum {
+			out[i][k] = float32(v)
+		}
+		l2Normalize(out[i])
+	}
+	return out
+}
+
+// slice returns the fitted texts of original texts start to end, with
+// owners counted from start. Adjustments keep their index in the whole
+// request.
+func (f *fittedTexts) slice(start, end int) *fittedTexts {
+	lo := sort.SearchInts(f.owners, start)
+	hi := sort.SearchInts(f.owners, end)
+	sub := &fittedTexts{
+		texts:     f.texts[lo:hi],
+		owners:    make([]int, hi-lo),
+		inputType: f.inputType,
+	}
+	for j, owner := range f.owners[lo:hi] {
+		sub.owners[j] = owner - start
+	}
+	for _, adj := range f.adjustments {
+		if adj.Index >= start && adj.Index < end {
+			sub.adjustments = append(sub.adjustments, adj)
+		}
+	}
+	return sub
+}
+
+// truncateToTokens keeps the longest prefix of text estimated to fit limit,
+// ending at whitespace where one is close to the cut
+func truncateToTokens(text string, limit int) string {
+	cut := fitPrefix(text, limit)
+	if ws := strings.LastIndexAny(text[:cut], " \t\n"); ws > cut*9/10 {
+		cut = ws
+	}
+	return text[:cut]
+}
+
+// splitToTokens cuts text into consecutive parts that each fit limit
+func splitToTokens(text string, limit int) []string {
+	var parts []string
+	for text != "" {
+		if estimateTokens(text) <= limit {
+			parts = append(parts, text)
+			break
+		}
+		part := truncateToTokens(text, limit)
+		if part == "" {
+			part = text[:fitPrefix(text, limit)]
+		}
+		parts = append(parts, part)
+		text = text[len(part):]
+	}
+	return parts
+}
+
+// fitPrefix returns the byte length of the longest prefix of text, on a
+// rune boundary, whose estimate is within limit, found by binary search
+func fitPrefix(text string, limit int) int {
+	lo, hi := 0, len(text)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if estimateTokens(text[:mid]) <= limit {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	for lo > 0 && lo < len(text) && !utf8.RuneStart(text[lo]) {
+		lo--
+	}
+	if lo == 0 && text != "" {
+		// Always make progress, even if a single rune is over the limit
+		_, size := utf8.DecodeRuneInString(text)
+		lo = size
+	}
+	return lo
+}
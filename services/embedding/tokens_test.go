@@ -0,0 +1,194 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFitTokenLimit(t *testing.T) {
+	long := "one two three four five six seven eight nine ten eleven twelve"
+
+	tests := []struct {
+		name       string
+		limit      int
+		prefixes   inputPrefixes
+		texts      []string
+		overflow   string
+		inputType  string
+		wantErr    bool
+		wantOwners []int
+		wantAction string // action of the single adjustment, "" for none
+	}{
+		{
+			name:       "no limit passes texts through",
+			texts:      []string{"a", long},
+			overflow:   OverflowReject,
+			wantOwners: []int{0, 1},
+		},
+		{
+			name:       "under the limit",
+			limit:      100,
+			texts:      []string{"short", long},
+			overflow:   OverflowReject,
+			wantOwners: []int{0, 1},
+		},
+		{
+			name:     "reject fails the request",
+			limit:    5,
+			texts:    []string{"short", long},
+			overflow: OverflowReject,
+			wantErr:  true,
+		},
+		{
+			name:       "truncate keeps one text per input",
+			limit:      5,
+			texts:      []string{"short", long},
+			overflow:   OverflowTruncate,
+			wantOwners: []int{0, 1},
+			wantAction: "truncated",
+		},
+		{
+			name:       "split embeds several parts for one input",
+			limit:      5,
+			texts:      []string{long, "short"},
+			overflow:   OverflowSplit,
+			wantOwners: []int{0, 0, 0, 0, 0, 1},
+			wantAction: "split",
+		},
+		{
+			name:       "prefix counts against the limit",
+			limit:      8,
+			prefixes:   inputPrefixes{query: "search_query: "},
+			texts:      []string{"one two three four five six"},
+			overflow:   OverflowSplit,
+			inputType:  InputQuery,
+			wantOwners: []int{0, 0, 0},
+			wantAction: "split",
+		},
+		{
+			name:     "unknown overflow mode",
+			texts:    []string{"a"},
+			overflow: "drop",
+			wantErr:  true,
+		},
+		{
+			name:      "unknown input type",
+			texts:     []string{"a"},
+			overflow:  OverflowReject,
+			inputType: "passage",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &EmbeddingService{tokenLimit: tt.limit, overflow: OverflowReject, prefixes: tt.prefixes}
+
+			fitted, err := s.fitTokenLimit(tt.texts, tt.overflow, tt.inputType)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %d texts", len(fitted.texts))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(fitted.owners, tt.wantOwners) {
+				t.Errorf("owners = %v, want %v", fitted.owners, tt.wantOwners)
+			}
+
+			prefix := tt.prefixes.forType(tt.inputType)
+			for i, text := range fitted.texts {
+				if !strings.HasPrefix(text, prefix) {
+					t.Errorf("text %d = %q, missing prefix %q", i, text, prefix)
+				}
+				if tt.limit > 0 && estimateTokens(text) > tt.limit {
+					t.Errorf("text %d is %d tokens, over the limit of %d", i, estimateTokens(text), tt.limit)
+				}
+			}
+
+			if tt.wantAction == "" {
+				if len(fitted.adjustments) != 0 {
+					t.Errorf("adjustments = %+v, want none", fitted.adjustments)
+				}
+				return
+			}
+			if len(fitted.adjustments) != 1 || fitted.adjustments[0].Action != tt.wantAction {
+				t.Fatalf("adjustments = %+v, want one %s", fitted.adjustments, tt.wantAction)
+			}
+			if tt.wantAction == "split" {
+				var joined strings.Builder
+				for j, owner := range fitted.owners {
+					if owner == fitted.adjustments[0].Index {
+						joined.WriteString(strings.TrimPrefix(fitted.texts[j], prefix))
+					}
+				}
+				if original := tt.texts[fitted.adjustments[0].Index]; joined.String() != original {
+					t.Errorf("split parts join to %q, want %q", joined.String(), original)
+				}
+			}
+		})
+	}
+}
+
+func TestCombine(t *testing.T) {
+	tests := []struct {
+		name       string
+		texts      []string
+		owners     []int
+		embeddings [][]float32
+		count      int
+		want       [][]float32
+	}{
+		{
+			name:       "one text per input is returned as is",
+			texts:      []string{"a", "b"},
+			owners:     []int{0, 1},
+			embeddings: [][]float32{{1, 2}, {3, 4}},
+			count:      2,
+			want:       [][]float32{{1, 2}, {3, 4}},
+		},
+		{
+			name:       "parts are averaged by length and normalized",
+			texts:      []string{"aaaa", "bb", "c"},
+			owners:     []int{0, 0, 1},
+			embeddings: [][]float32{{1, 0}, {0, 1}, {5, 5}},
+			count:      2,
+			want:       [][]float32{{float32(4 / math.Sqrt(20)), float32(2 / math.Sqrt(20))}, {5, 5}},
+		},
+		{
+			name:       "opposite parts of equal length cancel out",
+			texts:      []string{"aa", "bb"},
+			owners:     []int{0, 0},
+			embeddings: [][]float32{{1, 0}, {-1, 0}},
+			count:      1,
+			want:       [][]float32{{0, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fitted := &fittedTexts{texts: tt.texts, owners: tt.owners}
+			got := fitted.combine(tt.embeddings, tt.count)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d embeddings, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				if len(got[i]) != len(tt.want[i]) {
+					t.Fatalf("embedding %d = %v, want %v", i, got[i], tt.want[i])
+				}
+				for k := range tt.want[i] {
+					if math.Abs(float64(got[i][k]-tt.want[i][k])) > 1e-6 {
+						t.Errorf("embedding %d = %v, want %v", i, got[i], tt.want[i])
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prices in USD per 1,000 input tokens, for models billed by the token.
+// EMBEDDING_COST_PER_1K_TOKENS overrides the table; other models cost 0.
+var modelPricesPer1K = map[string]float64{
+	"text-embedding-ada-002":        0.0001,
+	"text-embedding-3-small":        0.00002,
+	"text-embedding-3-large":        0.00013,
+	"embed-english-v3.0":            0.0001,
+	"embed-multilingual-v3.0":       0.0001,
+	"embed-english-light-v3.0":      0.0001,
+	"embed-multilingual-light-v3.0": 0.0001,
+	"amazon.titan-embed-text-v1":    0.0001,
+	"amazon.titan-embed-text-v2:0":  0.00002,
+	"cohere.embed-english-v3":       0.0001,
+	"cohere.embed-multilingual-v3":  0.0001,
+}
+
+// resolvePricePer1K returns override when set, otherwise the price of the
+// first of the models found in the table
+func resolvePricePer1K(override float64, models ...string) float64 {
+	if override > 0 {
+		return override
+	}
+	for _, model := range models {
+		if price, ok := modelPricesPer1K[strings.ToLower(model)]; ok {
+			return price
+		}
+	}
+	return 0
+}
+
+// tokenUsage accumulates input tokens. Providers record what the API
+// reported; when a call reports nothing the service estimates it.
+type tokenUsage struct {
+	mu        sync.Mutex
+	tokens    int
+	reported  bool
+	estimated bool
+}
+
+func (u *tokenUsage) add(tokens int, estimated bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.tokens += tokens
+	u.reported = true
+	u.estimated = u.estimated || estimated
+}
+
+func (u *tokenUsage) get() (tokens int, reported, estimated bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.tokens, u.reported, u.estimated
+}
+
+type (
+	callUsageKey    struct{}
+	requestUsageKey struct{}
+)
+
+// recordUsage adds the input tokens a provider API reported for a call
+// made with ctx
+func recordUsage(ctx context.Context, tokens int) {
+	if u, ok := ctx.Value(callUsageKey{}).(*tokenUsage); ok {
+		u.add(tokens, false)
+	}
+}
+
+// withRequestUsage collects usage of every provider call made with ctx
+func withRequestUsage(ctx context.Context, u *tokenUsage) context.Context {
+	return context.WithValue(ctx, requestUsageKey{}, u)
+}
+
+// EmbeddingUsage reports the input tokens a request used and their price
+type EmbeddingUsage struct {
+	PromptTokens int     `json:"prompt_tokens"`
+	Estimated    bool    `json:"estimated,omitempty"` // some calls reported no usage and were estimated
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// usageStats are lifetime counters reported on /stats
+type usageStats struct {
+	mu              sync.Mutex
+	since           time.Time
+	requests        int
+	failedRequests  int
+	texts           int
+	apiCalls        int
+	promptTokens    int
+	estimatedTokens int
+	costUSD         float64
+}
+
+// EmbeddingStats is the /stats response
+type EmbeddingStats struct {
+	Provider        string    `json:"provider"`
+	Model           string    `json:"model"`
+	Since           time.Time `json:"since"`
+	Requests        int       `json:"requests"`
+	FailedRequests  int       `json:"failed_requests"`
+	Texts           int       `json:"texts"`
+	APICalls        int       `json:"api_calls"`
+	PromptTokens    int       `json:"prompt_tokens"`
+	EstimatedTokens int       `json:"estimated_tokens"` // part of prompt_tokens not reported by the provider
+	CostUSD         float64   `json:"cost_usd"`
+	PricePer1K      float64   `json:"price_per_1k_tokens"`
+}
+
+// recordCall accounts one provider call of texts made with ctx, estimating
+// its tokens if the provider reported none
+func (s *EmbeddingService) recordCall(ctx context.Context, call *tokenUsage, texts []string) {
+	tokens, reported, _ := call.get()
+	estimated := !reported
+	if estimated {
+		for _, text := range texts {
+			tokens += estimateTokens(text)
+		}
+	}
+	if u, ok := ctx.Value(requestUsageKey{}).(*tokenUsage); ok {
+		u.add(tokens, estimated)
+	}
+
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	s.stats.apiCalls++
+	s.stats.promptTokens += tokens
+	if estimated {
+		s.stats.estimatedTokens += tokens
+	}
+	s.stats.costUSD += s.cost(tokens)
+}
+
+// recordRequest accounts one /embed request or job
+func (s *EmbeddingService) recordRequest(texts int, err error) {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	s.stats.requests++
+	if err != nil {
+		s.stats.failedRequests++
+		return
+	}
+	s.stats.texts += texts
+}
+
+func (s *EmbeddingService) cost(tokens int) float64 {
+	return float64(tokens) / 1000 * s.pricePer1K
+}
+
+// requestUsage turns a request's accumulated usage into its report
+func (s *EmbeddingService) requestUsage(u *tokenUsage) *EmbeddingUsage {
+	tokens, _, estimated := u.get()
+	return &EmbeddingUsage{
+		PromptTokens: tokens,
+		Estimated:    estimated,
+		CostUSD:      s.cost(tokens),
+	}
+}
+
+func (s *EmbeddingService) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.stats.mu.Lock()
+	stats := EmbeddingStats{
+		Provider:        s.providerName,
+		Model:           s.provider.Model(),
+		Since:           s.stats.since,
+		Requests:        s.stats.requests,
+		FailedRequests:  s.stats.failedRequests,
+		Texts:           s.stats.texts,
+		APICalls:        s.stats.apiCalls,
+		PromptTokens:    s.stats.promptTokens,
+		EstimatedTokens: s.stats.estimatedTokens,
+		CostUSD:         s.stats.costUSD,
+		PricePer1K:      s.pricePer1K,
+	}
+	s.stats.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestUsage(t *testing.T) {
+	tests := []struct {
+		name          string
+		tokens        int // reported by the provider per call
+		texts         string
+		wantTokens    int
+		wantEstimated bool
+		wantCost      float64
+	}{
+		{name: "reported by the provider", tokens: 4, texts: `["one two", "three"]`, wantTokens: 8, wantCost: 0.0008},
+		// "one two" is 3 tokens by length, "three" 2
+		{name: "estimated when not reported", texts: `["one two", "three"]`, wantTokens: 5, wantEstimated: true, wantCost: 0.0005},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewEmbeddingService("length", &lengthProvider{tokens: tt.tokens}, 1, 1)
+			s.limitTokens(0, OverflowReject)
+			s.priceTokens(0.1)
+
+			rec := httptest.NewRecorder()
+			s.handleEmbed(rec, httptest.NewRequest(http.MethodPost, "/embed", strings.NewReader(`{"texts":`+tt.texts+`}`)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp EmbeddingResponse
+			_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+			if resp.Usage.PromptTokens != tt.wantTokens || resp.Usage.Estimated != tt.wantEstimated || math.Abs(resp.Usage.CostUSD-tt.wantCost) > 1e-12 {
+				t.Errorf("usage = %+v, want %d tokens (estimated %v) costing %g", resp.Usage, tt.wantTokens, tt.wantEstimated, tt.wantCost)
+			}
+
+			rec = httptest.NewRecorder()
+			s.handleStats(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+			var stats EmbeddingStats
+			_ = json.Unmarshal(rec.Body.Bytes(), &stats)
+			wantEstimated := 0
+			if tt.wantEstimated {
+				wantEstimated = tt.wantTokens
+			}
+			if stats.Requests != 1 || stats.Texts != 2 || stats.APICalls != 2 || stats.PromptTokens != tt.wantTokens ||
+				stats.EstimatedTokens != wantEstimated || stats.Model != "length" || stats.PricePer1K != 0.1 {
+				t.Errorf("stats = %+v", stats)
+			}
+		})
+	}
+}
+
+func TestFailedRequestStats(t *testing.T) {
+	s := NewEmbeddingService("length", &lengthProvider{failOn: "bad"}, 0, 1)
+	s.limitTokens(0, OverflowReject)
+	rec := httptest.NewRecorder()
+	s.handleEmbed(rec, httptest.NewRequest(http.MethodPost, "/embed", strings.NewReader(`{"texts":["bad"]}`)))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if s.stats.requests != 1 || s.stats.failedRequests != 1 || s.stats.texts != 0 {
+		t.Errorf("stats = %d requests, %d failed, %d texts", s.stats.requests, s.stats.failedRequests, s.stats.texts)
+	}
+}
+
+func TestResolvePricePer1K(t *testing.T) {
+	tests := []struct {
+		override float64
+		models   []string
+		want     float64
+	}{
+		{override: 0.5, models: []string{"text-embedding-3-small"}, want: 0.5},
+		{models: []string{"my-deployment", "Text-Embedding-3-Large"}, want: 0.00013},
+		{models: []string{"nomic-embed-text"}, want: 0},
+	}
+	for _, tt := range tests {
+		if got := resolvePricePer1K(tt.override, tt.models...); got != tt.want {
+			t.Errorf("resolvePricePer1K(%g, %v) = %g, want %g", tt.override, tt.models, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	// Vertex AI accepts at most this many instances per predict call
+	vertexMaxBatch = 250
+	// and at most 20k input tokens; texts are batched by characters
+	// (about 4 per token) to stay under it
+	vertexMaxBatchChars = 60000
+)
+
+// Output dimensions of Vertex AI text embedding models
+var vertexModelDimensions = map[string]int{
+	"text-embedding-004":              768,
+	"text-embedding-005":              768,
+	"text-multilingual-embedding-002": 768,
+	"textembedding-gecko@003":         768,
+}
+
+// serviceAccountKey is the subset of a service account JSON key used to
+// mint access tokens
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// VertexEmbedder implements interfaces.EmbeddingService with Vertex AI text
+// embedding models, authenticating as a service account
+type VertexEmbedder struct {
+	model      string
+	endpoint   string // the model's predict URL
+	httpClient *http.Client
+	retry      retryPolicy
+	dimension  learnedDimension
+}
+
+func init() {
+	registerProvider(ProviderVertex, func(_ context.Context, cfg *config.Config) (embeddingProvider, error) {
+		c := cfg.Embedding.Vertex
+		return NewVertexEmbedder(c.CredentialsFile, c.ProjectID, c.Location, c.Model, c.BaseURL, cfg.Embedding.MaxAttempts)
+	})
+}
+
+// NewVertexEmbedder creates an embedder for model in a GCP project and
+// location, using the service account key at credentialsFile. baseURL
+// overrides the regional endpoint, e.g. for Private Service Connect.
+func NewVertexEmbedder(credentialsFile, projectID, location, model, baseURL string, maxAttempts int) (*VertexEmbedder, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account key: %w", err)
+	}
+	var key serviceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("invalid service account key %s: %w", credentialsFile, err)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, fmt.Errorf("%s is not a service account key", credentialsFile)
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	conf := &jwt.Config{
+		Email:        key.ClientEmail,
+		PrivateKey:   []byte(key.PrivateKey),
+		PrivateKeyID: key.PrivateKeyID,
+		Scopes:       []string{"https://www.googleapis.com/auth/cloud-platform"},
+		TokenURL:     key.TokenURI,
+	}
+	// Tokens are cached and refreshed by the client as they expire
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: 30 * time.Second})
+	httpClient := conf.Client(ctx)
+	httpClient.Timeout = 60 * time.Second
+
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://%s-aiplatform.googleapis.com", location)
+	}
+	return &VertexEmbedder{
+		model: model,
+		endpoint: fmt.Sprintf("%s/v1/projects/%s/locations/%s/publishers/google/models/%s:predict",
+			strings.TrimSuffix(baseURL, "/"), projectID, location, model),
+		httpClient: httpClient,
+		retry:      newRetryPolicy(maxAttempts),
+		dimension:  learnedDimension{value: vertexModelDimensions[model]},
+	}, nil
+}
+
+// GenerateEmbedding creates a vector embedding for text
+func (e *VertexEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return firstEmbedding(e.GenerateBatchEmbeddings(ctx, []string{text}))
+}
+
+// GenerateBatchEmbeddings creates embeddings for multiple texts, in calls
+// within the instance and input size limits
+func (e *VertexEmbedder) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); {
+		end, chars := start, 0
+		for end < len(texts) && end-start < vertexMaxBatch && (end == start || chars+len(texts[end]) <= vertexMaxBatchChars) {
+			chars += len(texts[end])
+			end++
+		}
+		batch, err := e.predict(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, batch...)
+		start = end
+	}
+	if len(embeddings) > 0 {
+		e.dimension.learn(len(embeddings[0]))
+		logger.Info("Generated %d embeddings", len(embeddings))
+	}
+	return embeddings, nil
+}
+
+func (e *VertexEmbedder) predict(ctx context.Context, texts []string) ([][]float32, error) {
+	taskType := "RETRIEVAL_DOCUMENT"
+	if inputTypeOf(ctx) == InputQuery {
+		taskType = "RETRIEVAL_QUERY"
+	}
+	instances := make([]map[string]string, len(texts))
+	for i, text := range texts {
+		instances[i] = map[string]string{"content": text, "task_type": taskType}
+	}
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"instances":  instances,
+		"parameters": map[string]interface{}{"autoTruncate": true},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, errors.Internal("failed to build Vertex AI request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.retry.do(e.httpClient, req)
+	if err != nil {
+		return nil, errors.Network("Vertex AI request failed", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, errors.External("Vertex AI", "failed to generate embeddings", fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(body)))
+	}
+
+	var result struct {
+		Predictions []struct {
+			Embeddings struct {
+				Values     []float32 `json:"values"`
+				Statistics struct {
+					TokenCount float64 `json:"token_count"`
+				} `json:"statistics"`
+			} `json:"embeddings"`
+		} `json:"predictions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, errors.External("Vertex AI", "invalid embeddings response", err)
+	}
+	if len(result.Predictions) != len(texts) {
+		return nil, errors.External("Vertex AI", fmt.Sprintf("got %d embeddings for %d texts", len(result.Predictions), len(texts)), nil)
+	}
+
+	embeddings := make([][]float32, len(result.Predictions))
+	tokens := 0
+	for i, prediction := range result.Predictions {
+		embeddings[i] = prediction.Embeddings.Values
+		tokens += int(prediction.Embeddings.Statistics.TokenCount)
+	}
+	if tokens > 0 {
+		recordUsage(ctx, tokens)
+	}
+	return embeddings, nil
+}
+
+// GetDimension returns the dimension of embeddings, or 0 for a model not in
+// the table until its first embedding is generated
+func (e *VertexEmbedder) GetDimension() int {
+	return e.dimension.get()
+}
+
+// Model returns the embedding model
+func (e *VertexEmbedder) Model() string {
+	return e.model
+}
+
+// MaxBatchSize returns the most instances per predict call; batches are
+// further split by size to stay within the token limit
+func (e *VertexEmbedder) MaxBatchSize() int {
+	return vertexMaxBatch
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeServiceAccountKey writes a service account key whose tokens come
+// from tokenURI
+func writeServiceAccountKey(t *testing.T, tokenURI string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(serviceAccountKey{
+		Type:        "service_account",
+		ClientEmail: "embedder@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		TokenURI:    tokenURI,
+	})
+	path := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVertexEmbedder(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]interface{}
+	var taskTypes []string
+	var auths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"vertex-token","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+		if r.URL.Path != "/v1/projects/proj/locations/us-central1/publishers/google/models/text-embedding-004:predict" {
+			t.Errorf("predict path = %s", r.URL.Path)
+		}
+		var req struct {
+			Instances  []map[string]interface{} `json:"instances"`
+			Parameters map[string]interface{}   `json:"parameters"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Parameters["autoTruncate"] != true {
+			t.Errorf("parameters = %v, want autoTruncate", req.Parameters)
+		}
+
+		predictions := make([]map[string]interface{}, len(req.Instances))
+		var contents []interface{}
+		for i, instance := range req.Instances {
+			contents = append(contents, instance["content"])
+			predictions[i] = map[string]interface{}{
+				"embeddings": map[string]interface{}{"values": []float32{float32(i), 1, 0}, "statistics": map[string]interface{}{"token_count": 2.0}},
+			}
+		}
+		mu.Lock()
+		batches = append(batches, contents)
+		taskTypes = append(taskTypes, req.Instances[0]["task_type"].(string))
+		auths = append(auths, r.Header.Get("Authorization"))
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"predictions": predictions})
+	}))
+	t.Cleanup(server.Close)
+
+	e, err := NewVertexEmbedder(writeServiceAccountKey(t, server.URL+"/token"), "proj", "us-central1", "text-embedding-004", server.URL+"/", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.GetDimension() != 768 || e.MaxBatchSize() != vertexMaxBatch {
+		t.Errorf("dimension %d, batch %d before any call", e.GetDimension(), e.MaxBatchSize())
+	}
+
+	tests := []struct {
+		name        string
+		inputType   string
+		texts       []string
+		wantBatches int
+		wantTask    string
+	}{
+		{name: "documents", texts: []string{"a", "b"}, wantBatches: 1, wantTask: "RETRIEVAL_DOCUMENT"},
+		{name: "queries", inputType: InputQuery, texts: []string{"q"}, wantBatches: 1, wantTask: "RETRIEVAL_QUERY"},
+		{
+			name:        "long texts are split by size",
+			texts:       []string{strings.Repeat("x", vertexMaxBatchChars-10), strings.Repeat("y", 20), "z"},
+			wantBatches: 2,
+			wantTask:    "RETRIEVAL_DOCUMENT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches, taskTypes, auths = nil, nil, nil
+			got, tokens, err := embedWithUsage(withInputType(context.Background(), tt.inputType), e, tt.texts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.texts) || tokens != 2*len(tt.texts) {
+				t.Errorf("got %d embeddings and %d tokens for %d texts", len(got), tokens, len(tt.texts))
+			}
+			if len(batches) != tt.wantBatches {
+				t.Errorf("made %d predict calls, want %d", len(batches), tt.wantBatches)
+			}
+			var sent []interface{}
+			for _, batch := range batches {
+				sent = append(sent, batch...)
+			}
+			want := make([]interface{}, len(tt.texts))
+			for i, text := range tt.texts {
+				want[i] = text
+			}
+			if !reflect.DeepEqual(sent, want) {
+				t.Error("texts were not sent in order")
+			}
+			for i := range taskTypes {
+				if taskTypes[i] != tt.wantTask || auths[i] != "Bearer vertex-token" {
+					t.Errorf("call %d: task %s, auth %q", i, taskTypes[i], auths[i])
+				}
+			}
+		})
+	}
+	if e.GetDimension() != 3 {
+		t.Errorf("dimension = %d, want 3 from the responses", e.GetDimension())
+	}
+}
+
+func TestNewVertexEmbedderKeys(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		_ = os.WriteFile(path, []byte(content), 0o600)
+		return path
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "missing file", path: filepath.Join(dir, "absent.json")},
+		{name: "not json", path: write("bad.json", "{")},
+		{name: "user credentials", path: write("user.json", `{"type":"authorized_user","client_email":"a@b","private_key":"k"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewVertexEmbedder(tt.path, "proj", "us-central1", "text-embedding-004", "", 1); err == nil {
+				t.Error("NewVertexEmbedder() accepted the key")
+			}
+		})
+	}
+}
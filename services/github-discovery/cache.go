@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+)
+
+// BlobCache stores file contents on disk keyed by Git blob SHA. Blob SHAs are
+// content addresses, so entries never go stale and are shared across
+// repositories, retries, and projects.
+type BlobCache struct {
+	dir string
+}
+
+// NewBlobCache creates a cache rooted at dir, or returns nil when dir is empty
+func NewBlobCache(dir string) (*BlobCache, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create content cache directory: %w", err)
+	}
+	return &BlobCache{dir: dir}, nil
+}
+
+// Get returns cached content for a blob SHA
+func (c *BlobCache) Get(sha string) ([]byte, bool) {
+	if c == nil || len(sha) < 3 {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(c.path(sha))
+	if err != nil {
+		return nil, false
+	}
+
+	// Treat corrupted entries as misses so they get re-fetched and overwritten
+	if gitBlobSHA(content) != sha {
+		logger.Warning("Discarding corrupted cache entry for blob %s", sha)
+		return nil, false
+	}
+	return content, true
+}
+
+// Put stores content under its blob SHA if the content actually hashes to it
+func (c *BlobCache) Put(sha string, content []byte) {
+	if c == nil || len(sha) < 3 {
+		return
+	}
+	if gitBlobSHA(content) != sha {
+		logger.Debug("Not caching blob %s: content hash mismatch", sha)
+		return
+	}
+
+	path := c.path(sha)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		logger.Warning("Failed to create cache directory: %v", err)
+		return
+	}
+
+	// Write to a temp file and rename so concurrent readers never see partial content
+	tmp, err := os.CreateTemp(filepath.Dir(path), sha+".tmp-*")
+	if err != nil {
+		logger.Warning("Failed to write cache entry for blob %s: %v", sha, err)
+		return
+	}
+	_, writeErr := tmp.Write(content)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		_ = os.Remove(tmp.Name())
+		logger.Warning("Failed to write cache entry for blob %s", sha)
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		logger.Warning("Failed to store cache entry for blob %s: %v", sha, err)
+	}
+}
+
+// path shards entries by the first two hex characters, like .git/objects
+func (c *BlobCache) path(sha string) string {
+	return filepath.Join(c.dir, sha[:2], sha[2:])
+}
+
+// gitBlobSHA computes the SHA Git assigns to a blob with the given content
+func gitBlobSHA(content []byte) string {
+	h := sha1.New()
+	_, _ = fmt.Fprintf(h, "blob %d\x00", len(content))
+	_, _ = h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
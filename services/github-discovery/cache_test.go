@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitBlobSHA(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "", want: "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"},
+		{content: "hello\n", want: "ce013625030ba8dba906f756967f9e9ca394464a"},
+	}
+	for _, tt := range tests {
+		if got := gitBlobSHA([]byte(tt.content)); got != tt.want {
+			t.Errorf("gitBlobSHA(%q) = %s, want %s", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestBlobCache(t *testing.T) {
+	disabled, err := NewBlobCache("")
+	if err != nil || disabled != nil {
+		t.Fatalf("NewBlobCache(\"\") = %v, %v; want no cache", disabled, err)
+	}
+	disabled.Put(blobSHA("hello\n"), []byte("hello\n"))
+	if _, ok := disabled.Get(blobSHA("hello\n")); ok {
+		t.Error("disabled cache returned content")
+	}
+
+	dir := t.TempDir()
+	cache, err := NewBlobCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha := blobSHA("hello\n")
+	cache.Put(sha, []byte("hello\n"))
+	cache.Put(blobSHA("other"), []byte("not other"))
+	cache.Put("ab", []byte("short"))
+
+	tests := []struct {
+		name string
+		sha  string
+		want string
+		ok   bool
+	}{
+		{name: "stored", sha: sha, want: "hello\n", ok: true},
+		{name: "content not matching its SHA is not stored", sha: blobSHA("other")},
+		{name: "unknown", sha: blobSHA("unknown")},
+		{name: "short SHA", sha: "ab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, ok := cache.Get(tt.sha)
+			if ok != tt.ok || string(content) != tt.want {
+				t.Errorf("Get(%s) = %q, %v; want %q, %v", tt.sha, content, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	// Entries are sharded like .git/objects
+	path := filepath.Join(dir, sha[:2], sha[2:])
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("entry not at %s: %v", path, err)
+	}
+
+	// A corrupted entry is a miss, and the next Put repairs it
+	if err := os.WriteFile(path, []byte("corrupted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(sha); ok {
+		t.Error("corrupted entry was returned")
+	}
+	cache.Put(sha, []byte("hello\n"))
+	if content, ok := cache.Get(sha); !ok || string(content) != "hello\n" {
+		t.Errorf("Get after repair = %q, %v", content, ok)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// Sources for issue, discussion, and release documents
+const (
+	SourceIssue      = "issue"
+	SourceDiscussion = "discussion"
+	SourceRelease    = "release"
+)
+
+// GetIssues returns issues (excluding pull requests) updated since the given
+// time as Markdown documents containing the title, body, and comments
+func (s *GitHubService) GetIssues(ctx context.Context, owner, repo string, since time.Time) ([]*models.FileChange, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:       "all",
+		Sort:        "updated",
+		Direction:   "asc",
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	repoFullName := fmt.Sprintf("%s/%s", owner, repo)
+	var docs []*models.FileChange
+	for {
+		issues, resp, err := s.client.Issues.ListByRepo(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, errors.External("GitHub", "failed to list issues", err)
+		}
+
+		for _, issue := range issues {
+			if issue.IsPullRequest() {
+				continue
+			}
+
+			comments, err := s.getIssueComments(ctx, owner, repo, issue.GetNumber())
+			if err != nil {
+				logger.Warning("Failed to get comments for %s#%d: %v", repoFullName, issue.GetNumber(), err)
+			}
+
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "# %s\n\n", issue.GetTitle())
+			fmt.Fprintf(&sb, "Issue #%d (%s) opened by %s\n\n", issue.GetNumber(), issue.GetState(), issue.GetUser().GetLogin())
+			sb.WriteString(issue.GetBody())
+			sb.WriteString("\n")
+			for _, comment := range comments {
+				fmt.Fprintf(&sb, "\n## Comment by %s (%s)\n\n%s\n",
+					comment.GetUser().GetLogin(), comment.GetCreatedAt().Format(time.RFC3339), comment.GetBody())
+			}
+
+			docs = append(docs, conversationDocument(repoFullName, SourceIssue, "issues", issue.GetNumber(),
+				issue.GetUpdatedAt().Time, !since.IsZero() && issue.GetCreatedAt().Before(since), sb.String()))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	logger.Info("Found %d updated issues in %s", len(docs), repoFullName)
+	return docs, nil
+}
+
+// getIssueComments fetches every comment on an issue
+func (s *GitHubService) getIssueComments(ctx context.Context, owner, repo string, number int) ([]*github.IssueComment, error) {
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+
+	var all []*github.IssueComment
+	for {
+		comments, resp, err := s.client.Issues.ListComments(ctx, owner, repo, number, opts)
+		if err != nil {
+			return all, err
+		}
+		all = append(all, comments...)
+
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// discussionsQuery pages through discussions newest-updated first
+const discussionsQuery = `query($owner: String!, $name: String!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    discussions(first: 50, after: $cursor, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        number
+        title
+        body
+        createdAt
+        updatedAt
+        author { login }
+        category { name }
+        comments(first: 100) {
+          nodes { body createdAt author { login } }
+        }
+      }
+    }
+  }
+}`
+
+type discussionsResponse struct {
+	Data struct {
+		Repository struct {
+			Discussions struct {
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+				Nodes []struct {
+					Number    int       `json:"number"`
+					Title     string    `json:"title"`
+					Body      string    `json:"body"`
+					CreatedAt time.Time `json:"createdAt"`
+					UpdatedAt time.Time `json:"updatedAt"`
+					Author    struct {
+						Login string `json:"login"`
+					} `json:"author"`
+					Category struct {
+						Name string `json:"name"`
+					} `json:"category"`
+					Comments struct {
+						Nodes []struct {
+							Body      string    `json:"body"`
+							CreatedAt time.Time `json:"createdAt"`
+							Author    struct {
+								Login string `json:"login"`
+							} `json:"author"`
+						} `json:"nodes"`
+					} `json:"comments"`
+				} `json:"nodes"`
+			} `json:"discussions"`
+		} `json:"repository"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// GetDiscussions returns GitHub Discussions updated since the given time.
+// Discussions are only exposed through the GraphQL API.
+func (s *GitHubService) GetDiscussions(ctx context.Context, owner, repo string, since time.Time) ([]*models.FileChange, error) {
+	repoFullName := fmt.Sprintf("%s/%s", owner, repo)
+	var docs []*models.FileChange
+	var cursor *string
+
+	// GraphQL calls share one endpoint, so attribute them explicitly
+	ctx = withRateRepo(ctx, repoFullName)
+
+	for {
+		req, err := s.client.NewRequest("POST", "graphql", map[string]interface{}{
+			"query": discussionsQuery,
+			"variables": map[string]interface{}{
+				"owner":  owner,
+				"name":   repo,
+				"cursor": cursor,
+			},
+		})
+		if err != nil {
+			return nil, errors.Internal("failed to build discussions query", err)
+		}
+
+		var out discussionsResponse
+		if _, err := s.client.Do(ctx, req, &out); err != nil {
+			return nil, errors.External("GitHub", "failed to query discussions", err)
+		}
+		if len(out.Errors) > 0 {
+			return nil, errors.External("GitHub", "discussions query failed: "+out.Errors[0].Message, nil)
+		}
+
+		page := out.Data.Repository.Discussions
+		reachedSince := false
+		for _, d := range page.Nodes {
+			if !since.IsZero() && d.UpdatedAt.Before(since) {
+				reachedSince = true
+				break
+			}
+
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "# %s\n\n", d.Title)
+			fmt.Fprintf(&sb, "Discussion #%d in %s started by %s\n\n", d.Number, d.Category.Name, d.Author.Login)
+			sb.WriteString(d.Body)
+			sb.WriteString("\n")
+			for _, c := range d.Comments.Nodes {
+				fmt.Fprintf(&sb, "\n## Comment by %s (%s)\n\n%s\n", c.Author.Login, c.CreatedAt.Format(time.RFC3339), c.Body)
+			}
+
+			docs = append(docs, conversationDocument(repoFullName, SourceDiscussion, "discussions", d.Number,
+				d.UpdatedAt, !since.IsZero() && d.CreatedAt.Before(since), sb.String()))
+		}
+
+		if reachedSince || !page.PageInfo.HasNextPage {
+			break
+		}
+		endCursor := page.PageInfo.EndCursor
+		cursor = &endCursor
+	}
+
+	logger.Info("Found %d updated discussions in %s", len(docs), repoFullName)
+	return docs, nil
+}
+
+// conversationDocument wraps an issue, discussion, or release as a Markdown file change
+func conversationDocument(repoFullName, source, dir string, number int, updatedAt time.Time, existed bool, content string) *models.FileChange {
+	changeType := "added"
+	if existed {
+		changeType = "modified"
+	}
+
+	return &models.FileChange{
+		Repository:   repoFullName,
+		FilePath:     fmt.Sprintf("%s/%d.md", dir, number),
+		Content:      content,
+		CommitSHA:    updatedAt.UTC().Format(time.RFC3339),
+		LastModified: updatedAt,
+		ChangeType:   changeType,
+		Size:         int64(len(content)),
+		Source:       source,
+	}
+}
+
+// GetReleases returns published releases (tag, name, notes) as Markdown
+// documents, skipping drafts and releases published before since
+func (s *GitHubService) GetReleases(ctx context.Context, owner, repo string, since time.Time) ([]*models.FileChange, error) {
+	opts := &github.ListOptions{PerPage: 100}
+
+	repoFullName := fmt.Sprintf("%s/%s", owner, repo)
+	var docs []*models.FileChange
+	for {
+		releases, resp, err := s.client.Repositories.ListReleases(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, errors.External("GitHub", "failed to list releases", err)
+		}
+
+		for _, release := range releases {
+			if release.GetDraft() {
+				continue
+			}
+			published := release.GetPublishedAt().Time
+			if !since.IsZero() && published.Before(since) {
+				continue
+			}
+
+			name := release.GetName()
+			if name == "" {
+				name = release.GetTagName()
+			}
+
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "# %s (%s)\n\n", name, release.GetTagName())
+			fmt.Fprintf(&sb, "Release %s of %s published %s by %s", release.GetTagName(), repoFullName,
+				published.Format("2006-01-02"), release.GetAuthor().GetLogin())
+			if release.GetPrerelease() {
+				sb.WriteString(" (pre-release)")
+			}
+			sb.WriteString("\n\n")
+			sb.WriteString(release.GetBody())
+			sb.WriteString("\n")
+
+			doc := conversationDocument(repoFullName, SourceRelease, "releases", 0, published, false, sb.String())
+			doc.FilePath = fmt.Sprintf("releases/%s.md", release.GetTagName())
+			docs = append(docs, doc)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	logger.Info("Found %d releases in %s", len(docs), repoFullName)
+	return docs, nil
+}
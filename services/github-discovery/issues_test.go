@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestGetIssues(t *testing.T) {
+	api := &githubAPI{routes: map[string]string{
+		"/repos/org/repo/issues": `[` +
+			`{"number":1,"title":"Crash on start","body":"It crashes.","state":"open","user":{"login":"alice"},` +
+			`"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-03-01T00:00:00Z"},` +
+			`{"number":2,"title":"Fix crash","state":"open","pull_request":{"url":"https://api.github.com/repos/org/repo/pulls/2"},` +
+			`"created_at":"2026-03-01T00:00:00Z","updated_at":"2026-03-01T00:00:00Z"}]`,
+		"/repos/org/repo/issues?page=2": `[` +
+			`{"number":3,"title":"Docs typo","body":"Typo.","state":"closed","user":{"login":"bob"},` +
+			`"created_at":"2026-02-15T00:00:00Z","updated_at":"2026-02-20T00:00:00Z"}]`,
+		"/repos/org/repo/issues/1/comments": `[{"body":"Same here.","user":{"login":"carol"},"created_at":"2026-01-02T00:00:00Z"}]`,
+		"/repos/org/repo/issues/3/comments": `[]`,
+	}}
+	s := newTestGitHub(t, api, nil)
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  map[string]string // file path -> change type
+	}{
+		{name: "first sync adds every issue", want: map[string]string{"issues/1.md": "added", "issues/3.md": "added"}},
+		{
+			name:  "issues opened before since are modified",
+			since: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			want:  map[string]string{"issues/1.md": "modified", "issues/3.md": "added"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := s.GetIssues(context.Background(), "org", "repo", tt.since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, doc := range docs {
+				got[doc.FilePath] = doc.ChangeType
+				if doc.Source != SourceIssue || doc.Repository != "org/repo" {
+					t.Errorf("%s is a %s document of %s", doc.FilePath, doc.Source, doc.Repository)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	docs, err := s.GetIssues(context.Background(), "org", "repo", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Crash on start\n\nIssue #1 (open) opened by alice\n\nIt crashes.\n\n## Comment by carol (2026-01-02T00:00:00Z)\n\nSame here.\n"
+	if docs[0].Content != want {
+		t.Errorf("content = %q, want %q", docs[0].Content, want)
+	}
+	if docs[0].CommitSHA != "2026-03-01T00:00:00Z" {
+		t.Errorf("commit = %q, want the update time", docs[0].CommitSHA)
+	}
+}
+
+// graphqlAPI answers discussion queries with one page per cursor and
+// records the variables of each query
+type graphqlAPI struct {
+	pages map[string]string // cursor ("" for the first page) -> response
+
+	mu      sync.Mutex
+	cursors []string
+}
+
+func (a *graphqlAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Variables struct {
+			Owner  string  `json:"owner"`
+			Name   string  `json:"name"`
+			Cursor *string `json:"cursor"`
+		} `json:"variables"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	if r.URL.Path != "/graphql" || json.Unmarshal(body, &req) != nil || req.Variables.Owner != "org" || req.Variables.Name != "repo" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	cursor := ""
+	if req.Variables.Cursor != nil {
+		cursor = *req.Variables.Cursor
+	}
+	a.mu.Lock()
+	a.cursors = append(a.cursors, cursor)
+	a.mu.Unlock()
+	_, _ = w.Write([]byte(a.pages[cursor]))
+}
+
+// discussionsPage builds a discussions response holding discussions
+// numbered from first, updated on the given days of January 2026
+func discussionsPage(next string, first int, days ...int) string {
+	nodes := make([]string, len(days))
+	for i, day := range days {
+		nodes[i] = fmt.Sprintf(`{"number":%d,"title":"Topic %d","body":"Body.","createdAt":"2026-01-01T00:00:00Z",`+
+			`"updatedAt":"2026-01-%02dT00:00:00Z","author":{"login":"alice"},"category":{"name":"Q&A"},`+
+			`"comments":{"nodes":[{"body":"Answer.","createdAt":"2026-01-02T00:00:00Z","author":{"login":"bob"}}]}}`,
+			first+i, first+i, day)
+	}
+	return fmt.Sprintf(`{"data":{"repository":{"discussions":{"pageInfo":{"hasNextPage":%v,"endCursor":%q},"nodes":[%s]}}}}`,
+		next != "", next, strings.Join(nodes, ","))
+}
+
+func TestGetDiscussions(t *testing.T) {
+	tests := []struct {
+		name        string
+		since       time.Time
+		want        map[string]string // file path -> change type
+		wantCursors []string
+	}{
+		{
+			name:        "every page",
+			want:        map[string]string{"discussions/1.md": "added", "discussions/2.md": "added", "discussions/3.md": "added"},
+			wantCursors: []string{"", "page2"},
+		},
+		{
+			name:        "stops at the first discussion older than since",
+			since:       time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC),
+			want:        map[string]string{"discussions/1.md": "modified"},
+			wantCursors: []string{""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &graphqlAPI{pages: map[string]string{
+				"":      discussionsPage("page2", 1, 20, 10),
+				"page2": discussionsPage("", 3, 5),
+			}}
+			s := newTestGitHub(t, api, nil)
+
+			docs, err := s.GetDiscussions(context.Background(), "org", "repo", tt.since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, doc := range docs {
+				got[doc.FilePath] = doc.ChangeType
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("discussions = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(api.cursors, tt.wantCursors) {
+				t.Errorf("queried cursors %q, want %q", api.cursors, tt.wantCursors)
+			}
+
+			want := "# Topic 1\n\nDiscussion #1 in Q&A started by alice\n\nBody.\n\n## Comment by bob (2026-01-02T00:00:00Z)\n\nAnswer.\n"
+			if docs[0].Content != want || docs[0].Source != SourceDiscussion {
+				t.Errorf("first discussion = %s %q, want %q", docs[0].Source, docs[0].Content, want)
+			}
+			// GraphQL calls are attributed to the repository they query
+			if usage := s.RateUsage(); usage.Repositories["org/repo"] != len(tt.wantCursors) {
+				t.Errorf("usage = %v, want %d calls for org/repo", usage.Repositories, len(tt.wantCursors))
+			}
+		})
+	}
+}
+
+func TestGetDiscussionsErrors(t *testing.T) {
+	api := &graphqlAPI{pages: map[string]string{"": `{"errors":[{"message":"Could not resolve to a Repository"}]}`}}
+	s := newTestGitHub(t, api, nil)
+
+	if _, err := s.GetDiscussions(context.Background(), "org", "repo", time.Time{}); err == nil ||
+		!strings.Contains(err.Error(), "Could not resolve to a Repository") {
+		t.Errorf("GetDiscussions() = %v, want the query error", err)
+	}
+}
+
+func TestGetReleases(t *testing.T) {
+	api := &githubAPI{routes: map[string]string{
+		"/repos/org/repo/releases": `[` +
+			`{"tag_name":"v2.0.0-rc1","name":"","body":"Try it.","prerelease":true,"published_at":"2026-03-01T00:00:00Z","author":{"login":"alice"}},` +
+			`{"tag_name":"v1.1.0","name":"Draft","draft":true,"published_at":"2026-02-01T00:00:00Z"}]`,
+		"/repos/org/repo/releases?page=2": `[` +
+			`{"tag_name":"v1.0.0","name":"First","body":"Hello.","published_at":"2026-01-01T00:00:00Z","author":{"login":"bob"}}]`,
+	}}
+	s := newTestGitHub(t, api, nil)
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []string
+	}{
+		{
+			name: "published releases",
+			want: []string{
+				"releases/v2.0.0-rc1.md added # v2.0.0-rc1 (v2.0.0-rc1)\n\nRelease v2.0.0-rc1 of org/repo published 2026-03-01 by alice (pre-release)\n\nTry it.\n",
+				"releases/v1.0.0.md added # First (v1.0.0)\n\nRelease v1.0.0 of org/repo published 2026-01-01 by bob\n\nHello.\n",
+			},
+		},
+		{
+			name:  "published before since are skipped",
+			since: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+			want: []string{
+				"releases/v2.0.0-rc1.md added # v2.0.0-rc1 (v2.0.0-rc1)\n\nRelease v2.0.0-rc1 of org/repo published 2026-03-01 by alice (pre-release)\n\nTry it.\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := s.GetReleases(context.Background(), "org", "repo", tt.since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describeChanges(docs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("releases = %q, want %q", got, tt.want)
+			}
+			for _, doc := range docs {
+				if doc.Source != SourceRelease {
+					t.Errorf("%s source = %q", doc.FilePath, doc.Source)
+				}
+			}
+		})
+	}
+}
+
+func TestConversationDocument(t *testing.T) {
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	tests := []struct {
+		name    string
+		existed bool
+		want    models.FileChange
+	}{
+		{
+			name: "new",
+			want: models.FileChange{Repository: "org/repo", FilePath: "issues/4.md", Content: "text", CommitSHA: "2026-01-02T02:04:05Z",
+				LastModified: updated, ChangeType: "added", Size: 4, Source: SourceIssue},
+		},
+		{
+			name:    "existed before the last sync",
+			existed: true,
+			want: models.FileChange{Repository: "org/repo", FilePath: "issues/4.md", Content: "text", CommitSHA: "2026-01-02T02:04:05Z",
+				LastModified: updated, ChangeType: "modified", Size: 4, Source: SourceIssue},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := conversationDocument("org/repo", SourceIssue, "issues", 4, updated, tt.existed, "text")
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("conversationDocument() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleConversations(t *testing.T) {
+	api := &githubAPI{routes: map[string]string{
+		"/repos/org/repo/issues":            `[{"number":1,"title":"Bug","state":"open","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}]`,
+		"/repos/org/repo/releases":          `[{"tag_name":"v1","published_at":"2026-01-01T00:00:00Z"}]`,
+		"/repos/org/repo/issues/1/comments": `[]`,
+	}}
+	d := &DiscoveryServer{client: newTestGitHub(t, api, nil)}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		want    []string // file paths
+	}{
+		{name: "issues", handler: d.handleIssues, target: "/issues?repo=org/repo", want: []string{"issues/1.md"}},
+		{name: "issues since", handler: d.handleIssues, target: "/issues?repo=org/repo&since=2026-01-01T00:00:00Z", want: []string{"issues/1.md"}},
+		{name: "releases", handler: d.handleReleases, target: "/releases?repo=org/repo", want: []string{"releases/v1.md"}},
+		{name: "releases since", handler: d.handleReleases, target: "/releases?repo=org/repo&since=2026-02-01T00:00:00Z", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			var docs []*models.FileChange
+			if err := json.Unmarshal(rec.Body.Bytes(), &docs); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, doc := range docs {
+				got = append(got, doc.FilePath)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("documents = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,635 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// mtimePrefix marks sync pointers for directories without git metadata,
+// where the "commit SHA" is the newest file modification time
+const mtimePrefix = "mtime:"
+
+// LocalRepositoryService implements interfaces.RepositoryClient for directories on disk
+type LocalRepositoryService struct {
+	owner    string
+	roots    map[string]string // repository name -> absolute directory
+	manifest *fileManifest     // file lists per mtime pointer, nil disables deletion tracking
+}
+
+// NewLocalRepositoryService creates a provider serving each directory as a repository.
+// stateDir records file lists for directories without git metadata so deletions
+// can be reported; empty disables that.
+func NewLocalRepositoryService(owner string, paths []string, stateDir string) (*LocalRepositoryService, error) {
+	roots := make(map[string]string, len(paths))
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", abs, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", abs)
+		}
+
+		name := filepath.Base(abs)
+		if _, exists := roots[name]; exists {
+			return nil, fmt.Errorf("duplicate local repository name '%s'", name)
+		}
+		roots[name] = abs
+	}
+
+	manifest, err := newFileManifest(stateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalRepositoryService{owner: owner, roots: roots, manifest: manifest}, nil
+}
+
+// ListRepositories returns the configured directories matching the keyword
+func (s *LocalRepositoryService) ListRepositories(ctx context.Context, org, keyword string) ([]*models.Repository, error) {
+	names := make([]string, 0, len(s.roots))
+	for name := range s.roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var repos []*models.Repository
+	for _, name := range names {
+		if keyword != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(keyword)) {
+			continue
+		}
+		repo, err := s.GetRepository(ctx, s.owner, name)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+
+	logger.Info("Found %d local repositories matching keyword '%s'", len(repos), keyword)
+	return repos, nil
+}
+
+// GetRepository describes a configured directory as a repository
+func (s *LocalRepositoryService) GetRepository(ctx context.Context, owner, name string) (*models.Repository, error) {
+	root, ok := s.roots[name]
+	if !ok {
+		return nil, errors.NotFound(fmt.Sprintf("local repository %s", name))
+	}
+
+	branch := "local"
+	if isGitDir(root) {
+		if out, err := runGit(ctx, root, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+			branch = out
+		}
+	}
+
+	latest, err := s.GetLatestCommitSHA(ctx, s.owner, name, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, errors.Internal("failed to stat local repository", err)
+	}
+
+	return &models.Repository{
+		Name:          name,
+		FullName:      fmt.Sprintf("%s/%s", s.owner, name),
+		Owner:         s.owner,
+		DefaultBranch: branch,
+		LastCommit:    latest,
+		UpdatedAt:     info.ModTime(),
+		Private:       true,
+	}, nil
+}
+
+// GetChangedFiles detects files that changed since the last sync pointer,
+// using git history when available and file modification times otherwise
+func (s *LocalRepositoryService) GetChangedFiles(ctx context.Context, repo *models.Repository, lastCommitSHA string) ([]*models.FileChange, error) {
+	root, ok := s.roots[repo.Name]
+	if !ok {
+		return nil, errors.NotFound(fmt.Sprintf("local repository %s", repo.Name))
+	}
+
+	latest, err := s.GetLatestCommitSHA(ctx, repo.Owner, repo.Name, repo.DefaultBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isGitDir(root) {
+		return s.mtimeChangedFiles(repo, root, lastCommitSHA, latest)
+	}
+
+	if lastCommitSHA == "" || strings.HasPrefix(lastCommitSHA, mtimePrefix) {
+		return s.walkFiles(repo, root, latest, time.Time{})
+	}
+	if err := validateRef(lastCommitSHA); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.gitChangedFiles(ctx, repo, root, lastCommitSHA, latest)
+	if err != nil {
+		// Unknown commits (e.g. after a force-push) fall back to a full scan
+		logger.Warning("Failed to diff %s against %s, rescanning all files: %v", repo.FullName, lastCommitSHA, err)
+		return s.walkFiles(repo, root, latest, time.Time{})
+	}
+
+	logger.Info("Found %d changed files in %s", len(changes), repo.FullName)
+	return changes, nil
+}
+
+// mtimeChangedFiles lists files modified after an mtime pointer, plus files
+// recorded at that pointer which no longer exist
+func (s *LocalRepositoryService) mtimeChangedFiles(repo *models.Repository, root, lastCommitSHA, latest string) ([]*models.FileChange, error) {
+	var since time.Time
+	switch {
+	case lastCommitSHA == "":
+	case strings.HasPrefix(lastCommitSHA, mtimePrefix):
+		nanos, err := strconv.ParseInt(strings.TrimPrefix(lastCommitSHA, mtimePrefix), 10, 64)
+		if err != nil {
+			return nil, errors.Validation(fmt.Sprintf("invalid sync pointer '%s'", lastCommitSHA))
+		}
+		since = time.Unix(0, nanos)
+	default:
+		logger.Warning("Sync pointer %s is a commit but %s has no git metadata, rescanning all files", lastCommitSHA, root)
+		lastCommitSHA = ""
+	}
+
+	files, err := s.walkFiles(repo, root, latest, since)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, s.trackDeletions(repo, root, lastCommitSHA, latest)...), nil
+}
+
+// trackDeletions records the current file list under the latest pointer and
+// reports files from the previous pointer's list that are gone
+func (s *LocalRepositoryService) trackDeletions(repo *models.Repository, root, previous, latest string) []*models.FileChange {
+	if s.manifest == nil {
+		return nil
+	}
+
+	current, err := listFiles(root)
+	if err != nil {
+		logger.Warning("Failed to list files in %s, deletions will not be reported: %v", repo.FullName, err)
+		return nil
+	}
+
+	var before []string
+	recorded := false
+	if previous != "" {
+		if before, recorded = s.manifest.Files(repo.Name, previous); !recorded {
+			logger.Warning("No file list recorded for %s at %s, deletions since then will not be reported", repo.FullName, previous)
+		}
+	}
+
+	if err := s.manifest.Record(repo.Name, latest, current); err != nil {
+		logger.Warning("Failed to record file list for %s: %v", repo.FullName, err)
+	}
+	if !recorded {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(current))
+	for _, path := range current {
+		seen[path] = true
+	}
+	known := make(map[string]string, len(before))
+	for _, path := range before {
+		known[path] = ""
+	}
+
+	removed := removedFiles(repo, latest, known, seen)
+	if len(removed) > 0 {
+		logger.Info("Found %d removed files in %s", len(removed), repo.FullName)
+	}
+	return removed
+}
+
+// listFiles returns the slash-separated paths of every regular file under root
+func listFiles(root string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	return paths, err
+}
+
+// gitChangedFiles lists files changed between two commits via git diff,
+// reading content as committed at the target commit
+func (s *LocalRepositoryService) gitChangedFiles(ctx context.Context, repo *models.Repository, root, from, to string) ([]*models.FileChange, error) {
+	fromSHA, err := resolveCommit(ctx, root, from)
+	if err != nil {
+		return nil, err
+	}
+	toSHA, err := resolveCommit(ctx, root, to)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := runGit(ctx, root, "diff", "--name-status", "-M", "--end-of-options", fromSHA, toSHA)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var changes []*models.FileChange
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+
+		status, path := fields[0], fields[1]
+		changeType := "modified"
+		switch status[0] {
+		case 'A':
+			changeType = "added"
+		case 'D':
+			changeType = "removed"
+		case 'C':
+			// Copies leave the source untouched, so only the destination is new
+			if len(fields) < 3 {
+				continue
+			}
+			path = fields[2]
+			changeType = "added"
+		case 'R':
+			// Renames drop the old path and add the new one
+			changes = append(changes, &models.FileChange{
+				Repository:   repo.FullName,
+				FilePath:     path,
+				CommitSHA:    to,
+				LastModified: now,
+				ChangeType:   "removed",
+			})
+			if len(fields) < 3 {
+				continue
+			}
+			path = fields[2]
+			changeType = "renamed"
+		}
+
+		change := &models.FileChange{
+			Repository:   repo.FullName,
+			FilePath:     path,
+			CommitSHA:    to,
+			LastModified: now,
+			ChangeType:   changeType,
+		}
+
+		if changeType != "removed" {
+			content, err := gitShow(ctx, root, toSHA, path)
+			if err != nil {
+				logger.Warning("Failed to get content for %s: %v", path, err)
+				continue
+			}
+			change.SetContent(content)
+			change.Size = int64(len(content))
+			change.BlobSHA = gitBlobSHA(content)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// GetChangedBlobs hashes every file in the working tree and returns those whose
+// blob SHA differs from the known one, plus known files that no longer exist.
+// Files the rules reject are left out.
+func (s *LocalRepositoryService) GetChangedBlobs(ctx context.Context, repo *models.Repository, known map[string]string, rules filter.Rules) ([]*models.FileChange, error) {
+	root, ok := s.roots[repo.Name]
+	if !ok {
+		return nil, errors.NotFound(fmt.Sprintf("local repository %s", repo.Name))
+	}
+
+	latest, err := s.GetLatestCommitSHA(ctx, repo.Owner, repo.Name, repo.DefaultBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.walkFiles(repo, root, latest, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*models.FileChange
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		seen[file.FilePath] = true
+		if !indexable(file.FilePath, rules) {
+			continue
+		}
+
+		previous, existed := known[file.FilePath]
+		if existed && previous == file.BlobSHA {
+			continue
+		}
+		if existed {
+			file.ChangeType = "modified"
+		}
+		changes = append(changes, file)
+	}
+	changes = append(changes, removedFiles(repo, latest, known, seen)...)
+
+	logger.Info("Found %d changed blobs in %s (%d known files)", len(changes), repo.FullName, len(known))
+	return changes, nil
+}
+
+// walkFiles returns every file modified after since (all files for the zero time)
+func (s *LocalRepositoryService) walkFiles(repo *models.Repository, root, latest string, since time.Time) ([]*models.FileChange, error) {
+	var files []*models.FileChange
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if !info.ModTime().After(since) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			logger.Warning("Failed to get content for %s: %v", rel, err)
+			return nil
+		}
+
+		changeType := "added"
+		if !since.IsZero() {
+			changeType = "modified"
+		}
+
+		change := &models.FileChange{
+			Repository:   repo.FullName,
+			FilePath:     filepath.ToSlash(rel),
+			CommitSHA:    latest,
+			LastModified: info.ModTime(),
+			ChangeType:   changeType,
+			Size:         info.Size(),
+			BlobSHA:      gitBlobSHA(content),
+		}
+		change.SetContent(content)
+		files = append(files, change)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Internal("failed to walk local repository", err)
+	}
+
+	logger.Info("Found %d files in %s", len(files), repo.FullName)
+	return files, nil
+}
+
+// GetFileContent reads a file at ref for git checkouts, or from the working
+// tree when ref is empty, an mtime pointer, or the directory has no git metadata
+func (s *LocalRepositoryService) GetFileContent(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	root, ok := s.roots[repo]
+	if !ok {
+		return nil, errors.NotFound(fmt.Sprintf("local repository %s", repo))
+	}
+	if ref != "" && !strings.HasPrefix(ref, mtimePrefix) && isGitDir(root) {
+		sha, err := resolveCommit(ctx, root, ref)
+		if err != nil {
+			return nil, err
+		}
+		content, err := gitShow(ctx, root, sha, path)
+		if err != nil {
+			return nil, errors.Internal("failed to read committed file", err)
+		}
+		return content, nil
+	}
+	return s.readFile(root, path)
+}
+
+// readFile reads a repository-relative path, refusing paths that lead
+// outside the root once symlinks are resolved, into git metadata, or to
+// anything but a regular file
+func (s *LocalRepositoryService) readFile(root, path string) ([]byte, error) {
+	full := filepath.Join(root, filepath.FromSlash(path))
+	rel, ok := relativeToRoot(root, full)
+	if !ok {
+		return nil, errors.Validation(fmt.Sprintf("path %s escapes repository root", path))
+	}
+	if inGitDir(rel) {
+		return nil, errors.Validation(fmt.Sprintf("path %s is git metadata", path))
+	}
+
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, errors.Internal("failed to resolve local repository root", err)
+	}
+	resolved, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		return nil, errors.Internal("failed to read local file", err)
+	}
+	if rel, ok = relativeToRoot(resolvedRoot, resolved); !ok || inGitDir(rel) {
+		return nil, errors.Validation(fmt.Sprintf("path %s links outside the repository", path))
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, errors.Internal("failed to read local file", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errors.Validation(fmt.Sprintf("path %s is not a regular file", path))
+	}
+
+	content, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, errors.Internal("failed to read local file", err)
+	}
+	return content, nil
+}
+
+// relativeToRoot returns path relative to root, or false if it lies outside
+func relativeToRoot(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// inGitDir reports whether a root-relative path lies in a .git directory
+func inGitDir(rel string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if part == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLatestCommitSHA returns HEAD for git checkouts, or an mtime pointer otherwise
+func (s *LocalRepositoryService) GetLatestCommitSHA(ctx context.Context, owner, repo, branch string) (string, error) {
+	root, ok := s.roots[repo]
+	if !ok {
+		return "", errors.NotFound(fmt.Sprintf("local repository %s", repo))
+	}
+
+	if isGitDir(root) {
+		sha, err := runGit(ctx, root, "rev-parse", "HEAD")
+		if err != nil {
+			return "", errors.Internal("failed to read HEAD commit", err)
+		}
+		return sha, nil
+	}
+
+	// Directory mtimes count too: deleting a file only touches its parent
+	var newest time.Time
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return "", errors.Internal("failed to scan local repository", err)
+	}
+
+	return fmt.Sprintf("%s%d", mtimePrefix, newest.UnixNano()), nil
+}
+
+// Health checks that every configured directory is still readable
+func (s *LocalRepositoryService) Health(ctx context.Context) error {
+	for name, root := range s.roots {
+		if _, err := os.Stat(root); err != nil {
+			return fmt.Errorf("local repository %s unavailable: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// isGitDir reports whether a directory is a git checkout
+func isGitDir(root string) bool {
+	_, err := os.Stat(filepath.Join(root, ".git"))
+	return err == nil
+}
+
+// runGit runs a git command in dir and returns its trimmed stdout
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	return runGitEnv(ctx, dir, nil, args...)
+}
+
+// validateRef rejects refs git would parse as options, e.g. --output=<file>
+func validateRef(ref string) error {
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return errors.Validation(fmt.Sprintf("invalid git ref '%s'", ref))
+	}
+	return nil
+}
+
+// resolveCommit resolves a caller-supplied ref to the full SHA of the commit
+// it names, so only verified object names reach other git commands
+func resolveCommit(ctx context.Context, dir, ref string) (string, error) {
+	if err := validateRef(ref); err != nil {
+		return "", err
+	}
+	sha, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return "", errors.Validation(fmt.Sprintf("unknown git ref '%s'", ref))
+	}
+	return sha, nil
+}
+
+// gitShow returns a file's content as committed at rev, byte for byte; rev
+// should come from resolveCommit
+func gitShow(ctx context.Context, dir, rev, path string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", "show", "--end-of-options", rev+":"+path)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git show %s:%s: %w: %s", rev, path, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// runGitEnv runs a git command with extra environment variables
+func runGitEnv(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxManifestSnapshots bounds how many sync pointers keep a file list, so a
+// sync that failed before saving its pointer can still be diffed next time
+const maxManifestSnapshots = 5
+
+// fileManifest records the files present in plain directories at each mtime
+// sync pointer. Modification times cannot reveal deletions, so the provider
+// compares the current listing against the one recorded for the last pointer.
+type fileManifest struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// manifestFile is the on-disk layout: sync pointer -> sorted file paths
+type manifestFile struct {
+	Snapshots map[string][]string `json:"snapshots"`
+}
+
+// newFileManifest creates a manifest store rooted at dir, or returns nil when dir is empty
+func newFileManifest(dir string) (*fileManifest, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create local state directory: %w", err)
+	}
+	return &fileManifest{dir: dir}, nil
+}
+
+// Files returns the paths recorded for a repository at a sync pointer
+func (m *fileManifest) Files(repo, pointer string) ([]string, bool) {
+	if m == nil {
+		return nil, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	paths, ok := m.load(repo).Snapshots[pointer]
+	return paths, ok
+}
+
+// Record stores the paths present at a sync pointer, dropping the oldest snapshots
+func (m *fileManifest) Record(repo, pointer string, paths []string) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	manifest := m.load(repo)
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	manifest.Snapshots[pointer] = sorted
+
+	if len(manifest.Snapshots) > maxManifestSnapshots {
+		pointers := make([]string, 0, len(manifest.Snapshots))
+		for p := range manifest.Snapshots {
+			pointers = append(pointers, p)
+		}
+		sort.Slice(pointers, func(i, j int) bool {
+			return pointerNanos(pointers[i]) < pointerNanos(pointers[j])
+		})
+		for _, p := range pointers[:len(pointers)-maxManifestSnapshots] {
+			delete(manifest.Snapshots, p)
+		}
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	// Write through a temp file so a crash never leaves a truncated manifest
+	path := m.path(repo)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write file manifest: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write file manifest: %w", err)
+	}
+	return nil
+}
+
+// load reads a repository's manifest, treating missing or corrupted files as empty
+func (m *fileManifest) load(repo string) *manifestFile {
+	manifest := &manifestFile{}
+	if data, err := os.ReadFile(m.path(repo)); err == nil {
+		_ = json.Unmarshal(data, manifest)
+	}
+	if manifest.Snapshots == nil {
+		manifest.Snapshots = make(map[string][]string)
+	}
+	return manifest
+}
+
+func (m *fileManifest) path(repo string) string {
+	return filepath.Join(m.dir, repo+".json")
+}
+
+// pointerNanos orders mtime pointers; unparseable ones sort first
+func pointerNanos(pointer string) int64 {
+	nanos, err := strconv.ParseInt(strings.TrimPrefix(pointer, mtimePrefix), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return nanos
+}
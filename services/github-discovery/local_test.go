@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
+)
+
+// newGitRepo creates a repository with one commit per entry in commits, each
+// writing the given files, and returns its path and commit SHAs in order
+func newGitRepo(t *testing.T, commits ...map[string]string) (string, []string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := filepath.Join(t.TempDir(), "repo")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com"}
+	git := func(args ...string) string {
+		out, err := runGitEnv(context.Background(), root, env, args...)
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return out
+	}
+
+	git("init", "-q", "-b", "main")
+	var shas []string
+	for _, files := range commits {
+		for path, content := range files {
+			full := filepath.Join(root, filepath.FromSlash(path))
+			if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", "update")
+		shas = append(shas, git("rev-parse", "HEAD"))
+	}
+	return root, shas
+}
+
+func isValidation(err error) bool {
+	var appErr *errors.AppError
+	return stderrors.As(err, &appErr) && appErr.Type == errors.ErrTypeValidation
+}
+
+func TestLocalRefsAreNotOptions(t *testing.T) {
+	root, shas := newGitRepo(t,
+		map[string]string{"README.md": "v1"},
+		map[string]string{"README.md": "v2", "docs/guide.md": "guide"},
+	)
+	s, err := NewLocalRepositoryService("local", []string{root}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	repo, err := s.GetRepository(ctx, "local", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An option-like ref must never reach git, which would write this file
+	out := filepath.Join(t.TempDir(), "injected")
+	for _, ref := range []string{"--output=" + out, "-p", "--no-index"} {
+		if _, err := s.GetFileContent(ctx, "local", "repo", "README.md", ref); !isValidation(err) {
+			t.Errorf("GetFileContent(%q) = %v, want a validation error", ref, err)
+		}
+		if _, err := s.GetChangedFiles(ctx, repo, ref); !isValidation(err) {
+			t.Errorf("GetChangedFiles(%q) = %v, want a validation error", ref, err)
+		}
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("injected ref created %s", out)
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{name: "full sha", ref: shas[0], want: "v1"},
+		{name: "branch", ref: "main", want: "v2"},
+		{name: "relative ref", ref: "HEAD~1", want: "v1"},
+		{name: "unknown ref", ref: "no-such-branch", wantErr: true},
+		{name: "tree object", ref: shas[1] + "^{tree}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := s.GetFileContent(ctx, "local", "repo", "README.md", tt.ref)
+			if tt.wantErr {
+				if !isValidation(err) {
+					t.Errorf("err = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("content = %q, want %q", content, tt.want)
+			}
+		})
+	}
+
+	changes, err := s.GetChangedFiles(ctx, repo, shas[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, change := range changes {
+		got[change.FilePath] = change.ChangeType
+	}
+	if len(got) != 2 || got["README.md"] != "modified" || got["docs/guide.md"] != "added" {
+		t.Errorf("changes = %v, want README.md modified and docs/guide.md added", got)
+	}
+	for _, change := range changes {
+		if change.CommitSHA != shas[1] {
+			t.Errorf("%s at %s, want %s", change.FilePath, change.CommitSHA, shas[1])
+		}
+	}
+}
+
+func TestLocalGitChanges(t *testing.T) {
+	root, shas := newGitRepo(t, map[string]string{
+		"docs/guide.md": "a guide long enough for git to detect its rename\n",
+		"docs/old.md":   "old",
+		"README.md":     "v1",
+	})
+	env := []string{"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com"}
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"mv", "docs/guide.md", "docs/manual.md"},
+		{"rm", "-q", "docs/old.md"},
+		{"commit", "-q", "-m", "reorganize"},
+	} {
+		if _, err := runGitEnv(ctx, root, env, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewLocalRepositoryService("local", []string{root}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := s.GetRepository(ctx, "local", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := s.GetChangedFiles(ctx, repo, shas[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, change := range changes {
+		got[change.FilePath] = change.ChangeType
+		if change.ChangeType != "removed" && change.BlobSHA != gitBlobSHA([]byte(change.Content)) {
+			t.Errorf("%s has blob %s, not that of its content", change.FilePath, change.BlobSHA)
+		}
+	}
+	// A rename removes the old path
+	want := map[string]string{"docs/guide.md": "removed", "docs/manual.md": "renamed", "docs/old.md": "removed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+
+	// An unknown commit, e.g. after a force-push, rescans every file
+	changes, err = s.GetChangedFiles(ctx, repo, "0123456789abcdef0123456789abcdef01234567")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Errorf("rescan found %d files, want 2", len(changes))
+	}
+}
+
+func TestLocalGetChangedBlobs(t *testing.T) {
+	root, _ := newGitRepo(t, map[string]string{"README.md": "v1", "docs/a.md": "a"})
+	s, err := NewLocalRepositoryService("local", []string{root}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	repo, err := s.GetRepository(ctx, "local", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		known map[string]string
+		rules filter.Rules
+		want  map[string]string // file path -> change type
+	}{
+		{name: "first sync", want: map[string]string{"README.md": "added", "docs/a.md": "added"}},
+		{
+			name:  "excluded files skipped",
+			known: map[string]string{"README.md": gitBlobSHA([]byte("v1"))},
+			rules: filter.Rules{ExcludePatterns: []string{"docs"}},
+			want:  map[string]string{},
+		},
+		{
+			name:  "changed and removed",
+			known: map[string]string{"README.md": gitBlobSHA([]byte("v0")), "docs/a.md": gitBlobSHA([]byte("a")), "docs/gone.md": "gone"},
+			want:  map[string]string{"README.md": "modified", "docs/gone.md": "removed"},
+		},
+		{
+			name:  "nothing changed",
+			known: map[string]string{"README.md": gitBlobSHA([]byte("v1")), "docs/a.md": gitBlobSHA([]byte("a"))},
+			want:  map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := s.GetChangedBlobs(ctx, repo, tt.known, tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, change := range changes {
+				got[change.FilePath] = change.ChangeType
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalMtimeChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		stateDir bool
+		want     map[string]string // file path -> change type
+	}{
+		{name: "deletions tracked", stateDir: true, want: map[string]string{"new.md": "modified", "old.md": "removed"}},
+		{name: "deletions not tracked without state", want: map[string]string{"new.md": "modified"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Times ahead of now keep directory mtimes out of the way
+			root := filepath.Join(t.TempDir(), "notes")
+			synced := time.Now().Add(time.Hour).Truncate(time.Second)
+			for _, name := range []string{"keep.md", "old.md"} {
+				writeAt(t, filepath.Join(root, name), synced)
+			}
+			stateDir := ""
+			if tt.stateDir {
+				stateDir = t.TempDir()
+			}
+			s, err := NewLocalRepositoryService("local", []string{root}, stateDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			repo, err := s.GetRepository(ctx, "local", "notes")
+			if err != nil {
+				t.Fatal(err)
+			}
+			changes, err := s.GetChangedFiles(ctx, repo, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changes) != 2 || !strings.HasPrefix(changes[0].CommitSHA, mtimePrefix) {
+				t.Fatalf("first sync = %v, want both files at an mtime pointer", describeChanges(changes))
+			}
+			pointer := changes[0].CommitSHA
+
+			if err := os.Remove(filepath.Join(root, "old.md")); err != nil {
+				t.Fatal(err)
+			}
+			writeAt(t, filepath.Join(root, "new.md"), synced.Add(time.Hour))
+
+			changes, err = s.GetChangedFiles(ctx, repo, pointer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, change := range changes {
+				got[change.FilePath] = change.ChangeType
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	root := filepath.Join(t.TempDir(), "notes")
+	writeAt(t, filepath.Join(root, "keep.md"), time.Now())
+	s, err := NewLocalRepositoryService("local", []string{root}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	repo, err := s.GetRepository(ctx, "local", "notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetChangedFiles(ctx, repo, mtimePrefix+"soon"); !isValidation(err) {
+		t.Errorf("GetChangedFiles(bad pointer) = %v, want a validation error", err)
+	}
+}
+
+// writeAt writes a file, creating its directory, and sets its modification time
+func writeAt(t *testing.T, path string, modified time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalReadFileStaysInRoot(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "repo")
+	writeAt(t, filepath.Join(root, "README.md"), time.Now())
+	writeAt(t, filepath.Join(parent, "secret"), time.Now())
+
+	writeAt(t, filepath.Join(root, ".git", "config"), time.Now())
+	for link, target := range map[string]string{
+		"leak.md":      filepath.Join(parent, "secret"),
+		"outside":      parent,
+		"git-config":   filepath.Join(root, ".git", "config"),
+		"readme-again": filepath.Join(root, "README.md"),
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewLocalRepositoryService("local", []string{root}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		"../secret", "docs/../../secret", "leak.md", "outside/secret", "git-config", ".git/config", "docs/../.git/config", "docs",
+	} {
+		if _, err := s.GetFileContent(context.Background(), "local", "repo", path, ""); !isValidation(err) {
+			t.Errorf("GetFileContent(%q) = %v, want a validation error", path, err)
+		}
+	}
+	if content, err := s.GetFileContent(context.Background(), "local", "repo", "README.md", ""); err != nil || string(content) != "README.md" {
+		t.Errorf("GetFileContent(README.md) = %q, %v", content, err)
+	}
+	// Links that stay inside the repository are followed
+	if content, err := s.GetFileContent(context.Background(), "local", "repo", "readme-again", ""); err != nil || string(content) != "README.md" {
+		t.Errorf("GetFileContent(readme-again) = %q, %v", content, err)
+	}
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -14,6 +16,8 @@ import (
 	"github.com/google/go-github/v57/github"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/interfaces"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
 	"golang.org/x/oauth2"
@@ -22,15 +26,26 @@ import (
 // GitHubService implements interfaces.RepositoryClient
 type GitHubService struct {
 	client *github.Client
+	cache  *BlobCache
+	usage  *RateCounter
+	token  string
+	webURL string // wikis are cloned from here
 }
 
-// NewGitHubService creates a new GitHub service
-func NewGitHubService(token string) *GitHubService {
+// NewGitHubService creates a new GitHub service; cache may be nil
+func NewGitHubService(token string, cache *BlobCache) *GitHubService {
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	tc := oauth2.NewClient(context.Background(), ts)
+	usage := NewRateCounter(tc.Transport)
+	tc.Transport = usage
 	client := github.NewClient(tc)
 
-	return &GitHubService{client: client}
+	return &GitHubService{client: client, cache: cache, usage: usage, token: token, webURL: "https://github.com"}
+}
+
+// RateUsage returns GitHub API calls per repository since the service started
+func (s *GitHubService) RateUsage() *models.RateUsage {
+	return s.usage.Usage()
 }
 
 // ListRepositories finds all repositories matching the filter
@@ -72,6 +87,17 @@ func (s *GitHubService) ListRepositories(ctx context.Context, org, keyword strin
 
 // GetChangedFiles detects files that changed since last sync
 func (s *GitHubService) GetChangedFiles(ctx context.Context, repo *models.Repository, lastCommitSHA string) ([]*models.FileChange, error) {
+	return s.getChangedFiles(ctx, repo, lastCommitSHA, true)
+}
+
+// GetChangedPaths detects changed files like GetChangedFiles but returns only
+// paths and blob SHAs, leaving content to be fetched on demand via /content
+func (s *GitHubService) GetChangedPaths(ctx context.Context, repo *models.Repository, lastCommitSHA string) ([]*models.FileChange, error) {
+	return s.getChangedFiles(ctx, repo, lastCommitSHA, false)
+}
+
+// getChangedFiles detects changed files, optionally downloading their content
+func (s *GitHubService) getChangedFiles(ctx context.Context, repo *models.Repository, lastCommitSHA string, withContent bool) ([]*models.FileChange, error) {
 	var changes []*models.FileChange
 
 	// Get latest commit
@@ -82,7 +108,7 @@ func (s *GitHubService) GetChangedFiles(ctx context.Context, repo *models.Reposi
 
 	// If no last commit, fetch all files
 	if lastCommitSHA == "" {
-		return s.getAllFiles(ctx, repo)
+		return s.getAllFiles(ctx, repo, withContent)
 	}
 
 	// Compare commits
@@ -107,34 +133,40 @@ func (s *GitHubService) GetChangedFiles(ctx context.Context, repo *models.Reposi
 				LastModified: latestCommit.Commit.Author.Date.Time,
 				ChangeType:   changeType,
 				Size:         int64(*file.Changes),
+				BlobSHA:      file.GetSHA(),
 			})
 			continue
 		}
 
 		// Fetch file content for added/modified files
-		content, err := s.GetFileContent(ctx, repo.Owner, repo.Name, *file.Filename, repo.DefaultBranch)
-		if err != nil {
-			logger.Warning("Failed to get content for %s: %v", *file.Filename, err)
-			continue
+		var content []byte
+		if withContent {
+			content, err = s.getBlobContent(ctx, repo.Owner, repo.Name, *file.Filename, repo.DefaultBranch, file.GetSHA())
+			if err != nil {
+				logger.Warning("Failed to get content for %s: %v", *file.Filename, err)
+				continue
+			}
 		}
 
-		changes = append(changes, &models.FileChange{
+		change := &models.FileChange{
 			Repository:   repo.FullName,
 			FilePath:     *file.Filename,
-			Content:      string(content),
 			CommitSHA:    *latestCommit.SHA,
 			LastModified: latestCommit.Commit.Author.Date.Time,
 			ChangeType:   changeType,
 			Size:         int64(*file.Changes),
-		})
+			BlobSHA:      file.GetSHA(),
+		}
+		change.SetContent(content)
+		changes = append(changes, change)
 	}
 
 	logger.Info("Found %d changed files in %s", len(changes), repo.FullName)
 	return changes, nil
 }
 
-// getAllFiles fetches all files from repository
-func (s *GitHubService) getAllFiles(ctx context.Context, repo *models.Repository) ([]*models.FileChange, error) {
+// getAllFiles fetches all files from repository, optionally with content
+func (s *GitHubService) getAllFiles(ctx context.Context, repo *models.Repository, withContent bool) ([]*models.FileChange, error) {
 	var files []*models.FileChange
 
 	tree, _, err := s.client.Git.GetTree(ctx, repo.Owner, repo.Name, repo.DefaultBranch, true)
@@ -150,21 +182,26 @@ func (s *GitHubService) getAllFiles(ctx context.Context, repo *models.Repository
 	for _, entry := range tree.Entries {
 		if *entry.Type == "blob" {
 			// Fetch file content
-			content, err := s.GetFileContent(ctx, repo.Owner, repo.Name, *entry.Path, repo.DefaultBranch)
-			if err != nil {
-				logger.Warning("Failed to get content for %s: %v", *entry.Path, err)
-				continue
+			var content []byte
+			if withContent {
+				content, err = s.getBlobContent(ctx, repo.Owner, repo.Name, *entry.Path, repo.DefaultBranch, entry.GetSHA())
+				if err != nil {
+					logger.Warning("Failed to get content for %s: %v", *entry.Path, err)
+					continue
+				}
 			}
 
-			files = append(files, &models.FileChange{
+			change := &models.FileChange{
 				Repository:   repo.FullName,
 				FilePath:     *entry.Path,
-				Content:      string(content),
 				CommitSHA:    latestSHA,
 				LastModified: time.Now(),
 				ChangeType:   "added",
 				Size:         int64(*entry.Size),
-			})
+				BlobSHA:      entry.GetSHA(),
+			}
+			change.SetContent(content)
+			files = append(files, change)
 		}
 	}
 
@@ -172,6 +209,171 @@ func (s *GitHubService) getAllFiles(ctx context.Context, repo *models.Repository
 	return files, nil
 }
 
+// GetChangedBlobs diffs the full tree's blob SHAs against known per-file SHAs,
+// returning added/modified files with content and removed files without.
+// Unlike commit comparison this works without a last-commit pointer and
+// survives force-pushes that rewrite history. Files the rules reject are
+// skipped before their content is fetched.
+func (s *GitHubService) GetChangedBlobs(ctx context.Context, repo *models.Repository, known map[string]string, rules filter.Rules) ([]*models.FileChange, error) {
+	latestSHA, err := s.GetLatestCommitSHA(ctx, repo.Owner, repo.Name, repo.DefaultBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, _, err := s.client.Git.GetTree(ctx, repo.Owner, repo.Name, latestSHA, true)
+	if err != nil {
+		return nil, errors.External("GitHub", "failed to get repository tree", err)
+	}
+	if tree.GetTruncated() {
+		logger.Warning("Tree for %s was truncated by GitHub, blob diff may be incomplete", repo.FullName)
+	}
+
+	var changes []*models.FileChange
+	seen := make(map[string]bool, len(tree.Entries))
+	for _, entry := range tree.Entries {
+		if entry.GetType() != "blob" {
+			continue
+		}
+
+		path := entry.GetPath()
+		seen[path] = true
+		if !indexable(path, rules) {
+			continue
+		}
+
+		previous, existed := known[path]
+		if existed && previous == entry.GetSHA() {
+			continue
+		}
+
+		content, err := s.getBlobContent(ctx, repo.Owner, repo.Name, path, latestSHA, entry.GetSHA())
+		if err != nil {
+			logger.Warning("Failed to get content for %s: %v", path, err)
+			continue
+		}
+
+		changeType := "added"
+		if existed {
+			changeType = "modified"
+		}
+
+		change := &models.FileChange{
+			Repository:   repo.FullName,
+			FilePath:     path,
+			CommitSHA:    latestSHA,
+			LastModified: time.Now(),
+			ChangeType:   changeType,
+			Size:         int64(entry.GetSize()),
+			BlobSHA:      entry.GetSHA(),
+		}
+		change.SetContent(content)
+		changes = append(changes, change)
+	}
+
+	// Deletions can only be detected against a complete tree
+	if !tree.GetTruncated() {
+		changes = append(changes, removedFiles(repo, latestSHA, known, seen)...)
+	}
+
+	logger.Info("Found %d changed blobs in %s (%d known files)", len(changes), repo.FullName, len(known))
+	return changes, nil
+}
+
+// indexable reports whether the rules accept a path. Excluded files have no
+// recorded blob SHA, so without this every diff would report them as new.
+func indexable(path string, rules filter.Rules) bool {
+	return filter.Check(&models.FileChange{FilePath: path}, rules).Accepted()
+}
+
+// removedFiles reports known paths that are no longer present
+func removedFiles(repo *models.Repository, commitSHA string, known map[string]string, seen map[string]bool) []*models.FileChange {
+	var removed []*models.FileChange
+	for path, sha := range known {
+		if seen[path] {
+			continue
+		}
+		removed = append(removed, &models.FileChange{
+			Repository:   repo.FullName,
+			FilePath:     path,
+			CommitSHA:    commitSHA,
+			LastModified: time.Now(),
+			ChangeType:   "removed",
+			BlobSHA:      sha,
+		})
+	}
+	return removed
+}
+
+// GetPullRequestChanges returns the files changed by a pull request, with content
+// fetched from the head commit so proposed documentation can be indexed before merge
+func (s *GitHubService) GetPullRequestChanges(ctx context.Context, owner, repo string, number int) ([]*models.FileChange, error) {
+	pr, _, err := s.client.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, errors.External("GitHub", "failed to get pull request", err)
+	}
+
+	headSHA := pr.GetHead().GetSHA()
+	repoFullName := fmt.Sprintf("%s/%s", owner, repo)
+
+	// Content lives in the head repository, which differs from the base for forks
+	headOwner, headRepo := owner, repo
+	if head := pr.GetHead().GetRepo(); head != nil {
+		headOwner = head.GetOwner().GetLogin()
+		headRepo = head.GetName()
+	}
+
+	var changes []*models.FileChange
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		files, resp, err := s.client.PullRequests.ListFiles(ctx, owner, repo, number, opts)
+		if err != nil {
+			return nil, errors.External("GitHub", "failed to list pull request files", err)
+		}
+
+		for _, file := range files {
+			// Renames drop the old path and add the new one
+			if file.GetStatus() == "renamed" && file.GetPreviousFilename() != "" {
+				changes = append(changes, &models.FileChange{
+					Repository:   repoFullName,
+					FilePath:     file.GetPreviousFilename(),
+					CommitSHA:    headSHA,
+					LastModified: pr.GetUpdatedAt().Time,
+					ChangeType:   "removed",
+				})
+			}
+
+			change := &models.FileChange{
+				Repository:   repoFullName,
+				FilePath:     file.GetFilename(),
+				CommitSHA:    headSHA,
+				LastModified: pr.GetUpdatedAt().Time,
+				ChangeType:   file.GetStatus(),
+				BlobSHA:      file.GetSHA(),
+			}
+
+			if change.ChangeType != "removed" && change.ChangeType != "deleted" {
+				content, err := s.getBlobContent(ctx, headOwner, headRepo, change.FilePath, headSHA, file.GetSHA())
+				if err != nil {
+					logger.Warning("Failed to get content for %s: %v", change.FilePath, err)
+					continue
+				}
+				change.SetContent(content)
+			}
+
+			changes = append(changes, change)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	logger.Info("Found %d changed files in %s pull request #%d (%s...%s)",
+		len(changes), repoFullName, number, pr.GetBase().GetRef(), pr.GetHead().GetRef())
+	return changes, nil
+}
+
 // GetFileContent retrieves content of a specific file
 func (s *GitHubService) GetFileContent(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
 	fileContent, _, _, err := s.client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
@@ -187,6 +389,23 @@ func (s *GitHubService) GetFileContent(ctx context.Context, owner, repo, path, r
 	return []byte(content), nil
 }
 
+// getBlobContent returns file content from the blob cache when possible,
+// falling back to the contents API and caching the result
+func (s *GitHubService) getBlobContent(ctx context.Context, owner, repo, path, ref, blobSHA string) ([]byte, error) {
+	if content, ok := s.cache.Get(blobSHA); ok {
+		logger.Debug("Content cache hit for %s (%s)", path, blobSHA)
+		return content, nil
+	}
+
+	content, err := s.GetFileContent(ctx, owner, repo, path, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Put(blobSHA, content)
+	return content, nil
+}
+
 // GetLatestCommitSHA gets the latest commit SHA for a repository
 func (s *GitHubService) GetLatestCommitSHA(ctx context.Context, owner, repo, branch string) (string, error) {
 	commit, _, err := s.client.Repositories.GetCommit(ctx, owner, repo, branch, nil)
@@ -196,14 +415,79 @@ func (s *GitHubService) GetLatestCommitSHA(ctx context.Context, owner, repo, bra
 	return *commit.SHA, nil
 }
 
+// GetRepository retrieves a single repository by owner and name
+func (s *GitHubService) GetRepository(ctx context.Context, owner, name string) (*models.Repository, error) {
+	ghRepo, _, err := s.client.Repositories.Get(ctx, owner, name)
+	if err != nil {
+		return nil, errors.External("GitHub", "failed to get repository", err)
+	}
+
+	return &models.Repository{
+		ID:            *ghRepo.ID,
+		Name:          *ghRepo.Name,
+		FullName:      *ghRepo.FullName,
+		Owner:         owner,
+		DefaultBranch: *ghRepo.DefaultBranch,
+		UpdatedAt:     ghRepo.UpdatedAt.Time,
+		Private:       *ghRepo.Private,
+	}, nil
+}
+
+// Health checks the GitHub API connection
+func (s *GitHubService) Health(ctx context.Context) error {
+	_, _, err := s.client.Users.Get(ctx, "")
+	return err
+}
+
+// DiscoveryServer exposes a repository client over HTTP
+type DiscoveryServer struct {
+	client interfaces.RepositoryClient
+}
+
+// lazyChangeClient is implemented by providers that can report changes without content
+type lazyChangeClient interface {
+	GetChangedPaths(ctx context.Context, repo *models.Repository, lastCommitSHA string) ([]*models.FileChange, error)
+}
+
+// blobContentClient is implemented by providers that can serve content by blob SHA
+type blobContentClient interface {
+	getBlobContent(ctx context.Context, owner, repo, path, ref, blobSHA string) ([]byte, error)
+}
+
+// blobDiffClient is implemented by providers that support blob-level change detection
+type blobDiffClient interface {
+	GetChangedBlobs(ctx context.Context, repo *models.Repository, known map[string]string, rules filter.Rules) ([]*models.FileChange, error)
+}
+
+// wikiClient is implemented by providers that can ingest repository wikis
+type wikiClient interface {
+	GetWikiPages(ctx context.Context, owner, repo, lastCommitSHA string) ([]*models.FileChange, error)
+}
+
+// conversationClient is implemented by providers that can ingest issues, discussions, and releases
+type conversationClient interface {
+	GetIssues(ctx context.Context, owner, repo string, since time.Time) ([]*models.FileChange, error)
+	GetDiscussions(ctx context.Context, owner, repo string, since time.Time) ([]*models.FileChange, error)
+	GetReleases(ctx context.Context, owner, repo string, since time.Time) ([]*models.FileChange, error)
+}
+
+// rateUsageClient is implemented by providers that consume a metered API quota
+type rateUsageClient interface {
+	RateUsage() *models.RateUsage
+}
+
+// pullRequestClient is implemented by providers that support pull request sync
+type pullRequestClient interface {
+	GetPullRequestChanges(ctx context.Context, owner, repo string, number int) ([]*models.FileChange, error)
+}
+
 // HTTP Handlers
-func (s *GitHubService) handleHealth(w http.ResponseWriter, r *http.Request) {
-	// Test GitHub API connection
+func (d *DiscoveryServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	// Test provider connection
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	_, _, err := s.client.Users.Get(ctx, "")
-	if err != nil {
+	if err := d.client.Health(ctx); err != nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		_ = json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy", "error": err.Error()})
 		return
@@ -212,7 +496,7 @@ func (s *GitHubService) handleHealth(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
-func (s *GitHubService) handleRepositories(w http.ResponseWriter, r *http.Request) {
+func (d *DiscoveryServer) handleRepositories(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -226,7 +510,7 @@ func (s *GitHubService) handleRepositories(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	repos, err := s.ListRepositories(r.Context(), org, keyword)
+	repos, err := d.client.ListRepositories(r.Context(), org, keyword)
 	if err != nil {
 		logger.Error("Failed to list repositories: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -237,7 +521,17 @@ func (s *GitHubService) handleRepositories(w http.ResponseWriter, r *http.Reques
 	_ = json.NewEncoder(w).Encode(repos)
 }
 
-func (s *GitHubService) handleChanges(w http.ResponseWriter, r *http.Request) {
+// errorStatus is the HTTP status for err: 400 for invalid requests, 500
+// otherwise
+func errorStatus(err error) int {
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) && appErr.Type == errors.ErrTypeValidation {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (d *DiscoveryServer) handleChanges(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -260,26 +554,258 @@ func (s *GitHubService) handleChanges(w http.ResponseWriter, r *http.Request) {
 
 	// Get repository info
 	ctx := r.Context()
-	ghRepo, _, err := s.client.Repositories.Get(ctx, parts[0], parts[1])
+	repo, err := d.client.GetRepository(ctx, parts[0], parts[1])
 	if err != nil {
 		logger.Error("Failed to get repository: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	repo := &models.Repository{
-		ID:            *ghRepo.ID,
-		Name:          *ghRepo.Name,
-		FullName:      *ghRepo.FullName,
-		Owner:         parts[0],
-		DefaultBranch: *ghRepo.DefaultBranch,
-		UpdatedAt:     ghRepo.UpdatedAt.Time,
-		Private:       *ghRepo.Private,
+	// Lazy mode returns paths and SHAs only; providers without support return content as usual
+	var changes []*models.FileChange
+	if lazyClient, ok := d.client.(lazyChangeClient); ok && r.URL.Query().Get("lazy") == "true" {
+		changes, err = lazyClient.GetChangedPaths(ctx, repo, lastCommit)
+	} else {
+		changes, err = d.client.GetChangedFiles(ctx, repo, lastCommit)
 	}
-
-	changes, err := s.GetChangedFiles(ctx, repo, lastCommit)
 	if err != nil {
 		logger.Error("Failed to get changed files: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(changes)
+}
+
+func (d *DiscoveryServer) handleContent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	parts := strings.Split(query.Get("repo"), "/")
+	if len(parts) != 2 {
+		http.Error(w, "repo parameter is required in owner/name format", http.StatusBadRequest)
+		return
+	}
+
+	path := query.Get("path")
+	if path == "" {
+		http.Error(w, "path parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	var content []byte
+	var err error
+	if blobClient, ok := d.client.(blobContentClient); ok && query.Get("blob_sha") != "" {
+		content, err = blobClient.getBlobContent(r.Context(), parts[0], parts[1], path, query.Get("ref"), query.Get("blob_sha"))
+	} else {
+		content, err = d.client.GetFileContent(r.Context(), parts[0], parts[1], path, query.Get("ref"))
+	}
+	if err != nil {
+		logger.Error("Failed to get file content: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(content)
+}
+
+// BlobChangesRequest carries the per-file blob SHAs recorded by the last sync
+type BlobChangesRequest struct {
+	Repository string            `json:"repository"`
+	Known      map[string]string `json:"known"` // file path -> blob SHA
+	Rules      filter.Rules      `json:"rules"` // files to leave out of the diff
+}
+
+func (d *DiscoveryServer) handleBlobChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	blobClient, ok := d.client.(blobDiffClient)
+	if !ok {
+		http.Error(w, "blob change detection is not supported by this repository provider", http.StatusNotImplemented)
+		return
+	}
+
+	var req BlobChangesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	parts := strings.Split(req.Repository, "/")
+	if len(parts) != 2 {
+		http.Error(w, "invalid repository format, expected owner/name", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	repo, err := d.client.GetRepository(ctx, parts[0], parts[1])
+	if err != nil {
+		logger.Error("Failed to get repository: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	changes, err := blobClient.GetChangedBlobs(ctx, repo, req.Known, req.Rules)
+	if err != nil {
+		logger.Error("Failed to get changed blobs: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(changes)
+}
+
+func (d *DiscoveryServer) handleWiki(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	wiki, ok := d.client.(wikiClient)
+	if !ok {
+		http.Error(w, "wiki ingestion is not supported by this repository provider", http.StatusNotImplemented)
+		return
+	}
+
+	repoFullName := r.URL.Query().Get("repo")
+	lastCommit := r.URL.Query().Get("last_commit")
+
+	parts := strings.Split(repoFullName, "/")
+	if len(parts) != 2 {
+		http.Error(w, "repo parameter is required in owner/name format", http.StatusBadRequest)
+		return
+	}
+
+	pages, err := wiki.GetWikiPages(r.Context(), parts[0], parts[1], lastCommit)
+	if err != nil {
+		logger.Error("Failed to get wiki pages: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(pages)
+}
+
+func (d *DiscoveryServer) handleIssues(w http.ResponseWriter, r *http.Request) {
+	d.handleConversations(w, r, SourceIssue)
+}
+
+func (d *DiscoveryServer) handleDiscussions(w http.ResponseWriter, r *http.Request) {
+	d.handleConversations(w, r, SourceDiscussion)
+}
+
+func (d *DiscoveryServer) handleReleases(w http.ResponseWriter, r *http.Request) {
+	d.handleConversations(w, r, SourceRelease)
+}
+
+// handleConversations serves issue, discussion, or release documents with optional since filtering
+func (d *DiscoveryServer) handleConversations(w http.ResponseWriter, r *http.Request, source string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	conversations, ok := d.client.(conversationClient)
+	if !ok {
+		http.Error(w, "issue, discussion, and release ingestion is not supported by this repository provider", http.StatusNotImplemented)
+		return
+	}
+
+	parts := strings.Split(r.URL.Query().Get("repo"), "/")
+	if len(parts) != 2 {
+		http.Error(w, "repo parameter is required in owner/name format", http.StatusBadRequest)
+		return
+	}
+
+	var since time.Time
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "since must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	var docs []*models.FileChange
+	var err error
+	switch source {
+	case SourceDiscussion:
+		docs, err = conversations.GetDiscussions(r.Context(), parts[0], parts[1], since)
+	case SourceRelease:
+		docs, err = conversations.GetReleases(r.Context(), parts[0], parts[1], since)
+	default:
+		docs, err = conversations.GetIssues(r.Context(), parts[0], parts[1], since)
+	}
+	if err != nil {
+		logger.Error("Failed to get conversations: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(docs)
+}
+
+// handleRateUsage reports cumulative API calls per repository; callers
+// attribute usage to a sync by diffing snapshots taken before and after it
+func (d *DiscoveryServer) handleRateUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	usage := &models.RateUsage{Repositories: map[string]int{}, Remaining: -1}
+	if usageClient, ok := d.client.(rateUsageClient); ok {
+		usage = usageClient.RateUsage()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(usage)
+}
+
+func (d *DiscoveryServer) handlePullRequestChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prClient, ok := d.client.(pullRequestClient)
+	if !ok {
+		http.Error(w, "pull request sync is not supported by this repository provider", http.StatusNotImplemented)
+		return
+	}
+
+	repoFullName := r.URL.Query().Get("repo")
+	if repoFullName == "" {
+		http.Error(w, "repo parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	parts := strings.Split(repoFullName, "/")
+	if len(parts) != 2 {
+		http.Error(w, "invalid repo format, expected owner/name", http.StatusBadRequest)
+		return
+	}
+
+	number, err := strconv.Atoi(r.URL.Query().Get("number"))
+	if err != nil || number <= 0 {
+		http.Error(w, "number parameter must be a positive pull request number", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := prClient.GetPullRequestChanges(r.Context(), parts[0], parts[1], number)
+	if err != nil {
+		logger.Error("Failed to get pull request changes: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -310,14 +836,43 @@ func main() {
 
 	logger.Info("Starting GitHub Discovery Service on port %d", cfg.Services.GitHubServicePort)
 
-	// Create GitHub service
-	service := NewGitHubService(cfg.GitHub.Token)
+	// Create repository provider
+	var client interfaces.RepositoryClient
+	switch cfg.GitHub.Provider {
+	case "local":
+		client, err = NewLocalRepositoryService(cfg.GitHub.Organization, cfg.GitHub.LocalPaths, cfg.GitHub.LocalStateDir)
+		if err != nil {
+			logger.Fatal("Failed to create local repository provider: %v", err)
+		}
+	case "ssh":
+		client, err = NewSSHRepositoryService(context.Background(), cfg.GitHub)
+		if err != nil {
+			logger.Fatal("Failed to create SSH repository provider: %v", err)
+		}
+	default:
+		cache, err := NewBlobCache(cfg.GitHub.ContentCacheDir)
+		if err != nil {
+			logger.Fatal("Failed to create content cache: %v", err)
+		}
+		client = NewGitHubService(cfg.GitHub.Token, cache)
+	}
+	logger.Info("Using '%s' repository provider", cfg.GitHub.Provider)
+
+	service := &DiscoveryServer{client: client}
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", service.handleHealth)
 	mux.HandleFunc("/repositories", service.handleRepositories)
 	mux.HandleFunc("/changes", service.handleChanges)
+	mux.HandleFunc("/changes/blobs", service.handleBlobChanges)
+	mux.HandleFunc("/content", service.handleContent)
+	mux.HandleFunc("/pull-request/changes", service.handlePullRequestChanges)
+	mux.HandleFunc("/wiki", service.handleWiki)
+	mux.HandleFunc("/issues", service.handleIssues)
+	mux.HandleFunc("/discussions", service.handleDiscussions)
+	mux.HandleFunc("/releases", service.handleReleases)
+	mux.HandleFunc("/rate-usage", service.handleRateUsage)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Services.GitHubServicePort),
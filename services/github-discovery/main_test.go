@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// githubAPI serves canned GitHub API responses. routes maps a request path
+// to its JSON body, with later pages of a list under "<path>?page=<n>";
+// contents maps "owner/repo/path@ref" to the file the contents API returns.
+type githubAPI struct {
+	routes   map[string]string
+	contents map[string]string
+	header   http.Header // sent with every response
+
+	mu        sync.Mutex
+	requested []string
+}
+
+func (a *githubAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.requested = append(a.requested, r.URL.Path)
+	a.mu.Unlock()
+	for key, values := range a.header {
+		w.Header()[key] = values
+	}
+
+	if rest, ok := strings.CutPrefix(r.URL.Path, "/repos/"); ok {
+		if parts := strings.SplitN(rest, "/contents/", 2); len(parts) == 2 {
+			content, ok := a.contents[parts[0]+"/"+parts[1]+"@"+r.URL.Query().Get("ref")]
+			if !ok {
+				http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"type": "file", "path": parts[1], "encoding": "base64",
+				"content": base64.StdEncoding.EncodeToString([]byte(content)),
+			})
+			return
+		}
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	key := r.URL.Path
+	if page > 1 {
+		key = fmt.Sprintf("%s?page=%d", r.URL.Path, page)
+	}
+	body, ok := a.routes[key]
+	if !ok {
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		return
+	}
+	if _, more := a.routes[fmt.Sprintf("%s?page=%d", r.URL.Path, max(page, 1)+1)]; more {
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=%d>; rel="next"`, r.Host, r.URL.Path, max(page, 1)+1))
+	}
+	_, _ = w.Write([]byte(body))
+}
+
+// contentRequests counts the contents API calls served so far
+func (a *githubAPI) contentRequests() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := 0
+	for _, path := range a.requested {
+		if strings.Contains(path, "/contents/") {
+			n++
+		}
+	}
+	return n
+}
+
+// newTestGitHub returns a GitHub service whose API calls api serves
+func newTestGitHub(t *testing.T, api http.Handler, cache *BlobCache) *GitHubService {
+	t.Helper()
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	s := NewGitHubService("token", cache)
+	base, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.client.BaseURL = base
+	return s
+}
+
+// blobSHA is gitBlobSHA for a string
+func blobSHA(content string) string {
+	return gitBlobSHA([]byte(content))
+}
+
+// describeChanges renders changes as "path changeType content", in order
+func describeChanges(changes []*models.FileChange) []string {
+	out := make([]string, len(changes))
+	for i, change := range changes {
+		out[i] = fmt.Sprintf("%s %s %s", change.FilePath, change.ChangeType, change.Content)
+	}
+	return out
+}
+
+const testRepoJSON = `{"id":1,"name":"repo","full_name":"org/repo","default_branch":"main","updated_at":"2026-01-02T03:04:05Z","private":false,"has_wiki":true}`
+
+func TestGetPullRequestChanges(t *testing.T) {
+	tests := []struct {
+		name         string
+		headRepo     string // JSON of the head repository, empty for none
+		contentsRepo string
+	}{
+		{name: "same repository", contentsRepo: "org/repo"},
+		{name: "content read from the fork", headRepo: `,"repo":{"name":"fork","owner":{"login":"contributor"}}`, contentsRepo: "contributor/fork"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &githubAPI{
+				routes: map[string]string{
+					"/repos/org/repo/pulls/7": `{"number":7,"updated_at":"2026-01-02T03:04:05Z",` +
+						`"head":{"sha":"head","ref":"feature"` + tt.headRepo + `},"base":{"ref":"main"}}`,
+					"/repos/org/repo/pulls/7/files": fmt.Sprintf(`[{"filename":"docs/a.md","status":"added","sha":%q},`+
+						`{"filename":"docs/old.md","status":"removed","sha":"old"},`+
+						`{"filename":"docs/missing.md","status":"added","sha":"missing"}]`, blobSHA("A")),
+					"/repos/org/repo/pulls/7/files?page=2": fmt.Sprintf(`[{"filename":"docs/b.md","status":"modified","sha":%q},`+
+						`{"filename":"docs/c.md","status":"renamed","previous_filename":"docs/old-c.md","sha":%q}]`, blobSHA("B"), blobSHA("C")),
+				},
+				contents: map[string]string{
+					tt.contentsRepo + "/docs/a.md@head": "A",
+					tt.contentsRepo + "/docs/b.md@head": "B",
+					tt.contentsRepo + "/docs/c.md@head": "C",
+				},
+			}
+			cache, err := NewBlobCache(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := newTestGitHub(t, api, cache)
+
+			// Files without content are skipped, removed files need none, and
+			// a rename removes its old path
+			want := []string{
+				"docs/a.md added A", "docs/old.md removed ", "docs/b.md modified B",
+				"docs/old-c.md removed ", "docs/c.md renamed C",
+			}
+			for run := 0; run < 2; run++ {
+				changes, err := s.GetPullRequestChanges(context.Background(), "org", "repo", 7)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := describeChanges(changes); !reflect.DeepEqual(got, want) {
+					t.Fatalf("changes = %q, want %q", got, want)
+				}
+				for _, change := range changes {
+					if change.Repository != "org/repo" || change.CommitSHA != "head" {
+						t.Errorf("%s is in %s at %s, want org/repo at head", change.FilePath, change.Repository, change.CommitSHA)
+					}
+				}
+			}
+			// The second run reads the files from the cache and retries the missing one
+			if n := api.contentRequests(); n != 5 {
+				t.Errorf("made %d content requests, want 5", n)
+			}
+		})
+	}
+}
+
+func TestGetChangedBlobs(t *testing.T) {
+	tree := func(truncated bool) string {
+		return fmt.Sprintf(`{"sha":"latest","truncated":%v,"tree":[`+
+			`{"path":"README.md","type":"blob","sha":%q,"size":6},`+
+			`{"path":"docs","type":"tree","sha":"docs"},`+
+			`{"path":"docs/a.md","type":"blob","sha":%q,"size":2}]}`, truncated, blobSHA("readme"), blobSHA("a2"))
+	}
+
+	tests := []struct {
+		name      string
+		truncated bool
+		known     map[string]string
+		rules     filter.Rules
+		want      []string
+	}{
+		{name: "first sync adds everything", want: []string{"README.md added readme", "docs/a.md added a2"}},
+		{
+			// An excluded file never gets a recorded SHA, so it must not
+			// be fetched or reported as new on every sync
+			name:  "excluded files skipped",
+			known: map[string]string{"README.md": blobSHA("readme")},
+			rules: filter.Rules{ExcludePatterns: []string{"docs"}},
+			want:  []string{},
+		},
+		{
+			name:  "disallowed extensions skipped",
+			rules: filter.Rules{AllowedExtensions: []string{".txt"}},
+			want:  []string{},
+		},
+		{
+			name:  "unchanged skipped, changed and removed reported",
+			known: map[string]string{"README.md": blobSHA("readme"), "docs/a.md": blobSHA("a1"), "docs/gone.md": "gone"},
+			want:  []string{"docs/a.md modified a2", "docs/gone.md removed "},
+		},
+		{
+			name:      "truncated tree reports no removals",
+			truncated: true,
+			known:     map[string]string{"README.md": blobSHA("readme"), "docs/a.md": blobSHA("a1"), "docs/gone.md": "gone"},
+			want:      []string{"docs/a.md modified a2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &githubAPI{
+				routes: map[string]string{
+					"/repos/org/repo/commits/main":     `{"sha":"latest"}`,
+					"/repos/org/repo/git/trees/latest": tree(tt.truncated),
+				},
+				contents: map[string]string{"org/repo/README.md@latest": "readme", "org/repo/docs/a.md@latest": "a2"},
+			}
+			s := newTestGitHub(t, api, nil)
+			repo := &models.Repository{Owner: "org", Name: "repo", FullName: "org/repo", DefaultBranch: "main"}
+
+			changes, err := s.GetChangedBlobs(context.Background(), repo, tt.known, tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describeChanges(changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %q, want %q", got, tt.want)
+			}
+			fetched, fetches := 0, 0
+			for _, path := range api.requested {
+				if strings.Contains(path, "/contents/") {
+					fetched++
+				}
+			}
+			for _, change := range changes {
+				if change.ChangeType != "removed" {
+					fetches++
+				}
+			}
+			if fetched != fetches {
+				t.Errorf("fetched %d files, want only the %d changed ones", fetched, fetches)
+			}
+			for _, change := range changes {
+				if change.CommitSHA != "latest" {
+					t.Errorf("%s at %s, want latest", change.FilePath, change.CommitSHA)
+				}
+				if change.ChangeType == "removed" && change.BlobSHA != tt.known[change.FilePath] {
+					t.Errorf("removed %s has blob %s, want the known %s", change.FilePath, change.BlobSHA, tt.known[change.FilePath])
+				}
+			}
+		})
+	}
+}
+
+func TestHandleChanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		want        []string
+		wantFetches int
+	}{
+		{name: "since a commit", query: "&last_commit=old", want: []string{"docs/a.md modified A", "docs/gone.md removed "}, wantFetches: 1},
+		{name: "lazy leaves content out", query: "&last_commit=old&lazy=true", want: []string{"docs/a.md modified ", "docs/gone.md removed "}},
+		{name: "every file on the first sync", want: []string{"docs/a.md added A"}, wantFetches: 1},
+		{name: "lazy first sync", query: "&lazy=true", want: []string{"docs/a.md added "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &githubAPI{
+				routes: map[string]string{
+					"/repos/org/repo":              testRepoJSON,
+					"/repos/org/repo/commits/main": `{"sha":"latest","commit":{"author":{"date":"2026-01-02T03:04:05Z"}}}`,
+					"/repos/org/repo/compare/old...latest": fmt.Sprintf(`{"files":[`+
+						`{"filename":"docs/a.md","status":"modified","sha":%q,"changes":1},`+
+						`{"filename":"docs/gone.md","status":"removed","sha":"gone","changes":1}]}`, blobSHA("A")),
+					"/repos/org/repo/git/trees/main": fmt.Sprintf(`{"tree":[{"path":"docs/a.md","type":"blob","sha":%q,"size":1}]}`, blobSHA("A")),
+				},
+				contents: map[string]string{"org/repo/docs/a.md@main": "A"},
+			}
+			d := &DiscoveryServer{client: newTestGitHub(t, api, nil)}
+
+			rec := httptest.NewRecorder()
+			d.handleChanges(rec, httptest.NewRequest(http.MethodGet, "/changes?repo=org/repo"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			var changes []*models.FileChange
+			if err := json.Unmarshal(rec.Body.Bytes(), &changes); err != nil {
+				t.Fatal(err)
+			}
+			if got := describeChanges(changes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %q, want %q", got, tt.want)
+			}
+			for _, change := range changes {
+				if change.BlobSHA == "" {
+					t.Errorf("%s has no blob SHA to fetch its content by", change.FilePath)
+				}
+			}
+			if n := api.contentRequests(); n != tt.wantFetches {
+				t.Errorf("made %d content requests, want %d", n, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestHandleContent(t *testing.T) {
+	api := &githubAPI{contents: map[string]string{"org/repo/docs/a.md@main": "fresh"}}
+	cache, err := NewBlobCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Put(blobSHA("cached"), []byte("cached"))
+	d := &DiscoveryServer{client: newTestGitHub(t, api, cache)}
+
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		want        string
+		wantFetches int
+	}{
+		{name: "by blob SHA from the cache", query: "repo=org/repo&path=docs/a.md&ref=main&blob_sha=" + blobSHA("cached"), wantStatus: http.StatusOK, want: "cached"},
+		{name: "by blob SHA from the API", query: "repo=org/repo&path=docs/a.md&ref=main&blob_sha=" + blobSHA("fresh"), wantStatus: http.StatusOK, want: "fresh", wantFetches: 1},
+		{name: "fetched blob is cached", query: "repo=org/repo&path=docs/a.md&ref=main&blob_sha=" + blobSHA("fresh"), wantStatus: http.StatusOK, want: "fresh"},
+		{name: "by ref", query: "repo=org/repo&path=docs/a.md&ref=main", wantStatus: http.StatusOK, want: "fresh", wantFetches: 1},
+		{name: "missing file", query: "repo=org/repo&path=docs/b.md&ref=main", wantStatus: http.StatusInternalServerError, wantFetches: 1},
+		{name: "path is required", query: "repo=org/repo", wantStatus: http.StatusBadRequest},
+		{name: "repo is required", query: "path=docs/a.md", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := api.contentRequests()
+			rec := httptest.NewRecorder()
+			d.handleContent(rec, httptest.NewRequest(http.MethodGet, "/content?"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != tt.want {
+				t.Errorf("content = %q, want %q", rec.Body.String(), tt.want)
+			}
+			if n := api.contentRequests() - before; n != tt.wantFetches {
+				t.Errorf("made %d content requests, want %d", n, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestDiscoveryHandlers(t *testing.T) {
+	github := &DiscoveryServer{client: newTestGitHub(t, &githubAPI{}, nil)}
+	local, err := NewLocalRepositoryService("local", []string{t.TempDir()}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The local provider has no pull requests, wikis, or conversations
+	plain := &DiscoveryServer{client: local}
+
+	tests := []struct {
+		name       string
+		server     *DiscoveryServer
+		handler    func(*DiscoveryServer) http.HandlerFunc
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "pull request method", server: github, handler: prHandler, method: http.MethodPost, target: "/pull-request/changes?repo=org/repo&number=1", wantStatus: http.StatusMethodNotAllowed},
+		{name: "pull request unsupported", server: plain, handler: prHandler, method: http.MethodGet, target: "/pull-request/changes?repo=org/repo&number=1", wantStatus: http.StatusNotImplemented},
+		{name: "pull request repo required", server: github, handler: prHandler, method: http.MethodGet, target: "/pull-request/changes?number=1", wantStatus: http.StatusBadRequest},
+		{name: "pull request repo format", server: github, handler: prHandler, method: http.MethodGet, target: "/pull-request/changes?repo=repo&number=1", wantStatus: http.StatusBadRequest},
+		{name: "pull request number", server: github, handler: prHandler, method: http.MethodGet, target: "/pull-request/changes?repo=org/repo&number=abc", wantStatus: http.StatusBadRequest},
+		{name: "pull request number positive", server: github, handler: prHandler, method: http.MethodGet, target: "/pull-request/changes?repo=org/repo&number=0", wantStatus: http.StatusBadRequest},
+		{name: "pull request failure", server: github, handler: prHandler, method: http.MethodGet, target: "/pull-request/changes?repo=org/repo&number=1", wantStatus: http.StatusInternalServerError},
+		{name: "blob changes method", server: github, handler: blobHandler, method: http.MethodGet, target: "/changes/blobs", wantStatus: http.StatusMethodNotAllowed},
+		{name: "blob changes body", server: github, handler: blobHandler, method: http.MethodPost, target: "/changes/blobs", body: "{", wantStatus: http.StatusBadRequest},
+		{name: "blob changes repo format", server: github, handler: blobHandler, method: http.MethodPost, target: "/changes/blobs", body: `{"repository":"repo"}`, wantStatus: http.StatusBadRequest},
+		{name: "wiki unsupported", server: plain, handler: wikiHandler, method: http.MethodGet, target: "/wiki?repo=org/repo", wantStatus: http.StatusNotImplemented},
+		{name: "wiki repo format", server: github, handler: wikiHandler, method: http.MethodGet, target: "/wiki?repo=repo", wantStatus: http.StatusBadRequest},
+		{name: "issues unsupported", server: plain, handler: issuesHandler, method: http.MethodGet, target: "/issues?repo=org/repo", wantStatus: http.StatusNotImplemented},
+		{name: "issues since", server: github, handler: issuesHandler, method: http.MethodGet, target: "/issues?repo=org/repo&since=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "issues repo format", server: github, handler: issuesHandler, method: http.MethodGet, target: "/issues?repo=repo", wantStatus: http.StatusBadRequest},
+		{name: "rate usage method", server: github, handler: rateHandler, method: http.MethodPost, target: "/rate-usage", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(tt.server)(rec, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d (%s), want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func prHandler(d *DiscoveryServer) http.HandlerFunc     { return d.handlePullRequestChanges }
+func blobHandler(d *DiscoveryServer) http.HandlerFunc   { return d.handleBlobChanges }
+func wikiHandler(d *DiscoveryServer) http.HandlerFunc   { return d.handleWiki }
+func issuesHandler(d *DiscoveryServer) http.HandlerFunc { return d.handleIssues }
+func rateHandler(d *DiscoveryServer) http.HandlerFunc   { return d.handleRateUsage }
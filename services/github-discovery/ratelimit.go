@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// otherBucket collects API calls that are not tied to a repository
+const otherBucket = "_other"
+
+type rateRepoKey struct{}
+
+// withRateRepo attributes API calls made with ctx to a repository, for
+// endpoints such as GraphQL whose URL does not name the repository
+func withRateRepo(ctx context.Context, repoFullName string) context.Context {
+	return context.WithValue(ctx, rateRepoKey{}, repoFullName)
+}
+
+// RateCounter is an http.RoundTripper that counts GitHub API calls per repository
+type RateCounter struct {
+	base http.RoundTripper
+
+	mu        sync.Mutex
+	calls     map[string]int
+	since     time.Time
+	remaining int
+	limit     int
+	resetAt   time.Time
+}
+
+// NewRateCounter wraps a transport with per-repository call accounting
+func NewRateCounter(base http.RoundTripper) *RateCounter {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RateCounter{
+		base:      base,
+		calls:     make(map[string]int),
+		since:     time.Now(),
+		remaining: -1,
+	}
+}
+
+// RoundTrip records the call against its repository and captures rate limit headers
+func (c *RateCounter) RoundTrip(req *http.Request) (*http.Response, error) {
+	repo, _ := req.Context().Value(rateRepoKey{}).(string)
+	if repo == "" {
+		repo = repoFromPath(req.URL.Path)
+	}
+
+	resp, err := c.base.RoundTrip(req)
+
+	c.mu.Lock()
+	c.calls[repo]++
+	if resp != nil {
+		if v, convErr := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); convErr == nil {
+			c.remaining = v
+		}
+		if v, convErr := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); convErr == nil {
+			c.limit = v
+		}
+		if v, convErr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); convErr == nil {
+			c.resetAt = time.Unix(v, 0)
+		}
+	}
+	c.mu.Unlock()
+
+	return resp, err
+}
+
+// Usage returns a snapshot of calls per repository since the counter was
+// created. Counters are never reset, so concurrent syncs each diff their own
+// start and end snapshots instead of clearing each other's counts.
+func (c *RateCounter) Usage() *models.RateUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	usage := &models.RateUsage{
+		Repositories: make(map[string]int, len(c.calls)),
+		Since:        c.since,
+		Remaining:    c.remaining,
+		Limit:        c.limit,
+		ResetAt:      c.resetAt,
+	}
+	for repo, n := range c.calls {
+		usage.Repositories[repo] = n
+		usage.Total += n
+	}
+	return usage
+}
+
+// repoFromPath extracts "owner/repo" from a /repos/{owner}/{repo}/... API path
+func repoFromPath(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) >= 3 && parts[0] == "repos" {
+		return parts[1] + "/" + parts[2]
+	}
+	return otherBucket
+}
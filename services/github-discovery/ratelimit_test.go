@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestRepoFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/repos/org/repo", want: "org/repo"},
+		{path: "/repos/org/repo/contents/docs/a.md", want: "org/repo"},
+		{path: "/api/v3/repos/org/repo", want: otherBucket},
+		{path: "/repos/org", want: otherBucket},
+		{path: "/graphql", want: otherBucket},
+		{path: "/user", want: otherBucket},
+	}
+	for _, tt := range tests {
+		if got := repoFromPath(tt.path); got != tt.want {
+			t.Errorf("repoFromPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRateCounter(t *testing.T) {
+	remaining := 100
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remaining--
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Reset", "1767323045")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	counter := NewRateCounter(nil)
+	client := &http.Client{Transport: counter}
+	call := func(ctx context.Context, path string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	empty := counter.Usage()
+	if empty.Total != 0 || empty.Remaining != -1 || len(empty.Repositories) != 0 {
+		t.Errorf("usage before any call = %+v", empty)
+	}
+
+	ctx := context.Background()
+	call(ctx, "/repos/org/a/commits/main")
+	call(ctx, "/repos/org/a/contents/README.md")
+	before := counter.Usage()
+
+	call(ctx, "/repos/org/a/git/trees/main")
+	call(ctx, "/repos/org/b")
+	call(withRateRepo(ctx, "org/c"), "/graphql")
+	call(ctx, "/user")
+	call(ctx, "/fail")
+	after := counter.Usage()
+
+	want := map[string]int{"org/a": 3, "org/b": 1, "org/c": 1, otherBucket: 2}
+	if !reflect.DeepEqual(after.Repositories, want) || after.Total != 7 {
+		t.Errorf("usage = %v (%d total), want %v (7 total)", after.Repositories, after.Total, want)
+	}
+	if after.Remaining != 93 || after.Limit != 5000 || !after.ResetAt.Equal(time.Unix(1767323045, 0)) {
+		t.Errorf("quota = %d of %d until %s", after.Remaining, after.Limit, after.ResetAt)
+	}
+
+	// Snapshots are never reset, so a sync's share is the difference of two
+	if before.Repositories["org/a"] != 2 || before.Total != 2 {
+		t.Errorf("earlier snapshot changed: %+v", before)
+	}
+	delta := after.Delta(before)
+	wantDelta := map[string]int{"org/a": 1, "org/b": 1, "org/c": 1, otherBucket: 2}
+	if !reflect.DeepEqual(delta.Repositories, wantDelta) || delta.Total != 5 {
+		t.Errorf("delta = %v (%d total), want %v (5 total)", delta.Repositories, delta.Total, wantDelta)
+	}
+}
+
+func TestHandleRateUsage(t *testing.T) {
+	api := &githubAPI{
+		routes: map[string]string{"/repos/org/repo": testRepoJSON},
+		header: http.Header{"X-Ratelimit-Remaining": {"4321"}},
+	}
+	hosted := newTestGitHub(t, api, nil)
+	if _, err := hosted.GetRepository(context.Background(), "org", "repo"); err != nil {
+		t.Fatal(err)
+	}
+	local, err := NewLocalRepositoryService("local", []string{t.TempDir()}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		client        *DiscoveryServer
+		wantRepos     map[string]int
+		wantRemaining int
+	}{
+		{name: "metered provider", client: &DiscoveryServer{client: hosted}, wantRepos: map[string]int{"org/repo": 1}, wantRemaining: 4321},
+		{name: "unmetered provider", client: &DiscoveryServer{client: local}, wantRepos: map[string]int{}, wantRemaining: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.client.handleRateUsage(rec, httptest.NewRequest(http.MethodGet, "/rate-usage", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			var usage models.RateUsage
+			if err := json.Unmarshal(rec.Body.Bytes(), &usage); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(usage.Repositories, tt.wantRepos) || usage.Remaining != tt.wantRemaining {
+				t.Errorf("usage = %v with %d remaining, want %v with %d", usage.Repositories, usage.Remaining, tt.wantRepos, tt.wantRemaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// SSHRepositoryService implements interfaces.RepositoryClient by keeping SSH
+// clones of each repository up to date with per-repository deploy keys, then
+// delegating change detection to the local provider over the clones.
+//
+// Clones are driven through the git CLI rather than go-git: the local provider
+// already diffs and reads through git, and the CLI's ssh honours the same
+// known_hosts and key options operators use elsewhere.
+type SSHRepositoryService struct {
+	*LocalRepositoryService
+
+	host       string
+	owner      string
+	cloneDir   string
+	keys       map[string]string // repository name -> private key path
+	knownHosts string
+
+	mu sync.Mutex // serializes git fetches into the clone directory
+}
+
+// NewSSHRepositoryService clones (or refreshes) every configured repository
+func NewSSHRepositoryService(ctx context.Context, cfg config.GitHubConfig) (*SSHRepositoryService, error) {
+	cloneDir, err := filepath.Abs(filepath.Join(cfg.SSHCloneDir, cfg.Organization))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve clone directory: %w", err)
+	}
+	if err := os.MkdirAll(cloneDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create clone directory: %w", err)
+	}
+
+	// Host keys must be pinned up front; trusting them on first use would let
+	// a spoofed host serve the initial clone
+	if cfg.SSHKnownHostsPath == "" {
+		return nil, fmt.Errorf("SSH_KNOWN_HOSTS_PATH is required for the SSH provider")
+	}
+	if _, err := os.Stat(cfg.SSHKnownHostsPath); err != nil {
+		return nil, fmt.Errorf("SSH known_hosts file unavailable: %w", err)
+	}
+
+	keys := make(map[string]string, len(cfg.SSHRepositories))
+	for _, repo := range cfg.SSHRepositories {
+		key := cfg.SSHDeployKeys[repo]
+		if key == "" {
+			key = cfg.SSHKeyPath
+		}
+		if _, err := os.Stat(key); err != nil {
+			return nil, fmt.Errorf("SSH key for %s unavailable: %w", repo, err)
+		}
+		keys[repo] = key
+	}
+
+	s := &SSHRepositoryService{
+		host:       cfg.SSHHost,
+		owner:      cfg.Organization,
+		cloneDir:   cloneDir,
+		keys:       keys,
+		knownHosts: cfg.SSHKnownHostsPath,
+	}
+
+	paths := make([]string, 0, len(cfg.SSHRepositories))
+	for _, repo := range cfg.SSHRepositories {
+		if err := s.refresh(ctx, repo); err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.Join(cloneDir, repo))
+	}
+
+	local, err := NewLocalRepositoryService(cfg.Organization, paths, "")
+	if err != nil {
+		return nil, err
+	}
+	s.LocalRepositoryService = local
+
+	return s, nil
+}
+
+// ListRepositories refreshes every clone before listing
+func (s *SSHRepositoryService) ListRepositories(ctx context.Context, org, keyword string) ([]*models.Repository, error) {
+	for repo := range s.keys {
+		if err := s.refresh(ctx, repo); err != nil {
+			return nil, err
+		}
+	}
+	return s.LocalRepositoryService.ListRepositories(ctx, org, keyword)
+}
+
+// GetChangedFiles refreshes the clone, then diffs against the last sync pointer
+func (s *SSHRepositoryService) GetChangedFiles(ctx context.Context, repo *models.Repository, lastCommitSHA string) ([]*models.FileChange, error) {
+	if err := s.refresh(ctx, repo.Name); err != nil {
+		return nil, err
+	}
+	return s.LocalRepositoryService.GetChangedFiles(ctx, repo, lastCommitSHA)
+}
+
+// GetChangedBlobs refreshes the clone, then diffs blob SHAs
+func (s *SSHRepositoryService) GetChangedBlobs(ctx context.Context, repo *models.Repository, known map[string]string, rules filter.Rules) ([]*models.FileChange, error) {
+	if err := s.refresh(ctx, repo.Name); err != nil {
+		return nil, err
+	}
+	return s.LocalRepositoryService.GetChangedBlobs(ctx, repo, known, rules)
+}
+
+// Health checks that the remotes are reachable with their keys
+func (s *SSHRepositoryService) Health(ctx context.Context) error {
+	for repo := range s.keys {
+		if _, err := runGitEnv(ctx, s.cloneDir, s.sshEnv(repo), "ls-remote", "--exit-code", s.remoteURL(repo), "HEAD"); err != nil {
+			return fmt.Errorf("remote for %s unreachable: %w", repo, err)
+		}
+	}
+	return nil
+}
+
+// refresh clones a repository or resets an existing clone to the remote HEAD
+func (s *SSHRepositoryService) refresh(ctx context.Context, repo string) error {
+	if _, ok := s.keys[repo]; !ok {
+		return errors.NotFound(fmt.Sprintf("SSH repository %s", repo))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.cloneDir, repo)
+	env := s.sshEnv(repo)
+
+	if !isGitDir(dir) {
+		logger.Info("Cloning %s/%s over SSH", s.owner, repo)
+		if _, err := runGitEnv(ctx, s.cloneDir, env, "clone", "--quiet", s.remoteURL(repo), repo); err != nil {
+			return errors.External("git", "failed to clone repository", err)
+		}
+		return nil
+	}
+
+	if _, err := runGitEnv(ctx, dir, env, "fetch", "--quiet", "--prune", "origin", "HEAD"); err != nil {
+		return errors.External("git", "failed to fetch repository", err)
+	}
+	if _, err := runGit(ctx, dir, "reset", "--quiet", "--hard", "FETCH_HEAD"); err != nil {
+		return errors.Internal("failed to update clone", err)
+	}
+	return nil
+}
+
+// remoteURL builds the SSH remote for a repository
+func (s *SSHRepositoryService) remoteURL(repo string) string {
+	return fmt.Sprintf("git@%s:%s/%s.git", s.host, s.owner, repo)
+}
+
+// sshEnv pins git to the repository's deploy key and the configured host keys
+func (s *SSHRepositoryService) sshEnv(repo string) []string {
+	// GIT_SSH_COMMAND is run through a shell, so quote the paths
+	args := []string{
+		"ssh", "-i", shellQuote(s.keys[repo]), "-o", "IdentitiesOnly=yes", "-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=yes", "-o", "UserKnownHostsFile=" + shellQuote(s.knownHosts),
+	}
+	return []string{"GIT_SSH_COMMAND=" + strings.Join(args, " "), "GIT_TERMINAL_PROMPT=0"}
+}
+
+// shellQuote wraps a value in single quotes for safe use in a shell command
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "/keys/id_ed25519", want: `'/keys/id_ed25519'`},
+		{value: "/my keys/id", want: `'/my keys/id'`},
+		{value: "/keys/it's", want: `'/keys/it'\''s'`},
+		{value: "$(rm -rf /)", want: `'$(rm -rf /)'`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.value); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	// The quoted value survives a round trip through the shell
+	if _, err := exec.LookPath("sh"); err == nil {
+		for _, tt := range tests {
+			out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(tt.value)).Output()
+			if err != nil || string(out) != tt.value {
+				t.Errorf("sh printed %q (%v), want %q", out, err, tt.value)
+			}
+		}
+	}
+}
+
+// writeFiles creates empty files in dir and returns their paths
+func writeFiles(t *testing.T, dir string, names ...string) []string {
+	t.Helper()
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(dir, name)
+		if err := os.WriteFile(paths[i], nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func TestNewSSHRepositoryServiceConfig(t *testing.T) {
+	dir := t.TempDir()
+	files := writeFiles(t, dir, "known_hosts", "default key", "docs_key")
+	knownHosts, defaultKey, docsKey := files[0], files[1], files[2]
+
+	tests := []struct {
+		name    string
+		cfg     config.GitHubConfig
+		wantErr string
+	}{
+		{name: "known hosts required", cfg: config.GitHubConfig{}, wantErr: "SSH_KNOWN_HOSTS_PATH is required"},
+		{name: "known hosts missing", cfg: config.GitHubConfig{SSHKnownHostsPath: filepath.Join(dir, "missing")}, wantErr: "known_hosts file unavailable"},
+		{
+			name:    "key missing",
+			cfg:     config.GitHubConfig{SSHKnownHostsPath: knownHosts, SSHRepositories: []string{"docs"}, SSHKeyPath: filepath.Join(dir, "missing")},
+			wantErr: "SSH key for docs unavailable",
+		},
+		{name: "no repositories", cfg: config.GitHubConfig{SSHKnownHostsPath: knownHosts}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Organization = "org"
+			tt.cfg.SSHCloneDir = filepath.Join(t.TempDir(), "clones")
+			_, err := NewSSHRepositoryService(context.Background(), tt.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Git runs with the repository's own key, and every path is quoted
+	s, err := NewSSHRepositoryService(context.Background(), config.GitHubConfig{
+		Organization: "org", SSHCloneDir: t.TempDir(), SSHHost: "git.example.com", SSHKnownHostsPath: knownHosts,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.keys = map[string]string{"docs": docsKey, "site": defaultKey}
+	if got, want := s.remoteURL("docs"), "git@git.example.com:org/docs.git"; got != want {
+		t.Errorf("remoteURL() = %s, want %s", got, want)
+	}
+	want := []string{
+		"GIT_SSH_COMMAND=ssh -i " + shellQuote(defaultKey) + " -o IdentitiesOnly=yes -o BatchMode=yes" +
+			" -o StrictHostKeyChecking=yes -o UserKnownHostsFile=" + shellQuote(knownHosts),
+		"GIT_TERMINAL_PROMPT=0",
+	}
+	if got := s.sshEnv("site"); !reflect.DeepEqual(got, want) {
+		t.Errorf("sshEnv() = %q, want %q", got, want)
+	}
+}
+
+func TestSSHRefresh(t *testing.T) {
+	origin, shas := newGitRepo(t, map[string]string{"README.md": "v1"})
+	ctx := context.Background()
+
+	// Start from an existing clone, as after a restart; a local origin
+	// stands in for the SSH remote
+	knownHosts := writeFiles(t, t.TempDir(), "known_hosts", "key")
+	s, err := NewSSHRepositoryService(ctx, config.GitHubConfig{
+		Organization: "org", SSHCloneDir: t.TempDir(), SSHKnownHostsPath: knownHosts[0],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := filepath.Join(s.cloneDir, "docs")
+	if _, err := runGit(ctx, s.cloneDir, "clone", "--quiet", origin, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	s.keys["docs"] = knownHosts[1]
+	if s.LocalRepositoryService, err = NewLocalRepositoryService("org", []string{clone}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// New commits on the remote are fetched before changes are read
+	if err := os.WriteFile(filepath.Join(origin, "README.md"), []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com"}
+	if _, err := runGitEnv(ctx, origin, env, "commit", "-q", "-am", "update"); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := s.GetRepository(ctx, "org", "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := s.GetChangedFiles(ctx, repo, shas[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describeChanges(changes); !reflect.DeepEqual(got, []string{"README.md modified v2"}) {
+		t.Errorf("changes = %q, want README.md modified to v2", got)
+	}
+
+	// Local edits to the clone are discarded by the next refresh
+	if err := os.WriteFile(filepath.Join(clone, "README.md"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changes, err = s.GetChangedBlobs(ctx, repo, map[string]string{"README.md": blobSHA("v2")}, filter.Rules{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("changes after refresh = %q, want none", describeChanges(changes))
+	}
+
+	var appErr *errors.AppError
+	if err := s.refresh(ctx, "unknown"); !stderrors.As(err, &appErr) || appErr.Type != errors.ErrTypeNotFound {
+		t.Errorf("refresh(unknown) = %v, want a not found error", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// SourceWiki marks file changes that come from a repository wiki
+const SourceWiki = "wiki"
+
+// GetWikiPages clones a repository's wiki and returns pages changed since
+// lastCommitSHA (all pages when empty). Wikis are separate git repositories
+// without a REST API, so pages are reported under "<owner>/<repo>.wiki".
+func (s *GitHubService) GetWikiPages(ctx context.Context, owner, repo, lastCommitSHA string) ([]*models.FileChange, error) {
+	ghRepo, _, err := s.client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return nil, errors.External("GitHub", "failed to get repository", err)
+	}
+	if !ghRepo.GetHasWiki() {
+		logger.Debug("Wiki disabled for %s/%s", owner, repo)
+		return []*models.FileChange{}, nil
+	}
+
+	tmpDir, err := os.MkdirTemp("", "reposync-wiki-")
+	if err != nil {
+		return nil, errors.Internal("failed to create wiki clone directory", err)
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	wikiName := repo + ".wiki"
+	cloneDir := filepath.Join(tmpDir, wikiName)
+	if err := s.cloneWiki(ctx, owner, wikiName, cloneDir); err != nil {
+		// Wikis that were enabled but never edited have no backing repository
+		if strings.Contains(err.Error(), "not found") {
+			logger.Debug("No wiki content for %s/%s", owner, repo)
+			return []*models.FileChange{}, nil
+		}
+		return nil, errors.External("GitHub", "failed to clone wiki", err)
+	}
+
+	// Reuse the local provider for change detection over the clone
+	local, err := NewLocalRepositoryService(owner, []string{cloneDir}, "")
+	if err != nil {
+		return nil, err
+	}
+	wikiRepo, err := local.GetRepository(ctx, owner, wikiName)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := local.GetChangedFiles(ctx, wikiRepo, lastCommitSHA)
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		page.Source = SourceWiki
+	}
+
+	logger.Info("Found %d changed wiki pages in %s", len(pages), wikiRepo.FullName)
+	return pages, nil
+}
+
+// cloneWiki clones a wiki repository, passing the token through git's
+// environment-based config so it never appears in process arguments
+func (s *GitHubService) cloneWiki(ctx context.Context, owner, wikiName, dir string) error {
+	url := fmt.Sprintf("%s/%s/%s.git", s.webURL, owner, wikiName)
+	cmd := exec.CommandContext(ctx, "git", "clone", "--quiet", url, dir)
+
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if s.token != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + s.token))
+		cmd.Env = append(cmd.Env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials,
+		)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git clone %s: %w: %s", url, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
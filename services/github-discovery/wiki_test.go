@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestGetWikiPages(t *testing.T) {
+	wiki, shas := newGitRepo(t,
+		map[string]string{"Home.md": "home", "Setup.md": "setup v1"},
+		map[string]string{"Setup.md": "setup v2", "FAQ.md": "faq"},
+	)
+	// Wikis are cloned from <web URL>/<owner>/<repo>.wiki.git
+	web := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(web, "org"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGit(context.Background(), web, "clone", "--quiet", "--bare", wiki, filepath.Join(web, "org", "repo.wiki.git")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		repoJSON   string
+		lastCommit string
+		want       []string // "path changeType"
+		wantErr    bool
+	}{
+		{name: "every page on the first sync", repoJSON: testRepoJSON, want: []string{"FAQ.md added", "Home.md added", "Setup.md added"}},
+		{name: "pages changed since the last sync", repoJSON: testRepoJSON, lastCommit: shas[0], want: []string{"FAQ.md added", "Setup.md modified"}},
+		{name: "wiki disabled", repoJSON: strings.Replace(testRepoJSON, `"has_wiki":true`, `"has_wiki":false`, 1), want: []string{}},
+		{name: "repository lookup fails", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &githubAPI{routes: map[string]string{}}
+			if tt.repoJSON != "" {
+				api.routes["/repos/org/repo"] = tt.repoJSON
+			}
+			s := newTestGitHub(t, api, nil)
+			s.webURL = web
+
+			pages, err := s.GetWikiPages(context.Background(), "org", "repo", tt.lastCommit)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("GetWikiPages() succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, page := range pages {
+				got = append(got, page.FilePath+" "+page.ChangeType)
+				if page.Source != SourceWiki || page.Repository != "org/repo.wiki" {
+					t.Errorf("%s is a %q page of %s, want a wiki page of org/repo.wiki", page.FilePath, page.Source, page.Repository)
+				}
+				if page.CommitSHA != shas[1] {
+					t.Errorf("%s at %s, want %s", page.FilePath, page.CommitSHA, shas[1])
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// Changes to projects, file records, and repository sync states are
+// recorded in audit_log in the transaction that makes them, with who made
+// them and the row before and after. Batches of file records, which syncs
+// write by the thousand, are also summed up in one entry per project.
+
+// Audit actions
+const (
+	auditCreate = "create"
+	auditUpdate = "update"
+	auditDelete = "delete"
+	auditSave   = "save"   // a batch of file records
+	auditImport = "import" // file records of an import
+	auditPurge  = "purge"  // tombstones a purge removed
+)
+
+// Audited entities
+const (
+	auditProject   = "project"
+	auditRecord    = "sync_metadata"
+	auditRepoState = "repo_sync_state"
+	auditBatch     = "sync_batch"
+)
+
+// anonymousActor is the actor of changes made while no API keys are
+// configured, so callers can't be told apart
+const anonymousActor = "anonymous"
+
+// AuditEntry is a recorded change. OldValue and NewValue are the changed
+// row as a JSON object, missing for a create and a delete respectively; a
+// batch summary has only a NewValue, counting its records by repository.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityKey string          `json:"entity_key"`
+	ProjectID string          `json:"project_id,omitempty"`
+	OldValue  json.RawMessage `json:"old_value,omitempty"`
+	NewValue  json.RawMessage `json:"new_value,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type actorKey struct{}
+
+// withActor returns ctx carrying the actor its changes are audited under
+func withActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
+
+// requestCaller returns the ID in keys (caller ID to API key) of the key r
+// sends in X-API-Key or as a bearer token, or false if it sends none of them
+func requestCaller(r *http.Request, keys map[string]string) (string, bool) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			key = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+		}
+	}
+	if key == "" {
+		return "", false
+	}
+	for id, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// withRequestActor authenticates each request against keys (caller ID to
+// API key) and audits its changes under the caller's ID. Without keys every
+// request is let through as anonymous; with them, requests other than
+// /health are rejected unless they send one of the keys.
+func withRequestActor(keys map[string]string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := anonymousActor
+		if len(keys) > 0 && r.URL.Path != "/health" {
+			id, ok := requestCaller(r, keys)
+			if !ok {
+				http.Error(w, "a valid API key is required", http.StatusUnauthorized)
+				return
+			}
+			actor = id
+		}
+		next.ServeHTTP(w, r.WithContext(withActor(r.Context(), actor)))
+	})
+}
+
+// auditTarget is the row a change is made to
+type auditTarget struct {
+	entity    string
+	key       string
+	projectID string
+	table     string
+	where     string
+	args      []interface{}
+}
+
+func projectTarget(id string) auditTarget {
+	return auditTarget{entity: auditProject, key: id, projectID: id,
+		table: "projects", where: "id = ?", args: []interface{}{id}}
+}
+
+func recordTarget(projectID, repository, filePath string) auditTarget {
+	return auditTarget{entity: auditRecord, key: repository + "/" + filePath, projectID: projectID,
+		table: "sync_metadata", where: "project_id = ? AND repository = ? AND file_path = ?",
+		args: []interface{}{projectID, repository, filePath}}
+}
+
+func repoStateTarget(projectID, repository string) auditTarget {
+	return auditTarget{entity: auditRepoState, key: repository, projectID: projectID,
+		table: "repo_sync_state", where: "project_id = ? AND repository = ?",
+		args: []interface{}{projectID, repository}}
+}
+
+// audited runs write within tx and records the change it makes to target's
+// row; a write that changes nothing, like deleting a missing row, isn't
+// recorded
+func audited(ctx context.Context, tx *sql.Tx, target auditTarget, write func() error) error {
+	before, err := auditSnapshot(ctx, tx, target)
+	if err != nil {
+		return err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	after, err := auditSnapshot(ctx, tx, target)
+	if err != nil {
+		return err
+	}
+
+	action := auditUpdate
+	switch {
+	case before == nil && after == nil, bytes.Equal(before, after):
+		return nil
+	case before == nil:
+		action = auditCreate
+	case after == nil:
+		action = auditDelete
+	}
+	return writeAudit(ctx, tx, &AuditEntry{
+		Action: action, Entity: target.entity, EntityKey: target.key, ProjectID: target.projectID,
+		OldValue: before, NewValue: after,
+	})
+}
+
+// auditSnapshot reads target's row as a JSON object of its columns, or nil
+// if there is none
+func auditSnapshot(ctx context.Context, tx *sql.Tx, target auditTarget) (json.RawMessage, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT * FROM `+target.table+` WHERE `+target.where, target.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		switch v := values[i].(type) {
+		case []byte:
+			row[column] = string(v)
+		case time.Time:
+			row[column] = v.UTC()
+		default:
+			row[column] = v
+		}
+	}
+	return json.Marshal(row)
+}
+
+// auditRecords sums up a batch of file records, whose changes are audited
+// one by one, in one entry per project counting them by repository
+func auditRecords(ctx context.Context, tx *sql.Tx, action string, records []*models.SyncMetadata) error {
+	var projects []string
+	counts := make(map[string]map[string]int)
+	for _, m := range records {
+		if counts[m.ProjectID] == nil {
+			counts[m.ProjectID] = make(map[string]int)
+			projects = append(projects, m.ProjectID)
+		}
+		counts[m.ProjectID][m.Repository]++
+	}
+
+	for _, projectID := range projects {
+		total := 0
+		for _, n := range counts[projectID] {
+			total += n
+		}
+		value, err := json.Marshal(map[string]interface{}{"records": total, "repositories": counts[projectID]})
+		if err != nil {
+			return err
+		}
+		err = writeAudit(ctx, tx, &AuditEntry{
+			Action: action, Entity: auditBatch, EntityKey: projectID, ProjectID: projectID, NewValue: value,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAudit inserts entry within tx as made by the actor of ctx, now
+func writeAudit(ctx context.Context, tx *sql.Tx, entry *AuditEntry) error {
+	entry.Actor = actorFrom(ctx)
+	entry.CreatedAt = time.Now().UTC()
+	_, err := tx.ExecContext(ctx, `INSERT INTO audit_log (actor, action, entity, entity_key, project_id, old_value, new_value, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Actor, entry.Action, entry.Entity, entry.EntityKey, entry.ProjectID,
+		nullJSON(entry.OldValue), nullJSON(entry.NewValue), entry.CreatedAt)
+	return err
+}
+
+func nullJSON(value json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(value), Valid: value != nil}
+}
+
+// AuditFilter selects audit entries; zero fields match everything
+type AuditFilter struct {
+	ProjectID string
+	Entity    string
+	Actor     string
+	Since     time.Time // only entries made at or after this
+	AfterID   int64     // only entries with a greater id, the cursor of a page
+	Limit     int       // at most this many entries, defaultAuditLimit if 0
+}
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// ListAudit returns the audit entries matching filter, oldest first
+func (s *MetadataService) ListAudit(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	query := `SELECT id, actor, action, entity, entity_key, project_id, old_value, new_value, created_at
+		FROM audit_log WHERE id > ?`
+	args := []interface{}{filter.AfterID}
+	if filter.ProjectID != "" {
+		query += ` AND project_id = ?`
+		args = append(args, filter.ProjectID)
+	}
+	if filter.Entity != "" {
+		query += ` AND entity = ?`
+		args = append(args, filter.Entity)
+	}
+	if filter.Actor != "" {
+		query += ` AND actor = ?`
+		args = append(args, filter.Actor)
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND created_at >= ?`
+		args = append(args, filter.Since.UTC())
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	query += ` ORDER BY id LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Database("failed to list audit entries", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	entries := []*AuditEntry{}
+	for rows.Next() {
+		var (
+			entry              AuditEntry
+			oldValue, newValue sql.NullString
+		)
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Entity, &entry.EntityKey, &entry.ProjectID,
+			&oldValue, &newValue, &entry.CreatedAt); err != nil {
+			return nil, errors.Database("failed to scan audit entry", err)
+		}
+		if oldValue.Valid {
+			entry.OldValue = json.RawMessage(oldValue.String)
+		}
+		if newValue.Valid {
+			entry.NewValue = json.RawMessage(newValue.String)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Database("failed to list audit entries", err)
+	}
+	return entries, nil
+}
+
+// handleAudit lists audit entries, filtered by project_id, entity, actor,
+// and since (RFC 3339), a page of limit at a time after cursor
+func (s *MetadataService) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := AuditFilter{
+		ProjectID: query.Get("project_id"), Entity: query.Get("entity"), Actor: query.Get("actor"),
+		Limit: defaultAuditLimit,
+	}
+	if v := query.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "since must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		filter.Since = since
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		if limit > maxAuditLimit {
+			limit = maxAuditLimit
+		}
+		filter.Limit = limit
+	}
+	if v := query.Get("cursor"); v != "" {
+		after, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || after < 0 {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		filter.AfterID = after
+	}
+
+	entries, err := s.ListAudit(r.Context(), filter)
+	if err != nil {
+		logger.Error("Failed to list audit entries: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(entries) == filter.Limit {
+		w.Header().Set("X-Next-Cursor", strconv.FormatInt(entries[len(entries)-1].ID, 10))
+	}
+	_ = json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	s := newTestService(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/projects", s.handleProjects)
+	mux.HandleFunc("/metadata", s.handleMetadata)
+	mux.HandleFunc("/metadata/bulk", s.handleBulkMetadata)
+	mux.HandleFunc("/audit", s.handleAudit)
+	handler := withRequestActor(map[string]string{"admin": "admin-secret", "sync": "sync-secret"}, mux)
+
+	do := func(method, target, key, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code >= 300 {
+			t.Fatalf("%s %s: status = %d (%s)", method, target, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+	audit := func(query string) []*AuditEntry {
+		t.Helper()
+		var entries []*AuditEntry
+		if err := json.NewDecoder(do(http.MethodGet, "/audit?"+query, "admin-secret", "").Body).Decode(&entries); err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+
+	do(http.MethodPost, "/projects", "admin-secret", `{"id":"docs","name":"Docs"}`)
+	do(http.MethodPut, "/projects?id=docs", "admin-secret", `{"name":"Docs v2"}`)
+	do(http.MethodPost, "/metadata", "sync-secret", `{"project_id":"docs","repository":"org/a","file_path":"a.md","last_commit_sha":"s1"}`)
+	do(http.MethodPost, "/metadata/bulk", "sync-secret", `[
+		{"project_id":"docs","repository":"org/a","file_path":"b.md","last_commit_sha":"s1"},
+		{"project_id":"docs","repository":"org/b","file_path":"c.md","last_commit_sha":"s1"}]`)
+	do(http.MethodDelete, "/metadata?project_id=docs&repository=org/a&file_path=a.md", "sync-secret", "")
+	do(http.MethodDelete, "/projects?id=docs", "admin-secret", "")
+
+	entries := audit("project_id=docs")
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Action+" "+e.Entity)
+	}
+	want := []string{"create project", "update project", "create sync_metadata",
+		"create sync_metadata", "create sync_metadata", "save sync_batch", "delete sync_metadata", "delete project"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("audit entries = %v, want %v", got, want)
+	}
+
+	var actors []string
+	for _, e := range entries {
+		actors = append(actors, e.Actor)
+	}
+	if want := "admin admin sync sync sync sync sync admin"; strings.Join(actors, " ") != want {
+		t.Errorf("actors = %v, want %s", actors, want)
+	}
+
+	var before, after map[string]interface{}
+	if err := json.Unmarshal(entries[1].OldValue, &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(entries[1].NewValue, &after); err != nil {
+		t.Fatal(err)
+	}
+	if before["name"] != "Docs" || after["name"] != "Docs v2" {
+		t.Errorf("project update from %v to %v, want name Docs to Docs v2", before["name"], after["name"])
+	}
+	if entries[0].OldValue != nil || entries[7].NewValue != nil {
+		t.Errorf("create has old value %s, delete has new value %s; want neither", entries[0].OldValue, entries[7].NewValue)
+	}
+	if entries[3].EntityKey != "org/a/b.md" || entries[4].EntityKey != "org/b/c.md" || entries[4].NewValue == nil {
+		t.Errorf("batch records audited as %s and %s, want each with its new row", entries[3].EntityKey, entries[4].EntityKey)
+	}
+	if !strings.Contains(string(entries[5].NewValue), `"records":2`) {
+		t.Errorf("batch entry = %s, want its record count", entries[5].NewValue)
+	}
+
+	// The keys themselves are never recorded
+	rows, err := s.db.Query(`SELECT actor, COALESCE(old_value, ''), COALESCE(new_value, '') FROM audit_log`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var actor, oldValue, newValue string
+		if err := rows.Scan(&actor, &oldValue, &newValue); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(actor+oldValue+newValue, "secret") {
+			t.Errorf("audit row %s %s %s contains an API key", actor, oldValue, newValue)
+		}
+	}
+
+	if got := audit("project_id=docs&entity=project&actor=admin"); len(got) != 3 {
+		t.Errorf("%d project entries by the admin key, want 3", len(got))
+	}
+	page := do(http.MethodGet, "/audit?project_id=docs&limit=6", "admin-secret", "")
+	cursor := page.Header().Get("X-Next-Cursor")
+	if rest := audit("project_id=docs&cursor=" + cursor); cursor == "" || len(rest) != 2 || rest[0].Entity != auditRecord {
+		t.Errorf("page after cursor %q = %d entries, want the last 2", cursor, len(rest))
+	}
+	// A batch spanning projects is summed up once per project
+	do(http.MethodPost, "/metadata/bulk", "sync-secret", `[
+		{"project_id":"docs","repository":"org/a","file_path":"d.md","last_commit_sha":"s2"},
+		{"project_id":"wiki","repository":"org/w","file_path":"e.md","last_commit_sha":"s2"},
+		{"project_id":"wiki","repository":"org/w","file_path":"f.md","last_commit_sha":"s2"}]`)
+	for project, want := range map[string]string{"docs": `"records":1`, "wiki": `"records":2`} {
+		batches := audit("project_id=" + project + "&entity=sync_batch&cursor=" + cursor)
+		if len(batches) != 1 || !strings.Contains(string(batches[0].NewValue), want) {
+			t.Errorf("%s batch entries = %d, want one with %s", project, len(batches), want)
+		}
+	}
+
+	if got := audit("since=2999-01-01T00:00:00Z"); len(got) != 0 {
+		t.Errorf("%d entries in the future, want none", len(got))
+	}
+}
+
+func TestRequestAuthentication(t *testing.T) {
+	var actor string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { actor = actorFrom(r.Context()) })
+	keys := map[string]string{"orchestrator": "sync-secret"}
+
+	tests := []struct {
+		name      string
+		keys      map[string]string
+		path      string
+		header    string
+		value     string
+		wantCode  int
+		wantActor string
+	}{
+		{name: "known key", keys: keys, path: "/projects", header: "X-API-Key", value: "sync-secret", wantCode: http.StatusOK, wantActor: "orchestrator"},
+		{name: "bearer token", keys: keys, path: "/projects", header: "Authorization", value: "Bearer sync-secret", wantCode: http.StatusOK, wantActor: "orchestrator"},
+		{name: "unknown key", keys: keys, path: "/projects", header: "X-API-Key", value: "guess", wantCode: http.StatusUnauthorized},
+		{name: "no key", keys: keys, path: "/metadata", wantCode: http.StatusUnauthorized},
+		{name: "health needs no key", keys: keys, path: "/health", wantCode: http.StatusOK, wantActor: anonymousActor},
+		{name: "no keys configured", path: "/projects", header: "X-API-Key", value: "anything", wantCode: http.StatusOK, wantActor: anonymousActor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actor = ""
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			withRequestActor(tt.keys, next).ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode || actor != tt.wantActor {
+				t.Errorf("status %d as %q, want %d as %q", rec.Code, actor, tt.wantCode, tt.wantActor)
+			}
+		})
+	}
+}
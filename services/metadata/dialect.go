@@ -0,0 +1,356 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Metadata database drivers, selected with METADATA_DB_DRIVER
+const (
+	DriverSQLite = "sqlite"
+	DriverMySQL  = "mysql"
+)
+
+// dialect holds the SQL that differs between the supported databases; the
+// queries not listed here are shared
+type dialect struct {
+	driverName string
+
+	// schema statements, run in order on startup
+	schema []string
+
+	// saveSyncMetadata, saveProject, and saveRepoState insert a row or
+	// update the one with the same key
+	saveSyncMetadata string
+	saveProject      string
+	saveRepoState    string
+
+	// columns lists the column names of the table given as the only argument
+	columns func(db *sql.DB, table string) ([]string, error)
+}
+
+// dialectFor returns the dialect of a METADATA_DB_DRIVER value
+func dialectFor(driver string) (*dialect, error) {
+	switch driver {
+	case DriverSQLite, "sqlite3":
+		return sqliteDialect, nil
+	case DriverMySQL, "mariadb":
+		return mysqlDialect, nil
+	}
+	return nil, fmt.Errorf("unknown metadata database driver %q (available: %s, %s)", driver, DriverSQLite, DriverMySQL)
+}
+
+// openDB opens the metadata database at dsn, a file path for SQLite or a
+// data source name for MySQL
+func openDB(d *dialect, dsn string) (*sql.DB, error) {
+	switch d {
+	case sqliteDialect:
+		// Ensure data directory exists
+		if err := os.MkdirAll(filepath.Dir(dsn), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+		dsn = sqliteDSN(dsn)
+	case mysqlDialect:
+		var err error
+		if dsn, err = mysqlDSN(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return sql.Open(d.driverName, dsn)
+}
+
+var sqliteDialect = &dialect{
+	driverName: "sqlite3",
+	schema: []string{`
+	CREATE TABLE IF NOT EXISTS sync_metadata (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id TEXT NOT NULL,
+		repository TEXT NOT NULL,
+		file_path TEXT NOT NULL,
+		last_commit_sha TEXT NOT NULL,
+		last_synced_at DATETIME NOT NULL,
+		embedding_count INTEGER DEFAULT 0,
+		status TEXT DEFAULT 'synced',
+		blob_sha TEXT DEFAULT '',
+		UNIQUE(project_id, repository, file_path)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_sync_project ON sync_metadata(project_id);
+	CREATE INDEX IF NOT EXISTS idx_sync_repo ON sync_metadata(repository);
+
+	CREATE TABLE IF NOT EXISTS projects (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		organization TEXT NOT NULL,
+		filter_keyword TEXT,
+		namespace TEXT NOT NULL,
+		enabled BOOLEAN DEFAULT 1,
+		allowed_extensions TEXT,
+		exclude_patterns TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS file_vectors (
+		sync_id INTEGER NOT NULL,
+		chunk_index INTEGER NOT NULL,
+		vector_id TEXT NOT NULL,
+		PRIMARY KEY (sync_id, chunk_index)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_file_vectors_vector ON file_vectors(vector_id);
+
+	CREATE TABLE IF NOT EXISTS repo_sync_state (
+		project_id TEXT NOT NULL,
+		repository TEXT NOT NULL,
+		last_commit_sha TEXT NOT NULL,
+		last_synced_at DATETIME NOT NULL,
+		PRIMARY KEY (project_id, repository)
+	);
+
+	CREATE TABLE IF NOT EXISTS sync_jobs (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		status TEXT NOT NULL,
+		priority INTEGER NOT NULL DEFAULT 0,
+		checkpoint TEXT,
+		error TEXT,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		started_at DATETIME,
+		finished_at DATETIME
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_sync_jobs_queue ON sync_jobs(status, priority, created_at);
+	CREATE INDEX IF NOT EXISTS idx_sync_jobs_project ON sync_jobs(project_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		entity TEXT NOT NULL,
+		entity_key TEXT NOT NULL,
+		project_id TEXT NOT NULL DEFAULT '',
+		old_value TEXT,
+		new_value TEXT,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_project ON audit_log(project_id, id);
+	`},
+	saveSyncMetadata: `
+		INSERT INTO sync_metadata (project_id, repository, file_path, last_commit_sha, last_synced_at, embedding_count, status, blob_sha, embedding_model)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(project_id, repository, file_path) DO UPDATE SET
+			last_commit_sha = excluded.last_commit_sha,
+			last_synced_at = excluded.last_synced_at,
+			embedding_count = excluded.embedding_count,
+			status = excluded.status,
+			blob_sha = excluded.blob_sha,
+			embedding_model = COALESCE(NULLIF(excluded.embedding_model, ''), sync_metadata.embedding_model),
+			version = sync_metadata.version + 1
+	`,
+	saveProject: `
+		INSERT INTO projects (id, name, organization, filter_keyword, namespace, enabled, allowed_extensions, exclude_patterns, chunk_strategies, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			organization = excluded.organization,
+			filter_keyword = excluded.filter_keyword,
+			namespace = excluded.namespace,
+			enabled = excluded.enabled,
+			allowed_extensions = excluded.allowed_extensions,
+			exclude_patterns = excluded.exclude_patterns,
+			chunk_strategies = excluded.chunk_strategies,
+			updated_at = excluded.updated_at
+	`,
+	saveRepoState: `
+		INSERT INTO repo_sync_state (project_id, repository, last_commit_sha, last_synced_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(project_id, repository) DO UPDATE SET
+			last_commit_sha = excluded.last_commit_sha,
+			last_synced_at = excluded.last_synced_at,
+			version = repo_sync_state.version + 1
+	`,
+	columns: sqliteColumns,
+}
+
+// sqliteColumns lists a table's columns with PRAGMA table_info
+func sqliteColumns(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var columns []string
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+// The MySQL schema creates every column up front. Key columns are VARCHARs
+// sized so the unique (project_id, repository, file_path) key stays within
+// InnoDB's 3072-byte index limit in utf8mb4.
+var mysqlDialect = &dialect{
+	driverName: "mysql",
+	schema: []string{`
+	CREATE TABLE IF NOT EXISTS sync_metadata (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		project_id VARCHAR(64) NOT NULL,
+		repository VARCHAR(191) NOT NULL,
+		file_path VARCHAR(512) NOT NULL,
+		last_commit_sha VARCHAR(64) NOT NULL,
+		last_synced_at DATETIME(6) NOT NULL,
+		embedding_count INT DEFAULT 0,
+		status VARCHAR(32) DEFAULT 'synced',
+		blob_sha VARCHAR(64) DEFAULT '',
+		embedding_model VARCHAR(255) DEFAULT '',
+		version BIGINT NOT NULL DEFAULT 1,
+		UNIQUE KEY uniq_sync_file (project_id, repository, file_path),
+		KEY idx_sync_project (project_id),
+		KEY idx_sync_repo (repository)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, `
+	CREATE TABLE IF NOT EXISTS projects (
+		id VARCHAR(64) PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		organization VARCHAR(255) NOT NULL,
+		filter_keyword VARCHAR(255),
+		namespace VARCHAR(255) NOT NULL,
+		enabled BOOLEAN DEFAULT TRUE,
+		allowed_extensions TEXT,
+		exclude_patterns TEXT,
+		chunk_strategies TEXT,
+		created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+		updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, `
+	CREATE TABLE IF NOT EXISTS file_vectors (
+		sync_id BIGINT NOT NULL,
+		chunk_index INT NOT NULL,
+		vector_id VARCHAR(255) NOT NULL,
+		PRIMARY KEY (sync_id, chunk_index),
+		KEY idx_file_vectors_vector (vector_id)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, `
+	CREATE TABLE IF NOT EXISTS repo_sync_state (
+		project_id VARCHAR(64) NOT NULL,
+		repository VARCHAR(191) NOT NULL,
+		last_commit_sha VARCHAR(64) NOT NULL,
+		last_synced_at DATETIME(6) NOT NULL,
+		version BIGINT NOT NULL DEFAULT 1,
+		PRIMARY KEY (project_id, repository)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, `
+	CREATE TABLE IF NOT EXISTS sync_jobs (
+		id VARCHAR(32) PRIMARY KEY,
+		project_id VARCHAR(64) NOT NULL,
+		status VARCHAR(16) NOT NULL,
+		priority INT NOT NULL DEFAULT 0,
+		checkpoint MEDIUMTEXT,
+		error TEXT,
+		created_at DATETIME(6) NOT NULL,
+		updated_at DATETIME(6) NOT NULL,
+		started_at DATETIME(6),
+		finished_at DATETIME(6),
+		KEY idx_sync_jobs_queue (status, priority, created_at),
+		KEY idx_sync_jobs_project (project_id, created_at)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		actor VARCHAR(64) NOT NULL,
+		action VARCHAR(16) NOT NULL,
+		entity VARCHAR(32) NOT NULL,
+		entity_key TEXT NOT NULL,
+		project_id VARCHAR(64) NOT NULL DEFAULT '',
+		old_value MEDIUMTEXT,
+		new_value MEDIUMTEXT,
+		created_at DATETIME(6) NOT NULL,
+		KEY idx_audit_project (project_id, id)
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+	},
+	// VALUES() rather than a row alias, which MariaDB lacks
+	saveSyncMetadata: `
+		INSERT INTO sync_metadata (project_id, repository, file_path, last_commit_sha, last_synced_at, embedding_count, status, blob_sha, embedding_model)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			last_commit_sha = VALUES(last_commit_sha),
+			last_synced_at = VALUES(last_synced_at),
+			embedding_count = VALUES(embedding_count),
+			status = VALUES(status),
+			blob_sha = VALUES(blob_sha),
+			embedding_model = COALESCE(NULLIF(VALUES(embedding_model), ''), embedding_model),
+			version = version + 1
+	`,
+	saveProject: `
+		INSERT INTO projects (id, name, organization, filter_keyword, namespace, enabled, allowed_extensions, exclude_patterns, chunk_strategies, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			name = VALUES(name),
+			organization = VALUES(organization),
+			filter_keyword = VALUES(filter_keyword),
+			namespace = VALUES(namespace),
+			enabled = VALUES(enabled),
+			allowed_extensions = VALUES(allowed_extensions),
+			exclude_patterns = VALUES(exclude_patterns),
+			chunk_strategies = VALUES(chunk_strategies),
+			updated_at = VALUES(updated_at)
+	`,
+	saveRepoState: `
+		INSERT INTO repo_sync_state (project_id, repository, last_commit_sha, last_synced_at)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			last_commit_sha = VALUES(last_commit_sha),
+			last_synced_at = VALUES(last_synced_at),
+			version = version + 1
+	`,
+	columns: mysqlColumns,
+}
+
+// mysqlColumns lists a table's columns in the current database
+func mysqlColumns(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query(`SELECT column_name FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ?`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+// mysqlDSN checks a MySQL data source name and turns on parseTime, which
+// DATETIME columns need to scan into time.Time; times are kept in UTC
+func mysqlDSN(dsn string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid MySQL DSN: %w", err)
+	}
+	if cfg.DBName == "" {
+		return "", fmt.Errorf("invalid MySQL DSN: no database name")
+	}
+	cfg.ParseTime = true
+	return cfg.FormatDSN(), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMySQLDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		want    []string // substrings of the normalized DSN
+		wantErr bool
+	}{
+		{
+			name: "parseTime turned on",
+			dsn:  "reposync:secret@tcp(db:3306)/reposync",
+			want: []string{"reposync:secret@tcp(db:3306)/reposync", "parseTime=true"},
+		},
+		{
+			name: "options kept",
+			dsn:  "reposync:secret@tcp(db:3306)/reposync?timeout=5s&parseTime=false",
+			want: []string{"timeout=5s", "parseTime=true"},
+		},
+		{name: "no database", dsn: "reposync:secret@tcp(db:3306)/", wantErr: true},
+		{name: "malformed", dsn: "reposync:secret@db:3306/reposync", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mysqlDSN(tt.dsn)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("mysqlDSN(%q) = %q, want error", tt.dsn, got)
+				}
+				if strings.Contains(err.Error(), "secret") {
+					t.Errorf("error %q leaks the password", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("mysqlDSN(%q) = %q, want it to contain %q", tt.dsn, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDialectFor(t *testing.T) {
+	for driver, want := range map[string]*dialect{
+		"sqlite":  sqliteDialect,
+		"sqlite3": sqliteDialect,
+		"mysql":   mysqlDialect,
+		"mariadb": mysqlDialect,
+	} {
+		got, err := dialectFor(driver)
+		if err != nil || got != want {
+			t.Errorf("dialectFor(%q) = %v, %v, want the %s dialect", driver, got, err, want.driverName)
+		}
+	}
+	if _, err := dialectFor("postgres"); err == nil {
+		t.Error("dialectFor(postgres) succeeded, want an unknown driver error")
+	}
+}
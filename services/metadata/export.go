@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// exportVersion is the format version written to exports; imports reject
+// newer ones
+const exportVersion = 1
+
+// MetadataExport is a project's settings and sync records, as exported by
+// /export and loaded by /import
+type MetadataExport struct {
+	Version    int                    `json:"version"`
+	ExportedAt time.Time              `json:"exported_at"`
+	ProjectID  string                 `json:"project_id"`
+	Project    *models.Project        `json:"project,omitempty"` // nil for records of an unregistered project
+	Records    []*models.SyncMetadata `json:"records"`
+}
+
+// ExportMetadata returns a project's settings and every sync record of it,
+// or a NOT_FOUND error if there are neither
+func (s *MetadataService) ExportMetadata(ctx context.Context, projectID string) (*MetadataExport, error) {
+	project, err := s.GetProject(ctx, projectID)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+	records, err := s.ListSyncMetadata(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil && len(records) == 0 {
+		return nil, errors.NotFound("project")
+	}
+	if records == nil {
+		records = []*models.SyncMetadata{}
+	}
+
+	return &MetadataExport{
+		Version:    exportVersion,
+		ExportedAt: time.Now().UTC(),
+		ProjectID:  projectID,
+		Project:    project,
+		Records:    records,
+	}, nil
+}
+
+// ImportMetadata saves an export's project and records in one transaction,
+// overwriting the project's settings and the records of the same files
+func (s *MetadataService) ImportMetadata(ctx context.Context, export *MetadataExport) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if export.Project != nil {
+			if err := s.writeProject(ctx, tx, export.Project); err != nil {
+				return err
+			}
+		}
+		for _, m := range export.Records {
+			m.Version = 0 // overwrite whatever version is stored
+			err := audited(ctx, tx, recordTarget(m.ProjectID, m.Repository, m.FilePath), func() error {
+				return s.saveSyncMetadata(ctx, tx, m)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return auditRecords(ctx, tx, auditImport, export.Records)
+	})
+	if err != nil {
+		return dbError("failed to import metadata", err)
+	}
+	return nil
+}
+
+// handleExport returns ?project_id='s settings and sync records as a JSON
+// file, for backups and moving a project to another environment
+func (s *MetadataService) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectID := r.URL.Query().Get("project_id")
+	if projectID == "" {
+		http.Error(w, "project_id is required", http.StatusBadRequest)
+		return
+	}
+
+	export, err := s.ExportMetadata(r.Context(), projectID)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to export metadata of project %s: %v", projectID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="metadata-%s.json"`, projectID))
+	_ = json.NewEncoder(w).Encode(export)
+	logger.Info("Exported project %s with %d sync records", projectID, len(export.Records))
+}
+
+// handleImport loads a file made by /export, all of it or nothing. With
+// ?project_id= the project and its records are imported under that id,
+// e.g. to copy a project within one environment.
+func (s *MetadataService) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var export MetadataExport
+	if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if export.Version < 1 || export.Version > exportVersion {
+		http.Error(w, fmt.Sprintf("unsupported export version %d", export.Version), http.StatusBadRequest)
+		return
+	}
+
+	projectID := export.ProjectID
+	if id := r.URL.Query().Get("project_id"); id != "" {
+		projectID = id
+	}
+	if projectID == "" {
+		http.Error(w, "project_id is required", http.StatusBadRequest)
+		return
+	}
+	export.ProjectID = projectID
+
+	if export.Project != nil {
+		export.Project.ID = projectID
+		if err := validateProject(export.Project); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	for i, metadata := range export.Records {
+		if metadata == nil {
+			http.Error(w, fmt.Sprintf("record %d: null", i), http.StatusBadRequest)
+			return
+		}
+		metadata.ProjectID = projectID
+		if err := prepareMetadata(metadata); err != nil {
+			http.Error(w, fmt.Sprintf("record %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := s.ImportMetadata(r.Context(), &export); err != nil {
+		logger.Error("Failed to import metadata of project %s: %v", projectID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Imported project %s with %d sync records", projectID, len(export.Records))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "imported",
+		"project_id": projectID,
+		"project":    export.Project != nil,
+		"records":    len(export.Records),
+	})
+}
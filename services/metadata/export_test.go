@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestExportImport(t *testing.T) {
+	src := newTestService(t)
+	ctx := context.Background()
+	project := &models.Project{
+		ID: "docs", Name: "Docs", Organization: "org", Namespace: "docs", Enabled: true,
+		AllowedExtensions: []string{".md"}, ChunkStrategies: map[string]string{".md": "markdown"},
+	}
+	if err := src.SaveProject(ctx, project); err != nil {
+		t.Fatal(err)
+	}
+	synced := time.Date(2026, 5, 6, 7, 8, 9, 0, time.UTC)
+	for _, file := range []string{"a.md", "b.md"} {
+		err := src.SaveSyncMetadata(ctx, &models.SyncMetadata{
+			ProjectID: "docs", Repository: "org/repo", FilePath: file, LastCommitSHA: "sha",
+			LastSyncedAt: synced, EmbeddingCount: 2, Status: "synced", ChunkIDs: []string{file + "#0", file + "#1"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	src.handleExport(rec, httptest.NewRequest(http.MethodGet, "/export?project_id=docs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: status = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+	dump := rec.Body.Bytes()
+
+	// Import into another environment under a new id
+	dst := newTestService(t)
+	rec = httptest.NewRecorder()
+	dst.handleImport(rec, httptest.NewRequest(http.MethodPost, "/import?project_id=docs-staging", bytes.NewReader(dump)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import: status = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+
+	got, err := dst.GetProject(ctx, "docs-staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Docs" || !reflect.DeepEqual(got.AllowedExtensions, project.AllowedExtensions) ||
+		!reflect.DeepEqual(got.ChunkStrategies, project.ChunkStrategies) {
+		t.Errorf("imported project = %+v, want the settings of %+v", got, project)
+	}
+	records, err := dst.ListSyncMetadata(ctx, "docs-staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("%d imported records, want 2", len(records))
+	}
+	for _, r := range records {
+		if !r.LastSyncedAt.Equal(synced) || r.EmbeddingCount != 2 || len(r.ChunkIDs) != 2 {
+			t.Errorf("imported record = %+v, want the exported sync time, counts, and chunk IDs", r)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{name: "export an unknown project", method: http.MethodGet, target: "/export?project_id=none", wantStatus: http.StatusNotFound},
+		{name: "export without project_id", method: http.MethodGet, target: "/export", wantStatus: http.StatusBadRequest},
+		{name: "import a newer version", method: http.MethodPost, target: "/import", body: `{"version":99,"project_id":"x"}`, wantStatus: http.StatusBadRequest},
+		{name: "import an invalid record", method: http.MethodPost, target: "/import", body: `{"version":1,"project_id":"x","records":[{"repository":"org/repo"}]}`, wantStatus: http.StatusBadRequest},
+		{name: "import records without a project", method: http.MethodPost, target: "/import", body: `{"version":1,"project_id":"x","records":[{"repository":"org/repo","file_path":"a.md"}]}`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.method == http.MethodGet {
+				dst.handleExport(rec, req)
+			} else {
+				dst.handleImport(rec, req)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d (%s), want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+		})
+	}
+}
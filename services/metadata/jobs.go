@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// Sync jobs are kept here rather than in the orchestrator so that its queue
+// survives restarts, a run interrupted part way can resume from its
+// checkpoint, and finished runs stay available as history.
+
+// defaultJobListLimit is the number of jobs GET /jobs returns without limit
+const defaultJobListLimit = 100
+
+// syncJobColumns are the sync_jobs columns scanSyncJob reads, in order
+const syncJobColumns = `id, project_id, status, priority, checkpoint, error, created_at, updated_at, started_at, finished_at`
+
+// SyncJobUpdate changes the fields of a sync job that are set
+type SyncJobUpdate struct {
+	Status     string          `json:"status,omitempty"`
+	Priority   *int            `json:"priority,omitempty"`
+	Checkpoint json.RawMessage `json:"checkpoint,omitempty"`
+	Error      *string         `json:"error,omitempty"`
+}
+
+// JobFilter narrows a job listing; zero fields don't filter
+type JobFilter struct {
+	ProjectID string
+	Status    string
+	Limit     int
+}
+
+// finishedJob reports whether a job in status has stopped running
+func finishedJob(status string) bool {
+	return status == models.SyncJobCompleted || status == models.SyncJobFailed || status == models.SyncJobCancelled
+}
+
+// validJobStatus reports whether status is one of the sync job states
+func validJobStatus(status string) bool {
+	return status == models.SyncJobPending || status == models.SyncJobRunning || finishedJob(status)
+}
+
+// newJobID returns a random job ID
+func newJobID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// CreateSyncJob queues a job with a new ID
+func (s *MetadataService) CreateSyncJob(ctx context.Context, job *models.SyncJob) error {
+	now := time.Now().UTC()
+	job.ID = newJobID()
+	job.Status = models.SyncJobPending
+	job.CreatedAt, job.UpdatedAt = now, now
+	job.StartedAt, job.FinishedAt = nil, nil
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		return writeSyncJob(ctx, tx, job, true)
+	})
+	if err != nil {
+		return dbError("failed to create sync job", err)
+	}
+	return nil
+}
+
+// GetSyncJob returns a job by ID
+func (s *MetadataService) GetSyncJob(ctx context.Context, id string) (*models.SyncJob, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+syncJobColumns+` FROM sync_jobs WHERE id = ?`, id)
+	job, err := scanSyncJob(row)
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("sync job")
+	}
+	if err != nil {
+		return nil, errors.Database("failed to get sync job", err)
+	}
+	return job, nil
+}
+
+// ListSyncJobs returns jobs newest first
+func (s *MetadataService) ListSyncJobs(ctx context.Context, filter JobFilter) ([]*models.SyncJob, error) {
+	query := `SELECT ` + syncJobColumns + ` FROM sync_jobs WHERE 1 = 1`
+	var args []interface{}
+	if filter.ProjectID != "" {
+		query += ` AND project_id = ?`
+		args = append(args, filter.ProjectID)
+	}
+	if filter.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+	query += ` ORDER BY created_at DESC, id`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Database("failed to list sync jobs", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var jobs []*models.SyncJob
+	for rows.Next() {
+		job, err := scanSyncJob(rows)
+		if err != nil {
+			return nil, errors.Database("failed to scan sync job", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Database("failed to list sync jobs", err)
+	}
+	return jobs, nil
+}
+
+// UpdateSyncJob applies update to a job and returns the result. Moving to
+// running records when the job started, and to a finished state when it
+// finished; moving back to pending, e.g. to retry a failed job, clears both.
+func (s *MetadataService) UpdateSyncJob(ctx context.Context, id string, update *SyncJobUpdate) (*models.SyncJob, error) {
+	var job *models.SyncJob
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		job, err = scanSyncJob(tx.QueryRowContext(ctx, `SELECT `+syncJobColumns+` FROM sync_jobs WHERE id = ?`, id))
+		if err == sql.ErrNoRows {
+			return errors.NotFound("sync job")
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		if update.Status != "" && update.Status != job.Status {
+			job.Status = update.Status
+			switch {
+			case job.Status == models.SyncJobPending:
+				job.StartedAt, job.FinishedAt = nil, nil
+			case job.Status == models.SyncJobRunning:
+				job.StartedAt, job.FinishedAt = &now, nil
+			case finishedJob(job.Status):
+				job.FinishedAt = &now
+			}
+		}
+		if update.Priority != nil {
+			job.Priority = *update.Priority
+		}
+		if update.Checkpoint != nil {
+			job.Checkpoint = update.Checkpoint
+		}
+		if update.Error != nil {
+			job.Error = *update.Error
+		}
+		job.UpdatedAt = now
+		return writeSyncJob(ctx, tx, job, false)
+	})
+	if err != nil {
+		return nil, dbError("failed to update sync job", err)
+	}
+	return job, nil
+}
+
+// ClaimSyncJob marks the pending job of highest priority, oldest first,
+// running and returns it, or nil if no job is pending. With projectID only
+// that project's jobs are considered.
+func (s *MetadataService) ClaimSyncJob(ctx context.Context, projectID string) (*models.SyncJob, error) {
+	query := `SELECT ` + syncJobColumns + ` FROM sync_jobs WHERE status = ?`
+	args := []interface{}{models.SyncJobPending}
+	if projectID != "" {
+		query += ` AND project_id = ?`
+		args = append(args, projectID)
+	}
+	query += ` ORDER BY priority DESC, created_at, id LIMIT 1`
+
+	// Another instance may claim the same job between the select and the
+	// update on MySQL; the update then changes nothing and the next pending
+	// job is tried
+	for attempt := 0; attempt < 3; attempt++ {
+		var job *models.SyncJob
+		none := false
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			candidate, err := scanSyncJob(tx.QueryRowContext(ctx, query, args...))
+			if err == sql.ErrNoRows {
+				none = true
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			now := time.Now().UTC()
+			result, err := tx.ExecContext(ctx, `UPDATE sync_jobs SET status = ?, started_at = ?, finished_at = NULL, updated_at = ?
+				WHERE id = ? AND status = ?`, models.SyncJobRunning, now, now, candidate.ID, models.SyncJobPending)
+			if err != nil {
+				return err
+			}
+			if n, err := result.RowsAffected(); err != nil || n == 0 {
+				return err
+			}
+			candidate.Status = models.SyncJobRunning
+			candidate.StartedAt, candidate.FinishedAt, candidate.UpdatedAt = &now, nil, now
+			job = candidate
+			return nil
+		})
+		if err != nil {
+			return nil, dbError("failed to claim sync job", err)
+		}
+		if job != nil || none {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// DeleteSyncJob removes a job from the store
+func (s *MetadataService) DeleteSyncJob(ctx context.Context, id string) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM sync_jobs WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return errors.NotFound("sync job")
+		}
+		return err
+	})
+	if err != nil {
+		return dbError("failed to delete sync job", err)
+	}
+	return nil
+}
+
+// writeSyncJob inserts a job, or with insert false updates the stored one
+func writeSyncJob(ctx context.Context, tx *sql.Tx, job *models.SyncJob, insert bool) error {
+	var checkpoint sql.NullString
+	if len(job.Checkpoint) > 0 {
+		checkpoint = sql.NullString{String: string(job.Checkpoint), Valid: true}
+	}
+	var startedAt, finishedAt sql.NullTime
+	if job.StartedAt != nil {
+		startedAt = sql.NullTime{Time: *job.StartedAt, Valid: true}
+	}
+	if job.FinishedAt != nil {
+		finishedAt = sql.NullTime{Time: *job.FinishedAt, Valid: true}
+	}
+
+	if insert {
+		_, err := tx.ExecContext(ctx, `INSERT INTO sync_jobs (`+syncJobColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			job.ID, job.ProjectID, job.Status, job.Priority, checkpoint, job.Error,
+			job.CreatedAt, job.UpdatedAt, startedAt, finishedAt)
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE sync_jobs SET status = ?, priority = ?, checkpoint = ?, error = ?,
+		updated_at = ?, started_at = ?, finished_at = ? WHERE id = ?`,
+		job.Status, job.Priority, checkpoint, job.Error, job.UpdatedAt, startedAt, finishedAt, job.ID)
+	return err
+}
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSyncJob reads a row of syncJobColumns
+func scanSyncJob(row rowScanner) (*models.SyncJob, error) {
+	var (
+		job                   models.SyncJob
+		checkpoint, jobError  sql.NullString
+		startedAt, finishedAt sql.NullTime
+	)
+	err := row.Scan(&job.ID, &job.ProjectID, &job.Status, &job.Priority, &checkpoint, &jobError,
+		&job.CreatedAt, &job.UpdatedAt, &startedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint.Valid && checkpoint.String != "" {
+		job.Checkpoint = json.RawMessage(checkpoint.String)
+	}
+	job.Error = jobError.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+// handleJobs queues (POST), reports (GET), updates (PUT), or removes
+// (DELETE) sync jobs
+func (s *MetadataService) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.getJobs(w, r)
+	case http.MethodPost:
+		s.createJob(w, r)
+	case http.MethodPut:
+		s.updateJob(w, r)
+	case http.MethodDelete:
+		s.deleteJob(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getJobs returns the job named by ?id=, or the newest jobs, optionally of
+// one project and status
+func (s *MetadataService) getJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var (
+		result interface{}
+		err    error
+	)
+	if id := query.Get("id"); id != "" {
+		result, err = s.GetSyncJob(r.Context(), id)
+	} else {
+		filter := JobFilter{ProjectID: query.Get("project_id"), Status: query.Get("status"), Limit: defaultJobListLimit}
+		if filter.Status != "" && !validJobStatus(filter.Status) {
+			http.Error(w, fmt.Sprintf("invalid status %q", filter.Status), http.StatusBadRequest)
+			return
+		}
+		if v := query.Get("limit"); v != "" {
+			limit, convErr := strconv.Atoi(v)
+			if convErr != nil || limit <= 0 {
+				http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			filter.Limit = min(limit, maxListLimit)
+		}
+		var jobs []*models.SyncJob
+		jobs, err = s.ListSyncJobs(r.Context(), filter)
+		if jobs == nil {
+			jobs = []*models.SyncJob{}
+		}
+		result = jobs
+	}
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to get sync jobs: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// createJob queues a job for the project_id, priority, and optional
+// checkpoint in the body
+func (s *MetadataService) createJob(w http.ResponseWriter, r *http.Request) {
+	var job models.SyncJob
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if job.ProjectID == "" {
+		http.Error(w, "project_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.CreateSyncJob(r.Context(), &job); err != nil {
+		logger.Error("Failed to create sync job: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Queued sync job %s for project %s", job.ID, job.ProjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs?id="+job.ID)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// updateJob applies the SyncJobUpdate in the body to the job named by ?id=
+func (s *MetadataService) updateJob(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	var update SyncJobUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if update.Status != "" && !validJobStatus(update.Status) {
+		http.Error(w, fmt.Sprintf("invalid status %q", update.Status), http.StatusBadRequest)
+		return
+	}
+
+	job, err := s.UpdateSyncJob(r.Context(), id, &update)
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to update sync job %s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// deleteJob removes the job named by ?id=
+func (s *MetadataService) deleteJob(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	if err := s.DeleteSyncJob(r.Context(), id); err != nil {
+		if isNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to delete sync job %s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "id": id})
+}
+
+// handleClaimJob hands the next pending job, optionally of ?project_id=, to
+// the caller as running; 204 if there is none
+func (s *MetadataService) handleClaimJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, err := s.ClaimSyncJob(r.Context(), r.URL.Query().Get("project_id"))
+	if err != nil {
+		logger.Error("Failed to claim sync job: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}
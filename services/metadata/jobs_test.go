@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestJobs(t *testing.T) {
+	s := newTestService(t)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler := s.handleJobs
+		if strings.HasPrefix(target, "/jobs/claim") {
+			handler = s.handleClaimJob
+		}
+		handler(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) *models.SyncJob {
+		t.Helper()
+		var job models.SyncJob
+		if err := json.NewDecoder(rec.Body).Decode(&job); err != nil {
+			t.Fatalf("decode job: %v (status %d)", err, rec.Code)
+		}
+		return &job
+	}
+
+	if rec := do(http.MethodPost, "/jobs", `{"priority":1}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("job without a project: status = %d, want 400", rec.Code)
+	}
+	low := decode(do(http.MethodPost, "/jobs", `{"project_id":"p"}`))
+	high := decode(do(http.MethodPost, "/jobs", `{"project_id":"p","priority":5}`))
+	if low.ID == "" || low.Status != models.SyncJobPending || low.CreatedAt.IsZero() {
+		t.Fatalf("created job = %+v, want an id, status pending, and a creation time", low)
+	}
+
+	// The higher priority job is claimed first
+	claimed := decode(do(http.MethodPost, "/jobs/claim", ""))
+	if claimed.ID != high.ID || claimed.Status != models.SyncJobRunning || claimed.StartedAt == nil {
+		t.Fatalf("claimed %+v, want job %s running", claimed, high.ID)
+	}
+
+	rec := do(http.MethodPut, "/jobs?id="+high.ID, `{"checkpoint":{"repository":"org/a","page":3}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("checkpoint: status = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+	got := decode(do(http.MethodGet, "/jobs?id="+high.ID, ""))
+	if string(got.Checkpoint) != `{"repository":"org/a","page":3}` || got.Status != models.SyncJobRunning {
+		t.Errorf("job after checkpoint = %+v", got)
+	}
+
+	finished := decode(do(http.MethodPut, "/jobs?id="+high.ID, `{"status":"failed","error":"timeout"}`))
+	if finished.FinishedAt == nil || finished.Error != "timeout" || finished.Checkpoint == nil {
+		t.Errorf("failed job = %+v, want a finish time, the error, and the checkpoint kept", finished)
+	}
+	if rec := do(http.MethodPut, "/jobs?id="+high.ID, `{"status":"done"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown status: status = %d, want 400", rec.Code)
+	}
+
+	claimed = decode(do(http.MethodPost, "/jobs/claim?project_id=p", ""))
+	if claimed.ID != low.ID {
+		t.Errorf("claimed %s, want %s", claimed.ID, low.ID)
+	}
+	if rec := do(http.MethodPost, "/jobs/claim", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("claim with none pending: status = %d, want 204", rec.Code)
+	}
+
+	var jobs []*models.SyncJob
+	if err := json.NewDecoder(do(http.MethodGet, "/jobs?project_id=p&status=failed", "").Body).Decode(&jobs); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != high.ID {
+		t.Errorf("failed jobs = %+v, want only %s", jobs, high.ID)
+	}
+
+	if rec := do(http.MethodDelete, "/jobs?id="+low.ID, ""); rec.Code != http.StatusOK {
+		t.Errorf("delete: status = %d, want 200", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/jobs?id="+low.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("deleted job: status = %d, want 404", rec.Code)
+	}
+}
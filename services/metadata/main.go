@@ -1,116 +1,296 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/interfaces"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
 )
 
+// metadataBackend stores what the HTTP handlers read and write: records,
+// projects, and repository sync states. The SQL database backs everything
+// else the service offers.
+type metadataBackend interface {
+	interfaces.MetadataStore
+	SaveSyncBatch(ctx context.Context, batch *models.SyncBatch) error
+	SaveProjectWithRepoStates(ctx context.Context, project *models.Project, states []*models.RepoSyncState) error
+	SaveRepoSyncState(ctx context.Context, state *models.RepoSyncState) error
+	GetRepoSyncState(ctx context.Context, projectID, repository string) (*models.RepoSyncState, error)
+	ListRepoSyncStates(ctx context.Context, projectID string) ([]*models.RepoSyncState, error)
+	latestSyncMetadata(ctx context.Context, projectID, repository string) (*models.SyncMetadata, error)
+	listSyncMetadata(ctx context.Context, projectID string, filter ListFilter) ([]*models.SyncMetadata, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
 // MetadataService implements interfaces.MetadataStore
 type MetadataService struct {
-	db *sql.DB
+	db        *sql.DB
+	dialect   *dialect
+	retention RetentionPolicy
+
+	// store backs the HTTP handlers: the service itself on SQL, or Redis,
+	// in which case db is nil
+	store metadataBackend
+
+	// writes feeds the writer that serializes SQLite transactions; nil
+	// when they run directly. writerMu guards sends against stopWriter
+	// closing it.
+	writes        chan writeJob
+	writerDone    chan struct{}
+	writerMu      sync.RWMutex
+	writerStopped bool
 }
 
-// NewMetadataService creates a new metadata service
-func NewMetadataService(dbPath string) (*MetadataService, error) {
-	// Ensure data directory exists
-	if err := os.MkdirAll("./data", 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
+// NewMetadataService creates a new metadata service on the database of
+// driver (sqlite or mysql) at dsn, a file path for SQLite
+func NewMetadataService(driver, dsn string) (*MetadataService, error) {
+	d, err := dialectFor(driver)
+	if err != nil {
+		return nil, err
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := openDB(d, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	service := &MetadataService{db: db}
+	service := &MetadataService{db: db, dialect: d}
+	service.store = service
+	if d == sqliteDialect {
+		service.startWriter()
+	}
 	if err := service.initSchema(); err != nil {
+		_ = service.Close()
 		return nil, err
 	}
 
 	return service, nil
 }
 
+// NewRedisMetadataService creates a metadata service storing records,
+// projects, and repository sync states in the Redis server at rawURL
+func NewRedisMetadataService(ctx context.Context, rawURL string) (*MetadataService, error) {
+	store, err := NewRedisStore(ctx, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &MetadataService{store: store}, nil
+}
+
 // initSchema creates database tables
 func (s *MetadataService) initSchema() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS sync_metadata (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		project_id TEXT NOT NULL,
-		repository TEXT NOT NULL,
-		file_path TEXT NOT NULL,
-		last_commit_sha TEXT NOT NULL,
-		last_synced_at DATETIME NOT NULL,
-		embedding_count INTEGER DEFAULT 0,
-		status TEXT DEFAULT 'synced',
-		UNIQUE(project_id, repository, file_path)
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_sync_project ON sync_metadata(project_id);
-	CREATE INDEX IF NOT EXISTS idx_sync_repo ON sync_metadata(repository);
-	
-	CREATE TABLE IF NOT EXISTS projects (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		organization TEXT NOT NULL,
-		filter_keyword TEXT,
-		namespace TEXT NOT NULL,
-		enabled BOOLEAN DEFAULT 1,
-		allowed_extensions TEXT,
-		exclude_patterns TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	`
-
-	_, err := s.db.Exec(schema)
+	for _, statement := range s.dialect.schema {
+		if _, err := s.db.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	// Columns added after the initial schema; CREATE TABLE IF NOT EXISTS
+	// leaves existing databases untouched, so add them explicitly
+	if err := s.ensureColumn("sync_metadata", "blob_sha", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("sync_metadata", "embedding_model", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := s.ensureColumn("projects", "chunk_strategies", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	for _, table := range []string{"sync_metadata", "repo_sync_state"} {
+		if err := s.ensureColumn(table, "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+			return err
+		}
+	}
+	return s.migrateChunkIDs()
+}
+
+// ensureColumn adds a column to an existing table if it is missing
+func (s *MetadataService) ensureColumn(table, column, definition string) error {
+	columns, err := s.dialect.columns(s.db, table)
+	if err != nil {
+		return err
+	}
+	for _, name := range columns {
+		if name == column {
+			return nil
+		}
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
 // Implement interfaces.MetadataStore methods
 
 func (s *MetadataService) SaveSyncMetadata(ctx context.Context, metadata *models.SyncMetadata) error {
-	query := `
-		INSERT INTO sync_metadata (project_id, repository, file_path, last_commit_sha, last_synced_at, embedding_count, status)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(project_id, repository, file_path) DO UPDATE SET
-			last_commit_sha = excluded.last_commit_sha,
-			last_synced_at = excluded.last_synced_at,
-			embedding_count = excluded.embedding_count,
-			status = excluded.status
-	`
-
-	_, err := s.db.ExecContext(ctx, query,
-		metadata.ProjectID, metadata.Repository, metadata.FilePath,
-		metadata.LastCommitSHA, metadata.LastSyncedAt, metadata.EmbeddingCount, metadata.Status)
+	return s.SaveSyncMetadataBatch(ctx, []*models.SyncMetadata{metadata})
+}
+
+// SaveSyncMetadataBatch saves file records in one transaction, so either
+// all of them are written or none
+func (s *MetadataService) SaveSyncMetadataBatch(ctx context.Context, batch []*models.SyncMetadata) error {
+	return s.SaveSyncBatch(ctx, &models.SyncBatch{Records: batch})
+}
 
+// SaveSyncBatch saves file records and repository sync states in one
+// transaction, so a failure part way leaves none of them written and a
+// repository's state never moves past records that were not saved. Every
+// record is audited with its old and new values, and a batch also with a
+// count per project.
+func (s *MetadataService) SaveSyncBatch(ctx context.Context, batch *models.SyncBatch) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, m := range batch.Records {
+			err := audited(ctx, tx, recordTarget(m.ProjectID, m.Repository, m.FilePath), func() error {
+				return s.saveSyncMetadata(ctx, tx, m)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if len(batch.Records) > 1 {
+			if err := auditRecords(ctx, tx, auditSave, batch.Records); err != nil {
+				return err
+			}
+		}
+		for _, state := range batch.RepoStates {
+			if err := s.writeRepoState(ctx, tx, state); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.Database("failed to save sync metadata", err)
+		return dbError("failed to save sync metadata", err)
 	}
+	return nil
+}
+
+// updateSyncMetadata updates a file record only if it is still at the
+// version the writer read, bumping the version
+const updateSyncMetadata = `
+	UPDATE sync_metadata SET last_commit_sha = ?, last_synced_at = ?, embedding_count = ?, status = ?, blob_sha = ?,
+		embedding_model = COALESCE(NULLIF(?, ''), embedding_model), version = version + 1
+	WHERE project_id = ? AND repository = ? AND file_path = ? AND version = ?`
 
+// insertSyncMetadata adds a file record that must not exist yet; a
+// concurrent insert of the same key fails on the unique key
+const insertSyncMetadata = `
+	INSERT INTO sync_metadata (project_id, repository, file_path, last_commit_sha, last_synced_at, embedding_count, status, blob_sha, embedding_model)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// saveSyncMetadata saves a file record and its vector IDs within tx and sets
+// the record's new id and version. A record with a version only replaces
+// the stored one at that version, and one marked Create is only added if
+// none is stored; either fails with a CONFLICT error otherwise, so a sync
+// can't overwrite newer state another one saved meanwhile. Other records
+// are upserted.
+func (s *MetadataService) saveSyncMetadata(ctx context.Context, tx *sql.Tx, metadata *models.SyncMetadata) error {
+	switch {
+	case metadata.Version > 0:
+		result, err := tx.ExecContext(ctx, updateSyncMetadata,
+			metadata.LastCommitSHA, metadata.LastSyncedAt.UTC(), metadata.EmbeddingCount, metadata.Status, metadata.BlobSHA, metadata.EmbeddingModel,
+			metadata.ProjectID, metadata.Repository, metadata.FilePath, metadata.Version)
+		if err != nil {
+			return errors.Database("failed to save sync metadata", err)
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			if err != nil {
+				return errors.Database("failed to save sync metadata", err)
+			}
+			return errors.Conflict(fmt.Sprintf("sync metadata of %s/%s changed since version %d",
+				metadata.Repository, metadata.FilePath, metadata.Version))
+		}
+	case metadata.Create:
+		var existing int
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM sync_metadata WHERE project_id = ? AND repository = ? AND file_path = ?`,
+			metadata.ProjectID, metadata.Repository, metadata.FilePath).Scan(&existing)
+		if err != nil {
+			return errors.Database("failed to save sync metadata", err)
+		}
+		if existing > 0 {
+			return errors.Conflict(fmt.Sprintf("sync metadata of %s/%s already exists", metadata.Repository, metadata.FilePath))
+		}
+		_, err = tx.ExecContext(ctx, insertSyncMetadata,
+			metadata.ProjectID, metadata.Repository, metadata.FilePath,
+			metadata.LastCommitSHA, metadata.LastSyncedAt.UTC(), metadata.EmbeddingCount, metadata.Status, metadata.BlobSHA, metadata.EmbeddingModel)
+		if err != nil {
+			return errors.Database("failed to save sync metadata", err)
+		}
+	default:
+		_, err := tx.ExecContext(ctx, s.dialect.saveSyncMetadata,
+			metadata.ProjectID, metadata.Repository, metadata.FilePath,
+			metadata.LastCommitSHA, metadata.LastSyncedAt.UTC(), metadata.EmbeddingCount, metadata.Status, metadata.BlobSHA, metadata.EmbeddingModel)
+		if err != nil {
+			return errors.Database("failed to save sync metadata", err)
+		}
+	}
+
+	// The upsert keeps the id of an existing row, so look it up either way
+	var syncID int64
+	err := tx.QueryRowContext(ctx, `SELECT id, version FROM sync_metadata WHERE project_id = ? AND repository = ? AND file_path = ?`,
+		metadata.ProjectID, metadata.Repository, metadata.FilePath).Scan(&syncID, &metadata.Version)
+	if err != nil {
+		return errors.Database("failed to save sync metadata", err)
+	}
+	metadata.ID = syncID
+	if err := saveVectorIDs(ctx, tx, syncID, metadata.ChunkIDs); err != nil {
+		return errors.Database("failed to save vector IDs", err)
+	}
 	return nil
 }
 
 func (s *MetadataService) GetSyncMetadata(ctx context.Context, projectID, repository, filePath string) (*models.SyncMetadata, error) {
-	query := `SELECT id, project_id, repository, file_path, last_commit_sha, last_synced_at, embedding_count, status 
+	query := `SELECT id, project_id, repository, file_path, last_commit_sha, last_synced_at, embedding_count, status, blob_sha, embedding_model, version
 		FROM sync_metadata WHERE project_id = ? AND repository = ? AND file_path = ?`
 
 	var metadata models.SyncMetadata
 	err := s.db.QueryRowContext(ctx, query, projectID, repository, filePath).Scan(
 		&metadata.ID, &metadata.ProjectID, &metadata.Repository, &metadata.FilePath,
-		&metadata.LastCommitSHA, &metadata.LastSyncedAt, &metadata.EmbeddingCount, &metadata.Status)
+		&metadata.LastCommitSHA, &metadata.LastSyncedAt, &metadata.EmbeddingCount, &metadata.Status, &metadata.BlobSHA, &metadata.EmbeddingModel, &metadata.Version)
+
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("sync metadata")
+	}
+	if err != nil {
+		return nil, errors.Database("failed to get sync metadata", err)
+	}
+	if err := s.loadVectorIDs(ctx, []*models.SyncMetadata{&metadata}, `v.sync_id = ?`, metadata.ID); err != nil {
+		return nil, errors.Database("failed to get vector IDs", err)
+	}
+
+	return &metadata, nil
+}
+
+// latestSyncMetadata returns the most recently synced file record of a
+// repository, which carries the commit the repository was last synced at.
+// Tombstones are skipped as listings skip them.
+func (s *MetadataService) latestSyncMetadata(ctx context.Context, projectID, repository string) (*models.SyncMetadata, error) {
+	query := `SELECT id, project_id, repository, file_path, last_commit_sha, last_synced_at, embedding_count, status, blob_sha, embedding_model, version
+		FROM sync_metadata WHERE project_id = ? AND repository = ? AND status <> ? ORDER BY last_synced_at DESC, id DESC LIMIT 1`
+
+	var metadata models.SyncMetadata
+	err := s.db.QueryRowContext(ctx, query, projectID, repository, tombstoneStatus).Scan(
+		&metadata.ID, &metadata.ProjectID, &metadata.Repository, &metadata.FilePath,
+		&metadata.LastCommitSHA, &metadata.LastSyncedAt, &metadata.EmbeddingCount, &metadata.Status, &metadata.BlobSHA, &metadata.EmbeddingModel, &metadata.Version)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.NotFound("sync metadata")
@@ -118,15 +298,41 @@ func (s *MetadataService) GetSyncMetadata(ctx context.Context, projectID, reposi
 	if err != nil {
 		return nil, errors.Database("failed to get sync metadata", err)
 	}
+	if err := s.loadVectorIDs(ctx, []*models.SyncMetadata{&metadata}, `v.sync_id = ?`, metadata.ID); err != nil {
+		return nil, errors.Database("failed to get vector IDs", err)
+	}
 
 	return &metadata, nil
 }
 
 func (s *MetadataService) ListSyncMetadata(ctx context.Context, projectID string) ([]*models.SyncMetadata, error) {
-	query := `SELECT id, project_id, repository, file_path, last_commit_sha, last_synced_at, embedding_count, status 
-		FROM sync_metadata WHERE project_id = ?`
+	return s.listSyncMetadata(ctx, projectID, ListFilter{})
+}
+
+// ListFilter narrows and pages a listing of a project's file records.
+// Tombstones are left out unless Status asks for them.
+type ListFilter struct {
+	Repository string // only this repository's files, if set
+	Status     string // only files with this status, if set
+	AfterID    int64  // only records with a greater id, the cursor of a page
+	Limit      int    // at most this many records, 0 for all
+}
+
+// listSyncMetadata lists a project's file records matching filter, in id
+// order
+func (s *MetadataService) listSyncMetadata(ctx context.Context, projectID string, filter ListFilter) ([]*models.SyncMetadata, error) {
+	where := `m.project_id = ? AND (? = '' OR m.repository = ?) AND ((? = '' AND m.status <> ?) OR m.status = ?) AND m.id > ?`
+	args := []interface{}{projectID, filter.Repository, filter.Repository, filter.Status, tombstoneStatus, filter.Status, filter.AfterID}
 
-	rows, err := s.db.QueryContext(ctx, query, projectID)
+	query := `SELECT m.id, m.project_id, m.repository, m.file_path, m.last_commit_sha, m.last_synced_at, m.embedding_count, m.status, m.blob_sha, m.embedding_model, m.version
+		FROM sync_metadata m WHERE ` + where + ` ORDER BY m.id`
+	queryArgs := args
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		queryArgs = append(append([]interface{}{}, args...), filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, queryArgs...)
 	if err != nil {
 		return nil, errors.Database("failed to list sync metadata", err)
 	}
@@ -136,39 +342,72 @@ func (s *MetadataService) ListSyncMetadata(ctx context.Context, projectID string
 	for rows.Next() {
 		var metadata models.SyncMetadata
 		if err := rows.Scan(&metadata.ID, &metadata.ProjectID, &metadata.Repository, &metadata.FilePath,
-			&metadata.LastCommitSHA, &metadata.LastSyncedAt, &metadata.EmbeddingCount, &metadata.Status); err != nil {
+			&metadata.LastCommitSHA, &metadata.LastSyncedAt, &metadata.EmbeddingCount, &metadata.Status, &metadata.BlobSHA, &metadata.EmbeddingModel, &metadata.Version); err != nil {
 			return nil, errors.Database("failed to scan sync metadata", err)
 		}
 		results = append(results, &metadata)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Database("failed to list sync metadata", err)
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	// The page's records are the matching ones up to its last id
+	err = s.loadVectorIDs(ctx, results, where+` AND m.id <= ?`, append(args, results[len(results)-1].ID)...)
+	if err != nil {
+		return nil, errors.Database("failed to list vector IDs", err)
+	}
 
 	return results, nil
 }
 
 func (s *MetadataService) DeleteSyncMetadata(ctx context.Context, projectID, repository, filePath string) error {
-	query := `DELETE FROM sync_metadata WHERE project_id = ? AND repository = ? AND file_path = ?`
-	_, err := s.db.ExecContext(ctx, query, projectID, repository, filePath)
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		return audited(ctx, tx, recordTarget(projectID, repository, filePath), func() error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM file_vectors WHERE sync_id IN
+				(SELECT id FROM sync_metadata WHERE project_id = ? AND repository = ? AND file_path = ?)`, projectID, repository, filePath)
+			if err != nil {
+				return errors.Database("failed to delete vector IDs", err)
+			}
+			query := `DELETE FROM sync_metadata WHERE project_id = ? AND repository = ? AND file_path = ?`
+			_, err = tx.ExecContext(ctx, query, projectID, repository, filePath)
+			return err
+		})
+	})
 	if err != nil {
-		return errors.Database("failed to delete sync metadata", err)
+		return dbError("failed to delete sync metadata", err)
 	}
 	return nil
 }
 
 func (s *MetadataService) SaveProject(ctx context.Context, project *models.Project) error {
-	query := `
-		INSERT INTO projects (id, name, organization, filter_keyword, namespace, enabled, allowed_extensions, exclude_patterns, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET
-			name = excluded.name,
-			organization = excluded.organization,
-			filter_keyword = excluded.filter_keyword,
-			namespace = excluded.namespace,
-			enabled = excluded.enabled,
-			allowed_extensions = excluded.allowed_extensions,
-			exclude_patterns = excluded.exclude_patterns,
-			updated_at = excluded.updated_at
-	`
+	return s.SaveProjectWithRepoStates(ctx, project, nil)
+}
+
+// SaveProjectWithRepoStates saves a project and the commits its
+// repositories start syncing from in one transaction
+func (s *MetadataService) SaveProjectWithRepoStates(ctx context.Context, project *models.Project, states []*models.RepoSyncState) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.writeProject(ctx, tx, project); err != nil {
+			return err
+		}
+		for _, state := range states {
+			if err := s.writeRepoState(ctx, tx, state); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return dbError("failed to save project", err)
+	}
+	return nil
+}
 
+// writeProject upserts a project within tx, auditing the change
+func (s *MetadataService) writeProject(ctx context.Context, tx *sql.Tx, project *models.Project) error {
 	allowedExt := ""
 	if len(project.AllowedExtensions) > 0 {
 		data, _ := json.Marshal(project.AllowedExtensions)
@@ -181,27 +420,35 @@ func (s *MetadataService) SaveProject(ctx context.Context, project *models.Proje
 		excludePat = string(data)
 	}
 
-	_, err := s.db.ExecContext(ctx, query,
-		project.ID, project.Name, project.Organization, project.FilterKeyword,
-		project.Namespace, project.Enabled, allowedExt, excludePat, time.Now())
-
-	if err != nil {
-		return errors.Database("failed to save project", err)
+	strategies := ""
+	if len(project.ChunkStrategies) > 0 {
+		data, _ := json.Marshal(project.ChunkStrategies)
+		strategies = string(data)
 	}
 
-	return nil
+	return audited(ctx, tx, projectTarget(project.ID), func() error {
+		_, err := tx.ExecContext(ctx, s.dialect.saveProject,
+			project.ID, project.Name, project.Organization, project.FilterKeyword,
+			project.Namespace, project.Enabled, allowedExt, excludePat, strategies, time.Now())
+
+		if err != nil {
+			return errors.Database("failed to save project", err)
+		}
+
+		return nil
+	})
 }
 
 func (s *MetadataService) GetProject(ctx context.Context, projectID string) (*models.Project, error) {
-	query := `SELECT id, name, organization, filter_keyword, namespace, enabled, allowed_extensions, exclude_patterns, created_at, updated_at 
+	query := `SELECT id, name, organization, filter_keyword, namespace, enabled, allowed_extensions, exclude_patterns, chunk_strategies, created_at, updated_at 
 		FROM projects WHERE id = ?`
 
 	var project models.Project
-	var allowedExt, excludePat string
+	var allowedExt, excludePat, strategies string
 
 	err := s.db.QueryRowContext(ctx, query, projectID).Scan(
 		&project.ID, &project.Name, &project.Organization, &project.FilterKeyword,
-		&project.Namespace, &project.Enabled, &allowedExt, &excludePat,
+		&project.Namespace, &project.Enabled, &allowedExt, &excludePat, &strategies,
 		&project.CreatedAt, &project.UpdatedAt)
 
 	if err == sql.ErrNoRows {
@@ -217,12 +464,15 @@ func (s *MetadataService) GetProject(ctx context.Context, projectID string) (*mo
 	if excludePat != "" {
 		_ = json.Unmarshal([]byte(excludePat), &project.ExcludePatterns)
 	}
+	if strategies != "" {
+		_ = json.Unmarshal([]byte(strategies), &project.ChunkStrategies)
+	}
 
 	return &project, nil
 }
 
 func (s *MetadataService) ListProjects(ctx context.Context) ([]*models.Project, error) {
-	query := `SELECT id, name, organization, filter_keyword, namespace, enabled, allowed_extensions, exclude_patterns, created_at, updated_at 
+	query := `SELECT id, name, organization, filter_keyword, namespace, enabled, allowed_extensions, exclude_patterns, chunk_strategies, created_at, updated_at 
 		FROM projects`
 
 	rows, err := s.db.QueryContext(ctx, query)
@@ -234,10 +484,10 @@ func (s *MetadataService) ListProjects(ctx context.Context) ([]*models.Project,
 	var results []*models.Project
 	for rows.Next() {
 		var project models.Project
-		var allowedExt, excludePat string
+		var allowedExt, excludePat, strategies string
 
 		if err := rows.Scan(&project.ID, &project.Name, &project.Organization, &project.FilterKeyword,
-			&project.Namespace, &project.Enabled, &allowedExt, &excludePat,
+			&project.Namespace, &project.Enabled, &allowedExt, &excludePat, &strategies,
 			&project.CreatedAt, &project.UpdatedAt); err != nil {
 			return nil, errors.Database("failed to scan project", err)
 		}
@@ -248,6 +498,9 @@ func (s *MetadataService) ListProjects(ctx context.Context) ([]*models.Project,
 		if excludePat != "" {
 			_ = json.Unmarshal([]byte(excludePat), &project.ExcludePatterns)
 		}
+		if strategies != "" {
+			_ = json.Unmarshal([]byte(strategies), &project.ChunkStrategies)
+		}
 
 		results = append(results, &project)
 	}
@@ -256,21 +509,34 @@ func (s *MetadataService) ListProjects(ctx context.Context) ([]*models.Project,
 }
 
 func (s *MetadataService) DeleteProject(ctx context.Context, projectID string) error {
-	query := `DELETE FROM projects WHERE id = ?`
-	_, err := s.db.ExecContext(ctx, query, projectID)
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		return audited(ctx, tx, projectTarget(projectID), func() error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM projects WHERE id = ?`, projectID)
+			return err
+		})
+	})
 	if err != nil {
 		return errors.Database("failed to delete project", err)
 	}
 	return nil
 }
 
+// Ping checks the connection to the database
+func (s *MetadataService) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
 func (s *MetadataService) Close() error {
+	if s.db == nil {
+		return s.store.Close()
+	}
+	s.stopWriter()
 	return s.db.Close()
 }
 
 // HTTP Handlers
 func (s *MetadataService) handleHealth(w http.ResponseWriter, r *http.Request) {
-	if err := s.db.Ping(); err != nil {
+	if err := s.store.Ping(r.Context()); err != nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		_ = json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy", "error": err.Error()})
 		return
@@ -278,6 +544,459 @@ func (s *MetadataService) handleHealth(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
 
+// handleMetadata gets (GET), saves (POST), or deletes (DELETE) the record of
+// a single file
+func (s *MetadataService) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.getMetadata(w, r)
+	case http.MethodPost:
+		s.saveMetadata(w, r)
+	case http.MethodDelete:
+		s.deleteMetadata(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getMetadata returns the record of a file, or without file_path the
+// repository's most recently synced one
+func (s *MetadataService) getMetadata(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	projectID, repository, filePath := query.Get("project_id"), query.Get("repository"), query.Get("file_path")
+	if projectID == "" || repository == "" {
+		http.Error(w, "project_id and repository are required", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		metadata *models.SyncMetadata
+		err      error
+	)
+	if filePath != "" {
+		metadata, err = s.store.GetSyncMetadata(r.Context(), projectID, repository, filePath)
+	} else {
+		metadata, err = s.store.latestSyncMetadata(r.Context(), projectID, repository)
+	}
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to get sync metadata: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(metadata)
+}
+
+// saveMetadata upserts a file record from the request body
+func (s *MetadataService) saveMetadata(w http.ResponseWriter, r *http.Request) {
+	var metadata models.SyncMetadata
+	if err := json.NewDecoder(r.Body).Decode(&metadata); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := prepareMetadata(&metadata); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.SaveSyncMetadata(r.Context(), &metadata); err != nil {
+		if isConflict(err) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		logger.Error("Failed to save sync metadata: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "saved", "version": metadata.Version})
+}
+
+// maxBulkRecords bounds the records of one /metadata/bulk request
+const maxBulkRecords = 10000
+
+// handleBulkMetadata saves an array of file records, or a models.SyncBatch
+// of records and repository sync states, in one transaction; an invalid
+// record or state rejects the whole request
+func (s *MetadataService) handleBulkMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var batch models.SyncBatch
+	var err error
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(body, &batch.Records)
+	} else {
+		err = json.Unmarshal(body, &batch)
+	}
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(batch.Records)+len(batch.RepoStates) > maxBulkRecords {
+		http.Error(w, fmt.Sprintf("at most %d records per request", maxBulkRecords), http.StatusRequestEntityTooLarge)
+		return
+	}
+	for i, metadata := range batch.Records {
+		if metadata == nil {
+			http.Error(w, fmt.Sprintf("record %d: null", i), http.StatusBadRequest)
+			return
+		}
+		if err := prepareMetadata(metadata); err != nil {
+			http.Error(w, fmt.Sprintf("record %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+	}
+	for i, state := range batch.RepoStates {
+		if state == nil {
+			http.Error(w, fmt.Sprintf("repo state %d: null", i), http.StatusBadRequest)
+			return
+		}
+		if err := prepareRepoState(state); err != nil {
+			http.Error(w, fmt.Sprintf("repo state %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := s.store.SaveSyncBatch(r.Context(), &batch); err != nil {
+		if isConflict(err) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		logger.Error("Failed to save %d sync metadata records and %d repository states: %v",
+			len(batch.Records), len(batch.RepoStates), err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "saved",
+		"count":       len(batch.Records),
+		"repo_states": len(batch.RepoStates),
+	})
+}
+
+// prepareMetadata checks a record to save and fills in its sync time and
+// status if missing
+func prepareMetadata(metadata *models.SyncMetadata) error {
+	if metadata.ProjectID == "" || metadata.Repository == "" || metadata.FilePath == "" {
+		return errors.Validation("project_id, repository, and file_path are required")
+	}
+	if metadata.LastSyncedAt.IsZero() {
+		metadata.LastSyncedAt = time.Now()
+	}
+	if metadata.Status == "" {
+		metadata.Status = "synced"
+	}
+	return nil
+}
+
+// deleteMetadata drops the record named by the query parameters
+func (s *MetadataService) deleteMetadata(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	projectID, repository, filePath := query.Get("project_id"), query.Get("repository"), query.Get("file_path")
+	if projectID == "" || repository == "" || filePath == "" {
+		http.Error(w, "project_id, repository, and file_path are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.DeleteSyncMetadata(r.Context(), projectID, repository, filePath); err != nil {
+		logger.Error("Failed to delete sync metadata: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// maxListLimit bounds the records of one /metadata/list page
+const maxListLimit = 5000
+
+// handleListMetadata returns a project's file records, optionally filtered
+// by repository and status. With limit it returns one page and, when the
+// page is full, an X-Next-Cursor header to pass as cursor for the next.
+func (s *MetadataService) handleListMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	projectID := query.Get("project_id")
+	if projectID == "" {
+		http.Error(w, "project_id is required", http.StatusBadRequest)
+		return
+	}
+
+	filter := ListFilter{Repository: query.Get("repository"), Status: query.Get("status")}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		if limit > maxListLimit {
+			limit = maxListLimit
+		}
+		filter.Limit = limit
+	}
+	if v := query.Get("cursor"); v != "" {
+		after, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || after < 0 {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		filter.AfterID = after
+	}
+
+	entries, err := s.store.listSyncMetadata(r.Context(), projectID, filter)
+	if err != nil {
+		logger.Error("Failed to list sync metadata: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []*models.SyncMetadata{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if filter.Limit > 0 && len(entries) == filter.Limit {
+		w.Header().Set("X-Next-Cursor", strconv.FormatInt(entries[len(entries)-1].ID, 10))
+	}
+	_ = json.NewEncoder(w).Encode(entries)
+}
+
+// handleProjects lists and gets (GET), creates (POST), updates (PUT), or
+// deletes (DELETE) projects
+func (s *MetadataService) handleProjects(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.getProjects(w, r)
+	case http.MethodPost, http.MethodPut:
+		s.saveProject(w, r)
+	case http.MethodDelete:
+		s.deleteProject(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getProjects returns one project by id, or every project without one
+func (s *MetadataService) getProjects(w http.ResponseWriter, r *http.Request) {
+	var (
+		result interface{}
+		err    error
+	)
+	if id := r.URL.Query().Get("id"); id != "" {
+		result, err = s.store.GetProject(r.Context(), id)
+	} else {
+		var projects []*models.Project
+		projects, err = s.store.ListProjects(r.Context())
+		if projects == nil {
+			projects = []*models.Project{}
+		}
+		result = projects
+	}
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to get projects: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// ProjectRequest is the body of a project create or update. Enabled
+// shadows the project's own field so a missing value can default to true.
+// RepoStates optionally sets the commits repositories sync from next,
+// e.g. to start a project at the current heads instead of a full sync.
+type ProjectRequest struct {
+	models.Project
+	Enabled    *bool                   `json:"enabled"`
+	RepoStates []*models.RepoSyncState `json:"repo_states,omitempty"`
+}
+
+// saveProject creates a project (POST), failing if the id is taken, or
+// replaces an existing one (PUT, with the id in the body or ?id=)
+func (s *MetadataService) saveProject(w http.ResponseWriter, r *http.Request) {
+	var req ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	project := req.Project
+	if id := r.URL.Query().Get("id"); id != "" {
+		if project.ID != "" && project.ID != id {
+			http.Error(w, fmt.Sprintf("body id %q does not match ?id=%s", project.ID, id), http.StatusBadRequest)
+			return
+		}
+		project.ID = id
+	}
+	project.Enabled = req.Enabled == nil || *req.Enabled
+	if project.Name == "" {
+		project.Name = project.ID
+	}
+	if project.Namespace == "" {
+		project.Namespace = project.ID
+	}
+	if err := validateProject(&project); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i, state := range req.RepoStates {
+		if state == nil {
+			http.Error(w, fmt.Sprintf("repo state %d: null", i), http.StatusBadRequest)
+			return
+		}
+		if state.ProjectID != "" && state.ProjectID != project.ID {
+			http.Error(w, fmt.Sprintf("repo state %d: project_id %q does not match project %q", i, state.ProjectID, project.ID), http.StatusBadRequest)
+			return
+		}
+		state.ProjectID = project.ID
+		if err := prepareRepoState(state); err != nil {
+			http.Error(w, fmt.Sprintf("repo state %d: %v", i, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	_, err := s.store.GetProject(r.Context(), project.ID)
+	exists := err == nil
+	if err != nil && !isNotFound(err) {
+		logger.Error("Failed to get project: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.Method == http.MethodPost && exists {
+		http.Error(w, fmt.Sprintf("project %q already exists", project.ID), http.StatusConflict)
+		return
+	}
+	if r.Method == http.MethodPut && !exists {
+		http.Error(w, fmt.Sprintf("project %q not found", project.ID), http.StatusNotFound)
+		return
+	}
+
+	if err := s.store.SaveProjectWithRepoStates(r.Context(), &project, req.RepoStates); err != nil {
+		logger.Error("Failed to save project: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	saved, err := s.store.GetProject(r.Context(), project.ID)
+	if err != nil {
+		logger.Error("Failed to get saved project: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !exists {
+		w.WriteHeader(http.StatusCreated)
+	}
+	_ = json.NewEncoder(w).Encode(saved)
+}
+
+// deleteProject drops the project named by ?id=; its sync records are kept
+func (s *MetadataService) deleteProject(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.store.GetProject(r.Context(), id); err != nil {
+		if isNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to get project: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.store.DeleteProject(r.Context(), id); err != nil {
+		logger.Error("Failed to delete project: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "id": id})
+}
+
+// projectIDPattern keeps project IDs usable as vector namespaces and in URLs
+var projectIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// validateProject checks a project's id and filter and chunking settings
+func validateProject(project *models.Project) error {
+	if project.ID == "" {
+		return errors.Validation("id is required")
+	}
+	if !projectIDPattern.MatchString(project.ID) {
+		return errors.Validation(fmt.Sprintf("invalid id %q: use up to 64 letters, digits, '.', '_', or '-', starting with a letter or digit", project.ID))
+	}
+	for _, ext := range project.AllowedExtensions {
+		if !strings.HasPrefix(ext, ".") || len(ext) < 2 {
+			return errors.Validation(fmt.Sprintf("invalid allowed extension %q: must start with '.'", ext))
+		}
+	}
+	for _, pattern := range project.ExcludePatterns {
+		if strings.Trim(pattern, "/") == "" {
+			return errors.Validation("exclude patterns must not be empty")
+		}
+		for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return errors.Validation(fmt.Sprintf("invalid exclude pattern %q: %v", pattern, err))
+			}
+		}
+	}
+	for ext, strategy := range project.ChunkStrategies {
+		if !strings.HasPrefix(ext, ".") || len(ext) < 2 {
+			return errors.Validation(fmt.Sprintf("invalid chunk strategy extension %q: must start with '.'", ext))
+		}
+		if strategy == "" {
+			return errors.Validation(fmt.Sprintf("chunk strategy for %q must not be empty", ext))
+		}
+	}
+	return nil
+}
+
+// isNotFound reports whether err is a NOT_FOUND error
+// notSupported answers requests for a feature the metadata driver lacks
+func notSupported(driver string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, fmt.Sprintf("%s is not supported with METADATA_DB_DRIVER=%s", r.URL.Path, driver), http.StatusNotImplemented)
+	}
+}
+
+func isNotFound(err error) bool {
+	var appErr *errors.AppError
+	return stderrors.As(err, &appErr) && appErr.Type == errors.ErrTypeNotFound
+}
+
+// isConflict reports whether err is a write rejected for outdated data
+func isConflict(err error) bool {
+	var appErr *errors.AppError
+	return stderrors.As(err, &appErr) && appErr.Type == errors.ErrTypeConflict
+}
+
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -286,6 +1005,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Validate metadata-specific requirements
+	if err := cfg.ValidateForMetadata(); err != nil {
+		fmt.Printf("Failed to validate configuration: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Initialize logger
 	if err := logger.Init(cfg.Logging.Level, cfg.Logging.FilePath, "metadata-service"); err != nil {
 		fmt.Printf("Failed to initialize logger: %v\n", err)
@@ -295,28 +1020,89 @@ func main() {
 	logger.Info("Starting Metadata Service on port %d", cfg.Services.MetadataServicePort)
 
 	// Create metadata service
-	service, err := NewMetadataService(cfg.Database.MetadataDBPath)
+	var service *MetadataService
+	switch cfg.Database.Driver {
+	case DriverRedis:
+		service, err = NewRedisMetadataService(context.Background(), cfg.Database.RedisURL)
+	case DriverMySQL:
+		service, err = NewMetadataService(cfg.Database.Driver, cfg.Database.DSN)
+	default:
+		service, err = NewMetadataService(cfg.Database.Driver, cfg.Database.MetadataDBPath)
+	}
 	if err != nil {
 		logger.Fatal("Failed to create metadata service: %v", err)
 	}
+	logger.Info("Storing metadata in %s", cfg.Database.Driver)
+	if len(cfg.Database.APIKeys) == 0 {
+		logger.Warning("METADATA_API_KEYS is not set, so requests are not authenticated and changes are audited as %s", anonymousActor)
+	}
 	defer func() { _ = service.Close() }()
 
+	const day = 24 * time.Hour
+	service.retention = RetentionPolicy{
+		Jobs:       time.Duration(cfg.Database.JobRetentionDays) * day,
+		Tombstones: time.Duration(cfg.Database.TombstoneRetentionDays) * day,
+		Audit:      time.Duration(cfg.Database.AuditRetentionDays) * day,
+	}
+	purgeCtx, stopPurges := context.WithCancel(context.Background())
+	defer stopPurges()
+	if service.db != nil && cfg.Database.PurgeInterval > 0 {
+		go service.runPurges(purgeCtx, cfg.Database.PurgeInterval)
+	}
+
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", service.handleHealth)
+	mux.HandleFunc("/metadata", service.handleMetadata)
+	mux.HandleFunc("/metadata/list", service.handleListMetadata)
+	mux.HandleFunc("/metadata/bulk", service.handleBulkMetadata)
+	mux.HandleFunc("/projects", service.handleProjects)
+	mux.HandleFunc("/repo-state", service.handleRepoState)
+
+	// Served from SQL only; other drivers answer 501 so clients can tell a
+	// missing feature from a wrong URL
+	sqlRoutes := []struct {
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"/stats", service.handleStats},
+		{"/export", service.handleExport},
+		{"/import", service.handleImport},
+		{"/jobs", service.handleJobs},
+		{"/jobs/claim", service.handleClaimJob},
+		{"/purge", service.handlePurge},
+		{"/audit", service.handleAudit},
+	}
+	var unsupported []string
+	for _, route := range sqlRoutes {
+		if service.db != nil {
+			mux.HandleFunc(route.path, route.handler)
+			continue
+		}
+		mux.HandleFunc(route.path, notSupported(cfg.Database.Driver))
+		unsupported = append(unsupported, route.path)
+	}
+	if len(unsupported) > 0 {
+		logger.Warning("METADATA_DB_DRIVER=%s keeps no audit log, runs no purges, and answers %s with 501",
+			cfg.Database.Driver, strings.Join(unsupported, ", "))
+	}
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Services.MetadataServicePort),
-		Handler: mux,
+		Handler: withRequestActor(cfg.Database.APIKeys, mux),
 	}
 
-	// Graceful shutdown
+	// Graceful shutdown; the service is closed only once the requests in
+	// flight have finished
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 		<-sigChan
 
 		logger.Info("Shutting down metadata service...")
+		stopPurges()
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
@@ -330,4 +1116,5 @@ func main() {
 	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		logger.Fatal("Failed to start server: %v", err)
 	}
+	<-shutdownDone
 }
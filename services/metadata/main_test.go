@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// newTestService opens a metadata service on a fresh database
+func newTestService(t *testing.T) *MetadataService {
+	t.Helper()
+	service, err := NewMetadataService(DriverSQLite, filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = service.Close() })
+	return service
+}
+
+func TestGetMetadata(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	synced := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i, file := range []string{"docs/a.md", "docs/b.md"} {
+		err := s.SaveSyncMetadata(ctx, &models.SyncMetadata{
+			ProjectID:     "p",
+			Repository:    "org/repo",
+			FilePath:      file,
+			LastCommitSHA: file + "-sha",
+			LastSyncedAt:  synced.Add(time.Duration(i) * time.Hour),
+			Status:        "synced",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantSHA    string
+	}{
+		{name: "one file", query: "project_id=p&repository=org/repo&file_path=docs/a.md", wantStatus: http.StatusOK, wantSHA: "docs/a.md-sha"},
+		{name: "latest of the repository", query: "project_id=p&repository=org/repo", wantStatus: http.StatusOK, wantSHA: "docs/b.md-sha"},
+		{name: "unknown file", query: "project_id=p&repository=org/repo&file_path=missing.md", wantStatus: http.StatusNotFound},
+		{name: "unknown repository", query: "project_id=p&repository=org/other", wantStatus: http.StatusNotFound},
+		{name: "repository is required", query: "project_id=p", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleMetadata(rec, httptest.NewRequest(http.MethodGet, "/metadata?"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var metadata models.SyncMetadata
+			if err := json.NewDecoder(rec.Body).Decode(&metadata); err != nil {
+				t.Fatal(err)
+			}
+			if metadata.LastCommitSHA != tt.wantSHA {
+				t.Errorf("last_commit_sha = %q, want %q", metadata.LastCommitSHA, tt.wantSHA)
+			}
+		})
+	}
+}
+
+func TestProjects(t *testing.T) {
+	s := newTestService(t)
+
+	// The cases run in order against one database
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		body       string
+		wantStatus int
+		check      func(t *testing.T, project *models.Project)
+	}{
+		{
+			name:       "create with defaults",
+			method:     http.MethodPost,
+			body:       `{"id":"docs","allowed_extensions":[".md"]}`,
+			wantStatus: http.StatusCreated,
+			check: func(t *testing.T, p *models.Project) {
+				if p.Name != "docs" || p.Namespace != "docs" || !p.Enabled {
+					t.Errorf("project = %+v, want name and namespace docs, enabled", p)
+				}
+			},
+		},
+		{name: "create an existing id", method: http.MethodPost, body: `{"id":"docs"}`, wantStatus: http.StatusConflict},
+		{name: "invalid id", method: http.MethodPost, body: `{"id":"docs/../x"}`, wantStatus: http.StatusBadRequest},
+		{name: "missing id", method: http.MethodPost, body: `{"name":"Docs"}`, wantStatus: http.StatusBadRequest},
+		{name: "extension without a dot", method: http.MethodPost, body: `{"id":"api","allowed_extensions":["md"]}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed exclude pattern", method: http.MethodPost, body: `{"id":"api","exclude_patterns":["[dist"]}`, wantStatus: http.StatusBadRequest},
+		{name: "chunk strategy without a dot", method: http.MethodPost, body: `{"id":"api","chunk_strategies":{"md":"markdown"}}`, wantStatus: http.StatusBadRequest},
+		{
+			name:       "get one",
+			method:     http.MethodGet,
+			query:      "id=docs",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, p *models.Project) {
+				if len(p.AllowedExtensions) != 1 || p.AllowedExtensions[0] != ".md" {
+					t.Errorf("allowed_extensions = %v, want [.md]", p.AllowedExtensions)
+				}
+			},
+		},
+		{
+			name:       "update",
+			method:     http.MethodPut,
+			query:      "id=docs",
+			body:       `{"name":"Docs","namespace":"docs-v2","enabled":false}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, p *models.Project) {
+				if p.Name != "Docs" || p.Namespace != "docs-v2" || p.Enabled {
+					t.Errorf("project = %+v, want name Docs, namespace docs-v2, disabled", p)
+				}
+			},
+		},
+		{name: "update with a mismatched id", method: http.MethodPut, query: "id=docs", body: `{"id":"api"}`, wantStatus: http.StatusBadRequest},
+		{name: "update a missing project", method: http.MethodPut, body: `{"id":"api"}`, wantStatus: http.StatusNotFound},
+		{name: "delete", method: http.MethodDelete, query: "id=docs", wantStatus: http.StatusOK},
+		{name: "get a deleted project", method: http.MethodGet, query: "id=docs", wantStatus: http.StatusNotFound},
+		{name: "delete a missing project", method: http.MethodDelete, query: "id=docs", wantStatus: http.StatusNotFound},
+		{name: "delete without id", method: http.MethodDelete, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleProjects(rec, httptest.NewRequest(tt.method, "/projects?"+tt.query, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			if tt.check == nil {
+				return
+			}
+
+			var project models.Project
+			if err := json.NewDecoder(rec.Body).Decode(&project); err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, &project)
+		})
+	}
+}
+
+func TestListMetadataPages(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	for i := 0; i < 6; i++ {
+		repo, status := "org/a", "synced"
+		if i%2 == 1 {
+			repo = "org/b"
+		}
+		switch i {
+		case 4:
+			status = "failed"
+		case 5:
+			status = tombstoneStatus
+		}
+		err := s.SaveSyncMetadata(ctx, &models.SyncMetadata{
+			ProjectID: "p", Repository: repo, FilePath: fmt.Sprintf("doc%d.md", i),
+			LastCommitSHA: "sha", LastSyncedAt: time.Now(), Status: status,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// list follows X-Next-Cursor and returns the file paths of every page
+	list := func(t *testing.T, query string) ([]string, int) {
+		t.Helper()
+		var (
+			paths  []string
+			pages  int
+			cursor string
+		)
+		for {
+			rec := httptest.NewRecorder()
+			s.handleListMetadata(rec, httptest.NewRequest(http.MethodGet, "/metadata/list?project_id=p&"+query+"&cursor="+cursor, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d (%s), want 200", rec.Code, rec.Body.String())
+			}
+			var page []*models.SyncMetadata
+			if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+				t.Fatal(err)
+			}
+			pages++
+			for _, entry := range page {
+				paths = append(paths, entry.FilePath)
+			}
+			if cursor = rec.Header().Get("X-Next-Cursor"); cursor == "" {
+				return paths, pages
+			}
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantPaths []string
+		wantPages int
+	}{
+		{name: "everything at once", query: "", wantPaths: []string{"doc0.md", "doc1.md", "doc2.md", "doc3.md", "doc4.md"}, wantPages: 1},
+		{name: "pages of two", query: "limit=2", wantPaths: []string{"doc0.md", "doc1.md", "doc2.md", "doc3.md", "doc4.md"}, wantPages: 3},
+		{name: "one repository", query: "repository=org/a&limit=2", wantPaths: []string{"doc0.md", "doc2.md", "doc4.md"}, wantPages: 2},
+		{name: "one status", query: "status=failed", wantPaths: []string{"doc4.md"}, wantPages: 1},
+		{name: "repository and status", query: "repository=org/b&status=failed", wantPaths: nil, wantPages: 1},
+		{name: "tombstones only on request", query: "status=deleted", wantPaths: []string{"doc5.md"}, wantPages: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, pages := list(t, tt.query)
+			if !reflect.DeepEqual(paths, tt.wantPaths) || pages != tt.wantPages {
+				t.Errorf("got %v in %d pages, want %v in %d", paths, pages, tt.wantPaths, tt.wantPages)
+			}
+		})
+	}
+
+	for _, query := range []string{"limit=0", "limit=x", "cursor=-1", "cursor=x"} {
+		rec := httptest.NewRecorder()
+		s.handleListMetadata(rec, httptest.NewRequest(http.MethodGet, "/metadata/list?project_id=p&"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestBulkMetadata(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleBulkMetadata(rec, httptest.NewRequest(http.MethodPost, "/metadata/bulk", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`[
+		{"project_id":"p","repository":"org/repo","file_path":"a.md","last_commit_sha":"s1","chunk_ids":["a1"]},
+		{"project_id":"p","repository":"org/repo","file_path":"b.md","last_commit_sha":"s1"}
+	]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+	got, err := s.GetSyncMetadata(ctx, "p", "org/repo", "a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "synced" || got.LastSyncedAt.IsZero() || !reflect.DeepEqual(got.ChunkIDs, []string{"a1"}) {
+		t.Errorf("a.md = %+v, want status synced, a sync time, and chunk IDs [a1]", got)
+	}
+
+	// One invalid record rejects the batch, valid records included
+	rec = post(`[
+		{"project_id":"p","repository":"org/repo","file_path":"a.md","last_commit_sha":"s2"},
+		{"project_id":"p","repository":"org/repo","last_commit_sha":"s2"}
+	]`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "record 1") {
+		t.Fatalf("status = %d (%s), want 400 naming record 1", rec.Code, rec.Body.String())
+	}
+	got, err = s.GetSyncMetadata(ctx, "p", "org/repo", "a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LastCommitSHA != "s1" {
+		t.Errorf("a.md commit = %s after a rejected batch, want s1", got.LastCommitSHA)
+	}
+}
+
+func TestVersionedSaves(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleMetadata(rec, httptest.NewRequest(http.MethodPost, "/metadata", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(`{"project_id":"p","repository":"org/repo","file_path":"a.md","last_commit_sha":"s1","create":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("create: status = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+	got, err := s.GetSyncMetadata(ctx, "p", "org/repo", "a.md")
+	if err != nil || got.Version != 1 {
+		t.Fatalf("created record = %+v, %v; want version 1", got, err)
+	}
+
+	// Two writers that both found no record: the second conflicts
+	if rec := post(`{"project_id":"p","repository":"org/repo","file_path":"a.md","last_commit_sha":"s0","create":true}`); rec.Code != http.StatusConflict {
+		t.Fatalf("second create: status = %d (%s), want 409", rec.Code, rec.Body.String())
+	}
+
+	// Two writers that both read version 1: the first wins, the second conflicts
+	if rec := post(`{"project_id":"p","repository":"org/repo","file_path":"a.md","last_commit_sha":"s2","version":1}`); rec.Code != http.StatusOK {
+		t.Fatalf("first update: status = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+	if rec := post(`{"project_id":"p","repository":"org/repo","file_path":"a.md","last_commit_sha":"s0","version":1}`); rec.Code != http.StatusConflict {
+		t.Fatalf("stale update: status = %d (%s), want 409", rec.Code, rec.Body.String())
+	}
+	got, err = s.GetSyncMetadata(ctx, "p", "org/repo", "a.md")
+	if err != nil || got.LastCommitSHA != "s2" || got.Version != 2 {
+		t.Errorf("record = %+v, %v; want commit s2 at version 2", got, err)
+	}
+
+	state := &models.RepoSyncState{ProjectID: "p", Repository: "org/repo", LastCommitSHA: "s1", LastSyncedAt: time.Now(), Create: true}
+	if err := s.SaveRepoSyncState(ctx, state); err != nil || state.Version != 1 {
+		t.Fatalf("save state: version %d, %v; want version 1", state.Version, err)
+	}
+	err = s.SaveRepoSyncState(ctx, &models.RepoSyncState{ProjectID: "p", Repository: "org/repo", LastCommitSHA: "s0", LastSyncedAt: time.Now(), Create: true})
+	if !isConflict(err) {
+		t.Errorf("second state create: %v, want a conflict", err)
+	}
+	if err := s.SaveRepoSyncState(ctx, &models.RepoSyncState{ProjectID: "p", Repository: "org/repo", LastCommitSHA: "s2", LastSyncedAt: time.Now(), Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	err = s.SaveRepoSyncState(ctx, &models.RepoSyncState{ProjectID: "p", Repository: "org/repo", LastCommitSHA: "s0", LastSyncedAt: time.Now(), Version: 1})
+	if !isConflict(err) {
+		t.Errorf("stale state save: %v, want a conflict", err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// tombstoneStatus marks the record of a deleted file that is kept, e.g. to
+// report the deletion, until the retention policy purges it
+const tombstoneStatus = "deleted"
+
+// systemActor is the actor of changes the service makes on its own, like
+// scheduled purges
+const systemActor = "system"
+
+// RetentionPolicy is how long purges keep data; zero keeps it forever
+type RetentionPolicy struct {
+	Jobs       time.Duration // finished sync jobs, by when they finished
+	Tombstones time.Duration // tombstone records, by when they were last synced
+	Audit      time.Duration // audit entries, by when they were made
+}
+
+// PurgeResult counts what a purge removed
+type PurgeResult struct {
+	Jobs         int64 `json:"jobs"`
+	Tombstones   int64 `json:"tombstones"`
+	AuditEntries int64 `json:"audit_entries"`
+}
+
+// Purge removes the finished sync jobs, tombstone records, and audit entries
+// older than the policy allows as of now, in one transaction. Removed
+// tombstones are audited as deleted like any other record.
+func (s *MetadataService) Purge(ctx context.Context, policy RetentionPolicy, now time.Time) (*PurgeResult, error) {
+	var result PurgeResult
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if policy.Jobs > 0 {
+			res, err := tx.ExecContext(ctx, `DELETE FROM sync_jobs WHERE status IN (?, ?, ?) AND finished_at < ?`,
+				models.SyncJobCompleted, models.SyncJobFailed, models.SyncJobCancelled, now.Add(-policy.Jobs).UTC())
+			if err != nil {
+				return err
+			}
+			if result.Jobs, err = res.RowsAffected(); err != nil {
+				return err
+			}
+		}
+
+		if policy.Audit > 0 {
+			res, err := tx.ExecContext(ctx, `DELETE FROM audit_log WHERE created_at < ?`, now.Add(-policy.Audit).UTC())
+			if err != nil {
+				return err
+			}
+			if result.AuditEntries, err = res.RowsAffected(); err != nil {
+				return err
+			}
+		}
+
+		if policy.Tombstones > 0 {
+			expired, err := expiredTombstones(ctx, tx, now.Add(-policy.Tombstones).UTC())
+			if err != nil {
+				return err
+			}
+			for _, m := range expired {
+				err := audited(ctx, tx, recordTarget(m.ProjectID, m.Repository, m.FilePath), func() error {
+					if _, err := tx.ExecContext(ctx, `DELETE FROM file_vectors WHERE sync_id = ?`, m.ID); err != nil {
+						return err
+					}
+					_, err := tx.ExecContext(ctx, `DELETE FROM sync_metadata WHERE id = ?`, m.ID)
+					return err
+				})
+				if err != nil {
+					return err
+				}
+			}
+			if err := auditRecords(ctx, tx, auditPurge, expired); err != nil {
+				return err
+			}
+			result.Tombstones = int64(len(expired))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, dbError("failed to purge metadata", err)
+	}
+	return &result, nil
+}
+
+// expiredTombstones lists the tombstone records last synced before cutoff
+func expiredTombstones(ctx context.Context, tx *sql.Tx, cutoff time.Time) ([]*models.SyncMetadata, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, project_id, repository, file_path FROM sync_metadata
+		WHERE status = ? AND last_synced_at < ? ORDER BY id`, tombstoneStatus, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var expired []*models.SyncMetadata
+	for rows.Next() {
+		var m models.SyncMetadata
+		if err := rows.Scan(&m.ID, &m.ProjectID, &m.Repository, &m.FilePath); err != nil {
+			return nil, err
+		}
+		expired = append(expired, &m)
+	}
+	return expired, rows.Err()
+}
+
+// runPurges purges by the service's retention policy every interval until
+// ctx is done
+func (s *MetadataService) runPurges(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx = withActor(ctx, systemActor)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.Purge(ctx, s.retention, time.Now())
+			if err != nil {
+				logger.Error("Scheduled purge failed: %v", err)
+				continue
+			}
+			if result.Jobs > 0 || result.Tombstones > 0 || result.AuditEntries > 0 {
+				logger.Info("Purged %d sync jobs, %d tombstones, and %d audit entries", result.Jobs, result.Tombstones, result.AuditEntries)
+			}
+		}
+	}
+}
+
+// handlePurge purges by the retention policy now rather than at the next
+// scheduled purge
+func (s *MetadataService) handlePurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := s.Purge(r.Context(), s.retention, time.Now())
+	if err != nil {
+		logger.Error("Purge failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Purged %d sync jobs, %d tombstones, and %d audit entries", result.Jobs, result.Tombstones, result.AuditEntries)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestPurge(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, record := range []*models.SyncMetadata{
+		{FilePath: "old-tombstone.md", Status: tombstoneStatus, LastSyncedAt: now.AddDate(0, 0, -40), ChunkIDs: []string{"v1"}},
+		{FilePath: "new-tombstone.md", Status: tombstoneStatus, LastSyncedAt: now.AddDate(0, 0, -10)},
+		{FilePath: "old-synced.md", Status: "synced", LastSyncedAt: now.AddDate(0, 0, -400)},
+	} {
+		record.ProjectID, record.Repository, record.LastCommitSHA = "p", "org/repo", "sha"
+		if err := s.SaveSyncMetadata(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := &models.SyncJob{ProjectID: "p"}
+	running := &models.SyncJob{ProjectID: "p"}
+	for _, job := range []*models.SyncJob{old, running} {
+		if err := s.CreateSyncJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.UpdateSyncJob(ctx, old.ID, &SyncJobUpdate{Status: models.SyncJobCompleted}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateSyncJob(ctx, running.ID, &SyncJobUpdate{Status: models.SyncJobRunning}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Now only the tombstone older than 30 days goes
+	policy := RetentionPolicy{Jobs: 90 * 24 * time.Hour, Tombstones: 30 * 24 * time.Hour}
+	result, err := s.Purge(ctx, policy, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Jobs != 0 || result.Tombstones != 1 {
+		t.Errorf("purge now = %+v, want 0 jobs and 1 tombstone", result)
+	}
+	if _, err := s.GetSyncMetadata(ctx, "p", "org/repo", "old-tombstone.md"); !isNotFound(err) {
+		t.Errorf("old tombstone: %v, want it purged", err)
+	}
+	entries, err := s.ListAudit(ctx, AuditFilter{ProjectID: "p", Since: now.Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var purged []string
+	for _, e := range entries {
+		if e.Action == auditDelete || e.Action == auditPurge {
+			purged = append(purged, e.Action+" "+e.EntityKey)
+		}
+	}
+	if want := "delete org/repo/old-tombstone.md, purge p"; strings.Join(purged, ", ") != want {
+		t.Errorf("purge audited as %v, want %s", purged, want)
+	}
+	for _, file := range []string{"new-tombstone.md", "old-synced.md"} {
+		if _, err := s.GetSyncMetadata(ctx, "p", "org/repo", file); err != nil {
+			t.Errorf("%s: %v, want it kept", file, err)
+		}
+	}
+
+	// 100 days on the completed job has expired too, but never a running one
+	result, err = s.Purge(ctx, policy, now.AddDate(0, 0, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Jobs != 1 {
+		t.Errorf("purge in 100 days = %+v, want 1 job", result)
+	}
+	if _, err := s.GetSyncJob(ctx, running.ID); err != nil {
+		t.Errorf("running job: %v, want it kept", err)
+	}
+
+	// Audit entries go once they are older than their retention
+	result, err = s.Purge(ctx, RetentionPolicy{Audit: 90 * 24 * time.Hour}, now.AddDate(0, 0, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left, err := s.ListAudit(ctx, AuditFilter{}); err != nil || result.AuditEntries == 0 || len(left) != 0 {
+		t.Errorf("audit purge = %+v leaving %d entries, %v; want every entry purged", result, len(left), err)
+	}
+
+	// Zero retention keeps everything
+	if result, err := s.Purge(ctx, RetentionPolicy{}, now.AddDate(10, 0, 0)); err != nil || result.Jobs+result.Tombstones+result.AuditEntries != 0 {
+		t.Errorf("purge without retention = %+v, %v; want nothing purged", result, err)
+	}
+}
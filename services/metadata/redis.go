@@ -0,0 +1,575 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// DriverRedis selects the Redis metadata store with METADATA_DB_DRIVER
+const DriverRedis = "redis"
+
+// RedisStore keeps file records, projects, and repository sync states in
+// Redis, for deployments where the metadata is cheap to rebuild and write
+// throughput matters more than the SQL-only features (stats, jobs, export,
+// purge). Every file record is a hash, listed in a sorted set per project
+//
+//	reposync:file:<project>:<repository>:<path>  hash of the record's fields
+//	reposync:files:<project>                     sorted set of the project's file keys, scored by record id
+//	reposync:project:<id>                        hash of a project's settings
+//	reposync:projects                            set of project ids
+//	reposync:repo-state:<project>                hash of repository -> state JSON
+//	reposync:next-id                             counter for record ids
+//
+// Writes of a batch run in one MULTI/EXEC transaction, WATCHing the keys
+// whose versions they check.
+type RedisStore struct {
+	client *redisClient
+}
+
+const redisKeyPrefix = "reposync:"
+
+func redisFileKey(projectID, repository, filePath string) string {
+	return redisKeyPrefix + "file:" + projectID + ":" + repository + ":" + filePath
+}
+
+func redisFilesKey(projectID string) string { return redisKeyPrefix + "files:" + projectID }
+
+func redisProjectKey(id string) string { return redisKeyPrefix + "project:" + id }
+
+func redisRepoStatesKey(projectID string) string { return redisKeyPrefix + "repo-state:" + projectID }
+
+const (
+	redisProjectsKey = redisKeyPrefix + "projects"
+	redisNextIDKey   = redisKeyPrefix + "next-id"
+)
+
+// NewRedisStore connects to the Redis server at a redis:// URL
+func NewRedisStore(ctx context.Context, rawURL string) (*RedisStore, error) {
+	client, err := newRedisClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	store := &RedisStore{client: client}
+	if err := store.Ping(ctx); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	return store, nil
+}
+
+// Ping checks the connection to Redis
+func (r *RedisStore) Ping(ctx context.Context) error {
+	_, err := r.client.do(ctx, "PING")
+	return err
+}
+
+// Close closes the connections to Redis
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
+
+func (r *RedisStore) SaveSyncMetadata(ctx context.Context, metadata *models.SyncMetadata) error {
+	return r.SaveSyncBatch(ctx, &models.SyncBatch{Records: []*models.SyncMetadata{metadata}})
+}
+
+// SaveSyncBatch saves file records and repository sync states in one
+// transaction, checking versions as the SQL store does
+func (r *RedisStore) SaveSyncBatch(ctx context.Context, batch *models.SyncBatch) error {
+	if err := r.save(ctx, nil, batch.Records, batch.RepoStates); err != nil {
+		return dbError("failed to save sync metadata", err)
+	}
+	return nil
+}
+
+// SaveProject saves a project's settings
+func (r *RedisStore) SaveProject(ctx context.Context, project *models.Project) error {
+	return r.SaveProjectWithRepoStates(ctx, project, nil)
+}
+
+// SaveProjectWithRepoStates saves a project and the commits its
+// repositories start syncing from in one transaction
+func (r *RedisStore) SaveProjectWithRepoStates(ctx context.Context, project *models.Project, states []*models.RepoSyncState) error {
+	if err := r.save(ctx, project, nil, states); err != nil {
+		return dbError("failed to save project", err)
+	}
+	return nil
+}
+
+// SaveRepoSyncState records the commit a repository was synced at
+func (r *RedisStore) SaveRepoSyncState(ctx context.Context, state *models.RepoSyncState) error {
+	if err := r.save(ctx, nil, nil, []*models.RepoSyncState{state}); err != nil {
+		return dbError("failed to save repository sync state", err)
+	}
+	return nil
+}
+
+// redisSaveAttempts bounds the retries of a save whose keys other writers
+// keep changing
+const redisSaveAttempts = 5
+
+// errAborted is a MULTI/EXEC aborted by a write to a WATCHed key
+var errAborted = errors.Conflict("metadata changed by a concurrent write")
+
+// save writes a project, file records, and repository sync states in one
+// MULTI/EXEC. The keys written are WATCHed while their stored ids and
+// versions are read; when another write to them aborts the transaction, it
+// is retried with the new versions, so only a stale version conflicts.
+func (r *RedisStore) save(ctx context.Context, project *models.Project, records []*models.SyncMetadata, states []*models.RepoSyncState) error {
+	var err error
+	for attempt := 0; attempt < redisSaveAttempts; attempt++ {
+		if err = r.saveOnce(ctx, project, records, states); err != errAborted {
+			return err
+		}
+	}
+	return err
+}
+
+func (r *RedisStore) saveOnce(ctx context.Context, project *models.Project, records []*models.SyncMetadata, states []*models.RepoSyncState) error {
+	return r.client.withConn(ctx, func(conn *redisConn) error {
+		watch := []interface{}{"WATCH"}
+		reads := [][]interface{}{nil}
+		for _, m := range records {
+			key := redisFileKey(m.ProjectID, m.Repository, m.FilePath)
+			watch = append(watch, key)
+			reads = append(reads, []interface{}{"HMGET", key, "id", "version"})
+		}
+		for _, state := range states {
+			key := redisRepoStatesKey(state.ProjectID)
+			watch = append(watch, key)
+			reads = append(reads, []interface{}{"HGET", key, state.Repository})
+		}
+		if len(watch) == 1 {
+			reads = reads[1:]
+		} else {
+			reads[0] = watch
+		}
+
+		var replies []interface{}
+		if len(reads) > 0 {
+			var err error
+			if replies, err = conn.pipeline(reads...); err != nil {
+				return err
+			}
+			if len(watch) > 1 {
+				replies = replies[1:]
+			}
+		}
+
+		// Stored ids and versions of the records, and versions of the states
+		ids := make([]int64, len(records))
+		versions := make([]int64, len(records))
+		newRecords := 0
+		for i, m := range records {
+			fields, _ := replies[i].([]interface{})
+			if len(fields) == 2 {
+				ids[i], _ = strconv.ParseInt(replyString(fields[0]), 10, 64)
+				versions[i], _ = strconv.ParseInt(replyString(fields[1]), 10, 64)
+			}
+			if m.Version > 0 && m.Version != versions[i] {
+				return errors.Conflict(fmt.Sprintf("sync metadata of %s/%s changed since version %d", m.Repository, m.FilePath, m.Version))
+			}
+			if m.Create && ids[i] != 0 {
+				return errors.Conflict(fmt.Sprintf("sync metadata of %s/%s already exists", m.Repository, m.FilePath))
+			}
+			if ids[i] == 0 {
+				newRecords++
+			}
+		}
+		stateVersions := make([]int64, len(states))
+		for i, state := range states {
+			data := replyString(replies[len(records)+i])
+			if state.Create && data != "" {
+				return errors.Conflict(fmt.Sprintf("sync state of %s already exists", state.Repository))
+			}
+			if data != "" {
+				var stored models.RepoSyncState
+				if err := json.Unmarshal([]byte(data), &stored); err == nil {
+					stateVersions[i] = stored.Version
+				}
+			}
+			if state.Version > 0 && state.Version != stateVersions[i] {
+				return errors.Conflict(fmt.Sprintf("sync state of %s changed since version %d", state.Repository, state.Version))
+			}
+		}
+
+		// New records take ids from the counter, which isn't WATCHed, so an
+		// aborted transaction only leaves a gap
+		if newRecords > 0 {
+			reply, err := conn.pipeline([]interface{}{"INCRBY", redisNextIDKey, newRecords})
+			if err != nil {
+				return err
+			}
+			next, _ := reply[0].(int64)
+			next -= int64(newRecords)
+			for i := range ids {
+				if ids[i] == 0 {
+					next++
+					ids[i] = next
+				}
+			}
+		}
+
+		tx := [][]interface{}{{"MULTI"}}
+		if project != nil {
+			key := redisProjectKey(project.ID)
+			tx = append(tx,
+				append([]interface{}{"HSET", key}, projectFields(project)...),
+				[]interface{}{"HSETNX", key, "created_at", time.Now().UTC().Format(time.RFC3339Nano)},
+				[]interface{}{"SADD", redisProjectsKey, project.ID})
+		}
+		for i, m := range records {
+			key := redisFileKey(m.ProjectID, m.Repository, m.FilePath)
+			tx = append(tx,
+				append([]interface{}{"HSET", key}, recordFields(m, ids[i], versions[i]+1)...),
+				[]interface{}{"ZADD", redisFilesKey(m.ProjectID), ids[i], key})
+		}
+		stored := make([]models.RepoSyncState, len(states))
+		for i, state := range states {
+			stored[i] = *state
+			stored[i].LastSyncedAt = state.LastSyncedAt.UTC()
+			stored[i].Version = stateVersions[i] + 1
+			data, _ := json.Marshal(&stored[i])
+			tx = append(tx, []interface{}{"HSET", redisRepoStatesKey(state.ProjectID), state.Repository, string(data)})
+		}
+		tx = append(tx, []interface{}{"EXEC"})
+
+		replies, err := conn.pipeline(tx...)
+		if err != nil {
+			return err
+		}
+		if replies[len(replies)-1] == nil {
+			return errAborted
+		}
+
+		for i, m := range records {
+			m.ID, m.Version = ids[i], versions[i]+1
+		}
+		for i, state := range states {
+			state.Version = stored[i].Version
+		}
+		return nil
+	})
+}
+
+// recordFields are the hash fields of a file record. An empty embedding
+// model leaves the stored one, as in the SQL store.
+func recordFields(m *models.SyncMetadata, id, version int64) []interface{} {
+	chunkIDs, _ := json.Marshal(m.ChunkIDs)
+	fields := []interface{}{
+		"id", id,
+		"project_id", m.ProjectID,
+		"repository", m.Repository,
+		"file_path", m.FilePath,
+		"last_commit_sha", m.LastCommitSHA,
+		"last_synced_at", m.LastSyncedAt.UTC().Format(time.RFC3339Nano),
+		"embedding_count", m.EmbeddingCount,
+		"status", m.Status,
+		"blob_sha", m.BlobSHA,
+		"chunk_ids", string(chunkIDs),
+		"version", version,
+	}
+	if m.EmbeddingModel != "" {
+		fields = append(fields, "embedding_model", m.EmbeddingModel)
+	}
+	return fields
+}
+
+// parseRecord reads a file record from its hash fields
+func parseRecord(hash map[string]string) *models.SyncMetadata {
+	m := &models.SyncMetadata{
+		ProjectID:      hash["project_id"],
+		Repository:     hash["repository"],
+		FilePath:       hash["file_path"],
+		LastCommitSHA:  hash["last_commit_sha"],
+		Status:         hash["status"],
+		BlobSHA:        hash["blob_sha"],
+		EmbeddingModel: hash["embedding_model"],
+	}
+	m.ID, _ = strconv.ParseInt(hash["id"], 10, 64)
+	m.Version, _ = strconv.ParseInt(hash["version"], 10, 64)
+	m.EmbeddingCount, _ = strconv.Atoi(hash["embedding_count"])
+	m.LastSyncedAt, _ = time.Parse(time.RFC3339Nano, hash["last_synced_at"])
+	_ = json.Unmarshal([]byte(hash["chunk_ids"]), &m.ChunkIDs)
+	return m
+}
+
+// projectFields are the hash fields of a project's settings
+func projectFields(project *models.Project) []interface{} {
+	allowedExt, _ := json.Marshal(project.AllowedExtensions)
+	excludePat, _ := json.Marshal(project.ExcludePatterns)
+	strategies, _ := json.Marshal(project.ChunkStrategies)
+	return []interface{}{
+		"id", project.ID,
+		"name", project.Name,
+		"organization", project.Organization,
+		"filter_keyword", project.FilterKeyword,
+		"namespace", project.Namespace,
+		"enabled", strconv.FormatBool(project.Enabled),
+		"allowed_extensions", string(allowedExt),
+		"exclude_patterns", string(excludePat),
+		"chunk_strategies", string(strategies),
+		"updated_at", time.Now().UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// parseProject reads a project's settings from its hash fields
+func parseProject(hash map[string]string) *models.Project {
+	project := &models.Project{
+		ID:            hash["id"],
+		Name:          hash["name"],
+		Organization:  hash["organization"],
+		FilterKeyword: hash["filter_keyword"],
+		Namespace:     hash["namespace"],
+	}
+	project.Enabled, _ = strconv.ParseBool(hash["enabled"])
+	_ = json.Unmarshal([]byte(hash["allowed_extensions"]), &project.AllowedExtensions)
+	_ = json.Unmarshal([]byte(hash["exclude_patterns"]), &project.ExcludePatterns)
+	_ = json.Unmarshal([]byte(hash["chunk_strategies"]), &project.ChunkStrategies)
+	project.CreatedAt, _ = time.Parse(time.RFC3339Nano, hash["created_at"])
+	project.UpdatedAt, _ = time.Parse(time.RFC3339Nano, hash["updated_at"])
+	return project
+}
+
+func (r *RedisStore) GetSyncMetadata(ctx context.Context, projectID, repository, filePath string) (*models.SyncMetadata, error) {
+	reply, err := r.client.do(ctx, "HGETALL", redisFileKey(projectID, repository, filePath))
+	if err != nil {
+		return nil, errors.Database("failed to get sync metadata", err)
+	}
+	hash := replyHash(reply)
+	if len(hash) == 0 {
+		return nil, errors.NotFound("sync metadata")
+	}
+	return parseRecord(hash), nil
+}
+
+func (r *RedisStore) ListSyncMetadata(ctx context.Context, projectID string) ([]*models.SyncMetadata, error) {
+	return r.listSyncMetadata(ctx, projectID, ListFilter{})
+}
+
+// redisListBatch is the number of file keys read per ZRANGEBYSCORE while
+// listing
+const redisListBatch = 500
+
+// listSyncMetadata lists a project's file records matching filter, in id
+// order. Records are read from the sorted set a batch at a time after the
+// cursor, and filtered here since Redis keeps no index by repository or
+// status, until the page is full.
+func (r *RedisStore) listSyncMetadata(ctx context.Context, projectID string, filter ListFilter) ([]*models.SyncMetadata, error) {
+	var results []*models.SyncMetadata
+	after := filter.AfterID
+	for {
+		reply, err := r.client.do(ctx, "ZRANGEBYSCORE", redisFilesKey(projectID),
+			"("+strconv.FormatInt(after, 10), "+inf", "WITHSCORES", "LIMIT", 0, redisListBatch)
+		if err != nil {
+			return nil, errors.Database("failed to list sync metadata", err)
+		}
+		items, _ := reply.([]interface{})
+		if len(items) < 2 {
+			return results, nil
+		}
+		keys := make([]interface{}, 0, len(items)/2)
+		for i := 0; i+1 < len(items); i += 2 {
+			keys = append(keys, items[i])
+		}
+		after, _ = strconv.ParseInt(replyString(items[len(items)-1]), 10, 64)
+
+		hashes, err := r.readHashes(ctx, keys)
+		if err != nil {
+			return nil, errors.Database("failed to list sync metadata", err)
+		}
+		for _, hash := range hashes {
+			m := parseRecord(hash)
+			if (filter.Repository != "" && m.Repository != filter.Repository) ||
+				(filter.Status != "" && m.Status != filter.Status) || (filter.Status == "" && m.Status == tombstoneStatus) {
+				continue
+			}
+			results = append(results, m)
+			if filter.Limit > 0 && len(results) == filter.Limit {
+				return results, nil
+			}
+		}
+		if len(keys) < redisListBatch {
+			return results, nil
+		}
+	}
+}
+
+// latestSyncMetadata returns the most recently synced file record of a
+// repository
+func (r *RedisStore) latestSyncMetadata(ctx context.Context, projectID, repository string) (*models.SyncMetadata, error) {
+	records, err := r.listSyncMetadata(ctx, projectID, ListFilter{Repository: repository})
+	if err != nil {
+		return nil, err
+	}
+	var latest *models.SyncMetadata
+	for _, m := range records {
+		if latest == nil || !m.LastSyncedAt.Before(latest.LastSyncedAt) {
+			latest = m
+		}
+	}
+	if latest == nil {
+		return nil, errors.NotFound("sync metadata")
+	}
+	return latest, nil
+}
+
+func (r *RedisStore) DeleteSyncMetadata(ctx context.Context, projectID, repository, filePath string) error {
+	key := redisFileKey(projectID, repository, filePath)
+	err := r.client.withConn(ctx, func(conn *redisConn) error {
+		_, err := conn.pipeline(
+			[]interface{}{"MULTI"},
+			[]interface{}{"DEL", key},
+			[]interface{}{"ZREM", redisFilesKey(projectID), key},
+			[]interface{}{"EXEC"})
+		return err
+	})
+	if err != nil {
+		return errors.Database("failed to delete sync metadata", err)
+	}
+	return nil
+}
+
+func (r *RedisStore) GetProject(ctx context.Context, projectID string) (*models.Project, error) {
+	reply, err := r.client.do(ctx, "HGETALL", redisProjectKey(projectID))
+	if err != nil {
+		return nil, errors.Database("failed to get project", err)
+	}
+	hash := replyHash(reply)
+	if len(hash) == 0 {
+		return nil, errors.NotFound("project")
+	}
+	return parseProject(hash), nil
+}
+
+// ListProjects returns every project, by id
+func (r *RedisStore) ListProjects(ctx context.Context) ([]*models.Project, error) {
+	hashes, err := r.readSet(ctx, redisProjectsKey, redisProjectKey)
+	if err != nil {
+		return nil, errors.Database("failed to list projects", err)
+	}
+
+	projects := make([]*models.Project, 0, len(hashes))
+	for _, hash := range hashes {
+		projects = append(projects, parseProject(hash))
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects, nil
+}
+
+func (r *RedisStore) DeleteProject(ctx context.Context, projectID string) error {
+	err := r.client.withConn(ctx, func(conn *redisConn) error {
+		_, err := conn.pipeline(
+			[]interface{}{"MULTI"},
+			[]interface{}{"DEL", redisProjectKey(projectID)},
+			[]interface{}{"SREM", redisProjectsKey, projectID},
+			[]interface{}{"EXEC"})
+		return err
+	})
+	if err != nil {
+		return errors.Database("failed to delete project", err)
+	}
+	return nil
+}
+
+// GetRepoSyncState returns the commit a repository was last synced at
+func (r *RedisStore) GetRepoSyncState(ctx context.Context, projectID, repository string) (*models.RepoSyncState, error) {
+	reply, err := r.client.do(ctx, "HGET", redisRepoStatesKey(projectID), repository)
+	if err != nil {
+		return nil, errors.Database("failed to get repository sync state", err)
+	}
+	data := replyString(reply)
+	if data == "" {
+		return nil, errors.NotFound("repository sync state")
+	}
+	var state models.RepoSyncState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, errors.Database("failed to get repository sync state", err)
+	}
+	return &state, nil
+}
+
+// ListRepoSyncStates returns the sync state of every repository of a project
+func (r *RedisStore) ListRepoSyncStates(ctx context.Context, projectID string) ([]*models.RepoSyncState, error) {
+	reply, err := r.client.do(ctx, "HGETALL", redisRepoStatesKey(projectID))
+	if err != nil {
+		return nil, errors.Database("failed to list repository sync states", err)
+	}
+
+	var results []*models.RepoSyncState
+	for _, data := range replyHash(reply) {
+		var state models.RepoSyncState
+		if err := json.Unmarshal([]byte(data), &state); err != nil {
+			return nil, errors.Database("failed to scan repository sync state", err)
+		}
+		results = append(results, &state)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Repository < results[j].Repository })
+	return results, nil
+}
+
+// readSet reads the hashes at key(member) for each member of a set, in one
+// pipeline; hashes deleted since are left out
+func (r *RedisStore) readSet(ctx context.Context, setKey string, key func(member string) string) ([]map[string]string, error) {
+	reply, err := r.client.do(ctx, "SMEMBERS", setKey)
+	if err != nil {
+		return nil, err
+	}
+	members, _ := reply.([]interface{})
+	keys := make([]interface{}, len(members))
+	for i, member := range members {
+		keys[i] = key(replyString(member))
+	}
+	return r.readHashes(ctx, keys)
+}
+
+// readHashes reads the hashes at keys in one pipeline, in order; hashes
+// deleted since are left out
+func (r *RedisStore) readHashes(ctx context.Context, keys []interface{}) ([]map[string]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	commands := make([][]interface{}, len(keys))
+	for i, key := range keys {
+		commands[i] = []interface{}{"HGETALL", key}
+	}
+	var replies []interface{}
+	err := r.client.withConn(ctx, func(conn *redisConn) (err error) {
+		replies, err = conn.pipeline(commands...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]map[string]string, 0, len(replies))
+	for _, reply := range replies {
+		if hash := replyHash(reply); len(hash) > 0 {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, nil
+}
+
+// replyString returns a string reply, or "" for nil
+func replyString(reply interface{}) string {
+	s, _ := reply.(string)
+	return s
+}
+
+// replyHash returns an HGETALL reply as a map
+func replyHash(reply interface{}) map[string]string {
+	items, _ := reply.([]interface{})
+	hash := make(map[string]string, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		hash[replyString(items[i])] = replyString(items[i+1])
+	}
+	return hash
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// fakeRedis serves the commands the Redis store uses from memory, with
+// WATCH and MULTI/EXEC, over RESP on a local port
+type fakeRedis struct {
+	mu       sync.Mutex
+	strings  map[string]string
+	hashes   map[string]map[string]string
+	sets     map[string]map[string]bool
+	zsets    map[string]map[string]int64 // member scores; the store only scores by id
+	modified map[string]int              // writes per key, for WATCH
+}
+
+func newFakeRedis(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	f := &fakeRedis{
+		strings:  make(map[string]string),
+		hashes:   make(map[string]map[string]string),
+		sets:     make(map[string]map[string]bool),
+		zsets:    make(map[string]map[string]int64),
+		modified: make(map[string]int),
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return "redis://" + ln.Addr().String() + "/0"
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+
+	watched := make(map[string]int)
+	var queued [][]string
+	inMulti := false
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = replyString(item)
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "WATCH":
+			f.mu.Lock()
+			for _, key := range args[1:] {
+				watched[key] = f.modified[key]
+			}
+			f.mu.Unlock()
+			writeFakeReply(w, "OK")
+		case cmd == "MULTI":
+			inMulti, queued = true, nil
+			writeFakeReply(w, "OK")
+		case cmd == "EXEC":
+			f.mu.Lock()
+			aborted := false
+			for key, n := range watched {
+				aborted = aborted || f.modified[key] != n
+			}
+			var results []interface{}
+			if !aborted {
+				results = []interface{}{}
+				for _, q := range queued {
+					results = append(results, f.run(q))
+				}
+			}
+			f.mu.Unlock()
+			if aborted {
+				_, _ = w.WriteString("*-1\r\n")
+			} else {
+				writeFakeReply(w, results)
+			}
+			inMulti, queued, watched = false, nil, make(map[string]int)
+		case inMulti:
+			queued = append(queued, args)
+			writeFakeReply(w, "QUEUED")
+		default:
+			f.mu.Lock()
+			result := f.run(args)
+			f.mu.Unlock()
+			writeFakeReply(w, result)
+		}
+		if r.Buffered() == 0 {
+			_ = w.Flush()
+		}
+	}
+}
+
+// run runs a command with f.mu held
+func (f *fakeRedis) run(args []string) interface{} {
+	key := ""
+	if len(args) > 1 {
+		key = args[1]
+	}
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "PONG"
+	case "INCRBY":
+		n, _ := strconv.ParseInt(f.strings[key], 10, 64)
+		by, _ := strconv.ParseInt(args[2], 10, 64)
+		f.strings[key] = strconv.FormatInt(n+by, 10)
+		f.modified[key]++
+		return n + by
+	case "HSET", "HSETNX":
+		if f.hashes[key] == nil {
+			f.hashes[key] = make(map[string]string)
+		}
+		added := int64(0)
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, ok := f.hashes[key][args[i]]; ok && strings.EqualFold(args[0], "HSETNX") {
+				continue
+			} else if !ok {
+				added++
+			}
+			f.hashes[key][args[i]] = args[i+1]
+		}
+		f.modified[key]++
+		return added
+	case "HGET":
+		if v, ok := f.hashes[key][args[2]]; ok {
+			return v
+		}
+		return nil
+	case "HMGET":
+		values := make([]interface{}, 0, len(args)-2)
+		for _, field := range args[2:] {
+			if v, ok := f.hashes[key][field]; ok {
+				values = append(values, v)
+			} else {
+				values = append(values, nil)
+			}
+		}
+		return values
+	case "HGETALL":
+		values := []interface{}{}
+		for field, v := range f.hashes[key] {
+			values = append(values, field, v)
+		}
+		return values
+	case "SADD", "SREM":
+		if f.sets[key] == nil {
+			f.sets[key] = make(map[string]bool)
+		}
+		for _, member := range args[2:] {
+			f.sets[key][member] = strings.EqualFold(args[0], "SADD")
+		}
+		f.modified[key]++
+		return int64(len(args) - 2)
+	case "SMEMBERS":
+		members := []interface{}{}
+		for member, ok := range f.sets[key] {
+			if ok {
+				members = append(members, member)
+			}
+		}
+		return members
+	case "ZADD":
+		if f.zsets[key] == nil {
+			f.zsets[key] = make(map[string]int64)
+		}
+		for i := 2; i+1 < len(args); i += 2 {
+			score, _ := strconv.ParseInt(args[i], 10, 64)
+			f.zsets[key][args[i+1]] = score
+		}
+		f.modified[key]++
+		return int64((len(args) - 2) / 2)
+	case "ZREM":
+		for _, member := range args[2:] {
+			delete(f.zsets[key], member)
+		}
+		f.modified[key]++
+		return int64(len(args) - 2)
+	case "ZRANGEBYSCORE":
+		// Only the forms the store sends: an exclusive min, +inf, and
+		// optional WITHSCORES and LIMIT
+		low, _ := strconv.ParseInt(strings.TrimPrefix(args[2], "("), 10, 64)
+		withScores, offset, count := false, 0, -1
+		for i := 4; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "WITHSCORES":
+				withScores = true
+			case "LIMIT":
+				offset, _ = strconv.Atoi(args[i+1])
+				count, _ = strconv.Atoi(args[i+2])
+				i += 2
+			}
+		}
+		var members []string
+		for member, score := range f.zsets[key] {
+			if score > low {
+				members = append(members, member)
+			}
+		}
+		sort.Slice(members, func(i, j int) bool { return f.zsets[key][members[i]] < f.zsets[key][members[j]] })
+		members = members[min(offset, len(members)):]
+		if count >= 0 && count < len(members) {
+			members = members[:count]
+		}
+		values := []interface{}{}
+		for _, member := range members {
+			values = append(values, member)
+			if withScores {
+				values = append(values, strconv.FormatInt(f.zsets[key][member], 10))
+			}
+		}
+		return values
+	case "DEL":
+		delete(f.strings, key)
+		delete(f.hashes, key)
+		delete(f.sets, key)
+		delete(f.zsets, key)
+		f.modified[key]++
+		return int64(1)
+	}
+	return redisError("ERR unknown command " + args[0])
+}
+
+func writeFakeReply(w *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		_, _ = w.WriteString("$-1\r\n")
+	case redisError:
+		fmt.Fprintf(w, "-%s\r\n", string(v))
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		if v == "OK" || v == "QUEUED" || v == "PONG" {
+			fmt.Fprintf(w, "+%s\r\n", v)
+		} else {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+		}
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeFakeReply(w, item)
+		}
+	}
+}
+
+func newTestRedisService(t *testing.T) *MetadataService {
+	t.Helper()
+	service, err := NewRedisMetadataService(context.Background(), newFakeRedis(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = service.Close() })
+	return service
+}
+
+func TestRedisStore(t *testing.T) {
+	s := newTestRedisService(t)
+	ctx := context.Background()
+
+	rec := httptest.NewRecorder()
+	s.handleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("health: status = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+
+	project := &models.Project{ID: "p", Name: "Docs", Organization: "org", Enabled: true, AllowedExtensions: []string{".md"}}
+	states := []*models.RepoSyncState{{ProjectID: "p", Repository: "org/a", LastCommitSHA: "s0", LastSyncedAt: time.Now()}}
+	if err := s.store.SaveProjectWithRepoStates(ctx, project, states); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.store.GetProject(ctx, "p"); err != nil || got.Name != "Docs" || !got.Enabled || len(got.AllowedExtensions) != 1 || got.CreatedAt.IsZero() {
+		t.Fatalf("project = %+v, %v", got, err)
+	}
+
+	synced := time.Now().Add(-time.Hour)
+	batch := &models.SyncBatch{
+		Records: []*models.SyncMetadata{
+			{ProjectID: "p", Repository: "org/a", FilePath: "a.md", LastCommitSHA: "s1", LastSyncedAt: synced, Status: "synced", ChunkIDs: []string{"v1", "v2"}},
+			{ProjectID: "p", Repository: "org/a", FilePath: "b.md", LastCommitSHA: "s1", LastSyncedAt: synced.Add(time.Minute), Status: "synced"},
+			{ProjectID: "p", Repository: "org/b", FilePath: "c.md", LastCommitSHA: "s1", LastSyncedAt: synced, Status: "failed"},
+			{ProjectID: "p", Repository: "org/a", FilePath: "d.md", LastCommitSHA: "s1", LastSyncedAt: synced.Add(2 * time.Minute), Status: tombstoneStatus},
+		},
+		RepoStates: []*models.RepoSyncState{{ProjectID: "p", Repository: "org/a", LastCommitSHA: "s1", LastSyncedAt: synced, Version: states[0].Version}},
+	}
+	if err := s.store.SaveSyncBatch(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch.Records[0].ID == 0 || batch.Records[2].ID <= batch.Records[1].ID || batch.RepoStates[0].Version != 2 {
+		t.Fatalf("saved batch records %+v, state %+v; want ids assigned in order and state version 2", batch.Records, batch.RepoStates[0])
+	}
+
+	got, err := s.store.GetSyncMetadata(ctx, "p", "org/a", "a.md")
+	if err != nil || got.Version != 1 || len(got.ChunkIDs) != 2 || !got.LastSyncedAt.Equal(synced) {
+		t.Fatalf("record = %+v, %v", got, err)
+	}
+	if latest, err := s.store.latestSyncMetadata(ctx, "p", "org/a"); err != nil || latest.FilePath != "b.md" {
+		t.Errorf("latest record = %+v, %v; want b.md", latest, err)
+	}
+	failed, err := s.store.listSyncMetadata(ctx, "p", ListFilter{Status: "failed"})
+	if err != nil || len(failed) != 1 || failed[0].FilePath != "c.md" {
+		t.Errorf("failed records = %+v, %v; want c.md", failed, err)
+	}
+	tombstones, err := s.store.listSyncMetadata(ctx, "p", ListFilter{Status: tombstoneStatus})
+	if err != nil || len(tombstones) != 1 || tombstones[0].FilePath != "d.md" {
+		t.Errorf("tombstones = %+v, %v; want d.md", tombstones, err)
+	}
+	page, err := s.store.listSyncMetadata(ctx, "p", ListFilter{AfterID: batch.Records[0].ID, Limit: 1})
+	if err != nil || len(page) != 1 || page[0].FilePath != "b.md" {
+		t.Errorf("page after a.md = %+v, %v; want b.md", page, err)
+	}
+
+	// A stale version conflicts and leaves the record alone
+	stale := *got
+	stale.LastCommitSHA = "s0"
+	got.LastCommitSHA = "s2"
+	if err := s.store.SaveSyncMetadata(ctx, got); err != nil || got.Version != 2 {
+		t.Fatalf("update: version %d, %v; want version 2", got.Version, err)
+	}
+	if err := s.store.SaveSyncMetadata(ctx, &stale); !isConflict(err) {
+		t.Errorf("stale update: %v, want a conflict", err)
+	}
+	if got, _ := s.store.GetSyncMetadata(ctx, "p", "org/a", "a.md"); got.LastCommitSHA != "s2" {
+		t.Errorf("commit after stale update = %s, want s2", got.LastCommitSHA)
+	}
+	err = s.store.SaveSyncMetadata(ctx, &models.SyncMetadata{ProjectID: "p", Repository: "org/a", FilePath: "a.md", LastCommitSHA: "s0", Create: true})
+	if !isConflict(err) {
+		t.Errorf("create of an existing record: %v, want a conflict", err)
+	}
+	err = s.store.SaveRepoSyncState(ctx, &models.RepoSyncState{ProjectID: "p", Repository: "org/a", LastCommitSHA: "s0", LastSyncedAt: time.Now(), Create: true})
+	if !isConflict(err) {
+		t.Errorf("create of an existing state: %v, want a conflict", err)
+	}
+	err = s.store.SaveRepoSyncState(ctx, &models.RepoSyncState{ProjectID: "p", Repository: "org/a", LastCommitSHA: "s0", LastSyncedAt: time.Now(), Version: 1})
+	if !isConflict(err) {
+		t.Errorf("stale state save: %v, want a conflict", err)
+	}
+	if state, err := s.store.GetRepoSyncState(ctx, "p", "org/a"); err != nil || state.LastCommitSHA != "s1" || state.Version != 2 {
+		t.Errorf("state = %+v, %v; want commit s1 at version 2", state, err)
+	}
+
+	if err := s.store.DeleteSyncMetadata(ctx, "p", "org/a", "a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.store.GetSyncMetadata(ctx, "p", "org/a", "a.md"); !isNotFound(err) {
+		t.Errorf("deleted record: %v, want not found", err)
+	}
+	if records, _ := s.store.ListSyncMetadata(ctx, "p"); len(records) != 2 {
+		t.Errorf("%d records after delete, want 2", len(records))
+	}
+	if err := s.store.DeleteProject(ctx, "p"); err != nil {
+		t.Fatal(err)
+	}
+	if projects, err := s.store.ListProjects(ctx); err != nil || len(projects) != 0 {
+		t.Errorf("projects after delete = %+v, %v; want none", projects, err)
+	}
+}
+
+// Concurrent writers of the same record either win or get a conflict;
+// none is lost
+func TestRedisConcurrentSaves(t *testing.T) {
+	s := newTestRedisService(t)
+	ctx := context.Background()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		saved int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			record := &models.SyncMetadata{ProjectID: "p", Repository: "org/a", FilePath: "a.md", LastCommitSHA: fmt.Sprint(i), LastSyncedAt: time.Now()}
+			err := s.store.SaveSyncMetadata(ctx, record)
+			if err != nil && !isConflict(err) {
+				t.Error(err)
+			}
+			if err == nil {
+				mu.Lock()
+				saved++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	got, err := s.store.GetSyncMetadata(ctx, "p", "org/a", "a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != int64(saved) {
+		t.Errorf("version = %d after %d saves", got.Version, saved)
+	}
+	if records, _ := s.store.ListSyncMetadata(ctx, "p"); len(records) != 1 {
+		t.Errorf("%d records, want 1", len(records))
+	}
+}
+
+// Listings page through the sorted set by id, across ZRANGEBYSCORE batches
+func TestRedisListPages(t *testing.T) {
+	s := newTestRedisService(t)
+	ctx := context.Background()
+
+	total := redisListBatch + 10
+	records := make([]*models.SyncMetadata, total)
+	for i := range records {
+		repo := "org/a"
+		if i%3 == 0 {
+			repo = "org/b"
+		}
+		records[i] = &models.SyncMetadata{ProjectID: "p", Repository: repo, FilePath: fmt.Sprintf("doc%03d.md", i),
+			LastCommitSHA: "s1", LastSyncedAt: time.Now(), Status: "synced"}
+	}
+	if err := s.store.SaveSyncBatch(ctx, &models.SyncBatch{Records: records}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.store.DeleteSyncMetadata(ctx, "p", "org/a", "doc001.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   int
+	}{
+		{name: "everything", filter: ListFilter{}, want: total - 1},
+		{name: "pages of 7", filter: ListFilter{Limit: 7}, want: total - 1},
+		{name: "one repository in pages", filter: ListFilter{Repository: "org/b", Limit: 50}, want: (total + 2) / 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []int64
+			filter := tt.filter
+			for {
+				page, err := s.store.listSyncMetadata(ctx, "p", filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, m := range page {
+					ids = append(ids, m.ID)
+				}
+				if filter.Limit == 0 || len(page) < filter.Limit {
+					break
+				}
+				filter.AfterID = page[len(page)-1].ID
+			}
+			if len(ids) != tt.want {
+				t.Fatalf("listed %d records, want %d", len(ids), tt.want)
+			}
+			for i := 1; i < len(ids); i++ {
+				if ids[i] <= ids[i-1] {
+					t.Fatalf("ids out of order at %d: %d after %d", i, ids[i], ids[i-1])
+				}
+			}
+		})
+	}
+}
+
+func TestNotSupported(t *testing.T) {
+	rec := httptest.NewRecorder()
+	notSupported(DriverRedis)(rec, httptest.NewRequest(http.MethodGet, "/stats?project_id=p", nil))
+	if rec.Code != http.StatusNotImplemented || !strings.Contains(rec.Body.String(), "/stats is not supported with METADATA_DB_DRIVER=redis") {
+		t.Errorf("status = %d (%s), want 501 naming the route and driver", rec.Code, rec.Body.String())
+	}
+}
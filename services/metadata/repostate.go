@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// SaveRepoSyncState records the commit a repository was synced at
+func (s *MetadataService) SaveRepoSyncState(ctx context.Context, state *models.RepoSyncState) error {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.writeRepoState(ctx, tx, state)
+	})
+	if err != nil {
+		return dbError("failed to save repository sync state", err)
+	}
+	return nil
+}
+
+// writeRepoState saves a repository's sync state within tx and sets its new
+// version; like file records, a state with a version only replaces the
+// stored one at that version
+func (s *MetadataService) writeRepoState(ctx context.Context, tx *sql.Tx, state *models.RepoSyncState) error {
+	return audited(ctx, tx, repoStateTarget(state.ProjectID, state.Repository), func() error {
+		return s.putRepoState(ctx, tx, state)
+	})
+}
+
+func (s *MetadataService) putRepoState(ctx context.Context, tx *sql.Tx, state *models.RepoSyncState) error {
+	if state.Version > 0 {
+		result, err := tx.ExecContext(ctx, `UPDATE repo_sync_state SET last_commit_sha = ?, last_synced_at = ?, version = version + 1
+			WHERE project_id = ? AND repository = ? AND version = ?`,
+			state.LastCommitSHA, state.LastSyncedAt.UTC(), state.ProjectID, state.Repository, state.Version)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			if err != nil {
+				return err
+			}
+			return errors.Conflict(fmt.Sprintf("sync state of %s changed since version %d", state.Repository, state.Version))
+		}
+	} else if state.Create {
+		var existing int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM repo_sync_state WHERE project_id = ? AND repository = ?`,
+			state.ProjectID, state.Repository).Scan(&existing); err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errors.Conflict(fmt.Sprintf("sync state of %s already exists", state.Repository))
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO repo_sync_state (project_id, repository, last_commit_sha, last_synced_at) VALUES (?, ?, ?, ?)`,
+			state.ProjectID, state.Repository, state.LastCommitSHA, state.LastSyncedAt.UTC()); err != nil {
+			return err
+		}
+	} else if _, err := tx.ExecContext(ctx, s.dialect.saveRepoState,
+		state.ProjectID, state.Repository, state.LastCommitSHA, state.LastSyncedAt.UTC()); err != nil {
+		return err
+	}
+
+	return tx.QueryRowContext(ctx, `SELECT version FROM repo_sync_state WHERE project_id = ? AND repository = ?`,
+		state.ProjectID, state.Repository).Scan(&state.Version)
+}
+
+// GetRepoSyncState returns the commit a repository was last synced at
+func (s *MetadataService) GetRepoSyncState(ctx context.Context, projectID, repository string) (*models.RepoSyncState, error) {
+	query := `SELECT project_id, repository, last_commit_sha, last_synced_at, version
+		FROM repo_sync_state WHERE project_id = ? AND repository = ?`
+
+	var state models.RepoSyncState
+	err := s.db.QueryRowContext(ctx, query, projectID, repository).Scan(
+		&state.ProjectID, &state.Repository, &state.LastCommitSHA, &state.LastSyncedAt, &state.Version)
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("repository sync state")
+	}
+	if err != nil {
+		return nil, errors.Database("failed to get repository sync state", err)
+	}
+	return &state, nil
+}
+
+// ListRepoSyncStates returns the sync state of every repository of a project
+func (s *MetadataService) ListRepoSyncStates(ctx context.Context, projectID string) ([]*models.RepoSyncState, error) {
+	query := `SELECT project_id, repository, last_commit_sha, last_synced_at, version
+		FROM repo_sync_state WHERE project_id = ? ORDER BY repository`
+
+	rows, err := s.db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, errors.Database("failed to list repository sync states", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []*models.RepoSyncState
+	for rows.Next() {
+		var state models.RepoSyncState
+		if err := rows.Scan(&state.ProjectID, &state.Repository, &state.LastCommitSHA, &state.LastSyncedAt, &state.Version); err != nil {
+			return nil, errors.Database("failed to scan repository sync state", err)
+		}
+		results = append(results, &state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Database("failed to list repository sync states", err)
+	}
+	return results, nil
+}
+
+// handleRepoState gets (GET) or sets (PUT or POST) the commit repositories
+// were last synced at
+func (s *MetadataService) handleRepoState(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.getRepoState(w, r)
+	case http.MethodPut, http.MethodPost:
+		s.saveRepoState(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getRepoState returns a repository's sync state, or without repository
+// those of every repository of the project
+func (s *MetadataService) getRepoState(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	projectID, repository := query.Get("project_id"), query.Get("repository")
+	if projectID == "" {
+		http.Error(w, "project_id is required", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		result interface{}
+		err    error
+	)
+	if repository != "" {
+		result, err = s.store.GetRepoSyncState(r.Context(), projectID, repository)
+	} else {
+		var states []*models.RepoSyncState
+		states, err = s.store.ListRepoSyncStates(r.Context(), projectID)
+		if states == nil {
+			states = []*models.RepoSyncState{}
+		}
+		result = states
+	}
+	if err != nil {
+		if isNotFound(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to get repository sync state: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// saveRepoState records a repository's sync state from the request body
+func (s *MetadataService) saveRepoState(w http.ResponseWriter, r *http.Request) {
+	var state models.RepoSyncState
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := prepareRepoState(&state); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.SaveRepoSyncState(r.Context(), &state); err != nil {
+		if isConflict(err) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		logger.Error("Failed to save repository sync state: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(state)
+}
+
+// prepareRepoState checks a sync state to save and fills in its sync time if
+// missing
+func prepareRepoState(state *models.RepoSyncState) error {
+	if state.ProjectID == "" || state.Repository == "" || state.LastCommitSHA == "" {
+		return errors.Validation("project_id, repository, and last_commit_sha are required")
+	}
+	if state.LastSyncedAt.IsZero() {
+		state.LastSyncedAt = time.Now()
+	}
+	return nil
+}
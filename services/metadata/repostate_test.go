@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestRepoState(t *testing.T) {
+	s := newTestService(t)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleRepoState(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/repo-state?project_id=p&repository=org/a", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown repository: status = %d, want 404", rec.Code)
+	}
+	for _, body := range []string{
+		`{"project_id":"p","repository":"org/a","last_commit_sha":"c1"}`,
+		`{"project_id":"p","repository":"org/b","last_commit_sha":"c1"}`,
+		`{"project_id":"p","repository":"org/a","last_commit_sha":"c2"}`,
+	} {
+		if rec := do(http.MethodPut, "/repo-state", body); rec.Code != http.StatusOK {
+			t.Fatalf("save %s: status = %d (%s), want 200", body, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := do(http.MethodPut, "/repo-state", `{"project_id":"p","repository":"org/a"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("save without a commit: status = %d, want 400", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/repo-state?project_id=p&repository=org/a", "")
+	var state models.RepoSyncState
+	if err := json.NewDecoder(rec.Body).Decode(&state); err != nil {
+		t.Fatal(err)
+	}
+	if state.LastCommitSHA != "c2" || state.LastSyncedAt.IsZero() {
+		t.Errorf("org/a state = %+v, want commit c2 and a sync time", state)
+	}
+
+	rec = do(http.MethodGet, "/repo-state?project_id=p", "")
+	var states []*models.RepoSyncState
+	if err := json.NewDecoder(rec.Body).Decode(&states); err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 2 || states[0].Repository != "org/a" || states[1].Repository != "org/b" {
+		t.Errorf("states = %+v, want org/a and org/b", states)
+	}
+}
+
+func TestSyncBatch(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleBulkMetadata(rec, httptest.NewRequest(http.MethodPost, "/metadata/bulk", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`{"records":[{"project_id":"p","repository":"org/a","file_path":"a.md","last_commit_sha":"c1"}],
+		"repo_states":[{"project_id":"p","repository":"org/a","last_commit_sha":"c1"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+	if state, err := s.GetRepoSyncState(ctx, "p", "org/a"); err != nil || state.LastCommitSHA != "c1" {
+		t.Fatalf("org/a state = %+v, %v; want commit c1", state, err)
+	}
+
+	// An invalid state rejects the records with it
+	rec = post(`{"records":[{"project_id":"p","repository":"org/a","file_path":"a.md","last_commit_sha":"c2"}],
+		"repo_states":[{"project_id":"p","repository":"org/a"}]}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "repo state 0") {
+		t.Fatalf("status = %d (%s), want 400 naming repo state 0", rec.Code, rec.Body.String())
+	}
+
+	// A failed write rolls back the records saved before it
+	if _, err := s.db.Exec(`DROP TABLE repo_sync_state`); err != nil {
+		t.Fatal(err)
+	}
+	err := s.SaveSyncBatch(ctx, &models.SyncBatch{
+		Records:    []*models.SyncMetadata{{ProjectID: "p", Repository: "org/a", FilePath: "a.md", LastCommitSHA: "c2", LastSyncedAt: time.Now()}},
+		RepoStates: []*models.RepoSyncState{{ProjectID: "p", Repository: "org/a", LastCommitSHA: "c2", LastSyncedAt: time.Now()}},
+	})
+	if err == nil {
+		t.Fatal("saving a state without its table succeeded")
+	}
+	if got, err := s.GetSyncMetadata(ctx, "p", "org/a", "a.md"); err != nil || got.LastCommitSHA != "c1" {
+		t.Errorf("a.md = %+v, %v after a failed batch; want commit c1", got, err)
+	}
+}
+
+func TestProjectRepoStates(t *testing.T) {
+	s := newTestService(t)
+	create := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleProjects(rec, httptest.NewRequest(http.MethodPost, "/projects", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := create(`{"id":"p","repo_states":[{"repository":"org/a","last_commit_sha":"c1"},{"repository":"org/b"}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid state: status = %d, want 400", rec.Code)
+	}
+	if _, err := s.GetProject(context.Background(), "p"); !isNotFound(err) {
+		t.Errorf("project saved with an invalid state: %v", err)
+	}
+
+	rec = create(`{"id":"p","repo_states":[{"repository":"org/a","last_commit_sha":"c1"}]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d (%s), want 201", rec.Code, rec.Body.String())
+	}
+	state, err := s.GetRepoSyncState(context.Background(), "p", "org/a")
+	if err != nil || state.LastCommitSHA != "c1" {
+		t.Errorf("org/a state = %+v, %v; want commit c1", state, err)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A minimal Redis client speaking RESP2, enough for the Redis metadata
+// store: pipelined commands and MULTI/EXEC over pooled connections.
+
+const (
+	// Idle connections kept for reuse
+	redisPoolSize = 16
+	// Deadline of a round trip whose context has none
+	redisTimeout = 10 * time.Second
+)
+
+// redisError is an error reply from Redis
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// redisClient hands out pooled connections to one Redis server
+type redisClient struct {
+	addr     string
+	username string
+	password string
+	db       int
+
+	mu     sync.Mutex
+	idle   []*redisConn
+	closed bool
+}
+
+// newRedisClient parses a redis://[[user]:password@]host[:port][/db] URL
+func newRedisClient(rawURL string) (*redisClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("invalid Redis URL: scheme must be redis, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid Redis URL: no host")
+	}
+
+	client := &redisClient{addr: u.Host}
+	if u.Port() == "" {
+		client.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		client.username = u.User.Username()
+		client.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if client.db, err = strconv.Atoi(db); err != nil || client.db < 0 {
+			return nil, fmt.Errorf("invalid Redis URL: database %q is not a number", db)
+		}
+	}
+	return client, nil
+}
+
+// do runs one command
+func (c *redisClient) do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	var reply interface{}
+	err := c.withConn(ctx, func(conn *redisConn) error {
+		replies, err := conn.pipeline(args)
+		if err != nil {
+			return err
+		}
+		reply = replies[0]
+		return nil
+	})
+	return reply, err
+}
+
+// withConn runs fn on a connection of its own. A connection fn fails on is
+// closed rather than reused, since it may be mid-reply or still WATCHing.
+func (c *redisClient) withConn(ctx context.Context, fn func(conn *redisConn) error) error {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	err = fn(conn)
+	c.put(conn, err == nil)
+	return err
+}
+
+// get returns an idle connection or dials a new one
+func (c *redisClient) get(ctx context.Context) (*redisConn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("redis: client closed")
+	}
+	if n := len(c.idle); n > 0 {
+		conn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return conn, nil
+	}
+	c.mu.Unlock()
+
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	conn := &redisConn{Conn: netConn, r: bufio.NewReader(netConn), w: bufio.NewWriter(netConn)}
+	_ = conn.SetDeadline(time.Now().Add(redisTimeout))
+
+	var setup [][]interface{}
+	if c.password != "" {
+		if c.username != "" {
+			setup = append(setup, []interface{}{"AUTH", c.username, c.password})
+		} else {
+			setup = append(setup, []interface{}{"AUTH", c.password})
+		}
+	}
+	if c.db != 0 {
+		setup = append(setup, []interface{}{"SELECT", c.db})
+	}
+	if len(setup) > 0 {
+		if _, err := conn.pipeline(setup...); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// put returns a connection to the pool if reuse is true, and closes it
+// otherwise or if the pool is full
+func (c *redisClient) put(conn *redisConn, reuse bool) {
+	c.mu.Lock()
+	if reuse && !c.closed && len(c.idle) < redisPoolSize {
+		c.idle = append(c.idle, conn)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	_ = conn.Close()
+}
+
+// Close closes the idle connections; connections in use close when returned
+func (c *redisClient) Close() error {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle, c.closed = nil, true
+	c.mu.Unlock()
+
+	for _, conn := range idle {
+		_ = conn.Close()
+	}
+	return nil
+}
+
+// redisConn is a connection with buffered reads and writes
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// pipeline sends commands in one write and reads a reply for each. Replies
+// are strings, int64s, nil, or []interface{} of them; an error reply to any
+// command is returned as the error once all replies are read.
+func (c *redisConn) pipeline(commands ...[]interface{}) ([]interface{}, error) {
+	for _, args := range commands {
+		writeCommand(c.w, args)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+
+	replies := make([]interface{}, len(commands))
+	var replyErr error
+	for i := range commands {
+		reply, err := readReply(c.r)
+		if err != nil {
+			if _, ok := err.(redisError); !ok {
+				return nil, err
+			}
+			if replyErr == nil {
+				replyErr = err
+			}
+		}
+		replies[i] = reply
+	}
+	return replies, replyErr
+}
+
+// writeCommand encodes a command as an array of bulk strings
+func writeCommand(w *bufio.Writer, args []interface{}) {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		case int:
+			s = strconv.Itoa(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		default:
+			s = fmt.Sprint(v)
+		}
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+	}
+}
+
+// readReply decodes one reply. An error reply is returned as a redisError,
+// with the arrays around it still read in full.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		n, err := strconv.ParseInt(body, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed integer %q", body)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		var itemErr error
+		for i := range items {
+			item, err := readReply(r)
+			if err != nil {
+				if _, ok := err.(redisError); !ok {
+					return nil, err
+				}
+				if itemErr == nil {
+					itemErr = err
+				}
+			}
+			items[i] = item
+		}
+		return items, itemErr
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
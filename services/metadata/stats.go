@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// SyncStats aggregates a project's sync records per repository and status
+func (s *MetadataService) SyncStats(ctx context.Context, projectID string) (*models.SyncStats, error) {
+	query := `SELECT repository, status, COUNT(*), COALESCE(SUM(embedding_count), 0), MAX(last_synced_at)
+		FROM sync_metadata WHERE project_id = ? GROUP BY repository, status ORDER BY repository`
+
+	rows, err := s.db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, errors.Database("failed to compute sync stats", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	stats := &models.SyncStats{
+		ProjectID:    projectID,
+		Statuses:     make(map[string]int64),
+		Repositories: []*models.RepositoryStats{},
+	}
+	var repo *models.RepositoryStats
+	for rows.Next() {
+		var (
+			repository, status string
+			files, embeddings  int64
+			lastSynced         sql.NullString
+		)
+		if err := rows.Scan(&repository, &status, &files, &embeddings, &lastSynced); err != nil {
+			return nil, errors.Database("failed to scan sync stats", err)
+		}
+		synced, err := parseDBTime(lastSynced.String)
+		if err != nil {
+			return nil, errors.Database("failed to scan sync stats", err)
+		}
+
+		if repo == nil || repo.Repository != repository {
+			repo = &models.RepositoryStats{Repository: repository, Statuses: make(map[string]int64)}
+			stats.Repositories = append(stats.Repositories, repo)
+		}
+		repo.Files += files
+		repo.Embeddings += embeddings
+		repo.Statuses[status] += files
+		if synced.After(repo.LastSyncedAt) {
+			repo.LastSyncedAt = synced
+		}
+
+		stats.Files += files
+		stats.Embeddings += embeddings
+		stats.Statuses[status] += files
+		if stats.LastSyncedAt == nil || synced.After(*stats.LastSyncedAt) {
+			last := synced
+			stats.LastSyncedAt = &last
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Database("failed to compute sync stats", err)
+	}
+
+	return stats, nil
+}
+
+// dbTimeLayouts are the forms an aggregated DATETIME comes back in: SQLite
+// returns the text it stored, MySQL a time formatted as RFC 3339
+var dbTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// parseDBTime parses a DATETIME that was not scanned into a time.Time, the
+// zero time for ""
+func parseDBTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range dbTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+// handleStats returns aggregate sync statistics of a project
+func (s *MetadataService) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectID := r.URL.Query().Get("project_id")
+	if projectID == "" {
+		http.Error(w, "project_id is required", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := s.SyncStats(r.Context(), projectID)
+	if err != nil {
+		logger.Error("Failed to compute sync stats: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestSyncStats(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	for i, rec := range []struct {
+		repo, file, status string
+		embeddings         int
+	}{
+		{"org/a", "1.md", "synced", 3},
+		{"org/a", "2.md", "synced", 4},
+		{"org/a", "3.md", "failed", 0},
+		{"org/b", "1.md", "synced", 5},
+	} {
+		err := s.SaveSyncMetadata(ctx, &models.SyncMetadata{
+			ProjectID: "p", Repository: rec.repo, FilePath: rec.file, LastCommitSHA: "sha",
+			LastSyncedAt: base.Add(time.Duration(i) * time.Hour), EmbeddingCount: rec.embeddings, Status: rec.status,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleStats(rec, httptest.NewRequest(http.MethodGet, "/stats?project_id=p", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+	var stats models.SyncStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Files != 4 || stats.Embeddings != 12 {
+		t.Errorf("totals = %d files, %d embeddings, want 4 and 12", stats.Files, stats.Embeddings)
+	}
+	if want := map[string]int64{"synced": 3, "failed": 1}; !reflect.DeepEqual(stats.Statuses, want) {
+		t.Errorf("statuses = %v, want %v", stats.Statuses, want)
+	}
+	if stats.LastSyncedAt == nil || !stats.LastSyncedAt.Equal(base.Add(3*time.Hour)) {
+		t.Errorf("last synced = %v, want %v", stats.LastSyncedAt, base.Add(3*time.Hour))
+	}
+
+	want := []*models.RepositoryStats{
+		{Repository: "org/a", Files: 3, Embeddings: 7, LastSyncedAt: base.Add(2 * time.Hour), Statuses: map[string]int64{"synced": 2, "failed": 1}},
+		{Repository: "org/b", Files: 1, Embeddings: 5, LastSyncedAt: base.Add(3 * time.Hour), Statuses: map[string]int64{"synced": 1}},
+	}
+	if len(stats.Repositories) != len(want) {
+		t.Fatalf("%d repositories, want %d", len(stats.Repositories), len(want))
+	}
+	for i, got := range stats.Repositories {
+		if got.Repository != want[i].Repository || got.Files != want[i].Files || got.Embeddings != want[i].Embeddings ||
+			!got.LastSyncedAt.Equal(want[i].LastSyncedAt) || !reflect.DeepEqual(got.Statuses, want[i].Statuses) {
+			t.Errorf("repository %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	// A project without records has zero totals, not an error
+	rec = httptest.NewRecorder()
+	s.handleStats(rec, httptest.NewRequest(http.MethodGet, "/stats?project_id=empty", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("empty project: status = %d, want 200", rec.Code)
+	}
+}
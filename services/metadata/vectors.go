@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// The vector IDs of a file's chunks are kept in file_vectors, one row per
+// chunk keyed by the file's sync_metadata id, so that the orchestrator can
+// delete exactly the vectors a file stops producing or no longer exists for.
+
+// saveVectorIDs replaces the vector IDs recorded for a file's sync record
+func saveVectorIDs(ctx context.Context, tx *sql.Tx, syncID int64, ids []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM file_vectors WHERE sync_id = ?`, syncID); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO file_vectors (sync_id, chunk_index, vector_id) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for i, id := range ids {
+		if _, err := stmt.ExecContext(ctx, syncID, i, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadVectorIDs fills in the ChunkIDs of records from the file_vectors rows
+// matching where, a condition on file_vectors v joined with sync_metadata m
+func (s *MetadataService) loadVectorIDs(ctx context.Context, records []*models.SyncMetadata, where string, args ...interface{}) error {
+	if len(records) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*models.SyncMetadata, len(records))
+	for _, record := range records {
+		byID[record.ID] = record
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT v.sync_id, v.vector_id
+		FROM file_vectors v JOIN sync_metadata m ON m.id = v.sync_id
+		WHERE `+where+` ORDER BY v.sync_id, v.chunk_index`, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var (
+			syncID   int64
+			vectorID string
+		)
+		if err := rows.Scan(&syncID, &vectorID); err != nil {
+			return err
+		}
+		if record := byID[syncID]; record != nil {
+			record.ChunkIDs = append(record.ChunkIDs, vectorID)
+		}
+	}
+	return rows.Err()
+}
+
+// migrateChunkIDs moves vector IDs out of the chunk_ids JSON column of
+// databases written before file_vectors existed, and empties the column
+func (s *MetadataService) migrateChunkIDs() error {
+	columns, err := s.dialect.columns(s.db, "sync_metadata")
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, name := range columns {
+		found = found || name == "chunk_ids"
+	}
+	if !found {
+		return nil
+	}
+
+	rows, err := s.db.Query(`SELECT id, chunk_ids FROM sync_metadata WHERE chunk_ids IS NOT NULL AND chunk_ids <> ''`)
+	if err != nil {
+		return err
+	}
+	legacy := make(map[int64][]string)
+	for rows.Next() {
+		var (
+			syncID int64
+			data   string
+			ids    []string
+		)
+		if err := rows.Scan(&syncID, &data); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if err := json.Unmarshal([]byte(data), &ids); err == nil {
+			legacy[syncID] = ids
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for syncID, ids := range legacy {
+			if err := saveVectorIDs(ctx, tx, syncID, ids); err != nil {
+				return fmt.Errorf("failed to migrate vector IDs of sync record %d: %w", syncID, err)
+			}
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE sync_metadata SET chunk_ids = ''`)
+		return err
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestVectorIDs(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	save := func(file string, ids ...string) {
+		t.Helper()
+		err := s.SaveSyncMetadata(ctx, &models.SyncMetadata{
+			ProjectID: "p", Repository: "org/repo", FilePath: file,
+			LastCommitSHA: "sha", LastSyncedAt: time.Now(), Status: "synced", ChunkIDs: ids,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	countRows := func() int {
+		t.Helper()
+		var n int
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM file_vectors`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	save("a.md", "c3", "c1", "c2")
+	save("b.md", "d1")
+	// A re-sync replaces the file's IDs rather than adding to them
+	save("a.md", "c4", "c1")
+
+	got, err := s.GetSyncMetadata(ctx, "p", "org/repo", "a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"c4", "c1"}; !reflect.DeepEqual(got.ChunkIDs, want) {
+		t.Errorf("a.md chunk IDs = %v, want %v", got.ChunkIDs, want)
+	}
+
+	entries, err := s.ListSyncMetadata(ctx, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := make(map[string][]string)
+	for _, entry := range entries {
+		listed[entry.FilePath] = entry.ChunkIDs
+	}
+	if want := map[string][]string{"a.md": {"c4", "c1"}, "b.md": {"d1"}}; !reflect.DeepEqual(listed, want) {
+		t.Errorf("listed chunk IDs = %v, want %v", listed, want)
+	}
+
+	if err := s.DeleteSyncMetadata(ctx, "p", "org/repo", "a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(); n != 1 {
+		t.Errorf("%d file_vectors rows after deleting a.md, want 1", n)
+	}
+
+	save("b.md")
+	if n := countRows(); n != 0 {
+		t.Errorf("%d file_vectors rows after saving b.md without IDs, want 0", n)
+	}
+}
+
+func TestMigrateChunkIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A database from before file_vectors, with IDs in a JSON column
+	_, err = db.Exec(`
+	CREATE TABLE sync_metadata (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		project_id TEXT NOT NULL,
+		repository TEXT NOT NULL,
+		file_path TEXT NOT NULL,
+		last_commit_sha TEXT NOT NULL,
+		last_synced_at DATETIME NOT NULL,
+		embedding_count INTEGER DEFAULT 0,
+		status TEXT DEFAULT 'synced',
+		blob_sha TEXT DEFAULT '',
+		embedding_model TEXT DEFAULT '',
+		chunk_ids TEXT DEFAULT '',
+		UNIQUE(project_id, repository, file_path)
+	);
+	INSERT INTO sync_metadata (project_id, repository, file_path, last_commit_sha, last_synced_at, chunk_ids)
+	VALUES ('p', 'org/repo', 'a.md', 'sha', '2026-01-02 03:04:05', '["c1","c2"]');`)
+	_ = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewMetadataService(DriverSQLite, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	got, err := s.GetSyncMetadata(context.Background(), "p", "org/repo", "a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"c1", "c2"}; !reflect.DeepEqual(got.ChunkIDs, want) {
+		t.Errorf("chunk IDs = %v, want %v", got.ChunkIDs, want)
+	}
+	var legacy string
+	if err := s.db.QueryRow(`SELECT chunk_ids FROM sync_metadata`).Scan(&legacy); err != nil {
+		t.Fatal(err)
+	}
+	if legacy != "" {
+		t.Errorf("chunk_ids column = %q after migration, want it emptied", legacy)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"strings"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+)
+
+// sqliteOptions open SQLite in WAL mode, so reads don't block on a write,
+// wait up to 5s for a lock instead of failing with "database is locked", and
+// take the write lock when a transaction begins rather than on its first
+// write, which could otherwise fail without waiting. Options already in
+// METADATA_DB_PATH take precedence.
+const sqliteOptions = "_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"
+
+// sqliteDSN appends sqliteOptions to a SQLite database path
+func sqliteDSN(path string) string {
+	if strings.Contains(path, "?") {
+		return path + "&" + sqliteOptions
+	}
+	return path + "?" + sqliteOptions
+}
+
+// writeJob is a transaction for the writer to run
+type writeJob struct {
+	ctx  context.Context
+	fn   func(tx *sql.Tx) error
+	done chan error
+}
+
+// startWriter runs every write transaction on one goroutine, one at a time.
+// SQLite has a single writer, so parallel orchestrator workers would
+// otherwise queue on the database lock and fail once the busy timeout ran
+// out.
+func (s *MetadataService) startWriter() {
+	s.writes = make(chan writeJob)
+	s.writerDone = make(chan struct{})
+	go func() {
+		defer close(s.writerDone)
+		for job := range s.writes {
+			job.done <- runTx(job.ctx, s.db, job.fn)
+		}
+	}()
+}
+
+// stopWriter lets the writer finish the transactions already handed to it
+// and stops it; later transactions fail instead of reaching a closed channel
+func (s *MetadataService) stopWriter() {
+	if s.writes == nil {
+		return
+	}
+	s.writerMu.Lock()
+	if s.writerStopped {
+		s.writerMu.Unlock()
+		return
+	}
+	s.writerStopped = true
+	close(s.writes)
+	s.writerMu.Unlock()
+	<-s.writerDone
+}
+
+// inTx runs fn in a transaction that is committed if fn returns nil and
+// rolled back otherwise, through the writer if there is one
+func (s *MetadataService) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if s.writes == nil {
+		return runTx(ctx, s.db, fn)
+	}
+
+	// The read lock keeps stopWriter from closing the channel mid-send
+	s.writerMu.RLock()
+	if s.writerStopped {
+		s.writerMu.RUnlock()
+		return errors.Internal("metadata writer is stopped", nil)
+	}
+	job := writeJob{ctx: ctx, fn: fn, done: make(chan error, 1)}
+	select {
+	case s.writes <- job:
+	case <-ctx.Done():
+		s.writerMu.RUnlock()
+		return ctx.Err()
+	}
+	s.writerMu.RUnlock()
+	return <-job.done
+}
+
+// runTx runs fn in a transaction of db
+func runTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// dbError wraps a database error with message, keeping errors that are
+// already application errors
+func dbError(message string, err error) error {
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		return err
+	}
+	return errors.Database(message, err)
+}
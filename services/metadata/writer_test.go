@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestConcurrentWrites(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	const workers, perWorker = 20, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				err := s.SaveSyncMetadata(ctx, &models.SyncMetadata{
+					ProjectID:     "p",
+					Repository:    fmt.Sprintf("org/repo-%d", w),
+					FilePath:      fmt.Sprintf("docs/%d.md", i),
+					LastCommitSHA: "sha",
+					LastSyncedAt:  time.Now(),
+					Status:        "synced",
+					ChunkIDs:      []string{fmt.Sprintf("%d-%d-0", w, i)},
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent save: %v", err)
+	}
+
+	records, err := s.ListSyncMetadata(ctx, "p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != workers*perWorker {
+		t.Errorf("got %d records, want %d", len(records), workers*perWorker)
+	}
+}
+
+func TestWritesAfterStop(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	// Writes racing the stop either land or fail; none may panic on the
+	// closed channel
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_ = s.SaveSyncMetadata(ctx, &models.SyncMetadata{
+				ProjectID: "p", Repository: "org/repo", FilePath: fmt.Sprintf("docs/%d.md", i),
+				LastSyncedAt: time.Now(), Status: "synced",
+			})
+		}(i)
+	}
+	s.stopWriter()
+	wg.Wait()
+
+	var appErr *errors.AppError
+	err := s.inTx(ctx, func(*sql.Tx) error { return nil })
+	if !stderrors.As(err, &appErr) || appErr.Type != errors.ErrTypeInternal {
+		t.Errorf("write after stop = %v, want an internal error", err)
+	}
+	s.stopWriter() // stopping twice is harmless
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	if got := sqliteDSN("data/metadata.db"); got != "data/metadata.db?"+sqliteOptions {
+		t.Errorf("sqliteDSN = %q", got)
+	}
+	if got := sqliteDSN("data/metadata.db?cache=shared"); got != "data/metadata.db?cache=shared&"+sqliteOptions {
+		t.Errorf("sqliteDSN with options = %q", got)
+	}
+}
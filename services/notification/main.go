@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -19,25 +20,72 @@ import (
 	"github.com/slack-go/slack"
 )
 
+// Notification channels, selected with NOTIFICATION_CHANNELS
+const (
+	ChannelSlack   = "slack"
+	ChannelTeams   = "teams"
+	ChannelWebhook = "webhook"
+)
+
 // NotificationService implements interfaces.NotificationService
 type NotificationService struct {
-	webhookURL string
+	channels        []string
+	webhookURL      string // Slack
+	teamsWebhookURL string
+	webhooks        []string // outbound webhooks of the webhook channel
+	webhookSecret   string
 }
 
-// NewNotificationService creates a new notification service
-func NewNotificationService(webhookURL string) *NotificationService {
+// NewNotificationService creates a new notification service sending to the
+// configured channels
+func NewNotificationService(cfg config.NotificationsConfig) *NotificationService {
 	return &NotificationService{
-		webhookURL: webhookURL,
+		channels:        cfg.Channels,
+		webhookURL:      cfg.SlackWebhookURL,
+		teamsWebhookURL: cfg.TeamsWebhookURL,
+		webhooks:        cfg.WebhookURLs,
+		webhookSecret:   cfg.WebhookSecret,
 	}
 }
 
-// SendNotification sends a notification
+// SendNotification sends a notification to every configured channel. A
+// failing channel doesn't keep the others from being sent to; the first
+// failure is returned.
 func (s *NotificationService) SendNotification(ctx context.Context, payload *models.NotificationPayload) error {
-	if s.webhookURL == "" {
-		logger.Warning("Slack webhook URL not configured, skipping notification")
-		return nil
+	var firstErr error
+	for _, channel := range s.channels {
+		var err error
+		switch channel {
+		case ChannelSlack:
+			if s.webhookURL == "" {
+				logger.Warning("Slack webhook URL not configured, skipping notification")
+				continue
+			}
+			err = s.SendSlack(ctx, payload)
+		case ChannelTeams:
+			if s.teamsWebhookURL == "" {
+				logger.Warning("Teams webhook URL not configured, skipping notification")
+				continue
+			}
+			err = s.SendTeams(ctx, payload)
+		case ChannelWebhook:
+			if len(s.webhooks) == 0 {
+				logger.Warning("Webhook URLs not configured, skipping notification")
+				continue
+			}
+			err = s.SendWebhooks(ctx, payload)
+		default:
+			logger.Warning("Unknown notification channel %q, skipping", channel)
+			continue
+		}
+		if err != nil {
+			logger.Error("Failed to send %s notification: %v", channel, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
 	}
-	return s.SendSlack(ctx, payload)
+	return firstErr
 }
 
 // SendSlack sends a Slack notification
@@ -45,36 +93,47 @@ func (s *NotificationService) SendSlack(ctx context.Context, payload *models.Not
 	if s.webhookURL == "" {
 		return nil
 	}
+	if err := postJSON(ctx, s.webhookURL, "Slack", s.buildSlackMessage(payload)); err != nil {
+		return err
+	}
 
-	// Build Slack message
-	msg := s.buildSlackMessage(payload)
+	logger.Info("Slack notification sent successfully")
+	return nil
+}
 
-	// Send webhook
+// postJSON posts msg as JSON to the webhook of service, which must answer
+// with a 2xx status
+func postJSON(ctx context.Context, url, service string, msg interface{}) error {
 	jsonData, err := json.Marshal(msg)
 	if err != nil {
-		return errors.Internal("failed to marshal Slack message", err)
+		return errors.Internal(fmt.Sprintf("failed to marshal %s message", service), err)
 	}
+	return postBody(ctx, url, service, jsonData, nil)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.webhookURL, bytes.NewBuffer(jsonData))
+// postBody posts a JSON body with extra headers to the webhook of service
+func postBody(ctx context.Context, url, service string, jsonData []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return errors.Network("failed to create request", err)
 	}
 
+	for name, values := range header {
+		req.Header[name] = values
+	}
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return errors.Network("failed to send Slack notification", err)
+		return errors.Network(fmt.Sprintf("failed to send %s notification", service), err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(resp.Body)
-		return errors.External("Slack", fmt.Sprintf("unexpected status code %d: %s", resp.StatusCode, body), nil)
+		return errors.External(service, fmt.Sprintf("unexpected status code %d: %s", resp.StatusCode, body), nil)
 	}
-
-	logger.Info("Slack notification sent successfully")
 	return nil
 }
 
@@ -184,6 +243,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Validate notification-specific requirements
+	if err := cfg.ValidateForNotification(); err != nil {
+		fmt.Printf("Failed to validate configuration: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Initialize logger
 	if err := logger.Init(cfg.Logging.Level, cfg.Logging.FilePath, "notification-service"); err != nil {
 		fmt.Printf("Failed to initialize logger: %v\n", err)
@@ -193,7 +258,8 @@ func main() {
 	logger.Info("Starting Notification Service on port %d", cfg.Services.NotificationServicePort)
 
 	// Create notification service
-	service := NewNotificationService(cfg.Notifications.SlackWebhookURL)
+	service := NewNotificationService(cfg.Notifications)
+	logger.Info("Sending notifications to %s", strings.Join(cfg.Notifications.Channels, ", "))
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// webhook records the bodies posted to it and answers with status
+func webhook(t *testing.T, status int) (*httptest.Server, *[]map[string]interface{}) {
+	t.Helper()
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("webhook body: %v", err)
+		}
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+func TestBuildTeamsMessage(t *testing.T) {
+	msg := buildTeamsMessage(&models.NotificationPayload{
+		Type:    "error",
+		Title:   "Sync failed",
+		Message: "docs: 2 errors",
+		Result: &models.SyncResult{
+			Duration:            90 * time.Second,
+			RepositoriesScanned: 3,
+			FilesProcessed:      5,
+			FilesChanged:        6,
+			Errors:              []string{"embedding: rate limited"},
+		},
+		Timestamp: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+	})
+
+	if len(msg.Attachments) != 1 || msg.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("attachments = %+v, want one Adaptive Card", msg.Attachments)
+	}
+	body := msg.Attachments[0].Content.Body
+	if body[0].Text != "❌ Sync failed" || body[0].Color != "Attention" {
+		t.Errorf("title block = %+v, want the error title in Attention", body[0])
+	}
+
+	var facts map[string]string
+	var texts []string
+	for _, element := range body {
+		if element.Type == "FactSet" {
+			facts = make(map[string]string)
+			for _, fact := range element.Facts {
+				facts[fact.Title] = fact.Value
+			}
+		}
+		texts = append(texts, element.Text)
+	}
+	if facts["Duration"] != "1m30s" || facts["Files Processed"] != "5 / 6" || facts["Repositories"] != "3" {
+		t.Errorf("facts = %v", facts)
+	}
+	all := strings.Join(texts, "\n")
+	for _, want := range []string{"docs: 2 errors", "embedding: rate limited", "2024-05-01 08:00 UTC"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("card text %q doesn't contain %q", all, want)
+		}
+	}
+}
+
+func TestSendNotificationChannels(t *testing.T) {
+	slackServer, slackBodies := webhook(t, http.StatusInternalServerError)
+	teamsServer, teamsBodies := webhook(t, http.StatusAccepted)
+
+	s := NewNotificationService(config.NotificationsConfig{
+		Channels:        []string{ChannelSlack, ChannelTeams},
+		SlackWebhookURL: slackServer.URL,
+		TeamsWebhookURL: teamsServer.URL,
+	})
+	payload := &models.NotificationPayload{Type: "success", Title: "Sync complete", Timestamp: time.Now()}
+
+	// Slack failing doesn't keep the notification from Teams
+	if err := s.SendNotification(context.Background(), payload); err == nil || !strings.Contains(err.Error(), "Slack") {
+		t.Errorf("error = %v, want Slack's failure", err)
+	}
+	if len(*slackBodies) != 1 || len(*teamsBodies) != 1 {
+		t.Fatalf("slack got %d posts, teams %d; want one each", len(*slackBodies), len(*teamsBodies))
+	}
+	if (*teamsBodies)[0]["type"] != "message" {
+		t.Errorf("teams body = %v, want a message", (*teamsBodies)[0])
+	}
+
+	// A channel without a webhook is skipped
+	s = NewNotificationService(config.NotificationsConfig{Channels: []string{ChannelTeams}})
+	if err := s.SendNotification(context.Background(), payload); err != nil {
+		t.Errorf("unconfigured Teams: %v, want it skipped", err)
+	}
+}
+
+func TestSendWebhooks(t *testing.T) {
+	const secret = "shared-secret"
+	var got []models.NotificationPayload
+	deliveries := make(map[string]bool)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		// What a receiver does to verify a delivery: check the signature of
+		// the timestamp and body, then the timestamp's age and the delivery ID
+		timestamp := r.Header.Get(timestampHeader)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(r.Header.Get(signatureHeader)), []byte(want)) {
+			t.Errorf("signature = %q, want %q", r.Header.Get(signatureHeader), want)
+		}
+		sent, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			t.Errorf("timestamp %q: %v", timestamp, err)
+		} else if age := time.Since(time.Unix(sent, 0)); age < -webhookTolerance || age > webhookTolerance {
+			t.Errorf("timestamp is %v old, want within %v", age, webhookTolerance)
+		}
+		delivery := r.Header.Get(deliveryHeader)
+		if delivery == "" || deliveries[delivery] {
+			t.Errorf("delivery ID %q is empty or repeated", delivery)
+		}
+		deliveries[delivery] = true
+		if r.Header.Get(eventHeader) != "warning" {
+			t.Errorf("event = %q, want warning", r.Header.Get(eventHeader))
+		}
+
+		var payload models.NotificationPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("body: %v", err)
+		}
+		got = append(got, payload)
+	}))
+	t.Cleanup(receiver.Close)
+	broken, _ := webhook(t, http.StatusBadGateway)
+
+	s := NewNotificationService(config.NotificationsConfig{
+		Channels:      []string{ChannelWebhook},
+		WebhookURLs:   []string{broken.URL, receiver.URL},
+		WebhookSecret: secret,
+	})
+	payload := &models.NotificationPayload{
+		Type: "warning", Title: "Sync finished with warnings",
+		Result: &models.SyncResult{ProjectID: "docs", Warnings: []string{"large file skipped"}},
+	}
+
+	// The broken webhook fails the send but the other still gets the payload
+	if err := s.SendNotification(context.Background(), payload); err == nil {
+		t.Error("send succeeded, want the broken webhook's failure")
+	}
+	if len(got) != 1 || got[0].Title != payload.Title || got[0].Result == nil || got[0].Result.ProjectID != "docs" {
+		t.Errorf("receiver got %+v, want the payload", got)
+	}
+
+	// Sending again is a new delivery, with its own ID
+	_ = s.SendNotification(context.Background(), payload)
+	if len(got) != 2 || len(deliveries) != 2 {
+		t.Errorf("receiver got %d deliveries with %d IDs, want 2 of each", len(got), len(deliveries))
+	}
+
+	// A signature covers the timestamp, so replaying the body under a fresh
+	// timestamp doesn't verify
+	body := []byte(`{"type":"warning"}`)
+	if signPayload(secret, "1700000000", body) == signPayload(secret, "1700000001", body) {
+		t.Error("signature doesn't depend on the timestamp")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// A Teams incoming webhook takes a message with one Adaptive Card
+// attachment; see https://adaptivecards.io/explorer/ for the elements.
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+type adaptiveCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []cardElement `json:"body"`
+}
+
+// cardElement is a TextBlock or a FactSet
+type cardElement struct {
+	Type     string     `json:"type"`
+	Text     string     `json:"text,omitempty"`
+	Weight   string     `json:"weight,omitempty"`
+	Size     string     `json:"size,omitempty"`
+	Color    string     `json:"color,omitempty"`
+	FontType string     `json:"fontType,omitempty"`
+	IsSubtle bool       `json:"isSubtle,omitempty"`
+	Wrap     bool       `json:"wrap,omitempty"`
+	Facts    []cardFact `json:"facts,omitempty"`
+}
+
+type cardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// SendTeams sends a notification to the Microsoft Teams incoming webhook
+func (s *NotificationService) SendTeams(ctx context.Context, payload *models.NotificationPayload) error {
+	if s.teamsWebhookURL == "" {
+		return nil
+	}
+	if err := postJSON(ctx, s.teamsWebhookURL, "Teams", buildTeamsMessage(payload)); err != nil {
+		return err
+	}
+
+	logger.Info("Teams notification sent successfully")
+	return nil
+}
+
+// buildTeamsMessage renders a notification as an Adaptive Card with the
+// same details as the Slack message
+func buildTeamsMessage(payload *models.NotificationPayload) *teamsMessage {
+	var color, emoji string
+	switch payload.Type {
+	case "success":
+		color, emoji = "Good", "✅"
+	case "error":
+		color, emoji = "Attention", "❌"
+	case "warning":
+		color, emoji = "Warning", "⚠️"
+	default:
+		color, emoji = "Accent", "ℹ️"
+	}
+
+	body := []cardElement{
+		{Type: "TextBlock", Text: fmt.Sprintf("%s %s", emoji, payload.Title), Weight: "Bolder", Size: "Medium", Color: color, Wrap: true},
+	}
+	if payload.Message != "" {
+		body = append(body, cardElement{Type: "TextBlock", Text: payload.Message, Wrap: true})
+	}
+
+	if result := payload.Result; result != nil {
+		body = append(body, cardElement{Type: "FactSet", Facts: []cardFact{
+			{Title: "Duration", Value: result.Duration.String()},
+			{Title: "Repositories", Value: fmt.Sprintf("%d", result.RepositoriesScanned)},
+			{Title: "Files Processed", Value: fmt.Sprintf("%d / %d", result.FilesProcessed, result.FilesChanged)},
+			{Title: "Embeddings Generated", Value: fmt.Sprintf("%d", result.EmbeddingsGenerated)},
+		}})
+		if len(result.Errors) > 0 {
+			body = append(body, cardElement{Type: "TextBlock", Text: result.Errors[0], Color: "Attention", FontType: "Monospace", Wrap: true})
+		}
+	}
+
+	footer := "RepoSync"
+	if !payload.Timestamp.IsZero() {
+		footer += " · " + payload.Timestamp.UTC().Format("2006-01-02 15:04 UTC")
+	}
+	body = append(body, cardElement{Type: "TextBlock", Text: footer, Size: "Small", IsSubtle: true, Wrap: true})
+
+	return &teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: adaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+			},
+		}},
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// The webhook channel posts the NotificationPayload itself, for automation
+// rather than people. Receivers verify a delivery by computing the
+// HMAC-SHA256 of the timestamp header, a ".", and the raw body with the
+// shared secret and comparing it, in constant time, to the signature header.
+// They should then reject timestamps more than webhookTolerance away from
+// their own clock and delivery IDs they have already seen, so a captured
+// delivery can't be replayed.
+
+const (
+	// signatureHeader carries "sha256=" and the hex HMAC of the timestamp,
+	// ".", and the body
+	signatureHeader = "X-RepoSync-Signature-256"
+	// timestampHeader carries the Unix time, in seconds, the delivery was signed
+	timestampHeader = "X-RepoSync-Timestamp"
+	// deliveryHeader carries a random ID unique to each delivery
+	deliveryHeader = "X-RepoSync-Delivery"
+	// eventHeader carries the payload's type: success, error, or warning
+	eventHeader = "X-RepoSync-Event"
+
+	// webhookTolerance is how far a receiver should let a delivery's
+	// timestamp be from its own clock
+	webhookTolerance = 5 * time.Minute
+)
+
+// SendWebhooks posts a notification to every outbound webhook. All of them
+// are tried; the first failure is returned. Webhooks are logged by position
+// only, since their URLs often embed tokens.
+func (s *NotificationService) SendWebhooks(ctx context.Context, payload *models.NotificationPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Internal("failed to marshal webhook payload", err)
+	}
+
+	var firstErr error
+	for i, url := range s.webhooks {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		header := http.Header{}
+		header.Set(signatureHeader, signPayload(s.webhookSecret, timestamp, body))
+		header.Set(timestampHeader, timestamp)
+		header.Set(deliveryHeader, newDeliveryID())
+		header.Set(eventHeader, payload.Type)
+
+		if err := postBody(ctx, url, "Webhook", body, header); err != nil {
+			logger.Error("Failed to send notification to webhook %d: %v", i+1, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		logger.Info("Notification sent to webhook %d", i+1)
+	}
+	return firstErr
+}
+
+// signPayload returns the signature header value of body sent at timestamp
+func signPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newDeliveryID returns a random ID for one webhook delivery
+func newDeliveryID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
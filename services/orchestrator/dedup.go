@@ -0,0 +1,143 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// chunkDeduper drops chunks whose content hash was already embedded in the
+// current run, so vendored copies and boilerplate are embedded only once.
+// A file claims a hash before embedding and the claim only becomes seen once
+// its embedding succeeds, so a failed file does not hide the content from
+// later batches.
+type chunkDeduper struct {
+	mu      sync.Mutex
+	seen    map[string]bool // embedded
+	claimed map[string]bool // being embedded
+	skipped int
+}
+
+func newChunkDeduper() *chunkDeduper {
+	return &chunkDeduper{seen: make(map[string]bool), claimed: make(map[string]bool)}
+}
+
+// claim returns the documents whose content is neither embedded nor claimed
+// yet, claiming their hashes; a nil deduper or a document without a hash
+// lets everything through. Callers claim files in a fixed order so the same
+// file always owns a shared chunk.
+func (d *chunkDeduper) claim(documents []*models.Document) []*models.Document {
+	if d == nil {
+		return documents
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	unique := documents[:0:0]
+	for _, doc := range documents {
+		hash := doc.Metadata["content_hash"]
+		if hash != "" && (d.seen[hash] || d.claimed[hash]) {
+			d.skipped++
+			continue
+		}
+		if hash != "" {
+			d.claimed[hash] = true
+		}
+		unique = append(unique, doc)
+	}
+	return unique
+}
+
+// settle releases the documents' claims, marking their hashes seen if they
+// were embedded
+func (d *chunkDeduper) settle(documents []*models.Document, embedded bool) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, doc := range documents {
+		hash := doc.Metadata["content_hash"]
+		if hash == "" {
+			continue
+		}
+		delete(d.claimed, hash)
+		if embedded {
+			d.seen[hash] = true
+		}
+	}
+}
+
+// EmbeddingCache is a bounded LRU of vectors keyed by chunk content hash,
+// letting unchanged chunks from earlier runs skip the embedding service.
+// Each vector keeps the ID of the model that made it.
+type EmbeddingCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is most recently used
+	entries  map[string]*list.Element
+}
+
+type embeddingCacheEntry struct {
+	hash   string
+	vector []float32
+	model  string
+}
+
+// NewEmbeddingCache creates a cache holding up to capacity vectors, or returns nil when capacity is 0
+func NewEmbeddingCache(capacity int) *EmbeddingCache {
+	if capacity <= 0 {
+		return nil
+	}
+	return &EmbeddingCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached vector for a content hash and its model
+func (c *EmbeddingCache) Get(hash string) ([]float32, string, bool) {
+	if c == nil || hash == "" {
+		return nil, "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[hash]
+	if !ok {
+		return nil, "", false
+	}
+	c.order.MoveToFront(elem)
+	entry := elem.Value.(*embeddingCacheEntry)
+	return entry.vector, entry.model, true
+}
+
+// Put stores a vector, evicting the least recently used entry when full
+func (c *EmbeddingCache) Put(hash string, vector []float32, model string) {
+	if c == nil || hash == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[hash]; ok {
+		entry := elem.Value.(*embeddingCacheEntry)
+		entry.vector, entry.model = vector, model
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[hash] = c.order.PushFront(&embeddingCacheEntry{hash: hash, vector: vector, model: model})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*embeddingCacheEntry).hash)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/embeddingpb"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// A streamed chunk of embeddings can pass the 4MB gRPC default with large
+// models, so allow more
+const maxEmbeddingMessageSize = 64 << 20
+
+// newEmbeddingClient connects to the embedding service's gRPC API at
+// EMBEDDING_SERVICE_GRPC_ADDR, or returns nil to embed over HTTP
+func newEmbeddingClient() embeddingpb.EmbeddingServiceClient {
+	addr := os.Getenv("EMBEDDING_SERVICE_GRPC_ADDR")
+	if addr == "" {
+		return nil
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxEmbeddingMessageSize), grpc.MaxCallSendMsgSize(maxEmbeddingMessageSize)),
+	)
+	if err != nil {
+		logger.Warning("Embedding over HTTP, invalid EMBEDDING_SERVICE_GRPC_ADDR %q: %v", addr, err)
+		return nil
+	}
+	logger.Info("Embedding over gRPC at %s", addr)
+	return embeddingpb.NewEmbeddingServiceClient(conn)
+}
+
+// embedTextsGRPC streams the embeddings of texts from the embedding service,
+// returning them with the ID of the model that made them
+func (o *Orchestrator) embedTextsGRPC(ctx context.Context, texts []string) ([][]float32, string, error) {
+	stream, err := o.embeddingClient.EmbedStream(ctx, &embeddingpb.EmbedRequest{Texts: texts, InputType: "document"})
+	if err != nil {
+		return nil, "", err
+	}
+
+	embeddings := make([][]float32, len(texts))
+	received := 0
+	model := ""
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		model = resp.Model
+		offset := int(resp.Offset)
+		if offset < 0 || offset+len(resp.Embeddings) > len(texts) {
+			return nil, "", fmt.Errorf("embedding service returned embeddings %d to %d for %d texts", offset, offset+len(resp.Embeddings), len(texts))
+		}
+		for k, embedding := range resp.Embeddings {
+			embeddings[offset+k] = embedding.Values
+		}
+		received += len(resp.Embeddings)
+	}
+	if received != len(texts) {
+		return nil, "", fmt.Errorf("embedding service returned %d embeddings for %d texts", received, len(texts))
+	}
+	return embeddings, model, nil
+}
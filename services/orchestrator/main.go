@@ -7,15 +7,24 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"os/signal"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/embeddingpb"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/sparse"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/vectorpb"
 )
 
 // Orchestrator coordinates all microservices
@@ -28,6 +37,9 @@ type Orchestrator struct {
 	metadataServiceURL     string
 	httpClient             *http.Client
 	config                 *config.Config
+	embeddingCache         *EmbeddingCache
+	embeddingClient        embeddingpb.EmbeddingServiceClient  // nil to embed over HTTP
+	vectorClient           vectorpb.VectorStorageServiceClient // nil to upsert over HTTP
 }
 
 // NewOrchestrator creates a new orchestrator
@@ -41,6 +53,9 @@ func NewOrchestrator(cfg *config.Config) *Orchestrator {
 		metadataServiceURL:     getServiceURL("METADATA_SERVICE_URL", "http://localhost:8086"),
 		httpClient:             &http.Client{Timeout: 60 * time.Second},
 		config:                 cfg,
+		embeddingCache:         NewEmbeddingCache(cfg.Processing.EmbeddingCacheSize),
+		embeddingClient:        newEmbeddingClient(),
+		vectorClient:           newVectorStorageClient(),
 	}
 }
 
@@ -54,6 +69,12 @@ func (o *Orchestrator) SyncProject(ctx context.Context, projectID string, increm
 
 	logger.Info("Starting sync for project: %s (incremental: %v)", projectID, incremental)
 
+	// Snapshot API usage so only calls made during this sync are reported
+	usageBefore, err := o.getRateUsage(ctx)
+	if err != nil {
+		logger.Warning("Failed to get rate usage: %v", err)
+	}
+
 	// Step 1: Discover repositories from GitHub
 	repos, err := o.discoverRepositories(ctx)
 	if err != nil {
@@ -64,23 +85,73 @@ func (o *Orchestrator) SyncProject(ctx context.Context, projectID string, increm
 	result.RepositoriesScanned = len(repos)
 	logger.Info("Discovered %d repositories", len(repos))
 
+	// The records and sync states read now are the ones this sync replaces;
+	// saving one fails if another sync of the project saved a newer one, or
+	// created one this sync found missing, meanwhile
+	stateVersions, err := o.repoStateVersions(ctx, projectID)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to get repository sync states: %v", err))
+		o.sendNotification(ctx, result, "error")
+		return result, err
+	}
+	stored, err := o.storedRecords(ctx, projectID)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to get sync metadata: %v", err))
+		o.sendNotification(ctx, result, "error")
+		return result, err
+	}
+
 	// Step 2: Process each repository
+	project := o.loadProject(ctx, projectID)
+	rules := o.filterRules(project)
 	var allChangedFiles []*models.FileChange
+	previousCommits := make(map[string]string)
 	for _, repo := range repos {
-		// Get last commit SHA if incremental
-		lastCommitSHA := ""
-		if incremental {
-			lastCommitSHA, _ = o.getLastCommitSHA(ctx, projectID, repo.FullName)
-		}
+		var changedFiles []*models.FileChange
+		if incremental && o.config.Processing.ChangeDetection == "blob" {
+			// Diff the full tree against per-file blob SHAs from metadata
+			changedFiles, err = o.getChangedBlobs(ctx, repo, knownBlobs(stored, repo.FullName), rules)
+		} else {
+			// Get last commit SHA if incremental
+			lastCommitSHA := ""
+			if incremental {
+				lastCommitSHA, _ = o.getLastCommitSHA(ctx, projectID, repo.FullName)
+			}
+			previousCommits[repo.FullName] = lastCommitSHA
 
-		// Detect changed files
-		changedFiles, err := o.getChangedFiles(ctx, repo, lastCommitSHA)
+			// Detect changed files
+			changedFiles, err = o.getChangedFiles(ctx, repo, lastCommitSHA)
+		}
 		if err != nil {
 			result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to get changed files for %s: %v", repo.FullName, err))
 			continue
 		}
+		for _, file := range changedFiles {
+			if file.Branch == "" {
+				file.Branch = repo.DefaultBranch
+			}
+		}
 
 		allChangedFiles = append(allChangedFiles, changedFiles...)
+
+		if o.config.GitHub.IncludeWikis {
+			wikiPages, err := o.getWikiPages(ctx, projectID, repo, incremental)
+			if err != nil {
+				// A missing wiki must not stop the repository's conversations
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to get wiki pages for %s: %v", repo.FullName, err))
+			} else {
+				allChangedFiles = append(allChangedFiles, wikiPages...)
+			}
+		}
+
+		for _, kind := range o.conversationKinds() {
+			docs, err := o.getConversations(ctx, projectID, repo, kind, incremental)
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to get %s for %s: %v", kind, repo.FullName, err))
+				continue
+			}
+			allChangedFiles = append(allChangedFiles, docs...)
+		}
 	}
 
 	result.FilesDiscovered = len(allChangedFiles)
@@ -88,11 +159,20 @@ func (o *Orchestrator) SyncProject(ctx context.Context, projectID string, increm
 	logger.Info("Found %d changed files", len(allChangedFiles))
 
 	// Step 3: Filter and process files
-	validFiles := o.filterFiles(allChangedFiles)
+	validFiles := o.filterFiles(allChangedFiles, rules)
+	if o.config.Processing.LazyContentFetch {
+		validFiles = o.fetchContents(ctx, validFiles, result)
+	}
 	result.FilesProcessed = len(validFiles)
+	o.reportRateUsage(ctx, result, usageBefore)
+
+	// Removed files have nothing to chunk; their vectors are dropped in step 6
+	liveFiles, removed := splitRemovals(validFiles)
+	namespace := o.config.GitHub.Organization
 
 	// Step 4: Process files in batches
-	embeddings, chunks, err := o.processFiles(ctx, validFiles)
+	chunkIDs := make(map[string][]string)
+	embeddings, chunks, unchanged, err := o.processFiles(ctx, liveFiles, namespace, chunkStrategies(project), chunkIDs)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Failed to process files: %v", err))
 		o.sendNotification(ctx, result, "error")
@@ -100,6 +180,7 @@ func (o *Orchestrator) SyncProject(ctx context.Context, projectID string, increm
 	}
 
 	result.ChunksCreated = chunks
+	result.ChunksUnchanged = unchanged
 	result.EmbeddingsGenerated = len(embeddings)
 
 	// Step 5: Upsert to vector database
@@ -112,18 +193,92 @@ func (o *Orchestrator) SyncProject(ctx context.Context, projectID string, increm
 		result.VectorsUpserted = len(embeddings)
 	}
 
-	// Step 6: Update metadata
-	for _, file := range validFiles {
-		metadata := &models.SyncMetadata{
+	// Step 6: Drop vectors no file produces any more, then update metadata
+	recordedIDs := o.deleteStaleVectors(ctx, namespace, validFiles, chunkIDs, stored)
+	failedRepos := o.deleteRemovedVectors(ctx, namespace, removed)
+
+	fileModels := make(map[string]string)
+	fileEmbeddings := make(map[string]int)
+	for _, emb := range embeddings {
+		key := emb.Repository + "/" + emb.FilePath
+		fileEmbeddings[key]++
+		if model := emb.Metadata["embedding_model"]; model != "" {
+			fileModels[key] = model
+		}
+	}
+	records := make([]*models.SyncMetadata, 0, len(validFiles))
+	for _, file := range removed {
+		// Removed files become tombstones, which listings and blob diffs skip
+		// and purges remove, unless their vectors are still stored and need
+		// another attempt
+		if failedRepos[file.Repository] {
+			continue
+		}
+		records = append(records, &models.SyncMetadata{
+			ProjectID:     projectID,
+			Repository:    file.Repository,
+			FilePath:      file.FilePath,
+			LastCommitSHA: file.CommitSHA,
+			LastSyncedAt:  time.Now(),
+			Status:        tombstoneStatus,
+		})
+	}
+	for _, file := range liveFiles {
+		records = append(records, &models.SyncMetadata{
 			ProjectID:      projectID,
 			Repository:     file.Repository,
 			FilePath:       file.FilePath,
 			LastCommitSHA:  file.CommitSHA,
 			LastSyncedAt:   time.Now(),
-			EmbeddingCount: 0, // Would need to track per file
+			EmbeddingCount: fileEmbeddings[file.Repository+"/"+file.FilePath],
 			Status:         "synced",
+			BlobSHA:        file.BlobSHA,
+			EmbeddingModel: fileModels[file.Repository+"/"+file.FilePath],
+			ChunkIDs:       recordedIDs[file.Repository+"/"+file.FilePath],
+		})
+	}
+	for _, record := range records {
+		if entry := stored[record.Repository+"/"+record.FilePath]; entry != nil {
+			record.Version = entry.Version
+		} else {
+			record.Create = true
+		}
+	}
+	// Repository sync states are saved with the last batch, in its
+	// transaction, and only for repositories whose records were all saved;
+	// the others keep their old commit so the next sync retries their files.
+	// Records and states carry the versions read at the start, or are
+	// created, so a batch fails rather than overwrite what a concurrent sync
+	// saved since.
+	commits := syncedCommits(allChangedFiles, previousCommits)
+	unsaved := make(map[string]bool)
+	for start := 0; start == 0 || start < len(records); start += metadataBatchSize {
+		batch := &models.SyncBatch{Records: records[start:min(start+metadataBatchSize, len(records))]}
+		if start+metadataBatchSize >= len(records) {
+			for repository, commit := range commits {
+				if !unsaved[repository] {
+					version, found := stateVersions[repository]
+					batch.RepoStates = append(batch.RepoStates, &models.RepoSyncState{
+						ProjectID: projectID, Repository: repository, LastCommitSHA: commit, LastSyncedAt: time.Now(),
+						Version: version, Create: !found,
+					})
+				}
+			}
+		}
+		if len(batch.Records) == 0 && len(batch.RepoStates) == 0 {
+			break
 		}
-		_ = o.saveMetadata(ctx, metadata)
+		if err := o.saveMetadata(ctx, batch); err != nil {
+			logger.Warning("Failed to save metadata for %d files and %d repositories: %v",
+				len(batch.Records), len(batch.RepoStates), err)
+			for _, record := range batch.Records {
+				unsaved[record.Repository] = true
+			}
+		}
+	}
+
+	if err := o.ValidateSync(ctx, result); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Sync validation: %v", err))
 	}
 
 	result.EndTime = time.Now()
@@ -138,6 +293,67 @@ func (o *Orchestrator) SyncProject(ctx context.Context, projectID string, increm
 	return result, nil
 }
 
+// SyncPullRequest indexes the files changed by a pull request into a staging
+// namespace so proposed documentation can be reviewed before merge. Sync
+// metadata is left untouched since the changes are not on the default branch.
+func (o *Orchestrator) SyncPullRequest(ctx context.Context, projectID, repoFullName string, number int, namespace string) (*models.SyncResult, error) {
+	result := &models.SyncResult{
+		ProjectID:           projectID,
+		StartTime:           time.Now(),
+		RepositoriesScanned: 1,
+		Success:             false,
+	}
+
+	logger.Info("Starting pull request sync for %s#%d into namespace '%s'", repoFullName, number, namespace)
+
+	changedFiles, err := o.getPullRequestChanges(ctx, repoFullName, number)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to get pull request changes: %v", err))
+		return result, err
+	}
+	result.FilesDiscovered = len(changedFiles)
+	result.FilesChanged = len(changedFiles)
+
+	// Removed files have no content to stage; vectors an earlier sync of the
+	// pull request staged for them are dropped below
+	project := o.loadProject(ctx, projectID)
+	validFiles, removed := splitRemovals(o.filterFiles(changedFiles, o.filterRules(project)))
+	result.FilesProcessed = len(validFiles)
+
+	embeddings, chunks, unchanged, err := o.processFiles(ctx, validFiles, namespace, chunkStrategies(project), nil)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("Failed to process files: %v", err))
+		return result, err
+	}
+	result.ChunksCreated = chunks
+	result.ChunksUnchanged = unchanged
+	result.EmbeddingsGenerated = len(embeddings)
+
+	for _, emb := range embeddings {
+		emb.Namespace = namespace
+	}
+
+	if len(embeddings) > 0 {
+		if err := o.upsertVectors(ctx, embeddings, namespace); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Failed to upsert vectors: %v", err))
+			return result, err
+		}
+		result.VectorsUpserted = len(embeddings)
+	}
+
+	// Renamed files arrive as a removal of the old path plus the new path
+	for repo := range o.deleteRemovedVectors(ctx, namespace, removed) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to delete staged vectors of removed files in %s", repo))
+	}
+
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	result.Success = true
+
+	logger.Info("Pull request sync completed: %d embeddings in %s", result.EmbeddingsGenerated, result.Duration)
+	return result, nil
+}
+
 // discoverRepositories gets repositories from GitHub service
 func (o *Orchestrator) discoverRepositories(ctx context.Context) ([]*models.Repository, error) {
 	url := fmt.Sprintf("%s/repositories?org=%s&keyword=%s",
@@ -157,9 +373,125 @@ func (o *Orchestrator) discoverRepositories(ctx context.Context) ([]*models.Repo
 	return repos, nil
 }
 
+// getRateUsage gets the discovery service's cumulative per-repository API call counters
+func (o *Orchestrator) getRateUsage(ctx context.Context) (*models.RateUsage, error) {
+	resp, err := o.httpClient.Get(o.githubServiceURL + "/rate-usage")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("rate usage lookup failed: %s", body)
+	}
+
+	var usage models.RateUsage
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// reportRateUsage adds the API calls made since the before snapshot to the
+// sync warnings, heaviest repository first, flagging those over the budget.
+// Without a before snapshot the sync's share cannot be told apart, so nothing
+// is reported.
+func (o *Orchestrator) reportRateUsage(ctx context.Context, result *models.SyncResult, before *models.RateUsage) {
+	if before == nil {
+		return
+	}
+	after, err := o.getRateUsage(ctx)
+	if err != nil {
+		logger.Warning("Failed to get rate usage: %v", err)
+		return
+	}
+
+	usage := after.Delta(before)
+	if usage.Total == 0 {
+		return
+	}
+
+	repos := make([]string, 0, len(usage.Repositories))
+	for repo := range usage.Repositories {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return usage.Repositories[repos[i]] > usage.Repositories[repos[j]]
+	})
+
+	parts := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		parts = append(parts, fmt.Sprintf("%s=%d", repo, usage.Repositories[repo]))
+	}
+	summary := fmt.Sprintf("GitHub API calls: %d total (%s)", usage.Total, strings.Join(parts, ", "))
+	if usage.Remaining >= 0 {
+		summary += fmt.Sprintf("; %d remaining until %s", usage.Remaining, usage.ResetAt.Format(time.RFC3339))
+	}
+	result.Warnings = append(result.Warnings, summary)
+	logger.Info("%s", summary)
+
+	budget := o.config.GitHub.RepoCallBudget
+	if budget <= 0 {
+		return
+	}
+	for _, repo := range repos {
+		if calls := usage.Repositories[repo]; calls > budget {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("%s used %d GitHub API calls, over the per-repository budget of %d", repo, calls, budget))
+		}
+	}
+}
+
+// ValidateSync checks the vector store against the sync result: it must hold
+// at least the vectors the sync upserted
+func (o *Orchestrator) ValidateSync(ctx context.Context, result *models.SyncResult) error {
+	stats, err := o.getIndexStats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get index stats: %w", err)
+	}
+
+	namespace := o.config.GitHub.Organization
+	if count, ok := stats.Namespaces[namespace]; ok {
+		logger.Info("Namespace '%s' holds %d vectors (%d in total)", namespace, count, stats.TotalVectors)
+	}
+	if stats.TotalVectors < int64(result.VectorsUpserted) {
+		return fmt.Errorf("vector store holds %d vectors, fewer than the %d upserted", stats.TotalVectors, result.VectorsUpserted)
+	}
+	return nil
+}
+
+// getIndexStats gets the vector counts of the vector storage service
+func (o *Orchestrator) getIndexStats(ctx context.Context) (*models.IndexStats, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.vectorStorageURL+"/stats", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("index stats lookup failed: %s", body)
+	}
+
+	var stats models.IndexStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
 // getChangedFiles gets changed files for a repository
 func (o *Orchestrator) getChangedFiles(ctx context.Context, repo *models.Repository, lastCommitSHA string) ([]*models.FileChange, error) {
 	url := fmt.Sprintf("%s/changes?repo=%s&last_commit=%s", o.githubServiceURL, repo.FullName, lastCommitSHA)
+	if o.config.Processing.LazyContentFetch {
+		url += "&lazy=true"
+	}
 
 	resp, err := o.httpClient.Get(url)
 	if err != nil {
@@ -175,45 +507,263 @@ func (o *Orchestrator) getChangedFiles(ctx context.Context, repo *models.Reposit
 	return files, nil
 }
 
-// filterFiles filters files based on extensions and patterns
-func (o *Orchestrator) filterFiles(files []*models.FileChange) []*models.FileChange {
-	var validFiles []*models.FileChange
+// getWikiPages gets changed wiki pages for a repository
+func (o *Orchestrator) getWikiPages(ctx context.Context, projectID string, repo *models.Repository, incremental bool) ([]*models.FileChange, error) {
+	lastCommitSHA := ""
+	if incremental {
+		lastCommitSHA, _ = o.getLastCommitSHA(ctx, projectID, repo.FullName+".wiki")
+	}
 
-	for _, file := range files {
-		// Check extensions
-		valid := false
-		for _, ext := range o.config.Processing.AllowedExtensions {
-			if len(file.FilePath) >= len(ext) && file.FilePath[len(file.FilePath)-len(ext):] == ext {
-				valid = true
-				break
-			}
+	url := fmt.Sprintf("%s/wiki?repo=%s&last_commit=%s", o.githubServiceURL, repo.FullName, lastCommitSHA)
+
+	resp, err := o.httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("wiki lookup failed: %s", body)
+	}
+
+	var pages []*models.FileChange
+	if err := json.NewDecoder(resp.Body).Decode(&pages); err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+// conversationKinds lists the enabled issue/discussion/release sources (discovery endpoint names)
+func (o *Orchestrator) conversationKinds() []string {
+	var kinds []string
+	if o.config.GitHub.IncludeIssues {
+		kinds = append(kinds, "issues")
+	}
+	if o.config.GitHub.IncludeDiscussions {
+		kinds = append(kinds, "discussions")
+	}
+	if o.config.GitHub.IncludeReleases {
+		kinds = append(kinds, "releases")
+	}
+	return kinds
+}
+
+// getConversations gets issues, discussions, or releases updated since the last sync
+func (o *Orchestrator) getConversations(ctx context.Context, projectID string, repo *models.Repository, kind string, incremental bool) ([]*models.FileChange, error) {
+	params := neturl.Values{"repo": {repo.FullName}}
+	if incremental {
+		if last, err := o.getLastSyncMetadata(ctx, projectID, repo.FullName); err == nil && last != nil && !last.LastSyncedAt.IsZero() {
+			params.Set("since", last.LastSyncedAt.UTC().Format(time.RFC3339))
+		}
+	}
+
+	resp, err := o.httpClient.Get(fmt.Sprintf("%s/%s?%s", o.githubServiceURL, kind, params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s lookup failed: %s", kind, body)
+	}
+
+	var docs []*models.FileChange
+	if err := json.NewDecoder(resp.Body).Decode(&docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// getChangedBlobs detects changed files by diffing blob SHAs against the
+// known ones recorded in metadata. Discovery skips files the rules reject,
+// which never get a recorded SHA.
+func (o *Orchestrator) getChangedBlobs(ctx context.Context, repo *models.Repository, known map[string]string, rules filter.Rules) ([]*models.FileChange, error) {
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"repository": repo.FullName,
+		"known":      known,
+		"rules":      rules,
+	})
+
+	resp, err := o.httpClient.Post(
+		fmt.Sprintf("%s/changes/blobs", o.githubServiceURL),
+		"application/json",
+		bytes.NewBuffer(reqBody),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("blob change detection failed: %s", body)
+	}
+
+	var files []*models.FileChange
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// metadataPageSize is the number of file records fetched per metadata list request
+const metadataPageSize = 1000
+
+// listMetadata returns a project's file records, limited to one repository
+// and status if given, fetching them a page at a time. Tombstones are only
+// listed when asked for by status.
+func (o *Orchestrator) listMetadata(ctx context.Context, projectID, repository, status string) ([]*models.SyncMetadata, error) {
+	params := neturl.Values{"project_id": {projectID}, "limit": {strconv.Itoa(metadataPageSize)}}
+	if repository != "" {
+		params.Set("repository", repository)
+	}
+	if status != "" {
+		params.Set("status", status)
+	}
+
+	var entries []*models.SyncMetadata
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			fmt.Sprintf("%s/metadata/list?%s", o.metadataServiceURL, params.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := o.doMetadata(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("metadata lookup failed: %s", body)
+		}
+
+		var page []*models.SyncMetadata
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, page...)
+
+		cursor := resp.Header.Get("X-Next-Cursor")
+		if cursor == "" || cursor == params.Get("cursor") {
+			return entries, nil
 		}
+		params.Set("cursor", cursor)
+	}
+}
 
-		if !valid {
+// getPullRequestChanges gets the files changed by a pull request
+func (o *Orchestrator) getPullRequestChanges(ctx context.Context, repoFullName string, number int) ([]*models.FileChange, error) {
+	params := neturl.Values{
+		"repo":   {repoFullName},
+		"number": {strconv.Itoa(number)},
+	}
+
+	resp, err := o.httpClient.Get(fmt.Sprintf("%s/pull-request/changes?%s", o.githubServiceURL, params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("pull request lookup failed: %s", body)
+	}
+
+	var files []*models.FileChange
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// fetchContents downloads content for files discovered lazily, dropping files that fail
+func (o *Orchestrator) fetchContents(ctx context.Context, files []*models.FileChange, result *models.SyncResult) []*models.FileChange {
+	fetched := make([]*models.FileChange, 0, len(files))
+	for _, file := range files {
+		if file.Content != "" || isRemoval(file) {
+			fetched = append(fetched, file)
 			continue
 		}
 
-		// Check exclude patterns
-		excluded := false
-		for _, pattern := range o.config.Processing.ExcludePatterns {
-			if contains(file.FilePath, pattern) {
-				excluded = true
-				break
-			}
+		content, err := o.getFileContent(ctx, file)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to fetch content for %s/%s: %v", file.Repository, file.FilePath, err))
+			continue
 		}
+		file.SetContent(content)
+		fetched = append(fetched, file)
+	}
+	return fetched
+}
+
+// getFileContent fetches a single file's content from the GitHub service
+func (o *Orchestrator) getFileContent(ctx context.Context, file *models.FileChange) ([]byte, error) {
+	params := neturl.Values{
+		"repo":     {file.Repository},
+		"path":     {file.FilePath},
+		"ref":      {file.CommitSHA},
+		"blob_sha": {file.BlobSHA},
+	}
+
+	resp, err := o.httpClient.Get(fmt.Sprintf("%s/content?%s", o.githubServiceURL, params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("content fetch failed: %s", body)
+	}
+
+	return body, nil
+}
 
-		if !excluded {
+// filterFiles keeps the files the rules accept, plus removed files that
+// would have been accepted so their vectors and records can be cleaned up
+func (o *Orchestrator) filterFiles(files []*models.FileChange, rules filter.Rules) []*models.FileChange {
+	var validFiles []*models.FileChange
+
+	for _, file := range files {
+		decision := filter.Check(file, rules)
+		if decision.Accepted() || decision.Reason == filter.RejectDeleted {
 			validFiles = append(validFiles, file)
+			continue
 		}
+		logger.Debug("Skipping %s/%s: %s %s", file.Repository, file.FilePath, decision.Reason, decision.Detail)
 	}
 
 	return validFiles
 }
 
-// processFiles processes files into embeddings
-func (o *Orchestrator) processFiles(ctx context.Context, files []*models.FileChange) ([]*models.Embedding, int, error) {
+// processFiles processes files into embeddings for the given namespace,
+// returning the embeddings, the chunk count, and how many chunks were
+// skipped as already stored; strategies maps file extensions to chunking
+// strategies and may be nil. When chunkIDs is non-nil it receives, per
+// repository/path, the IDs of every chunk now stored for files that were
+// fully processed.
+func (o *Orchestrator) processFiles(ctx context.Context, files []*models.FileChange, namespace string, strategies map[string]string, chunkIDs map[string][]string) ([]*models.Embedding, int, int, error) {
 	var allEmbeddings []*models.Embedding
-	totalChunks := 0
+	totalChunks, totalUnchanged := 0, 0
+
+	// Duplicate chunks are only skipped within this run
+	var dedup *chunkDeduper
+	if o.config.Processing.DedupChunks {
+		dedup = newChunkDeduper()
+	}
 
 	// Process in batches
 	batchSize := o.config.Processing.MaxWorkers
@@ -224,63 +774,194 @@ func (o *Orchestrator) processFiles(ctx context.Context, files []*models.FileCha
 		}
 
 		batch := files[i:end]
-		embeddings, chunks, err := o.processBatch(ctx, batch)
+		embeddings, chunks, unchanged, err := o.processBatch(ctx, batch, dedup, namespace, strategies, chunkIDs)
 		if err != nil {
-			return nil, 0, err
+			return nil, 0, 0, err
 		}
 
 		allEmbeddings = append(allEmbeddings, embeddings...)
 		totalChunks += chunks
+		totalUnchanged += unchanged
 	}
 
-	return allEmbeddings, totalChunks, nil
+	if dedup != nil && dedup.skipped > 0 {
+		logger.Info("Skipped %d duplicate chunks", dedup.skipped)
+	}
+	if totalUnchanged > 0 {
+		logger.Info("Skipped %d unchanged chunks", totalUnchanged)
+	}
+
+	return allEmbeddings, totalChunks, totalUnchanged, nil
 }
 
 // processBatch processes a batch of files
-func (o *Orchestrator) processBatch(ctx context.Context, files []*models.FileChange) ([]*models.Embedding, int, error) {
+func (o *Orchestrator) processBatch(ctx context.Context, files []*models.FileChange, dedup *chunkDeduper, namespace string, strategies map[string]string, chunkIDs map[string][]string) ([]*models.Embedding, int, int, error) {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var allEmbeddings []*models.Embedding
-	totalChunks := 0
+	totalChunks, totalUnchanged := 0, 0
 
-	for _, file := range files {
+	// Chunk the whole batch in one round-trip
+	chunked, err := o.chunkDocuments(ctx, files, strategies)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	// Chunk IDs are derived from content, so an ID that is already stored
+	// means the chunk is identical to what was embedded before
+	stored := map[string]bool{}
+	if o.config.Processing.SkipUnchangedChunks {
+		stored, err = o.storedChunkIDs(ctx, chunked, namespace)
+		if err != nil {
+			logger.Warning("Re-embedding all chunks, failed to check stored vectors: %v", err)
+		}
+	}
+
+	// Decide which chunks each file embeds before fanning out, so the
+	// deduper hands shared content to the same file every run
+	pending := make([][]*models.Document, len(files))
+	for i, result := range chunked {
+		if result.Error != "" || result.Skipped != "" {
+			continue
+		}
+		var changed []*models.Document
+		for _, doc := range result.Documents {
+			if !stored[doc.ID] {
+				changed = append(changed, doc)
+			}
+		}
+		pending[i] = dedup.claim(changed)
+	}
+
+	for i, file := range files {
 		wg.Add(1)
-		go func(f *models.FileChange) {
+		go func(f *models.FileChange, result *chunkResult, documents []*models.Document) {
 			defer wg.Done()
 
-			// Chunk document
-			documents, err := o.chunkDocument(ctx, f)
-			if err != nil {
-				logger.Warning("Failed to chunk document %s: %v", f.FilePath, err)
+			if result.Error != "" {
+				logger.Warning("Failed to chunk document %s: %s", f.FilePath, result.Error)
+				return
+			}
+			if result.Skipped != "" {
+				logger.Info("Skipped %s: %s", f.FilePath, result.Skipped)
+				if chunkIDs != nil {
+					// Nothing is indexed for the file any more
+					mu.Lock()
+					chunkIDs[f.Repository+"/"+f.FilePath] = []string{}
+					mu.Unlock()
+				}
 				return
 			}
 
+			if o.config.Processing.SummarizeChunks {
+				o.summarizeDocuments(ctx, documents)
+			}
+
 			// Generate embeddings
 			embeddings, err := o.generateEmbeddings(ctx, documents)
+			dedup.settle(documents, err == nil)
 			if err != nil {
 				logger.Warning("Failed to generate embeddings for %s: %v", f.FilePath, err)
 				return
 			}
 
+			// Record only chunks with a vector: those already stored and
+			// those embedded now, not duplicates another file embedded
+			embedded := make(map[string]bool, len(documents))
+			for _, doc := range documents {
+				embedded[doc.ID] = true
+			}
+			ids := []string{}
+			unchanged := 0
+			for _, doc := range result.Documents {
+				if stored[doc.ID] {
+					unchanged++
+				}
+				if stored[doc.ID] || embedded[doc.ID] {
+					ids = append(ids, doc.ID)
+				}
+			}
+
 			mu.Lock()
 			allEmbeddings = append(allEmbeddings, embeddings...)
-			totalChunks += len(documents)
+			totalChunks += len(documents) + unchanged
+			totalUnchanged += unchanged
+			if chunkIDs != nil {
+				chunkIDs[f.Repository+"/"+f.FilePath] = ids
+			}
 			mu.Unlock()
-		}(file)
+		}(file, chunked[i], pending[i])
+	}
+
+	wg.Wait()
+	return allEmbeddings, totalChunks, totalUnchanged, nil
+}
+
+// storedChunkIDs returns which of the chunked documents' IDs already have
+// vectors in the namespace
+func (o *Orchestrator) storedChunkIDs(ctx context.Context, chunked []*chunkResult, namespace string) (map[string]bool, error) {
+	var ids []string
+	for _, result := range chunked {
+		for _, doc := range result.Documents {
+			ids = append(ids, doc.ID)
+		}
+	}
+	stored := make(map[string]bool)
+	if len(ids) == 0 {
+		return stored, nil
+	}
+
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"ids":       ids,
+		"namespace": namespace,
+	})
+
+	resp, err := o.httpClient.Post(
+		fmt.Sprintf("%s/exists", o.vectorStorageURL),
+		"application/json",
+		bytes.NewBuffer(reqBody),
+	)
+	if err != nil {
+		return stored, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return stored, fmt.Errorf("vector lookup failed: %s", body)
+	}
+
+	var result struct {
+		Existing []string `json:"existing"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return stored, err
 	}
 
-	wg.Wait()
-	return allEmbeddings, totalChunks, nil
+	for _, id := range result.Existing {
+		stored[id] = true
+	}
+	return stored, nil
+}
+
+// chunkResult is one file's outcome from the batch chunking endpoint
+type chunkResult struct {
+	Documents []*models.Document `json:"documents"`
+	Skipped   string             `json:"skipped,omitempty"`
+	Error     string             `json:"error,omitempty"`
 }
 
-// chunkDocument chunks a document
-func (o *Orchestrator) chunkDocument(ctx context.Context, file *models.FileChange) ([]*models.Document, error) {
+// chunkDocuments chunks a batch of documents, returning results in file order
+func (o *Orchestrator) chunkDocuments(ctx context.Context, files []*models.FileChange, strategies map[string]string) ([]*chunkResult, error) {
 	reqBody, _ := json.Marshal(map[string]interface{}{
-		"file_change": file,
+		"file_changes": files,
+		"strategies":   strategies,
+		"model":        o.config.Processing.EmbeddingModel,
+		"max_tokens":   o.config.Processing.EmbeddingMaxTokens,
 	})
 
 	resp, err := o.httpClient.Post(
-		fmt.Sprintf("%s/chunk", o.documentProcessorURL),
+		fmt.Sprintf("%s/chunk/batch", o.documentProcessorURL),
 		"application/json",
 		bytes.NewBuffer(reqBody),
 	)
@@ -289,70 +970,281 @@ func (o *Orchestrator) chunkDocument(ctx context.Context, file *models.FileChang
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("batch chunking failed: %s", body)
+	}
+
 	var result struct {
-		Documents []*models.Document `json:"documents"`
+		Results []*chunkResult `json:"results"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
 	}
+	if len(result.Results) != len(files) {
+		return nil, fmt.Errorf("document processor returned %d results for %d files", len(result.Results), len(files))
+	}
 
-	return result.Documents, nil
+	return result.Results, nil
 }
 
-// generateEmbeddings generates embeddings for documents
-func (o *Orchestrator) generateEmbeddings(ctx context.Context, documents []*models.Document) ([]*models.Embedding, error) {
+// summarizeDocuments adds a one-sentence summary and keywords to each
+// document's metadata. Enrichment is best-effort: failures are logged and
+// the documents are embedded without it.
+func (o *Orchestrator) summarizeDocuments(ctx context.Context, documents []*models.Document) {
 	if len(documents) == 0 {
-		return []*models.Embedding{}, nil
+		return
 	}
 
-	// Extract texts
 	texts := make([]string, len(documents))
 	for i, doc := range documents {
 		texts[i] = doc.Content
 	}
 
-	// Call embedding service
 	reqBody, _ := json.Marshal(map[string]interface{}{
 		"texts": texts,
 	})
 
 	resp, err := o.httpClient.Post(
-		fmt.Sprintf("%s/embed", o.embeddingServiceURL),
+		fmt.Sprintf("%s/summarize", o.embeddingServiceURL),
 		"application/json",
 		bytes.NewBuffer(reqBody),
 	)
 	if err != nil {
-		return nil, err
+		logger.Warning("Failed to summarize chunks of %s: %v", documents[0].FilePath, err)
+		return
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		logger.Warning("Failed to summarize chunks of %s: %s", documents[0].FilePath, body)
+		return
+	}
+
 	var result struct {
-		Embeddings [][]float32 `json:"embeddings"`
+		Summaries []struct {
+			Summary  string   `json:"summary"`
+			Keywords []string `json:"keywords"`
+			Error    string   `json:"error"`
+		} `json:"summaries"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || len(result.Summaries) != len(documents) {
+		logger.Warning("Unexpected summarize response for %s", documents[0].FilePath)
+		return
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	for i, summary := range result.Summaries {
+		if summary.Error != "" {
+			logger.Debug("No summary for %s chunk %d: %s", documents[i].FilePath, documents[i].ChunkIndex, summary.Error)
+			continue
+		}
+		if summary.Summary != "" {
+			documents[i].Metadata["summary"] = summary.Summary
+		}
+		if len(summary.Keywords) > 0 {
+			documents[i].Metadata["keywords"] = strings.Join(summary.Keywords, ",")
+		}
+	}
+}
+
+// generateEmbeddings generates embeddings for documents
+func (o *Orchestrator) generateEmbeddings(ctx context.Context, documents []*models.Document) ([]*models.Embedding, error) {
+	if len(documents) == 0 {
+		return []*models.Embedding{}, nil
+	}
+
+	// Reuse vectors for chunks embedded in earlier runs
+	vectors := make([][]float32, len(documents))
+	vectorModels := make([]string, len(documents))
+	var pending []int
+	for i, doc := range documents {
+		if vector, model, ok := o.embeddingCache.Get(doc.Metadata["content_hash"]); ok {
+			vectors[i], vectorModels[i] = vector, model
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	if len(pending) > 0 {
+		fresh, model, err := o.embedTexts(ctx, documents, pending)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range pending {
+			vectors[i], vectorModels[i] = fresh[j], model
+			o.embeddingCache.Put(documents[i].Metadata["content_hash"], fresh[j], model)
+		}
 	}
 
-	// Create embeddings
+	// Create embeddings, stamped with their model so vectors from different
+	// models in one index can be found and re-embedded, and with the time so
+	// vectors no sync refreshes can be cleaned up by age
+	indexedAt := time.Now().UTC().Format(time.RFC3339)
 	embeddings := make([]*models.Embedding, len(documents))
 	for i, doc := range documents {
+		if doc.Metadata == nil {
+			doc.Metadata = make(map[string]string)
+		}
+		doc.Metadata[models.MetadataIndexedAt] = indexedAt
+		if vectorModels[i] != "" {
+			doc.Metadata["embedding_model"] = vectorModels[i]
+		}
+		if o.config.VectorStore.StoreText {
+			doc.Metadata[models.MetadataText] = doc.Content
+		}
 		embeddings[i] = &models.Embedding{
 			ID:         doc.ID,
-			Vector:     result.Embeddings[i],
+			Vector:     vectors[i],
 			Metadata:   doc.Metadata,
 			Repository: doc.Repository,
 			FilePath:   doc.FilePath,
 			Namespace:  o.config.GitHub.Organization,
 		}
+		if o.config.VectorStore.SparseVectors {
+			embeddings[i].Sparse = sparse.Encode(doc.Content)
+		}
 	}
 
 	return embeddings, nil
 }
 
+// embedTexts calls the embedding service for the documents at the given
+// indexes, returning their vectors and the ID of the model that made them
+func (o *Orchestrator) embedTexts(ctx context.Context, documents []*models.Document, indexes []int) ([][]float32, string, error) {
+	// Extract texts
+	texts := make([]string, len(indexes))
+	for j, i := range indexes {
+		texts[j] = documents[i].Content
+	}
+
+	if o.embeddingClient != nil {
+		return o.embedTextsGRPC(ctx, texts)
+	}
+	if threshold := o.config.Processing.EmbeddingAsyncThreshold; threshold > 0 && len(texts) >= threshold {
+		return o.embedTextsAsync(ctx, texts)
+	}
+
+	// Call embedding service
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"texts":      texts,
+		"input_type": "document",
+	})
+
+	resp, err := o.httpClient.Post(
+		fmt.Sprintf("%s/embed", o.embeddingServiceURL),
+		"application/json",
+		bytes.NewBuffer(reqBody),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Model      string      `json:"model"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, "", err
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, "", fmt.Errorf("embedding service returned %d embeddings for %d texts", len(result.Embeddings), len(texts))
+	}
+
+	return result.Embeddings, result.Model, nil
+}
+
+// embeddingJobPollInterval is how often a background embedding job is checked
+const embeddingJobPollInterval = 2 * time.Second
+
+// embedTextsAsync embeds a large batch as a background job on the embedding
+// service and polls it, so no single request is held open for the whole batch
+func (o *Orchestrator) embedTextsAsync(ctx context.Context, texts []string) ([][]float32, string, error) {
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"texts":      texts,
+		"input_type": "document",
+	})
+	resp, err := o.httpClient.Post(o.embeddingServiceURL+"/embed/jobs", "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, "", err
+	}
+	var job struct {
+		ID string `json:"id"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&job)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || err != nil || job.ID == "" {
+		return nil, "", fmt.Errorf("embedding service did not accept the job (status %d)", resp.StatusCode)
+	}
+	logger.Info("Embedding %d texts in background job %s", len(texts), job.ID)
+
+	ticker := time.NewTicker(embeddingJobPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		case <-ticker.C:
+		}
+
+		status, err := o.embeddingJob(ctx, job.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		switch status.Status {
+		case "completed":
+			if status.Result == nil || len(status.Result.Embeddings) != len(texts) {
+				return nil, "", fmt.Errorf("embedding job %s returned no embeddings for %d texts", job.ID, len(texts))
+			}
+			return status.Result.Embeddings, status.Result.Model, nil
+		case "failed":
+			return nil, "", fmt.Errorf("embedding job %s failed: %s", job.ID, status.Error)
+		}
+	}
+}
+
+type embeddingJobStatus struct {
+	Status    string `json:"status"`
+	Total     int    `json:"total"`
+	Processed int    `json:"processed"`
+	Error     string `json:"error"`
+	Result    *struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Model      string      `json:"model"`
+	} `json:"result"`
+}
+
+// embeddingJob fetches a background embedding job's status
+func (o *Orchestrator) embeddingJob(ctx context.Context, id string) (*embeddingJobStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.embeddingServiceURL+"/embed/jobs?id="+neturl.QueryEscape(id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding job %s: status %d", id, resp.StatusCode)
+	}
+
+	var status embeddingJobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
 // upsertVectors upserts vectors to Pinecone
 func (o *Orchestrator) upsertVectors(ctx context.Context, embeddings []*models.Embedding, namespace string) error {
+	if o.vectorClient != nil {
+		return o.upsertVectorsGRPC(ctx, embeddings)
+	}
+
 	reqBody, _ := json.Marshal(map[string]interface{}{
 		"embeddings": embeddings,
 	})
@@ -375,43 +1267,230 @@ func (o *Orchestrator) upsertVectors(ctx context.Context, embeddings []*models.E
 	return nil
 }
 
-// saveMetadata saves sync metadata
-func (o *Orchestrator) saveMetadata(ctx context.Context, metadata *models.SyncMetadata) error {
-	reqBody, _ := json.Marshal(metadata)
+// doMetadata sends a request to the metadata service with the orchestrator's
+// API key, which the service authenticates and audits changes under
+func (o *Orchestrator) doMetadata(req *http.Request) (*http.Response, error) {
+	if o.config.Database.APIKey != "" {
+		req.Header.Set("X-API-Key", o.config.Database.APIKey)
+	}
+	return o.httpClient.Do(req)
+}
 
-	resp, err := o.httpClient.Post(
-		fmt.Sprintf("%s/metadata", o.metadataServiceURL),
-		"application/json",
-		bytes.NewBuffer(reqBody),
-	)
+// metadataBatchSize is the number of file records saved per bulk request
+const metadataBatchSize = 500
+
+// saveMetadata saves file records and repository sync states in one bulk
+// request, which the metadata service applies all or nothing
+func (o *Orchestrator) saveMetadata(ctx context.Context, batch *models.SyncBatch) error {
+	reqBody, _ := json.Marshal(batch)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/metadata/bulk", o.metadataServiceURL), bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.doMetadata(req)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("metadata save failed: %s", body)
+	}
 	return nil
 }
 
-// getLastCommitSHA gets the last synced commit SHA
+// getLastCommitSHA gets the commit a repository was last synced at. Before
+// repository sync state was recorded, that was the commit of its most
+// recently synced file, which is still used when there is no state.
 func (o *Orchestrator) getLastCommitSHA(ctx context.Context, projectID, repository string) (string, error) {
-	url := fmt.Sprintf("%s/metadata?project_id=%s&repository=%s", o.metadataServiceURL, projectID, repository)
-
-	resp, err := o.httpClient.Get(url)
+	state, err := o.getRepoState(ctx, projectID, repository)
 	if err != nil {
 		return "", err
 	}
+	if state != nil {
+		return state.LastCommitSHA, nil
+	}
+
+	metadata, err := o.getLastSyncMetadata(ctx, projectID, repository)
+	if err != nil || metadata == nil {
+		return "", err
+	}
+	return metadata.LastCommitSHA, nil
+}
+
+// syncedCommits returns the commit each repository was read at: that of its
+// changed files, or the previous one if nothing changed. Issues,
+// discussions, and releases carry timestamps instead of commits and are
+// left out; wiki pages count as their wiki's repository.
+func syncedCommits(files []*models.FileChange, previous map[string]string) map[string]string {
+	commits := make(map[string]string, len(previous))
+	for repository, commit := range previous {
+		if commit != "" {
+			commits[repository] = commit
+		}
+	}
+	for _, file := range files {
+		if (file.Source == "" || file.Source == "wiki") && file.CommitSHA != "" {
+			commits[file.Repository] = file.CommitSHA
+		}
+	}
+	return commits
+}
+
+// repoStateVersions returns the version of each repository sync state
+// recorded for a project
+func (o *Orchestrator) repoStateVersions(ctx context.Context, projectID string) (map[string]int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/repo-state?%s", o.metadataServiceURL, neturl.Values{"project_id": {projectID}}.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.doMetadata(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("sync state lookup failed: %s", body)
+	}
+
+	var states []*models.RepoSyncState
+	if err := json.NewDecoder(resp.Body).Decode(&states); err != nil {
+		return nil, err
+	}
+	versions := make(map[string]int64, len(states))
+	for _, state := range states {
+		versions[state.Repository] = state.Version
+	}
+	return versions, nil
+}
+
+// getRepoState gets a repository's sync state, or nil if none is recorded
+func (o *Orchestrator) getRepoState(ctx context.Context, projectID, repository string) (*models.RepoSyncState, error) {
+	params := neturl.Values{"project_id": {projectID}, "repository": {repository}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/repo-state?%s", o.metadataServiceURL, params.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.doMetadata(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("sync state lookup failed: %s", body)
+	}
+
+	var state models.RepoSyncState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// getLastSyncMetadata gets the last sync state for a repository, or nil if never synced
+func (o *Orchestrator) getLastSyncMetadata(ctx context.Context, projectID, repository string) (*models.SyncMetadata, error) {
+	params := neturl.Values{"project_id": {projectID}, "repository": {repository}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/metadata?%s", o.metadataServiceURL, params.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.doMetadata(req)
+	if err != nil {
+		return nil, err
+	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return "", nil
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("metadata lookup failed: %s", body)
 	}
 
 	var metadata models.SyncMetadata
 	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return metadata.LastCommitSHA, nil
+	return &metadata, nil
+}
+
+// getProject gets a project's settings, or nil if it is not registered
+func (o *Orchestrator) getProject(ctx context.Context, projectID string) (*models.Project, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/projects?%s", o.metadataServiceURL, neturl.Values{"id": {projectID}}.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.doMetadata(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("get project failed: %s", body)
+	}
+
+	var project models.Project
+	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+// loadProject gets a project's settings, or nil when it is not registered or
+// cannot be read, in which case the configured defaults apply
+func (o *Orchestrator) loadProject(ctx context.Context, projectID string) *models.Project {
+	project, err := o.getProject(ctx, projectID)
+	if err != nil {
+		logger.Warning("Using default filter rules and chunking strategies for project %s: %v", projectID, err)
+		return nil
+	}
+	return project
+}
+
+// filterRules returns the project's file filter rules, with the configured
+// ones filling in whatever the project leaves unset
+func (o *Orchestrator) filterRules(project *models.Project) filter.Rules {
+	return filter.ForProject(project, filter.Rules{
+		AllowedExtensions: o.config.Processing.AllowedExtensions,
+		ExcludePatterns:   o.config.Processing.ExcludePatterns,
+	})
+}
+
+// chunkStrategies returns the project's extension-to-strategy overrides.
+// Without them the document processor's configured mapping applies.
+func chunkStrategies(project *models.Project) map[string]string {
+	if project == nil {
+		return nil
+	}
+	return project.ChunkStrategies
 }
 
 // sendNotification sends a notification
@@ -466,6 +1545,45 @@ func (o *Orchestrator) handleSync(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(result)
 }
 
+func (o *Orchestrator) handleSyncPullRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projectID := r.URL.Query().Get("project_id")
+	if projectID == "" {
+		projectID = "default"
+	}
+
+	repo := r.URL.Query().Get("repo")
+	if repo == "" {
+		http.Error(w, "repo parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	number, err := strconv.Atoi(r.URL.Query().Get("number"))
+	if err != nil || number <= 0 {
+		http.Error(w, "number parameter must be a positive pull request number", http.StatusBadRequest)
+		return
+	}
+
+	// Default to a per-PR staging namespace so previews never mix with merged
+	// content; the repository name keeps equal PR numbers of two repositories apart
+	namespace := r.URL.Query().Get("namespace")
+	if namespace == "" {
+		namespace = fmt.Sprintf("%s-%s-pr-%d", o.config.GitHub.Organization, path.Base(repo), number)
+	}
+
+	result, err := o.SyncPullRequest(r.Context(), projectID, repo, number, namespace)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	_ = json.NewEncoder(w).Encode(result)
+}
+
 func (o *Orchestrator) handleHealth(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
 }
@@ -478,10 +1596,6 @@ func getServiceURL(envVar, defaultURL string) string {
 	return defaultURL
 }
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && s[:len(substr)] == substr
-}
-
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -511,6 +1625,7 @@ func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", orchestrator.handleHealth)
 	mux.HandleFunc("/sync", orchestrator.handleSync)
+	mux.HandleFunc("/sync/pull-request", orchestrator.handleSyncPullRequest)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Services.OrchestratorPort),
@@ -0,0 +1,673 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/filter"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// fakeServices stands in for every service the orchestrator calls
+type fakeServices struct {
+	repos   []*models.Repository
+	changes map[string][]*models.FileChange // repository -> changes
+	chunks  map[string][]string             // file path -> chunk IDs; one chunk per file if unset
+	stored  []*models.SyncMetadata          // listed a page of pageSize at a time
+	states  []*models.RepoSyncState
+	usage   []*models.RateUsage // served in turn, the last one repeatedly
+
+	pageSize   int
+	failDelete func(request map[string]interface{}) bool
+	failSave   func(batch *models.SyncBatch) bool
+	failEmbed  func(texts []string) bool
+	failRead   string // path whose GET requests fail
+
+	mu      sync.Mutex
+	deletes []map[string]interface{}
+	saved   []*models.SyncBatch // including failed saves
+	lists   int
+	blobs   []blobChangesRequest
+}
+
+// blobChangesRequest is the body of a /changes/blobs request
+type blobChangesRequest struct {
+	Repository string            `json:"repository"`
+	Known      map[string]string `json:"known"`
+	Rules      filter.Rules      `json:"rules"`
+}
+
+func (f *fakeServices) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	query := r.URL.Query()
+	if r.Method == http.MethodGet && r.URL.Path == f.failRead {
+		http.Error(w, "database locked", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/rate-usage":
+		if len(f.usage) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		usage := f.usage[0]
+		if len(f.usage) > 1 {
+			f.usage = f.usage[1:]
+		}
+		writeJSON(w, usage)
+	case "/repositories":
+		writeJSON(w, f.repos)
+	case "/changes":
+		writeJSON(w, f.changes[query.Get("repo")])
+	case "/changes/blobs":
+		var req blobChangesRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.blobs = append(f.blobs, req)
+		writeJSON(w, f.changes[req.Repository])
+	case "/pull-request/changes":
+		writeJSON(w, f.changes[query.Get("repo")+"#"+query.Get("number")])
+	case "/repo-state":
+		if query.Get("repository") == "" {
+			writeJSON(w, f.states)
+			return
+		}
+		for _, state := range f.states {
+			if state.Repository == query.Get("repository") {
+				writeJSON(w, state)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case "/metadata", "/projects":
+		http.NotFound(w, r)
+	case "/metadata/list":
+		f.lists++
+		start, _ := strconv.Atoi(query.Get("cursor"))
+		end := len(f.stored)
+		if f.pageSize > 0 && start+f.pageSize < end {
+			end = start + f.pageSize
+			w.Header().Set("X-Next-Cursor", strconv.Itoa(end))
+		}
+		var page []*models.SyncMetadata
+		for _, entry := range f.stored[start:end] {
+			if repository := query.Get("repository"); repository != "" && entry.Repository != repository {
+				continue
+			}
+			if status := query.Get("status"); (status == "" && entry.Status == "deleted") || (status != "" && entry.Status != status) {
+				continue
+			}
+			page = append(page, entry)
+		}
+		writeJSON(w, page)
+	case "/chunk/batch":
+		var req struct {
+			Files []*models.FileChange `json:"file_changes"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		results := make([]*chunkResult, len(req.Files))
+		for i, file := range req.Files {
+			ids, ok := f.chunks[file.FilePath]
+			if !ok {
+				ids = []string{file.Repository + "/" + file.FilePath + "#0"}
+			}
+			results[i] = &chunkResult{Documents: []*models.Document{}}
+			for j, id := range ids {
+				results[i].Documents = append(results[i].Documents, &models.Document{
+					ID: id, Repository: file.Repository, FilePath: file.FilePath, Content: file.Content,
+					ChunkIndex: j, Metadata: map[string]string{"content_hash": file.Content},
+				})
+			}
+		}
+		writeJSON(w, map[string]interface{}{"results": results})
+	case "/embed":
+		var req struct {
+			Texts []string `json:"texts"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if f.failEmbed != nil && f.failEmbed(req.Texts) {
+			http.Error(w, "embedding failed", http.StatusBadGateway)
+			return
+		}
+		embeddings := make([][]float32, len(req.Texts))
+		for i := range embeddings {
+			embeddings[i] = []float32{1, 0}
+		}
+		writeJSON(w, map[string]interface{}{"embeddings": embeddings, "model": "test/model"})
+	case "/delete":
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.deletes = append(f.deletes, req)
+		if f.failDelete != nil && f.failDelete(req) {
+			http.Error(w, "delete failed", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]string{"status": "ok"})
+	case "/metadata/bulk":
+		var batch models.SyncBatch
+		_ = json.NewDecoder(r.Body).Decode(&batch)
+		f.saved = append(f.saved, &batch)
+		if f.failSave != nil && f.failSave(&batch) {
+			http.Error(w, "version conflict", http.StatusConflict)
+			return
+		}
+		writeJSON(w, map[string]string{"status": "ok"})
+	case "/stats":
+		writeJSON(w, &models.IndexStats{TotalVectors: 10000})
+	case "/upsert", "/notify":
+		writeJSON(w, map[string]string{"status": "ok"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newTestOrchestrator returns an orchestrator whose services are all served by fake
+func newTestOrchestrator(t *testing.T, fake *fakeServices) *Orchestrator {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	cfg.GitHub.Organization = "org"
+	cfg.Processing.MaxWorkers = 50
+	return &Orchestrator{
+		githubServiceURL:       server.URL,
+		documentProcessorURL:   server.URL,
+		embeddingServiceURL:    server.URL,
+		vectorStorageURL:       server.URL,
+		notificationServiceURL: server.URL,
+		metadataServiceURL:     server.URL,
+		httpClient:             server.Client(),
+		config:                 cfg,
+	}
+}
+
+// savedRecord summarizes a saved file record for comparison
+type savedRecord struct {
+	Status   string
+	Commit   string
+	Version  int64
+	ChunkIDs []string
+}
+
+func savedRecords(batches []*models.SyncBatch) map[string]savedRecord {
+	records := make(map[string]savedRecord)
+	for _, batch := range batches {
+		for _, record := range batch.Records {
+			records[record.Repository+"/"+record.FilePath] = savedRecord{record.Status, record.LastCommitSHA, record.Version, record.ChunkIDs}
+		}
+	}
+	return records
+}
+
+func TestSyncProjectSavesVersionedRecords(t *testing.T) {
+	repo := &models.Repository{FullName: "org/docs", Name: "docs", DefaultBranch: "main"}
+	newFake := func() *fakeServices {
+		return &fakeServices{
+			repos: []*models.Repository{repo},
+			changes: map[string][]*models.FileChange{"org/docs": {
+				{Repository: "org/docs", FilePath: "guide.md", ChangeType: "modified", CommitSHA: "c2", Content: "guide"},
+				{Repository: "org/docs", FilePath: "new.md", ChangeType: "added", CommitSHA: "c2", Content: "new"},
+				{Repository: "org/docs", FilePath: "old.md", ChangeType: "removed", CommitSHA: "c2"},
+			}},
+			chunks: map[string][]string{"guide.md": {"guide-kept", "guide-new"}},
+			stored: []*models.SyncMetadata{
+				{Repository: "org/docs", FilePath: "guide.md", Status: "synced", ChunkIDs: []string{"guide-kept", "guide-stale"}, Version: 3},
+				{Repository: "org/docs", FilePath: "old.md", Status: "synced", ChunkIDs: []string{"old-0"}, Version: 5},
+			},
+			states:   []*models.RepoSyncState{{Repository: "org/docs", LastCommitSHA: "c1", Version: 7}},
+			pageSize: 1,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		failDelete func(request map[string]interface{}) bool
+		want       map[string]savedRecord
+	}{
+		{
+			name: "stale and removed vectors deleted",
+			want: map[string]savedRecord{
+				"org/docs/guide.md": {"synced", "c2", 3, []string{"guide-kept", "guide-new"}},
+				"org/docs/new.md":   {"synced", "c2", 0, []string{"org/docs/new.md#0"}},
+				"org/docs/old.md":   {tombstoneStatus, "c2", 5, nil},
+			},
+		},
+		{
+			// The stale IDs stay recorded so the next sync deletes them
+			name:       "stale deletion fails",
+			failDelete: func(request map[string]interface{}) bool { return request["ids"] != nil },
+			want: map[string]savedRecord{
+				"org/docs/guide.md": {"synced", "c2", 3, []string{"guide-kept", "guide-new", "guide-stale"}},
+				"org/docs/new.md":   {"synced", "c2", 0, []string{"org/docs/new.md#0"}},
+				"org/docs/old.md":   {tombstoneStatus, "c2", 5, nil},
+			},
+		},
+		{
+			// Without a tombstone the stored record still lists the vectors
+			name:       "removed file deletion fails",
+			failDelete: func(request map[string]interface{}) bool { return request["filter"] != nil },
+			want: map[string]savedRecord{
+				"org/docs/guide.md": {"synced", "c2", 3, []string{"guide-kept", "guide-new"}},
+				"org/docs/new.md":   {"synced", "c2", 0, []string{"org/docs/new.md#0"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFake()
+			fake.failDelete = tt.failDelete
+			o := newTestOrchestrator(t, fake)
+
+			result, err := o.SyncProject(context.Background(), "docs", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Success || result.VectorsUpserted != 3 || result.FilesProcessed != 3 {
+				t.Errorf("result = %+v", result)
+			}
+
+			if len(fake.saved) != 1 {
+				t.Fatalf("saved %d batches, want 1", len(fake.saved))
+			}
+			if got := savedRecords(fake.saved); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %+v\nwant %+v", got, tt.want)
+			}
+			states := fake.saved[0].RepoStates
+			if len(states) != 1 || states[0].Repository != "org/docs" || states[0].LastCommitSHA != "c2" || states[0].Version != 7 {
+				t.Errorf("repo states = %+v, want org/docs at c2 with version 7", states)
+			}
+
+			var deletedIDs []interface{}
+			var deletedPaths []interface{}
+			for _, request := range fake.deletes {
+				if ids, ok := request["ids"].([]interface{}); ok {
+					deletedIDs = append(deletedIDs, ids...)
+				}
+				if filter, ok := request["filter"].(map[string]interface{}); ok {
+					deletedPaths = append(deletedPaths, filter["file_path"].([]interface{})...)
+				}
+				if request["namespace"] != "org" {
+					t.Errorf("delete in namespace %v, want org", request["namespace"])
+				}
+			}
+			if !reflect.DeepEqual(deletedIDs, []interface{}{"guide-stale"}) || !reflect.DeepEqual(deletedPaths, []interface{}{"old.md"}) {
+				t.Errorf("deleted IDs %v and paths %v, want [guide-stale] and [old.md]", deletedIDs, deletedPaths)
+			}
+		})
+	}
+}
+
+func TestSyncProjectReadsVersionsFirst(t *testing.T) {
+	newFake := func() *fakeServices {
+		return &fakeServices{
+			repos: []*models.Repository{{FullName: "org/docs", Name: "docs", DefaultBranch: "main"}},
+			changes: map[string][]*models.FileChange{"org/docs": {
+				{Repository: "org/docs", FilePath: "back.md", ChangeType: "added", CommitSHA: "c2", Content: "back"},
+				{Repository: "org/docs", FilePath: "new.md", ChangeType: "added", CommitSHA: "c2", Content: "new"},
+			}},
+			stored: []*models.SyncMetadata{{Repository: "org/docs", FilePath: "back.md", Status: "deleted", Version: 9}},
+		}
+	}
+
+	t.Run("missing records and states are created", func(t *testing.T) {
+		fake := newFake()
+		o := newTestOrchestrator(t, fake)
+		if _, err := o.SyncProject(context.Background(), "docs", true); err != nil {
+			t.Fatal(err)
+		}
+		if len(fake.saved) != 1 {
+			t.Fatalf("saved %d batches, want 1", len(fake.saved))
+		}
+
+		// A tombstoned file coming back replaces its tombstone's version
+		got := make(map[string]string)
+		for _, record := range fake.saved[0].Records {
+			got[record.FilePath] = fmt.Sprintf("version %d create %v", record.Version, record.Create)
+		}
+		want := map[string]string{"back.md": "version 9 create false", "new.md": "version 0 create true"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("records = %v, want %v", got, want)
+		}
+		states := fake.saved[0].RepoStates
+		if len(states) != 1 || !states[0].Create || states[0].Version != 0 {
+			t.Errorf("repo states = %+v, want org/docs created", states)
+		}
+	})
+
+	// Without the versions, saves could only be unconditional
+	for _, path := range []string{"/metadata/list", "/repo-state"} {
+		t.Run("failed read of "+path, func(t *testing.T) {
+			fake := newFake()
+			fake.failRead = path
+			o := newTestOrchestrator(t, fake)
+			result, err := o.SyncProject(context.Background(), "docs", true)
+			if err == nil || result.Success {
+				t.Fatalf("sync succeeded with %+v, want it aborted", result)
+			}
+			if len(fake.saved) != 0 || result.FilesDiscovered != 0 {
+				t.Errorf("saved %d batches after discovering %d files, want the sync stopped before either", len(fake.saved), result.FilesDiscovered)
+			}
+		})
+	}
+}
+
+func TestSyncProjectBlobChangesSendRules(t *testing.T) {
+	// Discovery needs the rules to skip excluded files, which never get a
+	// recorded SHA and would otherwise be reported as new on every sync
+	fake := &fakeServices{
+		repos: []*models.Repository{{FullName: "org/docs", Name: "docs", DefaultBranch: "main"}},
+		stored: []*models.SyncMetadata{
+			{Repository: "org/docs", FilePath: "README.md", BlobSHA: "readme"},
+			{Repository: "org/other", FilePath: "a.md", BlobSHA: "other"},
+		},
+	}
+	o := newTestOrchestrator(t, fake)
+	o.config.Processing.ChangeDetection = "blob"
+	o.config.Processing.AllowedExtensions = []string{".md"}
+	o.config.Processing.ExcludePatterns = []string{"drafts"}
+
+	if _, err := o.SyncProject(context.Background(), "docs", true); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []blobChangesRequest{{
+		Repository: "org/docs",
+		Known:      map[string]string{"README.md": "readme"},
+		Rules:      filter.Rules{AllowedExtensions: []string{".md"}, ExcludePatterns: []string{"drafts"}},
+	}}
+	if !reflect.DeepEqual(fake.blobs, want) {
+		t.Errorf("blob requests = %+v, want %+v", fake.blobs, want)
+	}
+}
+
+func TestProcessFilesDedup(t *testing.T) {
+	// Both files hold the same content, so only one of them embeds it
+	files := func() []*models.FileChange {
+		var out []*models.FileChange
+		for _, path := range []string{"a.md", "b.md"} {
+			file := &models.FileChange{Repository: "org/docs", FilePath: path}
+			file.SetContent([]byte("shared"))
+			out = append(out, file)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		batchSize int
+		failFirst bool
+		want      map[string][]string
+	}{
+		{
+			// The earlier file owns the chunk however the workers interleave,
+			// and the other file records no chunk it has no vector for
+			name:      "first file owns shared chunks",
+			batchSize: 2,
+			want:      map[string][]string{"org/docs/a.md": {"org/docs/a.md#0"}, "org/docs/b.md": {}},
+		},
+		{
+			// A failed embedding does not mark the content as seen
+			name:      "failed owner leaves content to later batches",
+			batchSize: 1,
+			failFirst: true,
+			want:      map[string][]string{"org/docs/b.md": {"org/docs/b.md#0"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for run := 0; run < 10; run++ {
+				calls := 0
+				fake := &fakeServices{failEmbed: func([]string) bool {
+					calls++
+					return tt.failFirst && calls == 1
+				}}
+				o := newTestOrchestrator(t, fake)
+				o.config.Processing.MaxWorkers = tt.batchSize
+				o.config.Processing.DedupChunks = true
+
+				chunkIDs := map[string][]string{}
+				embeddings, _, _, err := o.processFiles(context.Background(), files(), "org", nil, chunkIDs)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(embeddings) != 1 {
+					t.Errorf("run %d: %d embeddings, want 1", run, len(embeddings))
+				}
+				if !reflect.DeepEqual(chunkIDs, tt.want) {
+					t.Fatalf("run %d: chunk IDs = %v, want %v", run, chunkIDs, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncProjectBatchesRepoStates(t *testing.T) {
+	// Enough files in the first repository to fill a whole batch, so the
+	// second repository's file and every state go in the next one
+	var big []*models.FileChange
+	for i := 0; i < metadataBatchSize; i++ {
+		big = append(big, &models.FileChange{Repository: "org/big", FilePath: fmt.Sprintf("doc%03d.md", i), ChangeType: "added", CommitSHA: "b2", Content: "doc"})
+	}
+	newFake := func() *fakeServices {
+		return &fakeServices{
+			repos: []*models.Repository{{FullName: "org/big"}, {FullName: "org/small"}, {FullName: "org/quiet"}},
+			changes: map[string][]*models.FileChange{
+				"org/big":   big,
+				"org/small": {{Repository: "org/small", FilePath: "README.md", ChangeType: "modified", CommitSHA: "s2", Content: "readme"}},
+			},
+			states: []*models.RepoSyncState{
+				{Repository: "org/big", LastCommitSHA: "b1", Version: 2},
+				{Repository: "org/small", LastCommitSHA: "s1", Version: 4},
+				{Repository: "org/quiet", LastCommitSHA: "q1", Version: 6},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		failSave func(batch *models.SyncBatch) bool
+		want     map[string]string // repository -> commit saved with its state version
+	}{
+		{name: "all saved", want: map[string]string{"org/big": "b2@2", "org/small": "s2@4", "org/quiet": "q1@6"}},
+		{
+			// The failed repository keeps its old commit so its files are retried
+			name:     "first batch fails",
+			failSave: func(batch *models.SyncBatch) bool { return len(batch.RepoStates) == 0 },
+			want:     map[string]string{"org/small": "s2@4", "org/quiet": "q1@6"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFake()
+			fake.failSave = tt.failSave
+			o := newTestOrchestrator(t, fake)
+			if _, err := o.SyncProject(context.Background(), "docs", true); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(fake.saved) != 2 || len(fake.saved[0].Records) != metadataBatchSize || len(fake.saved[1].Records) != 1 {
+				t.Fatalf("saved %d batches, want %d records then 1", len(fake.saved), metadataBatchSize)
+			}
+			if len(fake.saved[0].RepoStates) != 0 {
+				t.Errorf("first batch has %d repo states, want none", len(fake.saved[0].RepoStates))
+			}
+			got := make(map[string]string)
+			for _, state := range fake.saved[1].RepoStates {
+				got[state.Repository] = fmt.Sprintf("%s@%d", state.LastCommitSHA, state.Version)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("repo states = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncProjectStatesOnlySave(t *testing.T) {
+	// Nothing changed, but the sync still records the commit it read
+	fake := &fakeServices{
+		repos:  []*models.Repository{{FullName: "org/docs"}},
+		states: []*models.RepoSyncState{{Repository: "org/docs", LastCommitSHA: "c1", Version: 1}},
+	}
+	o := newTestOrchestrator(t, fake)
+	if _, err := o.SyncProject(context.Background(), "docs", true); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.saved) != 1 || len(fake.saved[0].Records) != 0 || len(fake.saved[0].RepoStates) != 1 {
+		t.Fatalf("saved = %+v, want one batch with only the repo state", fake.saved)
+	}
+	// Records are listed once at the start, live ones and tombstones
+	if len(fake.deletes) != 0 || fake.lists != 2 {
+		t.Errorf("made %d deletes and %d metadata lists for no changes, want none and 2", len(fake.deletes), fake.lists)
+	}
+}
+
+func TestSyncPullRequest(t *testing.T) {
+	// Local repositories are named after their directory, so the name may
+	// need escaping
+	const repo = "local/release notes&draft"
+	tests := []struct {
+		name         string
+		failDelete   bool
+		wantWarnings int
+	}{
+		{name: "removed and renamed files are unstaged"},
+		{name: "failed deletes are reported", failDelete: true, wantWarnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeServices{changes: map[string][]*models.FileChange{repo + "#7": {
+				{Repository: repo, FilePath: "new.md", ChangeType: "added", CommitSHA: "head", Content: "new"},
+				{Repository: repo, FilePath: "old.md", ChangeType: "removed", CommitSHA: "head"},
+				{Repository: repo, FilePath: "before.md", ChangeType: "removed", CommitSHA: "head"},
+				{Repository: repo, FilePath: "after.md", ChangeType: "renamed", CommitSHA: "head", Content: "after"},
+			}}}
+			if tt.failDelete {
+				fake.failDelete = func(map[string]interface{}) bool { return true }
+			}
+			o := newTestOrchestrator(t, fake)
+
+			result, err := o.SyncPullRequest(context.Background(), "docs", repo, 7, "pr-7")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Success || result.FilesDiscovered != 4 || result.VectorsUpserted != 2 || len(result.Warnings) != tt.wantWarnings {
+				t.Errorf("result = %+v", result)
+			}
+			want := []map[string]interface{}{{
+				"namespace": "pr-7",
+				"filter":    map[string]interface{}{"repository": []interface{}{repo}, "file_path": []interface{}{"old.md", "before.md"}},
+			}}
+			if !reflect.DeepEqual(fake.deletes, want) {
+				t.Errorf("deletes = %v, want %v", fake.deletes, want)
+			}
+		})
+	}
+}
+
+func TestSyncedCommits(t *testing.T) {
+	files := []*models.FileChange{
+		{Repository: "org/a", CommitSHA: "a2"},
+		{Repository: "org/a.wiki", CommitSHA: "w2", Source: "wiki"},
+		{Repository: "org/a", CommitSHA: "2026-01-02T00:00:00Z", Source: "issue"},
+		{Repository: "org/c", CommitSHA: "2026-01-02T00:00:00Z", Source: "release"},
+	}
+	previous := map[string]string{"org/a": "a1", "org/b": "b1", "org/new": ""}
+
+	want := map[string]string{"org/a": "a2", "org/a.wiki": "w2", "org/b": "b1"}
+	if got := syncedCommits(files, previous); !reflect.DeepEqual(got, want) {
+		t.Errorf("syncedCommits() = %v, want %v", got, want)
+	}
+}
+
+func TestListMetadataPages(t *testing.T) {
+	fake := &fakeServices{pageSize: 2}
+	for i := 0; i < 5; i++ {
+		repository := "org/a"
+		if i%2 == 1 {
+			repository = "org/b"
+		}
+		fake.stored = append(fake.stored, &models.SyncMetadata{Repository: repository, FilePath: fmt.Sprintf("%d.md", i), BlobSHA: strconv.Itoa(i)})
+	}
+	o := newTestOrchestrator(t, fake)
+
+	entries, err := o.listMetadata(context.Background(), "docs", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 || fake.lists != 3 {
+		t.Errorf("listed %d entries in %d pages, want 5 in 3", len(entries), fake.lists)
+	}
+
+	// Stored records include tombstones, which have no known blob
+	fake.stored = append(fake.stored, &models.SyncMetadata{Repository: "org/b", FilePath: "5.md", BlobSHA: "5", Status: "deleted", Version: 2})
+	stored, err := o.storedRecords(context.Background(), "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 6 || stored["org/b/5.md"] == nil || stored["org/b/5.md"].Version != 2 {
+		t.Errorf("stored records = %v, want all 6 with the tombstone", stored)
+	}
+	if want := map[string]string{"1.md": "1", "3.md": "3"}; !reflect.DeepEqual(knownBlobs(stored, "org/b"), want) {
+		t.Errorf("knownBlobs() = %v, want %v", knownBlobs(stored, "org/b"), want)
+	}
+}
+
+func TestReportRateUsage(t *testing.T) {
+	reset := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	before := &models.RateUsage{Repositories: map[string]int{"org/a": 10, "org/b": 5}, Total: 15, Remaining: 4985}
+
+	tests := []struct {
+		name   string
+		after  *models.RateUsage
+		budget int
+		before *models.RateUsage
+		want   []string
+	}{
+		{
+			name:   "calls since the snapshot",
+			after:  &models.RateUsage{Repositories: map[string]int{"org/a": 12, "org/b": 5, "org/c": 7}, Total: 24, Remaining: 4976, ResetAt: reset},
+			before: before,
+			want:   []string{"GitHub API calls: 9 total (org/c=7, org/a=2); 4976 remaining until 2026-01-02T03:04:05Z"},
+		},
+		{
+			name:   "over budget",
+			after:  &models.RateUsage{Repositories: map[string]int{"org/a": 12, "org/c": 7}, Total: 19, Remaining: -1},
+			budget: 5,
+			before: before,
+			want: []string{
+				"GitHub API calls: 9 total (org/c=7, org/a=2)",
+				"org/c used 7 GitHub API calls, over the per-repository budget of 5",
+			},
+		},
+		{name: "no calls", after: before, before: before},
+		{name: "no snapshot", after: before},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestOrchestrator(t, &fakeServices{usage: []*models.RateUsage{tt.after}})
+			o.config.GitHub.RepoCallBudget = tt.budget
+			result := &models.SyncResult{}
+			o.reportRateUsage(context.Background(), result, tt.before)
+			if strings.Join(result.Warnings, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("warnings = %q, want %q", result.Warnings, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// tombstoneStatus is the status of the record kept for a removed file until
+// the metadata service purges it
+const tombstoneStatus = "deleted"
+
+// isRemoval reports whether a change deletes its file
+func isRemoval(file *models.FileChange) bool {
+	return file.ChangeType == "removed" || file.ChangeType == "deleted"
+}
+
+// splitRemovals separates files to index from files that were deleted
+func splitRemovals(files []*models.FileChange) (live, removed []*models.FileChange) {
+	for _, file := range files {
+		if isRemoval(file) {
+			removed = append(removed, file)
+		} else {
+			live = append(live, file)
+		}
+	}
+	return live, removed
+}
+
+// storedRecords returns a project's file records, tombstones included, by
+// repository/path. A sync reads them when it starts: their versions are the
+// ones its saves are conditional on, and a file without one is created.
+func (o *Orchestrator) storedRecords(ctx context.Context, projectID string) (map[string]*models.SyncMetadata, error) {
+	stored := make(map[string]*models.SyncMetadata)
+	for _, status := range []string{"", tombstoneStatus} {
+		entries, err := o.listMetadata(ctx, projectID, "", status)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			stored[entry.Repository+"/"+entry.FilePath] = entry
+		}
+	}
+	return stored, nil
+}
+
+// knownBlobs returns the blob SHA recorded for each synced file in a repository
+func knownBlobs(stored map[string]*models.SyncMetadata, repository string) map[string]string {
+	known := make(map[string]string)
+	for _, entry := range stored {
+		if entry.Repository == repository && entry.Status != tombstoneStatus && entry.BlobSHA != "" {
+			known[entry.FilePath] = entry.BlobSHA
+		}
+	}
+	return known
+}
+
+// deleteStaleVectors deletes the stored chunks that re-processed files no
+// longer produce. Chunk IDs follow content, so an edit leaves the old chunks'
+// vectors behind unless they are removed by the IDs stored records list.
+// It returns the chunk IDs to record per repository/path: the new ones, plus
+// any stale ones whose deletion failed so the next sync retries them.
+// Removed files are skipped, since their vectors are dropped by
+// deleteRemovedVectors.
+func (o *Orchestrator) deleteStaleVectors(ctx context.Context, namespace string, files []*models.FileChange, chunkIDs map[string][]string, stored map[string]*models.SyncMetadata) map[string][]string {
+	previous := func(key string) []string {
+		if entry := stored[key]; entry != nil {
+			return entry.ChunkIDs
+		}
+		return nil
+	}
+
+	record := make(map[string][]string, len(files))
+	staleByFile := make(map[string][]string)
+	var stale []string
+	for _, file := range files {
+		if isRemoval(file) {
+			continue
+		}
+		key := file.Repository + "/" + file.FilePath
+		ids, ok := chunkIDs[key]
+		if !ok {
+			// Processing failed, so the stored vectors are still the file's
+			record[key] = previous(key)
+			continue
+		}
+		record[key] = ids
+
+		current := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			current[id] = true
+		}
+		for _, id := range previous(key) {
+			if !current[id] {
+				staleByFile[key] = append(staleByFile[key], id)
+				stale = append(stale, id)
+			}
+		}
+	}
+	if len(stale) == 0 {
+		return record
+	}
+
+	if err := o.deleteVectors(ctx, map[string]interface{}{"ids": stale, "namespace": namespace}); err != nil {
+		logger.Warning("Failed to delete %d stale vectors, retrying next sync: %v", len(stale), err)
+		for key, ids := range staleByFile {
+			record[key] = append(record[key], ids...)
+		}
+		return record
+	}
+
+	logger.Info("Deleted %d stale vectors from %d changed files", len(stale), len(staleByFile))
+	return record
+}
+
+// deleteRemovedVectors deletes every vector of removed files, one request per
+// repository, and returns the repositories whose deletion failed
+func (o *Orchestrator) deleteRemovedVectors(ctx context.Context, namespace string, files []*models.FileChange) map[string]bool {
+	paths := make(map[string][]string)
+	for _, file := range files {
+		paths[file.Repository] = append(paths[file.Repository], file.FilePath)
+	}
+
+	failed := make(map[string]bool)
+	for repo, repoPaths := range paths {
+		filter := models.MetadataFilter{"repository": {repo}, "file_path": repoPaths}
+		if err := o.deleteVectors(ctx, map[string]interface{}{"filter": filter, "namespace": namespace}); err != nil {
+			logger.Warning("Failed to delete vectors of %d removed files in %s: %v", len(repoPaths), repo, err)
+			failed[repo] = true
+			continue
+		}
+		logger.Info("Deleted vectors of %d removed files in %s", len(repoPaths), repo)
+	}
+	return failed
+}
+
+// deleteVectors sends a delete request (ids or filter, plus namespace) to vector storage
+func (o *Orchestrator) deleteVectors(ctx context.Context, request map[string]interface{}) error {
+	reqBody, _ := json.Marshal(request)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/delete", o.vectorStorageURL), bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("vector delete failed: %s", body)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestSplitRemovals(t *testing.T) {
+	files := []*models.FileChange{
+		{FilePath: "a.md", ChangeType: "added"},
+		{FilePath: "b.md", ChangeType: "removed"},
+		{FilePath: "c.md", ChangeType: "renamed"},
+		{FilePath: "d.md", ChangeType: "deleted"},
+	}
+	live, removed := splitRemovals(files)
+	if len(live) != 2 || live[0].FilePath != "a.md" || live[1].FilePath != "c.md" {
+		t.Errorf("live = %v, want a.md and c.md", live)
+	}
+	if len(removed) != 2 || removed[0].FilePath != "b.md" || removed[1].FilePath != "d.md" {
+		t.Errorf("removed = %v, want b.md and d.md", removed)
+	}
+}
+
+func TestDeleteStaleVectors(t *testing.T) {
+	stored := map[string]*models.SyncMetadata{
+		"org/a/edited.md":  {Repository: "org/a", FilePath: "edited.md", ChunkIDs: []string{"kept", "stale"}},
+		"org/a/failed.md":  {Repository: "org/a", FilePath: "failed.md", ChunkIDs: []string{"f1"}},
+		"org/a/removed.md": {Repository: "org/a", FilePath: "removed.md", ChunkIDs: []string{"r1"}},
+		"org/b/edited.md":  {Repository: "org/b", FilePath: "edited.md", ChunkIDs: []string{"b-stale"}},
+	}
+	files := []*models.FileChange{
+		{Repository: "org/a", FilePath: "edited.md", ChangeType: "modified"},
+		{Repository: "org/a", FilePath: "failed.md", ChangeType: "modified"},
+		{Repository: "org/a", FilePath: "removed.md", ChangeType: "removed"},
+		{Repository: "org/a", FilePath: "new.md", ChangeType: "added"},
+		{Repository: "org/b", FilePath: "edited.md", ChangeType: "modified"},
+	}
+	// failed.md was not processed, and org/b/edited.md is now skipped
+	chunkIDs := map[string][]string{
+		"org/a/edited.md": {"kept", "added"},
+		"org/a/new.md":    {"n1"},
+		"org/b/edited.md": {},
+	}
+
+	tests := []struct {
+		name       string
+		failDelete bool
+		want       map[string][]string
+	}{
+		{
+			name: "stale deleted",
+			want: map[string][]string{
+				"org/a/edited.md": {"kept", "added"},
+				"org/a/failed.md": {"f1"},
+				"org/a/new.md":    {"n1"},
+				"org/b/edited.md": {},
+			},
+		},
+		{
+			name:       "stale kept for retry",
+			failDelete: true,
+			want: map[string][]string{
+				"org/a/edited.md": {"kept", "added", "stale"},
+				"org/a/failed.md": {"f1"},
+				"org/a/new.md":    {"n1"},
+				"org/b/edited.md": {"b-stale"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeServices{}
+			if tt.failDelete {
+				fake.failDelete = func(map[string]interface{}) bool { return true }
+			}
+			o := newTestOrchestrator(t, fake)
+
+			record := o.deleteStaleVectors(context.Background(), "org", files, chunkIDs, stored)
+			if !reflect.DeepEqual(record, tt.want) {
+				t.Errorf("recorded IDs = %v, want %v", record, tt.want)
+			}
+
+			// One request for every stale ID; the removed file's are left
+			// to deleteRemovedVectors
+			if len(fake.deletes) != 1 {
+				t.Fatalf("sent %d delete requests, want 1", len(fake.deletes))
+			}
+			ids := fake.deletes[0]["ids"].([]interface{})
+			got := make(map[interface{}]bool)
+			for _, id := range ids {
+				got[id] = true
+			}
+			if len(ids) != 2 || !got["stale"] || !got["b-stale"] {
+				t.Errorf("deleted %v, want stale and b-stale", ids)
+			}
+		})
+	}
+}
+
+func TestDeleteStaleVectorsNothingStale(t *testing.T) {
+	fake := &fakeServices{}
+	o := newTestOrchestrator(t, fake)
+
+	stored := map[string]*models.SyncMetadata{"org/a/a.md": {Repository: "org/a", FilePath: "a.md", ChunkIDs: []string{"a1"}}}
+	files := []*models.FileChange{{Repository: "org/a", FilePath: "a.md", ChangeType: "modified"}}
+	record := o.deleteStaleVectors(context.Background(), "org", files, map[string][]string{"org/a/a.md": {"a1"}}, stored)
+	if len(fake.deletes) != 0 || !reflect.DeepEqual(record["org/a/a.md"], []string{"a1"}) {
+		t.Errorf("sent %d deletes, recorded %v", len(fake.deletes), record)
+	}
+}
+
+func TestDeleteRemovedVectors(t *testing.T) {
+	fake := &fakeServices{failDelete: func(request map[string]interface{}) bool {
+		filter := request["filter"].(map[string]interface{})
+		return filter["repository"].([]interface{})[0] == "org/b"
+	}}
+	o := newTestOrchestrator(t, fake)
+
+	files := []*models.FileChange{
+		{Repository: "org/a", FilePath: "one.md", ChangeType: "removed"},
+		{Repository: "org/b", FilePath: "two.md", ChangeType: "removed"},
+		{Repository: "org/a", FilePath: "three.md", ChangeType: "deleted"},
+	}
+	failed := o.deleteRemovedVectors(context.Background(), "org", files)
+	if !reflect.DeepEqual(failed, map[string]bool{"org/b": true}) {
+		t.Errorf("failed = %v, want org/b", failed)
+	}
+
+	// One filtered request per repository
+	paths := make(map[string][]string)
+	for _, request := range fake.deletes {
+		filter := request["filter"].(map[string]interface{})
+		repository := filter["repository"].([]interface{})[0].(string)
+		for _, path := range filter["file_path"].([]interface{}) {
+			paths[repository] = append(paths[repository], path.(string))
+		}
+		if request["namespace"] != "org" || request["ids"] != nil {
+			t.Errorf("request = %v, want a filter in namespace org", request)
+		}
+	}
+	if keys := sortedKeys(paths); !reflect.DeepEqual(keys, []string{"org/a", "org/b"}) || len(fake.deletes) != 2 {
+		t.Errorf("deleted in %v with %d requests, want org/a and org/b with 2", keys, len(fake.deletes))
+	}
+	if !reflect.DeepEqual(paths["org/a"], []string{"one.md", "three.md"}) || !reflect.DeepEqual(paths["org/b"], []string{"two.md"}) {
+		t.Errorf("deleted paths = %v", paths)
+	}
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
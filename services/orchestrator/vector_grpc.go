@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/vectorpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// An upsert of a large sync's vectors can pass the 4MB gRPC default
+const maxVectorMessageSize = 64 << 20
+
+// newVectorStorageClient connects to the vector storage service's gRPC API
+// at VECTOR_STORAGE_GRPC_ADDR, or returns nil to upsert over HTTP
+func newVectorStorageClient() vectorpb.VectorStorageServiceClient {
+	addr := os.Getenv("VECTOR_STORAGE_GRPC_ADDR")
+	if addr == "" {
+		return nil
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxVectorMessageSize), grpc.MaxCallSendMsgSize(maxVectorMessageSize)),
+	)
+	if err != nil {
+		logger.Warning("Upserting vectors over HTTP, invalid VECTOR_STORAGE_GRPC_ADDR %q: %v", addr, err)
+		return nil
+	}
+	logger.Info("Upserting vectors over gRPC at %s", addr)
+	return vectorpb.NewVectorStorageServiceClient(conn)
+}
+
+// upsertVectorsGRPC upserts embeddings through the vector storage gRPC API
+func (o *Orchestrator) upsertVectorsGRPC(ctx context.Context, embeddings []*models.Embedding) error {
+	req := &vectorpb.UpsertRequest{Embeddings: make([]*vectorpb.Embedding, len(embeddings))}
+	for i, emb := range embeddings {
+		req.Embeddings[i] = &vectorpb.Embedding{
+			Id:         emb.ID,
+			Values:     emb.Vector,
+			Metadata:   emb.Metadata,
+			Repository: emb.Repository,
+			FilePath:   emb.FilePath,
+			Namespace:  emb.Namespace,
+		}
+		if emb.Sparse != nil {
+			req.Embeddings[i].Sparse = &vectorpb.SparseVector{Indices: emb.Sparse.Indices, Values: emb.Sparse.Values}
+		}
+	}
+	_, err := o.vectorClient.Upsert(ctx, req)
+	return err
+}
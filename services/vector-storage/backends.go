@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/interfaces"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// Vector store backends, selected with VECTOR_BACKEND
+const (
+	BackendPinecone = "pinecone"
+	BackendQdrant   = "qdrant"
+	BackendWeaviate = "weaviate"
+	BackendMemory   = "memory"
+)
+
+// vectorStore is a vector database backend of the service
+type vectorStore interface {
+	interfaces.VectorStore
+
+	// ExistingIDs returns the subset of ids already stored in the namespace
+	ExistingIDs(ctx context.Context, ids []string, namespace string) ([]string, error)
+
+	// DeleteByFilter removes the vectors in the namespace whose metadata
+	// match filter
+	DeleteByFilter(ctx context.Context, filter models.MetadataFilter, namespace string) error
+
+	// DeleteNamespace removes every vector in the namespace
+	DeleteNamespace(ctx context.Context, namespace string) error
+
+	// Query returns the vectors most similar to q.Vector, best first, with
+	// their scores
+	Query(ctx context.Context, q VectorQuery) ([]Match, error)
+
+	// Stats counts the stored vectors, in total and per namespace
+	Stats(ctx context.Context) (*models.IndexStats, error)
+
+	// Fetch returns the stored vectors among ids, in the order given,
+	// skipping IDs that are not stored
+	Fetch(ctx context.Context, ids []string, namespace string) ([]StoredVector, error)
+
+	// Dimension is the vector length the store accepts, 0 if unknown
+	Dimension() int
+
+	// Scan returns a page of up to limit stored vectors of the namespace,
+	// starting at cursor ("" for the first page), and the cursor of the next
+	// page, "" after the last
+	Scan(ctx context.Context, namespace, cursor string, limit int) ([]StoredVector, string, error)
+}
+
+// StoredVector is a vector fetched by ID
+type StoredVector struct {
+	ID       string               `json:"id"`
+	Vector   []float32            `json:"vector"`
+	Sparse   *models.SparseVector `json:"sparse,omitempty"`
+	Metadata map[string]string    `json:"metadata,omitempty"`
+}
+
+// maxListedIDs bounds the IDs named in a validation error
+const maxListedIDs = 20
+
+// validateDimensions rejects embeddings whose length is not dimension,
+// naming the offending IDs; a dimension of 0 accepts any length
+func validateDimensions(embeddings []*models.Embedding, dimension int) error {
+	if dimension == 0 {
+		return nil
+	}
+
+	var offending []string
+	lengths := make(map[int]bool)
+	for _, emb := range embeddings {
+		if len(emb.Vector) != dimension {
+			offending = append(offending, emb.ID)
+			lengths[len(emb.Vector)] = true
+		}
+	}
+	if len(offending) == 0 {
+		return nil
+	}
+
+	found := make([]string, 0, len(lengths))
+	for n := range lengths {
+		found = append(found, fmt.Sprintf("%d", n))
+	}
+	sort.Strings(found)
+	listed := offending
+	more := ""
+	if len(listed) > maxListedIDs {
+		listed = listed[:maxListedIDs]
+		more = fmt.Sprintf(" and %d more", len(offending)-maxListedIDs)
+	}
+	return errors.Validation(fmt.Sprintf("%d embeddings have %s dimensions, the index has %d: %s%s",
+		len(offending), strings.Join(found, "/"), dimension, strings.Join(listed, ", "), more))
+}
+
+// inOrder orders fetched vectors as ids, dropping any not asked for
+func inOrder(ids []string, found map[string]StoredVector) []StoredVector {
+	vectors := make([]StoredVector, 0, len(found))
+	for _, id := range ids {
+		if v, ok := found[id]; ok {
+			vectors = append(vectors, v)
+			delete(found, id) // once, even if asked for twice
+		}
+	}
+	return vectors
+}
+
+// indexInitializer is a backend whose index must exist before it is used.
+// Backends that create collections on first write do not implement it.
+type indexInitializer interface {
+	// EnsureIndex creates the index if it is missing, reporting whether it did
+	EnsureIndex(ctx context.Context) (bool, error)
+}
+
+// VectorQuery is a similarity search of one namespace
+type VectorQuery struct {
+	Vector         []float32
+	Sparse         *models.SparseVector // hybrid search, weighted against Vector
+	TopK           int
+	Namespace      string
+	Filter         models.MetadataFilter
+	IncludeVectors bool
+}
+
+// Match is a stored vector found by a query
+type Match struct {
+	ID       string            `json:"id"`
+	Score    float32           `json:"score"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Vector   []float32         `json:"vector,omitempty"`
+}
+
+// matchEmbeddings turns matches into the embeddings QueryVectors returns
+func matchEmbeddings(matches []Match, namespace string) []*models.Embedding {
+	results := make([]*models.Embedding, len(matches))
+	for i, m := range matches {
+		results[i] = &models.Embedding{
+			ID:        m.ID,
+			Vector:    m.Vector,
+			Metadata:  m.Metadata,
+			Namespace: namespace,
+		}
+	}
+	return results
+}
+
+// storeFactory builds a backend from configuration
+type storeFactory func(ctx context.Context, cfg *config.Config) (vectorStore, error)
+
+var storeFactories = make(map[string]storeFactory)
+
+// registerBackend makes a backend selectable by name. Backends register
+// themselves from init in their own file.
+func registerBackend(name string, factory storeFactory) {
+	if _, exists := storeFactories[name]; exists {
+		panic("vector backend registered twice: " + name)
+	}
+	storeFactories[name] = factory
+}
+
+// backendNames lists the registered backends in order
+func backendNames() []string {
+	names := make([]string, 0, len(storeFactories))
+	for name := range storeFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newStore builds the named backend
+func newStore(ctx context.Context, name string, cfg *config.Config) (vectorStore, error) {
+	factory, ok := storeFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown vector backend %q (available: %s)", name, strings.Join(backendNames(), ", "))
+	}
+	store, err := factory(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s backend configuration: %w", name, err)
+	}
+	return store, nil
+}
+
+// nameUUID maps a vector ID to a stable name-based UUID, for stores whose
+// object IDs must be UUIDs
+func nameUUID(id string) string {
+	sum := sha1.Sum([]byte(id))
+	sum[6] = sum[6]&0x0f | 0x50 // version 5
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// metadataChecksum is the vector metadata key holding vectorChecksum
+const metadataChecksum = "checksum"
+
+// vectorChecksum hashes what an upsert would store: the ID, dense and
+// sparse values, and metadata, leaving out indexed_at and the checksum
+// itself so an unchanged vector hashes the same on every sync
+func vectorChecksum(emb *models.Embedding) string {
+	h := sha256.New()
+	buf := make([]byte, 4)
+	writeString := func(s string) {
+		binary.BigEndian.PutUint32(buf, uint32(len(s)))
+		h.Write(buf)
+		h.Write([]byte(s))
+	}
+	writeFloats := func(values []float32) {
+		binary.BigEndian.PutUint32(buf, uint32(len(values)))
+		h.Write(buf)
+		for _, v := range values {
+			binary.BigEndian.PutUint32(buf, math.Float32bits(v))
+			h.Write(buf)
+		}
+	}
+
+	writeString(emb.ID)
+	writeFloats(emb.Vector)
+	if emb.Sparse != nil {
+		binary.BigEndian.PutUint32(buf, uint32(len(emb.Sparse.Indices)))
+		h.Write(buf)
+		for _, i := range emb.Sparse.Indices {
+			binary.BigEndian.PutUint32(buf, i)
+			h.Write(buf)
+		}
+		writeFloats(emb.Sparse.Values)
+	}
+
+	keys := make([]string, 0, len(emb.Metadata))
+	for k := range emb.Metadata {
+		if k != models.MetadataIndexedAt && k != metadataChecksum {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeString(k)
+		writeString(emb.Metadata[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// skipUnchanged stamps each embedding with its checksum and drops those
+// stored in store with the same checksum, unless the stored copy was indexed
+// more than refreshAfter ago: re-upserting it then keeps indexed_at current
+// for age-based cleanup. If the stored vectors cannot be fetched, everything
+// is upserted.
+func (s *VectorStorageService) skipUnchanged(ctx context.Context, store vectorStore, embeddings []*models.Embedding) ([]*models.Embedding, int) {
+	byNamespace := make(map[string][]string)
+	for _, emb := range embeddings {
+		if emb.Metadata == nil {
+			emb.Metadata = make(map[string]string)
+		}
+		emb.Metadata[metadataChecksum] = vectorChecksum(emb)
+		byNamespace[emb.Namespace] = append(byNamespace[emb.Namespace], emb.ID)
+	}
+	if !s.dedupUpserts {
+		return embeddings, 0
+	}
+
+	current := make(map[string]bool) // namespace + "/" + ID, stored unchanged and recently
+	cutoff := time.Now().Add(-s.refreshAfter)
+	for namespace, ids := range byNamespace {
+		stored, err := store.Fetch(ctx, ids, namespace)
+		if err != nil {
+			logger.Warning("Upserting every vector, failed to fetch stored checksums: %v", err)
+			return embeddings, 0
+		}
+		for _, v := range stored {
+			indexedAt, err := time.Parse(time.RFC3339, v.Metadata[models.MetadataIndexedAt])
+			if err != nil || indexedAt.Before(cutoff) {
+				continue
+			}
+			current[namespace+"/"+v.ID+"/"+v.Metadata[metadataChecksum]] = true
+		}
+	}
+
+	changed := embeddings[:0:0]
+	for _, emb := range embeddings {
+		if !current[emb.Namespace+"/"+emb.ID+"/"+emb.Metadata[metadataChecksum]] {
+			changed = append(changed, emb)
+		}
+	}
+	return changed, len(embeddings) - len(changed)
+}
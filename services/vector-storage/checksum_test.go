@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestVectorChecksum(t *testing.T) {
+	base := func() *models.Embedding {
+		return &models.Embedding{ID: "a", Vector: []float32{1, 2}, Metadata: map[string]string{"repository": "org/x", models.MetadataIndexedAt: "2024-01-01T00:00:00Z"}}
+	}
+	sum := vectorChecksum(base())
+
+	tests := []struct {
+		name     string
+		change   func(*models.Embedding)
+		wantSame bool
+	}{
+		{name: "indexed_at is ignored", change: func(e *models.Embedding) { e.Metadata[models.MetadataIndexedAt] = "2024-06-01T00:00:00Z" }, wantSame: true},
+		{name: "stored checksum is ignored", change: func(e *models.Embedding) { e.Metadata[metadataChecksum] = "old" }, wantSame: true},
+		{name: "namespace is not part of the vector", change: func(e *models.Embedding) { e.Namespace = "other" }, wantSame: true},
+		{name: "vector values", change: func(e *models.Embedding) { e.Vector[1] = 3 }},
+		{name: "metadata value", change: func(e *models.Embedding) { e.Metadata["repository"] = "org/y" }},
+		{name: "metadata key", change: func(e *models.Embedding) { e.Metadata["branch"] = "main" }},
+		{name: "sparse values", change: func(e *models.Embedding) { e.Sparse = &models.SparseVector{Indices: []uint32{1}, Values: []float32{1}} }},
+		{name: "ID", change: func(e *models.Embedding) { e.ID = "b" }},
+		{name: "key and value boundary", change: func(e *models.Embedding) {
+			delete(e.Metadata, "repository")
+			e.Metadata["repositoryorg"] = "/x"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emb := base()
+			tt.change(emb)
+			if got := vectorChecksum(emb); (got == sum) != tt.wantSame {
+				t.Errorf("checksum same = %v, want %v", got == sum, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestSkipUnchanged(t *testing.T) {
+	now := time.Now().UTC()
+	incoming := func() []*models.Embedding {
+		stamp := map[string]string{models.MetadataIndexedAt: now.Format(time.RFC3339)}
+		return []*models.Embedding{
+			{ID: "same", Vector: []float32{1}, Namespace: "acme", Metadata: copyMap(stamp)},
+			{ID: "edited", Vector: []float32{2}, Namespace: "acme", Metadata: copyMap(stamp)},
+			{ID: "due", Vector: []float32{3}, Namespace: "acme", Metadata: copyMap(stamp)},
+			{ID: "new", Vector: []float32{4}, Namespace: "acme", Metadata: copyMap(stamp)},
+			{ID: "same", Vector: []float32{1}, Namespace: "other", Metadata: copyMap(stamp)},
+		}
+	}
+
+	store := NewMemoryStore(0)
+	stored := incoming()[:3]
+	stored[0].Metadata[models.MetadataIndexedAt] = now.AddDate(0, 0, -2).Format(time.RFC3339)
+	stored[1].Vector = []float32{9}
+	stored[2].Metadata[models.MetadataIndexedAt] = now.AddDate(0, 0, -8).Format(time.RFC3339)
+	for _, emb := range stored {
+		emb.Metadata[metadataChecksum] = vectorChecksum(emb)
+	}
+	_ = store.UpsertVectors(context.Background(), stored)
+
+	tests := []struct {
+		name        string
+		dedup       bool
+		wantIDs     []string
+		wantSkipped int
+	}{
+		{name: "skips vectors stored unchanged and recently", dedup: true, wantIDs: []string{"edited", "due", "new", "same"}, wantSkipped: 1},
+		{name: "disabled upserts everything", wantIDs: []string{"same", "edited", "due", "new", "same"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &VectorStorageService{store: store, dedupUpserts: tt.dedup, refreshAfter: 7 * 24 * time.Hour}
+			got, skipped := s.skipUnchanged(context.Background(), store, incoming())
+
+			if skipped != tt.wantSkipped {
+				t.Errorf("skipped = %d, want %d", skipped, tt.wantSkipped)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %d embeddings, want %v", len(got), tt.wantIDs)
+			}
+			for i, emb := range got {
+				if emb.ID != tt.wantIDs[i] {
+					t.Errorf("embedding %d = %s, want %s", i, emb.ID, tt.wantIDs[i])
+				}
+				if emb.Metadata[metadataChecksum] != vectorChecksum(emb) {
+					t.Errorf("embedding %s is not stamped with its checksum", emb.ID)
+				}
+			}
+		})
+	}
+}
+
+func copyMap(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
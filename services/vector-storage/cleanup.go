@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// CleanupRequest deletes the vectors of a namespace last upserted more than
+// OlderThanDays ago
+type CleanupRequest struct {
+	Namespace     string `json:"namespace"`
+	OlderThanDays int    `json:"older_than_days"`
+	DryRun        bool   `json:"dry_run,omitempty"` // count without deleting
+}
+
+// CleanupResult counts the vectors a cleanup found
+type CleanupResult struct {
+	Scanned int       `json:"scanned"`
+	Stale   int       `json:"stale"`   // indexed before the cutoff
+	Undated int       `json:"undated"` // without indexed_at, kept
+	Deleted int       `json:"deleted"`
+	Cutoff  time.Time `json:"cutoff"`
+	DryRun  bool      `json:"dry_run"`
+}
+
+// cleanupPageSize is the vectors read per page while looking for stale ones
+const cleanupPageSize = 100
+
+// cleanupStale scans the namespace for vectors indexed before cutoff and,
+// unless dryRun, deletes them once the scan is done so paging is not
+// disturbed. Vectors without a readable indexed_at are kept.
+func cleanupStale(ctx context.Context, store vectorStore, namespace string, cutoff time.Time, dryRun bool) (*CleanupResult, error) {
+	result := &CleanupResult{Cutoff: cutoff, DryRun: dryRun}
+	var stale []string
+	cursor := ""
+	for {
+		page, next, err := store.Scan(ctx, namespace, cursor, cleanupPageSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to scan namespace '%s' after %d vectors: %w", namespace, result.Scanned, err)
+		}
+		result.Scanned += len(page)
+
+		for _, v := range page {
+			indexedAt, err := time.Parse(time.RFC3339, v.Metadata[models.MetadataIndexedAt])
+			if err != nil {
+				result.Undated++
+				continue
+			}
+			if indexedAt.Before(cutoff) {
+				stale = append(stale, v.ID)
+			}
+		}
+
+		if next == "" || next == cursor {
+			break
+		}
+		cursor = next
+	}
+	result.Stale = len(stale)
+
+	if dryRun || len(stale) == 0 {
+		return result, nil
+	}
+	if err := store.DeleteVectors(ctx, stale, namespace); err != nil {
+		return result, fmt.Errorf("failed to delete %d stale vectors: %w", len(stale), err)
+	}
+	result.Deleted = len(stale)
+	return result, nil
+}
+
+// handleCleanup deletes vectors of a namespace that no sync has refreshed
+// within older_than_days, catching content whose source has vanished
+func (s *VectorStorageService) handleCleanup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CleanupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.OlderThanDays < 1 {
+		http.Error(w, "older_than_days must be at least 1", http.StatusBadRequest)
+		return
+	}
+
+	cutoff := time.Now().UTC().AddDate(0, 0, -req.OlderThanDays)
+	result, err := cleanupStale(r.Context(), s.store, req.Namespace, cutoff, req.DryRun)
+	if err != nil {
+		logger.Error("Cleanup of namespace '%s' failed: %v", req.Namespace, err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	logger.Info("Cleanup of namespace '%s': %d of %d vectors indexed before %s, %d deleted",
+		req.Namespace, result.Stale, result.Scanned, cutoff.Format(time.RFC3339), result.Deleted)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestCleanupStale(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	cutoff := now.AddDate(0, 0, -30)
+	stamped := func(id string, at time.Time) *models.Embedding {
+		return &models.Embedding{ID: id, Vector: []float32{1}, Namespace: "acme",
+			Metadata: map[string]string{models.MetadataIndexedAt: at.Format(time.RFC3339)}}
+	}
+
+	tests := []struct {
+		name       string
+		dryRun     bool
+		want       CleanupResult
+		wantRemain []string
+	}{
+		{
+			name:       "deletes vectors indexed before the cutoff",
+			want:       CleanupResult{Scanned: 5, Stale: 2, Undated: 2, Deleted: 2},
+			wantRemain: []string{"fresh", "undated", "unreadable"},
+		},
+		{
+			name:       "dry run only counts",
+			dryRun:     true,
+			want:       CleanupResult{Scanned: 5, Stale: 2, Undated: 2, DryRun: true},
+			wantRemain: []string{"fresh", "old", "older", "undated", "unreadable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(0)
+			_ = store.UpsertVectors(context.Background(), []*models.Embedding{
+				stamped("fresh", now.AddDate(0, 0, -1)),
+				stamped("old", now.AddDate(0, 0, -31)),
+				stamped("older", now.AddDate(-1, 0, 0)),
+				{ID: "undated", Vector: []float32{1}, Namespace: "acme", Metadata: map[string]string{}},
+				{ID: "unreadable", Vector: []float32{1}, Namespace: "acme", Metadata: map[string]string{models.MetadataIndexedAt: "yesterday"}},
+			})
+
+			result, err := cleanupStale(context.Background(), store, "acme", cutoff, tt.dryRun)
+			if err != nil {
+				t.Fatalf("cleanupStale() error: %v", err)
+			}
+			tt.want.Cutoff = cutoff
+			if *result != tt.want {
+				t.Errorf("result = %+v, want %+v", *result, tt.want)
+			}
+
+			if len(store.vectors["acme"]) != len(tt.wantRemain) {
+				t.Errorf("%d vectors remain, want %v", len(store.vectors["acme"]), tt.wantRemain)
+			}
+			for _, id := range tt.wantRemain {
+				if _, ok := store.vectors["acme"][id]; !ok {
+					t.Errorf("vector %s was deleted", id)
+				}
+			}
+		})
+	}
+}
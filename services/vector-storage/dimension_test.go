@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestValidateDimensions(t *testing.T) {
+	emb := func(id string, n int) *models.Embedding {
+		return &models.Embedding{ID: id, Vector: make([]float32, n)}
+	}
+	many := make([]*models.Embedding, 0, 25)
+	for i := 0; i < 25; i++ {
+		many = append(many, emb(fmt.Sprintf("v%02d", i), 2))
+	}
+
+	tests := []struct {
+		name       string
+		embeddings []*models.Embedding
+		dimension  int
+		wantErr    []string // substrings of the error, nil for none
+		notInErr   string
+	}{
+		{name: "matching lengths", embeddings: []*models.Embedding{emb("a", 3), emb("b", 3)}, dimension: 3},
+		{name: "unknown dimension accepts any length", embeddings: []*models.Embedding{emb("a", 3), emb("b", 5)}},
+		{
+			name:       "mismatches are listed",
+			embeddings: []*models.Embedding{emb("a", 3), emb("b", 2), emb("c", 4)},
+			dimension:  3,
+			wantErr:    []string{"[VALIDATION_ERROR]", "2 embeddings have 2/4 dimensions, the index has 3", "b, c"},
+			notInErr:   "a,",
+		},
+		{
+			name:       "long lists are capped",
+			embeddings: many,
+			dimension:  3,
+			wantErr:    []string{"25 embeddings", "v19", "and 5 more"},
+			notInErr:   "v20",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDimensions(tt.embeddings, tt.dimension)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+			if tt.notInErr != "" && strings.Contains(err.Error(), tt.notInErr) {
+				t.Errorf("error %q contains %q", err, tt.notInErr)
+			}
+		})
+	}
+}
+
+func TestHandleUpsertDimension(t *testing.T) {
+	store := NewMemoryStore(0)
+	store.dimension = 2
+	s := &VectorStorageService{store: store}
+
+	body := `{"embeddings":[{"id":"ok","vector":[1,2]},{"id":"short","vector":[1]}]}`
+	rec := httptest.NewRecorder()
+	s.handleUpsert(rec, httptest.NewRequest(http.MethodPost, "/upsert", bytes.NewBufferString(body)))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "short") {
+		t.Errorf("body %q does not name the offending ID", rec.Body.String())
+	}
+	if len(store.vectors) != 0 {
+		t.Errorf("stored %d namespaces, want none", len(store.vectors))
+	}
+}
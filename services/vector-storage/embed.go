@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+)
+
+// textEmbedder embeds texts with the embedding service, so callers can
+// search by text and migrations can re-embed stored chunks
+type textEmbedder struct {
+	url        string
+	httpClient *http.Client
+}
+
+func newTextEmbedder(url string) *textEmbedder {
+	return &textEmbedder{url: url, httpClient: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// embed returns the query embedding of text and the model it comes from
+func (e *textEmbedder) embed(ctx context.Context, text string) ([]float32, string, error) {
+	embeddings, model, err := e.embedTexts(ctx, []string{text}, "query")
+	if err != nil {
+		return nil, "", err
+	}
+	return embeddings[0], model, nil
+}
+
+// embedTexts returns one embedding per text, of input type "query" or
+// "document", and the model they come from
+func (e *textEmbedder) embedTexts(ctx context.Context, texts []string, inputType string) ([][]float32, string, error) {
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"texts":      texts,
+		"input_type": inputType,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url+"/embed", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, "", errors.Internal("failed to build embedding request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, "", errors.Network("embedding service request failed", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, "", errors.Validation(fmt.Sprintf("%s text rejected by the embedding service: %s", inputType, bytes.TrimSpace(body)))
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, "", errors.External("embedding service", "failed to embed texts", fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(body)))
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Model      string      `json:"model"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, "", errors.External("embedding service", "failed to decode response", err)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, "", errors.External("embedding service", fmt.Sprintf("returned %d embeddings for %d texts", len(result.Embeddings), len(texts)), nil)
+	}
+	return result.Embeddings, result.Model, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// exportPageSize is the vectors read from the store per page of an export
+const exportPageSize = 100
+
+// unsafeFileChars are replaced in the suggested export file name
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// handleExport streams a namespace as JSON Lines, one vector per line with
+// its id, vector, sparse values, and metadata, for backups and offline
+// analysis. A failure after the first line aborts the response, so a
+// truncated file is never mistaken for a complete one.
+func (s *VectorStorageService) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	page, cursor, err := s.store.Scan(r.Context(), namespace, "", exportPageSize)
+	if err != nil {
+		logger.Error("Failed to export namespace '%s': %v", namespace, err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	name := unsafeFileChars.ReplaceAllString(namespace, "_")
+	if name == "" {
+		name = "default"
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.jsonl"`, s.backend, name))
+
+	encoder := json.NewEncoder(w)
+	exported := 0
+	for {
+		for _, v := range page {
+			if err := encoder.Encode(v); err != nil {
+				logger.Warning("Export of namespace '%s' stopped after %d vectors: %v", namespace, exported, err)
+				return
+			}
+			exported++
+		}
+		if cursor == "" {
+			break
+		}
+
+		next := cursor
+		page, cursor, err = s.store.Scan(r.Context(), namespace, next, exportPageSize)
+		if err != nil {
+			logger.Error("Export of namespace '%s' failed after %d vectors: %v", namespace, exported, err)
+			panic(http.ErrAbortHandler)
+		}
+		if cursor == next {
+			cursor = ""
+		}
+	}
+
+	logger.Info("Exported %d vectors from namespace '%s'", exported, namespace)
+}
+
+const (
+	defaultImportBatch = 100
+	maxImportBatch     = 1000
+	// maxImportLine bounds one vector's line, room for large vectors and metadata
+	maxImportLine = 16 << 20
+)
+
+// ImportProgress is a line of an import's response: one per upserted batch,
+// then a final one with the status
+type ImportProgress struct {
+	Imported  int    `json:"imported"`
+	Unchanged int    `json:"unchanged,omitempty"` // of Imported, already stored with the same checksum
+	Status    string `json:"status,omitempty"`    // success or failed, on the last line
+	Error     string `json:"error,omitempty"`
+	Line      int    `json:"line,omitempty"` // line of the dump that failed
+}
+
+// handleImport loads a dump made by /export into ?namespace=, upserting
+// batch_size vectors at a time and streaming JSON Lines progress. Batches
+// before a failure stay imported; IDs are kept, so importing again resumes.
+func (s *VectorStorageService) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	batchSize := defaultImportBatch
+	if v := r.URL.Query().Get("batch_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxImportBatch {
+			http.Error(w, fmt.Sprintf("batch_size must be between 1 and %d", maxImportBatch), http.StatusBadRequest)
+			return
+		}
+		batchSize = n
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	report := func(p ImportProgress) {
+		_ = encoder.Encode(p)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	fail := func(imported, line int, err error) {
+		logger.Error("Import into namespace '%s' failed at line %d after %d vectors: %v", namespace, line, imported, err)
+		report(ImportProgress{Imported: imported, Status: "failed", Error: err.Error(), Line: line})
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+	batch := make([]*models.Embedding, 0, batchSize)
+	imported, unchanged, line := 0, 0, 0
+	// Batches go through upsert like any other write, so they are
+	// checksummed and, with DEDUP_UPSERTS, skipped when already stored
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		upserted, skipped, err := s.upsert(r.Context(), batch)
+		if err != nil {
+			return err
+		}
+		imported += upserted + skipped
+		unchanged += skipped
+		batch = batch[:0]
+		report(ImportProgress{Imported: imported, Unchanged: unchanged})
+		return nil
+	}
+
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var v StoredVector
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			fail(imported, line, fmt.Errorf("invalid vector: %w", err))
+			return
+		}
+		if v.ID == "" || len(v.Vector) == 0 {
+			fail(imported, line, fmt.Errorf("vector needs an id and values"))
+			return
+		}
+		emb := &models.Embedding{
+			ID:        v.ID,
+			Vector:    v.Vector,
+			Sparse:    v.Sparse,
+			Metadata:  v.Metadata,
+			Namespace: namespace,
+		}
+		if err := validateDimensions([]*models.Embedding{emb}, s.store.Dimension()); err != nil {
+			fail(imported, line, err)
+			return
+		}
+
+		batch = append(batch, emb)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				fail(imported, line, err)
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fail(imported, line+1, fmt.Errorf("failed to read dump: %w", err))
+		return
+	}
+	if err := flush(); err != nil {
+		fail(imported, line, err)
+		return
+	}
+
+	logger.Info("Imported %d vectors into namespace '%s'", imported, namespace)
+	report(ImportProgress{Imported: imported, Unchanged: unchanged, Status: "success"})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestHandleExport(t *testing.T) {
+	store := NewMemoryStore(0)
+	var embeddings []*models.Embedding
+	for _, id := range []string{"a", "b", "c"} {
+		embeddings = append(embeddings, &models.Embedding{ID: id, Vector: []float32{1, 2}, Namespace: "org/acme", Metadata: map[string]string{"file_path": id + ".md"}})
+	}
+	embeddings[1].Sparse = &models.SparseVector{Indices: []uint32{7}, Values: []float32{0.5}}
+	_ = store.UpsertVectors(context.Background(), embeddings)
+
+	tests := []struct {
+		name      string
+		namespace string
+		wantIDs   []string
+		wantFile  string
+	}{
+		{name: "every vector with its metadata", namespace: "org/acme", wantIDs: []string{"a", "b", "c"}, wantFile: "test-org_acme.jsonl"},
+		{name: "empty namespace", namespace: "", wantFile: "test-default.jsonl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewVectorStorageService(store, config.VectorStoreConfig{Backend: "test"}, "")
+			rec := httptest.NewRecorder()
+			service.handleExport(rec, httptest.NewRequest(http.MethodGet, "/export?namespace="+tt.namespace, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Disposition"); !strings.Contains(got, tt.wantFile) {
+				t.Errorf("Content-Disposition = %q, want file %s", got, tt.wantFile)
+			}
+
+			var ids []string
+			scanner := bufio.NewScanner(rec.Body)
+			for scanner.Scan() {
+				var v StoredVector
+				if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+					t.Fatalf("line %q is not a vector: %v", scanner.Text(), err)
+				}
+				if v.Metadata["file_path"] != v.ID+".md" || len(v.Vector) != 2 {
+					t.Errorf("vector %+v lost its values or metadata", v)
+				}
+				if (v.Sparse != nil) != (v.ID == "b") {
+					t.Errorf("vector %s sparse = %+v", v.ID, v.Sparse)
+				}
+				ids = append(ids, v.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("exported %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestHandleImport(t *testing.T) {
+	dump := `{"id":"a","vector":[1,0],"metadata":{"file_path":"a.md","checksum":"stale"}}
+{"id":"b","vector":[0,1],"sparse":{"indices":[3],"values":[1]}}
+
+{"id":"c","vector":[1,1]}
+`
+
+	tests := []struct {
+		name         string
+		body         string
+		query        string
+		wantStatus   string
+		wantProgress []int
+		wantStored   int
+		wantLine     int
+	}{
+		{name: "batches with progress", body: dump, query: "namespace=restored&batch_size=2", wantStatus: "success", wantProgress: []int{2, 3, 3}, wantStored: 3},
+		{name: "default batch size", body: dump, query: "namespace=restored", wantStatus: "success", wantProgress: []int{3, 3}, wantStored: 3},
+		{name: "invalid line keeps earlier batches", body: dump + "not json\n", query: "namespace=restored&batch_size=2", wantStatus: "failed", wantProgress: []int{2, 2}, wantStored: 2, wantLine: 5},
+		{name: "vector without values", body: `{"id":"a"}`, query: "namespace=restored", wantStatus: "failed", wantProgress: []int{0}, wantLine: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore(0)
+			service := NewVectorStorageService(store, config.VectorStoreConfig{Backend: "test"}, "")
+			rec := httptest.NewRecorder()
+			service.handleImport(rec, httptest.NewRequest(http.MethodPost, "/import?"+tt.query, strings.NewReader(tt.body)))
+
+			var progress []ImportProgress
+			scanner := bufio.NewScanner(rec.Body)
+			for scanner.Scan() {
+				var p ImportProgress
+				if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+					t.Fatalf("progress line %q: %v", scanner.Text(), err)
+				}
+				progress = append(progress, p)
+			}
+			if len(progress) != len(tt.wantProgress) {
+				t.Fatalf("progress = %+v, want %d lines", progress, len(tt.wantProgress))
+			}
+			for i, want := range tt.wantProgress {
+				if progress[i].Imported != want {
+					t.Errorf("progress line %d imported = %d, want %d", i, progress[i].Imported, want)
+				}
+			}
+			last := progress[len(progress)-1]
+			if last.Status != tt.wantStatus || last.Line != tt.wantLine {
+				t.Errorf("last line = %+v, want status %s at line %d", last, tt.wantStatus, tt.wantLine)
+			}
+
+			if got := len(store.vectors["restored"]); got != tt.wantStored {
+				t.Errorf("stored %d vectors, want %d", got, tt.wantStored)
+			}
+			if tt.wantStored > 1 && store.vectors["restored"]["b"].Sparse == nil {
+				t.Error("sparse values were not imported")
+			}
+			for id, v := range store.vectors["restored"] {
+				emb := &models.Embedding{ID: id, Vector: v.Vector, Sparse: v.Sparse, Metadata: v.Metadata}
+				if v.Metadata[metadataChecksum] != vectorChecksum(emb) {
+					t.Errorf("vector %s checksum = %q, not of what it holds", id, v.Metadata[metadataChecksum])
+				}
+			}
+		})
+	}
+
+	t.Run("importing again skips unchanged vectors", func(t *testing.T) {
+		store := NewMemoryStore(0)
+		service := NewVectorStorageService(store, config.VectorStoreConfig{Backend: "test", DedupUpserts: true, RefreshDays: 7}, "")
+		// indexed_at decides whether a stored copy is recent enough to skip
+		recent := strings.ReplaceAll(dump, `"file_path":"a.md"`, `"file_path":"a.md","indexed_at":"`+time.Now().UTC().Format(time.RFC3339)+`"`)
+		var last ImportProgress
+		for i := 0; i < 2; i++ {
+			rec := httptest.NewRecorder()
+			service.handleImport(rec, httptest.NewRequest(http.MethodPost, "/import?namespace=restored", strings.NewReader(recent)))
+			lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+			if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if last.Status != "success" || last.Imported != 3 || last.Unchanged != 1 {
+			t.Errorf("second import = %+v, want 3 imported with 1 unchanged", last)
+		}
+	})
+
+	t.Run("wrong dimension names the line", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		NewVectorStorageService(NewMemoryStore(2), config.VectorStoreConfig{Backend: "test"}, "").handleImport(rec, httptest.NewRequest(http.MethodPost, "/import?namespace=restored", strings.NewReader(dump+`{"id":"d","vector":[1,2,3]}`+"\n")))
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		var last ImportProgress
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+			t.Fatal(err)
+		}
+		if last.Status != "failed" || last.Line != 5 {
+			t.Errorf("last line = %+v, want failed at line 5", last)
+		}
+	})
+
+	t.Run("invalid batch size", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		NewVectorStorageService(NewMemoryStore(0), config.VectorStoreConfig{Backend: "test"}, "").handleImport(rec, httptest.NewRequest(http.MethodPost, "/import?batch_size=0", strings.NewReader(dump)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/vectorpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A batch of thousands of large vectors passes the 4MB gRPC default
+const maxGRPCMessageSize = 64 << 20
+
+// grpcServer serves the vectorpb API, the same operations as /upsert,
+// /query, and /delete without the cost of encoding every float as JSON
+type grpcServer struct {
+	vectorpb.UnimplementedVectorStorageServiceServer
+	service *VectorStorageService
+}
+
+// serveGRPC listens on port and serves the gRPC API until Stop is called on
+// the returned server
+func serveGRPC(service *VectorStorageService, port int) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, errors.Network(fmt.Sprintf("failed to listen on gRPC port %d", port), err)
+	}
+	server := newGRPCServer(service)
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			logger.Error("gRPC server error: %v", err)
+		}
+	}()
+	return server, nil
+}
+
+func newGRPCServer(service *VectorStorageService) *grpc.Server {
+	server := grpc.NewServer(grpc.MaxRecvMsgSize(maxGRPCMessageSize), grpc.MaxSendMsgSize(maxGRPCMessageSize))
+	vectorpb.RegisterVectorStorageServiceServer(server, &grpcServer{service: service})
+	return server
+}
+
+// Upsert stores the embeddings, skipping unchanged ones
+func (g *grpcServer) Upsert(ctx context.Context, req *vectorpb.UpsertRequest) (*vectorpb.UpsertResponse, error) {
+	embeddings := make([]*models.Embedding, len(req.Embeddings))
+	for i, emb := range req.Embeddings {
+		embeddings[i] = &models.Embedding{
+			ID:         emb.Id,
+			Vector:     emb.Values,
+			Metadata:   emb.Metadata,
+			Repository: emb.Repository,
+			FilePath:   emb.FilePath,
+			Namespace:  emb.Namespace,
+			Sparse:     sparseFromProto(emb.Sparse),
+		}
+	}
+
+	upserted, skipped, err := g.service.upsert(ctx, embeddings)
+	if err != nil {
+		logger.Error("Failed to upsert vectors: %v", err)
+		return nil, grpcError(err)
+	}
+	return &vectorpb.UpsertResponse{Upserted: int32(upserted), Skipped: int32(skipped)}, nil
+}
+
+// Query returns the stored vectors most similar to a vector or text
+func (g *grpcServer) Query(ctx context.Context, req *vectorpb.QueryRequest) (*vectorpb.QueryResponse, error) {
+	resp, err := g.service.query(ctx, QueryRequest{
+		Vector:         req.Vector,
+		Text:           req.Text,
+		TopK:           int(req.TopK),
+		Namespace:      req.Namespace,
+		Filter:         filterFromProto(req.Filter),
+		IncludeVectors: req.IncludeVectors,
+		Mode:           req.Mode,
+		Sparse:         sparseFromProto(req.Sparse),
+		Alpha:          req.Alpha,
+		MMR:            req.Mmr,
+		MMRLambda:      req.MmrLambda,
+		MinScore:       req.MinScore,
+	})
+	if err != nil {
+		logger.Error("Failed to query vectors: %v", err)
+		return nil, grpcError(err)
+	}
+
+	out := &vectorpb.QueryResponse{
+		Matches: make([]*vectorpb.Match, len(resp.Matches)),
+		Model:   resp.Model,
+		Mode:    resp.Mode,
+		Alpha:   resp.Alpha,
+	}
+	for i, m := range resp.Matches {
+		out.Matches[i] = &vectorpb.Match{Id: m.ID, Score: m.Score, Metadata: m.Metadata, Values: m.Vector}
+	}
+	return out, nil
+}
+
+// Delete removes vectors by ID, by filter, or the whole namespace
+func (g *grpcServer) Delete(ctx context.Context, req *vectorpb.DeleteRequest) (*vectorpb.DeleteResponse, error) {
+	err := g.service.deleteVectors(ctx, DeleteRequest{
+		IDs:       req.Ids,
+		Filter:    filterFromProto(req.Filter),
+		Namespace: req.Namespace,
+		DeleteAll: req.DeleteAll,
+	})
+	if err != nil {
+		logger.Error("Failed to delete vectors: %v", err)
+		return nil, grpcError(err)
+	}
+	return &vectorpb.DeleteResponse{Deleted: int32(len(req.Ids))}, nil
+}
+
+// grpcError maps a vector storage failure to a gRPC status
+func grpcError(err error) error {
+	if stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) {
+		switch appErr.Type {
+		case errors.ErrTypeValidation:
+			return status.Error(codes.InvalidArgument, err.Error())
+		case errors.ErrTypeNotFound:
+			return status.Error(codes.NotFound, err.Error())
+		case errors.ErrTypeNetwork, errors.ErrTypeExternal:
+			return status.Error(codes.Unavailable, err.Error())
+		}
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func sparseFromProto(sv *vectorpb.SparseVector) *models.SparseVector {
+	if sv == nil {
+		return nil
+	}
+	return &models.SparseVector{Indices: sv.Indices, Values: sv.Values}
+}
+
+func filterFromProto(filter map[string]*vectorpb.FilterValues) models.MetadataFilter {
+	if len(filter) == 0 {
+		return nil
+	}
+	out := make(models.MetadataFilter, len(filter))
+	for key, values := range filter {
+		out[key] = values.GetValues()
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/vectorpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCServer(t *testing.T) {
+	store := NewMemoryStore(0)
+	store.dimension = 2
+
+	lis := bufconn.Listen(1 << 20)
+	server := newGRPCServer(&VectorStorageService{store: store})
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	client := vectorpb.NewVectorStorageServiceClient(conn)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantCode codes.Code
+		wantIDs  []string // stored in namespace acme afterwards
+	}{
+		{
+			name: "upsert stores vectors",
+			call: func() error {
+				resp, err := client.Upsert(ctx, &vectorpb.UpsertRequest{Embeddings: []*vectorpb.Embedding{
+					{Id: "a", Values: []float32{1, 0}, Namespace: "acme", Metadata: map[string]string{"file_path": "a.md"}},
+					{Id: "b", Values: []float32{0, 1}, Namespace: "acme"},
+				}})
+				if err == nil && resp.Upserted != 2 {
+					t.Errorf("upserted = %d, want 2", resp.Upserted)
+				}
+				return err
+			},
+			wantIDs: []string{"a", "b"},
+		},
+		{
+			name: "wrong dimension is invalid",
+			call: func() error {
+				_, err := client.Upsert(ctx, &vectorpb.UpsertRequest{Embeddings: []*vectorpb.Embedding{
+					{Id: "c", Values: []float32{1, 0, 0}, Namespace: "acme"},
+				}})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+			wantIDs:  []string{"a", "b"},
+		},
+		{
+			name: "query needs a vector or text",
+			call: func() error {
+				_, err := client.Query(ctx, &vectorpb.QueryRequest{Namespace: "acme"})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+			wantIDs:  []string{"a", "b"},
+		},
+		{
+			name: "delete needs one selector",
+			call: func() error {
+				_, err := client.Delete(ctx, &vectorpb.DeleteRequest{Ids: []string{"a"}, DeleteAll: true, Namespace: "acme"})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+			wantIDs:  []string{"a", "b"},
+		},
+		{
+			name: "delete all needs a namespace",
+			call: func() error {
+				_, err := client.Delete(ctx, &vectorpb.DeleteRequest{DeleteAll: true})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+			wantIDs:  []string{"a", "b"},
+		},
+		{
+			name: "delete by ID",
+			call: func() error {
+				resp, err := client.Delete(ctx, &vectorpb.DeleteRequest{Ids: []string{"a"}, Namespace: "acme"})
+				if err == nil && resp.Deleted != 1 {
+					t.Errorf("deleted = %d, want 1", resp.Deleted)
+				}
+				return err
+			},
+			wantIDs: []string{"b"},
+		},
+	}
+
+	// The cases run in order against one store
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v (%v), want %v", code, err, tt.wantCode)
+			}
+			if got := len(store.vectors["acme"]); got != len(tt.wantIDs) {
+				t.Fatalf("stored %d vectors, want %v", got, tt.wantIDs)
+			}
+			for _, id := range tt.wantIDs {
+				if _, ok := store.vectors["acme"][id]; !ok {
+					t.Errorf("vector %s is not stored", id)
+				}
+			}
+		})
+	}
+}
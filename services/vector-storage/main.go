@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -14,243 +15,524 @@ import (
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
 	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
-	"github.com/pinecone-io/go-pinecone/pinecone"
-	"google.golang.org/protobuf/types/known/structpb"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/sparse"
+	"google.golang.org/grpc"
 )
 
-// VectorStorageService implements interfaces.VectorStore
+// VectorStorageService serves the configured vector store over HTTP
 type VectorStorageService struct {
-	client    *pinecone.Client
-	indexName string
-	dimension int
+	backend      string
+	store        vectorStore
+	embedder     *textEmbedder // for text queries and re-embedding
+	hybridAlpha  float64       // dense weight of hybrid queries without alpha
+	dedupUpserts bool          // skip upserts matching the stored checksum
+	refreshAfter time.Duration // age after which unchanged vectors are upserted anyway
 }
 
-// NewVectorStorageService creates a new vector storage service
-func NewVectorStorageService(apiKey, indexName string, dimension int) (*VectorStorageService, error) {
-	client, err := pinecone.NewClient(pinecone.NewClientParams{
-		ApiKey: apiKey,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Pinecone client: %w", err)
+// NewVectorStorageService creates a service over store, the backend named
+// cfg.Backend
+func NewVectorStorageService(store vectorStore, cfg config.VectorStoreConfig, embeddingServiceURL string) *VectorStorageService {
+	return &VectorStorageService{
+		backend:      cfg.Backend,
+		store:        store,
+		embedder:     newTextEmbedder(embeddingServiceURL),
+		hybridAlpha:  cfg.HybridAlpha,
+		dedupUpserts: cfg.DedupUpserts,
+		refreshAfter: time.Duration(cfg.RefreshDays) * 24 * time.Hour,
 	}
+}
 
-	return &VectorStorageService{
-		client:    client,
-		indexName: indexName,
-		dimension: dimension,
-	}, nil
+// HTTP Handlers
+type UpsertRequest struct {
+	Embeddings []*models.Embedding `json:"embeddings"`
+}
+
+// QueryRequest searches by vector or by text, which is embedded by the
+// embedding service. In hybrid mode the dense vector is combined with a
+// sparse one, given or built from the text, weighted by alpha. With MMR,
+// three times top_k matches are fetched and top_k of them picked for
+// diversity as well as relevance.
+type QueryRequest struct {
+	Vector         []float32             `json:"vector,omitempty"`
+	Text           string                `json:"text,omitempty"`
+	TopK           int                   `json:"top_k"`
+	Namespace      string                `json:"namespace"`
+	Filter         models.MetadataFilter `json:"filter,omitempty"`
+	IncludeVectors bool                  `json:"include_vectors,omitempty"`
+	Mode           string                `json:"mode,omitempty"`   // dense (default) or hybrid
+	Sparse         *models.SparseVector  `json:"sparse,omitempty"` // hybrid terms, instead of the text's
+	Alpha          *float64              `json:"alpha,omitempty"`  // dense weight, 0 to 1
+	MMR            bool                  `json:"mmr,omitempty"`
+	MMRLambda      *float64              `json:"mmr_lambda,omitempty"` // relevance weight, 0 to 1; default 0.5
+	MinScore       *float32              `json:"min_score,omitempty"`  // drop matches scoring lower
+}
+
+type QueryResponse struct {
+	Matches []Match  `json:"matches"`
+	Count   int      `json:"count"`
+	Model   string   `json:"model,omitempty"` // embedding model of a text query
+	Mode    string   `json:"mode"`
+	Alpha   *float64 `json:"alpha,omitempty"` // dense weight of a hybrid query
 }
 
-// UpsertVectors inserts or updates vectors
-func (s *VectorStorageService) UpsertVectors(ctx context.Context, embeddings []*models.Embedding) error {
-	if len(embeddings) == 0 {
-		return nil
+// Query modes
+const (
+	ModeDense  = "dense"
+	ModeHybrid = "hybrid"
+)
+
+const (
+	defaultTopK = 10
+	maxTopK     = 1000
+)
+
+func (s *VectorStorageService) handleUpsert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UpsertRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
 	}
 
-	// Determine namespace
-	namespace := ""
-	if len(embeddings) > 0 && embeddings[0].Namespace != "" {
-		namespace = embeddings[0].Namespace
+	upserted, skipped, err := s.upsert(r.Context(), req.Embeddings)
+	if err != nil {
+		logger.Error("Failed to upsert vectors: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
 	}
 
-	// Convert to Pinecone vectors
-	vectors := make([]*pinecone.Vector, len(embeddings))
-	for i, emb := range embeddings {
-		// Convert metadata to structpb.Struct
-		metadataMap := make(map[string]interface{})
-		for k, v := range emb.Metadata {
-			metadataMap[k] = v
-		}
-		metadata, err := structpb.NewStruct(metadataMap)
-		if err != nil {
-			return errors.Internal("failed to convert metadata", err)
-		}
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"upserted": upserted,
+		"skipped":  skipped,
+	})
+}
 
-		vectors[i] = &pinecone.Vector{
-			Id:       emb.ID,
-			Values:   emb.Vector,
-			Metadata: metadata,
-		}
+// upsert stores embeddings that changed since they were last stored,
+// returning how many were upserted and skipped
+func (s *VectorStorageService) upsert(ctx context.Context, embeddings []*models.Embedding) (upserted, skipped int, err error) {
+	return s.upsertInto(ctx, s.store, embeddings)
+}
+
+// upsertInto is upsert into store, which may be another index than the
+// service's own
+func (s *VectorStorageService) upsertInto(ctx context.Context, store vectorStore, embeddings []*models.Embedding) (upserted, skipped int, err error) {
+	if err := validateDimensions(embeddings, store.Dimension()); err != nil {
+		return 0, 0, err
 	}
 
-	// Get index connection
-	idx, err := s.client.DescribeIndex(ctx, s.indexName)
-	if err != nil {
-		return errors.External("Pinecone", "failed to describe index", err)
+	embeddings, skipped = s.skipUnchanged(ctx, store, embeddings)
+	if skipped > 0 {
+		logger.Info("Skipped %d unchanged vectors", skipped)
+	}
+	if err := store.UpsertVectors(ctx, embeddings); err != nil {
+		return 0, 0, err
 	}
+	return len(embeddings), skipped, nil
+}
 
-	idxConnection, err := s.client.Index(pinecone.NewIndexConnParams{Host: idx.Host, Namespace: namespace})
-	if err != nil {
-		return errors.External("Pinecone", "failed to connect to index", err)
+type ExistsRequest struct {
+	IDs       []string `json:"ids"`
+	Namespace string   `json:"namespace"`
+}
+
+// handleExists reports which vector IDs are already stored
+func (s *VectorStorageService) handleExists(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	// Upsert vectors (namespace is set on the connection)
-	_, err = idxConnection.UpsertVectors(ctx, vectors)
+	var req ExistsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := s.store.ExistingIDs(r.Context(), req.IDs, req.Namespace)
 	if err != nil {
-		return errors.External("Pinecone", "failed to upsert vectors", err)
+		logger.Error("Failed to look up vectors: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	logger.Info("Upserted %d vectors to namespace '%s'", len(vectors), namespace)
-	return nil
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"existing": existing,
+		"count":    len(existing),
+	})
+}
+
+// FetchRequest looks up stored vectors by ID
+type FetchRequest struct {
+	IDs       []string `json:"ids"`
+	Namespace string   `json:"namespace"`
 }
 
-// DeleteVectors removes vectors by IDs
-func (s *VectorStorageService) DeleteVectors(ctx context.Context, ids []string, namespace string) error {
-	if len(ids) == 0 {
-		return nil
+// maxFetchIDs bounds the IDs of one fetch request
+const maxFetchIDs = 1000
+
+// handleFetch returns stored vectors and metadata by ID, listing the IDs
+// that are not stored as missing
+func (s *VectorStorageService) handleFetch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	idx, err := s.client.DescribeIndex(ctx, s.indexName)
-	if err != nil {
-		return errors.External("Pinecone", "failed to describe index", err)
+	var req FetchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > maxFetchIDs {
+		http.Error(w, fmt.Sprintf("between 1 and %d ids are required", maxFetchIDs), http.StatusBadRequest)
+		return
 	}
 
-	idxConnection, err := s.client.Index(pinecone.NewIndexConnParams{Host: idx.Host, Namespace: namespace})
+	vectors, err := s.store.Fetch(r.Context(), req.IDs, req.Namespace)
 	if err != nil {
-		return errors.External("Pinecone", "failed to connect to index", err)
+		logger.Error("Failed to fetch vectors: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
 	}
 
-	err = idxConnection.DeleteVectorsById(ctx, ids)
-	if err != nil {
-		return errors.External("Pinecone", "failed to delete vectors", err)
+	stored := make(map[string]bool, len(vectors))
+	for _, v := range vectors {
+		stored[v.ID] = true
+	}
+	missing := []string{}
+	for _, id := range req.IDs {
+		if !stored[id] {
+			missing = append(missing, id)
+		}
 	}
 
-	logger.Info("Deleted %d vectors from namespace '%s'", len(ids), namespace)
-	return nil
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"vectors":   vectors,
+		"missing":   missing,
+		"count":     len(vectors),
+		"namespace": req.Namespace,
+	})
 }
 
-// QueryVectors searches for similar vectors
-func (s *VectorStorageService) QueryVectors(ctx context.Context, vector []float32, topK int, namespace string) ([]*models.Embedding, error) {
-	idx, err := s.client.DescribeIndex(ctx, s.indexName)
-	if err != nil {
-		return nil, errors.External("Pinecone", "failed to describe index", err)
+// DeleteRequest removes vectors by ID, by metadata filter, or with
+// DeleteAll every vector in the namespace
+type DeleteRequest struct {
+	IDs       []string              `json:"ids"`
+	Filter    models.MetadataFilter `json:"filter,omitempty"` // e.g. repository and file_path
+	Namespace string                `json:"namespace"`
+	DeleteAll bool                  `json:"delete_all,omitempty"`
+}
+
+// handleDelete removes vectors from a namespace
+func (s *VectorStorageService) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	idxConnection, err := s.client.Index(pinecone.NewIndexConnParams{Host: idx.Host, Namespace: namespace})
-	if err != nil {
-		return nil, errors.External("Pinecone", "failed to connect to index", err)
+	var req DeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := s.deleteVectors(r.Context(), req); err != nil {
+		logger.Error("Failed to delete vectors: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
 	}
 
-	topK32 := uint32(topK)
+	resp := map[string]interface{}{"status": "success", "namespace": req.Namespace}
+	switch {
+	case req.DeleteAll:
+		resp["deleted_all"] = true
+	case len(req.Filter) > 0:
+		resp["filter"] = req.Filter
+	default:
+		resp["deleted"] = len(req.IDs)
+	}
 
-	queryResp, err := idxConnection.QueryByVectorValues(ctx, &pinecone.QueryByVectorValuesRequest{
-		Vector:          vector,
-		TopK:            topK32,
-		IncludeMetadata: true,
-		IncludeValues:   true,
-	})
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
 
-	if err != nil {
-		return nil, errors.External("Pinecone", "failed to query vectors", err)
-	}
-
-	// Convert results
-	results := make([]*models.Embedding, len(queryResp.Matches))
-	for i, match := range queryResp.Matches {
-		metadata := make(map[string]string)
-		if match.Vector != nil && match.Vector.Metadata != nil {
-			for k, v := range match.Vector.Metadata.AsMap() {
-				if strVal, ok := v.(string); ok {
-					metadata[k] = strVal
-				} else {
-					metadata[k] = fmt.Sprintf("%v", v)
-				}
-			}
+// deleteVectors removes the vectors req selects
+func (s *VectorStorageService) deleteVectors(ctx context.Context, req DeleteRequest) error {
+	selectors := 0
+	for _, given := range []bool{len(req.IDs) > 0, len(req.Filter) > 0, req.DeleteAll} {
+		if given {
+			selectors++
 		}
+	}
+	if selectors != 1 {
+		return errors.Validation("exactly one of ids, filter, or delete_all is required")
+	}
+	// An empty namespace is the store's default one, never wiped by omission
+	if req.DeleteAll && req.Namespace == "" {
+		return errors.Validation("delete_all requires a namespace")
+	}
+
+	switch {
+	case req.DeleteAll:
+		return s.store.DeleteNamespace(ctx, req.Namespace)
+	case len(req.Filter) > 0:
+		return s.store.DeleteByFilter(ctx, req.Filter, req.Namespace)
+	default:
+		return s.store.DeleteVectors(ctx, req.IDs, req.Namespace)
+	}
+}
+
+// handleQuery returns the stored vectors most similar to a vector or text
+func (s *VectorStorageService) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req QueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := s.query(r.Context(), req)
+	if err != nil {
+		logger.Error("Failed to query vectors: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
 
-		var id string
-		var values []float32
-		if match.Vector != nil {
-			id = match.Vector.Id
-			values = match.Vector.Values
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// query validates req, embeds its text if given, and searches the store
+func (s *VectorStorageService) query(ctx context.Context, req QueryRequest) (*QueryResponse, error) {
+	if (len(req.Vector) == 0) == (req.Text == "") {
+		return nil, errors.Validation("one of vector or text is required")
+	}
+	if req.TopK == 0 {
+		req.TopK = defaultTopK
+	}
+	if req.TopK < 0 || req.TopK > maxTopK {
+		return nil, errors.Validation(fmt.Sprintf("top_k must be between 1 and %d", maxTopK))
+	}
+	if req.Mode == "" {
+		req.Mode = ModeDense
+	}
+	var querySparse *models.SparseVector
+	alpha := s.hybridAlpha
+	switch req.Mode {
+	case ModeDense:
+		if req.Sparse != nil || req.Alpha != nil {
+			return nil, errors.Validation("sparse and alpha require mode hybrid")
+		}
+	case ModeHybrid:
+		if req.Alpha != nil {
+			alpha = *req.Alpha
+		}
+		if alpha < 0 || alpha > 1 {
+			return nil, errors.Validation("alpha must be between 0 and 1")
+		}
+		if req.Sparse == nil && req.Text == "" {
+			return nil, errors.Validation("hybrid mode needs text or sparse")
+		}
+		querySparse = req.Sparse
+		if querySparse == nil {
+			querySparse = sparse.EncodeQuery(req.Text)
+		}
+		if querySparse != nil && len(querySparse.Indices) != len(querySparse.Values) {
+			return nil, errors.Validation("sparse indices and values must have the same length")
+		}
+	default:
+		return nil, errors.Validation(fmt.Sprintf("unknown mode %q (available: %s, %s)", req.Mode, ModeDense, ModeHybrid))
+	}
+	lambda := defaultMMRLambda
+	if req.MMRLambda != nil {
+		if !req.MMR {
+			return nil, errors.Validation("mmr_lambda requires mmr")
 		}
+		lambda = *req.MMRLambda
+	}
+	if lambda < 0 || lambda > 1 {
+		return nil, errors.Validation("mmr_lambda must be between 0 and 1")
+	}
 
-		results[i] = &models.Embedding{
-			ID:        id,
-			Vector:    values,
-			Metadata:  metadata,
-			Namespace: namespace,
+	resp := &QueryResponse{Mode: req.Mode}
+	vector := req.Vector
+	if req.Text != "" {
+		var err error
+		vector, resp.Model, err = s.embedder.embed(ctx, req.Text)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return results, nil
+	dense := vector
+	if req.Mode == ModeHybrid {
+		vector, querySparse = sparse.Scale(vector, querySparse, alpha)
+		resp.Alpha = &alpha
+	}
+
+	topK := req.TopK
+	if req.MMR {
+		topK = min(req.TopK*mmrFetchFactor, maxTopK)
+	}
+	matches, err := s.store.Query(ctx, VectorQuery{
+		Vector:         vector,
+		Sparse:         querySparse,
+		TopK:           topK,
+		Namespace:      req.Namespace,
+		Filter:         req.Filter,
+		IncludeVectors: req.IncludeVectors || req.MMR,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if req.MinScore != nil {
+		matches = aboveScore(matches, *req.MinScore)
+	}
+	if req.MMR {
+		matches = mmr(dense, matches, req.TopK, lambda)
+	}
+	if !req.IncludeVectors {
+		// Pinecone returns values only on request, the others may anyway
+		for i := range matches {
+			matches[i].Vector = nil
+		}
+	}
+	resp.Matches = matches
+	resp.Count = len(matches)
+	return resp, nil
 }
 
-// DescribeIndex gets index statistics
-func (s *VectorStorageService) DescribeIndex(ctx context.Context) (map[string]interface{}, error) {
-	idx, err := s.client.DescribeIndex(ctx, s.indexName)
+// handleMigrate copies a namespace to another namespace or index
+func (s *VectorStorageService) handleMigrate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MigrateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	target, err := s.migrationTarget(r.Context(), &req)
 	if err != nil {
-		return nil, errors.External("Pinecone", "failed to describe index", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
 	}
 
-	stats := map[string]interface{}{
-		"name":      idx.Name,
-		"dimension": idx.Dimension,
-		"metric":    idx.Metric,
-		"host":      idx.Host,
-		"status":    idx.Status.State,
+	result, err := s.migrate(r.Context(), target, req)
+	if err != nil {
+		logger.Error("Migration failed: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "failed", "error": err.Error(), "result": result})
+		return
 	}
+	logger.Info("Migrated namespace '%s' to '%s': %d of %d vectors", req.SourceNamespace, req.TargetNamespace, result.Migrated, result.Scanned)
 
-	return stats, nil
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "result": result})
 }
 
-// Health checks the connection health
-func (s *VectorStorageService) Health(ctx context.Context) error {
-	_, err := s.client.DescribeIndex(ctx, s.indexName)
-	return err
-}
+// handleStats reports vector counts, in total and per namespace
+func (s *VectorStorageService) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-// HTTP Handlers
-type UpsertRequest struct {
-	Embeddings []*models.Embedding `json:"embeddings"`
-}
+	stats, err := s.store.Stats(r.Context())
+	if err != nil {
+		logger.Error("Failed to get index stats: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-type QueryRequest struct {
-	Vector    []float32 `json:"vector"`
-	TopK      int       `json:"top_k"`
-	Namespace string    `json:"namespace"`
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
 }
 
-func (s *VectorStorageService) handleUpsert(w http.ResponseWriter, r *http.Request) {
+// indexInitTimeout bounds creating an index and waiting for it to be ready
+const indexInitTimeout = 5 * time.Minute
+
+// handleIndexInit creates the backend's index if it does not exist
+func (s *VectorStorageService) handleIndexInit(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var req UpsertRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	initializer, ok := s.store.(indexInitializer)
+	if !ok {
+		http.Error(w, fmt.Sprintf("the %s backend creates its collections on first write", s.backend), http.StatusBadRequest)
 		return
 	}
 
-	if err := s.UpsertVectors(r.Context(), req.Embeddings); err != nil {
-		logger.Error("Failed to upsert vectors: %v", err)
+	ctx, cancel := context.WithTimeout(r.Context(), indexInitTimeout)
+	defer cancel()
+	created, err := initializer.EnsureIndex(ctx)
+	if err != nil {
+		logger.Error("Failed to initialize index: %v", err)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	stats, err := s.store.DescribeIndex(r.Context())
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	stats["created"] = created
 
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":   "success",
-		"upserted": len(req.Embeddings),
-	})
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
 }
 
-func (s *VectorStorageService) handleHealth(w http.ResponseWriter, r *http.Request) {
-	if err := s.Health(r.Context()); err != nil {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_ = json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy", "error": err.Error()})
-		return
+// aboveScore keeps the matches scoring at least minScore, in order
+func aboveScore(matches []Match, minScore float32) []Match {
+	kept := matches[:0]
+	for _, m := range matches {
+		if m.Score >= minScore {
+			kept = append(kept, m)
+		}
 	}
+	return kept
+}
 
-	stats, err := s.DescribeIndex(r.Context())
+// errorStatus is the HTTP status for err: 400 for invalid requests, 500
+// otherwise
+func errorStatus(err error) int {
+	var appErr *errors.AppError
+	if stderrors.As(err, &appErr) && appErr.Type == errors.ErrTypeValidation {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// handleHealth describes the index, which reaches the backend just as
+// Health would, so one call answers both whether it's up and its details
+func (s *VectorStorageService) handleHealth(w http.ResponseWriter, r *http.Request) {
+	stats, err := s.store.DescribeIndex(r.Context())
 	if err != nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
-		_ = json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy", "error": err.Error()})
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy", "backend": s.backend, "error": err.Error()})
 		return
 	}
 
 	stats["status"] = "healthy"
+	stats["backend"] = s.backend
 	_ = json.NewEncoder(w).Encode(stats)
 }
 
@@ -277,25 +559,49 @@ func main() {
 	logger.Info("Starting Vector Storage Service on port %d", cfg.Services.VectorStoragePort)
 
 	// Create vector storage service
-	service, err := NewVectorStorageService(
-		cfg.Pinecone.APIKey,
-		cfg.Pinecone.IndexName,
-		cfg.Pinecone.Dimension,
-	)
+	store, err := newStore(context.Background(), cfg.VectorStore.Backend, cfg)
 	if err != nil {
 		logger.Fatal("Failed to create vector storage service: %v", err)
 	}
+	logger.Info("Storing vectors in %s", cfg.VectorStore.Backend)
+	if initializer, ok := store.(indexInitializer); ok && cfg.Pinecone.CreateIndex {
+		ctx, cancel := context.WithTimeout(context.Background(), indexInitTimeout)
+		if _, err := initializer.EnsureIndex(ctx); err != nil {
+			// Keep serving so /health reports the problem and /index/init can retry
+			logger.Warning("Failed to initialize index: %v", err)
+		}
+		cancel()
+	}
+	service := NewVectorStorageService(store, cfg.VectorStore, getServiceURL("EMBEDDING_SERVICE_URL", "http://localhost:8083"))
 
 	// Setup HTTP server
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", service.handleHealth)
 	mux.HandleFunc("/upsert", service.handleUpsert)
+	mux.HandleFunc("/exists", service.handleExists)
+	mux.HandleFunc("/fetch", service.handleFetch)
+	mux.HandleFunc("/query", service.handleQuery)
+	mux.HandleFunc("/delete", service.handleDelete)
+	mux.HandleFunc("/index/init", service.handleIndexInit)
+	mux.HandleFunc("/stats", service.handleStats)
+	mux.HandleFunc("/migrate", service.handleMigrate)
+	mux.HandleFunc("/export", service.handleExport)
+	mux.HandleFunc("/import", service.handleImport)
+	mux.HandleFunc("/cleanup", service.handleCleanup)
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Services.VectorStoragePort),
 		Handler: mux,
 	}
 
+	var grpcServer *grpc.Server
+	if port := cfg.Services.VectorStorageGRPCPort; port > 0 {
+		if grpcServer, err = serveGRPC(service, port); err != nil {
+			logger.Fatal("Failed to start gRPC server: %v", err)
+		}
+		logger.Info("Vector Storage Service gRPC API listening on port %d", port)
+	}
+
 	// Graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
@@ -309,6 +615,9 @@ func main() {
 		if err := server.Shutdown(ctx); err != nil {
 			logger.Error("Server shutdown error: %v", err)
 		}
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
 	}()
 
 	// Start server
@@ -317,3 +626,10 @@ func main() {
 		logger.Fatal("Failed to start server: %v", err)
 	}
 }
+
+func getServiceURL(envVar, defaultURL string) string {
+	if url := os.Getenv(envVar); url != "" {
+		return url
+	}
+	return defaultURL
+}
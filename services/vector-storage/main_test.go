@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestHandleQueryMinScore(t *testing.T) {
+	store := NewMemoryStore(2)
+	err := store.UpsertVectors(context.Background(), []*models.Embedding{
+		{ID: "close", Vector: []float32{1, 0.1}, Namespace: "acme"},
+		{ID: "near", Vector: []float32{1, 1}, Namespace: "acme"},
+		{ID: "far", Vector: []float32{0, 1}, Namespace: "acme"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &VectorStorageService{store: store}
+
+	tests := []struct {
+		name    string
+		body    string
+		wantIDs []string
+	}{
+		{name: "no threshold", body: `{"vector":[1,0],"namespace":"acme"}`, wantIDs: []string{"close", "near", "far"}},
+		{name: "threshold drops weak matches", body: `{"vector":[1,0],"namespace":"acme","min_score":0.5}`, wantIDs: []string{"close", "near"}},
+		{name: "threshold of zero keeps orthogonal matches", body: `{"vector":[1,0],"namespace":"acme","min_score":0}`, wantIDs: []string{"close", "near", "far"}},
+		{name: "threshold above every match", body: `{"vector":[1,0],"namespace":"acme","min_score":1.5}`, wantIDs: []string{}},
+		{name: "applied before mmr", body: `{"vector":[1,0],"top_k":2,"namespace":"acme","min_score":0.9,"mmr":true}`, wantIDs: []string{"close"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleQuery(rec, httptest.NewRequest(http.MethodPost, "/query", bytes.NewBufferString(tt.body)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d (%s)", rec.Code, rec.Body.String())
+			}
+
+			var resp QueryResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, len(resp.Matches))
+			for i, m := range resp.Matches {
+				ids[i] = m.ID
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || resp.Count != len(tt.wantIDs) {
+				t.Errorf("matches = %v (count %d), want %v", ids, resp.Count, tt.wantIDs)
+			}
+		})
+	}
+}
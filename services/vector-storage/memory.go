@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// MemoryStore implements interfaces.VectorStore in process, searching by
+// brute-force cosine similarity. Vectors are lost on restart, so it is meant
+// for development, tests, and demos without an external database.
+type MemoryStore struct {
+	dimension int // 0 accepts any length
+
+	mu      sync.RWMutex
+	vectors map[string]map[string]StoredVector // by namespace, then ID
+}
+
+func init() {
+	registerBackend(BackendMemory, func(_ context.Context, cfg *config.Config) (vectorStore, error) {
+		logger.Warning("Keeping vectors in memory; they are lost when the service stops")
+		return NewMemoryStore(cfg.VectorStore.Memory.Dimension), nil
+	})
+}
+
+// NewMemoryStore creates an empty store for vectors of the given dimension
+func NewMemoryStore(dimension int) *MemoryStore {
+	return &MemoryStore{
+		dimension: dimension,
+		vectors:   make(map[string]map[string]StoredVector),
+	}
+}
+
+// UpsertVectors inserts or updates vectors, each in its own namespace
+func (s *MemoryStore) UpsertVectors(_ context.Context, embeddings []*models.Embedding) error {
+	if err := validateDimensions(embeddings, s.dimension); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, emb := range embeddings {
+		if s.vectors[emb.Namespace] == nil {
+			s.vectors[emb.Namespace] = make(map[string]StoredVector)
+		}
+		s.vectors[emb.Namespace][emb.ID] = storedCopy(StoredVector{ID: emb.ID, Vector: emb.Vector, Sparse: emb.Sparse, Metadata: emb.Metadata})
+	}
+	return nil
+}
+
+// storedCopy copies a vector's slices and metadata, so callers can't change
+// what is stored and stored vectors can't be changed through results
+func storedCopy(v StoredVector) StoredVector {
+	out := StoredVector{ID: v.ID, Vector: append([]float32(nil), v.Vector...)}
+	if v.Sparse != nil {
+		out.Sparse = &models.SparseVector{
+			Indices: append([]uint32(nil), v.Sparse.Indices...),
+			Values:  append([]float32(nil), v.Sparse.Values...),
+		}
+	}
+	if v.Metadata != nil {
+		out.Metadata = make(map[string]string, len(v.Metadata))
+		for k, val := range v.Metadata {
+			out.Metadata[k] = val
+		}
+	}
+	return out
+}
+
+// DeleteVectors removes vectors by IDs
+func (s *MemoryStore) DeleteVectors(_ context.Context, ids []string, namespace string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.vectors[namespace], id)
+	}
+	return nil
+}
+
+// memoryMatches reports whether metadata has, for every filter key, one of
+// the key's values
+func memoryMatches(metadata map[string]string, filter models.MetadataFilter) bool {
+	for k, values := range filter {
+		v, ok := metadata[k]
+		if !ok {
+			return false
+		}
+		found := false
+		for _, want := range values {
+			if v == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// DeleteByFilter removes the vectors whose metadata match filter
+func (s *MemoryStore) DeleteByFilter(_ context.Context, filter models.MetadataFilter, namespace string) error {
+	if len(filter) == 0 {
+		return errors.Validation("filter is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	for id, v := range s.vectors[namespace] {
+		if memoryMatches(v.Metadata, filter) {
+			delete(s.vectors[namespace], id)
+			deleted++
+		}
+	}
+	logger.Info("Deleted %d vectors matching %v from namespace '%s'", deleted, filter, namespace)
+	return nil
+}
+
+// DeleteNamespace removes every vector in the namespace
+func (s *MemoryStore) DeleteNamespace(_ context.Context, namespace string) error {
+	s.mu.Lock()
+	delete(s.vectors, namespace)
+	s.mu.Unlock()
+	return nil
+}
+
+// ExistingIDs returns the subset of ids already stored in the namespace
+func (s *MemoryStore) ExistingIDs(_ context.Context, ids []string, namespace string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	existing := []string{}
+	for _, id := range ids {
+		if _, ok := s.vectors[namespace][id]; ok {
+			existing = append(existing, id)
+		}
+	}
+	return existing, nil
+}
+
+// Fetch returns stored vectors by ID
+func (s *MemoryStore) Fetch(_ context.Context, ids []string, namespace string) ([]StoredVector, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := make(map[string]StoredVector, len(ids))
+	for _, id := range ids {
+		if v, ok := s.vectors[namespace][id]; ok {
+			found[id] = storedCopy(v)
+		}
+	}
+	return inOrder(ids, found), nil
+}
+
+// Scan returns the namespace's vectors in ID order; the cursor is the ID the
+// next page starts at
+func (s *MemoryStore) Scan(_ context.Context, namespace, cursor string, limit int) ([]StoredVector, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0, len(s.vectors[namespace]))
+	for id := range s.vectors[namespace] {
+		if id >= cursor {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	next := ""
+	if len(ids) > limit {
+		next = ids[limit]
+		ids = ids[:limit]
+	}
+	page := make([]StoredVector, len(ids))
+	for i, id := range ids {
+		page[i] = storedCopy(s.vectors[namespace][id])
+	}
+	return page, next, nil
+}
+
+// QueryVectors searches for similar vectors
+func (s *MemoryStore) QueryVectors(ctx context.Context, vector []float32, topK int, namespace string, filter models.MetadataFilter) ([]*models.Embedding, error) {
+	matches, err := s.Query(ctx, VectorQuery{Vector: vector, TopK: topK, Namespace: namespace, Filter: filter, IncludeVectors: true})
+	if err != nil {
+		return nil, err
+	}
+	return matchEmbeddings(matches, namespace), nil
+}
+
+// Query scores every vector of the namespace that passes the filter by
+// cosine similarity, returning the best, ties in ID order
+func (s *MemoryStore) Query(_ context.Context, q VectorQuery) ([]Match, error) {
+	if q.Sparse != nil {
+		return nil, errors.Validation("hybrid search is not supported by the memory backend")
+	}
+	if s.dimension > 0 && len(q.Vector) != s.dimension {
+		return nil, errors.Validation(fmt.Sprintf("query vector has %d dimensions, the index has %d", len(q.Vector), s.dimension))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	matches := make([]Match, 0, len(s.vectors[q.Namespace]))
+	for _, v := range s.vectors[q.Namespace] {
+		if len(v.Vector) == len(q.Vector) && memoryMatches(v.Metadata, q.Filter) {
+			matches = append(matches, Match{ID: v.ID, Score: cosine(q.Vector, v.Vector)})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > q.TopK {
+		matches = matches[:q.TopK]
+	}
+	for i := range matches {
+		stored := storedCopy(s.vectors[q.Namespace][matches[i].ID])
+		matches[i].Metadata = stored.Metadata
+		if q.IncludeVectors {
+			matches[i].Vector = stored.Vector
+		}
+	}
+	return matches, nil
+}
+
+// cosine is the cosine similarity of two vectors of equal length, 0 when
+// either is all zeros
+func cosine(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(normA*normB))
+}
+
+// DescribeIndex gets index statistics
+func (s *MemoryStore) DescribeIndex(_ context.Context) (map[string]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	namespaces := make([]string, 0, len(s.vectors))
+	for ns := range s.vectors {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return map[string]interface{}{
+		"name":       BackendMemory,
+		"dimension":  s.dimension,
+		"metric":     "cosine",
+		"namespaces": namespaces,
+	}, nil
+}
+
+// Stats counts the vectors of every namespace
+func (s *MemoryStore) Stats(_ context.Context) (*models.IndexStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := &models.IndexStats{
+		Backend:    BackendMemory,
+		Dimension:  s.dimension,
+		Namespaces: make(map[string]int64, len(s.vectors)),
+	}
+	for ns, vectors := range s.vectors {
+		stats.Namespaces[ns] = int64(len(vectors))
+		stats.TotalVectors += int64(len(vectors))
+	}
+	return stats, nil
+}
+
+// Dimension is the configured vector length
+func (s *MemoryStore) Dimension() int {
+	return s.dimension
+}
+
+// Health always succeeds; there is nothing to connect to
+func (s *MemoryStore) Health(_ context.Context) error {
+	return nil
+}
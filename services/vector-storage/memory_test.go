@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestMemoryStoreQuery(t *testing.T) {
+	store := NewMemoryStore(2)
+	err := store.UpsertVectors(context.Background(), []*models.Embedding{
+		{ID: "east", Vector: []float32{1, 0}, Namespace: "acme", Metadata: map[string]string{"repository": "docs"}},
+		{ID: "north-east", Vector: []float32{1, 1}, Namespace: "acme", Metadata: map[string]string{"repository": "api"}},
+		{ID: "north", Vector: []float32{0, 2}, Namespace: "acme", Metadata: map[string]string{"repository": "docs"}},
+		{ID: "west", Vector: []float32{-1, 0}, Namespace: "acme"},
+		{ID: "other", Vector: []float32{1, 0}, Namespace: "other"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		query   VectorQuery
+		wantIDs []string
+		wantErr bool
+	}{
+		{
+			name:    "ranked by cosine similarity",
+			query:   VectorQuery{Vector: []float32{2, 0}, TopK: 10, Namespace: "acme"},
+			wantIDs: []string{"east", "north-east", "north", "west"},
+		},
+		{
+			name:    "top k",
+			query:   VectorQuery{Vector: []float32{0, 1}, TopK: 2, Namespace: "acme"},
+			wantIDs: []string{"north", "north-east"},
+		},
+		{
+			name:    "filter",
+			query:   VectorQuery{Vector: []float32{1, 1}, TopK: 10, Namespace: "acme", Filter: models.MetadataFilter{"repository": {"docs"}}},
+			wantIDs: []string{"east", "north"},
+		},
+		{
+			name:    "filter on a missing key",
+			query:   VectorQuery{Vector: []float32{1, 1}, TopK: 10, Namespace: "acme", Filter: models.MetadataFilter{"branch": {"main"}}},
+			wantIDs: []string{},
+		},
+		{
+			name:    "unknown namespace",
+			query:   VectorQuery{Vector: []float32{1, 0}, TopK: 10, Namespace: "missing"},
+			wantIDs: []string{},
+		},
+		{
+			name:    "wrong dimension",
+			query:   VectorQuery{Vector: []float32{1, 0, 0}, TopK: 10, Namespace: "acme"},
+			wantErr: true,
+		},
+		{
+			name:    "sparse is not supported",
+			query:   VectorQuery{Vector: []float32{1, 0}, Sparse: &models.SparseVector{}, TopK: 10, Namespace: "acme"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := store.Query(context.Background(), tt.query)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %d matches", len(matches))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ids := make([]string, len(matches))
+			for i, m := range matches {
+				ids[i] = m.ID
+				if m.Vector != nil {
+					t.Errorf("match %s has a vector without IncludeVectors", m.ID)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("matches = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreScan(t *testing.T) {
+	store := NewMemoryStore(0)
+	ctx := context.Background()
+	var embeddings []*models.Embedding
+	for _, id := range []string{"e", "a", "d", "b", "c"} {
+		embeddings = append(embeddings, &models.Embedding{ID: id, Vector: []float32{1}, Namespace: "acme"})
+	}
+	if err := store.UpsertVectors(ctx, embeddings); err != nil {
+		t.Fatal(err)
+	}
+
+	var pages [][]string
+	cursor := ""
+	for {
+		page, next, err := store.Scan(ctx, "acme", cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, v := range page {
+			ids = append(ids, v.ID)
+		}
+		pages = append(pages, ids)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+}
+
+func TestMemoryStoreDeleteByFilter(t *testing.T) {
+	store := NewMemoryStore(0)
+	ctx := context.Background()
+	err := store.UpsertVectors(ctx, []*models.Embedding{
+		{ID: "a", Vector: []float32{1}, Namespace: "acme", Metadata: map[string]string{"file_path": "a.md"}},
+		{ID: "b", Vector: []float32{1}, Namespace: "acme", Metadata: map[string]string{"file_path": "b.md"}},
+		{ID: "c", Vector: []float32{1}, Namespace: "acme", Metadata: map[string]string{"file_path": "c.md"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.DeleteByFilter(ctx, nil, "acme"); err == nil {
+		t.Error("expected an error for an empty filter")
+	}
+	if err := store.DeleteByFilter(ctx, models.MetadataFilter{"file_path": {"a.md", "c.md"}}, "acme"); err != nil {
+		t.Fatal(err)
+	}
+
+	existing, _ := store.ExistingIDs(ctx, []string{"a", "b", "c"}, "acme")
+	if !reflect.DeepEqual(existing, []string{"b"}) {
+		t.Errorf("existing = %v, want [b]", existing)
+	}
+}
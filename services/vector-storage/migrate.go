@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// indexOpener is a backend that can open another index of the same account,
+// as a migration target
+type indexOpener interface {
+	// OpenIndex returns a store for the named index, creating it with
+	// dimension if it does not exist
+	OpenIndex(ctx context.Context, name string, dimension int) (vectorStore, error)
+}
+
+// MigrateRequest copies every vector of a namespace to another namespace,
+// of the same index or of TargetIndex
+type MigrateRequest struct {
+	SourceNamespace string `json:"source_namespace"`
+	TargetNamespace string `json:"target_namespace"`
+	TargetIndex     string `json:"target_index,omitempty"`     // another Pinecone index, created if missing
+	TargetDimension int    `json:"target_dimension,omitempty"` // of a created target index, default the configured one
+	ReEmbed         bool   `json:"re_embed,omitempty"`         // embed the chunk text again instead of copying vectors
+	TextField       string `json:"text_field,omitempty"`       // metadata holding the chunk text, default "text"
+	BatchSize       int    `json:"batch_size,omitempty"`
+}
+
+// MigrateResult counts the vectors of a migration
+type MigrateResult struct {
+	Scanned   int    `json:"scanned"`
+	Migrated  int    `json:"migrated"`
+	Skipped   int    `json:"skipped"`         // re-embedding without chunk text
+	Unchanged int    `json:"unchanged"`       // already in the target with the same checksum
+	Model     string `json:"model,omitempty"` // embedding model of re-embedded vectors
+}
+
+const (
+	defaultMigrateBatch = 100
+	maxMigrateBatch     = 1000
+)
+
+// migrate streams the source namespace to target page by page,
+// re-embedding each page's chunk texts if asked to. Pages are written like
+// any upsert, so vectors of the wrong dimension are refused and each is
+// stamped with the checksum of what it now holds.
+func (s *VectorStorageService) migrate(ctx context.Context, target vectorStore, req MigrateRequest) (*MigrateResult, error) {
+	result := &MigrateResult{}
+	cursor := ""
+	for {
+		page, next, err := s.store.Scan(ctx, req.SourceNamespace, cursor, req.BatchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to read namespace '%s' after %d vectors: %w", req.SourceNamespace, result.Scanned, err)
+		}
+		result.Scanned += len(page)
+
+		embeddings := make([]*models.Embedding, 0, len(page))
+		var texts []string
+		for _, v := range page {
+			metadata := make(map[string]string, len(v.Metadata))
+			for k, val := range v.Metadata {
+				metadata[k] = val
+			}
+			if req.ReEmbed {
+				text := metadata[req.TextField]
+				if text == "" {
+					result.Skipped++
+					continue
+				}
+				texts = append(texts, text)
+				// The copied checksum is of the old vector
+				delete(metadata, metadataChecksum)
+			}
+			embeddings = append(embeddings, &models.Embedding{
+				ID:        v.ID,
+				Vector:    v.Vector,
+				Sparse:    v.Sparse,
+				Metadata:  metadata,
+				Namespace: req.TargetNamespace,
+			})
+		}
+
+		if req.ReEmbed && len(texts) > 0 {
+			vectors, model, err := s.embedder.embedTexts(ctx, texts, "document")
+			if err != nil {
+				return result, fmt.Errorf("failed to re-embed after %d vectors: %w", result.Migrated, err)
+			}
+			for i, emb := range embeddings {
+				emb.Vector = vectors[i]
+				if model != "" {
+					emb.Metadata["embedding_model"] = model
+				}
+			}
+			result.Model = model
+		}
+
+		if len(embeddings) > 0 {
+			upserted, unchanged, err := s.upsertInto(ctx, target, embeddings)
+			if err != nil {
+				return result, fmt.Errorf("failed to write namespace '%s' after %d vectors: %w", req.TargetNamespace, result.Migrated, err)
+			}
+			result.Migrated += upserted
+			result.Unchanged += unchanged
+			logger.Info("Migrated %d vectors from '%s' to '%s'", result.Migrated, req.SourceNamespace, req.TargetNamespace)
+		}
+
+		if next == "" || next == cursor {
+			return result, nil
+		}
+		cursor = next
+	}
+}
+
+// migrationTarget validates a migration request, filling in defaults, and
+// returns the store to write to
+func (s *VectorStorageService) migrationTarget(ctx context.Context, req *MigrateRequest) (vectorStore, error) {
+	if req.BatchSize == 0 {
+		req.BatchSize = defaultMigrateBatch
+	}
+	if req.BatchSize < 0 || req.BatchSize > maxMigrateBatch {
+		return nil, errors.Validation(fmt.Sprintf("batch_size must be between 1 and %d", maxMigrateBatch))
+	}
+	if req.TextField == "" {
+		req.TextField = models.MetadataText
+	}
+	if req.ReEmbed && s.embedder == nil {
+		return nil, errors.Validation("re-embedding needs the embedding service")
+	}
+
+	if req.TargetIndex == "" {
+		if req.TargetNamespace == req.SourceNamespace {
+			return nil, errors.Validation("target_namespace or target_index must differ from the source")
+		}
+		if req.TargetDimension != 0 {
+			return nil, errors.Validation("target_dimension requires target_index")
+		}
+		return s.store, nil
+	}
+
+	opener, ok := s.store.(indexOpener)
+	if !ok {
+		return nil, errors.Validation(fmt.Sprintf("the %s backend cannot migrate to another index", s.backend))
+	}
+	if req.TargetDimension < 0 {
+		return nil, errors.Validation("target_dimension must be positive")
+	}
+	return opener.OpenIndex(ctx, req.TargetIndex, req.TargetDimension)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestMigrate(t *testing.T) {
+	embedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Texts     []string `json:"texts"`
+			InputType string   `json:"input_type"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.InputType != "document" {
+			t.Errorf("input_type = %q, want document", req.InputType)
+		}
+		embeddings := make([][]float32, len(req.Texts))
+		for i, text := range req.Texts {
+			embeddings[i] = []float32{float32(len(text)), 0, 0}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": embeddings, "model": "test/new"})
+	}))
+	defer embedServer.Close()
+
+	seed := func() *MemoryStore {
+		store := NewMemoryStore(0)
+		_ = store.UpsertVectors(context.Background(), []*models.Embedding{
+			{ID: "a", Vector: []float32{1, 0}, Namespace: "acme", Metadata: map[string]string{models.MetadataText: "alpha", "repository": "org/x", metadataChecksum: "stale"}},
+			{ID: "b", Vector: []float32{0, 1}, Namespace: "acme", Metadata: map[string]string{models.MetadataText: "be"}},
+			{ID: "c", Vector: []float32{1, 1}, Namespace: "acme", Metadata: map[string]string{"repository": "org/y"}},
+		})
+		return store
+	}
+
+	tests := []struct {
+		name         string
+		req          MigrateRequest
+		wantResult   MigrateResult
+		wantVectors  map[string][]float32
+		wantModel    string
+		separateDest int // dimension of a separate target store, -1 for none
+		wantErr      bool
+	}{
+		{
+			name:         "copy to another namespace in pages",
+			req:          MigrateRequest{SourceNamespace: "acme", TargetNamespace: "acme-copy", BatchSize: 2},
+			wantResult:   MigrateResult{Scanned: 3, Migrated: 3},
+			wantVectors:  map[string][]float32{"a": {1, 0}, "b": {0, 1}, "c": {1, 1}},
+			separateDest: -1,
+		},
+		{
+			name:         "re-embed into another index",
+			req:          MigrateRequest{SourceNamespace: "acme", TargetNamespace: "acme", ReEmbed: true, TextField: models.MetadataText, BatchSize: 10},
+			wantResult:   MigrateResult{Scanned: 3, Migrated: 2, Skipped: 1, Model: "test/new"},
+			wantVectors:  map[string][]float32{"a": {5, 0, 0}, "b": {2, 0, 0}},
+			wantModel:    "test/new",
+			separateDest: 3,
+		},
+		{
+			name:         "vectors of the wrong dimension are refused",
+			req:          MigrateRequest{SourceNamespace: "acme", TargetNamespace: "acme", BatchSize: 10},
+			separateDest: 3,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := seed()
+			target := source
+			if tt.separateDest >= 0 {
+				target = NewMemoryStore(tt.separateDest)
+			}
+			s := &VectorStorageService{store: source, embedder: newTextEmbedder(embedServer.URL)}
+
+			result, err := s.migrate(context.Background(), target, tt.req)
+			if tt.wantErr {
+				if err == nil || len(target.vectors[tt.req.TargetNamespace]) != 0 {
+					t.Errorf("migrate() = %+v, %v; want an error and nothing written", result, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("migrate() error: %v", err)
+			}
+			if *result != tt.wantResult {
+				t.Errorf("result = %+v, want %+v", *result, tt.wantResult)
+			}
+
+			got := target.vectors[tt.req.TargetNamespace]
+			if len(got) != len(tt.wantVectors) {
+				t.Fatalf("target holds %d vectors, want %d", len(got), len(tt.wantVectors))
+			}
+			for id, want := range tt.wantVectors {
+				if !reflect.DeepEqual(got[id].Vector, want) {
+					t.Errorf("vector %s = %v, want %v", id, got[id].Vector, want)
+				}
+				if got[id].Metadata["embedding_model"] != tt.wantModel {
+					t.Errorf("vector %s model = %q, want %q", id, got[id].Metadata["embedding_model"], tt.wantModel)
+				}
+				stored := &models.Embedding{ID: id, Vector: got[id].Vector, Sparse: got[id].Sparse, Metadata: got[id].Metadata}
+				if got[id].Metadata[metadataChecksum] != vectorChecksum(stored) {
+					t.Errorf("vector %s checksum = %q, not of what it holds", id, got[id].Metadata[metadataChecksum])
+				}
+			}
+			if len(source.vectors["acme"]) != 3 {
+				t.Errorf("source namespace changed: %d vectors", len(source.vectors["acme"]))
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+// Candidates fetched per requested match when re-ranking with MMR
+const mmrFetchFactor = 3
+
+// defaultMMRLambda weighs relevance and diversity equally
+const defaultMMRLambda = 0.5
+
+// mmr re-ranks candidates by maximal marginal relevance, picking topK of
+// them one at a time: each pick maximizes lambda times its similarity to
+// the query minus (1 - lambda) times its highest similarity to an earlier
+// pick. Lambda 1 keeps the similarity order, lower values favor diverse
+// results. Candidates need their vectors; similarities are cosine.
+func mmr(query []float32, candidates []Match, topK int, lambda float64) []Match {
+	if topK > len(candidates) {
+		topK = len(candidates)
+	}
+
+	relevance := make([]float64, len(candidates))
+	for i, c := range candidates {
+		relevance[i] = float64(similarity(query, c.Vector))
+	}
+	// redundancy[i] is candidate i's highest similarity to a picked one
+	redundancy := make([]float64, len(candidates))
+	picked := make([]bool, len(candidates))
+
+	selected := make([]Match, 0, topK)
+	for len(selected) < topK {
+		best := -1
+		var bestScore float64
+		for i := range candidates {
+			if picked[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*redundancy[i]
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked[best] = true
+		selected = append(selected, candidates[best])
+		for i := range candidates {
+			if !picked[i] {
+				if sim := float64(similarity(candidates[i].Vector, candidates[best].Vector)); len(selected) == 1 || sim > redundancy[i] {
+					redundancy[i] = sim
+				}
+			}
+		}
+	}
+	return selected
+}
+
+// similarity is the cosine similarity of a and b, 0 if their lengths differ
+func similarity(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	return cosine(a, b)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+func TestMMR(t *testing.T) {
+	query := []float32{1, 0}
+	candidates := []Match{
+		{ID: "a", Vector: []float32{1, 0.1}},
+		{ID: "a-copy", Vector: []float32{1, 0.11}},
+		{ID: "b", Vector: []float32{1, -0.6}},
+		{ID: "c", Vector: []float32{0.2, 1}},
+	}
+
+	tests := []struct {
+		name    string
+		topK    int
+		lambda  float64
+		wantIDs []string
+	}{
+		{name: "lambda 1 keeps similarity order", topK: 3, lambda: 1, wantIDs: []string{"a", "a-copy", "b"}},
+		{name: "near duplicates are pushed down", topK: 3, lambda: 0.5, wantIDs: []string{"a", "b", "a-copy"}},
+		{name: "low lambda favors diversity", topK: 3, lambda: 0.3, wantIDs: []string{"a", "c", "b"}},
+		{name: "top k over the candidates", topK: 10, lambda: 0.3, wantIDs: []string{"a", "c", "b", "a-copy"}},
+		{name: "no candidates", topK: 3, lambda: 0.5, wantIDs: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := candidates
+			if len(tt.wantIDs) == 0 {
+				in = nil
+			}
+			got := mmr(query, in, tt.topK, tt.lambda)
+			ids := make([]string, len(got))
+			for i, m := range got {
+				ids[i] = m.ID
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("mmr() = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestHandleQueryMMR(t *testing.T) {
+	store := NewMemoryStore(2)
+	err := store.UpsertVectors(context.Background(), []*models.Embedding{
+		{ID: "a", Vector: []float32{1, 0.1}, Namespace: "acme"},
+		{ID: "a-copy", Vector: []float32{1, 0.11}, Namespace: "acme"},
+		{ID: "b", Vector: []float32{1, -0.6}, Namespace: "acme"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &VectorStorageService{store: store}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantIDs    []string
+	}{
+		{name: "without mmr", body: `{"vector":[1,0],"top_k":2,"namespace":"acme"}`, wantStatus: http.StatusOK, wantIDs: []string{"a", "a-copy"}},
+		{name: "with mmr", body: `{"vector":[1,0],"top_k":2,"namespace":"acme","mmr":true}`, wantStatus: http.StatusOK, wantIDs: []string{"a", "b"}},
+		{name: "lambda without mmr", body: `{"vector":[1,0],"namespace":"acme","mmr_lambda":0.5}`, wantStatus: http.StatusBadRequest},
+		{name: "lambda out of range", body: `{"vector":[1,0],"namespace":"acme","mmr":true,"mmr_lambda":2}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleQuery(rec, httptest.NewRequest(http.MethodPost, "/query", bytes.NewBufferString(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp QueryResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, len(resp.Matches))
+			for i, m := range resp.Matches {
+				ids[i] = m.ID
+				if m.Vector != nil {
+					t.Errorf("match %s has a vector without include_vectors", m.ID)
+				}
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("matches = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,669 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+	"github.com/pinecone-io/go-pinecone/pinecone"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// PineconeStore implements interfaces.VectorStore with a Pinecone index,
+// using Pinecone namespaces as namespaces
+type PineconeStore struct {
+	client    *pinecone.Client
+	indexName string
+	dimension int
+	metric    string // used when creating the index
+	cloud     string
+	region    string
+
+	mu    sync.Mutex
+	conns map[string]*pineconeConn // by namespace
+}
+
+// pineconeConnTTL is how long a cached connection is used before the index
+// is described again, to follow a recreated index to its new host
+const pineconeConnTTL = 10 * time.Minute
+
+// pineconeConn is an open connection to the index for one namespace
+type pineconeConn struct {
+	index   *pinecone.Index // description the connection was opened from
+	conn    *pinecone.IndexConnection
+	checked time.Time // zero after an error, to describe again on next use
+	users   int       // calls holding the connection
+	retired bool      // replaced after the host changed, closed once unused
+	closed  bool
+}
+
+func init() {
+	registerBackend(BackendPinecone, func(_ context.Context, cfg *config.Config) (vectorStore, error) {
+		return NewPineconeStore(cfg.Pinecone)
+	})
+}
+
+// NewPineconeStore connects to the Pinecone index cfg.IndexName
+func NewPineconeStore(cfg config.PineconeConfig) (*PineconeStore, error) {
+	client, err := pinecone.NewClient(pinecone.NewClientParams{
+		ApiKey: cfg.APIKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Pinecone client: %w", err)
+	}
+
+	return &PineconeStore{
+		client:    client,
+		indexName: cfg.IndexName,
+		dimension: cfg.Dimension,
+		metric:    cfg.Metric,
+		cloud:     cfg.Cloud,
+		region:    cfg.Region,
+		conns:     make(map[string]*pineconeConn),
+	}, nil
+}
+
+// OpenIndex returns a store for another index of the account, with the same
+// metric, cloud, and region, creating it with dimension (default the
+// configured one) if it does not exist
+func (s *PineconeStore) OpenIndex(ctx context.Context, name string, dimension int) (vectorStore, error) {
+	if dimension == 0 {
+		dimension = s.dimension
+	}
+	target := &PineconeStore{
+		client:    s.client,
+		indexName: name,
+		dimension: dimension,
+		metric:    s.metric,
+		cloud:     s.cloud,
+		region:    s.region,
+		conns:     make(map[string]*pineconeConn),
+	}
+	if _, err := target.EnsureIndex(ctx); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// connect returns the index description and the cached connection for
+// namespace, and a release func to call once done with the connection. Once
+// the cache entry is older than pineconeConnTTL or was invalidated, the index
+// is described again and a new connection opened only if its host changed;
+// the old one is closed when the last call using it releases it. The index is
+// described without holding the lock, so a slow answer doesn't stall other
+// namespaces; the entry is looked up again afterwards in case another call
+// refreshed it meanwhile.
+func (s *PineconeStore) connect(ctx context.Context, namespace string) (*pinecone.Index, *pinecone.IndexConnection, func(), error) {
+	if cached := s.cachedConn(namespace); cached != nil {
+		return cached.index, cached.conn, s.releaser(cached), nil
+	}
+
+	idx, err := s.client.DescribeIndex(ctx, s.indexName)
+	if err != nil {
+		return nil, nil, nil, errors.External("Pinecone", "failed to describe index", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached := s.conns[namespace]
+	if cached != nil && time.Since(cached.checked) >= pineconeConnTTL && cached.index.Host == idx.Host {
+		cached.index, cached.checked = idx, time.Now()
+	}
+	if cached != nil && time.Since(cached.checked) < pineconeConnTTL {
+		cached.users++
+		return cached.index, cached.conn, s.releaser(cached), nil
+	}
+
+	conn, err := s.client.Index(pinecone.NewIndexConnParams{Host: idx.Host, Namespace: namespace})
+	if err != nil {
+		return nil, nil, nil, errors.External("Pinecone", "failed to connect to index", err)
+	}
+	if cached != nil {
+		logger.Info("Pinecone index %s moved to %s, reconnecting", s.indexName, idx.Host)
+		cached.retired = true
+		s.closeIfUnused(cached)
+	}
+	fresh := &pineconeConn{index: idx, conn: conn, checked: time.Now(), users: 1}
+	s.conns[namespace] = fresh
+	return idx, conn, s.releaser(fresh), nil
+}
+
+// cachedConn returns namespace's connection, counting the caller as a user,
+// while it is fresh, or nil
+func (s *PineconeStore) cachedConn(namespace string) *pineconeConn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached := s.conns[namespace]
+	if cached == nil || time.Since(cached.checked) >= pineconeConnTTL {
+		return nil
+	}
+	cached.users++
+	return cached
+}
+
+// releaser returns a func that gives up one use of c, once
+func (s *PineconeStore) releaser(c *pineconeConn) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			c.users--
+			s.closeIfUnused(c)
+		})
+	}
+}
+
+// closeIfUnused closes a retired connection no call holds; s.mu must be held
+func (s *PineconeStore) closeIfUnused(c *pineconeConn) {
+	if c.retired && c.users == 0 && !c.closed {
+		c.closed = true
+		_ = c.conn.Close()
+	}
+}
+
+// invalidate makes the next call for namespace describe the index again, in
+// case a failure was caused by it having been deleted or moved
+func (s *PineconeStore) invalidate(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached := s.conns[namespace]; cached != nil {
+		cached.checked = time.Time{}
+	}
+}
+
+// pineconeIndexPoll is how often EnsureIndex checks whether a new index is ready
+const pineconeIndexPoll = 5 * time.Second
+
+// EnsureIndex creates the serverless index with the configured dimension,
+// metric, cloud, and region if it does not exist, and waits until it is
+// ready. An existing index must have the configured dimension.
+func (s *PineconeStore) EnsureIndex(ctx context.Context) (bool, error) {
+	idx, err := s.client.DescribeIndex(ctx, s.indexName)
+	if err == nil {
+		if int(idx.Dimension) != s.dimension {
+			return false, errors.Validation(fmt.Sprintf("Pinecone index %s has dimension %d, PINECONE_DIMENSION is %d",
+				s.indexName, idx.Dimension, s.dimension))
+		}
+		return false, nil
+	}
+	if !isPineconeNotFound(err) {
+		return false, errors.External("Pinecone", "failed to describe index", err)
+	}
+
+	logger.Info("Creating Pinecone index %s (dimension %d, metric %s, %s %s)",
+		s.indexName, s.dimension, s.metric, s.cloud, s.region)
+	_, err = s.client.CreateServerlessIndex(ctx, &pinecone.CreateServerlessIndexRequest{
+		Name:      s.indexName,
+		Dimension: int32(s.dimension),
+		Metric:    pinecone.IndexMetric(s.metric),
+		Cloud:     pinecone.Cloud(s.cloud),
+		Region:    s.region,
+	})
+	if err != nil {
+		return false, errors.External("Pinecone", "failed to create index", err)
+	}
+
+	ticker := time.NewTicker(pineconeIndexPoll)
+	defer ticker.Stop()
+	for {
+		idx, err := s.client.DescribeIndex(ctx, s.indexName)
+		if err == nil && idx.Status != nil && idx.Status.Ready {
+			logger.Info("Pinecone index %s is ready", s.indexName)
+			return true, nil
+		}
+		select {
+		case <-ctx.Done():
+			return true, errors.External("Pinecone", "index created but not ready", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// isPineconeNotFound reports whether err is Pinecone's 404
+func isPineconeNotFound(err error) bool {
+	var pcErr *pinecone.PineconeError
+	return stderrors.As(err, &pcErr) && pcErr.Code == http.StatusNotFound
+}
+
+// UpsertVectors inserts or updates vectors
+func (s *PineconeStore) UpsertVectors(ctx context.Context, embeddings []*models.Embedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+
+	// Determine namespace
+	namespace := ""
+	if len(embeddings) > 0 && embeddings[0].Namespace != "" {
+		namespace = embeddings[0].Namespace
+	}
+
+	// Convert to Pinecone vectors
+	vectors := make([]*pinecone.Vector, len(embeddings))
+	for i, emb := range embeddings {
+		// Convert metadata to structpb.Struct
+		metadataMap := make(map[string]interface{})
+		for k, v := range emb.Metadata {
+			metadataMap[k] = v
+		}
+		metadata, err := structpb.NewStruct(metadataMap)
+		if err != nil {
+			return errors.Internal("failed to convert metadata", err)
+		}
+
+		vectors[i] = &pinecone.Vector{
+			Id:           emb.ID,
+			Values:       emb.Vector,
+			SparseValues: pineconeSparse(emb.Sparse),
+			Metadata:     metadata,
+		}
+	}
+
+	// Get index connection
+	_, idxConnection, release, err := s.connect(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Upsert vectors (namespace is set on the connection)
+	_, err = idxConnection.UpsertVectors(ctx, vectors)
+	if err != nil {
+		s.invalidate(namespace)
+		return errors.External("Pinecone", "failed to upsert vectors", err)
+	}
+
+	logger.Info("Upserted %d vectors to namespace '%s'", len(vectors), namespace)
+	return nil
+}
+
+// DeleteVectors removes vectors by IDs
+func (s *PineconeStore) DeleteVectors(ctx context.Context, ids []string, namespace string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, idxConnection, release, err := s.connect(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for start := 0; start < len(ids); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(ids))
+		if err := idxConnection.DeleteVectorsById(ctx, ids[start:end]); err != nil {
+			s.invalidate(namespace)
+			return errors.External("Pinecone", "failed to delete vectors", err)
+		}
+	}
+
+	logger.Info("Deleted %d vectors from namespace '%s'", len(ids), namespace)
+	return nil
+}
+
+// DeleteByFilter removes the vectors whose metadata match filter. Pod
+// indexes delete by filter directly; serverless indexes don't support it, so
+// matching IDs are found by filtered queries and deleted in rounds.
+func (s *PineconeStore) DeleteByFilter(ctx context.Context, filter models.MetadataFilter, namespace string) error {
+	metadataFilter, err := pineconeFilter(filter)
+	if err != nil {
+		return err
+	}
+	if metadataFilter == nil {
+		return errors.Validation("filter is required")
+	}
+
+	idx, idxConnection, release, err := s.connect(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if idx.Spec == nil || idx.Spec.Serverless == nil {
+		if err := idxConnection.DeleteVectorsByFilter(ctx, metadataFilter); err != nil {
+			s.invalidate(namespace)
+			return errors.External("Pinecone", "failed to delete vectors by filter", err)
+		}
+		logger.Info("Deleted vectors matching %v from namespace '%s'", filter, namespace)
+		return nil
+	}
+
+	// Any vector works as the probe; the filter decides what matches. Deletes
+	// show in queries only eventually, so stop once a round finds nothing new.
+	probe := make([]float32, idx.Dimension)
+	if len(probe) > 0 {
+		probe[0] = 1
+	}
+	deleted := make(map[string]bool)
+	for {
+		resp, err := idxConnection.QueryByVectorValues(ctx, &pinecone.QueryByVectorValuesRequest{
+			Vector:         probe,
+			TopK:           filterDeleteBatch,
+			MetadataFilter: metadataFilter,
+		})
+		if err != nil {
+			s.invalidate(namespace)
+			return errors.External("Pinecone", "failed to query vectors to delete", err)
+		}
+
+		var ids []string
+		for _, match := range resp.Matches {
+			if match != nil && match.Vector != nil && !deleted[match.Vector.Id] {
+				ids = append(ids, match.Vector.Id)
+				deleted[match.Vector.Id] = true
+			}
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if err := idxConnection.DeleteVectorsById(ctx, ids); err != nil {
+			s.invalidate(namespace)
+			return errors.External("Pinecone", "failed to delete vectors", err)
+		}
+	}
+
+	logger.Info("Deleted %d vectors matching %v from namespace '%s'", len(deleted), filter, namespace)
+	return nil
+}
+
+// DeleteNamespace removes every vector in the namespace
+func (s *PineconeStore) DeleteNamespace(ctx context.Context, namespace string) error {
+	_, idxConnection, release, err := s.connect(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := idxConnection.DeleteAllVectorsInNamespace(ctx); err != nil {
+		s.invalidate(namespace)
+		return errors.External("Pinecone", "failed to delete namespace", err)
+	}
+
+	logger.Info("Deleted all vectors in namespace '%s'", namespace)
+	return nil
+}
+
+const (
+	// Pinecone fetches are sent as query parameters, so look IDs up in batches
+	fetchBatchSize = 100
+	// Most IDs Pinecone deletes per request
+	deleteBatchSize = 1000
+	// Matches per query when deleting by filter from a serverless index
+	filterDeleteBatch = 1000
+)
+
+// pineconeFilter translates filter to Pinecone's $eq and $in operators,
+// or is nil for an empty filter
+func pineconeFilter(filter models.MetadataFilter) (*pinecone.MetadataFilter, error) {
+	if len(filter) == 0 {
+		return nil, nil
+	}
+	conditions := make(map[string]interface{}, len(filter))
+	for k, values := range filter {
+		if len(values) == 1 {
+			conditions[k] = map[string]interface{}{"$eq": values[0]}
+			continue
+		}
+		in := make([]interface{}, len(values))
+		for i, v := range values {
+			in[i] = v
+		}
+		conditions[k] = map[string]interface{}{"$in": in}
+	}
+	f, err := structpb.NewStruct(conditions)
+	if err != nil {
+		return nil, errors.Validation(fmt.Sprintf("invalid filter: %v", err))
+	}
+	return f, nil
+}
+
+// ExistingIDs returns the subset of ids already stored in the namespace
+func (s *PineconeStore) ExistingIDs(ctx context.Context, ids []string, namespace string) ([]string, error) {
+	existing := []string{}
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	_, idxConnection, release, err := s.connect(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	for start := 0; start < len(ids); start += fetchBatchSize {
+		end := start + fetchBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		resp, err := idxConnection.FetchVectors(ctx, ids[start:end])
+		if err != nil {
+			s.invalidate(namespace)
+			return nil, errors.External("Pinecone", "failed to fetch vectors", err)
+		}
+		for _, id := range ids[start:end] {
+			if _, ok := resp.Vectors[id]; ok {
+				existing = append(existing, id)
+			}
+		}
+	}
+
+	return existing, nil
+}
+
+// Fetch returns stored vectors by ID, in batches of fetchBatchSize
+func (s *PineconeStore) Fetch(ctx context.Context, ids []string, namespace string) ([]StoredVector, error) {
+	if len(ids) == 0 {
+		return []StoredVector{}, nil
+	}
+
+	_, idxConnection, release, err := s.connect(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	found := make(map[string]StoredVector, len(ids))
+	for start := 0; start < len(ids); start += fetchBatchSize {
+		end := min(start+fetchBatchSize, len(ids))
+
+		resp, err := idxConnection.FetchVectors(ctx, ids[start:end])
+		if err != nil {
+			s.invalidate(namespace)
+			return nil, errors.External("Pinecone", "failed to fetch vectors", err)
+		}
+		for id, v := range resp.Vectors {
+			if v == nil {
+				continue
+			}
+			stored := StoredVector{ID: id, Vector: v.Values, Metadata: pineconeMetadata(v.Metadata)}
+			if v.SparseValues != nil {
+				stored.Sparse = &models.SparseVector{Indices: v.SparseValues.Indices, Values: v.SparseValues.Values}
+			}
+			found[id] = stored
+		}
+	}
+	return inOrder(ids, found), nil
+}
+
+// pineconeListLimit is the most IDs Pinecone lists per page
+const pineconeListLimit = 100
+
+// Scan lists a page of vector IDs and fetches them. Pinecone lists vectors
+// of serverless indexes only.
+func (s *PineconeStore) Scan(ctx context.Context, namespace, cursor string, limit int) ([]StoredVector, string, error) {
+	_, idxConnection, release, err := s.connect(ctx, namespace)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
+	pageSize := uint32(min(limit, pineconeListLimit))
+	req := &pinecone.ListVectorsRequest{Limit: &pageSize}
+	if cursor != "" {
+		req.PaginationToken = &cursor
+	}
+	resp, err := idxConnection.ListVectors(ctx, req)
+	if err != nil {
+		s.invalidate(namespace)
+		return nil, "", errors.External("Pinecone", "failed to list vectors", err)
+	}
+
+	ids := make([]string, 0, len(resp.VectorIds))
+	for _, id := range resp.VectorIds {
+		if id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	vectors, err := s.Fetch(ctx, ids, namespace)
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if resp.NextPaginationToken != nil {
+		next = *resp.NextPaginationToken
+	}
+	return vectors, next, nil
+}
+
+// pineconeMetadata converts metadata to strings, formatting other values
+func pineconeMetadata(md *pinecone.Metadata) map[string]string {
+	metadata := make(map[string]string)
+	if md == nil {
+		return metadata
+	}
+	for k, v := range md.AsMap() {
+		if strVal, ok := v.(string); ok {
+			metadata[k] = strVal
+		} else {
+			metadata[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	return metadata
+}
+
+// QueryVectors searches for similar vectors
+func (s *PineconeStore) QueryVectors(ctx context.Context, vector []float32, topK int, namespace string, filter models.MetadataFilter) ([]*models.Embedding, error) {
+	matches, err := s.Query(ctx, VectorQuery{Vector: vector, TopK: topK, Namespace: namespace, Filter: filter, IncludeVectors: true})
+	if err != nil {
+		return nil, err
+	}
+	return matchEmbeddings(matches, namespace), nil
+}
+
+// Query searches for similar vectors, filtering on metadata with $eq
+func (s *PineconeStore) Query(ctx context.Context, q VectorQuery) ([]Match, error) {
+	_, idxConnection, release, err := s.connect(ctx, q.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	filter, err := pineconeFilter(q.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	queryResp, err := idxConnection.QueryByVectorValues(ctx, &pinecone.QueryByVectorValuesRequest{
+		Vector:          q.Vector,
+		TopK:            uint32(q.TopK),
+		MetadataFilter:  filter,
+		IncludeMetadata: true,
+		IncludeValues:   q.IncludeVectors,
+		SparseValues:    pineconeSparse(q.Sparse),
+	})
+	if err != nil {
+		s.invalidate(q.Namespace)
+		return nil, errors.External("Pinecone", "failed to query vectors", err)
+	}
+
+	// Convert results
+	matches := make([]Match, 0, len(queryResp.Matches))
+	for _, match := range queryResp.Matches {
+		if match == nil || match.Vector == nil {
+			continue
+		}
+		matches = append(matches, Match{
+			ID:       match.Vector.Id,
+			Score:    match.Score,
+			Metadata: pineconeMetadata(match.Vector.Metadata),
+			Vector:   match.Vector.Values,
+		})
+	}
+
+	return matches, nil
+}
+
+// pineconeSparse converts a sparse vector, nil if it has no terms
+func pineconeSparse(sv *models.SparseVector) *pinecone.SparseValues {
+	if sv == nil || len(sv.Indices) == 0 {
+		return nil
+	}
+	return &pinecone.SparseValues{Indices: sv.Indices, Values: sv.Values}
+}
+
+// DescribeIndex gets index statistics
+func (s *PineconeStore) DescribeIndex(ctx context.Context) (map[string]interface{}, error) {
+	idx, err := s.client.DescribeIndex(ctx, s.indexName)
+	if err != nil {
+		return nil, errors.External("Pinecone", "failed to describe index", err)
+	}
+
+	stats := map[string]interface{}{
+		"name":      idx.Name,
+		"dimension": idx.Dimension,
+		"metric":    idx.Metric,
+		"host":      idx.Host,
+		"status":    idx.Status.State,
+	}
+
+	return stats, nil
+}
+
+// Stats counts vectors with DescribeIndexStats
+func (s *PineconeStore) Stats(ctx context.Context) (*models.IndexStats, error) {
+	_, idxConnection, release, err := s.connect(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err := idxConnection.DescribeIndexStats(ctx)
+	if err != nil {
+		s.invalidate("")
+		return nil, errors.External("Pinecone", "failed to describe index stats", err)
+	}
+
+	stats := &models.IndexStats{
+		Backend:      BackendPinecone,
+		Dimension:    int(resp.Dimension),
+		TotalVectors: int64(resp.TotalVectorCount),
+		Namespaces:   make(map[string]int64, len(resp.Namespaces)),
+		Fullness:     &resp.IndexFullness,
+	}
+	for name, summary := range resp.Namespaces {
+		if summary != nil {
+			stats.Namespaces[name] = int64(summary.VectorCount)
+		}
+	}
+	return stats, nil
+}
+
+// Dimension is the configured vector length
+func (s *PineconeStore) Dimension() int {
+	return s.dimension
+}
+
+// Health checks the connection health
+func (s *PineconeStore) Health(ctx context.Context) error {
+	_, err := s.client.DescribeIndex(ctx, s.indexName)
+	return err
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pinecone-io/go-pinecone/pinecone"
+)
+
+func TestIsPineconeNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "missing index", err: &pinecone.PineconeError{Code: http.StatusNotFound, Msg: errors.New("not found")}, want: true},
+		{name: "wrapped missing index", err: fmt.Errorf("describe: %w", &pinecone.PineconeError{Code: http.StatusNotFound}), want: true},
+		{name: "other status", err: &pinecone.PineconeError{Code: http.StatusUnauthorized, Msg: errors.New("bad key")}},
+		{name: "network error", err: errors.New("connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPineconeNotFound(tt.err); got != tt.want {
+				t.Errorf("isPineconeNotFound(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// newDescribedPinecone returns a store whose control plane describes index
+// docs at the host held by host, after delay; the store counts the calls
+func newDescribedPinecone(t *testing.T, host *atomic.Value, delay time.Duration) (*PineconeStore, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": "docs", "dimension": 3, "metric": "cosine", "host": host.Load(),
+			"spec":   map[string]interface{}{"serverless": map[string]string{"cloud": "aws", "region": "us-east-1"}},
+			"status": map[string]interface{}{"ready": true, "state": "Ready"},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := pinecone.NewClient(pinecone.NewClientParams{ApiKey: "test", Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &PineconeStore{client: client, indexName: "docs", dimension: 3, conns: make(map[string]*pineconeConn)}, &calls
+}
+
+func TestPineconeConnect(t *testing.T) {
+	var host atomic.Value
+	host.Store("docs-1.svc.pinecone.io")
+	const delay = 200 * time.Millisecond
+	s, calls := newDescribedPinecone(t, &host, delay)
+	ctx := context.Background()
+
+	// Namespaces are described in parallel rather than one after another
+	// under the lock
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(namespace string) {
+			defer wg.Done()
+			_, _, release, err := s.connect(ctx, namespace)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			release()
+		}(fmt.Sprintf("ns%d", i))
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed > 3*delay {
+		t.Errorf("connecting 5 namespaces took %v, want them described concurrently", elapsed)
+	}
+
+	// A fresh entry is reused without describing the index
+	_, first, release, err := s.connect(ctx, "ns0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	before := calls.Load()
+	_, again, release, _ := s.connect(ctx, "ns0")
+	if again != first || calls.Load() != before {
+		t.Error("fresh connection wasn't reused")
+	}
+	release()
+
+	// After invalidation the same host keeps the connection and a new one
+	// replaces it
+	s.invalidate("ns0")
+	_, again, release, _ = s.connect(ctx, "ns0")
+	if again != first {
+		t.Error("connection replaced though the host didn't change")
+	}
+
+	// The call still holding the old connection can finish with it
+	old := s.conns["ns0"]
+	host.Store("docs-2.svc.pinecone.io")
+	s.invalidate("ns0")
+	idx, moved, releaseMoved, err := s.connect(ctx, "ns0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseMoved()
+	if moved == first || idx.Host != "docs-2.svc.pinecone.io" {
+		t.Errorf("index moved to %s but connection wasn't replaced", idx.Host)
+	}
+	if old.closed {
+		t.Error("replaced connection closed while a call still held it")
+	}
+	release()
+	release() // releasing twice counts once
+	if !old.closed || old.users != 0 {
+		t.Errorf("replaced connection closed = %v with %d users after its last call released it", old.closed, old.users)
+	}
+}
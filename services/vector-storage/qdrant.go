@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+const (
+	// Payload field holding a point's vector ID; Qdrant point IDs must be
+	// integers or UUIDs, so points are stored under a UUID derived from it
+	qdrantIDField = "vector_id"
+	// Points per upsert request, keeping requests well under Qdrant's body limit
+	qdrantUpsertBatch = 256
+)
+
+// QdrantStore implements interfaces.VectorStore with Qdrant's REST API.
+// Each namespace is a collection, created with cosine distance on first
+// upsert, and vector metadata is the point payload.
+type QdrantStore struct {
+	baseURL    string
+	apiKey     string
+	collection string // for vectors without a namespace
+	dimension  int
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	ready map[string]bool // collections known to exist
+}
+
+func init() {
+	registerBackend(BackendQdrant, func(_ context.Context, cfg *config.Config) (vectorStore, error) {
+		q := cfg.VectorStore.Qdrant
+		return NewQdrantStore(q.URL, q.APIKey, q.Collection, q.Dimension), nil
+	})
+}
+
+// NewQdrantStore creates a store for the Qdrant server at baseURL
+func NewQdrantStore(baseURL, apiKey, collection string, dimension int) *QdrantStore {
+	return &QdrantStore{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		collection: collection,
+		dimension:  dimension,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		ready:      make(map[string]bool),
+	}
+}
+
+func (s *QdrantStore) collectionFor(namespace string) string {
+	if namespace == "" {
+		return s.collection
+	}
+	return namespace
+}
+
+type qdrantPoint struct {
+	ID      string                 `json:"id"`
+	Vector  []float32              `json:"vector,omitempty"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+	Score   float32                `json:"score,omitempty"`
+}
+
+// errQdrantNotFound is returned by do for a 404, which for most calls means
+// the collection doesn't exist yet
+var errQdrantNotFound = errors.NotFound("Qdrant collection")
+
+// do sends a JSON request and decodes the result field of the response
+// into out, if given
+func (s *QdrantStore) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return errors.Internal("failed to encode Qdrant request", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reader)
+	if err != nil {
+		return errors.Internal("failed to build Qdrant request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("api-key", s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return errors.Network("Qdrant request failed", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errQdrantNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return errors.External("Qdrant", fmt.Sprintf("%s %s failed", method, path), fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	envelope := struct {
+		Result interface{} `json:"result"`
+	}{Result: out}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return errors.External("Qdrant", "failed to decode response", err)
+	}
+	return nil
+}
+
+// ensureCollection creates the collection if it doesn't exist
+func (s *QdrantStore) ensureCollection(ctx context.Context, collection string, dimension int) error {
+	s.mu.Lock()
+	ready := s.ready[collection]
+	s.mu.Unlock()
+	if ready {
+		return nil
+	}
+
+	path := "/collections/" + url.PathEscape(collection)
+	err := s.do(ctx, http.MethodGet, path, nil, nil)
+	if err == errQdrantNotFound {
+		err = s.do(ctx, http.MethodPut, path, map[string]interface{}{
+			"vectors": map[string]interface{}{"size": dimension, "distance": "Cosine"},
+		}, nil)
+		if err == nil {
+			logger.Info("Created Qdrant collection '%s' (%d dimensions)", collection, dimension)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.ready[collection] = true
+	s.mu.Unlock()
+	return nil
+}
+
+// UpsertVectors inserts or updates vectors
+func (s *QdrantStore) UpsertVectors(ctx context.Context, embeddings []*models.Embedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+
+	namespace := embeddings[0].Namespace
+	collection := s.collectionFor(namespace)
+	dimension := s.dimension
+	if dimension <= 0 {
+		dimension = len(embeddings[0].Vector)
+	}
+	if err := s.ensureCollection(ctx, collection, dimension); err != nil {
+		return err
+	}
+
+	points := make([]qdrantPoint, len(embeddings))
+	for i, emb := range embeddings {
+		payload := make(map[string]interface{}, len(emb.Metadata)+1)
+		for k, v := range emb.Metadata {
+			payload[k] = v
+		}
+		payload[qdrantIDField] = emb.ID
+		points[i] = qdrantPoint{ID: nameUUID(emb.ID), Vector: emb.Vector, Payload: payload}
+	}
+
+	path := "/collections/" + url.PathEscape(collection) + "/points?wait=true"
+	for start := 0; start < len(points); start += qdrantUpsertBatch {
+		end := min(start+qdrantUpsertBatch, len(points))
+		if err := s.do(ctx, http.MethodPut, path, map[string]interface{}{"points": points[start:end]}, nil); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Upserted %d vectors to collection '%s'", len(points), collection)
+	return nil
+}
+
+// DeleteVectors removes vectors by IDs
+func (s *QdrantStore) DeleteVectors(ctx context.Context, ids []string, namespace string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	pointIDs := make([]string, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = nameUUID(id)
+	}
+	collection := s.collectionFor(namespace)
+	err := s.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(collection)+"/points/delete?wait=true",
+		map[string]interface{}{"points": pointIDs}, nil)
+	if err == errQdrantNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Deleted %d vectors from collection '%s'", len(ids), collection)
+	return nil
+}
+
+// qdrantFilter translates filter to Qdrant's must conditions, matching one
+// value or any of several
+func qdrantFilter(filter models.MetadataFilter) map[string]interface{} {
+	must := make([]map[string]interface{}, 0, len(filter))
+	for k, values := range filter {
+		match := map[string]interface{}{"any": values}
+		if len(values) == 1 {
+			match = map[string]interface{}{"value": values[0]}
+		}
+		must = append(must, map[string]interface{}{"key": k, "match": match})
+	}
+	return map[string]interface{}{"must": must}
+}
+
+// DeleteByFilter removes the vectors whose payload match filter
+func (s *QdrantStore) DeleteByFilter(ctx context.Context, filter models.MetadataFilter, namespace string) error {
+	if len(filter) == 0 {
+		return errors.Validation("filter is required")
+	}
+
+	collection := s.collectionFor(namespace)
+	err := s.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(collection)+"/points/delete?wait=true",
+		map[string]interface{}{"filter": qdrantFilter(filter)}, nil)
+	if err == errQdrantNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Deleted vectors matching %v from collection '%s'", filter, collection)
+	return nil
+}
+
+// DeleteNamespace drops the namespace's collection; the next upsert
+// creates it again
+func (s *QdrantStore) DeleteNamespace(ctx context.Context, namespace string) error {
+	collection := s.collectionFor(namespace)
+	err := s.do(ctx, http.MethodDelete, "/collections/"+url.PathEscape(collection), nil, nil)
+	if err != nil && err != errQdrantNotFound {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.ready, collection)
+	s.mu.Unlock()
+	logger.Info("Deleted collection '%s'", collection)
+	return nil
+}
+
+// ExistingIDs returns the subset of ids already stored in the namespace
+func (s *QdrantStore) ExistingIDs(ctx context.Context, ids []string, namespace string) ([]string, error) {
+	existing := []string{}
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	byPoint := make(map[string]string, len(ids))
+	pointIDs := make([]string, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = nameUUID(id)
+		byPoint[pointIDs[i]] = id
+	}
+
+	var points []qdrantPoint
+	err := s.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(s.collectionFor(namespace))+"/points",
+		map[string]interface{}{"ids": pointIDs, "with_payload": false, "with_vector": false}, &points)
+	if err == errQdrantNotFound {
+		return existing, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range points {
+		if id, ok := byPoint[p.ID]; ok {
+			existing = append(existing, id)
+		}
+	}
+	return existing, nil
+}
+
+// Fetch returns stored points by vector ID, with payload and vector
+func (s *QdrantStore) Fetch(ctx context.Context, ids []string, namespace string) ([]StoredVector, error) {
+	if len(ids) == 0 {
+		return []StoredVector{}, nil
+	}
+
+	pointIDs := make([]string, len(ids))
+	for i, id := range ids {
+		pointIDs[i] = nameUUID(id)
+	}
+
+	var points []qdrantPoint
+	err := s.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(s.collectionFor(namespace))+"/points",
+		map[string]interface{}{"ids": pointIDs, "with_payload": true, "with_vector": true}, &points)
+	if err == errQdrantNotFound {
+		return []StoredVector{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]StoredVector, len(points))
+	for _, p := range points {
+		id, metadata := qdrantPayload(p)
+		found[id] = StoredVector{ID: id, Vector: p.Vector, Metadata: metadata}
+	}
+	return inOrder(ids, found), nil
+}
+
+// Scan scrolls through the namespace's collection; the cursor is the point
+// ID the next page starts at
+func (s *QdrantStore) Scan(ctx context.Context, namespace, cursor string, limit int) ([]StoredVector, string, error) {
+	scroll := map[string]interface{}{"limit": limit, "with_payload": true, "with_vector": true}
+	if cursor != "" {
+		scroll["offset"] = cursor
+	}
+
+	var page struct {
+		Points []qdrantPoint `json:"points"`
+		Next   interface{}   `json:"next_page_offset"` // a UUID, an integer, or null
+	}
+	err := s.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(s.collectionFor(namespace))+"/points/scroll", scroll, &page)
+	if err == errQdrantNotFound {
+		return []StoredVector{}, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	vectors := make([]StoredVector, len(page.Points))
+	for i, p := range page.Points {
+		id, metadata := qdrantPayload(p)
+		vectors[i] = StoredVector{ID: id, Vector: p.Vector, Metadata: metadata}
+	}
+	next := ""
+	if page.Next != nil {
+		next = fmt.Sprintf("%v", page.Next)
+	}
+	return vectors, next, nil
+}
+
+// QueryVectors searches for similar vectors
+func (s *QdrantStore) QueryVectors(ctx context.Context, vector []float32, topK int, namespace string, filter models.MetadataFilter) ([]*models.Embedding, error) {
+	matches, err := s.Query(ctx, VectorQuery{Vector: vector, TopK: topK, Namespace: namespace, Filter: filter, IncludeVectors: true})
+	if err != nil {
+		return nil, err
+	}
+	return matchEmbeddings(matches, namespace), nil
+}
+
+// Query searches for similar vectors, filtering on payload values
+func (s *QdrantStore) Query(ctx context.Context, q VectorQuery) ([]Match, error) {
+	if q.Sparse != nil {
+		return nil, errors.Validation("hybrid search is not supported by the qdrant backend")
+	}
+	search := map[string]interface{}{
+		"vector":       q.Vector,
+		"limit":        q.TopK,
+		"with_payload": true,
+		"with_vector":  q.IncludeVectors,
+	}
+	if len(q.Filter) > 0 {
+		search["filter"] = qdrantFilter(q.Filter)
+	}
+
+	var points []qdrantPoint
+	err := s.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(s.collectionFor(q.Namespace))+"/points/search", search, &points)
+	if err == errQdrantNotFound {
+		return []Match{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]Match, len(points))
+	for i, p := range points {
+		id, metadata := qdrantPayload(p)
+		matches[i] = Match{ID: id, Score: p.Score, Metadata: metadata, Vector: p.Vector}
+	}
+	return matches, nil
+}
+
+// qdrantPayload splits a point's payload into its vector ID and metadata
+func qdrantPayload(p qdrantPoint) (string, map[string]string) {
+	id := p.ID
+	metadata := make(map[string]string, len(p.Payload))
+	for k, v := range p.Payload {
+		strVal, ok := v.(string)
+		if !ok {
+			strVal = fmt.Sprintf("%v", v)
+		}
+		if k == qdrantIDField {
+			id = strVal
+			continue
+		}
+		metadata[k] = strVal
+	}
+	return id, metadata
+}
+
+// DescribeIndex gets index statistics
+func (s *QdrantStore) DescribeIndex(ctx context.Context) (map[string]interface{}, error) {
+	var result struct {
+		Collections []struct {
+			Name string `json:"name"`
+		} `json:"collections"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/collections", nil, &result); err != nil {
+		return nil, err
+	}
+
+	collections := make([]string, len(result.Collections))
+	for i, c := range result.Collections {
+		collections[i] = c.Name
+	}
+	return map[string]interface{}{
+		"name":        s.collection,
+		"dimension":   s.dimension,
+		"metric":      "cosine",
+		"host":        s.baseURL,
+		"collections": collections,
+	}, nil
+}
+
+// Stats counts the points of every collection exactly; the default
+// collection is reported as namespace ""
+func (s *QdrantStore) Stats(ctx context.Context) (*models.IndexStats, error) {
+	var result struct {
+		Collections []struct {
+			Name string `json:"name"`
+		} `json:"collections"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/collections", nil, &result); err != nil {
+		return nil, err
+	}
+
+	stats := &models.IndexStats{
+		Backend:    BackendQdrant,
+		Dimension:  s.dimension,
+		Namespaces: make(map[string]int64, len(result.Collections)),
+	}
+	for _, c := range result.Collections {
+		var count struct {
+			Count int64 `json:"count"`
+		}
+		err := s.do(ctx, http.MethodPost, "/collections/"+url.PathEscape(c.Name)+"/points/count", map[string]bool{"exact": true}, &count)
+		if err == errQdrantNotFound {
+			continue // dropped meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		namespace := c.Name
+		if namespace == s.collection {
+			namespace = ""
+		}
+		stats.Namespaces[namespace] = count.Count
+		stats.TotalVectors += count.Count
+	}
+	return stats, nil
+}
+
+// Dimension is the configured vector length
+func (s *QdrantStore) Dimension() int {
+	return s.dimension
+}
+
+// Health checks the connection health
+func (s *QdrantStore) Health(ctx context.Context) error {
+	return s.do(ctx, http.MethodGet, "/collections", nil, nil)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestQdrantStats(t *testing.T) {
+	counts := map[string]int64{"reposync": 3, "acme": 40}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/collections":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"result": map[string]interface{}{
+					"collections": []map[string]string{{"name": "reposync"}, {"name": "acme"}, {"name": "dropped"}},
+				},
+			})
+		case r.Method == http.MethodPost:
+			var name string
+			for n := range counts {
+				if r.URL.Path == "/collections/"+n+"/points/count" {
+					name = n
+				}
+			}
+			if name == "" {
+				http.NotFound(w, r)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]int64{"count": counts[name]}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	stats, err := NewQdrantStore(server.URL, "", "reposync", 768).Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats() error: %v", err)
+	}
+
+	if stats.TotalVectors != 43 {
+		t.Errorf("TotalVectors = %d, want 43", stats.TotalVectors)
+	}
+	if want := map[string]int64{"": 3, "acme": 40}; !reflect.DeepEqual(stats.Namespaces, want) {
+		t.Errorf("Namespaces = %v, want %v", stats.Namespaces, want)
+	}
+	if stats.Backend != BackendQdrant || stats.Dimension != 768 || stats.Fullness != nil {
+		t.Errorf("stats = %+v, want qdrant, dimension 768, no fullness", stats)
+	}
+}
+
+func TestQdrantFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/collections/acme/points" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if len(req.IDs) != 3 || req.IDs[0] != nameUUID("b") {
+			t.Errorf("point IDs = %v, want the UUIDs of b, a, gone", req.IDs)
+		}
+		// Stored points come back in any order, without the missing one
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"result": []map[string]interface{}{
+				{"id": nameUUID("a"), "vector": []float32{1, 0}, "payload": map[string]interface{}{qdrantIDField: "a", "repository": "org/x"}},
+				{"id": nameUUID("b"), "vector": []float32{0, 1}, "payload": map[string]interface{}{qdrantIDField: "b", "chunk_index": 2}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	got, err := NewQdrantStore(server.URL, "", "reposync", 2).Fetch(context.Background(), []string{"b", "a", "gone"}, "acme")
+	if err != nil {
+		t.Fatalf("Fetch() error: %v", err)
+	}
+
+	want := []StoredVector{
+		{ID: "b", Vector: []float32{0, 1}, Metadata: map[string]string{"chunk_index": "2"}},
+		{ID: "a", Vector: []float32{1, 0}, Metadata: map[string]string{"repository": "org/x"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Fetch() = %+v, want %+v", got, want)
+	}
+}
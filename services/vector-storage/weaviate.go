@@ -0,0 +1,683 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/config"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/logger"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+const (
+	// Property holding an object's vector ID; Weaviate object IDs must be
+	// UUIDs, so objects are stored under a UUID derived from it
+	weaviateIDProperty = "vectorId"
+	// Objects per batch request
+	weaviateBatchSize = 100
+)
+
+// Characters Weaviate doesn't allow in class and property names
+var weaviateNameRe = regexp.MustCompile(`[^0-9A-Za-z_]`)
+
+// WeaviateStore implements interfaces.VectorStore with Weaviate's REST and
+// GraphQL APIs. Each project namespace is a class, created without a
+// vectorizer and with cosine distance on first upsert, and vector metadata
+// are the object's properties.
+type WeaviateStore struct {
+	baseURL    string
+	apiKey     string
+	class      string // for vectors without a namespace
+	dimension  int
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	ready map[string]bool // classes known to exist
+}
+
+func init() {
+	registerBackend(BackendWeaviate, func(_ context.Context, cfg *config.Config) (vectorStore, error) {
+		wv := cfg.VectorStore.Weaviate
+		return NewWeaviateStore(wv.URL, wv.APIKey, wv.Class, wv.Dimension), nil
+	})
+}
+
+// NewWeaviateStore creates a store for the Weaviate server at baseURL
+func NewWeaviateStore(baseURL, apiKey, class string, dimension int) *WeaviateStore {
+	return &WeaviateStore{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		class:      weaviateClassName(class),
+		dimension:  dimension,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		ready:      make(map[string]bool),
+	}
+}
+
+// weaviateClassName turns a namespace into a valid class name: letters,
+// digits and underscores, starting with a capital letter
+func weaviateClassName(name string) string {
+	name = weaviateNameRe.ReplaceAllString(name, "_")
+	if name == "" || !(name[0] >= 'A' && name[0] <= 'Z' || name[0] >= 'a' && name[0] <= 'z') {
+		name = "P" + name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// weaviatePropertyName turns a metadata key into a valid property name
+func weaviatePropertyName(key string) string {
+	key = weaviateNameRe.ReplaceAllString(key, "_")
+	if key == "" || key[0] >= '0' && key[0] <= '9' {
+		key = "_" + key
+	}
+	return key
+}
+
+func (s *WeaviateStore) classFor(namespace string) string {
+	if namespace == "" {
+		return s.class
+	}
+	return weaviateClassName(namespace)
+}
+
+// errWeaviateNotFound is returned by do for a 404, which for most calls
+// means the class doesn't exist yet
+var errWeaviateNotFound = errors.NotFound("Weaviate class")
+
+// do sends a JSON request and decodes the response into out, if given
+func (s *WeaviateStore) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return errors.Internal("failed to encode Weaviate request", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, reader)
+	if err != nil {
+		return errors.Internal("failed to build Weaviate request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return errors.Network("Weaviate request failed", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errWeaviateNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return errors.External("Weaviate", fmt.Sprintf("%s %s failed", method, path), fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.External("Weaviate", "failed to decode response", err)
+	}
+	return nil
+}
+
+type weaviateClass struct {
+	Class      string `json:"class"`
+	Properties []struct {
+		Name string `json:"name"`
+	} `json:"properties"`
+}
+
+// getClass returns a class's schema, or errWeaviateNotFound
+func (s *WeaviateStore) getClass(ctx context.Context, class string) (*weaviateClass, error) {
+	var schema weaviateClass
+	if err := s.do(ctx, http.MethodGet, "/v1/schema/"+url.PathEscape(class), nil, &schema); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// ensureClass creates the class if it doesn't exist
+func (s *WeaviateStore) ensureClass(ctx context.Context, class string) error {
+	s.mu.Lock()
+	ready := s.ready[class]
+	s.mu.Unlock()
+	if ready {
+		return nil
+	}
+
+	_, err := s.getClass(ctx, class)
+	if err == errWeaviateNotFound {
+		err = s.do(ctx, http.MethodPost, "/v1/schema", map[string]interface{}{
+			"class":             class,
+			"vectorizer":        "none",
+			"vectorIndexConfig": map[string]interface{}{"distance": "cosine"},
+			"properties": []map[string]interface{}{
+				{"name": weaviateIDProperty, "dataType": []string{"text"}},
+			},
+		}, nil)
+		if err == nil {
+			logger.Info("Created Weaviate class '%s'", class)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.ready[class] = true
+	s.mu.Unlock()
+	return nil
+}
+
+// weaviateWhere translates filter to a where filter: And over the keys, Or
+// over each key's values. ok is false if a key isn't a property of the
+// class, in which case nothing can match.
+func weaviateWhere(filter models.MetadataFilter, schema *weaviateClass) (where map[string]interface{}, ok bool) {
+	properties := make(map[string]bool, len(schema.Properties))
+	for _, p := range schema.Properties {
+		properties[p.Name] = true
+	}
+
+	keys := make([]string, 0, len(filter))
+	for k := range filter {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	operands := make([]interface{}, 0, len(filter))
+	for _, k := range keys {
+		name := weaviatePropertyName(k)
+		if !properties[name] {
+			return nil, false
+		}
+		values := make([]interface{}, len(filter[k]))
+		for i, v := range filter[k] {
+			values[i] = map[string]interface{}{"path": []interface{}{name}, "operator": "Equal", "valueText": v}
+		}
+		if len(values) == 1 {
+			operands = append(operands, values[0])
+		} else {
+			operands = append(operands, map[string]interface{}{"operator": "Or", "operands": values})
+		}
+	}
+	return map[string]interface{}{"operator": "And", "operands": operands}, true
+}
+
+// graphQLInput renders a where filter in GraphQL input syntax: unquoted
+// field names, and operators as enum values rather than strings
+func graphQLInput(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fields := make([]string, len(keys))
+		for i, k := range keys {
+			if op, isString := v[k].(string); isString && k == "operator" {
+				fields[i] = k + ": " + op
+				continue
+			}
+			fields[i] = k + ": " + graphQLInput(v[k])
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = graphQLInput(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// graphQL runs a query and decodes its data into out
+func (s *WeaviateStore) graphQL(ctx context.Context, query string, out interface{}) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/v1/graphql", map[string]string{"query": query}, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return errors.External("Weaviate", "GraphQL query failed", fmt.Errorf("%s", resp.Errors[0].Message))
+	}
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return errors.External("Weaviate", "failed to decode GraphQL response", err)
+	}
+	return nil
+}
+
+// UpsertVectors inserts or updates vectors
+func (s *WeaviateStore) UpsertVectors(ctx context.Context, embeddings []*models.Embedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+
+	class := s.classFor(embeddings[0].Namespace)
+	if err := s.ensureClass(ctx, class); err != nil {
+		return err
+	}
+
+	objects := make([]map[string]interface{}, len(embeddings))
+	for i, emb := range embeddings {
+		properties := make(map[string]interface{}, len(emb.Metadata)+1)
+		for k, v := range emb.Metadata {
+			properties[weaviatePropertyName(k)] = v
+		}
+		properties[weaviateIDProperty] = emb.ID
+		objects[i] = map[string]interface{}{
+			"class":      class,
+			"id":         nameUUID(emb.ID),
+			"vector":     emb.Vector,
+			"properties": properties,
+		}
+	}
+
+	for start := 0; start < len(objects); start += weaviateBatchSize {
+		end := min(start+weaviateBatchSize, len(objects))
+		var results []struct {
+			ID     string `json:"id"`
+			Result struct {
+				Errors *struct {
+					Error []struct {
+						Message string `json:"message"`
+					} `json:"error"`
+				} `json:"errors"`
+			} `json:"result"`
+		}
+		if err := s.do(ctx, http.MethodPost, "/v1/batch/objects", map[string]interface{}{"objects": objects[start:end]}, &results); err != nil {
+			return err
+		}
+		for _, r := range results {
+			if r.Result.Errors != nil && len(r.Result.Errors.Error) > 0 {
+				return errors.External("Weaviate", fmt.Sprintf("failed to upsert object %s", r.ID), fmt.Errorf("%s", r.Result.Errors.Error[0].Message))
+			}
+		}
+	}
+
+	logger.Info("Upserted %d vectors to class '%s'", len(objects), class)
+	return nil
+}
+
+// DeleteVectors removes vectors by IDs
+func (s *WeaviateStore) DeleteVectors(ctx context.Context, ids []string, namespace string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	class := s.classFor(namespace)
+	objectIDs := make([]string, len(ids))
+	for i, id := range ids {
+		objectIDs[i] = nameUUID(id)
+	}
+	err := s.do(ctx, http.MethodDelete, "/v1/batch/objects", map[string]interface{}{
+		"match": map[string]interface{}{
+			"class": class,
+			"where": map[string]interface{}{
+				"path":           []string{"id"},
+				"operator":       "ContainsAny",
+				"valueTextArray": objectIDs,
+			},
+		},
+	}, nil)
+	if err == errWeaviateNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Deleted %d vectors from class '%s'", len(ids), class)
+	return nil
+}
+
+// DeleteByFilter removes the objects whose properties match filter
+func (s *WeaviateStore) DeleteByFilter(ctx context.Context, filter models.MetadataFilter, namespace string) error {
+	if len(filter) == 0 {
+		return errors.Validation("filter is required")
+	}
+
+	class := s.classFor(namespace)
+	schema, err := s.getClass(ctx, class)
+	if err == errWeaviateNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	where, ok := weaviateWhere(filter, schema)
+	if !ok {
+		return nil
+	}
+	err = s.do(ctx, http.MethodDelete, "/v1/batch/objects", map[string]interface{}{
+		"match": map[string]interface{}{"class": class, "where": where},
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Deleted vectors matching %v from class '%s'", filter, class)
+	return nil
+}
+
+// DeleteNamespace drops the namespace's class with its objects; the next
+// upsert creates it again
+func (s *WeaviateStore) DeleteNamespace(ctx context.Context, namespace string) error {
+	class := s.classFor(namespace)
+	err := s.do(ctx, http.MethodDelete, "/v1/schema/"+url.PathEscape(class), nil, nil)
+	if err != nil && err != errWeaviateNotFound {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.ready, class)
+	s.mu.Unlock()
+	logger.Info("Deleted class '%s'", class)
+	return nil
+}
+
+// ExistingIDs returns the subset of ids already stored in the namespace
+func (s *WeaviateStore) ExistingIDs(ctx context.Context, ids []string, namespace string) ([]string, error) {
+	existing := []string{}
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	class := s.classFor(namespace)
+	if _, err := s.getClass(ctx, class); err == errWeaviateNotFound {
+		return existing, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	values, _ := json.Marshal(ids)
+	query := fmt.Sprintf(`{ Get { %s(where: {path: ["%s"], operator: ContainsAny, valueTextArray: %s}, limit: %d) { %s } } }`,
+		class, weaviateIDProperty, values, len(ids), weaviateIDProperty)
+	var data struct {
+		Get map[string][]map[string]interface{} `json:"Get"`
+	}
+	if err := s.graphQL(ctx, query, &data); err != nil {
+		return nil, err
+	}
+	for _, obj := range data.Get[class] {
+		if id, ok := obj[weaviateIDProperty].(string); ok {
+			existing = append(existing, id)
+		}
+	}
+	return existing, nil
+}
+
+// QueryVectors searches for similar vectors
+func (s *WeaviateStore) QueryVectors(ctx context.Context, vector []float32, topK int, namespace string, filter models.MetadataFilter) ([]*models.Embedding, error) {
+	matches, err := s.Query(ctx, VectorQuery{Vector: vector, TopK: topK, Namespace: namespace, Filter: filter, IncludeVectors: true})
+	if err != nil {
+		return nil, err
+	}
+	return matchEmbeddings(matches, namespace), nil
+}
+
+// Query searches for similar vectors, filtering on property values. The
+// score is the cosine similarity, 1 minus Weaviate's distance.
+func (s *WeaviateStore) Query(ctx context.Context, q VectorQuery) ([]Match, error) {
+	if q.Sparse != nil {
+		return nil, errors.Validation("hybrid search is not supported by the weaviate backend")
+	}
+	class := s.classFor(q.Namespace)
+	schema, err := s.getClass(ctx, class)
+	if err == errWeaviateNotFound {
+		return []Match{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	additional := "id distance"
+	if q.IncludeVectors {
+		additional += " vector"
+	}
+
+	args := make([]string, 0, 3)
+	values, _ := json.Marshal(q.Vector)
+	args = append(args, fmt.Sprintf("nearVector: {vector: %s}", values), fmt.Sprintf("limit: %d", q.TopK))
+	if len(q.Filter) > 0 {
+		where, ok := weaviateWhere(q.Filter, schema)
+		if !ok {
+			return []Match{}, nil
+		}
+		args = append(args, "where: "+graphQLInput(where))
+	}
+
+	query := fmt.Sprintf(`{ Get { %s(%s) { %s } } }`, class, strings.Join(args, ", "), weaviateFields(schema, additional))
+	var data struct {
+		Get map[string][]map[string]json.RawMessage `json:"Get"`
+	}
+	if err := s.graphQL(ctx, query, &data); err != nil {
+		return nil, err
+	}
+
+	matches := make([]Match, 0, len(data.Get[class]))
+	for _, obj := range data.Get[class] {
+		matches = append(matches, weaviateMatch(obj))
+	}
+	return matches, nil
+}
+
+// weaviateFields lists every property of a class plus the additional fields
+// for GraphQL, which only returns the fields asked for
+func weaviateFields(schema *weaviateClass, additional string) string {
+	fields := make([]string, 0, len(schema.Properties)+1)
+	for _, p := range schema.Properties {
+		fields = append(fields, p.Name)
+	}
+	fields = append(fields, "_additional { "+additional+" }")
+	return strings.Join(fields, " ")
+}
+
+// weaviateMatch converts a GraphQL object to a match: the vector ID
+// property, the additional id, distance, and vector, and the other
+// properties as metadata
+func weaviateMatch(obj map[string]json.RawMessage) Match {
+	m := Match{Metadata: make(map[string]string, len(obj))}
+	for k, raw := range obj {
+		switch k {
+		case "_additional":
+			var additional struct {
+				ID       string    `json:"id"`
+				Distance float32   `json:"distance"`
+				Vector   []float32 `json:"vector"`
+			}
+			_ = json.Unmarshal(raw, &additional)
+			m.Score = 1 - additional.Distance
+			m.Vector = additional.Vector
+			if m.ID == "" {
+				m.ID = additional.ID
+			}
+		case weaviateIDProperty:
+			_ = json.Unmarshal(raw, &m.ID)
+		default:
+			var value interface{}
+			if err := json.Unmarshal(raw, &value); err != nil || value == nil {
+				continue
+			}
+			strVal, ok := value.(string)
+			if !ok {
+				strVal = fmt.Sprintf("%v", value)
+			}
+			m.Metadata[k] = strVal
+		}
+	}
+	return m
+}
+
+// Scan pages through the namespace's class with Weaviate's cursor, the
+// object UUID after which the next page starts
+func (s *WeaviateStore) Scan(ctx context.Context, namespace, cursor string, limit int) ([]StoredVector, string, error) {
+	class := s.classFor(namespace)
+	schema, err := s.getClass(ctx, class)
+	if err == errWeaviateNotFound {
+		return []StoredVector{}, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	args := fmt.Sprintf("limit: %d", limit)
+	if cursor != "" {
+		args += fmt.Sprintf(", after: %q", cursor)
+	}
+	query := fmt.Sprintf(`{ Get { %s(%s) { %s } } }`, class, args, weaviateFields(schema, "id vector"))
+	var data struct {
+		Get map[string][]map[string]json.RawMessage `json:"Get"`
+	}
+	if err := s.graphQL(ctx, query, &data); err != nil {
+		return nil, "", err
+	}
+
+	objects := data.Get[class]
+	vectors := make([]StoredVector, len(objects))
+	next := ""
+	for i, obj := range objects {
+		m := weaviateMatch(obj)
+		vectors[i] = StoredVector{ID: m.ID, Vector: m.Vector, Metadata: m.Metadata}
+
+		var additional struct {
+			ID string `json:"id"`
+		}
+		_ = json.Unmarshal(obj["_additional"], &additional)
+		next = additional.ID
+	}
+	if len(objects) < limit {
+		next = ""
+	}
+	return vectors, next, nil
+}
+
+// Fetch returns stored objects by vector ID, with properties and vector
+func (s *WeaviateStore) Fetch(ctx context.Context, ids []string, namespace string) ([]StoredVector, error) {
+	if len(ids) == 0 {
+		return []StoredVector{}, nil
+	}
+
+	class := s.classFor(namespace)
+	schema, err := s.getClass(ctx, class)
+	if err == errWeaviateNotFound {
+		return []StoredVector{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values, _ := json.Marshal(ids)
+	query := fmt.Sprintf(`{ Get { %s(where: {path: ["%s"], operator: ContainsAny, valueTextArray: %s}, limit: %d) { %s } } }`,
+		class, weaviateIDProperty, values, len(ids), weaviateFields(schema, "id vector"))
+	var data struct {
+		Get map[string][]map[string]json.RawMessage `json:"Get"`
+	}
+	if err := s.graphQL(ctx, query, &data); err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]StoredVector, len(data.Get[class]))
+	for _, obj := range data.Get[class] {
+		m := weaviateMatch(obj)
+		found[m.ID] = StoredVector{ID: m.ID, Vector: m.Vector, Metadata: m.Metadata}
+	}
+	return inOrder(ids, found), nil
+}
+
+// DescribeIndex gets index statistics
+func (s *WeaviateStore) DescribeIndex(ctx context.Context) (map[string]interface{}, error) {
+	var schema struct {
+		Classes []weaviateClass `json:"classes"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/v1/schema", nil, &schema); err != nil {
+		return nil, err
+	}
+
+	classes := make([]string, len(schema.Classes))
+	for i, c := range schema.Classes {
+		classes[i] = c.Class
+	}
+	return map[string]interface{}{
+		"name":      s.class,
+		"dimension": s.dimension,
+		"metric":    "cosine",
+		"host":      s.baseURL,
+		"classes":   classes,
+	}, nil
+}
+
+// Stats counts the objects of every class with an Aggregate query. Classes
+// are named after namespaces but not reversibly, so they are reported by
+// class name; the default class is namespace "".
+func (s *WeaviateStore) Stats(ctx context.Context) (*models.IndexStats, error) {
+	var schema struct {
+		Classes []weaviateClass `json:"classes"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/v1/schema", nil, &schema); err != nil {
+		return nil, err
+	}
+
+	stats := &models.IndexStats{
+		Backend:    BackendWeaviate,
+		Dimension:  s.dimension,
+		Namespaces: make(map[string]int64, len(schema.Classes)),
+	}
+	for _, c := range schema.Classes {
+		var data struct {
+			Aggregate map[string][]struct {
+				Meta struct {
+					Count int64 `json:"count"`
+				} `json:"meta"`
+			} `json:"Aggregate"`
+		}
+		if err := s.graphQL(ctx, fmt.Sprintf("{ Aggregate { %s { meta { count } } } }", c.Class), &data); err != nil {
+			return nil, err
+		}
+
+		var count int64
+		if groups := data.Aggregate[c.Class]; len(groups) > 0 {
+			count = groups[0].Meta.Count
+		}
+		namespace := c.Class
+		if namespace == s.class {
+			namespace = ""
+		}
+		stats.Namespaces[namespace] = count
+		stats.TotalVectors += count
+	}
+	return stats, nil
+}
+
+// Dimension is the configured vector length
+func (s *WeaviateStore) Dimension() int {
+	return s.dimension
+}
+
+// Health checks the connection health
+func (s *WeaviateStore) Health(ctx context.Context) error {
+	return s.do(ctx, http.MethodGet, "/v1/.well-known/ready", nil, nil)
+}
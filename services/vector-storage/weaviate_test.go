@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/errors"
+	"github.com/nadeeshame/Go_RepoSync_Micro/pkg/models"
+)
+
+// weaviateSchema is the class schema the fake server serves for class
+func weaviateSchema(class string, properties ...string) map[string]interface{} {
+	props := make([]map[string]string, len(properties))
+	for i, p := range properties {
+		props[i] = map[string]string{"name": p}
+	}
+	return map[string]interface{}{"class": class, "properties": props}
+}
+
+func TestWeaviateUpsert(t *testing.T) {
+	var created []map[string]interface{}
+	var batches [][]map[string]interface{}
+	schemaChecks := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("Authorization = %q, want the API key", got)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/schema/Acme_docs":
+			schemaChecks++
+			http.NotFound(w, r)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/schema":
+			var class map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&class)
+			created = append(created, class)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/batch/objects":
+			var req struct {
+				Objects []map[string]interface{} `json:"objects"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			batches = append(batches, req.Objects)
+			results := make([]map[string]interface{}, len(req.Objects))
+			for i, obj := range req.Objects {
+				results[i] = map[string]interface{}{"id": obj["id"], "result": map[string]interface{}{}}
+				if obj["id"] == nameUUID("bad") {
+					results[i]["result"] = map[string]interface{}{
+						"errors": map[string]interface{}{"error": []map[string]string{{"message": "vector lengths don't match"}}},
+					}
+				}
+			}
+			_ = json.NewEncoder(w).Encode(results)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := NewWeaviateStore(server.URL, "key", "reposync", 2)
+	ctx := context.Background()
+	err := store.UpsertVectors(ctx, []*models.Embedding{
+		{ID: "a", Vector: []float32{1, 0}, Namespace: "acme-docs", Metadata: map[string]string{"file_path": "a.md", "chunk-index": "0", "2x": "y"}},
+	})
+	if err != nil {
+		t.Fatalf("UpsertVectors() error: %v", err)
+	}
+
+	if len(created) != 1 || created[0]["class"] != "Acme_docs" || created[0]["vectorizer"] != "none" {
+		t.Fatalf("created classes = %v, want Acme_docs without a vectorizer", created)
+	}
+	want := map[string]interface{}{
+		"class":  "Acme_docs",
+		"id":     nameUUID("a"),
+		"vector": []interface{}{1.0, 0.0},
+		"properties": map[string]interface{}{
+			weaviateIDProperty: "a",
+			"file_path":        "a.md",
+			"chunk_index":      "0",
+			"_2x":              "y",
+		},
+	}
+	if len(batches) != 1 || !reflect.DeepEqual(batches[0][0], want) {
+		t.Errorf("batch objects = %v, want %v", batches, want)
+	}
+
+	// The class is known now, so the second upsert goes straight to the
+	// batch, which reports the failed object
+	err = store.UpsertVectors(ctx, []*models.Embedding{
+		{ID: "b", Vector: []float32{0, 1}, Namespace: "acme-docs"},
+		{ID: "bad", Vector: []float32{0}, Namespace: "acme-docs"},
+	})
+	var appErr *errors.AppError
+	if !stderrors.As(err, &appErr) || appErr.Type != errors.ErrTypeExternal || !strings.Contains(err.Error(), "vector lengths don't match") {
+		t.Errorf("UpsertVectors() error = %v, want the object's error", err)
+	}
+	if schemaChecks != 1 || len(created) != 1 {
+		t.Errorf("checked the schema %d times and created %d classes, want 1 each", schemaChecks, len(created))
+	}
+}
+
+func TestWeaviateQuery(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/schema/Reposync":
+			_ = json.NewEncoder(w).Encode(weaviateSchema("Reposync", weaviateIDProperty, "repository", "file_path"))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/graphql":
+			var req struct {
+				Query string `json:"query"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			queries = append(queries, req.Query)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"Get": map[string]interface{}{
+						"Reposync": []map[string]interface{}{{
+							weaviateIDProperty: "a",
+							"repository":       "org/x",
+							"file_path":        "a.md",
+							"_additional":      map[string]interface{}{"id": nameUUID("a"), "distance": 0.25},
+						}},
+					},
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := NewWeaviateStore(server.URL, "", "reposync", 2)
+	ctx := context.Background()
+	matches, err := store.Query(ctx, VectorQuery{
+		Vector: []float32{1, 0},
+		TopK:   3,
+		Filter: models.MetadataFilter{"repository": {"org/x"}, "file_path": {"a.md", "b.md"}},
+	})
+	if err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+
+	want := []Match{{ID: "a", Score: 0.75, Metadata: map[string]string{"repository": "org/x", "file_path": "a.md"}}}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("Query() = %+v, want %+v", matches, want)
+	}
+	where := `where: {operands: [{operands: [{operator: Equal, path: ["file_path"], valueText: "a.md"}, ` +
+		`{operator: Equal, path: ["file_path"], valueText: "b.md"}], operator: Or}, ` +
+		`{operator: Equal, path: ["repository"], valueText: "org/x"}], operator: And}`
+	if len(queries) != 1 || !strings.Contains(queries[0], "nearVector: {vector: [1,0]}, limit: 3, "+where) {
+		t.Errorf("queries = %q, want a nearVector query with %s", queries, where)
+	}
+
+	// A filter on a property the class doesn't have matches nothing, and
+	// a namespace without a class has nothing to search
+	for _, q := range []VectorQuery{
+		{Vector: []float32{1, 0}, TopK: 3, Filter: models.MetadataFilter{"branch": {"main"}}},
+		{Vector: []float32{1, 0}, TopK: 3, Namespace: "empty"},
+	} {
+		matches, err := store.Query(ctx, q)
+		if err != nil || len(matches) != 0 {
+			t.Errorf("Query(%+v) = %v, %v, want no matches", q, matches, err)
+		}
+	}
+	if len(queries) != 1 {
+		t.Errorf("ran %d GraphQL queries, want no more for unmatchable queries", len(queries))
+	}
+
+	if _, err := store.Query(ctx, VectorQuery{Vector: []float32{1, 0}, TopK: 3, Sparse: &models.SparseVector{}}); err == nil {
+		t.Error("hybrid Query() succeeded, want a validation error")
+	}
+}
+
+func TestWeaviateDeleteByFilter(t *testing.T) {
+	var deletes []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/schema/Acme":
+			_ = json.NewEncoder(w).Encode(weaviateSchema("Acme", weaviateIDProperty, "repository"))
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/batch/objects":
+			var req struct {
+				Match map[string]interface{} `json:"match"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			deletes = append(deletes, req.Match)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": map[string]int{"matches": 2}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := NewWeaviateStore(server.URL, "", "reposync", 2)
+	ctx := context.Background()
+	if err := store.DeleteByFilter(ctx, models.MetadataFilter{"repository": {"org/x"}}, "acme"); err != nil {
+		t.Fatalf("DeleteByFilter() error: %v", err)
+	}
+	want := map[string]interface{}{
+		"class": "Acme",
+		"where": map[string]interface{}{
+			"operator": "And",
+			"operands": []interface{}{
+				map[string]interface{}{"path": []interface{}{"repository"}, "operator": "Equal", "valueText": "org/x"},
+			},
+		},
+	}
+	if len(deletes) != 1 || !reflect.DeepEqual(deletes[0], want) {
+		t.Errorf("delete matches = %v, want %v", deletes, want)
+	}
+
+	// Nothing can match an unknown property or a missing class
+	if err := store.DeleteByFilter(ctx, models.MetadataFilter{"branch": {"main"}}, "acme"); err != nil {
+		t.Errorf("DeleteByFilter() on an unknown property error: %v", err)
+	}
+	if err := store.DeleteByFilter(ctx, models.MetadataFilter{"repository": {"org/x"}}, "gone"); err != nil {
+		t.Errorf("DeleteByFilter() on a missing class error: %v", err)
+	}
+	if len(deletes) != 1 {
+		t.Errorf("sent %d deletes, want none for filters nothing matches", len(deletes))
+	}
+
+	var appErr *errors.AppError
+	if err := store.DeleteByFilter(ctx, nil, "acme"); !stderrors.As(err, &appErr) || appErr.Type != errors.ErrTypeValidation {
+		t.Errorf("DeleteByFilter() without a filter error = %v, want a validation error", err)
+	}
+}
+
+func TestWeaviateErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/schema/Broken":
+			http.Error(w, "disk full", http.StatusInternalServerError)
+		case r.URL.Path == "/v1/schema/Reposync":
+			_ = json.NewEncoder(w).Encode(weaviateSchema("Reposync", weaviateIDProperty))
+		case r.URL.Path == "/v1/graphql":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"errors": []map[string]string{{"message": "Cannot query field \"nope\""}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := NewWeaviateStore(server.URL, "", "reposync", 2)
+	ctx := context.Background()
+	query := VectorQuery{Vector: []float32{1, 0}, TopK: 1}
+
+	tests := []struct {
+		name     string
+		call     func() error
+		wantType errors.ErrorType
+		wantText string
+	}{
+		{
+			name: "error status",
+			call: func() error {
+				_, err := store.Query(ctx, VectorQuery{Vector: query.Vector, TopK: 1, Namespace: "broken"})
+				return err
+			},
+			wantType: errors.ErrTypeExternal,
+			wantText: "status 500: disk full",
+		},
+		{
+			name:     "GraphQL errors",
+			call:     func() error { _, err := store.Query(ctx, query); return err },
+			wantType: errors.ErrTypeExternal,
+			wantText: `Cannot query field "nope"`,
+		},
+		{
+			name:     "missing class on delete",
+			call:     func() error { return store.DeleteVectors(ctx, []string{"a"}, "gone") },
+			wantType: "",
+		},
+		{
+			name: "unreachable server",
+			call: func() error {
+				_, err := NewWeaviateStore("http://127.0.0.1:1", "", "reposync", 2).Query(ctx, query)
+				return err
+			},
+			wantType: errors.ErrTypeNetwork,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if tt.wantType == "" {
+				if err != nil {
+					t.Errorf("error = %v, want none", err)
+				}
+				return
+			}
+			var appErr *errors.AppError
+			if !stderrors.As(err, &appErr) || appErr.Type != tt.wantType || !strings.Contains(err.Error(), tt.wantText) {
+				t.Errorf("error = %v, want a %s error containing %q", err, tt.wantType, tt.wantText)
+			}
+		})
+	}
+}